@@ -255,6 +255,12 @@ test.docs: istioctl ## Run the documentation examples tests.
 .PHONY: build
 build: build-$(TARGET_ARCH) ## Build the sail-operator binary.
 
+.PHONY: build-sailctl
+build-sailctl: TARGET_OS=$(shell go env GOOS)
+build-sailctl: TARGET_ARCH=$(shell go env GOARCH)
+build-sailctl: ## Build the sailctl CLI for the host platform.
+	GOOS=$(TARGET_OS) GOARCH=$(TARGET_ARCH) CGO_ENABLED=$(CGO_ENABLED) LDFLAGS="$(LD_FLAGS)" common/scripts/gobuild.sh $(REPO_ROOT)/out/$(TARGET_OS)_$(TARGET_ARCH)/sailctl ./cmd/sailctl
+
 .PHONY: run
 run: gen ## Run a controller from your host.
 	POD_NAMESPACE=${NAMESPACE} go run ./cmd/main.go --config-file=./hack/config.properties
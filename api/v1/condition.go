@@ -64,6 +64,16 @@ func SetCondition(conditions *[]StatusCondition, condition StatusCondition) {
 	*conditions = append(*conditions, condition)
 }
 
+// RemoveCondition removes the condition of the specified type from the slice, if present.
+func RemoveCondition(conditions *[]StatusCondition, conditionType ConditionType) {
+	for i, existing := range *conditions {
+		if existing.Type == conditionType {
+			*conditions = append((*conditions)[:i], (*conditions)[i+1:]...)
+			return
+		}
+	}
+}
+
 // GetCondition returns the condition of the specified type from the slice,
 // or a default condition with Status=Unknown if not found.
 func GetCondition(conditions []StatusCondition, conditionType ConditionType) StatusCondition {
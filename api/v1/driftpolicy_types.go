@@ -0,0 +1,62 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+// DriftAction defines what the operator does when an object that it deployed was changed
+// by someone else.
+// +kubebuilder:validation:Enum=Revert;Report;Ignore
+type DriftAction string
+
+const (
+	// DriftActionRevert reports the changed fields and reverts them to the values in the
+	// Helm release manifest.
+	DriftActionRevert DriftAction = "Revert"
+
+	// DriftActionReport reports the changed fields and keeps them, also when the
+	// Helm release is upgraded.
+	DriftActionReport DriftAction = "Report"
+
+	// DriftActionIgnore neither reports nor reverts the changed fields.
+	DriftActionIgnore DriftAction = "Ignore"
+)
+
+// DriftPolicy defines how the operator handles changes made directly to the objects it deployed
+// (for example, a manual edit of the istiod Deployment).
+type DriftPolicy struct {
+	// Defines the action to take for drifted fields that aren't matched by any rule.
+	// Deleted objects are recreated whenever the Helm charts are upgraded, regardless of the action.
+	// +kubebuilder:default=Revert
+	Action DriftAction `json:"action,omitempty"`
+
+	// Defines the action to take for specific objects or fields. The first matching rule wins.
+	Rules []DriftRule `json:"rules,omitempty"`
+}
+
+// DriftRule selects the fields of the deployed objects to which a DriftAction applies.
+type DriftRule struct {
+	// The kind of the object, e.g. Deployment. If not set, the rule matches objects of all kinds.
+	Kind string `json:"kind,omitempty"`
+
+	// The name of the object. If not set, the rule matches objects with any name.
+	Name string `json:"name,omitempty"`
+
+	// The dot-separated path of the field, without list indices, e.g. `spec.template.spec.containers.image`.
+	// The rule also matches all fields nested under the path. If not set, the rule matches all fields.
+	Path string `json:"path,omitempty"`
+
+	// The action to take when a matching field drifts.
+	// +kubebuilder:validation:Required
+	Action DriftAction `json:"action"`
+}
@@ -28,6 +28,8 @@ const (
 
 	DefaultRevisionDeletionGracePeriodSeconds = 30
 	MinRevisionDeletionGracePeriodSeconds     = 0
+
+	DefaultPromotionSoakTimeSeconds = 300
 )
 
 // IstioSpec defines the desired state of Istio
@@ -36,8 +38,9 @@ type IstioSpec struct {
 	// +sail:version
 	// Defines the version of Istio to install.
 	// Must be one of: v1.31-latest, v1.31.0-beta.1, v1.30-latest, v1.30.3, v1.30.2, v1.30.1, v1.30.0, v1.29-latest, v1.29.6, v1.29.5, v1.29.4, v1.29.3, v1.29.2, v1.29.1, v1.29.0, master, v1.32.0-alpha.8dc789c5.
+	// Versions provided by an IstioChartSource can also be used.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=1,displayName="Istio Version",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:fieldGroup:General", "urn:alm:descriptor:com.tectonic.ui:select:v1.31-latest", "urn:alm:descriptor:com.tectonic.ui:select:v1.31.0-beta.1", "urn:alm:descriptor:com.tectonic.ui:select:v1.30-latest", "urn:alm:descriptor:com.tectonic.ui:select:v1.30.3", "urn:alm:descriptor:com.tectonic.ui:select:v1.30.2", "urn:alm:descriptor:com.tectonic.ui:select:v1.30.1", "urn:alm:descriptor:com.tectonic.ui:select:v1.30.0", "urn:alm:descriptor:com.tectonic.ui:select:v1.29-latest", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.6", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.5", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.4", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.3", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.2", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.1", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.0", "urn:alm:descriptor:com.tectonic.ui:select:master", "urn:alm:descriptor:com.tectonic.ui:select:v1.32.0-alpha.8dc789c5"}
-	// +kubebuilder:validation:Enum=v1.31-latest;v1.31.0-beta.1;v1.30-latest;v1.30.3;v1.30.2;v1.30.1;v1.30.0;v1.29-latest;v1.29.6;v1.29.5;v1.29.4;v1.29.3;v1.29.2;v1.29.1;v1.29.0;v1.28-latest;v1.28.10;v1.28.9;v1.28.8;v1.28.7;v1.28.6;v1.28.5;v1.28.4;v1.28.3;v1.28.2;v1.28.1;v1.28.0;v1.27-latest;v1.27.9;v1.27.8;v1.27.7;v1.27.6;v1.27.5;v1.27.4;v1.27.3;v1.27.2;v1.27.1;v1.27.0;v1.26-latest;v1.26.8;v1.26.7;v1.26.6;v1.26.5;v1.26.4;v1.26.3;v1.26.2;v1.26.1;v1.26.0;v1.25-latest;v1.25.5;v1.25.4;v1.25.3;v1.25.2;v1.25.1;v1.24-latest;v1.24.6;v1.24.5;v1.24.4;v1.24.3;v1.24.2;v1.24.1;v1.24.0;v1.23-latest;v1.23.6;v1.23.5;v1.23.4;v1.23.3;v1.23.2;v1.22-latest;v1.22.8;v1.22.7;v1.22.6;v1.22.5;v1.21.6;master;v1.32.0-alpha.8dc789c5
+	// +kubebuilder:validation:Pattern=`^(master|v\d+\.\d+(\.\d+)?(-[0-9A-Za-z.]+)?)$`
 	// +kubebuilder:default=v1.31.0-beta.1
 	Version string `json:"version"`
 
@@ -63,6 +66,21 @@ type IstioSpec struct {
 	// Defines the values to be passed to the Helm charts when installing Istio.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Helm Values"
 	Values *Values `json:"values,omitempty"`
+
+	// Defines how the operator handles changes made directly to the objects it deployed.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Drift Policy"
+	DriftPolicy *DriftPolicy `json:"driftPolicy,omitempty"`
+
+	// Defines patches that are applied to the objects rendered from the Helm charts, in order. Use them
+	// to change settings that aren't exposed through the Helm values.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Patches"
+	Patches []Patch `json:"patches,omitempty"`
+
+	// Defines the IstioCNI and ZTunnel resources that the revision depends on. If not set, it depends on
+	// the resources named `default`.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Dependencies"
+	// +optional
+	Dependencies *Dependencies `json:"dependencies,omitempty"`
 }
 
 // IstioUpdateStrategy defines how the control plane should be updated when the version in
@@ -96,6 +114,39 @@ type IstioUpdateStrategy struct {
 	// Defaults to false.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=3,displayName="Update Workloads Automatically",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:booleanSwitch"}
 	UpdateWorkloads bool `json:"updateWorkloads,omitempty"`
+
+	// Defines how a new revision is promoted to become the active revision. When promotion is
+	// configured, the revision created for a new spec.version first serves only the canary
+	// namespaces. The revision is promoted to become the active revision once it and the workloads
+	// in the canary namespaces have been healthy for the configured soak time. If the new revision
+	// degrades, the canary namespaces and any IstioRevisionTags that target the Istio are rolled
+	// back to the previously active revision.
+	// When promotion isn't configured, the new revision becomes the active revision immediately.
+	// Only used with the "RevisionBased" strategy.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=4,displayName="Promotion"
+	// +optional
+	Promotion *RevisionPromotion `json:"promotion,omitempty"`
+}
+
+// RevisionPromotion defines how a new revision is canaried before it is promoted to become the
+// active revision.
+type RevisionPromotion struct {
+	// Selects the namespaces that should use the new revision while it is being canaried. The
+	// operator sets the istio.io/rev label of these namespaces to the name of the new revision.
+	// Namespaces that use the istio-injection label are never relabeled. If not set, the new
+	// revision doesn't serve any namespaces before it is promoted.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=1,displayName="Canary Namespace Selector"
+	// +optional
+	CanaryNamespaceSelector *metav1.LabelSelector `json:"canaryNamespaceSelector,omitempty"`
+
+	// Defines how many seconds the new revision and the workloads in the canary namespaces must
+	// remain healthy before the revision is promoted. After promotion, the previously active revision
+	// is kept for the same amount of time, so that the operator can roll back to it if the promoted
+	// revision degrades. The default value is 300.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=2,displayName="Soak Time (seconds)",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:number"}
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	SoakTimeSeconds *int64 `json:"soakTimeSeconds,omitempty"`
 }
 
 // IstioStatus defines the observed state of Istio
@@ -112,12 +163,66 @@ type IstioStatus struct {
 	// Reports the current state of the object.
 	State IstioConditionReason `json:"state,omitempty"`
 
+	// The version that spec.version resolves to (e.g. the patch version that an alias like `v1.30-latest`
+	// refers to).
+	ResolvedVersion string `json:"resolvedVersion,omitempty"`
+
 	// The name of the active revision.
 	ActiveRevisionName string `json:"activeRevisionName,omitempty"`
 
 	// Reports information about the underlying IstioRevisions.
 	// +optional
 	Revisions RevisionSummary `json:"revisions"`
+
+	// Reports the progress of the staged promotion of the most recent revision. Only set when
+	// spec.updateStrategy.promotion is configured.
+	// +optional
+	Promotion *RevisionPromotionStatus `json:"promotion,omitempty"`
+
+	// Reports the changes that the operator would make to the cluster if the
+	// sailoperator.io/dry-run annotation was removed from the object. Only set
+	// while the annotation is present.
+	// +optional
+	Plan *IstioRevisionPlan `json:"plan,omitempty"`
+}
+
+// PromotionPhase represents a phase of the staged promotion of a revision.
+type PromotionPhase string
+
+const (
+	// PromotionPhaseCanary means that the new revision serves the canary namespaces, but hasn't become healthy yet.
+	PromotionPhaseCanary PromotionPhase = "Canary"
+
+	// PromotionPhaseSoaking means that the new revision has become healthy and is soaking before it is promoted.
+	PromotionPhaseSoaking PromotionPhase = "Soaking"
+
+	// PromotionPhasePromoted means that the new revision was promoted to become the active revision.
+	PromotionPhasePromoted PromotionPhase = "Promoted"
+
+	// PromotionPhaseRolledBack means that the new revision degraded and the operator rolled back to the
+	// previously active revision. The revision isn't promoted again until spec.version changes.
+	PromotionPhaseRolledBack PromotionPhase = "RolledBack"
+)
+
+// RevisionPromotionStatus reports the progress of the staged promotion of a revision.
+type RevisionPromotionStatus struct {
+	// The name of the revision that is being promoted.
+	RevisionName string `json:"revisionName"`
+
+	// The name of the revision that was active when the promotion started. This is the revision
+	// the operator rolls back to if the promoted revision degrades.
+	PreviousRevisionName string `json:"previousRevisionName,omitempty"`
+
+	// The current phase of the promotion.
+	Phase PromotionPhase `json:"phase"`
+
+	// The time since which the revision and the workloads in the canary namespaces have been healthy.
+	// +optional
+	HealthySince *metav1.Time `json:"healthySince,omitempty"`
+
+	// The time at which the revision was promoted to become the active revision.
+	// +optional
+	PromotionTime *metav1.Time `json:"promotionTime,omitempty"`
 }
 
 // RevisionSummary contains information on the number of IstioRevisions associated with this Istio.
@@ -145,6 +250,11 @@ func (s *IstioStatus) SetCondition(condition StatusCondition) {
 	SetCondition(&s.Conditions, condition)
 }
 
+// RemoveCondition removes the condition of the specified type from the list of conditions
+func (s *IstioStatus) RemoveCondition(conditionType IstioConditionType) {
+	RemoveCondition(&s.Conditions, conditionType)
+}
+
 // IstioConditionType is an alias for ConditionType.
 type IstioConditionType = ConditionType
 
@@ -158,6 +268,14 @@ const (
 
 	// IstioReasonReconcileError indicates that the reconciliation of the resource has failed, but will be retried.
 	IstioReasonReconcileError IstioConditionReason = "ReconcileError"
+
+	// IstioReasonInvalidPatch indicates that one of the patches in spec.patches is invalid or can't be applied
+	// to the rendered objects. The reconciliation isn't retried until the resource is updated.
+	IstioReasonInvalidPatch IstioConditionReason = "InvalidPatch"
+
+	// IstioReasonDryRun indicates that the sailoperator.io/dry-run annotation is set, so the operator
+	// only computed the changes it would make to the cluster and reported them in status.plan.
+	IstioReasonDryRun IstioConditionReason = "DryRun"
 )
 
 const (
@@ -198,6 +316,45 @@ const (
 	IstioReasonDependencyCheckFailed IstioConditionReason = "DependencyCheckFailed"
 )
 
+const (
+	// IstioConditionWorkloadsUpdated signifies whether all workloads have been moved to the active revision.
+	// The condition is only reported when spec.updateStrategy.updateWorkloads is enabled.
+	IstioConditionWorkloadsUpdated IstioConditionType = "WorkloadsUpdated"
+
+	// IstioReasonWaitingForActiveRevision indicates that workloads will be moved once the active revision is ready.
+	IstioReasonWaitingForActiveRevision IstioConditionReason = "WaitingForActiveRevision"
+
+	// IstioReasonWorkloadUpdateInProgress indicates that workloads are being restarted to move them to the active revision.
+	IstioReasonWorkloadUpdateInProgress IstioConditionReason = "WorkloadUpdateInProgress"
+
+	// IstioReasonManualRestartRequired indicates that some pods reference an inactive revision, but aren't controlled
+	// by a Deployment, StatefulSet or DaemonSet, so the operator can't restart them.
+	IstioReasonManualRestartRequired IstioConditionReason = "ManualRestartRequired"
+
+	// IstioReasonWorkloadUpdateBlocked indicates that some workloads can't be moved to the active revision, either
+	// because their restart didn't complete in time or because they would still be injected by another revision
+	// when restarted, e.g. because their namespace references a revision tag that points to another revision.
+	IstioReasonWorkloadUpdateBlocked IstioConditionReason = "WorkloadUpdateBlocked"
+)
+
+const (
+	// IstioConditionRevisionPromoted signifies whether the revision for the current spec.version has been
+	// promoted to become the active revision. The condition is only reported when
+	// spec.updateStrategy.promotion is configured.
+	IstioConditionRevisionPromoted IstioConditionType = "RevisionPromoted"
+
+	// IstioReasonCanaryNotHealthy indicates that the new revision serves the canary namespaces, but it or the
+	// workloads in the canary namespaces aren't healthy yet.
+	IstioReasonCanaryNotHealthy IstioConditionReason = "CanaryNotHealthy"
+
+	// IstioReasonCanarySoaking indicates that the new revision is healthy and will be promoted once the soak time expires.
+	IstioReasonCanarySoaking IstioConditionReason = "CanarySoaking"
+
+	// IstioReasonRolledBack indicates that the new revision degraded and the operator rolled back to the
+	// previously active revision.
+	IstioReasonRolledBack IstioConditionReason = "RolledBack"
+)
+
 const (
 	// IstioReasonHealthy indicates that the control plane is fully reconciled and that all components are ready.
 	IstioReasonHealthy IstioConditionReason = "Healthy"
@@ -19,7 +19,8 @@ import (
 )
 
 const (
-	IstioCNIKind = "IstioCNI"
+	IstioCNIKind        = "IstioCNI"
+	DefaultIstioCNIName = "default"
 )
 
 // IstioCNISpec defines the desired state of IstioCNI
@@ -27,8 +28,9 @@ type IstioCNISpec struct {
 	// +sail:version
 	// Defines the version of Istio to install.
 	// Must be one of: v1.31-latest, v1.31.0-beta.1, v1.30-latest, v1.30.3, v1.30.2, v1.30.1, v1.30.0, v1.29-latest, v1.29.6, v1.29.5, v1.29.4, v1.29.3, v1.29.2, v1.29.1, v1.29.0, master, v1.32.0-alpha.8dc789c5.
+	// Versions provided by an IstioChartSource can also be used.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=1,displayName="Istio Version",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:fieldGroup:General", "urn:alm:descriptor:com.tectonic.ui:select:v1.31-latest", "urn:alm:descriptor:com.tectonic.ui:select:v1.31.0-beta.1", "urn:alm:descriptor:com.tectonic.ui:select:v1.30-latest", "urn:alm:descriptor:com.tectonic.ui:select:v1.30.3", "urn:alm:descriptor:com.tectonic.ui:select:v1.30.2", "urn:alm:descriptor:com.tectonic.ui:select:v1.30.1", "urn:alm:descriptor:com.tectonic.ui:select:v1.30.0", "urn:alm:descriptor:com.tectonic.ui:select:v1.29-latest", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.6", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.5", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.4", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.3", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.2", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.1", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.0", "urn:alm:descriptor:com.tectonic.ui:select:master", "urn:alm:descriptor:com.tectonic.ui:select:v1.32.0-alpha.8dc789c5"}
-	// +kubebuilder:validation:Enum=v1.31-latest;v1.31.0-beta.1;v1.30-latest;v1.30.3;v1.30.2;v1.30.1;v1.30.0;v1.29-latest;v1.29.6;v1.29.5;v1.29.4;v1.29.3;v1.29.2;v1.29.1;v1.29.0;v1.28-latest;v1.28.10;v1.28.9;v1.28.8;v1.28.7;v1.28.6;v1.28.5;v1.28.4;v1.28.3;v1.28.2;v1.28.1;v1.28.0;v1.27-latest;v1.27.9;v1.27.8;v1.27.7;v1.27.6;v1.27.5;v1.27.4;v1.27.3;v1.27.2;v1.27.1;v1.27.0;v1.26-latest;v1.26.8;v1.26.7;v1.26.6;v1.26.5;v1.26.4;v1.26.3;v1.26.2;v1.26.1;v1.26.0;v1.25-latest;v1.25.5;v1.25.4;v1.25.3;v1.25.2;v1.25.1;v1.24-latest;v1.24.6;v1.24.5;v1.24.4;v1.24.3;v1.24.2;v1.24.1;v1.24.0;v1.23-latest;v1.23.6;v1.23.5;v1.23.4;v1.23.3;v1.23.2;v1.22-latest;v1.22.8;v1.22.7;v1.22.6;v1.22.5;v1.21.6;master;v1.32.0-alpha.8dc789c5
+	// +kubebuilder:validation:Pattern=`^(master|v\d+\.\d+(\.\d+)?(-[0-9A-Za-z.]+)?)$`
 	// +kubebuilder:default=v1.31.0-beta.1
 	Version string `json:"version"`
 
@@ -49,6 +51,22 @@ type IstioCNISpec struct {
 	// Defines the values to be passed to the Helm charts when installing Istio CNI.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Helm Values"
 	Values *CNIValues `json:"values,omitempty"`
+
+	// Restricts the istio-cni-node DaemonSet to the nodes that have all of the given labels, in addition to the node
+	// selector of the Helm chart. Several IstioCNI resources can be installed in different namespaces if their
+	// node selectors select disjoint sets of nodes.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Node Selector"
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Defines how the operator handles changes made directly to the objects it deployed.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Drift Policy"
+	DriftPolicy *DriftPolicy `json:"driftPolicy,omitempty"`
+
+	// Defines patches that are applied to the objects rendered from the Helm charts, in order. Use them
+	// to change settings that aren't exposed through the Helm values.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Patches"
+	Patches []Patch `json:"patches,omitempty"`
 }
 
 // IstioCNIStatus defines the observed state of IstioCNI
@@ -64,6 +82,20 @@ type IstioCNIStatus struct {
 
 	// Reports the current state of the object.
 	State IstioCNIConditionReason `json:"state,omitempty"`
+
+	// The version that spec.version resolves to (e.g. the patch version that an alias like `v1.30-latest`
+	// refers to).
+	ResolvedVersion string `json:"resolvedVersion,omitempty"`
+
+	// Reports the readiness of each object deployed for this resource.
+	// +optional
+	Resources []ResourceStatus `json:"resources,omitempty"`
+
+	// Reports the revisions of the Helm release of this resource that are retained
+	// by the operator, newest first. A retained revision can be reinstalled by setting
+	// the sailoperator.io/rollback-to annotation to its number.
+	// +optional
+	ReleaseHistory []HelmReleaseRevision `json:"releaseHistory,omitempty"`
 }
 
 // GetCondition returns the condition of the specified type
@@ -92,6 +124,14 @@ const (
 
 	// IstioCNIReasonReconcileError indicates that the reconciliation of the resource has failed, but will be retried.
 	IstioCNIReasonReconcileError IstioCNIConditionReason = "ReconcileError"
+
+	// IstioCNIReasonInvalidPatch indicates that one of the patches in spec.patches is invalid or can't be applied
+	// to the rendered objects. The reconciliation isn't retried until the resource is updated.
+	IstioCNIReasonInvalidPatch IstioCNIConditionReason = "InvalidPatch"
+
+	// IstioCNIReasonRolledBack indicates that the sailoperator.io/rollback-to annotation is set, so the
+	// operator reinstalled a previous revision of the Helm release instead of the one defined in the spec.
+	IstioCNIReasonRolledBack IstioCNIConditionReason = "RolledBack"
 )
 
 const (
@@ -103,6 +143,43 @@ const (
 
 	// IstioCNIReasonReadinessCheckFailed indicates that the DaemonSet readiness status could not be ascertained.
 	IstioCNIReasonReadinessCheckFailed IstioCNIConditionReason = "ReadinessCheckFailed"
+
+	// IstioCNIReasonResourcesNotReady indicates that the istio-cni-node DaemonSet has pods running, but some of the objects
+	// deployed for the resource are missing or still being rolled out. See status.resources for details.
+	IstioCNIReasonResourcesNotReady IstioCNIConditionReason = "ResourcesNotReady"
+)
+
+const (
+	// IstioCNIConditionDriftDetected signifies whether someone other than the operator changed the objects
+	// deployed for the IstioCNI.
+	IstioCNIConditionDriftDetected IstioCNIConditionType = "DriftDetected"
+
+	// IstioCNIReasonDriftReverted indicates that drifted fields were found and reverted to the values in the
+	// Helm release manifest.
+	IstioCNIReasonDriftReverted IstioCNIConditionReason = "DriftReverted"
+
+	// IstioCNIReasonDriftReported indicates that drifted fields were found, but kept as configured in spec.driftPolicy.
+	IstioCNIReasonDriftReported IstioCNIConditionReason = "DriftReported"
+
+	// IstioCNIReasonNoDrift indicates that the deployed objects match the Helm release manifest.
+	IstioCNIReasonNoDrift IstioCNIConditionReason = "NoDrift"
+
+	// IstioCNIReasonDriftCheckFailed indicates that the deployed objects could not be compared with the Helm release manifest.
+	IstioCNIReasonDriftCheckFailed IstioCNIConditionReason = "DriftCheckFailed"
+)
+
+const (
+	// IstioCNIConditionFieldConflicts signifies whether the operator skipped fields of the objects deployed for the
+	// IstioCNI, because other field managers own them. It's only set when the operator uses server-side apply.
+	IstioCNIConditionFieldConflicts IstioCNIConditionType = "FieldConflicts"
+
+	// IstioCNIReasonConflictsSkipped indicates that some fields in the Helm release manifest are owned by other
+	// field managers (e.g. spec.replicas of a Deployment scaled by a HorizontalPodAutoscaler), so they were
+	// left as set by those managers instead of being overwritten.
+	IstioCNIReasonConflictsSkipped IstioCNIConditionReason = "ConflictsSkipped"
+
+	// IstioCNIReasonNoConflicts indicates that all fields in the Helm release manifest were applied.
+	IstioCNIReasonNoConflicts IstioCNIConditionReason = "NoConflicts"
 )
 
 const (
@@ -119,7 +196,6 @@ const (
 // +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.state",description="The current state of this object."
 // +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".spec.version",description="The version of the Istio CNI installation."
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="The age of the object"
-// +kubebuilder:validation:XValidation:rule="self.metadata.name == 'default'",message="metadata.name must be 'default'"
 
 // IstioCNI represents a deployment of the Istio CNI component.
 type IstioCNI struct {
@@ -29,8 +29,9 @@ type IstioRevisionSpec struct {
 	// +sail:version
 	// Defines the version of Istio to install.
 	// Must be one of: v1.31.0-beta.1, v1.30.3, v1.30.2, v1.30.1, v1.30.0, v1.29.6, v1.29.5, v1.29.4, v1.29.3, v1.29.2, v1.29.1, v1.29.0, v1.32.0-alpha.8dc789c5.
+	// Versions provided by an IstioChartSource can also be used.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=1,displayName="Istio Version",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:fieldGroup:General", "urn:alm:descriptor:com.tectonic.ui:select:v1.31.0-beta.1", "urn:alm:descriptor:com.tectonic.ui:select:v1.30.3", "urn:alm:descriptor:com.tectonic.ui:select:v1.30.2", "urn:alm:descriptor:com.tectonic.ui:select:v1.30.1", "urn:alm:descriptor:com.tectonic.ui:select:v1.30.0", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.6", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.5", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.4", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.3", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.2", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.1", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.0", "urn:alm:descriptor:com.tectonic.ui:select:v1.32.0-alpha.8dc789c5"}
-	// +kubebuilder:validation:Enum=v1.31.0-beta.1;v1.30.3;v1.30.2;v1.30.1;v1.30.0;v1.29.6;v1.29.5;v1.29.4;v1.29.3;v1.29.2;v1.29.1;v1.29.0;v1.28.10;v1.28.9;v1.28.8;v1.28.7;v1.28.6;v1.28.5;v1.28.4;v1.28.3;v1.28.2;v1.28.1;v1.28.0;v1.27.9;v1.27.8;v1.27.7;v1.27.6;v1.27.5;v1.27.4;v1.27.3;v1.27.2;v1.27.1;v1.27.0;v1.26.8;v1.26.7;v1.26.6;v1.26.5;v1.26.4;v1.26.3;v1.26.2;v1.26.1;v1.26.0;v1.25.5;v1.25.4;v1.25.3;v1.25.2;v1.25.1;v1.24.6;v1.24.5;v1.24.4;v1.24.3;v1.24.2;v1.24.1;v1.24.0;v1.23.6;v1.23.5;v1.23.4;v1.23.3;v1.23.2;v1.22.8;v1.22.7;v1.22.6;v1.22.5;v1.21.6;v1.32.0-alpha.8dc789c5
+	// +kubebuilder:validation:Pattern=`^v\d+\.\d+\.\d+(-[0-9A-Za-z.]+)?$`
 	Version string `json:"version"`
 
 	// Namespace to which the Istio components should be installed.
@@ -41,6 +42,34 @@ type IstioRevisionSpec struct {
 	// Defines the values to be passed to the Helm charts when installing Istio.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Helm Values"
 	Values *Values `json:"values,omitempty"`
+
+	// Defines how the operator handles changes made directly to the objects it deployed.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Drift Policy"
+	DriftPolicy *DriftPolicy `json:"driftPolicy,omitempty"`
+
+	// Defines patches that are applied to the objects rendered from the Helm charts, in order. Use them
+	// to change settings that aren't exposed through the Helm values.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Patches"
+	Patches []Patch `json:"patches,omitempty"`
+
+	// Defines the IstioCNI and ZTunnel resources that the revision depends on. If not set, it depends on
+	// the resources named `default`.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Dependencies"
+	// +optional
+	Dependencies *Dependencies `json:"dependencies,omitempty"`
+}
+
+// Dependencies references the IstioCNI and ZTunnel resources that a revision depends on.
+type Dependencies struct {
+	// Name of the IstioCNI resource that the revision depends on if it uses the Istio CNI plugin.
+	// Defaults to `default`.
+	// +optional
+	IstioCNI string `json:"istioCNI,omitempty"`
+
+	// Name of the ZTunnel resource that the revision depends on if it uses the ambient data plane.
+	// Defaults to `default`.
+	// +optional
+	ZTunnel string `json:"ztunnel,omitempty"`
 }
 
 // IstioRevisionStatus defines the observed state of IstioRevision
@@ -56,6 +85,81 @@ type IstioRevisionStatus struct {
 
 	// Reports the current state of the object.
 	State IstioRevisionConditionReason `json:"state,omitempty"`
+
+	// Reports the changes that the operator would make to the cluster if the
+	// sailoperator.io/dry-run annotation was removed from the object. Only set
+	// while the annotation is present.
+	// +optional
+	Plan *IstioRevisionPlan `json:"plan,omitempty"`
+
+	// Reports the readiness of each object deployed for this resource.
+	// +optional
+	Resources []ResourceStatus `json:"resources,omitempty"`
+
+	// Reports the revisions of the Helm release of this resource that are retained
+	// by the operator, newest first. A retained revision can be reinstalled by setting
+	// the sailoperator.io/rollback-to annotation to its number.
+	// +optional
+	ReleaseHistory []HelmReleaseRevision `json:"releaseHistory,omitempty"`
+
+	// Reports the namespaces and pods that reference this revision.
+	// +optional
+	Workloads *WorkloadInventory `json:"workloads,omitempty"`
+}
+
+// WorkloadInventory summarizes the namespaces and pods that reference an
+// IstioRevision or IstioRevisionTag.
+type WorkloadInventory struct {
+	// Number of namespaces whose istio.io/rev or istio-injection label references
+	// the revision or tag.
+	Namespaces int32 `json:"namespaces"`
+
+	// Names of the first namespaces counted in namespaces, in alphabetical order.
+	// At most 10 names are listed.
+	// +optional
+	NamespaceNames []string `json:"namespaceNames,omitempty"`
+
+	// Number of pods that were injected by the revision. For an IstioRevisionTag,
+	// only the pods injected by the tag's revision that reference the tag are counted.
+	InjectedPods int32 `json:"injectedPods"`
+
+	// Number of pods that reference the revision or tag through their labels or
+	// the labels of their namespace, but that were injected by a different revision.
+	// These pods must be restarted to move them to the revision.
+	PendingRestartPods int32 `json:"pendingRestartPods"`
+}
+
+// IstioRevisionPlan describes how installing the Helm charts of an Istio or IstioRevision
+// would change the objects in the cluster.
+type IstioRevisionPlan struct {
+	// Objects that would be created.
+	// +optional
+	Added []PlannedObject `json:"added,omitempty"`
+
+	// Objects that would be updated.
+	// +optional
+	Changed []PlannedObject `json:"changed,omitempty"`
+
+	// Objects that would be deleted.
+	// +optional
+	Removed []PlannedObject `json:"removed,omitempty"`
+}
+
+// PlannedObject identifies an object in an IstioRevisionPlan.
+type PlannedObject struct {
+	// APIVersion of the object.
+	APIVersion string `json:"apiVersion"`
+
+	// Kind of the object.
+	Kind string `json:"kind"`
+
+	// Namespace of the object. Empty for cluster-scoped objects and for objects
+	// that are created in the namespace of the Helm release.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the object.
+	Name string `json:"name"`
 }
 
 // GetCondition returns the condition of the specified type
@@ -84,6 +188,18 @@ const (
 
 	// IstioRevisionReasonReconcileError indicates that the reconciliation of the resource has failed, but will be retried.
 	IstioRevisionReasonReconcileError IstioRevisionConditionReason = "ReconcileError"
+
+	// IstioRevisionReasonInvalidPatch indicates that one of the patches in spec.patches is invalid or can't be applied
+	// to the rendered objects. The reconciliation isn't retried until the resource is updated.
+	IstioRevisionReasonInvalidPatch IstioRevisionConditionReason = "InvalidPatch"
+
+	// IstioRevisionReasonDryRun indicates that the sailoperator.io/dry-run annotation is set, so the operator
+	// only computed the changes it would make to the cluster and reported them in status.plan.
+	IstioRevisionReasonDryRun IstioRevisionConditionReason = "DryRun"
+
+	// IstioRevisionReasonRolledBack indicates that the sailoperator.io/rollback-to annotation is set, so the
+	// operator reinstalled a previous revision of the Helm release instead of the one defined in the spec.
+	IstioRevisionReasonRolledBack IstioRevisionConditionReason = "RolledBack"
 )
 
 const (
@@ -102,6 +218,10 @@ const (
 
 	// IstioRevisionReasonReadinessCheckFailed indicates that istiod readiness status could not be ascertained.
 	IstioRevisionReasonReadinessCheckFailed IstioRevisionConditionReason = "ReadinessCheckFailed"
+
+	// IstioRevisionReasonResourcesNotReady indicates that the istiod Deployment has pods running, but some of the objects
+	// deployed for the resource are missing or still being rolled out. See status.resources for details.
+	IstioRevisionReasonResourcesNotReady IstioRevisionConditionReason = "ResourcesNotReady"
 )
 
 const (
@@ -141,6 +261,39 @@ const (
 	IstioRevisionDependencyCheckFailed IstioRevisionConditionReason = "DependencyCheckFailed"
 )
 
+const (
+	// IstioRevisionConditionDriftDetected signifies whether someone other than the operator changed the objects
+	// deployed for the IstioRevision.
+	IstioRevisionConditionDriftDetected IstioRevisionConditionType = "DriftDetected"
+
+	// IstioRevisionReasonDriftReverted indicates that drifted fields were found and reverted to the values in the
+	// Helm release manifest.
+	IstioRevisionReasonDriftReverted IstioRevisionConditionReason = "DriftReverted"
+
+	// IstioRevisionReasonDriftReported indicates that drifted fields were found, but kept as configured in spec.driftPolicy.
+	IstioRevisionReasonDriftReported IstioRevisionConditionReason = "DriftReported"
+
+	// IstioRevisionReasonNoDrift indicates that the deployed objects match the Helm release manifest.
+	IstioRevisionReasonNoDrift IstioRevisionConditionReason = "NoDrift"
+
+	// IstioRevisionReasonDriftCheckFailed indicates that the deployed objects could not be compared with the Helm release manifest.
+	IstioRevisionReasonDriftCheckFailed IstioRevisionConditionReason = "DriftCheckFailed"
+)
+
+const (
+	// IstioRevisionConditionFieldConflicts signifies whether the operator skipped fields of the objects deployed for the
+	// IstioRevision, because other field managers own them. It's only set when the operator uses server-side apply.
+	IstioRevisionConditionFieldConflicts IstioRevisionConditionType = "FieldConflicts"
+
+	// IstioRevisionReasonConflictsSkipped indicates that some fields in the Helm release manifest are owned by other
+	// field managers (e.g. spec.replicas of a Deployment scaled by a HorizontalPodAutoscaler), so they were
+	// left as set by those managers instead of being overwritten.
+	IstioRevisionReasonConflictsSkipped IstioRevisionConditionReason = "ConflictsSkipped"
+
+	// IstioRevisionReasonNoConflicts indicates that all fields in the Helm release manifest were applied.
+	IstioRevisionReasonNoConflicts IstioRevisionConditionReason = "NoConflicts"
+)
+
 const (
 	// IstioRevisionReasonHealthy indicates that the control plane is fully reconciled and that all components are ready.
 	IstioRevisionReasonHealthy IstioRevisionConditionReason = "Healthy"
@@ -64,6 +64,10 @@ type IstioRevisionTagStatus struct {
 
 	// IstioRevision stores the name of the referenced IstioRevision
 	IstioRevision string `json:"istioRevision"`
+
+	// Reports the namespaces and pods that reference this tag.
+	// +optional
+	Workloads *WorkloadInventory `json:"workloads,omitempty"`
 }
 
 // GetCondition returns the condition of the specified type
@@ -0,0 +1,58 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+// PatchType defines the format of a Patch.
+// +kubebuilder:validation:Enum=StrategicMerge;JSON6902
+type PatchType string
+
+const (
+	// PatchTypeStrategicMerge patches are merged into the target objects using the strategic merge patch
+	// rules of the object's kind. A JSON merge patch (RFC 7386) is applied to objects of custom kinds.
+	PatchTypeStrategicMerge PatchType = "StrategicMerge"
+
+	// PatchTypeJSON6902 patches are lists of JSON patch (RFC 6902) operations.
+	PatchTypeJSON6902 PatchType = "JSON6902"
+)
+
+// Patch modifies the objects rendered from the Helm charts before they are applied to the cluster.
+// Patches are meant for settings that aren't exposed through the Helm values.
+type Patch struct {
+	// Selects the objects to which the patch is applied.
+	// +kubebuilder:validation:Required
+	Target PatchTarget `json:"target"`
+
+	// The format of the patch.
+	// +kubebuilder:default=StrategicMerge
+	Type PatchType `json:"type,omitempty"`
+
+	// The patch in YAML or JSON format. A StrategicMerge patch is a partial object, e.g.
+	// `{"metadata": {"annotations": {"foo": "bar"}}}`, while a JSON6902 patch is a list of operations, e.g.
+	// `[{"op": "add", "path": "/metadata/annotations/foo", "value": "bar"}]`.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Patch string `json:"patch"`
+}
+
+// PatchTarget selects the objects to which a Patch is applied.
+type PatchTarget struct {
+	// The kind of the objects, e.g. DaemonSet.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Kind string `json:"kind"`
+
+	// The name of the object. If not set, the patch is applied to all objects of the given kind.
+	Name string `json:"name,omitempty"`
+}
@@ -0,0 +1,87 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ReadinessStatus is the readiness of an object deployed by the operator. The values are modeled after
+// the statuses computed by kstatus.
+// +kubebuilder:validation:Enum=Current;InProgress;Failed;NotFound;Unknown
+type ReadinessStatus string
+
+const (
+	// ReadinessStatusCurrent means that the object is fully rolled out and ready.
+	ReadinessStatusCurrent ReadinessStatus = "Current"
+
+	// ReadinessStatusInProgress means that the object is being rolled out or isn't ready yet.
+	ReadinessStatusInProgress ReadinessStatus = "InProgress"
+
+	// ReadinessStatusFailed means that the rollout of the object failed and won't complete without intervention.
+	ReadinessStatusFailed ReadinessStatus = "Failed"
+
+	// ReadinessStatusNotFound means that the object doesn't exist in the cluster.
+	ReadinessStatusNotFound ReadinessStatus = "NotFound"
+
+	// ReadinessStatusUnknown means that the readiness of the object couldn't be determined.
+	ReadinessStatusUnknown ReadinessStatus = "Unknown"
+)
+
+// ResourceStatus reports the readiness of an object deployed by the operator.
+type ResourceStatus struct {
+	// APIVersion of the object.
+	APIVersion string `json:"apiVersion"`
+
+	// Kind of the object.
+	Kind string `json:"kind"`
+
+	// Namespace of the object. Empty for cluster-scoped objects.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the object.
+	Name string `json:"name"`
+
+	// Readiness of the object.
+	Status ReadinessStatus `json:"status"`
+
+	// Human-readable details about the readiness of the object, such as the rollout progress
+	// (e.g. "updatedReplicas 2/3").
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// HelmReleaseRevision describes a revision of a Helm release deployed by the operator.
+type HelmReleaseRevision struct {
+	// Number of the revision. It's incremented on every install, upgrade and rollback.
+	Revision int32 `json:"revision"`
+
+	// Version of the chart deployed in this revision.
+	// +optional
+	ChartVersion string `json:"chartVersion,omitempty"`
+
+	// Time when this revision was deployed.
+	// +optional
+	Updated metav1.Time `json:"updated,omitempty"`
+
+	// Helm status of the revision (e.g. deployed, superseded, failed).
+	// +optional
+	Status string `json:"status,omitempty"`
+
+	// Helm description of the revision (e.g. "Upgrade complete" or "Rollback to 2").
+	// +optional
+	Description string `json:"description,omitempty"`
+}
@@ -19,7 +19,8 @@ import (
 )
 
 const (
-	ZTunnelKind = "ZTunnel"
+	ZTunnelKind        = "ZTunnel"
+	DefaultZTunnelName = "default"
 )
 
 // ZTunnelSpec defines the desired state of ZTunnel
@@ -27,8 +28,9 @@ type ZTunnelSpec struct {
 	// +sail:version
 	// Defines the version of Istio to install.
 	// Must be one of: v1.31-latest, v1.31.0-beta.1, v1.30-latest, v1.30.3, v1.30.2, v1.30.1, v1.30.0, v1.29-latest, v1.29.6, v1.29.5, v1.29.4, v1.29.3, v1.29.2, v1.29.1, v1.29.0, master, v1.32.0-alpha.8dc789c5.
+	// Versions provided by an IstioChartSource can also be used.
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,order=1,displayName="Istio Version",xDescriptors={"urn:alm:descriptor:com.tectonic.ui:fieldGroup:General", "urn:alm:descriptor:com.tectonic.ui:select:v1.31-latest", "urn:alm:descriptor:com.tectonic.ui:select:v1.31.0-beta.1", "urn:alm:descriptor:com.tectonic.ui:select:v1.30-latest", "urn:alm:descriptor:com.tectonic.ui:select:v1.30.3", "urn:alm:descriptor:com.tectonic.ui:select:v1.30.2", "urn:alm:descriptor:com.tectonic.ui:select:v1.30.1", "urn:alm:descriptor:com.tectonic.ui:select:v1.30.0", "urn:alm:descriptor:com.tectonic.ui:select:v1.29-latest", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.6", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.5", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.4", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.3", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.2", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.1", "urn:alm:descriptor:com.tectonic.ui:select:v1.29.0", "urn:alm:descriptor:com.tectonic.ui:select:master", "urn:alm:descriptor:com.tectonic.ui:select:v1.32.0-alpha.8dc789c5"}
-	// +kubebuilder:validation:Enum=v1.31-latest;v1.31.0-beta.1;v1.30-latest;v1.30.3;v1.30.2;v1.30.1;v1.30.0;v1.29-latest;v1.29.6;v1.29.5;v1.29.4;v1.29.3;v1.29.2;v1.29.1;v1.29.0;v1.28-latest;v1.28.10;v1.28.9;v1.28.8;v1.28.7;v1.28.6;v1.28.5;v1.28.4;v1.28.3;v1.28.2;v1.28.1;v1.28.0;v1.27-latest;v1.27.9;v1.27.8;v1.27.7;v1.27.6;v1.27.5;v1.27.4;v1.27.3;v1.27.2;v1.27.1;v1.27.0;v1.26-latest;v1.26.8;v1.26.7;v1.26.6;v1.26.5;v1.26.4;v1.26.3;v1.26.2;v1.26.1;v1.26.0;v1.25-latest;v1.25.5;v1.25.4;v1.25.3;v1.25.2;v1.25.1;v1.24-latest;v1.24.6;v1.24.5;v1.24.4;v1.24.3;v1.24.2;v1.24.1;v1.24.0;master;v1.32.0-alpha.8dc789c5
+	// +kubebuilder:validation:Pattern=`^(master|v\d+\.\d+(\.\d+)?(-[0-9A-Za-z.]+)?)$`
 	// +kubebuilder:default=v1.31.0-beta.1
 	Version string `json:"version"`
 
@@ -41,9 +43,25 @@ type ZTunnelSpec struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Helm Values"
 	Values *ZTunnelValues `json:"values,omitempty"`
 
+	// Restricts the ztunnel DaemonSet to the nodes that have all of the given labels, in addition to the node
+	// selector of the Helm chart. Several ZTunnel resources can be installed in different namespaces if their
+	// node selectors select disjoint sets of nodes.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Node Selector"
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
 	// The Istio control plane that this ZTunnel instance is associated with. Valid references are Istio and IstioRevision resources, Istio resources are always resolved to their current active revision.
 	// Values relevant for ZTunnel will be copied from the referenced IstioRevision resource, these are `spec.values.global`, `spec.values.meshConfig`, `spec.values.revision`. Any user configuration in the ZTunnel spec will always take precedence over the settings copied from the Istio resource, however.
 	TargetRef *TargetReference `json:"targetRef,omitempty"`
+
+	// Defines how the operator handles changes made directly to the objects it deployed.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Drift Policy"
+	DriftPolicy *DriftPolicy `json:"driftPolicy,omitempty"`
+
+	// Defines patches that are applied to the objects rendered from the Helm charts, in order. Use them
+	// to change settings that aren't exposed through the Helm values.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Patches"
+	Patches []Patch `json:"patches,omitempty"`
 }
 
 // ZTunnelStatus defines the observed state of ZTunnel
@@ -60,8 +78,22 @@ type ZTunnelStatus struct {
 	// Reports the current state of the object.
 	State ZTunnelConditionReason `json:"state,omitempty"`
 
+	// The version that spec.version resolves to (e.g. the patch version that an alias like `v1.30-latest`
+	// refers to).
+	ResolvedVersion string `json:"resolvedVersion,omitempty"`
+
 	// IstioRevision stores the name of the referenced IstioRevision
 	IstioRevision string `json:"istioRevision,omitempty"`
+
+	// Reports the readiness of each object deployed for this resource.
+	// +optional
+	Resources []ResourceStatus `json:"resources,omitempty"`
+
+	// Reports the revisions of the Helm release of this resource that are retained
+	// by the operator, newest first. A retained revision can be reinstalled by setting
+	// the sailoperator.io/rollback-to annotation to its number.
+	// +optional
+	ReleaseHistory []HelmReleaseRevision `json:"releaseHistory,omitempty"`
 }
 
 // GetCondition returns the condition of the specified type
@@ -90,6 +122,14 @@ const (
 
 	// ZTunnelReasonReconcileError indicates that the reconciliation of the resource has failed, but will be retried.
 	ZTunnelReasonReconcileError ZTunnelConditionReason = "ReconcileError"
+
+	// ZTunnelReasonInvalidPatch indicates that one of the patches in spec.patches is invalid or can't be applied
+	// to the rendered objects. The reconciliation isn't retried until the resource is updated.
+	ZTunnelReasonInvalidPatch ZTunnelConditionReason = "InvalidPatch"
+
+	// ZTunnelReasonRolledBack indicates that the sailoperator.io/rollback-to annotation is set, so the
+	// operator reinstalled a previous revision of the Helm release instead of the one defined in the spec.
+	ZTunnelReasonRolledBack ZTunnelConditionReason = "RolledBack"
 )
 
 const (
@@ -101,6 +141,43 @@ const (
 
 	// ZTunnelReasonReadinessCheckFailed indicates that the DaemonSet readiness status could not be ascertained.
 	ZTunnelReasonReadinessCheckFailed ZTunnelConditionReason = "ReadinessCheckFailed"
+
+	// ZTunnelReasonResourcesNotReady indicates that the ztunnel DaemonSet has pods running, but some of the objects
+	// deployed for the resource are missing or still being rolled out. See status.resources for details.
+	ZTunnelReasonResourcesNotReady ZTunnelConditionReason = "ResourcesNotReady"
+)
+
+const (
+	// ZTunnelConditionDriftDetected signifies whether someone other than the operator changed the objects
+	// deployed for the ZTunnel.
+	ZTunnelConditionDriftDetected ZTunnelConditionType = "DriftDetected"
+
+	// ZTunnelReasonDriftReverted indicates that drifted fields were found and reverted to the values in the
+	// Helm release manifest.
+	ZTunnelReasonDriftReverted ZTunnelConditionReason = "DriftReverted"
+
+	// ZTunnelReasonDriftReported indicates that drifted fields were found, but kept as configured in spec.driftPolicy.
+	ZTunnelReasonDriftReported ZTunnelConditionReason = "DriftReported"
+
+	// ZTunnelReasonNoDrift indicates that the deployed objects match the Helm release manifest.
+	ZTunnelReasonNoDrift ZTunnelConditionReason = "NoDrift"
+
+	// ZTunnelReasonDriftCheckFailed indicates that the deployed objects could not be compared with the Helm release manifest.
+	ZTunnelReasonDriftCheckFailed ZTunnelConditionReason = "DriftCheckFailed"
+)
+
+const (
+	// ZTunnelConditionFieldConflicts signifies whether the operator skipped fields of the objects deployed for the
+	// ZTunnel, because other field managers own them. It's only set when the operator uses server-side apply.
+	ZTunnelConditionFieldConflicts ZTunnelConditionType = "FieldConflicts"
+
+	// ZTunnelReasonConflictsSkipped indicates that some fields in the Helm release manifest are owned by other
+	// field managers (e.g. spec.replicas of a Deployment scaled by a HorizontalPodAutoscaler), so they were
+	// left as set by those managers instead of being overwritten.
+	ZTunnelReasonConflictsSkipped ZTunnelConditionReason = "ConflictsSkipped"
+
+	// ZTunnelReasonNoConflicts indicates that all fields in the Helm release manifest were applied.
+	ZTunnelReasonNoConflicts ZTunnelConditionReason = "NoConflicts"
 )
 
 const (
@@ -118,7 +195,6 @@ const (
 // +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".spec.version",description="The version of the Istio ztunnel installation."
 // +kubebuilder:printcolumn:name="Revision",type="string",JSONPath=".status.istioRevision",description="The referenced IstioRevision."
 // +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="The age of the object"
-// +kubebuilder:validation:XValidation:rule="self.metadata.name == 'default'",message="metadata.name must be 'default'"
 
 // ZTunnel represents a deployment of the Istio ztunnel component.
 type ZTunnel struct {
@@ -836,6 +836,56 @@ func (in *DefaultPodDisruptionBudgetConfig) DeepCopy() *DefaultPodDisruptionBudg
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Dependencies) DeepCopyInto(out *Dependencies) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Dependencies.
+func (in *Dependencies) DeepCopy() *Dependencies {
+	if in == nil {
+		return nil
+	}
+	out := new(Dependencies)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftPolicy) DeepCopyInto(out *DriftPolicy) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]DriftRule, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftPolicy.
+func (in *DriftPolicy) DeepCopy() *DriftPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DriftRule) DeepCopyInto(out *DriftRule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DriftRule.
+func (in *DriftRule) DeepCopy() *DriftRule {
+	if in == nil {
+		return nil
+	}
+	out := new(DriftRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ExperimentalConfig) DeepCopyInto(out *ExperimentalConfig) {
 	*out = *in
@@ -1182,6 +1232,22 @@ func (in *HTTPRetry) DeepCopy() *HTTPRetry {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HelmReleaseRevision) DeepCopyInto(out *HelmReleaseRevision) {
+	*out = *in
+	in.Updated.DeepCopyInto(&out.Updated)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HelmReleaseRevision.
+func (in *HelmReleaseRevision) DeepCopy() *HelmReleaseRevision {
+	if in == nil {
+		return nil
+	}
+	out := new(HelmReleaseRevision)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Istio) DeepCopyInto(out *Istio) {
 	*out = *in
@@ -1276,6 +1342,23 @@ func (in *IstioCNISpec) DeepCopyInto(out *IstioCNISpec) {
 		*out = new(CNIValues)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.DriftPolicy != nil {
+		in, out := &in.DriftPolicy, &out.DriftPolicy
+		*out = new(DriftPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Patches != nil {
+		in, out := &in.Patches, &out.Patches
+		*out = make([]Patch, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioCNISpec.
@@ -1298,6 +1381,18 @@ func (in *IstioCNIStatus) DeepCopyInto(out *IstioCNIStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]ResourceStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReleaseHistory != nil {
+		in, out := &in.ReleaseHistory, &out.ReleaseHistory
+		*out = make([]HelmReleaseRevision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioCNIStatus.
@@ -1401,6 +1496,36 @@ func (in *IstioRevisionList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IstioRevisionPlan) DeepCopyInto(out *IstioRevisionPlan) {
+	*out = *in
+	if in.Added != nil {
+		in, out := &in.Added, &out.Added
+		*out = make([]PlannedObject, len(*in))
+		copy(*out, *in)
+	}
+	if in.Changed != nil {
+		in, out := &in.Changed, &out.Changed
+		*out = make([]PlannedObject, len(*in))
+		copy(*out, *in)
+	}
+	if in.Removed != nil {
+		in, out := &in.Removed, &out.Removed
+		*out = make([]PlannedObject, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioRevisionPlan.
+func (in *IstioRevisionPlan) DeepCopy() *IstioRevisionPlan {
+	if in == nil {
+		return nil
+	}
+	out := new(IstioRevisionPlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *IstioRevisionSpec) DeepCopyInto(out *IstioRevisionSpec) {
 	*out = *in
@@ -1409,6 +1534,21 @@ func (in *IstioRevisionSpec) DeepCopyInto(out *IstioRevisionSpec) {
 		*out = new(Values)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.DriftPolicy != nil {
+		in, out := &in.DriftPolicy, &out.DriftPolicy
+		*out = new(DriftPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Patches != nil {
+		in, out := &in.Patches, &out.Patches
+		*out = make([]Patch, len(*in))
+		copy(*out, *in)
+	}
+	if in.Dependencies != nil {
+		in, out := &in.Dependencies, &out.Dependencies
+		*out = new(Dependencies)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioRevisionSpec.
@@ -1431,6 +1571,28 @@ func (in *IstioRevisionStatus) DeepCopyInto(out *IstioRevisionStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = new(IstioRevisionPlan)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]ResourceStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReleaseHistory != nil {
+		in, out := &in.ReleaseHistory, &out.ReleaseHistory
+		*out = make([]HelmReleaseRevision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Workloads != nil {
+		in, out := &in.Workloads, &out.Workloads
+		*out = new(WorkloadInventory)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioRevisionStatus.
@@ -1528,6 +1690,11 @@ func (in *IstioRevisionTagStatus) DeepCopyInto(out *IstioRevisionTagStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Workloads != nil {
+		in, out := &in.Workloads, &out.Workloads
+		*out = new(WorkloadInventory)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioRevisionTagStatus.
@@ -1553,6 +1720,21 @@ func (in *IstioSpec) DeepCopyInto(out *IstioSpec) {
 		*out = new(Values)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.DriftPolicy != nil {
+		in, out := &in.DriftPolicy, &out.DriftPolicy
+		*out = new(DriftPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Patches != nil {
+		in, out := &in.Patches, &out.Patches
+		*out = make([]Patch, len(*in))
+		copy(*out, *in)
+	}
+	if in.Dependencies != nil {
+		in, out := &in.Dependencies, &out.Dependencies
+		*out = new(Dependencies)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioSpec.
@@ -1576,6 +1758,16 @@ func (in *IstioStatus) DeepCopyInto(out *IstioStatus) {
 		}
 	}
 	out.Revisions = in.Revisions
+	if in.Promotion != nil {
+		in, out := &in.Promotion, &out.Promotion
+		*out = new(RevisionPromotionStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = new(IstioRevisionPlan)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioStatus.
@@ -1596,6 +1788,11 @@ func (in *IstioUpdateStrategy) DeepCopyInto(out *IstioUpdateStrategy) {
 		*out = new(int64)
 		**out = **in
 	}
+	if in.Promotion != nil {
+		in, out := &in.Promotion, &out.Promotion
+		*out = new(RevisionPromotion)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IstioUpdateStrategy.
@@ -3832,6 +4029,37 @@ func (in *OutlierDetection) DeepCopy() *OutlierDetection {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Patch) DeepCopyInto(out *Patch) {
+	*out = *in
+	out.Target = in.Target
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Patch.
+func (in *Patch) DeepCopy() *Patch {
+	if in == nil {
+		return nil
+	}
+	out := new(Patch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PatchTarget) DeepCopyInto(out *PatchTarget) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PatchTarget.
+func (in *PatchTarget) DeepCopy() *PatchTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(PatchTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PeerCaCrlConfig) DeepCopyInto(out *PeerCaCrlConfig) {
 	*out = *in
@@ -4146,6 +4374,21 @@ func (in *PilotTaintControllerConfig) DeepCopy() *PilotTaintControllerConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlannedObject) DeepCopyInto(out *PlannedObject) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlannedObject.
+func (in *PlannedObject) DeepCopy() *PlannedObject {
+	if in == nil {
+		return nil
+	}
+	out := new(PlannedObject)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PolicyTargetReference) DeepCopyInto(out *PolicyTargetReference) {
 	*out = *in
@@ -4931,6 +5174,21 @@ func (in *ResourceQuotas) DeepCopy() *ResourceQuotas {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceStatus) DeepCopyInto(out *ResourceStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceStatus.
+func (in *ResourceStatus) DeepCopy() *ResourceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourcesRequestsConfig) DeepCopyInto(out *ResourcesRequestsConfig) {
 	*out = *in
@@ -4956,6 +5214,54 @@ func (in *ResourcesRequestsConfig) DeepCopy() *ResourcesRequestsConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RevisionPromotion) DeepCopyInto(out *RevisionPromotion) {
+	*out = *in
+	if in.CanaryNamespaceSelector != nil {
+		in, out := &in.CanaryNamespaceSelector, &out.CanaryNamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SoakTimeSeconds != nil {
+		in, out := &in.SoakTimeSeconds, &out.SoakTimeSeconds
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RevisionPromotion.
+func (in *RevisionPromotion) DeepCopy() *RevisionPromotion {
+	if in == nil {
+		return nil
+	}
+	out := new(RevisionPromotion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RevisionPromotionStatus) DeepCopyInto(out *RevisionPromotionStatus) {
+	*out = *in
+	if in.HealthySince != nil {
+		in, out := &in.HealthySince, &out.HealthySince
+		*out = (*in).DeepCopy()
+	}
+	if in.PromotionTime != nil {
+		in, out := &in.PromotionTime, &out.PromotionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RevisionPromotionStatus.
+func (in *RevisionPromotionStatus) DeepCopy() *RevisionPromotionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RevisionPromotionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RevisionSummary) DeepCopyInto(out *RevisionSummary) {
 	*out = *in
@@ -6005,6 +6311,26 @@ func (in *WaypointConfig) DeepCopy() *WaypointConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadInventory) DeepCopyInto(out *WorkloadInventory) {
+	*out = *in
+	if in.NamespaceNames != nil {
+		in, out := &in.NamespaceNames, &out.NamespaceNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadInventory.
+func (in *WorkloadInventory) DeepCopy() *WorkloadInventory {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadInventory)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkloadSelector) DeepCopyInto(out *WorkloadSelector) {
 	*out = *in
@@ -6352,11 +6678,28 @@ func (in *ZTunnelSpec) DeepCopyInto(out *ZTunnelSpec) {
 		*out = new(ZTunnelValues)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	if in.TargetRef != nil {
 		in, out := &in.TargetRef, &out.TargetRef
 		*out = new(TargetReference)
 		**out = **in
 	}
+	if in.DriftPolicy != nil {
+		in, out := &in.DriftPolicy, &out.DriftPolicy
+		*out = new(DriftPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Patches != nil {
+		in, out := &in.Patches, &out.Patches
+		*out = make([]Patch, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZTunnelSpec.
@@ -6379,6 +6722,18 @@ func (in *ZTunnelStatus) DeepCopyInto(out *ZTunnelStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]ResourceStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReleaseHistory != nil {
+		in, out := &in.ReleaseHistory, &out.ReleaseHistory
+		*out = make([]HelmReleaseRevision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZTunnelStatus.
@@ -36,6 +36,18 @@ var (
 
 func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(GroupVersion,
+		&IstioChartSource{},
+		&IstioChartSourceList{},
+		&IstioGateway{},
+		&IstioGatewayList{},
+		&IstioVersionCatalog{},
+		&IstioVersionCatalogList{},
+		&MeshCluster{},
+		&MeshClusterList{},
+		&MetricsIntegration{},
+		&MetricsIntegrationList{},
+		&TracingIntegration{},
+		&TracingIntegrationList{},
 		&ZTunnel{},
 		&ZTunnelList{},
 	)
@@ -0,0 +1,123 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// IstioTargetKind is the kind of the Istio resources that an integration can configure.
+	IstioTargetKind = "Istio"
+
+	// KialiTargetKind is the kind of the Kiali resources that an integration can configure.
+	KialiTargetKind = "Kiali"
+)
+
+// TargetReference identifies a resource that the integration configures.
+// +kubebuilder:validation:XValidation:rule="self.kind != 'Kiali' || has(self.__namespace__)",message="namespace must be set when kind is Kiali"
+type TargetReference struct {
+	// Kind specifies the kind of resource (e.g. "Istio", "Kiali").
+	// +kubebuilder:validation:Enum=Istio;Kiali
+	Kind string `json:"kind"`
+
+	// Name is the name of the target resource.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the target resource.
+	// Only required for namespace-scoped resources like Kiali.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// NamespacedReference identifies a namespaced resource that the integration references.
+type NamespacedReference struct {
+	// Name is the name of the referenced resource.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the referenced resource.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	Namespace string `json:"namespace"`
+}
+
+// IntegrationStatus defines the observed state of an integration.
+type IntegrationStatus struct {
+	// ObservedGeneration is the most recent generation observed for this
+	// integration. It corresponds to the object's generation, which is
+	// updated on mutation by the API Server. The information in the status
+	// pertains to this particular generation of the object.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Targets reports the status of the integration for each resource in spec.targetRefs.
+	Targets []TargetStatus `json:"targets,omitempty"`
+}
+
+// TargetStatus reports the status of the integration for a single target resource.
+type TargetStatus struct {
+	// Kind is the kind of the target resource.
+	Kind string `json:"kind"`
+
+	// Name is the name of the target resource.
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the target resource.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Represents the latest available observations of the integration's state for this target.
+	Conditions []v1.StatusCondition `json:"conditions,omitempty"`
+}
+
+// GetCondition returns the condition of the specified type
+func (s *TargetStatus) GetCondition(conditionType IntegrationConditionType) v1.StatusCondition {
+	if s != nil {
+		return v1.GetCondition(s.Conditions, v1.ConditionType(conditionType))
+	}
+	return v1.StatusCondition{Type: v1.ConditionType(conditionType), Status: metav1.ConditionUnknown}
+}
+
+// SetCondition sets a specific condition in the list of conditions
+func (s *TargetStatus) SetCondition(condition v1.StatusCondition) {
+	v1.SetCondition(&s.Conditions, condition)
+}
+
+// IntegrationConditionType represents the type of an integration condition.
+type IntegrationConditionType string
+
+// IntegrationConditionReason represents the reason for an integration condition.
+type IntegrationConditionReason string
+
+const (
+	// IntegrationConditionAccepted signifies whether the integration has been accepted
+	// for the target resource and is applied to it.
+	IntegrationConditionAccepted IntegrationConditionType = "Accepted"
+
+	// IntegrationReasonTargetNotFound indicates that the target resource does not exist.
+	IntegrationReasonTargetNotFound IntegrationConditionReason = "TargetNotFound"
+
+	// IntegrationReasonUnsupportedTarget indicates that the operator can't configure the target resource yet.
+	IntegrationReasonUnsupportedTarget IntegrationConditionReason = "UnsupportedTarget"
+
+	// IntegrationReasonConflict indicates that the target is already configured by an older integration of the same kind.
+	IntegrationReasonConflict IntegrationConditionReason = "Conflict"
+
+	// IntegrationReasonResolutionFailed indicates that the target resource could not be resolved.
+	IntegrationReasonResolutionFailed IntegrationConditionReason = "ResolutionFailed"
+)
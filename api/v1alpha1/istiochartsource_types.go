@@ -0,0 +1,205 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	IstioChartSourceKind = "IstioChartSource"
+)
+
+// ChartSourceType identifies the type of repository that a chart source fetches the charts from.
+// +kubebuilder:validation:Enum=OCI;HTTP
+type ChartSourceType string
+
+const (
+	// ChartSourceTypeOCI fetches the charts from an OCI registry, where they are stored as OCI artifacts
+	// (e.g. pushed with `helm push`).
+	ChartSourceTypeOCI ChartSourceType = "OCI"
+
+	// ChartSourceTypeHTTP fetches the chart archives from a Helm chart repository served over HTTP(S).
+	ChartSourceTypeHTTP ChartSourceType = "HTTP"
+)
+
+// IstioChartSourceSpec defines the desired state of IstioChartSource
+// +kubebuilder:validation:XValidation:rule="self.type != 'OCI' || self.url.startsWith('oci://')",message="url must start with oci:// when type is OCI"
+// +kubebuilder:validation:XValidation:rule="self.type != 'HTTP' || self.url.startsWith('https://') || self.url.startsWith('http://')",message="url must start with https:// or http:// when type is HTTP"
+type IstioChartSourceSpec struct {
+	// Type specifies the type of the repository.
+	Type ChartSourceType `json:"type"`
+
+	// URL of the repository. For OCI registries, this is the reference of the repository that contains
+	// the charts (e.g. `oci://quay.io/example/istio-charts`); a chart is pulled from `<url>/<chart>:<version>`.
+	// For HTTP repositories, this is the URL of the chart repository; a chart is downloaded from
+	// `<url>/<chart>-<version>.tgz`.
+	// +kubebuilder:validation:MinLength=1
+	URL string `json:"url"`
+
+	// CredentialsSecretRef references a Secret in the operator namespace that contains the `username`
+	// and `password` used to authenticate with the repository.
+	// +optional
+	CredentialsSecretRef *LocalObjectReference `json:"credentialsSecretRef,omitempty"`
+
+	// PlainHTTP specifies whether the OCI registry is accessed over plain HTTP instead of HTTPS.
+	// +optional
+	PlainHTTP bool `json:"plainHTTP,omitempty"`
+
+	// Versions lists the Istio versions that the operator fetches from the repository. Once fetched and
+	// verified, they can be used in the `spec.version` field of the Istio, IstioCNI and ZTunnel resources.
+	// +kubebuilder:validation:MinItems=1
+	// +listType=map
+	// +listMapKey=name
+	Versions []ChartSourceVersion `json:"versions"`
+}
+
+// LocalObjectReference references an object in the operator namespace.
+type LocalObjectReference struct {
+	// Name of the referenced object.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	Name string `json:"name"`
+}
+
+// ChartSourceVersion describes an Istio version provided by a chart source.
+type ChartSourceVersion struct {
+	// Name of the version, as used in the `spec.version` field (e.g. `v1.30.4`). It must not be one of the
+	// versions embedded in the operator.
+	// +kubebuilder:validation:Pattern=`^v\d+\.\d+\.\d+(-[0-9A-Za-z.]+)?$`
+	Name string `json:"name"`
+
+	// Charts lists the charts of this version and the digests of their archives. The charts must be prepared
+	// for the operator in the same way as the embedded charts, and must include the `base`, `istiod` and
+	// `revisiontags` charts. Add the `cni` and `ztunnel` charts to use the version in IstioCNI and ZTunnel
+	// resources.
+	// +kubebuilder:validation:MinItems=1
+	// +listType=map
+	// +listMapKey=name
+	Charts []ChartArtifact `json:"charts"`
+
+	// Profiles references the archive that contains the profiles of this version. The archive is fetched like
+	// a chart named `profiles` and must contain a `profiles` directory. If not set, the profiles are taken
+	// from the version specified in `profilesFrom`.
+	// +optional
+	Profiles *ArtifactDigest `json:"profiles,omitempty"`
+
+	// ProfilesFrom specifies the embedded version whose profiles are used for this version when `profiles`
+	// is not set. Defaults to the newest embedded version with the same major and minor version.
+	// +optional
+	ProfilesFrom string `json:"profilesFrom,omitempty"`
+}
+
+// ChartArtifact identifies a chart archive and its digest.
+type ChartArtifact struct {
+	// Name of the chart.
+	// +kubebuilder:validation:Enum=base;istiod;revisiontags;gateway;cni;ztunnel
+	Name string `json:"name"`
+
+	// Version of the chart in the repository. Defaults to the name of the Istio version without the `v` prefix.
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	ArtifactDigest `json:",inline"`
+}
+
+// ArtifactDigest specifies the digest that a fetched archive must match.
+type ArtifactDigest struct {
+	// Digest of the archive. For OCI registries, this is the digest of the layer that contains the archive.
+	// Archives that don't match the digest are rejected.
+	// +kubebuilder:validation:Pattern=`^sha256:[a-f0-9]{64}$`
+	Digest string `json:"digest"`
+}
+
+// IstioChartSourceStatus defines the observed state of IstioChartSource
+type IstioChartSourceStatus struct {
+	// ObservedGeneration is the most recent generation observed for this
+	// IstioChartSource object. It corresponds to the object's generation, which is
+	// updated on mutation by the API Server. The information in the status
+	// pertains to this particular generation of the object.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Represents the latest available observations of the object's current state.
+	Conditions []v1.StatusCondition `json:"conditions,omitempty"`
+
+	// AvailableVersions lists the versions that were fetched and verified and can be used.
+	AvailableVersions []string `json:"availableVersions,omitempty"`
+}
+
+// GetCondition returns the condition of the specified type
+func (s *IstioChartSourceStatus) GetCondition(conditionType IstioChartSourceConditionType) v1.StatusCondition {
+	if s != nil {
+		return v1.GetCondition(s.Conditions, v1.ConditionType(conditionType))
+	}
+	return v1.StatusCondition{Type: v1.ConditionType(conditionType), Status: metav1.ConditionUnknown}
+}
+
+// SetCondition sets a specific condition in the list of conditions
+func (s *IstioChartSourceStatus) SetCondition(condition v1.StatusCondition) {
+	v1.SetCondition(&s.Conditions, condition)
+}
+
+// IstioChartSourceConditionType represents the type of an IstioChartSource condition.
+type IstioChartSourceConditionType string
+
+// IstioChartSourceConditionReason represents the reason for an IstioChartSource condition.
+type IstioChartSourceConditionReason string
+
+const (
+	// IstioChartSourceConditionReady signifies whether all versions of the IstioChartSource were fetched and
+	// verified and can be used.
+	IstioChartSourceConditionReady IstioChartSourceConditionType = "Ready"
+
+	// IstioChartSourceReasonInvalidSpec indicates that the spec of the IstioChartSource is invalid, e.g.
+	// because it declares a version that is also provided by the operator or by another IstioChartSource.
+	IstioChartSourceReasonInvalidSpec IstioChartSourceConditionReason = "InvalidSpec"
+
+	// IstioChartSourceReasonFetchFailed indicates that a chart or profiles archive could not be fetched.
+	IstioChartSourceReasonFetchFailed IstioChartSourceConditionReason = "FetchFailed"
+
+	// IstioChartSourceReasonVerificationFailed indicates that a fetched archive doesn't match its digest or
+	// doesn't contain the expected files.
+	IstioChartSourceReasonVerificationFailed IstioChartSourceConditionReason = "VerificationFailed"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,categories=istio-io
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Type",type="string",JSONPath=".spec.type",description="The type of the repository."
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description="Whether all versions were fetched and verified."
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="The age of the object"
+
+// IstioChartSource declares a repository from which the operator fetches the charts and profiles of Istio
+// versions that aren't embedded in the operator.
+type IstioChartSource struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec IstioChartSourceSpec `json:"spec"`
+
+	// +optional
+	Status IstioChartSourceStatus `json:"status"`
+}
+
+// +kubebuilder:object:root=true
+
+// IstioChartSourceList contains a list of IstioChartSource
+type IstioChartSourceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []IstioChartSource `json:"items"`
+}
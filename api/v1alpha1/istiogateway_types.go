@@ -0,0 +1,316 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	k8sv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	IstioGatewayKind = "IstioGateway"
+)
+
+// IstioGatewaySpec defines the desired state of IstioGateway
+type IstioGatewaySpec struct {
+	// Defines the version of the gateway chart to install. If not set, the version of the referenced IstioRevision
+	// is used, so that the gateway is upgraded together with the control plane.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^(master|v\d+\.\d+(\.\d+)?(-[0-9A-Za-z.]+)?)$`
+	Version string `json:"version,omitempty"`
+
+	// The Istio control plane that injects the gateway proxies. Valid references are Istio and IstioRevision
+	// resources, Istio resources are always resolved to their current active revision. When the referenced
+	// IstioRevision changes, the gateway pods are restarted so that they run the proxy of the new control plane.
+	TargetRef v1.TargetReference `json:"targetRef"`
+
+	// Defines the values to be passed to the gateway Helm chart.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Helm Values"
+	// +optional
+	Values *GatewayValues `json:"values,omitempty"`
+
+	// Defines patches that are applied to the objects rendered from the Helm chart, in order. Use them
+	// to change settings that aren't exposed through the Helm values.
+	// +operator-sdk:csv:customresourcedefinitions:type=spec,displayName="Patches"
+	// +optional
+	Patches []v1.Patch `json:"patches,omitempty"`
+}
+
+// GatewayValues defines the values of the gateway Helm chart.
+type GatewayValues struct {
+	// Number of gateway replicas. Ignored when autoscaling is enabled.
+	ReplicaCount *int32 `json:"replicaCount,omitempty"`
+
+	// Configuration of the gateway Service.
+	Service *GatewayService `json:"service,omitempty"`
+
+	// Configuration of the HorizontalPodAutoscaler of the gateway Deployment.
+	Autoscaling *GatewayAutoscaling `json:"autoscaling,omitempty"`
+
+	// Configuration of the PodDisruptionBudget of the gateway Deployment. The PodDisruptionBudget is only
+	// created when more than one replica is requested.
+	PodDisruptionBudget *GatewayPodDisruptionBudget `json:"podDisruptionBudget,omitempty"`
+
+	// Configuration of the gateway ServiceAccount.
+	ServiceAccount *GatewayServiceAccount `json:"serviceAccount,omitempty"`
+
+	// K8s resources settings of the gateway container.
+	Resources *k8sv1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Environment variables to set in the gateway container.
+	Env map[string]string `json:"env,omitempty"`
+
+	// Labels to apply to the gateway pods. The `istio` label selects the gateway in Istio Gateway resources and
+	// defaults to the name of the IstioGateway without the `istio-` prefix.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Annotations to apply to all top level resources.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// Annotations to apply to the gateway pods.
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+
+	// K8s node selector settings of the gateway pods.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// K8s tolerations of the gateway pods.
+	Tolerations []k8sv1.Toleration `json:"tolerations,omitempty"`
+
+	// K8s affinity settings of the gateway pods.
+	Affinity *k8sv1.Affinity `json:"affinity,omitempty"`
+
+	// K8s topology spread constraints of the gateway pods.
+	TopologySpreadConstraints []k8sv1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// Makes the gateway an east-west gateway for the specified network. The gateway then exposes the ports used
+	// for multi-network traffic and requests the network view of that network.
+	NetworkGateway *string `json:"networkGateway,omitempty"`
+
+	// Specifies the image pull policy of the gateway container.
+	// +kubebuilder:validation:Enum=Always;Never;IfNotPresent
+	ImagePullPolicy *k8sv1.PullPolicy `json:"imagePullPolicy,omitempty"`
+
+	// Name of the PriorityClass of the gateway pods.
+	PriorityClassName *string `json:"priorityClassName,omitempty"`
+
+	// Duration in seconds the gateway pods need to terminate gracefully.
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+
+	// Additional volumes to add to the gateway pods.
+	Volumes []k8sv1.Volume `json:"volumes,omitempty"`
+
+	// Additional volumeMounts to add to the gateway container.
+	VolumeMounts []k8sv1.VolumeMount `json:"volumeMounts,omitempty"`
+}
+
+// GatewayService defines the configuration of the gateway Service.
+type GatewayService struct {
+	// Type of the Service. If set to `None`, no Service is created.
+	// +kubebuilder:validation:Enum=ClusterIP;NodePort;LoadBalancer;None
+	Type *string `json:"type,omitempty"`
+
+	// Ports exposed by the Service. Replaces the default ports `status-port` (15021), `http2` (80) and `https` (443).
+	Ports []GatewayServicePort `json:"ports,omitempty"`
+
+	// Annotations to apply to the Service.
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// IP address requested for the load balancer.
+	LoadBalancerIP *string `json:"loadBalancerIP,omitempty"`
+
+	// Client IP ranges that are allowed to access the load balancer.
+	LoadBalancerSourceRanges []string `json:"loadBalancerSourceRanges,omitempty"`
+
+	// Specifies how the Service routes external traffic to the gateway pods.
+	// +kubebuilder:validation:Enum=Cluster;Local
+	ExternalTrafficPolicy *string `json:"externalTrafficPolicy,omitempty"`
+
+	// External IP addresses of the Service.
+	ExternalIPs []string `json:"externalIPs,omitempty"`
+}
+
+// GatewayServicePort defines a port exposed by the gateway Service.
+type GatewayServicePort struct {
+	// Name of the port. Istio uses the prefix of the name to determine the protocol (e.g. `http2` or `https`).
+	Name string `json:"name"`
+
+	// Port exposed by the Service.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	Port int32 `json:"port"`
+
+	// Port of the gateway pods that the Service forwards the traffic to. Defaults to the value of `port`.
+	// +optional
+	TargetPort *intstr.IntOrString `json:"targetPort,omitempty"`
+
+	// IP protocol of the port.
+	// +kubebuilder:validation:Enum=TCP;UDP;SCTP
+	// +optional
+	Protocol *k8sv1.Protocol `json:"protocol,omitempty"`
+}
+
+// GatewayAutoscaling defines the configuration of the HorizontalPodAutoscaler of the gateway Deployment.
+type GatewayAutoscaling struct {
+	// Enables the HorizontalPodAutoscaler.
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Minimum number of replicas.
+	MinReplicas *int32 `json:"minReplicas,omitempty"`
+
+	// Maximum number of replicas.
+	MaxReplicas *int32 `json:"maxReplicas,omitempty"`
+
+	// Target CPU utilization in percent.
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+
+	// Target memory utilization in percent.
+	TargetMemoryUtilizationPercentage *int32 `json:"targetMemoryUtilizationPercentage,omitempty"`
+
+	// Scaling behavior of the HorizontalPodAutoscaler.
+	AutoscaleBehavior *autoscalingv2.HorizontalPodAutoscalerBehavior `json:"autoscaleBehavior,omitempty"`
+}
+
+// GatewayPodDisruptionBudget defines the configuration of the PodDisruptionBudget of the gateway Deployment.
+type GatewayPodDisruptionBudget struct {
+	// Minimum number or percentage of gateway pods that must be available.
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+
+	// Maximum number or percentage of gateway pods that can be unavailable.
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+// GatewayServiceAccount defines the configuration of the gateway ServiceAccount.
+type GatewayServiceAccount struct {
+	// Specifies whether a ServiceAccount is created for the gateway.
+	Create *bool `json:"create,omitempty"`
+
+	// Name of the ServiceAccount. Defaults to the name of the IstioGateway.
+	Name *string `json:"name,omitempty"`
+
+	// Annotations to apply to the ServiceAccount.
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// IstioGatewayStatus defines the observed state of IstioGateway
+type IstioGatewayStatus struct {
+	// ObservedGeneration is the most recent generation observed for this
+	// IstioGateway object. It corresponds to the object's generation, which is
+	// updated on mutation by the API Server. The information in the status
+	// pertains to this particular generation of the object.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Represents the latest available observations of the object's current state.
+	Conditions []v1.StatusCondition `json:"conditions,omitempty"`
+
+	// Reports the current state of the object.
+	State IstioGatewayConditionReason `json:"state,omitempty"`
+
+	// The version of the gateway chart that is installed (e.g. the patch version that an alias like `v1.30-latest`
+	// refers to).
+	ResolvedVersion string `json:"resolvedVersion,omitempty"`
+
+	// IstioRevision stores the name of the referenced IstioRevision
+	IstioRevision string `json:"istioRevision,omitempty"`
+
+	// Reports the readiness of each object deployed for this resource.
+	// +optional
+	Resources []v1.ResourceStatus `json:"resources,omitempty"`
+}
+
+// GetCondition returns the condition of the specified type
+func (s *IstioGatewayStatus) GetCondition(conditionType IstioGatewayConditionType) v1.StatusCondition {
+	if s != nil {
+		return v1.GetCondition(s.Conditions, v1.ConditionType(conditionType))
+	}
+	return v1.StatusCondition{Type: v1.ConditionType(conditionType), Status: metav1.ConditionUnknown}
+}
+
+// SetCondition sets a specific condition in the list of conditions
+func (s *IstioGatewayStatus) SetCondition(condition v1.StatusCondition) {
+	v1.SetCondition(&s.Conditions, condition)
+}
+
+// IstioGatewayConditionType is an alias for ConditionType.
+type IstioGatewayConditionType = v1.ConditionType
+
+// IstioGatewayConditionReason is an alias for ConditionReason.
+type IstioGatewayConditionReason = v1.ConditionReason
+
+const (
+	// IstioGatewayConditionReconciled signifies whether the controller has
+	// successfully reconciled the resources defined through the CR.
+	IstioGatewayConditionReconciled IstioGatewayConditionType = "Reconciled"
+
+	// IstioGatewayReasonReconcileError indicates that the reconciliation of the resource has failed, but will be retried.
+	IstioGatewayReasonReconcileError IstioGatewayConditionReason = "ReconcileError"
+
+	// IstioGatewayReasonInvalidPatch indicates that one of the patches in spec.patches is invalid or can't be applied
+	// to the rendered objects. The reconciliation isn't retried until the resource is updated.
+	IstioGatewayReasonInvalidPatch IstioGatewayConditionReason = "InvalidPatch"
+)
+
+const (
+	// IstioGatewayConditionReady signifies whether the gateway Deployment is ready.
+	IstioGatewayConditionReady IstioGatewayConditionType = "Ready"
+
+	// IstioGatewayReasonDeploymentNotReady indicates that the gateway Deployment is not ready.
+	IstioGatewayReasonDeploymentNotReady IstioGatewayConditionReason = "DeploymentNotReady"
+
+	// IstioGatewayReasonReadinessCheckFailed indicates that the Deployment readiness status could not be ascertained.
+	IstioGatewayReasonReadinessCheckFailed IstioGatewayConditionReason = "ReadinessCheckFailed"
+
+	// IstioGatewayReasonResourcesNotReady indicates that the gateway Deployment is ready, but some of the objects
+	// deployed for the resource are missing or still being rolled out. See status.resources for details.
+	IstioGatewayReasonResourcesNotReady IstioGatewayConditionReason = "ResourcesNotReady"
+)
+
+const (
+	// IstioGatewayReasonHealthy indicates that the gateway is fully reconciled and that all components are ready.
+	IstioGatewayReasonHealthy IstioGatewayConditionReason = "Healthy"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Namespaced,categories=istio-io
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description="Whether the gateway is ready to handle requests."
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.state",description="The current state of this object."
+// +kubebuilder:printcolumn:name="Version",type="string",JSONPath=".status.resolvedVersion",description="The version of the installed gateway chart."
+// +kubebuilder:printcolumn:name="Revision",type="string",JSONPath=".status.istioRevision",description="The referenced IstioRevision."
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="The age of the object"
+
+// IstioGateway represents an ingress or egress gateway deployed in its namespace from the gateway Helm chart.
+// The gateway Deployment, Service and related objects are named after the IstioGateway.
+type IstioGateway struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec IstioGatewaySpec `json:"spec"`
+
+	// +optional
+	Status IstioGatewayStatus `json:"status"`
+}
+
+// +kubebuilder:object:root=true
+
+// IstioGatewayList contains a list of IstioGateway
+type IstioGatewayList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []IstioGateway `json:"items"`
+}
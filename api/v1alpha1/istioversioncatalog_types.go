@@ -0,0 +1,136 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	IstioVersionCatalogKind = "IstioVersionCatalog"
+)
+
+// IstioVersionCatalogSpec defines the desired state of IstioVersionCatalog
+type IstioVersionCatalogSpec struct {
+	// DefaultVersion overrides the default version of the operator. It is used for the Istio, IstioCNI and ZTunnel
+	// resources that are created without a version, and must be installable and not end-of-life. If it can't be
+	// installed, the default version of the operator is used instead.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^(master|v\d+\.\d+(\.\d+)?(-[0-9A-Za-z.]+)?)$`
+	DefaultVersion string `json:"defaultVersion,omitempty"`
+
+	// Versions lists the aliases and end-of-life versions that extend or override the versions known to the
+	// operator.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Versions []CatalogVersion `json:"versions,omitempty"`
+}
+
+// CatalogVersion defines a version alias or marks a version as end-of-life.
+// +kubebuilder:validation:XValidation:rule="has(self.ref) != (has(self.eol) && self.eol)",message="exactly one of ref and eol must be set"
+type CatalogVersion struct {
+	// Name of the version or alias (e.g. `v1.30-latest`).
+	// +kubebuilder:validation:Pattern=`^(master|v\d+\.\d+(\.\d+)?(-[0-9A-Za-z.]+)?)$`
+	Name string `json:"name"`
+
+	// Ref makes this entry an alias of the specified version, which must be embedded in the operator or provided
+	// by an IstioChartSource. The alias takes precedence over an embedded alias with the same name.
+	// +optional
+	// +kubebuilder:validation:Pattern=`^(master|v\d+\.\d+(\.\d+)?(-[0-9A-Za-z.]+)?)$`
+	Ref string `json:"ref,omitempty"`
+
+	// EOL marks the version as end-of-life. End-of-life versions, and aliases that refer to them, can no longer
+	// be installed.
+	// +optional
+	EOL bool `json:"eol,omitempty"`
+}
+
+// IstioVersionCatalogStatus defines the observed state of IstioVersionCatalog
+type IstioVersionCatalogStatus struct {
+	// ObservedGeneration is the most recent generation observed for this
+	// IstioVersionCatalog object. It corresponds to the object's generation, which is
+	// updated on mutation by the API Server. The information in the status
+	// pertains to this particular generation of the object.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Represents the latest available observations of the object's current state.
+	Conditions []v1.StatusCondition `json:"conditions,omitempty"`
+}
+
+// GetCondition returns the condition of the specified type
+func (s *IstioVersionCatalogStatus) GetCondition(conditionType IstioVersionCatalogConditionType) v1.StatusCondition {
+	if s != nil {
+		return v1.GetCondition(s.Conditions, v1.ConditionType(conditionType))
+	}
+	return v1.StatusCondition{Type: v1.ConditionType(conditionType), Status: metav1.ConditionUnknown}
+}
+
+// SetCondition sets a specific condition in the list of conditions
+func (s *IstioVersionCatalogStatus) SetCondition(condition v1.StatusCondition) {
+	v1.SetCondition(&s.Conditions, condition)
+}
+
+// IstioVersionCatalogConditionType represents the type of an IstioVersionCatalog condition.
+type IstioVersionCatalogConditionType string
+
+// IstioVersionCatalogConditionReason represents the reason for an IstioVersionCatalog condition.
+type IstioVersionCatalogConditionReason string
+
+const (
+	// IstioVersionCatalogConditionReady signifies whether all aliases and the default version in the
+	// IstioVersionCatalog resolve to a version that can be installed.
+	IstioVersionCatalogConditionReady IstioVersionCatalogConditionType = "Ready"
+
+	// IstioVersionCatalogReasonUnresolvedAlias indicates that an alias refers to a version that is neither
+	// embedded in the operator nor provided by an IstioChartSource, or that is end-of-life.
+	IstioVersionCatalogReasonUnresolvedAlias IstioVersionCatalogConditionReason = "UnresolvedAlias"
+
+	// IstioVersionCatalogReasonInvalidDefaultVersion indicates that the default version is neither embedded in the
+	// operator nor provided by an IstioChartSource, or that it is end-of-life.
+	IstioVersionCatalogReasonInvalidDefaultVersion IstioVersionCatalogConditionReason = "InvalidDefaultVersion"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,categories=istio-io
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description="Whether all aliases and the default version resolve to a version that can be installed."
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="The age of the object"
+// +kubebuilder:validation:XValidation:rule="self.metadata.name == 'default'",message="metadata.name must be 'default'"
+
+// IstioVersionCatalog extends or overrides the catalog of Istio versions embedded in the operator at runtime. It
+// can define version aliases (e.g. point `v1.30-latest` to a patch version provided by an IstioChartSource), mark
+// versions as end-of-life and override the default version. Only a single IstioVersionCatalog named `default` is allowed.
+type IstioVersionCatalog struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata"`
+
+	// +optional
+	Spec IstioVersionCatalogSpec `json:"spec"`
+
+	// +optional
+	Status IstioVersionCatalogStatus `json:"status"`
+}
+
+// +kubebuilder:object:root=true
+
+// IstioVersionCatalogList contains a list of IstioVersionCatalog
+type IstioVersionCatalogList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []IstioVersionCatalog `json:"items"`
+}
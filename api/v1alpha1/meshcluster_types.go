@@ -0,0 +1,254 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	MeshClusterKind = "MeshCluster"
+)
+
+// MeshClusterSpec defines the desired state of MeshCluster
+// +kubebuilder:validation:XValidation:rule="self.targetRef.kind == 'Istio'",message="targetRef must reference an Istio resource"
+type MeshClusterSpec struct {
+	// The Istio control plane that runs in this cluster. The operator sets the mesh ID, cluster name and network
+	// in its values, and creates the remote secrets and the east-west gateway in its namespace. Only Istio
+	// resources can be referenced.
+	TargetRef v1.TargetReference `json:"targetRef"`
+
+	// The ID of the mesh. It must be the same in all clusters of the mesh.
+	// +kubebuilder:validation:MinLength=1
+	MeshID string `json:"meshID"`
+
+	// The name of this cluster. It must be unique in the mesh and is the name under which the other clusters
+	// refer to this cluster in their peers.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	ClusterName string `json:"clusterName"`
+
+	// The network of this cluster. Set it when the pods in this cluster can't reach the pods in the other
+	// clusters directly; the operator then deploys an east-west gateway that handles the cross-network
+	// traffic.
+	// +optional
+	Network string `json:"network,omitempty"`
+
+	// Configuration of the east-west gateway. The gateway is only deployed when spec.network is set.
+	// +optional
+	EastWestGateway *MeshClusterEastWestGateway `json:"eastWestGateway,omitempty"`
+
+	// The clusters whose services and endpoints the control plane in this cluster discovers. For each peer, the
+	// operator creates an istio-remote-secret that gives the control plane read access to the peer cluster, and
+	// rotates the token in it before it expires. Peers are ignored when the control plane in this cluster is a
+	// remote control plane.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Peers []MeshClusterPeer `json:"peers,omitempty"`
+}
+
+// MeshClusterEastWestGateway defines the configuration of the east-west gateway.
+type MeshClusterEastWestGateway struct {
+	// Type of the gateway Service. The address of the Service must be reachable from the other clusters.
+	// +kubebuilder:validation:Enum=LoadBalancer;NodePort
+	// +kubebuilder:default=LoadBalancer
+	ServiceType string `json:"serviceType,omitempty"`
+
+	// Exposes istiod through the east-west gateway, so that remote clusters can use the control plane in this
+	// cluster.
+	// +optional
+	ExposeIstiod bool `json:"exposeIstiod,omitempty"`
+}
+
+// MeshClusterPeer defines a cluster whose services and endpoints are discovered by the control plane.
+type MeshClusterPeer struct {
+	// The name of the peer cluster, as set in spec.clusterName of the peer's MeshCluster.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=63
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// References the Secret in the operator namespace that contains the kubeconfig of the peer cluster. The
+	// operator only uses this kubeconfig to request tokens for the istio-reader-service-account in the peer
+	// cluster; the control plane never sees it.
+	KubeconfigSecretRef SecretKeyReference `json:"kubeconfigSecretRef"`
+
+	// The namespace of the istio-reader-service-account in the peer cluster. Defaults to the namespace of the
+	// Istio resource referenced in spec.targetRef.
+	// +optional
+	// +kubebuilder:validation:MaxLength=63
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// SecretKeyReference references a key in a Secret in the operator namespace.
+type SecretKeyReference struct {
+	// Name of the Secret.
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	Name string `json:"name"`
+
+	// The key in the Secret.
+	// +kubebuilder:default=kubeconfig
+	Key string `json:"key,omitempty"`
+}
+
+// MeshClusterRole is the role of the cluster in the mesh.
+type MeshClusterRole string
+
+const (
+	// MeshClusterRolePrimary indicates that the control plane runs in this cluster.
+	MeshClusterRolePrimary MeshClusterRole = "Primary"
+
+	// MeshClusterRoleRemote indicates that this cluster uses the control plane of another cluster.
+	MeshClusterRoleRemote MeshClusterRole = "Remote"
+)
+
+// MeshClusterStatus defines the observed state of MeshCluster
+type MeshClusterStatus struct {
+	// ObservedGeneration is the most recent generation observed for this
+	// MeshCluster object. It corresponds to the object's generation, which is
+	// updated on mutation by the API Server. The information in the status
+	// pertains to this particular generation of the object.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Represents the latest available observations of the object's current state.
+	Conditions []v1.StatusCondition `json:"conditions,omitempty"`
+
+	// Reports the current state of the object.
+	State MeshClusterConditionReason `json:"state,omitempty"`
+
+	// The role of this cluster in the mesh. A cluster whose active IstioRevision uses the `remote` profile is a
+	// remote cluster; all other clusters are primary clusters.
+	Role MeshClusterRole `json:"role,omitempty"`
+
+	// The addresses at which the east-west gateway is reachable from the other clusters.
+	EastWestGatewayAddresses []string `json:"eastWestGatewayAddresses,omitempty"`
+
+	// Reports the connectivity to each peer cluster.
+	// +optional
+	Peers []MeshClusterPeerStatus `json:"peers,omitempty"`
+}
+
+// MeshClusterPeerStatus reports the connectivity to a peer cluster.
+type MeshClusterPeerStatus struct {
+	// The name of the peer cluster.
+	Name string `json:"name"`
+
+	// Whether the peer cluster's API server accepts the token in the istio-remote-secret.
+	Connected bool `json:"connected"`
+
+	// The Kubernetes version of the peer cluster.
+	// +optional
+	ServerVersion string `json:"serverVersion,omitempty"`
+
+	// The time at which the token in the istio-remote-secret expires. The operator renews the token before that.
+	// +optional
+	TokenExpirationTime *metav1.Time `json:"tokenExpirationTime,omitempty"`
+
+	// Explains why the peer cluster isn't connected.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// GetCondition returns the condition of the specified type
+func (s *MeshClusterStatus) GetCondition(conditionType MeshClusterConditionType) v1.StatusCondition {
+	if s != nil {
+		return v1.GetCondition(s.Conditions, v1.ConditionType(conditionType))
+	}
+	return v1.StatusCondition{Type: v1.ConditionType(conditionType), Status: metav1.ConditionUnknown}
+}
+
+// SetCondition sets a specific condition in the list of conditions
+func (s *MeshClusterStatus) SetCondition(condition v1.StatusCondition) {
+	v1.SetCondition(&s.Conditions, condition)
+}
+
+// MeshClusterConditionType is an alias for ConditionType.
+type MeshClusterConditionType = v1.ConditionType
+
+// MeshClusterConditionReason is an alias for ConditionReason.
+type MeshClusterConditionReason = v1.ConditionReason
+
+const (
+	// MeshClusterConditionReconciled signifies whether the controller has
+	// successfully reconciled the resources defined through the CR.
+	MeshClusterConditionReconciled MeshClusterConditionType = "Reconciled"
+
+	// MeshClusterReasonReconcileError indicates that the reconciliation of the resource has failed, but will be retried.
+	MeshClusterReasonReconcileError MeshClusterConditionReason = "ReconcileError"
+
+	// MeshClusterReasonReferenceNotFound indicates that the Istio resource referenced in spec.targetRef doesn't
+	// exist or has no active revision yet.
+	MeshClusterReasonReferenceNotFound MeshClusterConditionReason = "ReferenceNotFound"
+)
+
+const (
+	// MeshClusterConditionReady signifies whether the cluster is connected to the rest of the mesh.
+	MeshClusterConditionReady MeshClusterConditionType = "Ready"
+
+	// MeshClusterReasonPeersNotConnected indicates that the control plane can't connect to one or more peer
+	// clusters. See status.peers for details.
+	MeshClusterReasonPeersNotConnected MeshClusterConditionReason = "PeersNotConnected"
+
+	// MeshClusterReasonEastWestGatewayNotReady indicates that the east-west gateway isn't ready or has no
+	// address yet.
+	MeshClusterReasonEastWestGatewayNotReady MeshClusterConditionReason = "EastWestGatewayNotReady"
+
+	// MeshClusterReasonRemoteIstiodNotReady indicates that this is a remote cluster and the control plane in
+	// the primary cluster isn't reachable.
+	MeshClusterReasonRemoteIstiodNotReady MeshClusterConditionReason = "RemoteIstiodNotReady"
+)
+
+const (
+	// MeshClusterReasonHealthy indicates that the cluster is fully reconciled and connected to the rest of the mesh.
+	MeshClusterReasonHealthy MeshClusterConditionReason = "Healthy"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,categories=istio-io
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".spec.clusterName",description="The name of this cluster in the mesh."
+// +kubebuilder:printcolumn:name="Role",type="string",JSONPath=".status.role",description="The role of this cluster in the mesh."
+// +kubebuilder:printcolumn:name="Ready",type="string",JSONPath=".status.conditions[?(@.type==\"Ready\")].status",description="Whether the cluster is connected to the rest of the mesh."
+// +kubebuilder:printcolumn:name="Status",type="string",JSONPath=".status.state",description="The current state of this object."
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="The age of the object"
+// +kubebuilder:validation:XValidation:rule="self.metadata.name == 'default'",message="metadata.name must be 'default'"
+
+// MeshCluster joins this cluster to a multi-primary or primary-remote mesh. It sets the mesh ID, cluster name and
+// network of the referenced Istio control plane, deploys the east-west gateway, and maintains the
+// istio-remote-secrets that give the control plane access to the peer clusters. Only a single MeshCluster named
+// `default` is allowed.
+type MeshCluster struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec MeshClusterSpec `json:"spec"`
+
+	// +optional
+	Status MeshClusterStatus `json:"status"`
+}
+
+// +kubebuilder:object:root=true
+
+// MeshClusterList contains a list of MeshCluster
+type MeshClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []MeshCluster `json:"items"`
+}
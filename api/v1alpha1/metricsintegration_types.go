@@ -0,0 +1,97 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	MetricsIntegrationKind = "MetricsIntegration"
+)
+
+// MetricsIntegrationSpec defines the desired state of MetricsIntegration
+type MetricsIntegrationSpec struct {
+	// TargetRefs specifies the resources that this integration configures.
+	// +kubebuilder:validation:MinItems=1
+	TargetRefs []TargetReference `json:"targetRefs"`
+
+	MetricsConfig `json:",inline"`
+}
+
+// MetricsType identifies the type of metrics integration.
+// +kubebuilder:validation:Enum=UserWorkloadMonitoring;ClusterObservabilityOperator
+type MetricsType string
+
+const (
+	// MetricsTypeUserWorkloadMonitoring integrates with OpenShift User Workload Monitoring.
+	MetricsTypeUserWorkloadMonitoring MetricsType = "UserWorkloadMonitoring"
+
+	// MetricsTypeClusterObservabilityOperator integrates with a MonitoringStack managed by the Cluster Observability Operator.
+	MetricsTypeClusterObservabilityOperator MetricsType = "ClusterObservabilityOperator"
+)
+
+// MetricsConfig configures a metrics backend.
+// +kubebuilder:validation:XValidation:rule="self.type != 'ClusterObservabilityOperator' || has(self.clusterObservabilityOperator)",message="clusterObservabilityOperator must be set when type is ClusterObservabilityOperator"
+type MetricsConfig struct {
+	// Type specifies the metrics integration type.
+	Type MetricsType `json:"type"`
+
+	// UserWorkloadMonitoring configures integration with OpenShift User Workload Monitoring.
+	// +optional
+	UserWorkloadMonitoring *UserWorkloadMonitoringConfig `json:"userWorkloadMonitoring,omitempty"`
+
+	// ClusterObservabilityOperator configures integration with the Cluster Observability
+	// Operator's MonitoringStack resource for metrics collection.
+	// +optional
+	ClusterObservabilityOperator *ClusterObservabilityOperatorConfig `json:"clusterObservabilityOperator,omitempty"`
+}
+
+// UserWorkloadMonitoringConfig configures the OpenShift User Workload Monitoring integration.
+type UserWorkloadMonitoringConfig struct{}
+
+// ClusterObservabilityOperatorConfig configures the Cluster Observability Operator integration.
+type ClusterObservabilityOperatorConfig struct {
+	// MonitoringStackRef is a reference to a MonitoringStack resource that defines
+	// the Prometheus stack used for scraping Istio metrics.
+	MonitoringStackRef NamespacedReference `json:"monitoringStackRef"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,categories=istio-io
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Type",type="string",JSONPath=".spec.type",description="The type of metrics integration."
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="The age of the object"
+
+// MetricsIntegration configures Istio and related components to work with a metrics backend.
+type MetricsIntegration struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec MetricsIntegrationSpec `json:"spec"`
+
+	// +optional
+	Status IntegrationStatus `json:"status"`
+}
+
+// +kubebuilder:object:root=true
+
+// MetricsIntegrationList contains a list of MetricsIntegration
+type MetricsIntegrationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []MetricsIntegration `json:"items"`
+}
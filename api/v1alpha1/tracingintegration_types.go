@@ -0,0 +1,99 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	TracingIntegrationKind = "TracingIntegration"
+)
+
+// TracingIntegrationSpec defines the desired state of TracingIntegration
+type TracingIntegrationSpec struct {
+	// TargetRefs specifies the resources that this integration configures.
+	// +kubebuilder:validation:MinItems=1
+	TargetRefs []TargetReference `json:"targetRefs"`
+
+	TracingConfig `json:",inline"`
+}
+
+// TracingType identifies the type of tracing integration.
+// +kubebuilder:validation:Enum=OpenTelemetry;TempoStack
+type TracingType string
+
+const (
+	// TracingTypeOpenTelemetry sends traces to an OpenTelemetry Collector.
+	TracingTypeOpenTelemetry TracingType = "OpenTelemetry"
+
+	// TracingTypeTempoStack sends traces to the distributor of a TempoStack.
+	TracingTypeTempoStack TracingType = "TempoStack"
+)
+
+// TracingConfig configures a tracing backend.
+// +kubebuilder:validation:XValidation:rule="self.type != 'OpenTelemetry' || has(self.openTelemetry)",message="openTelemetry must be set when type is OpenTelemetry"
+// +kubebuilder:validation:XValidation:rule="self.type != 'TempoStack' || has(self.tempoStack)",message="tempoStack must be set when type is TempoStack"
+type TracingConfig struct {
+	// Type specifies the tracing integration type.
+	Type TracingType `json:"type"`
+
+	// OpenTelemetry configures integration with an OpenTelemetry Collector.
+	// +optional
+	OpenTelemetry *OpenTelemetryConfig `json:"openTelemetry,omitempty"`
+
+	// TempoStack configures integration with a TempoStack resource.
+	// +optional
+	TempoStack *TempoStackConfig `json:"tempoStack,omitempty"`
+}
+
+// OpenTelemetryConfig configures the OpenTelemetry integration.
+type OpenTelemetryConfig struct {
+	// OTELCollectorRef is a reference to an OpenTelemetry Collector resource.
+	OTELCollectorRef NamespacedReference `json:"otelCollectorRef"`
+}
+
+// TempoStackConfig configures the TempoStack integration.
+type TempoStackConfig struct {
+	// TempoStackRef is a reference to a TempoStack resource.
+	TempoStackRef NamespacedReference `json:"tempoStackRef"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster,categories=istio-io
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Type",type="string",JSONPath=".spec.type",description="The type of tracing integration."
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="The age of the object"
+
+// TracingIntegration configures Istio and related components to work with a tracing backend.
+type TracingIntegration struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec TracingIntegrationSpec `json:"spec"`
+
+	// +optional
+	Status IntegrationStatus `json:"status"`
+}
+
+// +kubebuilder:object:root=true
+
+// TracingIntegrationList contains a list of TracingIntegration
+type TracingIntegrationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+	Items           []TracingIntegration `json:"items"`
+}
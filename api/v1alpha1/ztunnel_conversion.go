@@ -0,0 +1,150 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// ZTunnelConversionDataAnnotation holds the fields of a ZTunnel spec that the version of the object can't
+// represent, so that they are restored when the object is converted back to the version that has them.
+const ZTunnelConversionDataAnnotation = "sailoperator.io/ztunnel-conversion-data"
+
+// defaultZTunnelProfile is the default of spec.profile. It isn't stored in the annotation, because it's the
+// only profile that v1 supports.
+const defaultZTunnelProfile = "ambient"
+
+// ztunnelConversionData contains the spec fields that only exist in one of the ZTunnel versions. The status
+// isn't preserved, because the operator recomputes it.
+// +kubebuilder:object:generate=false
+type ztunnelConversionData struct {
+	Profile      string              `json:"profile,omitempty"`
+	NodeSelector map[string]string   `json:"nodeSelector,omitempty"`
+	TargetRef    *v1.TargetReference `json:"targetRef,omitempty"`
+	DriftPolicy  *v1.DriftPolicy     `json:"driftPolicy,omitempty"`
+	Patches      []v1.Patch          `json:"patches,omitempty"`
+}
+
+var _ conversion.Convertible = &ZTunnel{}
+
+// ConvertTo converts this ZTunnel to the v1 version. The profile isn't supported in v1, so it's kept in the
+// ZTunnelConversionDataAnnotation, unless it's the default.
+func (src *ZTunnel) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1.ZTunnel)
+	if !ok {
+		return fmt.Errorf("unsupported conversion target %T", dstRaw)
+	}
+
+	src = src.DeepCopy()
+	data, err := popConversionData(&src.ObjectMeta)
+	if err != nil {
+		return err
+	}
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = v1.ZTunnelSpec{
+		Version:      src.Spec.Version,
+		Namespace:    src.Spec.Namespace,
+		Values:       src.Spec.Values,
+		NodeSelector: data.NodeSelector,
+		TargetRef:    data.TargetRef,
+		DriftPolicy:  data.DriftPolicy,
+		Patches:      data.Patches,
+	}
+	dst.Status = v1.ZTunnelStatus{
+		ObservedGeneration: src.Status.ObservedGeneration,
+		Conditions:         src.Status.Conditions,
+		State:              v1.ConditionReason(src.Status.State),
+	}
+	profile := src.Spec.Profile
+	if profile == defaultZTunnelProfile {
+		profile = ""
+	}
+	return setConversionData(&dst.ObjectMeta, ztunnelConversionData{Profile: profile})
+}
+
+// ConvertFrom converts the v1 version of ZTunnel to this version. The fields that don't exist in v1alpha1 are
+// kept in the ZTunnelConversionDataAnnotation.
+func (dst *ZTunnel) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1.ZTunnel)
+	if !ok {
+		return fmt.Errorf("unsupported conversion source %T", srcRaw)
+	}
+
+	src = src.DeepCopy()
+	data, err := popConversionData(&src.ObjectMeta)
+	if err != nil {
+		return err
+	}
+	if data.Profile == "" {
+		data.Profile = defaultZTunnelProfile
+	}
+	dst.ObjectMeta = src.ObjectMeta
+	dst.Spec = ZTunnelSpec{
+		Version:   src.Spec.Version,
+		Profile:   data.Profile,
+		Namespace: src.Spec.Namespace,
+		Values:    src.Spec.Values,
+	}
+	dst.Status = ZTunnelStatus{
+		ObservedGeneration: src.Status.ObservedGeneration,
+		Conditions:         src.Status.Conditions,
+		State:              ZTunnelConditionReason(src.Status.State),
+	}
+	return setConversionData(&dst.ObjectMeta, ztunnelConversionData{
+		NodeSelector: src.Spec.NodeSelector,
+		TargetRef:    src.Spec.TargetRef,
+		DriftPolicy:  src.Spec.DriftPolicy,
+		Patches:      src.Spec.Patches,
+	})
+}
+
+// popConversionData removes the ZTunnelConversionDataAnnotation from the object and returns its contents.
+func popConversionData(meta *metav1.ObjectMeta) (ztunnelConversionData, error) {
+	var data ztunnelConversionData
+	value, found := meta.Annotations[ZTunnelConversionDataAnnotation]
+	if !found {
+		return data, nil
+	}
+	delete(meta.Annotations, ZTunnelConversionDataAnnotation)
+	if len(meta.Annotations) == 0 {
+		meta.Annotations = nil
+	}
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return data, fmt.Errorf("failed to parse annotation %s: %w", ZTunnelConversionDataAnnotation, err)
+	}
+	return data, nil
+}
+
+// setConversionData stores the data in the ZTunnelConversionDataAnnotation of the object, unless it's empty.
+func setConversionData(meta *metav1.ObjectMeta, data ztunnelConversionData) error {
+	if data.Profile == "" && len(data.NodeSelector) == 0 && data.TargetRef == nil && data.DriftPolicy == nil &&
+		len(data.Patches) == 0 {
+		return nil
+	}
+	value, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal annotation %s: %w", ZTunnelConversionDataAnnotation, err)
+	}
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[ZTunnelConversionDataAnnotation] = string(value)
+	return nil
+}
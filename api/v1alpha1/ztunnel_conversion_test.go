@@ -0,0 +1,126 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"testing"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestZTunnelConvertTo(t *testing.T) {
+	testCases := []struct {
+		name     string
+		profile  string
+		expected map[string]string
+	}{
+		{
+			name:     "default profile",
+			profile:  "ambient",
+			expected: map[string]string{"foo": "bar"},
+		},
+		{
+			name:    "other profile",
+			profile: "openshift-ambient",
+			expected: map[string]string{
+				"foo":                           "bar",
+				ZTunnelConversionDataAnnotation: `{"profile":"openshift-ambient"}`,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			src := &ZTunnel{
+				ObjectMeta: metav1.ObjectMeta{Name: "default", Annotations: map[string]string{"foo": "bar"}},
+				Spec: ZTunnelSpec{
+					Version:   "v1.30.0",
+					Profile:   tc.profile,
+					Namespace: "ztunnel",
+					Values:    &v1.ZTunnelValues{ZTunnel: &v1.ZTunnelConfig{Hub: ptr.Of("quay.io/foo")}},
+				},
+				Status: ZTunnelStatus{
+					ObservedGeneration: 3,
+					Conditions:         []v1.StatusCondition{{Type: v1.ZTunnelConditionReady, Status: metav1.ConditionTrue}},
+					State:              ZTunnelReasonHealthy,
+				},
+			}
+			dst := &v1.ZTunnel{}
+			require.NoError(t, src.ConvertTo(dst))
+
+			assert.Equal(t, tc.expected, dst.Annotations)
+			assert.Equal(t, v1.ZTunnelSpec{
+				Version:   "v1.30.0",
+				Namespace: "ztunnel",
+				Values:    &v1.ZTunnelValues{ZTunnel: &v1.ZTunnelConfig{Hub: ptr.Of("quay.io/foo")}},
+			}, dst.Spec)
+			assert.Equal(t, v1.ZTunnelStatus{
+				ObservedGeneration: 3,
+				Conditions:         []v1.StatusCondition{{Type: v1.ZTunnelConditionReady, Status: metav1.ConditionTrue}},
+				State:              v1.ZTunnelReasonHealthy,
+			}, dst.Status)
+			assert.Equal(t, map[string]string{"foo": "bar"}, src.Annotations, "source object must not be modified")
+
+			roundTrip := &ZTunnel{}
+			require.NoError(t, roundTrip.ConvertFrom(dst))
+			assert.Equal(t, src, roundTrip)
+		})
+	}
+}
+
+func TestZTunnelConvertFrom(t *testing.T) {
+	src := &v1.ZTunnel{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1.ZTunnelSpec{
+			Version:      "v1.30.0",
+			Namespace:    "ztunnel",
+			NodeSelector: map[string]string{"pool": "gpu"},
+			TargetRef:    &v1.TargetReference{Kind: v1.IstioKind, Name: "default"},
+			DriftPolicy:  &v1.DriftPolicy{Action: v1.DriftActionReport},
+			Patches: []v1.Patch{{
+				Target: v1.PatchTarget{Kind: "DaemonSet"},
+				Patch:  `{"metadata":{"labels":{"foo":"bar"}}}`,
+			}},
+		},
+		Status: v1.ZTunnelStatus{
+			ObservedGeneration: 2,
+			State:              v1.ZTunnelReasonHealthy,
+			IstioRevision:      "default",
+		},
+	}
+	dst := &ZTunnel{}
+	require.NoError(t, dst.ConvertFrom(src))
+
+	assert.Equal(t, ZTunnelSpec{Version: "v1.30.0", Profile: "ambient", Namespace: "ztunnel"}, dst.Spec)
+	assert.Equal(t, ZTunnelStatus{ObservedGeneration: 2, State: ZTunnelReasonHealthy}, dst.Status)
+	assert.Contains(t, dst.Annotations, ZTunnelConversionDataAnnotation)
+	assert.Empty(t, src.Annotations, "source object must not be modified")
+
+	roundTrip := &v1.ZTunnel{}
+	require.NoError(t, dst.ConvertTo(roundTrip))
+	assert.Nil(t, roundTrip.Annotations)
+	assert.Equal(t, src.Spec, roundTrip.Spec)
+}
+
+func TestZTunnelConvertInvalidAnnotation(t *testing.T) {
+	src := &ZTunnel{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Annotations: map[string]string{ZTunnelConversionDataAnnotation: "{"}},
+	}
+	assert.ErrorContains(t, src.ConvertTo(&v1.ZTunnel{}), "failed to parse annotation")
+}
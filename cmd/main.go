@@ -21,22 +21,36 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/istio-ecosystem/sail-operator/controllers/chartsource"
+	"github.com/istio-ecosystem/sail-operator/controllers/integration"
 	"github.com/istio-ecosystem/sail-operator/controllers/istio"
 	"github.com/istio-ecosystem/sail-operator/controllers/istiocni"
+	"github.com/istio-ecosystem/sail-operator/controllers/istiogateway"
 	"github.com/istio-ecosystem/sail-operator/controllers/istiorevision"
 	"github.com/istio-ecosystem/sail-operator/controllers/istiorevisiontag"
+	"github.com/istio-ecosystem/sail-operator/controllers/meshcluster"
+	"github.com/istio-ecosystem/sail-operator/controllers/monitoring"
+	"github.com/istio-ecosystem/sail-operator/controllers/versioncatalog"
 	"github.com/istio-ecosystem/sail-operator/controllers/webhook"
 	"github.com/istio-ecosystem/sail-operator/controllers/ztunnel"
+	"github.com/istio-ecosystem/sail-operator/pkg/admission"
+	sourceregistry "github.com/istio-ecosystem/sail-operator/pkg/chartsource"
 	"github.com/istio-ecosystem/sail-operator/pkg/config"
 	"github.com/istio-ecosystem/sail-operator/pkg/enqueuelogger"
 	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	"github.com/istio-ecosystem/sail-operator/pkg/revision"
 	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
+	"github.com/istio-ecosystem/sail-operator/pkg/storagemigration"
 	"github.com/istio-ecosystem/sail-operator/pkg/version"
 	"github.com/istio-ecosystem/sail-operator/resources"
 	configv1 "github.com/openshift/api/config/v1"
 	openshifttls "github.com/openshift/controller-runtime-common/pkg/tls"
 	openshiftcrypto "github.com/openshift/library-go/pkg/crypto"
+	"k8s.io/apimachinery/pkg/types"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -45,30 +59,56 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	ctrlwebhook "sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
 var setupLog = ctrl.Log.WithName("setup")
 
+const (
+	webhookCertDir = "/tmp/k8s-webhook-server/serving-certs"
+	ztunnelCRDName = "ztunnels.sailoperator.io"
+)
+
 func main() {
 	var metricsAddr string
 	var probeAddr string
 	var configFile string
 	var resourceDirectory string
+	var chartCacheDirectory string
 	var logAPIRequests bool
 	var printVersion bool
 	var leaderElectionEnabled bool
+	var enableWebhooks bool
+	var conversionWebhookService string
+	var helmMaxHistory int
+	var serverSideApply bool
 	var reconcilerCfg config.ReconcilerConfig
 
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8443", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.StringVar(&configFile, "config-file", "/etc/sail-operator/config.properties", "Location of the config file, propagated by k8s downward APIs")
 	flag.StringVar(&resourceDirectory, "resource-directory", "", "Where to find resources (e.g. charts). If empty, uses embedded resources.")
+	flag.StringVar(&chartCacheDirectory, "chart-cache-directory", "/var/cache/sail-operator/charts",
+		"Where to store the charts and profiles fetched from the repositories declared in IstioChartSources.")
 	flag.IntVar(&reconcilerCfg.MaxConcurrentReconciles, "max-concurrent-reconciles", 1,
 		"MaxConcurrentReconciles is the maximum number of concurrent Reconciles which can be run.")
+	flag.IntVar(&helmMaxHistory, "helm-max-history", helm.DefaultMaxHistory,
+		"The number of revisions retained in the history of each Helm release. Retained revisions can be restored "+
+			"with the sailoperator.io/rollback-to annotation. 0 retains all revisions.")
+	flag.BoolVar(&serverSideApply, "server-side-apply", false,
+		"Apply the objects of Helm releases with server-side apply. Fields owned by other field managers (e.g. the replicas "+
+			"of a Deployment scaled by a HorizontalPodAutoscaler) are left as they are and reported in the FieldConflicts condition.")
 	flag.BoolVar(&logAPIRequests, "log-api-requests", false, "Whether to log each request sent to the Kubernetes API server")
 	flag.BoolVar(&printVersion, "version", printVersion, "Prints version information and exits")
 	flag.BoolVar(&leaderElectionEnabled, "leader-elect", true,
 		"Enable leader election for this operator. Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Serve the admission webhook that validates Istio, IstioCNI and ZTunnel resources, and the conversion webhook "+
+			"that converts ZTunnel resources between versions. The serving certificate must be mounted in "+webhookCertDir+".")
+	flag.StringVar(&conversionWebhookService, "conversion-webhook-service", "",
+		"The namespace/name of the Service through which the webhooks are served. If set, the operator configures the "+
+			"ZTunnel CRD to use the conversion webhook, trusting the ca.crt in "+webhookCertDir+". "+
+			"Not needed when the operator is installed by OLM, which configures the conversion webhook itself.")
 
 	flag.BoolVar(&enqueuelogger.LogEnqueueEvents, "log-enqueue-events", false, "Whether to log events that cause an object to be enqueued for reconciliation")
 
@@ -92,6 +132,10 @@ func main() {
 		setupLog.Info("using embedded resources")
 		reconcilerCfg.ResourceFS = resources.FS
 	}
+	// the versions fetched from IstioChartSources are served alongside the embedded or filesystem resources
+	reconcilerCfg.ChartSources = sourceregistry.NewRegistry(reconcilerCfg.ResourceFS)
+	reconcilerCfg.ResourceFS = reconcilerCfg.ChartSources
+	reconcilerCfg.VersionCatalog = &reconciler.Notifier{}
 	reconcilerCfg.OperatorNamespace = os.Getenv("POD_NAMESPACE")
 	if reconcilerCfg.OperatorNamespace == "" {
 		contents, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
@@ -177,9 +221,15 @@ func main() {
 		TLSOpts:        metricsServerTLSOptions,
 	}
 
+	webhookServer := ctrlwebhook.NewServer(ctrlwebhook.Options{
+		CertDir: webhookCertDir,
+		TLSOpts: metricsServerTLSOptions,
+	})
+
 	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
 		Scheme:                  scheme.Scheme,
 		Metrics:                 metricsServerOptions,
+		WebhookServer:           webhookServer,
 		HealthProbeBindAddress:  probeAddr,
 		LeaderElection:          leaderElectionEnabled,
 		LeaderElectionID:        "sail-operator-lock",
@@ -201,23 +251,47 @@ func main() {
 		os.Exit(1)
 	}
 
-	chartManager := helm.NewChartManager(mgr.GetConfig(), os.Getenv("HELM_DRIVER"))
+	// the catalog must be applied before the controllers resolve any versions
+	if err := versioncatalog.Load(ctx, mgr.GetAPIReader()); err != nil {
+		setupLog.Error(err, "unable to load IstioVersionCatalog")
+		os.Exit(1)
+	}
+
+	chartManagerOpts := []helm.ChartManagerOption{helm.WithMaxHistory(helmMaxHistory)}
+	if serverSideApply {
+		chartManagerOpts = append(chartManagerOpts, helm.WithServerSideApply(mgr.GetAPIReader()))
+	}
+	chartManager := helm.NewChartManager(mgr.GetConfig(), os.Getenv("HELM_DRIVER"), chartManagerOpts...)
+
+	reconcilerCfg.GatewayAPIAvailable, err = revision.IsGatewayAPIAvailable(mgr.GetRESTMapper())
+	if err != nil {
+		setupLog.Error(err, "unable to determine whether the Gateway API is installed")
+		os.Exit(1)
+	}
+	setupLog.Info("detected Gateway API", "available", reconcilerCfg.GatewayAPIAvailable)
+
+	// the IstioRevision and IstioRevisionTag controllers look up the namespaces and pods that reference a
+	// revision through these indexes instead of listing all of them
+	if err := revision.RegisterUsageIndexes(ctx, mgr.GetFieldIndexer()); err != nil {
+		setupLog.Error(err, "unable to register field indexes")
+		os.Exit(1)
+	}
 
-	err = istio.NewReconciler(reconcilerCfg, mgr.GetClient(), mgr.GetScheme()).
+	err = istio.NewReconciler(reconcilerCfg, mgr.GetClient(), mgr.GetAPIReader(), mgr.GetScheme(), chartManager).
 		SetupWithManager(mgr)
 	if err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Istio")
 		os.Exit(1)
 	}
 
-	err = istiorevision.NewReconciler(reconcilerCfg, mgr.GetClient(), mgr.GetScheme(), chartManager).
+	err = istiorevision.NewReconciler(reconcilerCfg, mgr.GetClient(), mgr.GetCache(), mgr.GetAPIReader(), mgr.GetScheme(), chartManager).
 		SetupWithManager(mgr)
 	if err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "IstioRevision")
 		os.Exit(1)
 	}
 
-	err = istiorevisiontag.NewReconciler(reconcilerCfg, mgr.GetClient(), mgr.GetScheme(), chartManager).
+	err = istiorevisiontag.NewReconciler(reconcilerCfg, mgr.GetClient(), mgr.GetCache(), mgr.GetAPIReader(), mgr.GetScheme(), chartManager).
 		SetupWithManager(mgr)
 	if err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "IstioRevisionTag")
@@ -238,6 +312,49 @@ func main() {
 		os.Exit(1)
 	}
 
+	err = istiogateway.NewReconciler(reconcilerCfg, mgr.GetClient(), mgr.GetScheme(), chartManager).
+		SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "IstioGateway")
+		os.Exit(1)
+	}
+
+	err = integration.NewReconciler(reconcilerCfg, mgr.GetClient(), mgr.GetScheme()).
+		SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Integration")
+		os.Exit(1)
+	}
+
+	err = chartsource.NewReconciler(reconcilerCfg, mgr.GetClient(), mgr.GetAPIReader(), mgr.GetScheme(),
+		sourceregistry.NewCache(chartCacheDirectory)).
+		SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "IstioChartSource")
+		os.Exit(1)
+	}
+
+	err = versioncatalog.NewReconciler(reconcilerCfg, mgr.GetClient(), mgr.GetScheme()).
+		SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "IstioVersionCatalog")
+		os.Exit(1)
+	}
+
+	err = meshcluster.NewReconciler(reconcilerCfg, mgr.GetClient(), mgr.GetAPIReader(), mgr.GetScheme()).
+		SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "MeshCluster")
+		os.Exit(1)
+	}
+
+	err = monitoring.NewReconciler(reconcilerCfg, mgr.GetClient(), mgr.GetScheme()).
+		SetupWithManager(mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Monitoring")
+		os.Exit(1)
+	}
+
 	err = webhook.NewReconciler(reconcilerCfg, mgr.GetClient(), mgr.GetScheme()).
 		SetupWithManager(mgr)
 	if err != nil {
@@ -245,6 +362,27 @@ func main() {
 		os.Exit(1)
 	}
 
+	if enableWebhooks {
+		if err := admission.SetupWithManager(mgr, reconcilerCfg); err != nil {
+			setupLog.Error(err, "unable to set up admission webhooks")
+			os.Exit(1)
+		}
+		if conversionWebhookService != "" {
+			if err := configureConversionWebhook(ctx, mgr, conversionWebhookService); err != nil {
+				setupLog.Error(err, "unable to configure conversion webhook")
+				os.Exit(1)
+			}
+		}
+
+		// rewrite the ZTunnels that are still stored as v1alpha1, so that v1alpha1 can be removed from the CRD.
+		// The objects can only be converted without losing fields while this operator serves the conversion
+		// webhook; the migrator also checks that the CRD actually uses it, which OLM configures on its own.
+		if err := mgr.Add(storagemigration.New(mgr.GetClient(), mgr.GetAPIReader(), ztunnelCRDName)); err != nil {
+			setupLog.Error(err, "unable to set up storage version migration")
+			os.Exit(1)
+		}
+	}
+
 	if reconcilerCfg.TLSConfig != nil && reconcilerCfg.TLSConfig.OpenShift != nil {
 		tlsWatcher := &openshifttls.SecurityProfileWatcher{
 			Client:                    mgr.GetClient(),
@@ -284,6 +422,12 @@ func main() {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if enableWebhooks {
+		if err := mgr.AddReadyzCheck("webhook", webhookServer.StartedChecker()); err != nil {
+			setupLog.Error(err, "unable to set up webhook ready check")
+			os.Exit(1)
+		}
+	}
 
 	setupLog.Info("starting sail-operator manager")
 	if err := mgr.Start(ctx); err != nil {
@@ -292,6 +436,26 @@ func main() {
 	}
 }
 
+// configureConversionWebhook configures the ZTunnel CRD to use the conversion webhook served through the given
+// Service, which is specified as namespace/name.
+func configureConversionWebhook(ctx context.Context, mgr ctrl.Manager, service string) error {
+	namespace, name, found := strings.Cut(service, "/")
+	if !found || namespace == "" || name == "" {
+		return fmt.Errorf("invalid conversion webhook service %q: must be namespace/name", service)
+	}
+	caBundle, err := os.ReadFile(filepath.Join(webhookCertDir, "ca.crt"))
+	if err != nil {
+		return fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	// the manager's client can't read objects before the manager is started
+	cl, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	return admission.ConfigureConversionWebhook(ctx, cl, ztunnelCRDName,
+		types.NamespacedName{Namespace: namespace, Name: name}, caBundle)
+}
+
 type requestLogger struct {
 	rt http.RoundTripper
 }
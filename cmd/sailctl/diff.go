@@ -0,0 +1,124 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"text/tabwriter"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newDiffRevisionsCommand(newClient func() (client.Client, error)) *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff-revisions REVISION1 REVISION2",
+		Short: "Compare the specs of two IstioRevisions field by field",
+		Long: "Compare the specs of two IstioRevisions field by field, including every field of spec.values.\n" +
+			"Only the fields that differ are listed; fields that are only set in one of the revisions are shown as <unset>.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+			return diffRevisions(cmd.Context(), cl, cmd.OutOrStdout(), args[0], args[1])
+		},
+	}
+}
+
+func diffRevisions(ctx context.Context, cl client.Client, out io.Writer, name1, name2 string) error {
+	var specs [2]map[string]string
+	for i, name := range []string{name1, name2} {
+		rev := v1.IstioRevision{}
+		if err := cl.Get(ctx, client.ObjectKey{Name: name}, &rev); err != nil {
+			return fmt.Errorf("failed to get IstioRevision %s: %w", name, err)
+		}
+		fields, err := flattenSpec(rev.Spec)
+		if err != nil {
+			return fmt.Errorf("failed to read the spec of IstioRevision %s: %w", name, err)
+		}
+		specs[i] = fields
+	}
+
+	allFields := maps.Clone(specs[0])
+	maps.Copy(allFields, specs[1])
+	paths := slices.Sorted(maps.Keys(allFields))
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintf(w, "FIELD\t%s\t%s\n", name1, name2)
+	differences := 0
+	for _, path := range paths {
+		value1, found1 := specs[0][path]
+		value2, found2 := specs[1][path]
+		if found1 == found2 && value1 == value2 {
+			continue
+		}
+		differences++
+		fmt.Fprintf(w, "%s\t%s\t%s\n", path, unsetIfMissing(value1, found1), unsetIfMissing(value2, found2))
+	}
+	if differences == 0 {
+		fmt.Fprintf(out, "The specs of IstioRevisions %s and %s are identical\n", name1, name2)
+		return nil
+	}
+	return w.Flush()
+}
+
+// flattenSpec returns the leaf fields of the given spec, keyed by their path (e.g. spec.values.pilot.env.FOO).
+// List items are addressed by their index.
+func flattenSpec(spec v1.IstioRevisionSpec) (map[string]string, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	var obj any
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil, err
+	}
+	fields := map[string]string{}
+	flatten("spec", obj, fields)
+	return fields, nil
+}
+
+func flatten(path string, value any, fields map[string]string) {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			flatten(path+"."+key, child, fields)
+		}
+	case []any:
+		for i, child := range v {
+			flatten(fmt.Sprintf("%s[%d]", path, i), child, fields)
+		}
+	case string:
+		fields[path] = v
+	default:
+		data, _ := json.Marshal(v)
+		fields[path] = string(data)
+	}
+}
+
+func unsetIfMissing(value string, found bool) string {
+	if !found {
+		return "<unset>"
+	}
+	return value
+}
@@ -0,0 +1,60 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// sailctl inspects and operates the service meshes managed by the Sail Operator.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
+	"github.com/istio-ecosystem/sail-operator/pkg/version"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	configFlags := genericclioptions.NewConfigFlags(false)
+	newClient := func() (client.Client, error) {
+		cfg, err := configFlags.ToRESTConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+		}
+		return client.New(cfg, client.Options{Scheme: scheme.Scheme})
+	}
+
+	cmd := &cobra.Command{
+		Use:          "sailctl",
+		Short:        "Inspect and operate service meshes managed by the Sail Operator",
+		Version:      version.Info.Version,
+		SilenceUsage: true,
+	}
+	configFlags.AddFlags(cmd.PersistentFlags())
+
+	cmd.AddCommand(
+		newStatusCommand(newClient),
+		newVersionsCommand(),
+		newDiffRevisionsCommand(newClient),
+		newMigrateNamespacesCommand(newClient),
+	)
+	return cmd
+}
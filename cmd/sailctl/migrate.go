@@ -0,0 +1,122 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/revision"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type migrateNamespacesOptions struct {
+	from     string
+	to       string
+	selector string
+	dryRun   bool
+}
+
+func newMigrateNamespacesCommand(newClient func() (client.Client, error)) *cobra.Command {
+	opts := migrateNamespacesOptions{}
+	cmd := &cobra.Command{
+		Use:   "migrate-namespaces --from REVISION --to REVISION",
+		Short: "Relabel the namespaces that use one revision so that they use another",
+		Long: "Relabel the namespaces whose injection labels select the --from revision or revision tag, so that they\n" +
+			"select the --to revision or revision tag instead. The istio-injection=enabled label is replaced by the\n" +
+			"istio.io/rev label. Existing pods keep their sidecars until they are restarted.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+			return migrateNamespaces(cmd.Context(), cl, cmd.OutOrStdout(), opts)
+		},
+	}
+	cmd.Flags().StringVar(&opts.from, "from", "", "The revision or revision tag that the namespaces use now")
+	cmd.Flags().StringVar(&opts.to, "to", "", "The revision or revision tag that the namespaces should use")
+	cmd.Flags().StringVarP(&opts.selector, "selector", "l", "", "Only migrate the namespaces that match this label selector")
+	cmd.Flags().BoolVar(&opts.dryRun, "dry-run", false, "Only print the namespaces that would be migrated")
+	_ = cmd.MarkFlagRequired("from")
+	_ = cmd.MarkFlagRequired("to")
+	return cmd
+}
+
+func migrateNamespaces(ctx context.Context, cl client.Client, out io.Writer, opts migrateNamespacesOptions) error {
+	if opts.from == opts.to {
+		return errors.New("--from and --to must be different")
+	}
+	selector, err := labels.Parse(opts.selector)
+	if err != nil {
+		return fmt.Errorf("invalid label selector: %w", err)
+	}
+	if err := checkRevisionExists(ctx, cl, opts.to); err != nil {
+		return err
+	}
+
+	nsList := corev1.NamespaceList{}
+	if err := cl.List(ctx, &nsList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	migrated := 0
+	for _, ns := range nsList.Items {
+		if !revision.NamespaceReferencesRevision(ns, opts.from) {
+			continue
+		}
+		migrated++
+		if opts.dryRun {
+			fmt.Fprintf(out, "namespace/%s would be migrated from %s to %s (dry run)\n", ns.Name, opts.from, opts.to)
+			continue
+		}
+
+		patch := client.MergeFrom(ns.DeepCopy())
+		delete(ns.Labels, constants.IstioInjectionLabel)
+		ns.Labels[constants.IstioRevLabel] = opts.to
+		if err := cl.Patch(ctx, &ns, patch); err != nil {
+			return fmt.Errorf("failed to update namespace %s: %w", ns.Name, err)
+		}
+		fmt.Fprintf(out, "namespace/%s migrated from %s to %s\n", ns.Name, opts.from, opts.to)
+	}
+
+	if migrated == 0 {
+		fmt.Fprintf(out, "No namespaces use %s\n", opts.from)
+	} else if !opts.dryRun {
+		fmt.Fprintln(out, "Restart the workloads in these namespaces to inject the sidecars of the new revision")
+	}
+	return nil
+}
+
+// checkRevisionExists returns an error if there's neither an IstioRevision nor an IstioRevisionTag with
+// the given name.
+func checkRevisionExists(ctx context.Context, cl client.Client, name string) error {
+	for _, obj := range []client.Object{&v1.IstioRevision{}, &v1.IstioRevisionTag{}} {
+		if err := cl.Get(ctx, client.ObjectKey{Name: name}, obj); err == nil {
+			return nil
+		} else if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to get %s: %w", name, err)
+		}
+	}
+	return fmt.Errorf("neither an IstioRevision nor an IstioRevisionTag named %q exists", name)
+}
@@ -0,0 +1,196 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
+	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestPrintStatus(t *testing.T) {
+	istio := &v1.Istio{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", UID: "istio-uid"},
+		Spec:       v1.IstioSpec{Namespace: "istio-system", Version: istioversion.Default},
+		Status:     v1.IstioStatus{State: v1.IstioReasonHealthy, ActiveRevisionName: "default-v2"},
+	}
+	owner := []metav1.OwnerReference{{APIVersion: v1.GroupVersion.String(), Kind: v1.IstioKind, Name: "default", UID: "istio-uid"}}
+	objects := []client.Object{
+		istio,
+		&v1.IstioRevision{
+			ObjectMeta: metav1.ObjectMeta{Name: "default-v1", UID: "rev1-uid", OwnerReferences: owner},
+			Spec:       v1.IstioRevisionSpec{Namespace: "istio-system", Version: "v1"},
+			Status:     v1.IstioRevisionStatus{State: v1.IstioRevisionReasonHealthy},
+		},
+		&v1.IstioRevision{
+			ObjectMeta: metav1.ObjectMeta{Name: "default-v2", UID: "rev2-uid", OwnerReferences: owner},
+			Spec:       v1.IstioRevisionSpec{Namespace: "istio-system", Version: "v2"},
+			Status:     v1.IstioRevisionStatus{State: v1.IstioRevisionReasonHealthy},
+		},
+		&v1.IstioRevision{
+			ObjectMeta: metav1.ObjectMeta{Name: "standalone", UID: "rev3-uid"},
+			Spec:       v1.IstioRevisionSpec{Namespace: "istio-system", Version: "v2"},
+		},
+		&v1.IstioRevisionTag{
+			ObjectMeta: metav1.ObjectMeta{Name: "prod"},
+			Status:     v1.IstioRevisionTagStatus{IstioRevision: "default-v2"},
+		},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "bookinfo", Labels: map[string]string{"istio.io/rev": "prod"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "legacy", Labels: map[string]string{"istio.io/rev": "default-v1"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+		newPod("bookinfo", "productpage", nil, map[string]string{"istio.io/rev": "default-v2"}),
+		newPod("legacy", "app", nil, map[string]string{"istio.io/rev": "default-v1"}),
+		newPod("other", "labeled", map[string]string{"istio.io/rev": "default-v2"}, nil),
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objects...).WithStatusSubresource(objects...).Build()
+
+	out := &bytes.Buffer{}
+	require.NoError(t, printStatus(context.Background(), cl, out, ""))
+	expected := `Istio default (namespace istio-system, version ` + istioversion.Default + `, state Healthy)
+├── IstioRevision default-v1 (version v1, state Healthy)
+│   └── Namespace legacy (istio.io/rev=default-v1, 1 pod)
+└── IstioRevision default-v2 (version v2, state Healthy, active)
+    ├── IstioRevisionTag prod
+    │   └── Namespace bookinfo (istio.io/rev=prod)
+    ├── Namespace bookinfo (1 pod)
+    └── Namespace other (1 pod)
+IstioRevision standalone (version v2, state -, not in use)
+`
+	assert.Equal(t, expected, out.String())
+
+	out.Reset()
+	require.NoError(t, printStatus(context.Background(), cl, out, "default"))
+	assert.NotContains(t, out.String(), "standalone")
+
+	assert.ErrorContains(t, printStatus(context.Background(), cl, out, "missing"), `Istio "missing" not found`)
+}
+
+func TestPrintVersions(t *testing.T) {
+	out := &bytes.Buffer{}
+	require.NoError(t, printVersions(out))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 1+len(istioversion.List)+len(istioversion.EOL))
+	assert.Regexp(t, "^"+istioversion.Default+` .* default$`, lines[1])
+	for _, alias := range istioversion.Aliases() {
+		assert.Contains(t, out.String(), alias.Name)
+	}
+	if len(istioversion.EOL) > 0 {
+		assert.Regexp(t, "^"+istioversion.EOL[0]+` .* end-of-life$`, lines[1+len(istioversion.List)])
+	}
+}
+
+func TestDiffRevisions(t *testing.T) {
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		&v1.IstioRevision{
+			ObjectMeta: metav1.ObjectMeta{Name: "rev1"},
+			Spec: v1.IstioRevisionSpec{Namespace: "istio-system", Version: "v1", Values: &v1.Values{
+				Global: &v1.GlobalConfig{Hub: ptr.Of("docker.io/istio")},
+				Pilot:  &v1.PilotConfig{Env: map[string]string{"A": "1", "B": "2"}},
+			}},
+		},
+		&v1.IstioRevision{
+			ObjectMeta: metav1.ObjectMeta{Name: "rev2"},
+			Spec: v1.IstioRevisionSpec{Namespace: "istio-system", Version: "v2", Values: &v1.Values{
+				Global: &v1.GlobalConfig{Hub: ptr.Of("docker.io/istio")},
+				Pilot:  &v1.PilotConfig{Env: map[string]string{"A": "1"}, AutoscaleEnabled: ptr.Of(false)},
+			}},
+		},
+	).Build()
+
+	out := &bytes.Buffer{}
+	require.NoError(t, diffRevisions(context.Background(), cl, out, "rev1", "rev2"))
+	assert.Equal(t, `FIELD                                rev1      rev2
+spec.values.pilot.autoscaleEnabled   <unset>   false
+spec.values.pilot.env.B              2         <unset>
+spec.version                         v1        v2
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, diffRevisions(context.Background(), cl, out, "rev1", "rev1"))
+	assert.Equal(t, "The specs of IstioRevisions rev1 and rev1 are identical\n", out.String())
+
+	assert.ErrorContains(t, diffRevisions(context.Background(), cl, out, "rev1", "missing"), "failed to get IstioRevision missing")
+}
+
+func TestMigrateNamespaces(t *testing.T) {
+	newClient := func() client.Client {
+		return fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+			&v1.IstioRevision{ObjectMeta: metav1.ObjectMeta{Name: "canary"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "rev-label", Labels: map[string]string{"istio.io/rev": "default", "team": "a"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "injection-label", Labels: map[string]string{"istio-injection": "enabled"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "other-rev", Labels: map[string]string{"istio.io/rev": "other"}}},
+		).Build()
+	}
+	namespaceLabels := func(cl client.Client) map[string]map[string]string {
+		nsList := corev1.NamespaceList{}
+		require.NoError(t, cl.List(context.Background(), &nsList))
+		result := map[string]map[string]string{}
+		for _, ns := range nsList.Items {
+			result[ns.Name] = ns.Labels
+		}
+		return result
+	}
+
+	t.Run("migrate", func(t *testing.T) {
+		cl := newClient()
+		out := &bytes.Buffer{}
+		require.NoError(t, migrateNamespaces(context.Background(), cl, out, migrateNamespacesOptions{from: "default", to: "canary"}))
+		assert.Equal(t, map[string]map[string]string{
+			"rev-label":       {"istio.io/rev": "canary", "team": "a"},
+			"injection-label": {"istio.io/rev": "canary"},
+			"other-rev":       {"istio.io/rev": "other"},
+		}, namespaceLabels(cl))
+		assert.Contains(t, out.String(), "namespace/rev-label migrated from default to canary")
+	})
+
+	t.Run("selector", func(t *testing.T) {
+		cl := newClient()
+		opts := migrateNamespacesOptions{from: "default", to: "canary", selector: "team=a"}
+		require.NoError(t, migrateNamespaces(context.Background(), cl, &bytes.Buffer{}, opts))
+		assert.Equal(t, map[string]string{"istio-injection": "enabled"}, namespaceLabels(cl)["injection-label"])
+		assert.Equal(t, map[string]string{"istio.io/rev": "canary", "team": "a"}, namespaceLabels(cl)["rev-label"])
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		cl := newClient()
+		out := &bytes.Buffer{}
+		require.NoError(t, migrateNamespaces(context.Background(), cl, out, migrateNamespacesOptions{from: "default", to: "canary", dryRun: true}))
+		assert.Equal(t, map[string]string{"istio.io/rev": "default", "team": "a"}, namespaceLabels(cl)["rev-label"])
+		assert.Contains(t, out.String(), "namespace/rev-label would be migrated from default to canary (dry run)")
+	})
+
+	t.Run("unknown target", func(t *testing.T) {
+		err := migrateNamespaces(context.Background(), newClient(), &bytes.Buffer{}, migrateNamespacesOptions{from: "default", to: "missing"})
+		assert.ErrorContains(t, err, `neither an IstioRevision nor an IstioRevisionTag named "missing" exists`)
+	})
+}
+
+func newPod(namespace, name string, labels, annotations map[string]string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels, Annotations: annotations}}
+}
@@ -0,0 +1,255 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/revision"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newStatusCommand(newClient func() (client.Client, error)) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status [ISTIO_NAME]",
+		Short: "Show the Istio resources, their revisions and tags, and the namespaces and pods that use them",
+		Long: "Show a tree of the Istio resources, their IstioRevisions and IstioRevisionTags, and the namespaces and pods\n" +
+			"that use each revision or tag. A revision is in use under the same rules that the operator applies to\n" +
+			"the InUse condition of the IstioRevision. IstioRevisions that don't belong to an Istio resource are listed\n" +
+			"separately.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cl, err := newClient()
+			if err != nil {
+				return err
+			}
+			istioName := ""
+			if len(args) > 0 {
+				istioName = args[0]
+			}
+			return printStatus(cmd.Context(), cl, cmd.OutOrStdout(), istioName)
+		},
+	}
+}
+
+// meshSnapshot contains all the objects that are needed to show the status of the meshes.
+type meshSnapshot struct {
+	istios     []v1.Istio
+	revisions  []v1.IstioRevision
+	tags       []v1.IstioRevisionTag
+	namespaces []corev1.Namespace
+	pods       []corev1.Pod
+}
+
+func loadMeshSnapshot(ctx context.Context, cl client.Client) (meshSnapshot, error) {
+	istioList := v1.IstioList{}
+	if err := cl.List(ctx, &istioList); err != nil {
+		return meshSnapshot{}, fmt.Errorf("failed to list Istios: %w", err)
+	}
+	revList := v1.IstioRevisionList{}
+	if err := cl.List(ctx, &revList); err != nil {
+		return meshSnapshot{}, fmt.Errorf("failed to list IstioRevisions: %w", err)
+	}
+	tagList := v1.IstioRevisionTagList{}
+	if err := cl.List(ctx, &tagList); err != nil {
+		return meshSnapshot{}, fmt.Errorf("failed to list IstioRevisionTags: %w", err)
+	}
+	nsList := corev1.NamespaceList{}
+	if err := cl.List(ctx, &nsList); err != nil {
+		return meshSnapshot{}, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	podList := corev1.PodList{}
+	if err := cl.List(ctx, &podList); err != nil {
+		return meshSnapshot{}, fmt.Errorf("failed to list pods: %w", err)
+	}
+	return meshSnapshot{
+		istios:     istioList.Items,
+		revisions:  revList.Items,
+		tags:       tagList.Items,
+		namespaces: nsList.Items,
+		pods:       podList.Items,
+	}, nil
+}
+
+func printStatus(ctx context.Context, cl client.Client, out io.Writer, istioName string) error {
+	snapshot, err := loadMeshSnapshot(ctx, cl)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	ownedRevisions := map[types.UID]bool{}
+	for _, istio := range snapshot.istios {
+		var revisions []v1.IstioRevision
+		for _, rev := range snapshot.revisions {
+			if isOwnedBy(rev, istio.UID) {
+				revisions = append(revisions, rev)
+				ownedRevisions[rev.UID] = true
+			}
+		}
+		if istioName != "" && istio.Name != istioName {
+			continue
+		}
+		found = true
+
+		root := &treeNode{label: fmt.Sprintf("Istio %s (namespace %s, version %s, state %s)",
+			istio.Name, istio.Spec.Namespace, istio.Spec.Version, orNone(string(istio.Status.State)))}
+		for _, rev := range revisions {
+			root.children = append(root.children, newRevisionNode(snapshot, rev, rev.Name == istio.Status.ActiveRevisionName))
+		}
+		root.print(out)
+	}
+	if istioName != "" {
+		if !found {
+			return fmt.Errorf("Istio %q not found", istioName)
+		}
+		return nil
+	}
+
+	for _, rev := range snapshot.revisions {
+		if !ownedRevisions[rev.UID] {
+			newRevisionNode(snapshot, rev, false).print(out)
+		}
+	}
+	if len(snapshot.istios) == 0 && len(snapshot.revisions) == 0 {
+		fmt.Fprintln(out, "No Istio or IstioRevision resources found")
+	}
+	return nil
+}
+
+// newRevisionNode returns a tree node for the revision, with children for the tags that point to it and the
+// namespaces that use it.
+func newRevisionNode(snapshot meshSnapshot, rev v1.IstioRevision, active bool) *treeNode {
+	refs := revision.FindReferencesIn(&rev, snapshot.tags, snapshot.namespaces, snapshot.pods)
+
+	details := []string{"version " + rev.Spec.Version, "state " + orNone(string(rev.Status.State))}
+	if active {
+		details = append(details, "active")
+	}
+	if !refs.InUse() {
+		details = append(details, "not in use")
+	} else if refs.EnabledByDefault {
+		details = append(details, "injects all namespaces by default")
+	}
+	node := &treeNode{label: fmt.Sprintf("IstioRevision %s (%s)", rev.Name, strings.Join(details, ", "))}
+
+	for _, tagName := range refs.Tags {
+		tagNode := node.add("IstioRevisionTag " + tagName)
+		addWorkloadNodes(tagNode, snapshot.namespaces, tagName, tagReferences(snapshot, tagName))
+	}
+	addWorkloadNodes(node, snapshot.namespaces, rev.Name, refs)
+	return node
+}
+
+// tagReferences returns the namespaces and pods that reference the given IstioRevisionTag.
+func tagReferences(snapshot meshSnapshot, tagName string) revision.References {
+	refs := revision.References{}
+	nsMap := map[string]corev1.Namespace{}
+	for _, ns := range snapshot.namespaces {
+		if revision.NamespaceReferencesRevision(ns, tagName) {
+			refs.Namespaces = append(refs.Namespaces, ns.Name)
+		}
+		nsMap[ns.Name] = ns
+	}
+	for _, pod := range snapshot.pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if ns, found := nsMap[pod.Namespace]; found && revision.PodReferencesRevision(pod, ns, tagName) {
+			refs.Pods = append(refs.Pods, types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name})
+		}
+	}
+	return refs
+}
+
+// addWorkloadNodes adds a node for each namespace that references the revision or tag with the given name,
+// either through its labels or because it contains pods that reference it.
+func addWorkloadNodes(parent *treeNode, namespaces []corev1.Namespace, name string, refs revision.References) {
+	podCounts := map[string]int{}
+	for _, pod := range refs.Pods {
+		podCounts[pod.Namespace]++
+	}
+	for _, ns := range namespaces {
+		labeled := slices.Contains(refs.Namespaces, ns.Name)
+		pods := podCounts[ns.Name]
+		if !labeled && pods == 0 {
+			continue
+		}
+
+		var details []string
+		if labeled {
+			if ns.Labels[constants.IstioInjectionLabel] == constants.IstioInjectionEnabledValue {
+				details = append(details, constants.IstioInjectionLabel+"="+constants.IstioInjectionEnabledValue)
+			} else {
+				details = append(details, constants.IstioRevLabel+"="+name)
+			}
+		}
+		if pods > 0 {
+			details = append(details, pluralize(pods, "pod"))
+		}
+		parent.add(fmt.Sprintf("Namespace %s (%s)", ns.Name, strings.Join(details, ", ")))
+	}
+}
+
+func isOwnedBy(rev v1.IstioRevision, ownerUID types.UID) bool {
+	return slices.ContainsFunc(rev.OwnerReferences, func(owner metav1.OwnerReference) bool {
+		return owner.UID == ownerUID
+	})
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// treeNode is a node in a tree that is printed with box-drawing characters.
+type treeNode struct {
+	label    string
+	children []*treeNode
+}
+
+func (n *treeNode) add(label string) *treeNode {
+	child := &treeNode{label: label}
+	n.children = append(n.children, child)
+	return child
+}
+
+func (n *treeNode) print(out io.Writer) {
+	fmt.Fprintln(out, n.label)
+	n.printChildren(out, "")
+}
+
+func (n *treeNode) printChildren(out io.Writer, prefix string) {
+	for i, child := range n.children {
+		branch, indent := "├── ", "│   "
+		if i == len(n.children)-1 {
+			branch, indent = "└── ", "    "
+		}
+		fmt.Fprintln(out, prefix+branch+child.label)
+		child.printChildren(out, prefix+indent)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
+	"github.com/spf13/cobra"
+)
+
+func newVersionsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "versions",
+		Short: "List the Istio versions supported by this release of the Sail Operator",
+		Long: "List the Istio versions supported by this release of the Sail Operator, together with their aliases.\n" +
+			"End-of-life versions are listed too, but can no longer be installed. This command doesn't connect to a cluster,\n" +
+			"so versions added by IstioChartSources or an IstioVersionCatalog aren't listed.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return printVersions(cmd.OutOrStdout())
+		},
+	}
+}
+
+func printVersions(out io.Writer) error {
+	aliases := map[string][]string{}
+	for _, alias := range istioversion.Aliases() {
+		aliases[alias.Ref] = append(aliases[alias.Ref], alias.Name)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tISTIO VERSION\tALIASES\tSTATUS")
+	for _, v := range istioversion.List {
+		status := "supported"
+		if v.Name == istioversion.Default {
+			status = "default"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.Name, v.Version, orNone(strings.Join(aliases[v.Name], ", ")), status)
+	}
+	for _, name := range istioversion.EOL {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, "-", "-", "end-of-life")
+	}
+	return w.Flush()
+}
+
+// orNone returns "-" for empty strings, so that the columns of a table stay aligned.
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,287 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/go-logr/logr"
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"github.com/istio-ecosystem/sail-operator/pkg/chartsource"
+	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/enqueuelogger"
+	"github.com/istio-ecosystem/sail-operator/pkg/errlist"
+	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// requiredCharts are the charts that every version must provide, since they are needed to install an Istio control plane
+var requiredCharts = []string{constants.BaseChartName, "istiod", "revisiontags"}
+
+// Reconciler reconciles IstioChartSource objects. It fetches the charts and profiles of the versions declared in
+// the IstioChartSource, verifies and caches them, and registers the versions in the chart source registry, which
+// makes them available to the other reconcilers.
+type Reconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Config config.ReconcilerConfig
+	Cache  *chartsource.Cache
+	// SecretReader reads the credential Secrets. It shouldn't be backed by the cache, so that the operator
+	// doesn't need to watch all Secrets in the cluster.
+	SecretReader client.Reader
+}
+
+func NewReconciler(
+	reconcilerCfg config.ReconcilerConfig, client client.Client, secretReader client.Reader, scheme *runtime.Scheme, cache *chartsource.Cache,
+) *Reconciler {
+	return &Reconciler{
+		Client:       client,
+		Scheme:       scheme,
+		Config:       reconcilerCfg,
+		Cache:        cache,
+		SecretReader: secretReader,
+	}
+}
+
+// +kubebuilder:rbac:groups=sailoperator.io,resources=istiochartsources,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=sailoperator.io,resources=istiochartsources/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=sailoperator.io,resources=istiochartsources/finalizers,verbs=update
+
+// Reconcile fetches the versions declared in the IstioChartSource and registers the ones that were fetched and
+// verified successfully.
+func (r *Reconciler) Reconcile(ctx context.Context, src *v1alpha1.IstioChartSource) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	reconcileErr := r.doReconcile(ctx, src)
+
+	log.Info("Reconciliation done. Updating status.")
+	status := r.determineStatus(src, reconcileErr)
+	statusErr := reconciler.UpdateStatus(ctx, r.Client, src, src.Status, status, nil)
+
+	return ctrl.Result{}, errors.Join(reconcileErr, statusErr)
+}
+
+// Finalize unregisters the versions provided by the IstioChartSource.
+func (r *Reconciler) Finalize(_ context.Context, src *v1alpha1.IstioChartSource) error {
+	r.Config.ChartSources.Remove(src.Name)
+	return nil
+}
+
+func (r *Reconciler) doReconcile(ctx context.Context, src *v1alpha1.IstioChartSource) error {
+	if err := r.validate(src); err != nil {
+		r.Config.ChartSources.Remove(src.Name)
+		return err
+	}
+
+	creds, err := r.getCredentials(ctx, src)
+	if err != nil {
+		return err
+	}
+	fetcher, err := chartsource.NewFetcher(src.Spec, creds)
+	if err != nil {
+		return err
+	}
+
+	var versions []chartsource.Version
+	var errs errlist.Builder
+	for _, sv := range src.Spec.Versions {
+		version, err := r.fetchVersion(ctx, fetcher, sv)
+		if err != nil {
+			errs.Add(fmt.Errorf("version %s: %w", sv.Name, err))
+			continue
+		}
+		versions = append(versions, version)
+	}
+
+	// the versions that were fetched successfully are made available even if other versions failed
+	errs.Add(r.Config.ChartSources.Set(src.Name, versions))
+	return errs.Error()
+}
+
+func (r *Reconciler) validate(src *v1alpha1.IstioChartSource) error {
+	for _, sv := range src.Spec.Versions {
+		if istioversion.IsEmbeddedVersion(sv.Name) {
+			return reconciler.NewValidationError(fmt.Sprintf("version %s is embedded in the operator", sv.Name))
+		}
+		if owner := r.Config.ChartSources.Owner(sv.Name); owner != "" && owner != src.Name {
+			return reconciler.NewValidationError(fmt.Sprintf("version %s is already provided by IstioChartSource %q", sv.Name, owner))
+		}
+		for _, name := range requiredCharts {
+			if !hasChart(sv, name) {
+				return reconciler.NewValidationError(fmt.Sprintf("version %s is missing the %s chart", sv.Name, name))
+			}
+		}
+		if sv.Profiles == nil {
+			if _, err := getProfilesFrom(sv); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func hasChart(sv v1alpha1.ChartSourceVersion, name string) bool {
+	for _, chart := range sv.Charts {
+		if chart.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// getProfilesFrom returns the embedded version whose profiles are used for the given version.
+func getProfilesFrom(sv v1alpha1.ChartSourceVersion) (string, error) {
+	if sv.ProfilesFrom != "" {
+		if !istioversion.IsEmbeddedVersion(sv.ProfilesFrom) {
+			return "", reconciler.NewValidationError(fmt.Sprintf("profilesFrom of version %s: %s is not embedded in the operator", sv.Name, sv.ProfilesFrom))
+		}
+		return istioversion.Resolve(sv.ProfilesFrom)
+	}
+
+	version, err := semver.NewVersion(strings.TrimPrefix(sv.Name, "v"))
+	if err != nil {
+		return "", reconciler.NewValidationError(fmt.Sprintf("invalid version %s: %s", sv.Name, err))
+	}
+	// istioversion.List is ordered from the newest to the oldest version
+	for _, embedded := range istioversion.List {
+		if embedded.Version != nil && embedded.Version.Major() == version.Major() && embedded.Version.Minor() == version.Minor() {
+			return embedded.Name, nil
+		}
+	}
+	return "", reconciler.NewValidationError(fmt.Sprintf(
+		"no embedded version matches %d.%d; set profiles or profilesFrom for version %s", version.Major(), version.Minor(), sv.Name))
+}
+
+func (r *Reconciler) getCredentials(ctx context.Context, src *v1alpha1.IstioChartSource) (*chartsource.Credentials, error) {
+	if src.Spec.CredentialsSecretRef == nil {
+		return nil, nil
+	}
+
+	secret := corev1.Secret{}
+	key := types.NamespacedName{Namespace: r.Config.OperatorNamespace, Name: src.Spec.CredentialsSecretRef.Name}
+	if err := r.SecretReader.Get(ctx, key, &secret); err != nil {
+		return nil, fmt.Errorf("failed to get credentials Secret %s: %w", key, err)
+	}
+	return &chartsource.Credentials{
+		Username: string(secret.Data["username"]),
+		Password: string(secret.Data["password"]),
+	}, nil
+}
+
+func (r *Reconciler) fetchVersion(ctx context.Context, fetcher chartsource.Fetcher, sv v1alpha1.ChartSourceVersion) (chartsource.Version, error) {
+	log := logf.FromContext(ctx)
+	defaultChartVersion := strings.TrimPrefix(sv.Name, "v")
+	version := chartsource.Version{Name: sv.Name, Charts: map[string]string{}}
+
+	for _, chart := range sv.Charts {
+		chartVersion := chart.Version
+		if chartVersion == "" {
+			chartVersion = defaultChartVersion
+		}
+		dir, err := r.Cache.Get(chart.Digest, chartsource.ChartArchive, func() ([]byte, error) {
+			log.Info("Fetching chart", "chart", chart.Name, "version", chartVersion)
+			return fetcher.Fetch(ctx, chart.Name, chartVersion)
+		})
+		if err != nil {
+			return version, fmt.Errorf("chart %s: %w", chart.Name, err)
+		}
+		version.Charts[chart.Name] = dir
+	}
+
+	if sv.Profiles != nil {
+		dir, err := r.Cache.Get(sv.Profiles.Digest, chartsource.ProfilesArchive, func() ([]byte, error) {
+			log.Info("Fetching profiles", "version", defaultChartVersion)
+			return fetcher.Fetch(ctx, "profiles", defaultChartVersion)
+		})
+		if err != nil {
+			return version, fmt.Errorf("profiles: %w", err)
+		}
+		version.ProfilesDir = dir
+	} else {
+		profilesFrom, err := getProfilesFrom(sv)
+		if err != nil {
+			return version, err
+		}
+		version.ProfilesFrom = profilesFrom
+	}
+	return version, nil
+}
+
+func (r *Reconciler) determineStatus(src *v1alpha1.IstioChartSource, reconcileErr error) v1alpha1.IstioChartSourceStatus {
+	status := *src.Status.DeepCopy()
+	status.ObservedGeneration = src.Generation
+	status.AvailableVersions = r.Config.ChartSources.Versions(src.Name)
+
+	c := v1.StatusCondition{Type: v1.ConditionType(v1alpha1.IstioChartSourceConditionReady)}
+	switch {
+	case reconcileErr == nil:
+		c.Status = metav1.ConditionTrue
+		c.Reason = v1.ConditionReason(v1alpha1.IstioChartSourceConditionReady)
+	case reconciler.IsValidationError(reconcileErr):
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1.ConditionReason(v1alpha1.IstioChartSourceReasonInvalidSpec)
+		c.Message = reconcileErr.Error()
+	case chartsource.IsVerificationError(reconcileErr):
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1.ConditionReason(v1alpha1.IstioChartSourceReasonVerificationFailed)
+		c.Message = reconcileErr.Error()
+	default:
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1.ConditionReason(v1alpha1.IstioChartSourceReasonFetchFailed)
+		c.Message = reconcileErr.Error()
+	}
+	status.SetCondition(c)
+	return status
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	logger := mgr.GetLogger().WithName("ctrlr").WithName("istiochartsource")
+
+	// mainObjectHandler handles the IstioChartSource watch events
+	mainObjectHandler := enqueuelogger.WrapIfNecessary(v1alpha1.IstioChartSourceKind, logger, &handler.EnqueueRequestForObject{})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{
+			LogConstructor: func(req *reconcile.Request) logr.Logger {
+				log := logger
+				if req != nil {
+					log = log.WithValues(v1alpha1.IstioChartSourceKind, req.Name)
+				}
+				return log
+			},
+			MaxConcurrentReconciles: r.Config.MaxConcurrentReconciles,
+		}).
+		// we use the Watches function instead of For(), so that we can wrap the handler so that events that cause the object to be enqueued are logged
+		Watches(&v1alpha1.IstioChartSource{}, mainObjectHandler).
+		Named("istiochartsource").
+		Complete(reconciler.NewStandardReconcilerWithFinalizer[*v1alpha1.IstioChartSource](r.Client, r.Reconcile, r.Finalize, constants.FinalizerName))
+}
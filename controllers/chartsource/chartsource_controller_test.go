@@ -0,0 +1,208 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsource
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"github.com/istio-ecosystem/sail-operator/pkg/chartsource"
+	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var ctx = context.Background()
+
+// chartArchive returns a minimal chart archive for the named chart and its digest
+func chartArchive(t *testing.T, name string) ([]byte, string) {
+	t.Helper()
+	chartYAML := fmt.Sprintf("apiVersion: v2\nname: %s\nversion: 1.30.99\n", name)
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: name + "/Chart.yaml", Mode: 0o644, Size: int64(len(chartYAML))}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte(chartYAML)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return buf.Bytes(), "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func TestReconcile(t *testing.T) {
+	archives := map[string][]byte{}
+	digests := map[string]string{}
+	for _, name := range []string{"base", "istiod", "revisiontags"} {
+		archives[name], digests[name] = chartArchive(t, name)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name, found := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/"), "-1.30.99.tgz")
+		if user, pass, _ := r.BasicAuth(); !found || archives[name] == nil || user != "user" || pass != "secret" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(archives[name])
+	}))
+	defer server.Close()
+
+	charts := func(names ...string) []v1alpha1.ChartArtifact {
+		var result []v1alpha1.ChartArtifact
+		for _, name := range names {
+			result = append(result, v1alpha1.ChartArtifact{Name: name, ArtifactDigest: v1alpha1.ArtifactDigest{Digest: digests[name]}})
+		}
+		return result
+	}
+	_, otherDigest := chartArchive(t, "other")
+
+	tests := []struct {
+		name              string
+		versions          []v1alpha1.ChartSourceVersion
+		url               string
+		expectedStatus    metav1.ConditionStatus
+		expectedReason    v1alpha1.IstioChartSourceConditionReason
+		expectedAvailable []string
+	}{
+		{
+			name:              "ready",
+			versions:          []v1alpha1.ChartSourceVersion{{Name: "v1.30.99", Charts: charts("base", "istiod", "revisiontags")}},
+			expectedStatus:    metav1.ConditionTrue,
+			expectedReason:    v1alpha1.IstioChartSourceConditionReason(v1alpha1.IstioChartSourceConditionReady),
+			expectedAvailable: []string{"v1.30.99"},
+		},
+		{
+			name:           "embedded version",
+			versions:       []v1alpha1.ChartSourceVersion{{Name: istioversion.List[0].Name, Charts: charts("base", "istiod", "revisiontags")}},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1alpha1.IstioChartSourceReasonInvalidSpec,
+		},
+		{
+			name:           "missing required chart",
+			versions:       []v1alpha1.ChartSourceVersion{{Name: "v1.30.99", Charts: charts("base", "istiod")}},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1alpha1.IstioChartSourceReasonInvalidSpec,
+		},
+		{
+			name:           "no embedded version to take profiles from",
+			versions:       []v1alpha1.ChartSourceVersion{{Name: "v9.0.0", Charts: charts("base", "istiod", "revisiontags")}},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1alpha1.IstioChartSourceReasonInvalidSpec,
+		},
+		{
+			name: "digest mismatch",
+			versions: []v1alpha1.ChartSourceVersion{{Name: "v1.30.99", Charts: append(charts("base", "revisiontags"),
+				v1alpha1.ChartArtifact{Name: "istiod", ArtifactDigest: v1alpha1.ArtifactDigest{Digest: otherDigest}})}},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1alpha1.IstioChartSourceReasonVerificationFailed,
+		},
+		{
+			name: "fetch failure keeps other versions available",
+			versions: []v1alpha1.ChartSourceVersion{
+				{Name: "v1.30.99", Charts: charts("base", "istiod", "revisiontags")},
+				{Name: "v1.30.98", Charts: append(charts("base", "revisiontags"),
+					v1alpha1.ChartArtifact{Name: "istiod", Version: "1.30.98", ArtifactDigest: v1alpha1.ArtifactDigest{Digest: otherDigest}})},
+			},
+			expectedStatus:    metav1.ConditionFalse,
+			expectedReason:    v1alpha1.IstioChartSourceReasonFetchFailed,
+			expectedAvailable: []string{"v1.30.99"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			src := &v1alpha1.IstioChartSource{
+				ObjectMeta: metav1.ObjectMeta{Name: "charts", Generation: 2},
+				Spec: v1alpha1.IstioChartSourceSpec{
+					Type:                 v1alpha1.ChartSourceTypeHTTP,
+					URL:                  server.URL,
+					CredentialsSecretRef: &v1alpha1.LocalObjectReference{Name: "creds"},
+					Versions:             tc.versions,
+				},
+			}
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "sail-operator"},
+				Data:       map[string][]byte{"username": []byte("user"), "password": []byte("secret")},
+			}
+
+			cl := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(src, secret).
+				WithStatusSubresource(&v1alpha1.IstioChartSource{}).
+				Build()
+			registry := chartsource.NewRegistry(fstest.MapFS{})
+			cfg := config.ReconcilerConfig{OperatorNamespace: "sail-operator", ChartSources: registry}
+			r := NewReconciler(cfg, cl, cl, scheme.Scheme, chartsource.NewCache(t.TempDir()))
+			t.Cleanup(func() { registry.Remove(src.Name) })
+
+			_, err := r.Reconcile(ctx, src)
+			if tc.expectedStatus == metav1.ConditionTrue || tc.expectedReason == v1alpha1.IstioChartSourceReasonInvalidSpec {
+				g.Expect(err == nil || reconciler.IsValidationError(err)).To(BeTrue(), "unexpected error: %v", err)
+			} else {
+				g.Expect(err).To(HaveOccurred())
+			}
+
+			g.Expect(cl.Get(ctx, types.NamespacedName{Name: src.Name}, src)).To(Succeed())
+			g.Expect(src.Status.ObservedGeneration).To(Equal(int64(2)))
+			g.Expect(src.Status.AvailableVersions).To(Equal(tc.expectedAvailable))
+
+			ready := src.Status.GetCondition(v1alpha1.IstioChartSourceConditionReady)
+			g.Expect(ready.Status).To(Equal(tc.expectedStatus))
+			g.Expect(ready.Reason).To(Equal(v1.ConditionReason(tc.expectedReason)))
+
+			for _, version := range tc.expectedAvailable {
+				g.Expect(istioversion.ValidateVersion(version)).To(Succeed())
+			}
+		})
+	}
+}
+
+func TestFinalize(t *testing.T) {
+	g := NewWithT(t)
+
+	registry := chartsource.NewRegistry(fstest.MapFS{})
+	g.Expect(registry.Set("charts", []chartsource.Version{{Name: "v1.30.99", ProfilesFrom: istioversion.List[0].Name}})).To(Succeed())
+	r := NewReconciler(config.ReconcilerConfig{ChartSources: registry}, nil, nil, scheme.Scheme, nil)
+
+	src := &v1alpha1.IstioChartSource{ObjectMeta: metav1.ObjectMeta{Name: "charts"}}
+	g.Expect(r.Finalize(ctx, src)).To(Succeed())
+	g.Expect(registry.Versions("charts")).To(BeEmpty())
+	g.Expect(istioversion.ValidateVersion("v1.30.99")).ToNot(Succeed())
+}
@@ -0,0 +1,230 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/go-logr/logr"
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/enqueuelogger"
+	"github.com/istio-ecosystem/sail-operator/pkg/errlist"
+	"github.com/istio-ecosystem/sail-operator/pkg/integration"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Reconciler reconciles MetricsIntegration and TracingIntegration objects. The extension providers of the
+// integrations are added to the values of the targeted Istio objects by the Istio controller; this controller
+// resolves the target references and reports the status of the integration for each target.
+type Reconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Config config.ReconcilerConfig
+}
+
+func NewReconciler(reconcilerCfg config.ReconcilerConfig, client client.Client, scheme *runtime.Scheme) *Reconciler {
+	return &Reconciler{
+		Client: client,
+		Scheme: scheme,
+		Config: reconcilerCfg,
+	}
+}
+
+// getAcceptedFunc returns the integration that takes precedence for the given target, or nil if there is none.
+type getAcceptedFunc func(ctx context.Context, target v1alpha1.TargetReference) (client.Object, error)
+
+// +kubebuilder:rbac:groups=sailoperator.io,resources=metricsintegrations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=sailoperator.io,resources=metricsintegrations/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=sailoperator.io,resources=tracingintegrations,verbs=get;list;watch
+// +kubebuilder:rbac:groups=sailoperator.io,resources=tracingintegrations/status,verbs=get;update;patch
+
+// ReconcileMetricsIntegration updates the status of the given MetricsIntegration.
+func (r *Reconciler) ReconcileMetricsIntegration(ctx context.Context, metrics *v1alpha1.MetricsIntegration) (ctrl.Result, error) {
+	status, err := r.determineStatus(ctx, metrics, v1alpha1.MetricsIntegrationKind, metrics.Spec.TargetRefs, metrics.Status,
+		func(ctx context.Context, target v1alpha1.TargetReference) (client.Object, error) {
+			accepted, err := integration.GetMetricsIntegrationForTarget(ctx, r.Client, target)
+			if accepted == nil {
+				return nil, err
+			}
+			return accepted, err
+		})
+	return ctrl.Result{}, reconciler.UpdateStatus(ctx, r.Client, metrics, metrics.Status, status, err)
+}
+
+// ReconcileTracingIntegration updates the status of the given TracingIntegration.
+func (r *Reconciler) ReconcileTracingIntegration(ctx context.Context, tracing *v1alpha1.TracingIntegration) (ctrl.Result, error) {
+	status, err := r.determineStatus(ctx, tracing, v1alpha1.TracingIntegrationKind, tracing.Spec.TargetRefs, tracing.Status,
+		func(ctx context.Context, target v1alpha1.TargetReference) (client.Object, error) {
+			accepted, err := integration.GetTracingIntegrationForTarget(ctx, r.Client, target)
+			if accepted == nil {
+				return nil, err
+			}
+			return accepted, err
+		})
+	return ctrl.Result{}, reconciler.UpdateStatus(ctx, r.Client, tracing, tracing.Status, status, err)
+}
+
+func (r *Reconciler) determineStatus(
+	ctx context.Context, obj client.Object, kind string, targetRefs []v1alpha1.TargetReference,
+	currentStatus v1alpha1.IntegrationStatus, getAccepted getAcceptedFunc,
+) (v1alpha1.IntegrationStatus, error) {
+	var errs errlist.Builder
+	status := v1alpha1.IntegrationStatus{ObservedGeneration: obj.GetGeneration()}
+	for _, ref := range targetRefs {
+		targetStatus := v1alpha1.TargetStatus{
+			Kind:      ref.Kind,
+			Name:      ref.Name,
+			Namespace: ref.Namespace,
+		}
+		// keep the existing conditions so that their lastTransitionTime is preserved
+		for _, existing := range currentStatus.Targets {
+			if existing.Kind == ref.Kind && existing.Name == ref.Name && existing.Namespace == ref.Namespace {
+				targetStatus.Conditions = slices.Clone(existing.Conditions)
+			}
+		}
+
+		acceptedCondition, err := r.determineAcceptedCondition(ctx, obj, kind, ref, getAccepted)
+		errs.Add(err)
+		targetStatus.SetCondition(acceptedCondition)
+		status.Targets = append(status.Targets, targetStatus)
+	}
+	return status, errs.Error()
+}
+
+func (r *Reconciler) determineAcceptedCondition(
+	ctx context.Context, obj client.Object, kind string, ref v1alpha1.TargetReference, getAccepted getAcceptedFunc,
+) (v1.StatusCondition, error) {
+	c := v1.StatusCondition{Type: v1.ConditionType(v1alpha1.IntegrationConditionAccepted)}
+
+	_, err := integration.GetIstioFromTargetReference(ctx, r.Client, ref)
+	if apierrors.IsNotFound(err) {
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1.ConditionReason(v1alpha1.IntegrationReasonTargetNotFound)
+		c.Message = fmt.Sprintf("%s %q not found", ref.Kind, ref.Name)
+		return c, nil
+	} else if reconciler.IsValidationError(err) {
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1.ConditionReason(v1alpha1.IntegrationReasonUnsupportedTarget)
+		c.Message = err.Error()
+		return c, nil
+	} else if err != nil {
+		c.Status = metav1.ConditionUnknown
+		c.Reason = v1.ConditionReason(v1alpha1.IntegrationReasonResolutionFailed)
+		c.Message = fmt.Sprintf("failed to get %s %q: %s", ref.Kind, ref.Name, err)
+		return c, err
+	}
+
+	accepted, err := getAccepted(ctx, ref)
+	if err != nil {
+		c.Status = metav1.ConditionUnknown
+		c.Reason = v1.ConditionReason(v1alpha1.IntegrationReasonResolutionFailed)
+		c.Message = fmt.Sprintf("failed to determine which %s configures the target: %s", kind, err)
+		return c, err
+	}
+	if accepted != nil && accepted.GetUID() != obj.GetUID() {
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1.ConditionReason(v1alpha1.IntegrationReasonConflict)
+		c.Message = fmt.Sprintf("%s %q is already configured by %s %q", ref.Kind, ref.Name, kind, accepted.GetName())
+		return c, nil
+	}
+
+	c.Status = metav1.ConditionTrue
+	c.Reason = v1.ConditionReason(v1alpha1.IntegrationConditionAccepted)
+	return c, nil
+}
+
+// SetupWithManager sets up the MetricsIntegration and TracingIntegration controllers with the Manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	err := r.setupController(mgr, "metricsintegration", v1alpha1.MetricsIntegrationKind, &v1alpha1.MetricsIntegration{},
+		r.mapToMetricsIntegrations, reconciler.NewStandardReconciler[*v1alpha1.MetricsIntegration](r.Client, r.ReconcileMetricsIntegration))
+	if err != nil {
+		return err
+	}
+	return r.setupController(mgr, "tracingintegration", v1alpha1.TracingIntegrationKind, &v1alpha1.TracingIntegration{},
+		r.mapToTracingIntegrations, reconciler.NewStandardReconciler[*v1alpha1.TracingIntegration](r.Client, r.ReconcileTracingIntegration))
+}
+
+func (r *Reconciler) setupController(
+	mgr ctrl.Manager, name, kind string, obj client.Object, mapFunc handler.MapFunc, rec reconcile.Reconciler,
+) error {
+	logger := mgr.GetLogger().WithName("ctrlr").WithName(name)
+
+	// allIntegrationsHandler enqueues all integrations of the same kind, because the integration that takes
+	// precedence for a target changes when another integration is created or deleted, and the status of every
+	// integration referencing an Istio changes when the Istio is created or deleted
+	allIntegrationsHandler := enqueuelogger.WrapIfNecessary(kind, logger, handler.EnqueueRequestsFromMapFunc(mapFunc))
+
+	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{
+			LogConstructor: func(req *reconcile.Request) logr.Logger {
+				log := logger
+				if req != nil {
+					log = log.WithValues(kind, req.Name)
+				}
+				return log
+			},
+			MaxConcurrentReconciles: r.Config.MaxConcurrentReconciles,
+		}).
+		// we use the Watches function instead of For(), so that we can wrap the handler so that events that cause the object to be enqueued are logged
+		Watches(obj, allIntegrationsHandler).
+		Named(name).
+		Watches(&v1.Istio{}, allIntegrationsHandler, builder.WithPredicates(predicate.Funcs{
+			UpdateFunc: func(event.UpdateEvent) bool { return false },
+		})).
+		Complete(rec)
+}
+
+func (r *Reconciler) mapToMetricsIntegrations(ctx context.Context, _ client.Object) []reconcile.Request {
+	list := v1alpha1.MetricsIntegrationList{}
+	if err := r.Client.List(ctx, &list); err != nil {
+		logf.FromContext(ctx).Error(err, "failed to list MetricsIntegrations")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, item := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: item.Name}})
+	}
+	return requests
+}
+
+func (r *Reconciler) mapToTracingIntegrations(ctx context.Context, _ client.Object) []reconcile.Request {
+	list := v1alpha1.TracingIntegrationList{}
+	if err := r.Client.List(ctx, &list); err != nil {
+		logf.FromContext(ctx).Error(err, "failed to list TracingIntegrations")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, item := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: item.Name}})
+	}
+	return requests
+}
@@ -0,0 +1,149 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var ctx = context.Background()
+
+func TestReconcileMetricsIntegration(t *testing.T) {
+	older := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	newer := metav1.NewTime(time.Now().Truncate(time.Second))
+
+	tests := []struct {
+		name           string
+		target         v1alpha1.TargetReference
+		existing       []client.Object
+		expectedStatus metav1.ConditionStatus
+		expectedReason v1alpha1.IntegrationConditionReason
+	}{
+		{
+			name:           "accepted",
+			target:         v1alpha1.TargetReference{Kind: v1alpha1.IstioTargetKind, Name: "default"},
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: v1alpha1.IntegrationConditionReason(v1alpha1.IntegrationConditionAccepted),
+		},
+		{
+			name:           "istio not found",
+			target:         v1alpha1.TargetReference{Kind: v1alpha1.IstioTargetKind, Name: "missing"},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1alpha1.IntegrationReasonTargetNotFound,
+		},
+		{
+			name:           "kiali target unsupported",
+			target:         v1alpha1.TargetReference{Kind: v1alpha1.KialiTargetKind, Name: "kiali", Namespace: "istio-system"},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1alpha1.IntegrationReasonUnsupportedTarget,
+		},
+		{
+			name:   "conflict with older integration",
+			target: v1alpha1.TargetReference{Kind: v1alpha1.IstioTargetKind, Name: "default"},
+			existing: []client.Object{
+				&v1alpha1.MetricsIntegration{
+					ObjectMeta: metav1.ObjectMeta{Name: "older", UID: "older-uid", CreationTimestamp: older},
+					Spec: v1alpha1.MetricsIntegrationSpec{
+						TargetRefs:    []v1alpha1.TargetReference{{Kind: v1alpha1.IstioTargetKind, Name: "default"}},
+						MetricsConfig: v1alpha1.MetricsConfig{Type: v1alpha1.MetricsTypeUserWorkloadMonitoring},
+					},
+				},
+			},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1alpha1.IntegrationReasonConflict,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			metrics := &v1alpha1.MetricsIntegration{
+				ObjectMeta: metav1.ObjectMeta{Name: "metrics", UID: "metrics-uid", Generation: 3, CreationTimestamp: newer},
+				Spec: v1alpha1.MetricsIntegrationSpec{
+					TargetRefs:    []v1alpha1.TargetReference{tc.target},
+					MetricsConfig: v1alpha1.MetricsConfig{Type: v1alpha1.MetricsTypeUserWorkloadMonitoring},
+				},
+			}
+			istio := &v1.Istio{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+
+			cl := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(append(tc.existing, metrics, istio)...).
+				WithStatusSubresource(&v1alpha1.MetricsIntegration{}).
+				Build()
+			r := NewReconciler(config.ReconcilerConfig{}, cl, scheme.Scheme)
+
+			_, err := r.ReconcileMetricsIntegration(ctx, metrics)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			g.Expect(cl.Get(ctx, types.NamespacedName{Name: metrics.Name}, metrics)).To(Succeed())
+			g.Expect(metrics.Status.ObservedGeneration).To(Equal(int64(3)))
+			g.Expect(metrics.Status.Targets).To(HaveLen(1))
+			g.Expect(metrics.Status.Targets[0].Name).To(Equal(tc.target.Name))
+
+			accepted := metrics.Status.Targets[0].GetCondition(v1alpha1.IntegrationConditionAccepted)
+			g.Expect(accepted.Status).To(Equal(tc.expectedStatus))
+			g.Expect(string(accepted.Reason)).To(Equal(string(tc.expectedReason)))
+		})
+	}
+}
+
+func TestReconcileTracingIntegration(t *testing.T) {
+	g := NewWithT(t)
+
+	tracing := &v1alpha1.TracingIntegration{
+		ObjectMeta: metav1.ObjectMeta{Name: "tracing", UID: "tracing-uid", Generation: 1},
+		Spec: v1alpha1.TracingIntegrationSpec{
+			TargetRefs: []v1alpha1.TargetReference{
+				{Kind: v1alpha1.IstioTargetKind, Name: "default"},
+				{Kind: v1alpha1.IstioTargetKind, Name: "missing"},
+			},
+			TracingConfig: v1alpha1.TracingConfig{
+				Type: v1alpha1.TracingTypeOpenTelemetry,
+				OpenTelemetry: &v1alpha1.OpenTelemetryConfig{
+					OTELCollectorRef: v1alpha1.NamespacedReference{Name: "otel", Namespace: "observability"},
+				},
+			},
+		},
+	}
+	istio := &v1.Istio{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+
+	cl := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(tracing, istio).
+		WithStatusSubresource(&v1alpha1.TracingIntegration{}).
+		Build()
+	r := NewReconciler(config.ReconcilerConfig{}, cl, scheme.Scheme)
+
+	_, err := r.ReconcileTracingIntegration(ctx, tracing)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(cl.Get(ctx, types.NamespacedName{Name: tracing.Name}, tracing)).To(Succeed())
+	g.Expect(tracing.Status.Targets).To(HaveLen(2))
+	g.Expect(tracing.Status.Targets[0].GetCondition(v1alpha1.IntegrationConditionAccepted).Status).To(Equal(metav1.ConditionTrue))
+	g.Expect(tracing.Status.Targets[1].GetCondition(v1alpha1.IntegrationConditionAccepted).Status).To(Equal(metav1.ConditionFalse))
+}
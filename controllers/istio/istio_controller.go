@@ -23,10 +23,16 @@ import (
 
 	"github.com/go-logr/logr"
 	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
 	"github.com/istio-ecosystem/sail-operator/pkg/config"
 	"github.com/istio-ecosystem/sail-operator/pkg/enqueuelogger"
 	"github.com/istio-ecosystem/sail-operator/pkg/errlist"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/istio-ecosystem/sail-operator/pkg/integration"
+	"github.com/istio-ecosystem/sail-operator/pkg/istiovalues"
 	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
+	"github.com/istio-ecosystem/sail-operator/pkg/meshcluster"
+	sharedreconcile "github.com/istio-ecosystem/sail-operator/pkg/reconcile"
 	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
 	"github.com/istio-ecosystem/sail-operator/pkg/revision"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -43,24 +49,42 @@ import (
 	"istio.io/istio/pkg/ptr"
 )
 
+// workloadUpdateRequeueInterval defines how often the progress of moving workloads to the active revision is checked.
+const workloadUpdateRequeueInterval = 10 * time.Second
+
 // Reconciler reconciles an Istio object
 type Reconciler struct {
 	Config config.ReconcilerConfig
 	client.Client
 	Scheme *runtime.Scheme
+
+	// APIReader reads the pods whose status is needed when moving workloads between revisions, since the
+	// operator only caches the metadata of pods
+	APIReader client.Reader
+
+	// ChartManager is only used to compute the plan in dry-run mode; the Helm charts themselves are
+	// installed by the IstioRevision and IstioRevisionTag controllers.
+	ChartManager helm.ChartReconciler
 }
 
-func NewReconciler(cfg config.ReconcilerConfig, client client.Client, scheme *runtime.Scheme) *Reconciler {
+func NewReconciler(
+	cfg config.ReconcilerConfig, client client.Client, apiReader client.Reader, scheme *runtime.Scheme, chartManager helm.ChartReconciler,
+) *Reconciler {
 	return &Reconciler{
-		Config: cfg,
-		Client: client,
-		Scheme: scheme,
+		Config:       cfg,
+		Client:       client,
+		Scheme:       scheme,
+		APIReader:    apiReader,
+		ChartManager: chartManager,
 	}
 }
 
 // +kubebuilder:rbac:groups=sailoperator.io,resources=istios,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=sailoperator.io,resources=istios/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=sailoperator.io,resources=istios/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources=namespaces;pods,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups="apps",resources=deployments;daemonsets;statefulsets,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups="apps",resources=replicasets,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -71,32 +95,111 @@ func (r *Reconciler) Reconcile(ctx context.Context, istio *v1.Istio) (ctrl.Resul
 	log := logf.FromContext(ctx)
 
 	log.Info("Reconciling")
-	result, reconcileErr := r.doReconcile(ctx, istio)
+	result, outcome, reconcileErr := r.doReconcile(ctx, istio)
 
 	log.Info("Reconciliation done. Updating status.")
-	statusErr := r.updateStatus(ctx, istio, reconcileErr)
+	statusErr := r.updateStatus(ctx, istio, outcome, reconcileErr)
 
 	return result, errors.Join(reconcileErr, statusErr)
 }
 
+// reconcileOutcome holds the information determined during reconciliation that is reported in the status.
+type reconcileOutcome struct {
+	// workloadUpdate is nil when workloads weren't updated during the reconciliation.
+	workloadUpdate *revision.WorkloadUpdateProgress
+
+	// promotion is nil when the promotion of the revision wasn't evaluated during the reconciliation.
+	promotion *promotionOutcome
+
+	// plan is only set in dry-run mode.
+	plan *v1.IstioRevisionPlan
+}
+
 // doReconcile is the function that actually reconciles the Istio object. Any error reported by this
 // function should get reported in the status of the Istio object by the caller.
-func (r *Reconciler) doReconcile(ctx context.Context, istio *v1.Istio) (result ctrl.Result, err error) {
+func (r *Reconciler) doReconcile(ctx context.Context, istio *v1.Istio) (ctrl.Result, reconcileOutcome, error) {
+	var outcome reconcileOutcome
 	if err := validate(istio); err != nil {
-		return ctrl.Result{}, err
+		return ctrl.Result{}, outcome, err
 	}
 
-	if err = r.reconcileActiveRevision(ctx, istio); err != nil {
-		return ctrl.Result{}, err
+	// in dry-run mode, the IstioRevision isn't created or updated, since the other controllers would install
+	// its new spec; the changes are only planned and reported in the status. The revisions must not be
+	// promoted or pruned and the workloads must not be moved to the new revision either.
+	if revision.IsDryRun(istio) {
+		// a revision that was rolled back wouldn't be updated either, so there's nothing to plan
+		plan := &v1.IstioRevisionPlan{}
+		if !isRolledBack(istio) {
+			var err error
+			if plan, err = r.planDesiredRevision(ctx, istio); err != nil {
+				return ctrl.Result{}, outcome, err
+			}
+		}
+		outcome.plan = plan
+		return ctrl.Result{}, outcome, nil
+	}
+
+	// a revision that was rolled back isn't updated until spec.version changes
+	if !isRolledBack(istio) {
+		if err := r.reconcileDesiredRevision(ctx, istio); err != nil {
+			return ctrl.Result{}, outcome, err
+		}
+	}
+
+	var result ctrl.Result
+	activeRevisionName := getDesiredRevisionName(istio)
+	var canary *revision.Canary
+	var retainedRevisionNames []string
+	if usesPromotion(istio) {
+		promotion, err := r.reconcilePromotion(ctx, istio)
+		if err != nil {
+			return ctrl.Result{}, outcome, fmt.Errorf("failed to promote revision: %w", err)
+		}
+		outcome.promotion = promotion
+		activeRevisionName = promotion.activeRevisionName
+		canary = promotion.canary
+		retainedRevisionNames = promotion.retainedRevisionNames
+		requeueAfter(&result, promotion.requeueAfter)
+	}
+
+	if updatesWorkloads(istio) {
+		progress, err := revision.UpdateWorkloads(ctx, r.Client, r.APIReader, istio.UID, activeRevisionName, canary)
+		if err != nil {
+			return ctrl.Result{}, outcome, fmt.Errorf("failed to update workloads: %w", err)
+		}
+		outcome.workloadUpdate = &progress
+		if !progress.Done() {
+			// rollouts of restarted workloads don't trigger a reconciliation of the Istio object,
+			// so we need to check their progress periodically
+			requeueAfter(&result, workloadUpdateRequeueInterval)
+		}
 	}
 
 	// We cannot prune revisions that manage an external cluster because the operator currently
 	// has no way of knowing if the revision is still in use on the external cluster.
 	if !managesExternalRevision(istio) {
-		return revision.PruneInactive(ctx, r.Client, istio.UID, getActiveRevisionName(istio), getPruningGracePeriod(istio))
+		pruneResult, err := revision.PruneInactive(ctx, r.Client, istio.UID, activeRevisionName, getPruningGracePeriod(istio), retainedRevisionNames...)
+		if err != nil {
+			return pruneResult, outcome, err
+		}
+		requeueAfter(&result, pruneResult.RequeueAfter)
+	}
+
+	return result, outcome, nil
+}
+
+// requeueAfter sets result.RequeueAfter to the specified duration, unless the result is already
+// requeued sooner. A zero duration is ignored.
+func requeueAfter(result *ctrl.Result, after time.Duration) {
+	if after > 0 && (result.RequeueAfter == 0 || after < result.RequeueAfter) {
+		result.RequeueAfter = after
 	}
+}
 
-	return result, err
+// updatesWorkloads returns true if the operator should move the workloads to the active revision.
+func updatesWorkloads(istio *v1.Istio) bool {
+	strategy := istio.Spec.UpdateStrategy
+	return strategy != nil && strategy.Type == v1.UpdateStrategyTypeRevisionBased && strategy.UpdateWorkloads
 }
 
 func managesExternalRevision(istio *v1.Istio) bool {
@@ -115,37 +218,155 @@ func validate(istio *v1.Istio) error {
 	if istio.Spec.Namespace == "" {
 		return reconciler.NewValidationError("spec.namespace not set")
 	}
-	return nil
+	return sharedreconcile.ValidatePatches(istio.Spec.Patches)
+}
+
+// reconcileDesiredRevision creates or updates the revision for the current spec.version.
+func (r *Reconciler) reconcileDesiredRevision(ctx context.Context, istio *v1.Istio) error {
+	version, values, err := r.computeRevisionValues(ctx, istio)
+	if err != nil {
+		return err
+	}
+
+	return revision.CreateOrUpdate(ctx, r.Client,
+		getDesiredRevisionName(istio),
+		version, istio.Spec.Namespace, values, istio.Spec.DriftPolicy, istio.Spec.Patches, istio.Spec.Dependencies,
+		metav1.OwnerReference{
+			APIVersion:         v1.GroupVersion.String(),
+			Kind:               v1.IstioKind,
+			Name:               istio.Name,
+			UID:                istio.UID,
+			Controller:         ptr.Of(true),
+			BlockOwnerDeletion: ptr.Of(true),
+		})
 }
 
-func (r *Reconciler) reconcileActiveRevision(ctx context.Context, istio *v1.Istio) error {
+// computeRevisionValues resolves the version and computes the values of the revision for the current spec.version.
+func (r *Reconciler) computeRevisionValues(ctx context.Context, istio *v1.Istio) (string, *v1.Values, error) {
 	version, err := istioversion.Resolve(istio.Spec.Version)
 	if err != nil {
 		if istioversion.IsEOLVersion(istio.Spec.Version) {
-			return reconciler.NewValidationError(fmt.Sprintf("version %q is end-of-life and cannot be installed; use a supported version", istio.Spec.Version))
+			return "", nil, reconciler.NewValidationError(
+				fmt.Sprintf("version %q is end-of-life and cannot be installed; use a supported version", istio.Spec.Version))
 		}
-		return fmt.Errorf("failed to resolve Istio version for %q: %w", istio.Name, err)
+		return "", nil, fmt.Errorf("failed to resolve Istio version for %q: %w", istio.Name, err)
 	}
 
 	values, err := revision.ComputeValues(
 		istio.Spec.Values, istio.Spec.Namespace, version,
 		r.Config.Platform, r.Config.DefaultProfile, istio.Spec.Profile,
-		r.Config.ResourceFS, getActiveRevisionName(istio), r.Config.TLSConfig)
+		r.Config.ResourceFS, getDesiredRevisionName(istio), r.Config.TLSConfig)
 	if err != nil {
-		return err
+		return "", nil, err
 	}
 
-	return revision.CreateOrUpdate(ctx, r.Client,
-		getActiveRevisionName(istio),
-		version, istio.Spec.Namespace, values,
-		metav1.OwnerReference{
+	// add the extension providers of the MetricsIntegrations and TracingIntegrations that target this Istio
+	providers, err := integration.GetExtensionProviders(ctx, r.Client, istio.Name, getClusterDomain(values))
+	if err != nil {
+		return "", nil, err
+	}
+	istiovalues.ApplyExtensionProviders(values, providers)
+
+	// set the mesh ID, cluster name and network of the MeshCluster that targets this Istio
+	mc, err := meshcluster.GetForIstio(ctx, r.Client, istio.Name)
+	if err != nil {
+		return "", nil, err
+	}
+	meshcluster.ApplyValues(values, mc)
+	return version, values, nil
+}
+
+// planDesiredRevision computes the changes that updating the revision for the current spec.version would make
+// to the Helm releases of the revision and of the IstioRevisionTags that point to it, without changing anything
+// in the cluster.
+func (r *Reconciler) planDesiredRevision(ctx context.Context, istio *v1.Istio) (*v1.IstioRevisionPlan, error) {
+	log := logf.FromContext(ctx)
+	version, values, err := r.computeRevisionValues(ctx, istio)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := sharedreconcile.Config{
+		ResourceFS:        r.Config.ResourceFS,
+		Platform:          r.Config.Platform,
+		DefaultProfile:    r.Config.DefaultProfile,
+		OperatorNamespace: r.Config.OperatorNamespace,
+		ChartManager:      r.ChartManager,
+		TLSConfig:         r.Config.TLSConfig,
+	}
+	istiodReconciler := sharedreconcile.NewIstiodReconciler(cfg, r.Client)
+	if err := istiodReconciler.Validate(ctx, version, istio.Spec.Namespace, values); err != nil {
+		return nil, err
+	}
+
+	// the objects are owned by the IstioRevision, so its UID must be used to compare them with the live
+	// releases; if the revision doesn't exist yet, all objects are reported as added anyway
+	revName := getDesiredRevisionName(istio)
+	rev := v1.IstioRevision{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: revName}, &rev); err != nil && !apierrors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get IstioRevision %q: %w", revName, err)
+	}
+	revOwnerRef := metav1.OwnerReference{
+		APIVersion:         v1.GroupVersion.String(),
+		Kind:               v1.IstioRevisionKind,
+		Name:               revName,
+		UID:                rev.UID,
+		Controller:         ptr.Of(true),
+		BlockOwnerDeletion: ptr.Of(true),
+	}
+
+	log.Info("Dry-run mode enabled. Computing the changes to the Helm releases", "IstioRevision", revName)
+	diff, err := istiodReconciler.Plan(ctx, version, istio.Spec.Namespace, values, istio.Spec.Patches, revName, &revOwnerRef)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := r.tagsForRevision(ctx, istio, revName)
+	if err != nil {
+		return nil, err
+	}
+	tagReconciler := sharedreconcile.NewRevisionTagReconciler(cfg, r.Client)
+	for _, tag := range tags {
+		tagOwnerRef := metav1.OwnerReference{
 			APIVersion:         v1.GroupVersion.String(),
-			Kind:               v1.IstioKind,
-			Name:               istio.Name,
-			UID:                istio.UID,
+			Kind:               v1.IstioRevisionTagKind,
+			Name:               tag.Name,
+			UID:                tag.UID,
 			Controller:         ptr.Of(true),
 			BlockOwnerDeletion: ptr.Of(true),
-		})
+		}
+		tagDiff, err := tagReconciler.Plan(ctx, version, istio.Spec.Namespace, tag.Name, revName, values, &tagOwnerRef)
+		if err != nil {
+			return nil, err
+		}
+		diff.Append(tagDiff)
+	}
+	return revision.NewPlan(diff), nil
+}
+
+// tagsForRevision returns the IstioRevisionTags that would point to the given revision once it's updated: the tags
+// that reference it directly and, unless the revision must first be promoted, the tags that reference the Istio.
+func (r *Reconciler) tagsForRevision(ctx context.Context, istio *v1.Istio, revName string) ([]v1.IstioRevisionTag, error) {
+	tagList := v1.IstioRevisionTagList{}
+	if err := r.Client.List(ctx, &tagList); err != nil {
+		return nil, fmt.Errorf("failed to list IstioRevisionTags: %w", err)
+	}
+	var tags []v1.IstioRevisionTag
+	for _, tag := range tagList.Items {
+		ref := tag.Spec.TargetRef
+		if (ref.Kind == v1.IstioRevisionKind && ref.Name == revName) ||
+			(ref.Kind == v1.IstioKind && ref.Name == istio.Name && !usesPromotion(istio)) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+func getClusterDomain(values *v1.Values) string {
+	if values != nil && values.Global != nil && values.Global.Proxy != nil && values.Global.Proxy.ClusterDomain != nil {
+		return *values.Global.Proxy.ClusterDomain
+	}
+	return ""
 }
 
 func getPruningGracePeriod(istio *v1.Istio) time.Duration {
@@ -160,9 +381,9 @@ func getPruningGracePeriod(istio *v1.Istio) time.Duration {
 	return time.Duration(period) * time.Second
 }
 
-func (r *Reconciler) getActiveRevision(ctx context.Context, istio *v1.Istio) (v1.IstioRevision, error) {
+func (r *Reconciler) getRevision(ctx context.Context, name string) (v1.IstioRevision, error) {
 	rev := v1.IstioRevision{}
-	err := r.Client.Get(ctx, GetActiveRevisionKey(istio), &rev)
+	err := r.Client.Get(ctx, types.NamespacedName{Name: name}, &rev)
 	if err != nil {
 		return rev, fmt.Errorf("get failed: %w", err)
 	}
@@ -175,7 +396,18 @@ func GetActiveRevisionKey(istio *v1.Istio) types.NamespacedName {
 	}
 }
 
+// getActiveRevisionName returns the name of the active revision. Without staged promotion, the revision
+// for the current spec.version becomes the active revision immediately. With staged promotion, the active
+// revision only changes when the operator promotes a revision, so it's read from the status.
 func getActiveRevisionName(istio *v1.Istio) string {
+	if usesPromotion(istio) && istio.Status.ActiveRevisionName != "" {
+		return istio.Status.ActiveRevisionName
+	}
+	return getDesiredRevisionName(istio)
+}
+
+// getDesiredRevisionName returns the name of the revision for the current spec.version.
+func getDesiredRevisionName(istio *v1.Istio) string {
 	var strategy v1.UpdateStrategyType
 	if istio.Spec.UpdateStrategy != nil {
 		strategy = istio.Spec.UpdateStrategy.Type
@@ -202,7 +434,14 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	ownedResourceHandler := wrapEventHandler(logger,
 		handler.EnqueueRequestForOwner(r.Scheme, r.RESTMapper(), &v1.Istio{}, handler.OnlyControllerOwner()))
 
-	return ctrl.NewControllerManagedBy(mgr).
+	// integrationHandler handles the MetricsIntegrations, TracingIntegrations and the MeshCluster that target the
+	// Istio CR, since they contribute to its values
+	integrationHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapIntegrationToReconcileRequests))
+
+	// versionHandler handles changes to the versions provided by IstioChartSources and the IstioVersionCatalog
+	versionHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapVersionChangeToReconcileRequests))
+
+	b := ctrl.NewControllerManagedBy(mgr).
 		WithOptions(controller.Options{
 			LogConstructor: func(req *reconcile.Request) logr.Logger {
 				log := logger
@@ -217,20 +456,64 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Watches(&v1.Istio{}, mainObjectHandler).
 		Named("istio").
 		Watches(&v1.IstioRevision{}, ownedResourceHandler).
+		Watches(&v1alpha1.MetricsIntegration{}, integrationHandler).
+		Watches(&v1alpha1.TracingIntegration{}, integrationHandler).
+		Watches(&v1alpha1.MeshCluster{}, integrationHandler)
+	b = r.Config.ChartSources.Watch(b, versionHandler)
+	return r.Config.VersionCatalog.Watch(b, versionHandler).
 		Complete(reconciler.NewStandardReconciler(r.Client, r.Reconcile))
 }
 
-func (r *Reconciler) determineStatus(ctx context.Context, istio *v1.Istio, reconcileErr error) (v1.IstioStatus, error) {
+// mapVersionChangeToReconcileRequests returns all Istio objects whose spec.version may refer to different charts
+// after the versions provided by IstioChartSources or the IstioVersionCatalog have changed.
+func (r *Reconciler) mapVersionChangeToReconcileRequests(ctx context.Context, _ client.Object) []reconcile.Request {
+	list := v1.IstioList{}
+	if err := r.Client.List(ctx, &list); err != nil {
+		logf.FromContext(ctx).Error(err, "failed to list Istios")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, istio := range list.Items {
+		if istioversion.IsAffectedByChange(istio.Spec.Version, istio.Status.ResolvedVersion) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: istio.Name}})
+		}
+	}
+	return requests
+}
+
+// mapIntegrationToReconcileRequests returns all Istio objects, because a MetricsIntegration, TracingIntegration or
+// MeshCluster event may affect both the Istio objects it targets now and the ones it targeted previously.
+func (r *Reconciler) mapIntegrationToReconcileRequests(ctx context.Context, _ client.Object) []reconcile.Request {
+	log := logf.FromContext(ctx)
+	istioList := v1.IstioList{}
+	if err := r.Client.List(ctx, &istioList); err != nil {
+		log.Error(err, "failed to list Istios")
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0, len(istioList.Items))
+	for _, istio := range istioList.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: istio.Name}})
+	}
+	return requests
+}
+
+func (r *Reconciler) determineStatus(ctx context.Context, istio *v1.Istio, outcome reconcileOutcome, reconcileErr error) (v1.IstioStatus, error) {
 	var errs errlist.Builder
 	status := *istio.Status.DeepCopy()
 	status.ObservedGeneration = istio.Generation
+	status.ResolvedVersion, _ = istioversion.Resolve(istio.Spec.Version)
 
 	// set Reconciled and Ready conditions
 	if reconcileErr != nil {
+		reason := v1.IstioReasonReconcileError
+		if helm.IsPatchError(reconcileErr) {
+			reason = v1.IstioReasonInvalidPatch
+		}
 		status.SetCondition(v1.StatusCondition{
 			Type:    v1.IstioConditionReconciled,
 			Status:  metav1.ConditionFalse,
-			Reason:  v1.IstioReasonReconcileError,
+			Reason:  reason,
 			Message: reconcileErr.Error(),
 		})
 		status.SetCondition(v1.StatusCondition{
@@ -242,7 +525,10 @@ func (r *Reconciler) determineStatus(ctx context.Context, istio *v1.Istio, recon
 		status.State = v1.IstioReasonReconcileError
 	} else {
 		status.ActiveRevisionName = getActiveRevisionName(istio)
-		rev, err := r.getActiveRevision(ctx, istio)
+		if outcome.promotion != nil {
+			status.ActiveRevisionName = outcome.promotion.activeRevisionName
+		}
+		rev, err := r.getRevision(ctx, status.ActiveRevisionName)
 		if apierrors.IsNotFound(err) {
 			revisionNotFound := func(conditionType v1.IstioConditionType) v1.StatusCondition {
 				return v1.StatusCondition{
@@ -277,6 +563,19 @@ func (r *Reconciler) determineStatus(ctx context.Context, istio *v1.Istio, recon
 		}
 	}
 
+	// in dry-run mode, the Reconciled condition reports the plan instead of the state of the active revision
+	status.Plan = outcome.plan
+	if plan := outcome.plan; reconcileErr == nil && plan != nil {
+		status.SetCondition(v1.StatusCondition{
+			Type:   v1.IstioConditionReconciled,
+			Status: metav1.ConditionFalse,
+			Reason: v1.IstioReasonDryRun,
+			Message: fmt.Sprintf("dry-run mode is enabled; %d objects would be added, %d changed and %d removed (see status.plan)",
+				len(plan.Added), len(plan.Changed), len(plan.Removed)),
+		})
+		status.State = v1.IstioReasonDryRun
+	}
+
 	// count the ready, in-use, and total revisions
 	if revs, err := revision.ListOwned(ctx, r.Client, istio.UID); err == nil {
 		status.Revisions.Total = int32(len(revs))
@@ -296,11 +595,64 @@ func (r *Reconciler) determineStatus(ctx context.Context, istio *v1.Istio, recon
 		status.Revisions.InUse = -1
 		errs.Add(err)
 	}
+
+	// set the WorkloadsUpdated condition; if the workloads weren't updated during this reconciliation
+	// (e.g. due to a reconciliation error), the previously reported condition is preserved
+	if !updatesWorkloads(istio) {
+		status.RemoveCondition(v1.IstioConditionWorkloadsUpdated)
+	} else if outcome.workloadUpdate != nil {
+		status.SetCondition(determineWorkloadsUpdatedCondition(*outcome.workloadUpdate))
+	}
+
+	// set the RevisionPromoted condition; as above, the previous promotion status is preserved
+	// if the promotion wasn't evaluated during this reconciliation
+	if !usesPromotion(istio) {
+		status.RemoveCondition(v1.IstioConditionRevisionPromoted)
+		status.Promotion = nil
+	} else if outcome.promotion != nil {
+		status.SetCondition(outcome.promotion.condition)
+		status.Promotion = outcome.promotion.status
+	}
 	return status, errs.Error()
 }
 
-func (r *Reconciler) updateStatus(ctx context.Context, istio *v1.Istio, reconcileErr error) error {
-	status, err := r.determineStatus(ctx, istio, reconcileErr)
+func determineWorkloadsUpdatedCondition(progress revision.WorkloadUpdateProgress) v1.StatusCondition {
+	c := v1.StatusCondition{
+		Type:   v1.IstioConditionWorkloadsUpdated,
+		Status: metav1.ConditionFalse,
+	}
+	switch {
+	case !progress.ActiveRevisionReady:
+		c.Reason = v1.IstioReasonWaitingForActiveRevision
+		c.Message = "workloads will be moved once the active IstioRevision is ready"
+	case progress.PendingWorkloads > 0 || progress.RestartingWorkloads > 0:
+		c.Reason = v1.IstioReasonWorkloadUpdateInProgress
+		c.Message = fmt.Sprintf("restarting %d workload(s); %d workload(s) waiting to be restarted",
+			progress.RestartingWorkloads, progress.PendingWorkloads)
+	case len(progress.FailedWorkloads) > 0 || len(progress.SkippedWorkloads) > 0:
+		c.Reason = v1.IstioReasonWorkloadUpdateBlocked
+		var msgs []string
+		if len(progress.FailedWorkloads) > 0 {
+			msgs = append(msgs, "restart did not complete in time: "+strings.Join(progress.FailedWorkloads, ", "))
+		}
+		if len(progress.SkippedWorkloads) > 0 {
+			msgs = append(msgs, "would still use another revision when restarted: "+strings.Join(progress.SkippedWorkloads, ", "))
+		}
+		c.Message = "some workloads can't be moved to the active revision; " + strings.Join(msgs, "; ")
+	case progress.UnmanagedPods > 0:
+		c.Reason = v1.IstioReasonManualRestartRequired
+		c.Message = fmt.Sprintf("%d pod(s) not controlled by a Deployment, StatefulSet or DaemonSet must be restarted manually",
+			progress.UnmanagedPods)
+	default:
+		c.Status = metav1.ConditionTrue
+		c.Reason = v1.ConditionReason(v1.IstioConditionWorkloadsUpdated)
+		c.Message = "all workloads use the active revision"
+	}
+	return c
+}
+
+func (r *Reconciler) updateStatus(ctx context.Context, istio *v1.Istio, outcome reconcileOutcome, reconcileErr error) error {
+	status, err := r.determineStatus(ctx, istio, outcome, reconcileErr)
 	return reconciler.UpdateStatus(ctx, r.Client, istio, istio.Status, status, err)
 }
 
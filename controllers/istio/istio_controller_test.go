@@ -17,19 +17,28 @@ package istio
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"os"
 	"runtime/debug"
 	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
 	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
 	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
 	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
+	"github.com/istio-ecosystem/sail-operator/pkg/revision"
 	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
 	"github.com/istio-ecosystem/sail-operator/pkg/test/testtime"
 	. "github.com/onsi/gomega"
+	"helm.sh/helm/v4/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -63,7 +72,7 @@ func TestReconcile(t *testing.T) {
 		cl := newFakeClientBuilder().
 			WithObjects(istio).
 			Build()
-		reconciler := NewReconciler(cfg, cl, scheme.Scheme)
+		reconciler := NewReconciler(cfg, cl, cl, scheme.Scheme, nil)
 
 		_, err := reconciler.Reconcile(ctx, istio)
 		if err == nil {
@@ -101,7 +110,7 @@ func TestReconcile(t *testing.T) {
 			Build()
 		cfg := newReconcilerTestConfig(t)
 		cfg.DefaultProfile = "invalid-profile"
-		reconciler := NewReconciler(cfg, cl, scheme.Scheme)
+		reconciler := NewReconciler(cfg, cl, cl, scheme.Scheme, nil)
 
 		_, err := reconciler.Reconcile(ctx, istio)
 		if err == nil {
@@ -142,7 +151,7 @@ func TestReconcile(t *testing.T) {
 				},
 			}).
 			Build()
-		reconciler := NewReconciler(cfg, cl, scheme.Scheme)
+		reconciler := NewReconciler(cfg, cl, cl, scheme.Scheme, nil)
 
 		_, err := reconciler.Reconcile(ctx, istio)
 		if err == nil {
@@ -564,9 +573,9 @@ func TestDetermineStatus(t *testing.T) {
 				WithObjects(initObjs...).
 				WithInterceptorFuncs(interceptorFuncs).
 				Build()
-			reconciler := NewReconciler(cfg, cl, scheme.Scheme)
+			reconciler := NewReconciler(cfg, cl, cl, scheme.Scheme, nil)
 
-			status, err := reconciler.determineStatus(ctx, istio, tc.reconciliationErr)
+			status, err := reconciler.determineStatus(ctx, istio, reconcileOutcome{}, tc.reconciliationErr)
 			if (err != nil) != tc.wantErr {
 				t.Errorf("determineStatus() error = %v, wantErr %v", err, tc.wantErr)
 			}
@@ -781,9 +790,9 @@ func TestUpdateStatus(t *testing.T) {
 				WithObjects(initObjs...).
 				WithInterceptorFuncs(interceptorFuncs).
 				Build()
-			reconciler := NewReconciler(cfg, cl, scheme.Scheme)
+			reconciler := NewReconciler(cfg, cl, cl, scheme.Scheme, nil)
 
-			err := reconciler.updateStatus(ctx, istio, tc.reconciliationErr)
+			err := reconciler.updateStatus(ctx, istio, reconcileOutcome{}, tc.reconciliationErr)
 			if (err != nil) != tc.wantErr {
 				t.Errorf("updateStatus() error = %v, wantErr %v", err, tc.wantErr)
 			}
@@ -815,6 +824,8 @@ func TestGetActiveRevisionName(t *testing.T) {
 		name                 string
 		version              string
 		updateStrategyType   *v1.UpdateStrategyType
+		promotion            bool
+		activeRevisionName   string
 		expectedRevisionName string
 	}{
 		{
@@ -841,6 +852,21 @@ func TestGetActiveRevisionName(t *testing.T) {
 			updateStrategyType:   ptr.Of(v1.UpdateStrategyTypeRevisionBased),
 			expectedRevisionName: "test-istio-2-0-0",
 		},
+		{
+			name:                 "RevisionBased with promotion uses status",
+			version:              "2.0.0",
+			updateStrategyType:   ptr.Of(v1.UpdateStrategyTypeRevisionBased),
+			promotion:            true,
+			activeRevisionName:   "test-istio-1-0-0",
+			expectedRevisionName: "test-istio-1-0-0",
+		},
+		{
+			name:                 "RevisionBased with promotion and no active revision",
+			version:              "2.0.0",
+			updateStrategyType:   ptr.Of(v1.UpdateStrategyTypeRevisionBased),
+			promotion:            true,
+			expectedRevisionName: "test-istio-2-0-0",
+		},
 	}
 
 	for _, tt := range tests {
@@ -857,7 +883,11 @@ func TestGetActiveRevisionName(t *testing.T) {
 				istio.Spec.UpdateStrategy = &v1.IstioUpdateStrategy{
 					Type: *tt.updateStrategyType,
 				}
+				if tt.promotion {
+					istio.Spec.UpdateStrategy.Promotion = &v1.RevisionPromotion{}
+				}
 			}
+			istio.Status.ActiveRevisionName = tt.activeRevisionName
 			actual := getActiveRevisionName(istio)
 			if actual != tt.expectedRevisionName {
 				t.Errorf("getActiveRevisionName() = %v, want %v", actual, tt.expectedRevisionName)
@@ -867,9 +897,13 @@ func TestGetActiveRevisionName(t *testing.T) {
 }
 
 func newFakeClientBuilder() *fake.ClientBuilder {
-	return fake.NewClientBuilder().
+	b := fake.NewClientBuilder().
 		WithScheme(scheme.Scheme).
 		WithStatusSubresource(&v1.Istio{})
+	for _, index := range revision.UsageIndexes() {
+		b = b.WithIndex(index.Object, index.Field, index.Extract)
+	}
+	return b
 }
 
 func TestGetPruningGracePeriod(t *testing.T) {
@@ -960,6 +994,374 @@ func TestManagesExternalRevision(t *testing.T) {
 	}
 }
 
+func TestDetermineWorkloadsUpdatedCondition(t *testing.T) {
+	testCases := []struct {
+		name           string
+		progress       revision.WorkloadUpdateProgress
+		expectedStatus metav1.ConditionStatus
+		expectedReason v1.IstioConditionReason
+	}{
+		{
+			name:           "active revision not ready",
+			progress:       revision.WorkloadUpdateProgress{},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1.IstioReasonWaitingForActiveRevision,
+		},
+		{
+			name:           "workloads restarting",
+			progress:       revision.WorkloadUpdateProgress{ActiveRevisionReady: true, RestartingWorkloads: 1, PendingWorkloads: 2},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1.IstioReasonWorkloadUpdateInProgress,
+		},
+		{
+			name: "workloads blocked",
+			progress: revision.WorkloadUpdateProgress{
+				ActiveRevisionReady: true,
+				FailedWorkloads:     []string{"Deployment ns1/a"},
+				SkippedWorkloads:    []string{"Deployment ns2/b"},
+			},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1.IstioReasonWorkloadUpdateBlocked,
+		},
+		{
+			name:           "unmanaged pods",
+			progress:       revision.WorkloadUpdateProgress{ActiveRevisionReady: true, UnmanagedPods: 1},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1.IstioReasonManualRestartRequired,
+		},
+		{
+			name:           "done",
+			progress:       revision.WorkloadUpdateProgress{ActiveRevisionReady: true, UpdatedNamespaces: 3},
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: v1.ConditionReason(v1.IstioConditionWorkloadsUpdated),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := determineWorkloadsUpdatedCondition(tc.progress)
+			if c.Type != v1.IstioConditionWorkloadsUpdated {
+				t.Errorf("Expected condition type %q, but got %q", v1.IstioConditionWorkloadsUpdated, c.Type)
+			}
+			if c.Status != tc.expectedStatus {
+				t.Errorf("Expected condition status %q, but got %q", tc.expectedStatus, c.Status)
+			}
+			if c.Reason != tc.expectedReason {
+				t.Errorf("Expected condition reason %q, but got %q", tc.expectedReason, c.Reason)
+			}
+		})
+	}
+}
+
+func TestReconcileUpdatesWorkloads(t *testing.T) {
+	cfg := newReconcilerTestConfig(t)
+	cfg.DefaultProfile = "default"
+	cfg.ResourceFS = fstest.MapFS{
+		istioversion.Default + "/profiles/default.yaml": &fstest.MapFile{Data: []byte("spec:\n  values: {}\n")},
+	}
+
+	istio := &v1.Istio{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: istioName,
+			UID:  istioUID,
+		},
+		Spec: v1.IstioSpec{
+			Version:   istioversion.Default,
+			Namespace: istioNamespace,
+			UpdateStrategy: &v1.IstioUpdateStrategy{
+				Type:            v1.UpdateStrategyTypeRevisionBased,
+				UpdateWorkloads: true,
+			},
+		},
+	}
+
+	cl := newFakeClientBuilder().
+		WithStatusSubresource(&v1.Istio{}).
+		WithObjects(istio).
+		Build()
+	reconciler := NewReconciler(cfg, cl, cl, scheme.Scheme, nil)
+
+	result, err := reconciler.Reconcile(ctx, istio)
+	Must(t, err)
+	if result.RequeueAfter != workloadUpdateRequeueInterval {
+		t.Errorf("Expected Istio to be requeued after %v, but got %v", workloadUpdateRequeueInterval, result.RequeueAfter)
+	}
+
+	Must(t, cl.Get(ctx, istioKey, istio))
+	cond := istio.Status.GetCondition(v1.IstioConditionWorkloadsUpdated)
+	if cond.Reason != v1.IstioReasonWaitingForActiveRevision {
+		t.Errorf("Expected WorkloadsUpdated condition reason to be %q, but got %q", v1.IstioReasonWaitingForActiveRevision, cond.Reason)
+	}
+
+	// disabling updateWorkloads removes the condition
+	istio.Spec.UpdateStrategy.UpdateWorkloads = false
+	Must(t, cl.Update(ctx, istio))
+	_, err = reconciler.Reconcile(ctx, istio)
+	Must(t, err)
+
+	Must(t, cl.Get(ctx, istioKey, istio))
+	for _, c := range istio.Status.Conditions {
+		if c.Type == v1.IstioConditionWorkloadsUpdated {
+			t.Errorf("Expected WorkloadsUpdated condition to be removed, but it wasn't")
+		}
+	}
+}
+
+func TestReconcileAppliesIntegrations(t *testing.T) {
+	cfg := newReconcilerTestConfig(t)
+	cfg.DefaultProfile = "default"
+	cfg.ResourceFS = fstest.MapFS{
+		istioversion.Default + "/profiles/default.yaml": &fstest.MapFile{Data: []byte("spec:\n  values: {}\n")},
+	}
+
+	userProvider := &v1.MeshConfigExtensionProvider{
+		Name:   ptr.Of("otel"),
+		Zipkin: &v1.MeshConfigExtensionProviderZipkinTracingProvider{Service: ptr.Of("zipkin.tracing"), Port: ptr.Of(uint32(9411))},
+	}
+	istio := &v1.Istio{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: istioName,
+			UID:  istioUID,
+		},
+		Spec: v1.IstioSpec{
+			Version:   istioversion.Default,
+			Namespace: istioNamespace,
+			Values: &v1.Values{
+				MeshConfig: &v1.MeshConfig{ExtensionProviders: []*v1.MeshConfigExtensionProvider{userProvider}},
+			},
+		},
+	}
+	metrics := &v1alpha1.MetricsIntegration{
+		ObjectMeta: metav1.ObjectMeta{Name: "metrics"},
+		Spec: v1alpha1.MetricsIntegrationSpec{
+			TargetRefs:    []v1alpha1.TargetReference{{Kind: v1alpha1.IstioTargetKind, Name: istioName}},
+			MetricsConfig: v1alpha1.MetricsConfig{Type: v1alpha1.MetricsTypeUserWorkloadMonitoring},
+		},
+	}
+	tracing := &v1alpha1.TracingIntegration{
+		ObjectMeta: metav1.ObjectMeta{Name: "tracing"},
+		Spec: v1alpha1.TracingIntegrationSpec{
+			TargetRefs: []v1alpha1.TargetReference{{Kind: v1alpha1.IstioTargetKind, Name: istioName}},
+			TracingConfig: v1alpha1.TracingConfig{
+				Type: v1alpha1.TracingTypeOpenTelemetry,
+				OpenTelemetry: &v1alpha1.OpenTelemetryConfig{
+					OTELCollectorRef: v1alpha1.NamespacedReference{Name: "otel", Namespace: "observability"},
+				},
+			},
+		},
+	}
+
+	cl := newFakeClientBuilder().
+		WithStatusSubresource(&v1.Istio{}).
+		WithObjects(istio, metrics, tracing).
+		Build()
+	reconciler := NewReconciler(cfg, cl, cl, scheme.Scheme, nil)
+
+	_, err := reconciler.Reconcile(ctx, istio)
+	Must(t, err)
+
+	rev := &v1.IstioRevision{}
+	Must(t, cl.Get(ctx, types.NamespacedName{Name: getDesiredRevisionName(istio)}, rev))
+
+	// the user's "otel" provider takes precedence over the one from the TracingIntegration
+	expected := []*v1.MeshConfigExtensionProvider{
+		userProvider,
+		{Name: ptr.Of("prometheus"), Prometheus: &v1.MeshConfigExtensionProviderPrometheusMetricsProvider{}},
+	}
+	if diff := cmp.Diff(expected, rev.Spec.Values.MeshConfig.ExtensionProviders); diff != "" {
+		t.Errorf("unexpected extension providers; diff (-expected, +actual):\n%v", diff)
+	}
+}
+
+func TestReconcileAppliesMeshCluster(t *testing.T) {
+	cfg := newReconcilerTestConfig(t)
+	cfg.DefaultProfile = "default"
+	cfg.ResourceFS = fstest.MapFS{
+		istioversion.Default + "/profiles/default.yaml": &fstest.MapFile{Data: []byte("spec:\n  values: {}\n")},
+	}
+
+	istio := &v1.Istio{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: istioName,
+			UID:  istioUID,
+		},
+		Spec: v1.IstioSpec{
+			Version:   istioversion.Default,
+			Namespace: istioNamespace,
+			Values: &v1.Values{
+				Global: &v1.GlobalConfig{Network: ptr.Of("user-network")},
+			},
+		},
+	}
+	mc := &v1alpha1.MeshCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1alpha1.MeshClusterSpec{
+			TargetRef:   v1.TargetReference{Kind: v1.IstioKind, Name: istioName},
+			MeshID:      "mesh1",
+			ClusterName: "cluster1",
+			Network:     "network1",
+		},
+	}
+
+	cl := newFakeClientBuilder().
+		WithStatusSubresource(&v1.Istio{}).
+		WithObjects(istio, mc).
+		Build()
+	reconciler := NewReconciler(cfg, cl, cl, scheme.Scheme, nil)
+
+	_, err := reconciler.Reconcile(ctx, istio)
+	Must(t, err)
+
+	rev := &v1.IstioRevision{}
+	Must(t, cl.Get(ctx, types.NamespacedName{Name: getDesiredRevisionName(istio)}, rev))
+
+	// the network set by the user takes precedence over the one in the MeshCluster
+	global := rev.Spec.Values.Global
+	if *global.MeshID != "mesh1" || *global.MultiCluster.ClusterName != "cluster1" || *global.Network != "user-network" {
+		t.Errorf("unexpected global values: meshID=%s, clusterName=%s, network=%s",
+			*global.MeshID, *global.MultiCluster.ClusterName, *global.Network)
+	}
+}
+
+// recordingChartManager records the Helm releases that are planned and installed.
+type recordingChartManager struct {
+	planned   []string
+	installed []string
+}
+
+func (m *recordingChartManager) UpgradeOrInstallChart(_ context.Context, _ fs.FS, _ string, _ helm.Values,
+	namespace, releaseName string, _ *metav1.OwnerReference, _ ...helm.ChartOption,
+) (release.Releaser, error) {
+	m.installed = append(m.installed, namespace+"/"+releaseName)
+	return nil, nil
+}
+
+func (m *recordingChartManager) UninstallChart(_ context.Context, releaseName, namespace string) (*release.UninstallReleaseResponse, error) {
+	m.installed = append(m.installed, "uninstall "+namespace+"/"+releaseName)
+	return nil, nil
+}
+
+func (m *recordingChartManager) PlanChart(_ context.Context, _ fs.FS, _ string, _ helm.Values,
+	namespace, releaseName string, _ *metav1.OwnerReference, _ ...helm.ChartOption,
+) (helm.ReleaseDiff, error) {
+	m.planned = append(m.planned, namespace+"/"+releaseName)
+	return helm.ReleaseDiff{
+		Changed: []helm.ObjectReference{{APIVersion: "v1", Kind: "ConfigMap", Namespace: namespace, Name: releaseName}},
+	}, nil
+}
+
+func TestReconcileDryRun(t *testing.T) {
+	cfg := newReconcilerTestConfig(t)
+	cfg.DefaultProfile = "default"
+	cfg.OperatorNamespace = "sail-operator"
+	cfg.ResourceFS = fstest.MapFS{
+		istioversion.Default + "/profiles/default.yaml": &fstest.MapFile{Data: []byte("spec:\n  values: {}\n")},
+	}
+
+	istio := &v1.Istio{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        istioName,
+			UID:         istioUID,
+			Annotations: map[string]string{constants.DryRunAnnotationKey: "true"},
+		},
+		Spec: v1.IstioSpec{
+			Version:   istioversion.Default,
+			Namespace: istioNamespace,
+		},
+	}
+	ownerRef := metav1.OwnerReference{APIVersion: v1.GroupVersion.String(), Kind: v1.IstioKind, Name: istioName, UID: istioUID, Controller: ptr.Of(true)}
+	// the live revision still uses the previous version, which must stay installed during the dry-run
+	liveRev := &v1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            istioName,
+			UID:             "live-rev-uid",
+			OwnerReferences: []metav1.OwnerReference{ownerRef},
+		},
+		Spec: v1.IstioRevisionSpec{
+			Version:   "v1.0.0",
+			Namespace: istioNamespace,
+			Values:    &v1.Values{Revision: ptr.Of(istioName)},
+		},
+	}
+	// the old revision isn't in use and its grace period has expired, so it would normally be pruned
+	oldRev := &v1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            istioName + "-old",
+			OwnerReferences: []metav1.OwnerReference{ownerRef},
+		},
+		Spec: v1.IstioRevisionSpec{Namespace: istioNamespace},
+		Status: v1.IstioRevisionStatus{
+			Conditions: []v1.StatusCondition{
+				{
+					Type:               v1.IstioRevisionConditionInUse,
+					Status:             metav1.ConditionFalse,
+					LastTransitionTime: metav1.NewTime(time.Now().Add(-time.Hour)),
+				},
+			},
+		},
+	}
+	tag := &v1.IstioRevisionTag{
+		ObjectMeta: metav1.ObjectMeta{Name: v1.DefaultRevisionTag, UID: "tag-uid"},
+		Spec:       v1.IstioRevisionTagSpec{TargetRef: v1.TargetReference{Kind: v1.IstioKind, Name: istioName}},
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: istioNamespace}}
+
+	cl := newFakeClientBuilder().
+		WithStatusSubresource(&v1.Istio{}).
+		WithObjects(istio, liveRev, oldRev, tag, ns).
+		Build()
+	chartManager := &recordingChartManager{}
+	reconciler := NewReconciler(cfg, cl, cl, scheme.Scheme, chartManager)
+
+	_, err := reconciler.Reconcile(ctx, istio)
+	Must(t, err)
+
+	rev := &v1.IstioRevision{}
+	Must(t, cl.Get(ctx, types.NamespacedName{Name: liveRev.Name}, rev))
+	if diff := cmp.Diff(liveRev.Spec, rev.Spec); diff != "" {
+		t.Errorf("expected the spec of the live IstioRevision to be left unchanged in dry-run mode; diff (-expected, +actual):\n%v", diff)
+	}
+	Must(t, cl.Get(ctx, types.NamespacedName{Name: oldRev.Name}, &v1.IstioRevision{}))
+	if len(chartManager.installed) > 0 {
+		t.Errorf("expected no Helm release to be changed in dry-run mode, got %v", chartManager.installed)
+	}
+	expectedPlanned := []string{
+		istioNamespace + "/" + istioName + "-istiod",
+		istioNamespace + "/default-revisiontags",
+		"sail-operator/default-base",
+	}
+	if diff := cmp.Diff(expectedPlanned, chartManager.planned); diff != "" {
+		t.Errorf("unexpected planned Helm releases; diff (-expected, +actual):\n%v", diff)
+	}
+
+	Must(t, cl.Get(ctx, istioKey, istio))
+	if istio.Status.Plan == nil || len(istio.Status.Plan.Changed) != len(expectedPlanned) {
+		t.Errorf("expected status.plan to report the changes to all planned releases, got %+v", istio.Status.Plan)
+	}
+	if reason := istio.Status.GetCondition(v1.IstioConditionReconciled).Reason; reason != v1.IstioReasonDryRun {
+		t.Errorf("expected Reconciled condition reason %s, got %s", v1.IstioReasonDryRun, reason)
+	}
+
+	// removing the annotation updates the IstioRevision, clears the plan and resumes pruning
+	istio.Annotations = nil
+	Must(t, cl.Update(ctx, istio))
+
+	_, err = reconciler.Reconcile(ctx, istio)
+	Must(t, err)
+
+	Must(t, cl.Get(ctx, types.NamespacedName{Name: liveRev.Name}, rev))
+	if rev.Spec.Version != istioversion.Default {
+		t.Errorf("expected the IstioRevision to be updated to version %s, got %s", istioversion.Default, rev.Spec.Version)
+	}
+	if err := cl.Get(ctx, types.NamespacedName{Name: oldRev.Name}, &v1.IstioRevision{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the old IstioRevision to be pruned, got %v", err)
+	}
+	Must(t, cl.Get(ctx, istioKey, istio))
+	if istio.Status.Plan != nil {
+		t.Errorf("expected status.plan to be cleared, got %+v", istio.Status.Plan)
+	}
+}
+
 func Must(t *testing.T, err error) {
 	t.Helper()
 	if err != nil {
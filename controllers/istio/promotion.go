@@ -0,0 +1,270 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istio
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/revision"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// promotionRequeueInterval defines how often the health of a revision is checked while it is being promoted.
+const promotionRequeueInterval = 10 * time.Second
+
+// promotionOutcome is the result of evaluating the staged promotion of the revision for the current spec.version.
+type promotionOutcome struct {
+	// activeRevisionName is the name of the revision that is active after the evaluation.
+	activeRevisionName string
+
+	// canary is nil when no revision is being canaried.
+	canary *revision.Canary
+
+	// retainedRevisionNames are the names of the inactive revisions that must not be pruned.
+	retainedRevisionNames []string
+
+	status       *v1.RevisionPromotionStatus
+	condition    v1.StatusCondition
+	requeueAfter time.Duration
+}
+
+// usesPromotion returns true if new revisions must be canaried before they become the active revision.
+func usesPromotion(istio *v1.Istio) bool {
+	strategy := istio.Spec.UpdateStrategy
+	return strategy != nil && strategy.Type == v1.UpdateStrategyTypeRevisionBased && strategy.Promotion != nil
+}
+
+// isRolledBack returns true if the revision for the current spec.version degraded during its promotion and
+// the operator rolled back to the previous revision. Such a revision isn't updated or promoted again until
+// spec.version changes.
+func isRolledBack(istio *v1.Istio) bool {
+	promotion := istio.Status.Promotion
+	return usesPromotion(istio) && promotion != nil &&
+		promotion.Phase == v1.PromotionPhaseRolledBack && promotion.RevisionName == getDesiredRevisionName(istio)
+}
+
+func getPromotionSoakTime(istio *v1.Istio) time.Duration {
+	seconds := int64(v1.DefaultPromotionSoakTimeSeconds)
+	if usesPromotion(istio) && istio.Spec.UpdateStrategy.Promotion.SoakTimeSeconds != nil {
+		seconds = max(*istio.Spec.UpdateStrategy.Promotion.SoakTimeSeconds, 0)
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// reconcilePromotion moves the canary namespaces to the revision for the current spec.version and promotes
+// the revision once it has been healthy for the soak time. If the revision degrades before or shortly after
+// its promotion, the operator rolls back to the previously active revision.
+func (r *Reconciler) reconcilePromotion(ctx context.Context, istio *v1.Istio) (*promotionOutcome, error) {
+	desiredRevisionName := getDesiredRevisionName(istio)
+	activeRevisionName := istio.Status.ActiveRevisionName
+	soakTime := getPromotionSoakTime(istio)
+
+	var promotion *v1.RevisionPromotionStatus
+	if istio.Status.Promotion != nil {
+		promotion = istio.Status.Promotion.DeepCopy()
+	}
+
+	if activeRevisionName != "" && activeRevisionName != desiredRevisionName {
+		if found, err := r.revisionExists(ctx, activeRevisionName); err != nil {
+			return nil, err
+		} else if !found {
+			activeRevisionName = ""
+		}
+	}
+
+	var outcome *promotionOutcome
+	var err error
+	switch {
+	case activeRevisionName == "":
+		// there's no revision to canary against, so the new revision becomes active immediately
+		outcome = &promotionOutcome{activeRevisionName: desiredRevisionName, status: promotion}
+	case activeRevisionName == desiredRevisionName:
+		outcome, err = r.verifyPromotedRevision(ctx, promotion, activeRevisionName, soakTime)
+	case promotion != nil && promotion.RevisionName == desiredRevisionName && promotion.Phase == v1.PromotionPhaseRolledBack:
+		outcome = &promotionOutcome{activeRevisionName: activeRevisionName, status: promotion}
+	default:
+		if promotion == nil || promotion.RevisionName != desiredRevisionName || promotion.Phase == v1.PromotionPhasePromoted {
+			promotion = &v1.RevisionPromotionStatus{
+				RevisionName:         desiredRevisionName,
+				PreviousRevisionName: activeRevisionName,
+				Phase:                v1.PromotionPhaseCanary,
+			}
+		}
+		outcome, err = r.canaryRevision(ctx, istio, promotion, activeRevisionName, soakTime)
+	}
+	if err != nil {
+		return nil, err
+	}
+	outcome.condition = determineRevisionPromotedCondition(outcome.status, desiredRevisionName, outcome.activeRevisionName, soakTime)
+	return outcome, nil
+}
+
+// canaryRevision serves the canary namespaces with the revision being promoted and promotes it after it
+// has been healthy for the soak time. If the revision degrades after it became healthy, the canary
+// namespaces are moved back to the active revision.
+func (r *Reconciler) canaryRevision(
+	ctx context.Context, istio *v1.Istio, promotion *v1.RevisionPromotionStatus, activeRevisionName string, soakTime time.Duration,
+) (*promotionOutcome, error) {
+	log := logf.FromContext(ctx)
+
+	canary := revision.Canary{RevisionName: promotion.RevisionName}
+	health, err := revision.GetCanaryHealth(ctx, r.Client, r.APIReader, canary)
+	if err != nil {
+		return nil, err
+	}
+	// the canary namespaces are only moved once the revision is ready to inject their pods
+	if health.RevisionReady {
+		selector := istio.Spec.UpdateStrategy.Promotion.CanaryNamespaceSelector
+		if canary, err = revision.MoveNamespacesToCanary(ctx, r.Client, selector, promotion.RevisionName); err != nil {
+			return nil, err
+		}
+		if health, err = revision.GetCanaryHealth(ctx, r.Client, r.APIReader, canary); err != nil {
+			return nil, err
+		}
+	}
+
+	outcome := &promotionOutcome{
+		activeRevisionName:    activeRevisionName,
+		canary:                &canary,
+		retainedRevisionNames: []string{promotion.RevisionName},
+		status:                promotion,
+		requeueAfter:          promotionRequeueInterval,
+	}
+	now := metav1.NewTime(time.Now().Truncate(time.Second))
+	switch {
+	case health.Healthy():
+		if promotion.HealthySince == nil {
+			promotion.HealthySince = &now
+		}
+		promotion.Phase = v1.PromotionPhaseSoaking
+		if now.Sub(promotion.HealthySince.Time) < soakTime {
+			break
+		}
+
+		log.Info("Promoting IstioRevision", "IstioRevision", promotion.RevisionName, "PreviousIstioRevision", activeRevisionName)
+		promotion.Phase = v1.PromotionPhasePromoted
+		promotion.PromotionTime = &now
+		outcome.activeRevisionName = promotion.RevisionName
+		outcome.canary = nil
+		outcome.retainedRevisionNames = []string{activeRevisionName}
+		outcome.requeueAfter = min(promotionRequeueInterval, soakTime)
+	case health.Degraded() && promotion.Phase == v1.PromotionPhaseSoaking:
+		log.Info("IstioRevision degraded during promotion; rolling back", "IstioRevision", promotion.RevisionName,
+			"PreviousIstioRevision", activeRevisionName)
+		if err := revision.RollBackNamespaces(ctx, r.Client, promotion.RevisionName, activeRevisionName); err != nil {
+			return nil, err
+		}
+		promotion.Phase = v1.PromotionPhaseRolledBack
+		promotion.HealthySince = nil
+		outcome.canary = nil
+		outcome.retainedRevisionNames = nil
+		outcome.requeueAfter = 0
+	default:
+		promotion.HealthySince = nil
+	}
+	return outcome, nil
+}
+
+// verifyPromotedRevision rolls back to the previous revision if the recently promoted revision
+// degrades while the previous revision is still ready. Rollbacks are only possible for one soak
+// time after the promotion.
+func (r *Reconciler) verifyPromotedRevision(
+	ctx context.Context, promotion *v1.RevisionPromotionStatus, activeRevisionName string, soakTime time.Duration,
+) (*promotionOutcome, error) {
+	log := logf.FromContext(ctx)
+	outcome := &promotionOutcome{activeRevisionName: activeRevisionName, status: promotion}
+	if promotion == nil || promotion.Phase != v1.PromotionPhasePromoted || promotion.RevisionName != activeRevisionName ||
+		promotion.PromotionTime == nil || promotion.PreviousRevisionName == "" {
+		return outcome, nil
+	}
+	remaining := time.Until(promotion.PromotionTime.Add(soakTime))
+	if remaining <= 0 {
+		return outcome, nil
+	}
+	outcome.retainedRevisionNames = []string{promotion.PreviousRevisionName}
+	outcome.requeueAfter = min(promotionRequeueInterval, remaining)
+
+	health, err := revision.GetCanaryHealth(ctx, r.Client, r.APIReader, revision.Canary{RevisionName: activeRevisionName})
+	if err != nil {
+		return nil, err
+	}
+	if !health.Degraded() {
+		return outcome, nil
+	}
+	previousHealth, err := revision.GetCanaryHealth(ctx, r.Client, r.APIReader, revision.Canary{RevisionName: promotion.PreviousRevisionName})
+	if err != nil {
+		return nil, err
+	}
+	if !previousHealth.RevisionReady {
+		log.Info("Promoted IstioRevision degraded, but the previous revision isn't ready; not rolling back",
+			"IstioRevision", activeRevisionName, "PreviousIstioRevision", promotion.PreviousRevisionName)
+		return outcome, nil
+	}
+
+	log.Info("Promoted IstioRevision degraded; rolling back", "IstioRevision", activeRevisionName,
+		"PreviousIstioRevision", promotion.PreviousRevisionName)
+	if err := revision.RollBackNamespaces(ctx, r.Client, activeRevisionName, promotion.PreviousRevisionName); err != nil {
+		return nil, err
+	}
+	promotion.Phase = v1.PromotionPhaseRolledBack
+	promotion.HealthySince = nil
+	promotion.PromotionTime = nil
+	return &promotionOutcome{activeRevisionName: promotion.PreviousRevisionName, status: promotion}, nil
+}
+
+func (r *Reconciler) revisionExists(ctx context.Context, name string) (bool, error) {
+	rev := v1.IstioRevision{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: name}, &rev); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get IstioRevision %s: %w", name, err)
+	}
+	return true, nil
+}
+
+func determineRevisionPromotedCondition(
+	promotion *v1.RevisionPromotionStatus, desiredRevisionName, activeRevisionName string, soakTime time.Duration,
+) v1.StatusCondition {
+	c := v1.StatusCondition{
+		Type:   v1.IstioConditionRevisionPromoted,
+		Status: metav1.ConditionFalse,
+	}
+	switch {
+	case promotion == nil || desiredRevisionName == activeRevisionName:
+		c.Status = metav1.ConditionTrue
+		c.Reason = v1.ConditionReason(v1.IstioConditionRevisionPromoted)
+		c.Message = fmt.Sprintf("revision %s is the active revision", activeRevisionName)
+	case promotion.Phase == v1.PromotionPhaseRolledBack:
+		c.Reason = v1.IstioReasonRolledBack
+		c.Message = fmt.Sprintf("revision %s degraded during its promotion; rolled back to revision %s",
+			promotion.RevisionName, activeRevisionName)
+	case promotion.HealthySince != nil:
+		c.Reason = v1.IstioReasonCanarySoaking
+		c.Message = fmt.Sprintf("revision %s has been healthy since %s; it will be promoted after a soak time of %s",
+			promotion.RevisionName, promotion.HealthySince.UTC().Format(time.RFC3339), soakTime)
+	default:
+		c.Reason = v1.IstioReasonCanaryNotHealthy
+		c.Message = fmt.Sprintf("waiting for revision %s and the workloads in the canary namespaces to become healthy",
+			promotion.RevisionName)
+	}
+	return c
+}
@@ -0,0 +1,318 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istio
+
+import (
+	"testing"
+	"time"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestReconcilePromotion(t *testing.T) {
+	oldRevName := istioName + "-1-0-0"
+	newRevName := istioName + "-1-1-0"
+	cfg := newReconcilerTestConfig(t)
+	soakTime := 60 * time.Second
+
+	newIstio := func(status v1.IstioStatus) *v1.Istio {
+		return &v1.Istio{
+			ObjectMeta: metav1.ObjectMeta{Name: istioName, UID: istioUID},
+			Spec: v1.IstioSpec{
+				Version:   "1.1.0",
+				Namespace: istioNamespace,
+				UpdateStrategy: &v1.IstioUpdateStrategy{
+					Type: v1.UpdateStrategyTypeRevisionBased,
+					Promotion: &v1.RevisionPromotion{
+						CanaryNamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"canary": "true"}},
+						SoakTimeSeconds:         ptr.Of(int64(soakTime.Seconds())),
+					},
+				},
+			},
+			Status: status,
+		}
+	}
+
+	newRevision := func(name string, ready metav1.ConditionStatus) *v1.IstioRevision {
+		return &v1.IstioRevision{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: v1.IstioRevisionStatus{
+				Conditions: []v1.StatusCondition{{Type: v1.IstioRevisionConditionReady, Status: ready}},
+			},
+		}
+	}
+
+	newCanaryNamespace := func(revisionName string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   "canary",
+			Labels: map[string]string{"canary": "true", constants.IstioRevLabel: revisionName},
+		}}
+	}
+
+	ago := func(d time.Duration) *metav1.Time {
+		return ptr.Of(metav1.NewTime(time.Now().Add(-d).Truncate(time.Second)))
+	}
+
+	testCases := []struct {
+		name                  string
+		status                v1.IstioStatus
+		objects               []client.Object
+		expectActiveRevision  string
+		expectPhase           v1.PromotionPhase
+		expectReason          v1.IstioConditionReason
+		expectCanary          bool
+		expectRetained        []string
+		expectCanaryNamespace string
+	}{
+		{
+			name:                 "activates first revision immediately",
+			objects:              []client.Object{newRevision(newRevName, metav1.ConditionFalse)},
+			expectActiveRevision: newRevName,
+			expectReason:         v1.ConditionReason(v1.IstioConditionRevisionPromoted),
+		},
+		{
+			name:   "waits for new revision to become ready before moving canary namespaces",
+			status: v1.IstioStatus{ActiveRevisionName: oldRevName},
+			objects: []client.Object{
+				newRevision(oldRevName, metav1.ConditionTrue),
+				newRevision(newRevName, metav1.ConditionUnknown),
+				newCanaryNamespace(oldRevName),
+			},
+			expectActiveRevision:  oldRevName,
+			expectPhase:           v1.PromotionPhaseCanary,
+			expectReason:          v1.IstioReasonCanaryNotHealthy,
+			expectCanary:          true,
+			expectRetained:        []string{newRevName},
+			expectCanaryNamespace: oldRevName,
+		},
+		{
+			name:   "starts soaking once new revision is healthy",
+			status: v1.IstioStatus{ActiveRevisionName: oldRevName},
+			objects: []client.Object{
+				newRevision(oldRevName, metav1.ConditionTrue),
+				newRevision(newRevName, metav1.ConditionTrue),
+				newCanaryNamespace(oldRevName),
+			},
+			expectActiveRevision:  oldRevName,
+			expectPhase:           v1.PromotionPhaseSoaking,
+			expectReason:          v1.IstioReasonCanarySoaking,
+			expectCanary:          true,
+			expectRetained:        []string{newRevName},
+			expectCanaryNamespace: newRevName,
+		},
+		{
+			name: "promotes new revision after soak time",
+			status: v1.IstioStatus{
+				ActiveRevisionName: oldRevName,
+				Promotion: &v1.RevisionPromotionStatus{
+					RevisionName:         newRevName,
+					PreviousRevisionName: oldRevName,
+					Phase:                v1.PromotionPhaseSoaking,
+					HealthySince:         ago(2 * soakTime),
+				},
+			},
+			objects: []client.Object{
+				newRevision(oldRevName, metav1.ConditionTrue),
+				newRevision(newRevName, metav1.ConditionTrue),
+				newCanaryNamespace(newRevName),
+			},
+			expectActiveRevision:  newRevName,
+			expectPhase:           v1.PromotionPhasePromoted,
+			expectReason:          v1.ConditionReason(v1.IstioConditionRevisionPromoted),
+			expectRetained:        []string{oldRevName},
+			expectCanaryNamespace: newRevName,
+		},
+		{
+			name: "rolls back when new revision degrades while soaking",
+			status: v1.IstioStatus{
+				ActiveRevisionName: oldRevName,
+				Promotion: &v1.RevisionPromotionStatus{
+					RevisionName:         newRevName,
+					PreviousRevisionName: oldRevName,
+					Phase:                v1.PromotionPhaseSoaking,
+					HealthySince:         ago(soakTime / 2),
+				},
+			},
+			objects: []client.Object{
+				newRevision(oldRevName, metav1.ConditionTrue),
+				newRevision(newRevName, metav1.ConditionFalse),
+				newCanaryNamespace(newRevName),
+			},
+			expectActiveRevision:  oldRevName,
+			expectPhase:           v1.PromotionPhaseRolledBack,
+			expectReason:          v1.IstioReasonRolledBack,
+			expectCanaryNamespace: oldRevName,
+		},
+		{
+			name: "doesn't promote rolled back revision again",
+			status: v1.IstioStatus{
+				ActiveRevisionName: oldRevName,
+				Promotion: &v1.RevisionPromotionStatus{
+					RevisionName:         newRevName,
+					PreviousRevisionName: oldRevName,
+					Phase:                v1.PromotionPhaseRolledBack,
+				},
+			},
+			objects: []client.Object{
+				newRevision(oldRevName, metav1.ConditionTrue),
+				newRevision(newRevName, metav1.ConditionTrue),
+				newCanaryNamespace(oldRevName),
+			},
+			expectActiveRevision:  oldRevName,
+			expectPhase:           v1.PromotionPhaseRolledBack,
+			expectReason:          v1.IstioReasonRolledBack,
+			expectCanaryNamespace: oldRevName,
+		},
+		{
+			name: "rolls back when promoted revision degrades",
+			status: v1.IstioStatus{
+				ActiveRevisionName: newRevName,
+				Promotion: &v1.RevisionPromotionStatus{
+					RevisionName:         newRevName,
+					PreviousRevisionName: oldRevName,
+					Phase:                v1.PromotionPhasePromoted,
+					PromotionTime:        ago(soakTime / 2),
+				},
+			},
+			objects: []client.Object{
+				newRevision(oldRevName, metav1.ConditionTrue),
+				newRevision(newRevName, metav1.ConditionFalse),
+				newCanaryNamespace(newRevName),
+			},
+			expectActiveRevision:  oldRevName,
+			expectPhase:           v1.PromotionPhaseRolledBack,
+			expectReason:          v1.IstioReasonRolledBack,
+			expectCanaryNamespace: oldRevName,
+		},
+		{
+			name: "keeps promoted revision after soak time",
+			status: v1.IstioStatus{
+				ActiveRevisionName: newRevName,
+				Promotion: &v1.RevisionPromotionStatus{
+					RevisionName:         newRevName,
+					PreviousRevisionName: oldRevName,
+					Phase:                v1.PromotionPhasePromoted,
+					PromotionTime:        ago(2 * soakTime),
+				},
+			},
+			objects: []client.Object{
+				newRevision(oldRevName, metav1.ConditionTrue),
+				newRevision(newRevName, metav1.ConditionFalse),
+				newCanaryNamespace(newRevName),
+			},
+			expectActiveRevision:  newRevName,
+			expectPhase:           v1.PromotionPhasePromoted,
+			expectReason:          v1.ConditionReason(v1.IstioConditionRevisionPromoted),
+			expectCanaryNamespace: newRevName,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			cl := newFakeClientBuilder().WithObjects(tc.objects...).Build()
+			reconciler := NewReconciler(cfg, cl, cl, scheme.Scheme, nil)
+
+			outcome, err := reconciler.reconcilePromotion(ctx, newIstio(tc.status))
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(outcome.activeRevisionName).To(Equal(tc.expectActiveRevision))
+			g.Expect(outcome.condition.Reason).To(Equal(tc.expectReason))
+			g.Expect(outcome.canary != nil).To(Equal(tc.expectCanary))
+			g.Expect(outcome.retainedRevisionNames).To(Equal(tc.expectRetained))
+			if tc.expectPhase == "" {
+				g.Expect(outcome.status).To(BeNil())
+			} else {
+				g.Expect(outcome.status).ToNot(BeNil())
+				g.Expect(outcome.status.Phase).To(Equal(tc.expectPhase))
+			}
+
+			if tc.expectCanaryNamespace != "" {
+				ns := &corev1.Namespace{}
+				g.Expect(cl.Get(ctx, client.ObjectKey{Name: "canary"}, ns)).To(Succeed())
+				g.Expect(ns.Labels[constants.IstioRevLabel]).To(Equal(tc.expectCanaryNamespace))
+			}
+		})
+	}
+}
+
+func TestDetermineRevisionPromotedCondition(t *testing.T) {
+	testCases := []struct {
+		name           string
+		promotion      *v1.RevisionPromotionStatus
+		activeRevision string
+		expectedStatus metav1.ConditionStatus
+		expectedReason v1.IstioConditionReason
+	}{
+		{
+			name:           "no promotion",
+			activeRevision: "new",
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: v1.ConditionReason(v1.IstioConditionRevisionPromoted),
+		},
+		{
+			name:           "canary not healthy",
+			promotion:      &v1.RevisionPromotionStatus{RevisionName: "new", Phase: v1.PromotionPhaseCanary},
+			activeRevision: "old",
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1.IstioReasonCanaryNotHealthy,
+		},
+		{
+			name: "canary soaking",
+			promotion: &v1.RevisionPromotionStatus{
+				RevisionName: "new", Phase: v1.PromotionPhaseSoaking, HealthySince: ptr.Of(metav1.Now()),
+			},
+			activeRevision: "old",
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1.IstioReasonCanarySoaking,
+		},
+		{
+			name:           "rolled back",
+			promotion:      &v1.RevisionPromotionStatus{RevisionName: "new", Phase: v1.PromotionPhaseRolledBack},
+			activeRevision: "old",
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1.IstioReasonRolledBack,
+		},
+		{
+			name:           "promoted",
+			promotion:      &v1.RevisionPromotionStatus{RevisionName: "new", Phase: v1.PromotionPhasePromoted},
+			activeRevision: "new",
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: v1.ConditionReason(v1.IstioConditionRevisionPromoted),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := determineRevisionPromotedCondition(tc.promotion, "new", tc.activeRevision, time.Minute)
+			if c.Type != v1.IstioConditionRevisionPromoted {
+				t.Errorf("Expected condition type %q, but got %q", v1.IstioConditionRevisionPromoted, c.Type)
+			}
+			if c.Status != tc.expectedStatus {
+				t.Errorf("Expected condition status %q, but got %q", tc.expectedStatus, c.Status)
+			}
+			if c.Reason != tc.expectedReason {
+				t.Errorf("Expected condition reason %q, but got %q", tc.expectedReason, c.Reason)
+			}
+		})
+	}
+}
@@ -26,14 +26,17 @@ import (
 	"github.com/istio-ecosystem/sail-operator/pkg/enqueuelogger"
 	"github.com/istio-ecosystem/sail-operator/pkg/errlist"
 	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
 	sharedreconcile "github.com/istio-ecosystem/sail-operator/pkg/reconcile"
 	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	"github.com/istio-ecosystem/sail-operator/pkg/validation"
 	"github.com/istio-ecosystem/sail-operator/pkg/watches"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -83,28 +86,36 @@ func NewReconciler(cfg config.ReconcilerConfig, client client.Client, scheme *ru
 func (r *Reconciler) Reconcile(ctx context.Context, cni *v1.IstioCNI) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
 
-	reconcileErr := r.doReconcile(ctx, cni)
+	drift, reconcileErr := r.doReconcile(ctx, cni)
 
 	log.Info("Reconciliation done. Updating status.")
-	statusErr := r.updateStatus(ctx, cni, reconcileErr)
+	statusErr := r.updateStatus(ctx, cni, drift, reconcileErr)
 
 	return ctrl.Result{}, errors.Join(reconcileErr, statusErr)
 }
 
 func (r *Reconciler) Finalize(ctx context.Context, cni *v1.IstioCNI) error {
-	cniReconciler := r.newCNIReconciler()
+	if err := r.validateNamespaceNotShared(ctx, cni); reconciler.IsValidationError(err) {
+		// the Helm release in the namespace belongs to another IstioCNI
+		return nil
+	} else if err != nil {
+		return err
+	}
+	cniReconciler := r.newCNIReconciler(cni)
 	return cniReconciler.Uninstall(ctx, cni.Spec.Namespace)
 }
 
-func (r *Reconciler) doReconcile(ctx context.Context, cni *v1.IstioCNI) error {
+func (r *Reconciler) doReconcile(ctx context.Context, cni *v1.IstioCNI) (*sharedreconcile.DriftReport, error) {
 	log := logf.FromContext(ctx)
-	cniReconciler := r.newCNIReconciler()
+	cniReconciler := r.newCNIReconciler(cni)
 
 	if err := cniReconciler.Validate(ctx, cni.Spec.Version, cni.Spec.Namespace); err != nil {
-		return err
+		return nil, err
+	}
+	if err := r.validateNamespaceNotShared(ctx, cni); err != nil {
+		return nil, err
 	}
 
-	log.Info("Installing Helm chart")
 	ownerReference := metav1.OwnerReference{
 		APIVersion:         v1.GroupVersion.String(),
 		Kind:               v1.IstioCNIKind,
@@ -113,17 +124,61 @@ func (r *Reconciler) doReconcile(ctx context.Context, cni *v1.IstioCNI) error {
 		Controller:         ptr.Of(true),
 		BlockOwnerDeletion: ptr.Of(true),
 	}
-	return cniReconciler.Install(ctx, cni.Spec.Version, cni.Spec.Namespace, cni.Spec.Values, cni.Spec.Profile, &ownerReference)
+
+	if rollbackRevision, err := sharedreconcile.RollbackRevision(cni); err != nil {
+		return nil, err
+	} else if rollbackRevision > 0 {
+		log.Info("Rollback requested. Reinstalling a previous revision of the Helm release", "revision", rollbackRevision)
+		_, err := cniReconciler.Rollback(ctx, cni.Spec.Namespace, rollbackRevision)
+		return nil, err
+	}
+
+	patches := sharedreconcile.WithNodeSelector(cni.Spec.Patches, cni.Spec.NodeSelector)
+	drift := cniReconciler.DetectDrift(ctx, cni.Spec.Namespace, cni.Spec.DriftPolicy)
+	cniReconciler.KeepingFields(drift)
+	if !drift.UpgradeAllowed() {
+		diff, err := cniReconciler.Plan(ctx, cni.Spec.Version, cni.Spec.Namespace, cni.Spec.Values, cni.Spec.Profile, patches, &ownerReference)
+		if err != nil {
+			return drift, err
+		}
+		if diff.IsEmpty() {
+			log.Info("Helm chart is up to date; keeping drifted fields as configured in spec.driftPolicy")
+			return drift, nil
+		}
+	}
+
+	log.Info("Installing Helm chart")
+	if err := cniReconciler.Install(ctx, cni.Spec.Version, cni.Spec.Namespace, cni.Spec.Values, cni.Spec.Profile, patches, &ownerReference); err != nil {
+		return drift, err
+	}
+	drift.ChartsUpgraded()
+	return drift, nil
 }
 
-func (r *Reconciler) newCNIReconciler() *sharedreconcile.CNIReconciler {
+// validateNamespaceNotShared checks that no other IstioCNI that takes precedence uses the same namespace, because
+// both would manage the same Helm release
+func (r *Reconciler) validateNamespaceNotShared(ctx context.Context, cni *v1.IstioCNI) error {
+	cniList := v1.IstioCNIList{}
+	if err := r.Client.List(ctx, &cniList); err != nil {
+		return fmt.Errorf("list failed: %w", err)
+	}
+	for _, other := range cniList.Items {
+		if other.Name != cni.Name && other.Spec.Namespace == cni.Spec.Namespace &&
+			validation.ResourceTakesPrecedence(&other.ObjectMeta, &cni.ObjectMeta) {
+			return reconciler.NewValidationError(fmt.Sprintf("namespace %q is already used by IstioCNI %q", cni.Spec.Namespace, other.Name))
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) newCNIReconciler(cni *v1.IstioCNI) *sharedreconcile.CNIReconciler {
 	return sharedreconcile.NewCNIReconciler(sharedreconcile.Config{
 		ResourceFS:        r.Config.ResourceFS,
 		Platform:          r.Config.Platform,
 		DefaultProfile:    r.Config.DefaultProfile,
 		OperatorNamespace: r.Config.OperatorNamespace,
 		ChartManager:      r.ChartManager,
-	}, r.Client)
+	}, r.Client).ForInstance(cni.Name)
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -131,7 +186,7 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	logger := mgr.GetLogger().WithName("ctrlr").WithName("istiocni")
 
 	// mainObjectHandler handles the IstioCNI watch events
-	mainObjectHandler := wrapEventHandler(logger, &handler.EnqueueRequestForObject{})
+	mainObjectHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapIstioCNIToReconcileRequests))
 
 	// ownedResourceHandler handles resources that are owned by the IstioCNI CR
 	ownedResourceHandler := wrapEventHandler(logger,
@@ -139,6 +194,9 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 	namespaceHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapNamespaceToReconcileRequest))
 
+	// versionHandler handles changes to the versions provided by IstioChartSources and the IstioVersionCatalog
+	versionHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapVersionChangeToReconcileRequests))
+
 	b := ctrl.NewControllerManagedBy(mgr).
 		WithOptions(controller.Options{
 			LogConstructor: func(req *reconcile.Request) logr.Logger {
@@ -155,45 +213,133 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Named("istiocni")
 
 	watches.RegisterOwnedWatches(b, watches.CNIWatches, ownedResourceHandler, nil)
+	b = r.Config.ChartSources.Watch(b, versionHandler)
+	b = r.Config.VersionCatalog.Watch(b, versionHandler)
 
 	return b.
 		// +lint-watches:ignore: Namespace (not present in charts, but must be watched to reconcile IstioCni when its namespace is created)
-		Watches(&corev1.Namespace{}, namespaceHandler).
+		Watches(&corev1.Namespace{}, namespaceHandler, builder.OnlyMetadata).
 		Complete(reconciler.NewStandardReconcilerWithFinalizer[*v1.IstioCNI](r.Client, r.Reconcile, r.Finalize, constants.FinalizerName))
 }
 
-func (r *Reconciler) determineStatus(ctx context.Context, cni *v1.IstioCNI, reconcileErr error) (v1.IstioCNIStatus, error) {
+func (r *Reconciler) determineStatus(
+	ctx context.Context, cni *v1.IstioCNI, drift *sharedreconcile.DriftReport, reconcileErr error,
+) (v1.IstioCNIStatus, error) {
 	var errs errlist.Builder
-	reconciledCondition := r.determineReconciledCondition(reconcileErr)
+	rollbackRevision, _ := sharedreconcile.RollbackRevision(cni)
+	reconciledCondition := r.determineReconciledCondition(rollbackRevision, reconcileErr)
 	readyCondition, err := r.determineReadyCondition(ctx, cni)
 	errs.Add(err)
+	var resources []v1.ResourceStatus
+	var history []v1.HelmReleaseRevision
+	var conflicts *sharedreconcile.FieldConflictReport
+	if r.ChartManager != nil {
+		cniReconciler := r.newCNIReconciler(cni)
+		resources, err = cniReconciler.CheckReadiness(ctx, cni.Spec.Namespace)
+		errs.Add(err)
+		history, err = cniReconciler.ReleaseHistory(ctx, cni.Spec.Namespace)
+		errs.Add(err)
+		conflicts = cniReconciler.FieldConflicts(cni.Spec.Namespace)
+	}
+	readyCondition = reconciler.ApplyResourcesReadiness(readyCondition, resources, v1.IstioCNIReasonResourcesNotReady)
 
 	status := *cni.Status.DeepCopy()
 	status.ObservedGeneration = cni.Generation
+	status.ResolvedVersion, _ = istioversion.Resolve(cni.Spec.Version)
+	status.Resources = resources
+	status.ReleaseHistory = history
 	status.SetCondition(reconciledCondition)
 	status.SetCondition(readyCondition)
+	if driftCondition := r.determineDriftCondition(cni, drift); driftCondition != nil {
+		status.SetCondition(*driftCondition)
+	}
+	if conflictsCondition := r.determineFieldConflictsCondition(conflicts); conflictsCondition != nil {
+		status.SetCondition(*conflictsCondition)
+	}
 	status.State = reconciler.DeriveState(v1.IstioCNIReasonHealthy, reconciledCondition, readyCondition)
 	return status, errs.Error()
 }
 
-func (r *Reconciler) updateStatus(ctx context.Context, cni *v1.IstioCNI, reconcileErr error) error {
-	status, err := r.determineStatus(ctx, cni, reconcileErr)
+func (r *Reconciler) updateStatus(ctx context.Context, cni *v1.IstioCNI, drift *sharedreconcile.DriftReport, reconcileErr error) error {
+	status, err := r.determineStatus(ctx, cni, drift, reconcileErr)
 	return reconciler.UpdateStatus(ctx, r.Client, cni, cni.Status, status, err)
 }
 
-func (r *Reconciler) determineReconciledCondition(err error) v1.StatusCondition {
+func (r *Reconciler) determineReconciledCondition(rollbackRevision int, err error) v1.StatusCondition {
 	c := v1.StatusCondition{Type: v1.IstioCNIConditionReconciled}
-	if err == nil {
+	if err == nil && rollbackRevision > 0 {
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1.IstioCNIReasonRolledBack
+		c.Message = fmt.Sprintf("the istio-cni Helm release was rolled back to revision %d because the %s annotation is set; "+
+			"remove the annotation to install the version and values defined in the spec", rollbackRevision, constants.RollbackAnnotationKey)
+	} else if err == nil {
 		c.Status = metav1.ConditionTrue
 		c.Reason = v1.ConditionReason(v1.IstioCNIConditionReconciled)
 	} else {
 		c.Status = metav1.ConditionFalse
-		c.Reason = v1.IstioCNIReasonReconcileError
-		c.Message = fmt.Sprintf("error reconciling resource: %v", err)
+		switch {
+		case helm.IsPatchError(err):
+			c.Reason = v1.IstioCNIReasonInvalidPatch
+			c.Message = err.Error()
+		default:
+			c.Reason = v1.IstioCNIReasonReconcileError
+			c.Message = fmt.Sprintf("error reconciling resource: %v", err)
+		}
 	}
 	return c
 }
 
+// determineDriftCondition returns the DriftDetected condition, or nil if drift wasn't checked. A condition
+// that reports reverted drift is kept until the spec of the IstioCNI changes, so that users can see it.
+func (r *Reconciler) determineDriftCondition(cni *v1.IstioCNI, drift *sharedreconcile.DriftReport) *v1.StatusCondition {
+	if drift == nil {
+		return nil
+	}
+
+	c := v1.StatusCondition{Type: v1.IstioCNIConditionDriftDetected}
+	switch {
+	case drift.Err != nil:
+		c.Status = metav1.ConditionUnknown
+		c.Reason = v1.IstioCNIReasonDriftCheckFailed
+		c.Message = fmt.Sprintf("failed to check for drift: %v", drift.Err)
+	case len(drift.Drifts) == 0:
+		previous := cni.Status.GetCondition(v1.IstioCNIConditionDriftDetected)
+		if previous.Reason == v1.IstioCNIReasonDriftReverted && cni.Status.ObservedGeneration == cni.Generation {
+			return &previous
+		}
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1.IstioCNIReasonNoDrift
+	case drift.Reverted:
+		c.Status = metav1.ConditionTrue
+		c.Reason = v1.IstioCNIReasonDriftReverted
+		c.Message = "reverted changes made outside of the operator: " + drift.Message()
+	default:
+		c.Status = metav1.ConditionTrue
+		c.Reason = v1.IstioCNIReasonDriftReported
+		c.Message = "found changes made outside of the operator: " + drift.Message()
+	}
+	return &c
+}
+
+// determineFieldConflictsCondition returns the FieldConflicts condition, or nil if the operator doesn't use
+// server-side apply.
+func (r *Reconciler) determineFieldConflictsCondition(conflicts *sharedreconcile.FieldConflictReport) *v1.StatusCondition {
+	if conflicts == nil {
+		return nil
+	}
+
+	c := v1.StatusCondition{Type: v1.IstioCNIConditionFieldConflicts}
+	if len(conflicts.Conflicts) == 0 {
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1.IstioCNIReasonNoConflicts
+	} else {
+		c.Status = metav1.ConditionTrue
+		c.Reason = v1.IstioCNIReasonConflictsSkipped
+		c.Message = "kept fields owned by other field managers: " + conflicts.Message()
+	}
+	return &c
+}
+
 func (r *Reconciler) determineReadyCondition(ctx context.Context, cni *v1.IstioCNI) (v1.StatusCondition, error) {
 	return reconciler.CheckDaemonSetReadiness(ctx, r.Client, r.cniDaemonSetKey(cni),
 		"istio-cni-node", v1.IstioCNIConditionReady, v1.IstioCNIDaemonSetNotReady, v1.IstioCNIReasonReadinessCheckFailed)
@@ -206,6 +352,22 @@ func (r *Reconciler) cniDaemonSetKey(cni *v1.IstioCNI) client.ObjectKey {
 	}
 }
 
+// mapIstioCNIToReconcileRequests returns the IstioCNI and the other IstioCNIs that use the same namespace, so that an
+// IstioCNI that was rejected because of another one in its namespace is reconciled again when the other one is deleted
+func (r *Reconciler) mapIstioCNIToReconcileRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	requests := []reconcile.Request{{NamespacedName: types.NamespacedName{Name: obj.GetName()}}}
+	cni, ok := obj.(*v1.IstioCNI)
+	if !ok {
+		return requests
+	}
+	for _, req := range r.mapNamespaceToReconcileRequest(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: cni.Spec.Namespace}}) {
+		if req.Name != cni.Name {
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
 func (r *Reconciler) mapNamespaceToReconcileRequest(ctx context.Context, ns client.Object) []reconcile.Request {
 	log := logf.FromContext(ctx)
 
@@ -225,6 +387,23 @@ func (r *Reconciler) mapNamespaceToReconcileRequest(ctx context.Context, ns clie
 	return requests
 }
 
+// mapVersionChangeToReconcileRequests returns all IstioCNI objects whose spec.version may refer to different charts
+// after the versions provided by IstioChartSources or the IstioVersionCatalog have changed.
+func (r *Reconciler) mapVersionChangeToReconcileRequests(ctx context.Context, _ client.Object) []reconcile.Request {
+	list := v1.IstioCNIList{}
+	if err := r.Client.List(ctx, &list); err != nil {
+		logf.FromContext(ctx).Error(err, "failed to list IstioCNIs")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, cni := range list.Items {
+		if istioversion.IsAffectedByChange(cni.Spec.Version, cni.Status.ResolvedVersion) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: cni.Name}})
+		}
+	}
+	return requests
+}
+
 func wrapEventHandler(logger logr.Logger, handler handler.EventHandler) handler.EventHandler {
 	return enqueuelogger.WrapIfNecessary(v1.IstioCNIKind, logger, handler)
 }
@@ -19,10 +19,13 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
 	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
 	"github.com/istio-ecosystem/sail-operator/pkg/istiovalues"
 	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
 	sharedreconcile "github.com/istio-ecosystem/sail-operator/pkg/reconcile"
@@ -32,9 +35,11 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"istio.io/istio/pkg/ptr"
 )
@@ -663,16 +668,25 @@ func TestDetermineStatus(t *testing.T) {
 	cfg := newReconcilerTestConfig(t)
 
 	tests := []struct {
-		name         string
-		reconcileErr error
+		name           string
+		annotations    map[string]string
+		reconcileErr   error
+		expectedReason v1.IstioCNIConditionReason
 	}{
 		{
-			name:         "no error",
-			reconcileErr: nil,
+			name:           "no error",
+			reconcileErr:   nil,
+			expectedReason: v1.IstioCNIConditionReason(v1.IstioCNIConditionReconciled),
+		},
+		{
+			name:           "reconcile error",
+			reconcileErr:   fmt.Errorf("some reconcile error"),
+			expectedReason: v1.IstioCNIReasonReconcileError,
 		},
 		{
-			name:         "reconcile error",
-			reconcileErr: fmt.Errorf("some reconcile error"),
+			name:           "rolled back",
+			annotations:    map[string]string{constants.RollbackAnnotationKey: "2"},
+			expectedReason: v1.IstioCNIReasonRolledBack,
 		},
 	}
 
@@ -686,17 +700,20 @@ func TestDetermineStatus(t *testing.T) {
 
 			cni := &v1.IstioCNI{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:       "my-cni",
-					Generation: 123,
+					Name:        "my-cni",
+					Generation:  123,
+					Annotations: tt.annotations,
 				},
 			}
 
-			status, err := r.determineStatus(ctx, cni, tt.reconcileErr)
+			status, err := r.determineStatus(ctx, cni, nil, tt.reconcileErr)
 			g.Expect(err).ToNot(HaveOccurred())
 
 			g.Expect(status.ObservedGeneration).To(Equal(cni.Generation))
 
-			reconciledCondition := r.determineReconciledCondition(tt.reconcileErr)
+			rollbackRevision, _ := sharedreconcile.RollbackRevision(cni)
+			reconciledCondition := r.determineReconciledCondition(rollbackRevision, tt.reconcileErr)
+			g.Expect(reconciledCondition.Reason).To(Equal(tt.expectedReason))
 			readyCondition, err := r.determineReadyCondition(ctx, cni)
 			g.Expect(err).ToNot(HaveOccurred())
 
@@ -707,6 +724,164 @@ func TestDetermineStatus(t *testing.T) {
 	}
 }
 
+func TestDetermineDriftCondition(t *testing.T) {
+	cfg := newReconcilerTestConfig(t)
+	r := NewReconciler(cfg, fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(), scheme.Scheme, nil)
+
+	drifts := []helm.Drift{{
+		Object: helm.ObjectReference{APIVersion: "apps/v1", Kind: "DaemonSet", Namespace: "istio-cni", Name: "istio-cni-node"},
+		Fields: []string{"spec.template.spec.containers[0].image"},
+	}}
+	revertedCondition := v1.StatusCondition{
+		Type:    v1.IstioCNIConditionDriftDetected,
+		Status:  metav1.ConditionTrue,
+		Reason:  v1.IstioCNIReasonDriftReverted,
+		Message: "reverted changes made outside of the operator: DaemonSet istio-cni/istio-cni-node (spec.template.spec.containers[0].image)",
+	}
+
+	tests := []struct {
+		name               string
+		drift              *sharedreconcile.DriftReport
+		observedGeneration int64
+		previous           *v1.StatusCondition
+		expected           *v1.StatusCondition
+	}{
+		{
+			name:     "drift not checked",
+			drift:    nil,
+			expected: nil,
+		},
+		{
+			name:  "drift check failed",
+			drift: &sharedreconcile.DriftReport{Err: fmt.Errorf("simulated error")},
+			expected: &v1.StatusCondition{
+				Type:    v1.IstioCNIConditionDriftDetected,
+				Status:  metav1.ConditionUnknown,
+				Reason:  v1.IstioCNIReasonDriftCheckFailed,
+				Message: "failed to check for drift: simulated error",
+			},
+		},
+		{
+			name:  "no drift",
+			drift: &sharedreconcile.DriftReport{},
+			expected: &v1.StatusCondition{
+				Type:   v1.IstioCNIConditionDriftDetected,
+				Status: metav1.ConditionFalse,
+				Reason: v1.IstioCNIReasonNoDrift,
+			},
+		},
+		{
+			name:     "drift reverted",
+			drift:    &sharedreconcile.DriftReport{Drifts: drifts, Revert: true, Reverted: true},
+			expected: &revertedCondition,
+		},
+		{
+			name:  "drift reported",
+			drift: &sharedreconcile.DriftReport{Drifts: drifts, Keep: true},
+			expected: &v1.StatusCondition{
+				Type:    v1.IstioCNIConditionDriftDetected,
+				Status:  metav1.ConditionTrue,
+				Reason:  v1.IstioCNIReasonDriftReported,
+				Message: "found changes made outside of the operator: DaemonSet istio-cni/istio-cni-node (spec.template.spec.containers[0].image)",
+			},
+		},
+		{
+			name:               "reverted drift is kept while generation is unchanged",
+			drift:              &sharedreconcile.DriftReport{},
+			observedGeneration: 2,
+			previous:           &revertedCondition,
+			expected:           &revertedCondition,
+		},
+		{
+			name:               "reverted drift is cleared when generation changes",
+			drift:              &sharedreconcile.DriftReport{},
+			observedGeneration: 1,
+			previous:           &revertedCondition,
+			expected: &v1.StatusCondition{
+				Type:   v1.IstioCNIConditionDriftDetected,
+				Status: metav1.ConditionFalse,
+				Reason: v1.IstioCNIReasonNoDrift,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			cni := &v1.IstioCNI{
+				ObjectMeta: metav1.ObjectMeta{Name: "default", Generation: 2},
+				Status:     v1.IstioCNIStatus{ObservedGeneration: tt.observedGeneration},
+			}
+			if tt.previous != nil {
+				cni.Status.SetCondition(*tt.previous)
+			}
+
+			condition := r.determineDriftCondition(cni, tt.drift)
+			if tt.expected == nil {
+				g.Expect(condition).To(BeNil())
+				return
+			}
+			g.Expect(condition).ToNot(BeNil())
+			g.Expect(normalize(*condition)).To(Equal(normalize(*tt.expected)))
+		})
+	}
+}
+
+func TestDetermineFieldConflictsCondition(t *testing.T) {
+	cfg := newReconcilerTestConfig(t)
+	r := NewReconciler(cfg, fake.NewClientBuilder().WithScheme(scheme.Scheme).Build(), scheme.Scheme, nil)
+
+	tests := []struct {
+		name      string
+		conflicts *sharedreconcile.FieldConflictReport
+		expected  *v1.StatusCondition
+	}{
+		{
+			name:      "server-side apply disabled",
+			conflicts: nil,
+			expected:  nil,
+		},
+		{
+			name:      "no conflicts",
+			conflicts: &sharedreconcile.FieldConflictReport{},
+			expected: &v1.StatusCondition{
+				Type:   v1.IstioCNIConditionFieldConflicts,
+				Status: metav1.ConditionFalse,
+				Reason: v1.IstioCNIReasonNoConflicts,
+			},
+		},
+		{
+			name: "conflicts skipped",
+			conflicts: &sharedreconcile.FieldConflictReport{Conflicts: []helm.FieldConflict{{
+				Object:  helm.ObjectReference{APIVersion: "apps/v1", Kind: "DaemonSet", Namespace: "istio-cni", Name: "istio-cni-node"},
+				Field:   "spec.template.spec.containers[0].image",
+				Manager: "argocd-controller",
+			}}},
+			expected: &v1.StatusCondition{
+				Type:   v1.IstioCNIConditionFieldConflicts,
+				Status: metav1.ConditionTrue,
+				Reason: v1.IstioCNIReasonConflictsSkipped,
+				Message: "kept fields owned by other field managers: " +
+					"DaemonSet istio-cni/istio-cni-node spec.template.spec.containers[0].image (owned by argocd-controller)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			condition := r.determineFieldConflictsCondition(tt.conflicts)
+			if tt.expected == nil {
+				g.Expect(condition).To(BeNil())
+				return
+			}
+			g.Expect(condition).ToNot(BeNil())
+			g.Expect(normalize(*condition)).To(Equal(normalize(*tt.expected)))
+		})
+	}
+}
+
 func normalize(condition v1.StatusCondition) v1.StatusCondition {
 	condition.LastTransitionTime = metav1.Time{}
 	return condition
@@ -720,3 +895,36 @@ func newReconcilerTestConfig(t *testing.T) config.ReconcilerConfig {
 		MaxConcurrentReconciles: 1,
 	}
 }
+
+func TestValidateNamespaceNotShared(t *testing.T) {
+	earlyTimestamp := metav1.Now()
+	lateTimestamp := metav1.NewTime(earlyTimestamp.Add(time.Hour))
+
+	newCNI := func(name, namespace string, creationTimestamp metav1.Time) *v1.IstioCNI {
+		return &v1.IstioCNI{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: creationTimestamp},
+			Spec:       v1.IstioCNISpec{Version: istioversion.Default, Namespace: namespace},
+		}
+	}
+	defaultCNI := newCNI("default", "istio-cni", earlyTimestamp)
+	sharedCNI := newCNI("pool-a", "istio-cni", lateTimestamp)
+	otherCNI := newCNI("pool-b", "istio-cni-b", lateTimestamp)
+
+	g := NewWithT(t)
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(defaultCNI, sharedCNI, otherCNI).Build()
+	r := NewReconciler(newReconcilerTestConfig(t), cl, scheme.Scheme, nil)
+
+	g.Expect(r.validateNamespaceNotShared(context.TODO(), defaultCNI)).To(Succeed())
+	g.Expect(r.validateNamespaceNotShared(context.TODO(), otherCNI)).To(Succeed())
+	err := r.validateNamespaceNotShared(context.TODO(), sharedCNI)
+	g.Expect(reconciler.IsValidationError(err)).To(BeTrue())
+	g.Expect(err).To(MatchError(ContainSubstring(`namespace "istio-cni" is already used by IstioCNI "default"`)))
+
+	g.Expect(r.mapIstioCNIToReconcileRequests(context.TODO(), defaultCNI)).To(ConsistOf(
+		reconcile.Request{NamespacedName: types.NamespacedName{Name: "default"}},
+		reconcile.Request{NamespacedName: types.NamespacedName{Name: "pool-a"}},
+	))
+	g.Expect(r.mapIstioCNIToReconcileRequests(context.TODO(), otherCNI)).To(ConsistOf(
+		reconcile.Request{NamespacedName: types.NamespacedName{Name: "pool-b"}},
+	))
+}
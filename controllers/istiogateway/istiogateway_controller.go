@@ -0,0 +1,317 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiogateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/enqueuelogger"
+	"github.com/istio-ecosystem/sail-operator/pkg/errlist"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
+	sharedreconcile "github.com/istio-ecosystem/sail-operator/pkg/reconcile"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	"github.com/istio-ecosystem/sail-operator/pkg/revision"
+	"github.com/istio-ecosystem/sail-operator/pkg/watches"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+// Reconciler reconciles the IstioGateway object
+type Reconciler struct {
+	client.Client
+	Config       config.ReconcilerConfig
+	Scheme       *runtime.Scheme
+	ChartManager *helm.ChartManager
+}
+
+func NewReconciler(cfg config.ReconcilerConfig, client client.Client, scheme *runtime.Scheme, chartManager *helm.ChartManager,
+) *Reconciler {
+	return &Reconciler{
+		Config:       cfg,
+		Client:       client,
+		Scheme:       scheme,
+		ChartManager: chartManager,
+	}
+}
+
+// +kubebuilder:rbac:groups=sailoperator.io,resources=istiogateways,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=sailoperator.io,resources=istiogateways/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=sailoperator.io,resources=istiogateways/finalizers,verbs=update
+// +kubebuilder:rbac:groups="",resources="*",verbs="*"
+// +kubebuilder:rbac:groups="rbac.authorization.k8s.io",resources=roles;rolebindings,verbs="*"
+// +kubebuilder:rbac:groups="apps",resources=deployments,verbs="*"
+// +kubebuilder:rbac:groups="autoscaling",resources=horizontalpodautoscalers,verbs="*"
+// +kubebuilder:rbac:groups="policy",resources=poddisruptionbudgets,verbs="*"
+// +kubebuilder:rbac:groups="networking.k8s.io",resources=networkpolicies,verbs="*"
+
+// Reconcile is part of the main kubernetes reconciliation loop which aims to
+// move the current state of the cluster closer to the desired state.
+//
+// For more details, check Reconcile and its Result here:
+// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.14.1/pkg/reconcile
+func (r *Reconciler) Reconcile(ctx context.Context, gw *v1alpha1.IstioGateway) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	rev, reconcileErr := r.doReconcile(ctx, gw)
+
+	log.Info("Reconciliation done. Updating status.")
+	statusErr := r.updateStatus(ctx, gw, rev, reconcileErr)
+
+	return ctrl.Result{}, errors.Join(reconcileErr, statusErr)
+}
+
+func (r *Reconciler) Finalize(ctx context.Context, gw *v1alpha1.IstioGateway) error {
+	return r.newGatewayReconciler().Uninstall(ctx, gw.Namespace, gw.Name)
+}
+
+func (r *Reconciler) doReconcile(ctx context.Context, gw *v1alpha1.IstioGateway) (*v1.IstioRevision, error) {
+	log := logf.FromContext(ctx)
+	gatewayReconciler := r.newGatewayReconciler()
+
+	log.Info("Retrieving referenced IstioRevision")
+	rev, err := revision.GetIstioRevisionFromTargetReference(ctx, r.Client, gw.Spec.TargetRef)
+	if err != nil {
+		return nil, err
+	}
+
+	// the spec of an IstioRevision in dry-run mode hasn't been installed yet, so the gateway keeps the version
+	// and values of the previous spec
+	if revision.IsDryRun(rev) {
+		log.Info("Referenced IstioRevision is in dry-run mode; keeping the installed gateway Helm chart")
+		return rev, nil
+	}
+
+	version := chartVersion(gw, rev)
+	if err := gatewayReconciler.Validate(ctx, version, gw.Namespace); err != nil {
+		return rev, err
+	}
+
+	log.Info("Installing gateway Helm chart")
+	ownerReference := r.ownerReference(gw)
+	return rev, gatewayReconciler.Install(
+		ctx, version, gw.Namespace, gw.Name, gw.Spec.Values, gw.Spec.Patches, &ownerReference, revisionValues(rev))
+}
+
+func (r *Reconciler) ownerReference(gw *v1alpha1.IstioGateway) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion:         v1alpha1.GroupVersion.String(),
+		Kind:               v1alpha1.IstioGatewayKind,
+		Name:               gw.Name,
+		UID:                gw.UID,
+		Controller:         ptr.Of(true),
+		BlockOwnerDeletion: ptr.Of(true),
+	}
+}
+
+// chartVersion returns the version of the gateway chart to install. Unless spec.version is set, the gateway
+// follows the version of the referenced IstioRevision.
+func chartVersion(gw *v1alpha1.IstioGateway, rev *v1.IstioRevision) string {
+	if gw.Spec.Version != "" || rev == nil {
+		return gw.Spec.Version
+	}
+	return rev.Spec.Version
+}
+
+// revisionValues returns the gateway values derived from the referenced IstioRevision. The pods are labeled
+// with the revision, so that they are injected by its control plane, and annotated with its version, so that
+// they are restarted and receive the new proxy when the control plane is upgraded.
+func revisionValues(rev *v1.IstioRevision) helm.Values {
+	version, err := istioversion.Resolve(rev.Spec.Version)
+	if err != nil {
+		version = rev.Spec.Version
+	}
+	return sharedreconcile.GatewayRevisionValues(version, rev.Spec.Values)
+}
+
+func (r *Reconciler) newGatewayReconciler() *sharedreconcile.GatewayReconciler {
+	return sharedreconcile.NewGatewayReconciler(sharedreconcile.Config{
+		ResourceFS:        r.Config.ResourceFS,
+		Platform:          r.Config.Platform,
+		DefaultProfile:    r.Config.DefaultProfile,
+		OperatorNamespace: r.Config.OperatorNamespace,
+		ChartManager:      r.ChartManager,
+		TLSConfig:         r.Config.TLSConfig,
+	}, r.Client)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	logger := mgr.GetLogger().WithName("ctrlr").WithName("istiogateway")
+
+	// mainObjectHandler handles the IstioGateway watch events
+	mainObjectHandler := wrapEventHandler(logger, &handler.EnqueueRequestForObject{})
+
+	// operatorResourcesHandler handles watch events from operator CRDs Istio and IstioRevision
+	operatorResourcesHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapOperatorResourceToReconcileRequests))
+
+	// ownedResourceHandler handles resources that are owned by the IstioGateway CR
+	ownedResourceHandler := wrapEventHandler(logger,
+		handler.EnqueueRequestForOwner(r.Scheme, r.RESTMapper(), &v1alpha1.IstioGateway{}, handler.OnlyControllerOwner()))
+
+	// versionHandler handles changes to the versions provided by IstioChartSources and the IstioVersionCatalog
+	versionHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapVersionChangeToReconcileRequests))
+
+	b := ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{
+			LogConstructor: func(req *reconcile.Request) logr.Logger {
+				log := logger
+				if req != nil {
+					log = log.WithValues("IstioGateway", req.NamespacedName)
+				}
+				return log
+			},
+			MaxConcurrentReconciles: r.Config.MaxConcurrentReconciles,
+		}).
+		// we use the Watches function instead of For(), so that we can wrap the handler so that events that cause the object to be enqueued are logged
+		Watches(&v1alpha1.IstioGateway{}, mainObjectHandler).
+		Named("istiogateway")
+
+	watches.RegisterOwnedWatches(b, watches.GatewayWatches, ownedResourceHandler, nil)
+	b = r.Config.ChartSources.Watch(b, versionHandler)
+	b = r.Config.VersionCatalog.Watch(b, versionHandler)
+
+	return b.
+		Watches(&v1.Istio{}, operatorResourcesHandler).
+		Watches(&v1.IstioRevision{}, operatorResourcesHandler).
+		Complete(reconciler.NewStandardReconcilerWithFinalizer[*v1alpha1.IstioGateway](r.Client, r.Reconcile, r.Finalize, constants.FinalizerName))
+}
+
+func (r *Reconciler) determineStatus(
+	ctx context.Context, gw *v1alpha1.IstioGateway, rev *v1.IstioRevision, reconcileErr error,
+) (v1alpha1.IstioGatewayStatus, error) {
+	var errs errlist.Builder
+	reconciledCondition := r.determineReconciledCondition(reconcileErr)
+	readyCondition, err := r.determineReadyCondition(ctx, gw)
+	errs.Add(err)
+	var resources []v1.ResourceStatus
+	if r.ChartManager != nil {
+		resources, err = r.newGatewayReconciler().CheckReadiness(ctx, gw.Namespace, gw.Name)
+		errs.Add(err)
+	}
+	readyCondition = reconciler.ApplyResourcesReadiness(readyCondition, resources, v1alpha1.IstioGatewayReasonResourcesNotReady)
+
+	status := *gw.Status.DeepCopy()
+	status.ObservedGeneration = gw.Generation
+	if rev == nil || !revision.IsDryRun(rev) {
+		status.ResolvedVersion = ""
+		status.IstioRevision = ""
+		if rev != nil {
+			status.ResolvedVersion, _ = istioversion.Resolve(chartVersion(gw, rev))
+			status.IstioRevision = rev.Name
+		}
+	}
+	status.Resources = resources
+	status.SetCondition(reconciledCondition)
+	status.SetCondition(readyCondition)
+	status.State = reconciler.DeriveState(v1alpha1.IstioGatewayReasonHealthy, reconciledCondition, readyCondition)
+	return status, errs.Error()
+}
+
+func (r *Reconciler) updateStatus(ctx context.Context, gw *v1alpha1.IstioGateway, rev *v1.IstioRevision, reconcileErr error) error {
+	status, err := r.determineStatus(ctx, gw, rev, reconcileErr)
+	return reconciler.UpdateStatus(ctx, r.Client, gw, gw.Status, status, err)
+}
+
+func (r *Reconciler) determineReconciledCondition(err error) v1.StatusCondition {
+	c := v1.StatusCondition{Type: v1alpha1.IstioGatewayConditionReconciled}
+	if err == nil {
+		c.Status = metav1.ConditionTrue
+		c.Reason = v1.ConditionReason(v1alpha1.IstioGatewayConditionReconciled)
+	} else {
+		c.Status = metav1.ConditionFalse
+		switch {
+		case helm.IsPatchError(err):
+			c.Reason = v1alpha1.IstioGatewayReasonInvalidPatch
+			c.Message = err.Error()
+		default:
+			c.Reason = v1alpha1.IstioGatewayReasonReconcileError
+			c.Message = fmt.Sprintf("error reconciling resource: %v", err)
+		}
+	}
+	return c
+}
+
+func (r *Reconciler) determineReadyCondition(ctx context.Context, gw *v1alpha1.IstioGateway) (v1.StatusCondition, error) {
+	return reconciler.CheckDeploymentReadiness(ctx, r.Client, client.ObjectKeyFromObject(gw),
+		"gateway", v1alpha1.IstioGatewayConditionReady, v1alpha1.IstioGatewayReasonDeploymentNotReady,
+		v1alpha1.IstioGatewayReasonReadinessCheckFailed)
+}
+
+// mapOperatorResourceToReconcileRequests returns the IstioGateways that reference the given Istio or IstioRevision,
+// or that are currently injected by the given IstioRevision.
+func (r *Reconciler) mapOperatorResourceToReconcileRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	var kind string
+	switch obj.(type) {
+	case *v1.Istio:
+		kind = v1.IstioKind
+	case *v1.IstioRevision:
+		kind = v1.IstioRevisionKind
+	default:
+		return nil
+	}
+	list := v1alpha1.IstioGatewayList{}
+	if err := r.Client.List(ctx, &list); err != nil {
+		logf.FromContext(ctx).Error(err, "failed to list IstioGateways")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, gw := range list.Items {
+		referenced := gw.Spec.TargetRef.Kind == kind && gw.Spec.TargetRef.Name == obj.GetName()
+		injected := kind == v1.IstioRevisionKind && gw.Status.IstioRevision == obj.GetName()
+		if referenced || injected {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: gw.Namespace, Name: gw.Name}})
+		}
+	}
+	return requests
+}
+
+// mapVersionChangeToReconcileRequests returns all IstioGateways whose chart version may refer to different charts
+// after the versions provided by IstioChartSources or the IstioVersionCatalog have changed.
+func (r *Reconciler) mapVersionChangeToReconcileRequests(ctx context.Context, _ client.Object) []reconcile.Request {
+	list := v1alpha1.IstioGatewayList{}
+	if err := r.Client.List(ctx, &list); err != nil {
+		logf.FromContext(ctx).Error(err, "failed to list IstioGateways")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, gw := range list.Items {
+		// gateways without spec.version follow their IstioRevision, which is reconciled when it changes
+		if gw.Spec.Version != "" && istioversion.IsAffectedByChange(gw.Spec.Version, gw.Status.ResolvedVersion) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: gw.Namespace, Name: gw.Name}})
+		}
+	}
+	return requests
+}
+
+func wrapEventHandler(logger logr.Logger, handler handler.EventHandler) handler.EventHandler {
+	return enqueuelogger.WrapIfNecessary(v1alpha1.IstioGatewayKind, logger, handler)
+}
@@ -0,0 +1,201 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiogateway
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
+	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestChartVersion(t *testing.T) {
+	g := NewWithT(t)
+	rev := &v1.IstioRevision{Spec: v1.IstioRevisionSpec{Version: "v1.30.3"}}
+
+	g.Expect(chartVersion(&v1alpha1.IstioGateway{}, rev)).To(Equal("v1.30.3"))
+	g.Expect(chartVersion(&v1alpha1.IstioGateway{Spec: v1alpha1.IstioGatewaySpec{Version: "v1.30.2"}}, rev)).To(Equal("v1.30.2"))
+	g.Expect(chartVersion(&v1alpha1.IstioGateway{}, nil)).To(BeEmpty())
+}
+
+func TestRevisionValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		rev      *v1.IstioRevision
+		expected helm.Values
+	}{
+		{
+			name: "no values",
+			rev:  &v1.IstioRevision{Spec: v1.IstioRevisionSpec{Version: istioversion.Default}},
+			expected: helm.Values{
+				"podAnnotations": map[string]any{constants.IstioVersionKey: istioversion.Default},
+			},
+		},
+		{
+			name: "revision and network policy",
+			rev: &v1.IstioRevision{Spec: v1.IstioRevisionSpec{
+				Version: istioversion.Default,
+				Values: &v1.Values{
+					Revision: ptr.Of("canary"),
+					Global: &v1.GlobalConfig{
+						Hub:           ptr.Of("quay.io/example"),
+						NetworkPolicy: &v1.NetworkPolicyConfig{Enabled: ptr.Of(true)},
+					},
+				},
+			}},
+			expected: helm.Values{
+				"podAnnotations": map[string]any{constants.IstioVersionKey: istioversion.Default},
+				"revision":       "canary",
+				"global":         helm.Values{"networkPolicy": map[string]any{"enabled": true}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(revisionValues(tt.rev)).To(Equal(tt.expected))
+		})
+	}
+}
+
+func TestDoReconcileWithoutRevision(t *testing.T) {
+	g := NewWithT(t)
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	r := NewReconciler(newReconcilerTestConfig(t), cl, scheme.Scheme, nil)
+
+	gw := &v1alpha1.IstioGateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "istio-ingressgateway", Namespace: "istio-ingress"},
+		Spec: v1alpha1.IstioGatewaySpec{
+			TargetRef: v1.TargetReference{Kind: v1.IstioKind, Name: "default"},
+		},
+	}
+	rev, err := r.doReconcile(context.TODO(), gw)
+	g.Expect(err).To(HaveOccurred())
+	g.Expect(rev).To(BeNil())
+}
+
+func TestDetermineStatus(t *testing.T) {
+	ctx := context.TODO()
+	rev := &v1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec:       v1.IstioRevisionSpec{Version: istioversion.Default},
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "istio-ingressgateway", Namespace: "istio-ingress"},
+		Status:     appsv1.DeploymentStatus{Replicas: 1, ReadyReplicas: 1},
+	}
+
+	tests := []struct {
+		name          string
+		rev           *v1.IstioRevision
+		objects       []client.Object
+		reconcileErr  error
+		expectedState v1.ConditionReason
+	}{
+		{
+			name:          "healthy",
+			rev:           rev,
+			objects:       []client.Object{deployment},
+			expectedState: v1alpha1.IstioGatewayReasonHealthy,
+		},
+		{
+			name:          "deployment not found",
+			rev:           rev,
+			expectedState: v1alpha1.IstioGatewayReasonDeploymentNotReady,
+		},
+		{
+			name:          "reconcile error",
+			objects:       []client.Object{deployment},
+			reconcileErr:  fmt.Errorf("some reconcile error"),
+			expectedState: v1alpha1.IstioGatewayReasonReconcileError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(tt.objects...).Build()
+			r := NewReconciler(newReconcilerTestConfig(t), cl, scheme.Scheme, nil)
+
+			gw := &v1alpha1.IstioGateway{
+				ObjectMeta: metav1.ObjectMeta{Name: "istio-ingressgateway", Namespace: "istio-ingress", Generation: 123},
+			}
+			status, err := r.determineStatus(ctx, gw, tt.rev, tt.reconcileErr)
+			g.Expect(err).ToNot(HaveOccurred())
+
+			g.Expect(status.ObservedGeneration).To(Equal(gw.Generation))
+			g.Expect(status.State).To(Equal(tt.expectedState))
+			if tt.rev != nil {
+				g.Expect(status.IstioRevision).To(Equal(tt.rev.Name))
+				g.Expect(status.ResolvedVersion).To(Equal(istioversion.Default))
+			} else {
+				g.Expect(status.IstioRevision).To(BeEmpty())
+				g.Expect(status.ResolvedVersion).To(BeEmpty())
+			}
+		})
+	}
+}
+
+func TestMapOperatorResourceToReconcileRequests(t *testing.T) {
+	g := NewWithT(t)
+	gateways := []client.Object{
+		&v1alpha1.IstioGateway{
+			ObjectMeta: metav1.ObjectMeta{Name: "by-istio", Namespace: "ns1"},
+			Spec:       v1alpha1.IstioGatewaySpec{TargetRef: v1.TargetReference{Kind: v1.IstioKind, Name: "default"}},
+			Status:     v1alpha1.IstioGatewayStatus{IstioRevision: "default-v1-30-3"},
+		},
+		&v1alpha1.IstioGateway{
+			ObjectMeta: metav1.ObjectMeta{Name: "by-revision", Namespace: "ns2"},
+			Spec:       v1alpha1.IstioGatewaySpec{TargetRef: v1.TargetReference{Kind: v1.IstioRevisionKind, Name: "canary"}},
+		},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(gateways...).Build()
+	r := NewReconciler(newReconcilerTestConfig(t), cl, scheme.Scheme, nil)
+	ctx := context.TODO()
+
+	g.Expect(r.mapOperatorResourceToReconcileRequests(ctx, &v1.Istio{ObjectMeta: metav1.ObjectMeta{Name: "default"}})).
+		To(ConsistOf(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "by-istio"}}))
+	g.Expect(r.mapOperatorResourceToReconcileRequests(ctx, &v1.IstioRevision{ObjectMeta: metav1.ObjectMeta{Name: "default-v1-30-3"}})).
+		To(ConsistOf(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns1", Name: "by-istio"}}))
+	g.Expect(r.mapOperatorResourceToReconcileRequests(ctx, &v1.IstioRevision{ObjectMeta: metav1.ObjectMeta{Name: "canary"}})).
+		To(ConsistOf(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "ns2", Name: "by-revision"}}))
+	g.Expect(r.mapOperatorResourceToReconcileRequests(ctx, &v1.Istio{ObjectMeta: metav1.ObjectMeta{Name: "other"}})).To(BeEmpty())
+}
+
+func newReconcilerTestConfig(t *testing.T) config.ReconcilerConfig {
+	return config.ReconcilerConfig{
+		ResourceFS:              os.DirFS(t.TempDir()),
+		Platform:                config.PlatformKubernetes,
+		DefaultProfile:          "",
+		MaxConcurrentReconciles: 1,
+	}
+}
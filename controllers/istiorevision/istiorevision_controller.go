@@ -27,6 +27,7 @@ import (
 	"github.com/istio-ecosystem/sail-operator/pkg/enqueuelogger"
 	"github.com/istio-ecosystem/sail-operator/pkg/errlist"
 	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
 	predicate2 "github.com/istio-ecosystem/sail-operator/pkg/predicate"
 	sharedreconcile "github.com/istio-ecosystem/sail-operator/pkg/reconcile"
 	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
@@ -52,25 +53,31 @@ import (
 	"istio.io/istio/pkg/ptr"
 )
 
-const (
-	istioCniName = "default"
-	ztunnelName  = "default"
-)
-
 // Reconciler reconciles an IstioRevision object
 type Reconciler struct {
 	client.Client
 	Config       config.ReconcilerConfig
 	Scheme       *runtime.Scheme
 	ChartManager *helm.ChartManager
+
+	// UsageReader looks up the namespaces and pods that reference a revision. It must be backed by a cache
+	// that has the indexes returned by revision.UsageIndexes.
+	UsageReader client.Reader
+
+	// APIReader reads the phase of the pods found through UsageReader, since the cache only holds their metadata.
+	APIReader client.Reader
 }
 
-func NewReconciler(cfg config.ReconcilerConfig, client client.Client, scheme *runtime.Scheme, chartManager *helm.ChartManager) *Reconciler {
+func NewReconciler(
+	cfg config.ReconcilerConfig, client client.Client, usageReader, apiReader client.Reader, scheme *runtime.Scheme, chartManager *helm.ChartManager,
+) *Reconciler {
 	return &Reconciler{
 		Config:       cfg,
 		Client:       client,
 		Scheme:       scheme,
 		ChartManager: chartManager,
+		UsageReader:  usageReader,
+		APIReader:    apiReader,
 	}
 }
 
@@ -85,6 +92,7 @@ func NewReconciler(cfg config.ReconcilerConfig, client client.Client, scheme *ru
 // +kubebuilder:rbac:groups="admissionregistration.k8s.io",resources=validatingwebhookconfigurations;mutatingwebhookconfigurations,verbs="*"
 // +kubebuilder:rbac:groups="autoscaling",resources=horizontalpodautoscalers,verbs="*"
 // +kubebuilder:rbac:groups="discovery.k8s.io",resources=endpointslices,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups="gateway.networking.k8s.io",resources=gateways,verbs=get;list;watch
 // +kubebuilder:rbac:groups="apiextensions.k8s.io",resources=customresourcedefinitions,verbs=get;list;watch
 // +kubebuilder:rbac:groups="k8s.cni.cncf.io",resources=network-attachment-definitions,verbs="*"
 // +kubebuilder:rbac:groups="security.openshift.io",resources=securitycontextconstraints,resourceNames=privileged,verbs=use
@@ -98,32 +106,48 @@ func NewReconciler(cfg config.ReconcilerConfig, client client.Client, scheme *ru
 func (r *Reconciler) Reconcile(ctx context.Context, rev *v1.IstioRevision) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
 
-	reconcileErr := r.doReconcile(ctx, rev)
+	outcome, reconcileErr := r.doReconcile(ctx, rev)
 
 	log.Info("Reconciliation done. Updating status.")
-	statusErr := r.updateStatus(ctx, rev, reconcileErr)
+	statusErr := r.updateStatus(ctx, rev, outcome, reconcileErr)
 
 	return ctrl.Result{}, errors.Join(reconcileErr, statusErr)
 }
 
-func (r *Reconciler) doReconcile(ctx context.Context, rev *v1.IstioRevision) error {
+// reconcileOutcome holds the information determined during reconciliation that is reported in the status.
+type reconcileOutcome struct {
+	// plan is only set in dry-run mode.
+	plan *v1.IstioRevisionPlan
+
+	// drift is nil when drift wasn't checked during the reconciliation.
+	drift *sharedreconcile.DriftReport
+
+	// rollbackRevision is the revision of the istiod Helm release that was reinstalled because of the
+	// sailoperator.io/rollback-to annotation. It's 0 if no rollback was requested.
+	rollbackRevision int
+}
+
+// doReconcile installs the Helm charts of the IstioRevision. In dry-run mode, the charts aren't installed;
+// instead, the changes that would be made to the cluster are returned as a plan.
+func (r *Reconciler) doReconcile(ctx context.Context, rev *v1.IstioRevision) (reconcileOutcome, error) {
 	log := logf.FromContext(ctx)
 	istiodReconciler := r.newIstiodReconciler()
 
+	var outcome reconcileOutcome
+
 	// CRD-specific validations
 	if err := r.validateRevisionConsistency(rev); err != nil {
-		return err
+		return outcome, err
 	}
 	if err := r.validateNoTagConflict(ctx, rev); err != nil {
-		return err
+		return outcome, err
 	}
 
 	// General validations
 	if err := istiodReconciler.Validate(ctx, rev.Spec.Version, rev.Spec.Namespace, rev.Spec.Values); err != nil {
-		return err
+		return outcome, err
 	}
 
-	log.Info("Installing Helm chart")
 	ownerReference := metav1.OwnerReference{
 		APIVersion:         v1.GroupVersion.String(),
 		Kind:               v1.IstioRevisionKind,
@@ -132,22 +156,104 @@ func (r *Reconciler) doReconcile(ctx context.Context, rev *v1.IstioRevision) err
 		Controller:         ptr.Of(true),
 		BlockOwnerDeletion: ptr.Of(true),
 	}
-	return istiodReconciler.Install(ctx, rev.Spec.Version, rev.Spec.Namespace, rev.Spec.Values, rev.Name, &ownerReference)
+
+	if revision.IsDryRun(rev) {
+		log.Info("Dry-run mode enabled. Computing the changes to the Helm releases")
+		istiodReconciler.KeepingFields(istiodReconciler.DetectDrift(ctx, rev.Spec.Namespace, rev.Name, rev.Spec.DriftPolicy))
+		diff, err := istiodReconciler.Plan(ctx, rev.Spec.Version, rev.Spec.Namespace, rev.Spec.Values, rev.Spec.Patches, rev.Name, &ownerReference)
+		if err != nil {
+			return outcome, err
+		}
+		tagDiff, err := r.planTags(ctx, rev)
+		if err != nil {
+			return outcome, err
+		}
+		diff.Append(tagDiff)
+		outcome.plan = revision.NewPlan(diff)
+		return outcome, nil
+	}
+
+	if rollbackRevision, err := sharedreconcile.RollbackRevision(rev); err != nil {
+		return outcome, err
+	} else if rollbackRevision > 0 {
+		log.Info("Rollback requested. Reinstalling a previous revision of the istiod Helm release", "revision", rollbackRevision)
+		if _, err := istiodReconciler.Rollback(ctx, rev.Spec.Namespace, rev.Name, rollbackRevision); err != nil {
+			return outcome, err
+		}
+		outcome.rollbackRevision = rollbackRevision
+		return outcome, nil
+	}
+
+	outcome.drift = istiodReconciler.DetectDrift(ctx, rev.Spec.Namespace, rev.Name, rev.Spec.DriftPolicy)
+	istiodReconciler.KeepingFields(outcome.drift)
+	if !outcome.drift.UpgradeAllowed() {
+		diff, err := istiodReconciler.Plan(ctx, rev.Spec.Version, rev.Spec.Namespace, rev.Spec.Values, rev.Spec.Patches, rev.Name, &ownerReference)
+		if err != nil {
+			return outcome, err
+		}
+		if diff.IsEmpty() {
+			log.Info("Helm charts are up to date; keeping drifted fields as configured in spec.driftPolicy")
+			return outcome, nil
+		}
+	}
+
+	log.Info("Installing Helm chart")
+	if err := istiodReconciler.Install(ctx, rev.Spec.Version, rev.Spec.Namespace, rev.Spec.Values, rev.Spec.Patches, rev.Name, &ownerReference); err != nil {
+		return outcome, err
+	}
+	outcome.drift.ChartsUpgraded()
+	return outcome, nil
 }
 
 func (r *Reconciler) Finalize(ctx context.Context, rev *v1.IstioRevision) error {
+	revision.ForgetComputedValues(rev)
 	istiodReconciler := r.newIstiodReconciler()
 	return istiodReconciler.Uninstall(ctx, rev.Spec.Namespace, rev.Name)
 }
 
+// planTags computes the changes that the IstioRevisionTag controller would make to the Helm releases of the tags
+// that point to the revision once the dry-run annotation is removed.
+func (r *Reconciler) planTags(ctx context.Context, rev *v1.IstioRevision) (helm.ReleaseDiff, error) {
+	tagList := v1.IstioRevisionTagList{}
+	if err := r.Client.List(ctx, &tagList); err != nil {
+		return helm.ReleaseDiff{}, fmt.Errorf("failed to list IstioRevisionTags: %w", err)
+	}
+	tagReconciler := sharedreconcile.NewRevisionTagReconciler(r.sharedConfig(), r.Client)
+	var diff helm.ReleaseDiff
+	for _, tag := range tagList.Items {
+		ref := tag.Spec.TargetRef
+		if tag.Status.IstioRevision != rev.Name && (ref.Kind != v1.IstioRevisionKind || ref.Name != rev.Name) {
+			continue
+		}
+		ownerReference := metav1.OwnerReference{
+			APIVersion:         v1.GroupVersion.String(),
+			Kind:               v1.IstioRevisionTagKind,
+			Name:               tag.Name,
+			UID:                tag.UID,
+			Controller:         ptr.Of(true),
+			BlockOwnerDeletion: ptr.Of(true),
+		}
+		tagDiff, err := tagReconciler.Plan(ctx, rev.Spec.Version, rev.Spec.Namespace, tag.Name, rev.Name, rev.Spec.Values, &ownerReference)
+		if err != nil {
+			return helm.ReleaseDiff{}, err
+		}
+		diff.Append(tagDiff)
+	}
+	return diff, nil
+}
+
 func (r *Reconciler) newIstiodReconciler() *sharedreconcile.IstiodReconciler {
-	return sharedreconcile.NewIstiodReconciler(sharedreconcile.Config{
+	return sharedreconcile.NewIstiodReconciler(r.sharedConfig(), r.Client)
+}
+
+func (r *Reconciler) sharedConfig() sharedreconcile.Config {
+	return sharedreconcile.Config{
 		ResourceFS:        r.Config.ResourceFS,
 		Platform:          r.Config.Platform,
 		DefaultProfile:    r.Config.DefaultProfile,
 		OperatorNamespace: r.Config.OperatorNamespace,
 		ChartManager:      r.ChartManager,
-	}, r.Client)
+	}
 }
 
 // validateRevisionConsistency validates that the IstioRevision CR fields are consistent
@@ -231,14 +337,21 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// nsHandler triggers reconciliation in two cases:
 	// - when a namespace that is referenced in IstioRevision.spec.namespace is
 	//   created, so that the control plane is installed immediately.
-	// - when a namespace that references the IstioRevision CR via the istio.io/rev
-	//   or istio-injection labels is updated, so that the InUse condition of
+	// - when a namespace starts or stops referencing the IstioRevision CR via the
+	//   istio.io/rev or istio-injection labels, so that the InUse condition of
 	//   the IstioRevision CR is updated.
-	nsHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapNamespaceToReconcileRequest))
+	nsHandler := wrapEventHandler(logger, revision.EnqueueReferenceChanges(r.namespaceDependents))
 
-	// podHandler handles pods that reference the IstioRevision CR via the istio.io/rev or sidecar.istio.io/inject labels.
-	// The handler triggers the reconciliation of the referenced IstioRevision CR so that its InUse condition is updated.
-	podHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapPodToReconcileRequest))
+	// podHandler handles pods that reference the IstioRevision CR via the istio.io/rev annotation or via the
+	// istio.io/rev or sidecar.istio.io/inject labels of the pod or its namespace. The handler triggers the
+	// reconciliation of the IstioRevision CRs that the pod started or stopped referencing, so that their InUse
+	// condition and workload inventory are updated.
+	podHandler := wrapEventHandler(logger, revision.EnqueueReferenceChanges(r.podReferences))
+
+	// waypointHandler handles waypoint Gateways, which reference the IstioRevision CR via their istio.io/rev label
+	// or that of their namespace. The handler triggers the reconciliation of the IstioRevision CRs that the
+	// waypoint started or stopped referencing, so that their InUse condition is updated.
+	waypointHandler := wrapEventHandler(logger, revision.EnqueueReferenceChanges(r.waypointReferences))
 
 	// revisionTagHandler handles IstioRevisionTags that reference the IstioRevision CR via their targetRef.
 	// The handler triggers the reconciliation of the referenced IstioRevision CR so that its InUse condition is updated.
@@ -252,6 +365,9 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// or if it's owned by an Endpoints object which in turn is owned by an IstioRevision.
 	endpointSliceHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapEndpointSliceToReconcileRequests))
 
+	// chartSourceHandler handles changes to the versions provided by IstioChartSources
+	chartSourceHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapChartSourceToReconcileRequests))
+
 	b := ctrl.NewControllerManagedBy(mgr).
 		WithOptions(controller.Options{
 			LogConstructor: func(req *reconcile.Request) logr.Logger {
@@ -273,13 +389,20 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 		reflect.TypeOf(&discoveryv1.EndpointSlice{}): endpointSliceHandler,
 	}
 	watches.RegisterOwnedWatches(b, watches.IstiodWatches, ownedResourceHandler, handlerOverrides, predicate2.IgnoreUpdateWhenAnnotation())
+	b = r.Config.ChartSources.Watch(b, chartSourceHandler)
+	if r.Config.GatewayAPIAvailable {
+		// +lint-watches:ignore: Gateway (not found in charts, but must be watched to reconcile IstioRevision when a waypoint references it)
+		b = b.Watches(revision.Gateway(), waypointHandler, builder.WithPredicates(watches.AsPredicate(watches.IgnoreStatusChanges())))
+	}
 
 	return b.
+		// namespaces and pods are only watched through their metadata, which is all that's needed to determine the
+		// revisions they reference; caching entire pods is too expensive in large clusters
 		// +lint-watches:ignore: Namespace (not found in charts, but must be watched to reconcile IstioRevision when its namespace is created)
-		Watches(&corev1.Namespace{}, nsHandler,
+		Watches(&corev1.Namespace{}, nsHandler, builder.OnlyMetadata,
 			builder.WithPredicates(watches.AsPredicate(watches.IgnoreStatusChanges()), predicate2.IgnoreUpdateWhenAnnotation())).
 		// +lint-watches:ignore: Pod (not found in charts, but must be watched to reconcile IstioRevision when a pod references it)
-		Watches(&corev1.Pod{}, podHandler,
+		Watches(&corev1.Pod{}, podHandler, builder.OnlyMetadata,
 			builder.WithPredicates(watches.AsPredicate(watches.IgnoreStatusChanges()), predicate2.IgnoreUpdateWhenAnnotation())).
 		Watches(&v1.IstioRevisionTag{}, revisionTagHandler).
 		Watches(&v1.IstioCNI{}, istioCniHandler).
@@ -288,35 +411,74 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Complete(reconciler.NewStandardReconcilerWithFinalizer[*v1.IstioRevision](r.Client, r.Reconcile, r.Finalize, constants.FinalizerName))
 }
 
-func (r *Reconciler) determineStatus(ctx context.Context, rev *v1.IstioRevision, reconcileErr error) (v1.IstioRevisionStatus, error) {
+func (r *Reconciler) determineStatus(
+	ctx context.Context, rev *v1.IstioRevision, outcome reconcileOutcome, reconcileErr error,
+) (v1.IstioRevisionStatus, error) {
 	var errs errlist.Builder
-	reconciledCondition := r.determineReconciledCondition(reconcileErr)
+	reconciledCondition := r.determineReconciledCondition(outcome, reconcileErr)
 	readyCondition, err := r.determineReadyCondition(ctx, rev)
 	errs.Add(err)
+	var resources []v1.ResourceStatus
+	var history []v1.HelmReleaseRevision
+	var conflicts *sharedreconcile.FieldConflictReport
+	if r.ChartManager != nil {
+		istiodReconciler := r.newIstiodReconciler()
+		resources, err = istiodReconciler.CheckReadiness(ctx, rev.Spec.Namespace, rev.Name)
+		errs.Add(err)
+		history, err = istiodReconciler.ReleaseHistory(ctx, rev.Spec.Namespace, rev.Name)
+		errs.Add(err)
+		conflicts = istiodReconciler.FieldConflicts(rev.Spec.Namespace, rev.Name)
+	}
+	readyCondition = reconciler.ApplyResourcesReadiness(readyCondition, resources, v1.IstioRevisionReasonResourcesNotReady)
 	dependenciesHealthyCondition, err := r.determineDependenciesHealthyCondition(ctx, rev)
 	errs.Add(err)
 
 	inUseCondition, err := r.determineInUseCondition(ctx, rev)
 	errs.Add(err)
 
+	workloads, err := revision.GetWorkloadInventory(ctx, r.UsageReader, rev.Name, rev.Name)
+	errs.Add(err)
+
 	status := *rev.Status.DeepCopy()
 	status.ObservedGeneration = rev.Generation
+	status.Plan = outcome.plan
+	status.Resources = resources
+	status.ReleaseHistory = history
+	if workloads != nil {
+		status.Workloads = workloads
+	}
 	status.SetCondition(reconciledCondition)
 	status.SetCondition(readyCondition)
 	status.SetCondition(dependenciesHealthyCondition)
 	status.SetCondition(inUseCondition)
+	if driftCondition := r.determineDriftCondition(rev, outcome.drift); driftCondition != nil {
+		status.SetCondition(*driftCondition)
+	}
+	if conflictsCondition := r.determineFieldConflictsCondition(conflicts); conflictsCondition != nil {
+		status.SetCondition(*conflictsCondition)
+	}
 	status.State = reconciler.DeriveState(v1.IstioRevisionReasonHealthy, reconciledCondition, readyCondition, dependenciesHealthyCondition)
 	return status, errs.Error()
 }
 
-func (r *Reconciler) updateStatus(ctx context.Context, rev *v1.IstioRevision, reconcileErr error) error {
-	status, err := r.determineStatus(ctx, rev, reconcileErr)
+func (r *Reconciler) updateStatus(ctx context.Context, rev *v1.IstioRevision, outcome reconcileOutcome, reconcileErr error) error {
+	status, err := r.determineStatus(ctx, rev, outcome, reconcileErr)
 	return reconciler.UpdateStatus(ctx, r.Client, rev, rev.Status, status, err)
 }
 
-func (r *Reconciler) determineReconciledCondition(err error) v1.StatusCondition {
+func (r *Reconciler) determineReconciledCondition(outcome reconcileOutcome, err error) v1.StatusCondition {
 	c := v1.StatusCondition{Type: v1.IstioRevisionConditionReconciled}
-	if err == nil {
+	if plan := outcome.plan; err == nil && plan != nil {
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1.IstioRevisionReasonDryRun
+		c.Message = fmt.Sprintf("dry-run mode is enabled; %d objects would be added, %d changed and %d removed (see status.plan)",
+			len(plan.Added), len(plan.Changed), len(plan.Removed))
+	} else if err == nil && outcome.rollbackRevision > 0 {
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1.IstioRevisionReasonRolledBack
+		c.Message = fmt.Sprintf("the istiod Helm release was rolled back to revision %d because the %s annotation is set; "+
+			"remove the annotation to install the version and values defined in the spec", outcome.rollbackRevision, constants.RollbackAnnotationKey)
+	} else if err == nil {
 		c.Status = metav1.ConditionTrue
 		c.Reason = v1.ConditionReason(v1.IstioRevisionConditionReconciled)
 	} else {
@@ -325,6 +487,9 @@ func (r *Reconciler) determineReconciledCondition(err error) v1.StatusCondition
 		case reconciler.IsNameAlreadyExistsError(err):
 			c.Reason = v1.IstioRevisionReasonNameAlreadyExists
 			c.Message = err.Error()
+		case helm.IsPatchError(err):
+			c.Reason = v1.IstioRevisionReasonInvalidPatch
+			c.Message = err.Error()
 		default:
 			c.Reason = v1.IstioRevisionReasonReconcileError
 			c.Message = fmt.Sprintf("error reconciling resource: %v", err)
@@ -339,7 +504,7 @@ func (r *Reconciler) determineReadyCondition(ctx context.Context, rev *v1.IstioR
 		Status: metav1.ConditionFalse,
 	}
 
-	if !revision.IsUsingRemoteControlPlane(rev) {
+	if !revision.IsUsingRemoteControlPlane(rev, r.Config) {
 		istiod := appsv1.Deployment{}
 		if err := r.Client.Get(ctx, istiodDeploymentKey(rev), &istiod); err == nil {
 			if istiod.Status.Replicas == 0 {
@@ -392,14 +557,15 @@ func (r *Reconciler) determineReadyCondition(ctx context.Context, rev *v1.IstioR
 
 func (r *Reconciler) determineDependenciesHealthyCondition(ctx context.Context, rev *v1.IstioRevision) (v1.StatusCondition, error) {
 	if revision.DependsOnIstioCNI(rev, r.Config) {
+		cniName := revision.IstioCNIName(rev)
 		cni := v1.IstioCNI{}
-		if err := r.Client.Get(ctx, client.ObjectKey{Name: istioCniName}, &cni); err != nil {
+		if err := r.Client.Get(ctx, client.ObjectKey{Name: cniName}, &cni); err != nil {
 			if apierrors.IsNotFound(err) {
 				return v1.StatusCondition{
 					Type:    v1.IstioRevisionConditionDependenciesHealthy,
 					Status:  metav1.ConditionFalse,
 					Reason:  v1.IstioRevisionReasonIstioCNINotFound,
-					Message: "IstioCNI resource does not exist",
+					Message: fmt.Sprintf("IstioCNI resource %q does not exist", cniName),
 				}, nil
 			}
 
@@ -407,7 +573,7 @@ func (r *Reconciler) determineDependenciesHealthyCondition(ctx context.Context,
 				Type:    v1.IstioRevisionConditionDependenciesHealthy,
 				Status:  metav1.ConditionUnknown,
 				Reason:  v1.IstioRevisionDependencyCheckFailed,
-				Message: fmt.Sprintf("failed to get IstioCNI status: %v", err),
+				Message: fmt.Sprintf("failed to get status of IstioCNI %q: %v", cniName, err),
 			}, fmt.Errorf("get failed: %w", err)
 		}
 
@@ -416,12 +582,13 @@ func (r *Reconciler) determineDependenciesHealthyCondition(ctx context.Context,
 				Type:    v1.IstioRevisionConditionDependenciesHealthy,
 				Status:  metav1.ConditionFalse,
 				Reason:  v1.IstioRevisionReasonIstioCNINotHealthy,
-				Message: "IstioCNI resource status indicates that the component is not healthy",
+				Message: fmt.Sprintf("IstioCNI resource %q status indicates that the component is not healthy", cniName),
 			}, nil
 		}
 	}
 
 	if revision.DependsOnZTunnel(rev, r.Config) {
+		ztunnelName := revision.ZTunnelName(rev)
 		ztunnel := v1.ZTunnel{}
 		if err := r.Client.Get(ctx, client.ObjectKey{Name: ztunnelName}, &ztunnel); err != nil {
 			if apierrors.IsNotFound(err) {
@@ -429,7 +596,7 @@ func (r *Reconciler) determineDependenciesHealthyCondition(ctx context.Context,
 					Type:    v1.IstioRevisionConditionDependenciesHealthy,
 					Status:  metav1.ConditionFalse,
 					Reason:  v1.IstioRevisionReasonZTunnelNotFound,
-					Message: "ZTunnel resource does not exist",
+					Message: fmt.Sprintf("ZTunnel resource %q does not exist", ztunnelName),
 				}, nil
 			}
 
@@ -437,7 +604,7 @@ func (r *Reconciler) determineDependenciesHealthyCondition(ctx context.Context,
 				Type:    v1.IstioRevisionConditionDependenciesHealthy,
 				Status:  metav1.ConditionUnknown,
 				Reason:  v1.IstioRevisionDependencyCheckFailed,
-				Message: fmt.Sprintf("failed to get ZTunnel status: %v", err),
+				Message: fmt.Sprintf("failed to get status of ZTunnel %q: %v", ztunnelName, err),
 			}, fmt.Errorf("get failed: %w", err)
 		}
 
@@ -446,7 +613,7 @@ func (r *Reconciler) determineDependenciesHealthyCondition(ctx context.Context,
 				Type:    v1.IstioRevisionConditionDependenciesHealthy,
 				Status:  metav1.ConditionFalse,
 				Reason:  v1.IstioRevisionReasonZTunnelNotHealthy,
-				Message: "ZTunnel resource status indicates that the component is not healthy",
+				Message: fmt.Sprintf("ZTunnel resource %q status indicates that the component is not healthy", ztunnelName),
 			}, nil
 		}
 	}
@@ -458,6 +625,57 @@ func (r *Reconciler) determineDependenciesHealthyCondition(ctx context.Context,
 	}, nil
 }
 
+// determineDriftCondition returns the DriftDetected condition, or nil if drift wasn't checked. A condition
+// that reports reverted drift is kept until the spec of the IstioRevision changes, so that users can see it.
+func (r *Reconciler) determineDriftCondition(rev *v1.IstioRevision, drift *sharedreconcile.DriftReport) *v1.StatusCondition {
+	if drift == nil {
+		return nil
+	}
+
+	c := v1.StatusCondition{Type: v1.IstioRevisionConditionDriftDetected}
+	switch {
+	case drift.Err != nil:
+		c.Status = metav1.ConditionUnknown
+		c.Reason = v1.IstioRevisionReasonDriftCheckFailed
+		c.Message = fmt.Sprintf("failed to check for drift: %v", drift.Err)
+	case len(drift.Drifts) == 0:
+		previous := rev.Status.GetCondition(v1.IstioRevisionConditionDriftDetected)
+		if previous.Reason == v1.IstioRevisionReasonDriftReverted && rev.Status.ObservedGeneration == rev.Generation {
+			return &previous
+		}
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1.IstioRevisionReasonNoDrift
+	case drift.Reverted:
+		c.Status = metav1.ConditionTrue
+		c.Reason = v1.IstioRevisionReasonDriftReverted
+		c.Message = "reverted changes made outside of the operator: " + drift.Message()
+	default:
+		c.Status = metav1.ConditionTrue
+		c.Reason = v1.IstioRevisionReasonDriftReported
+		c.Message = "found changes made outside of the operator: " + drift.Message()
+	}
+	return &c
+}
+
+// determineFieldConflictsCondition returns the FieldConflicts condition, or nil if the operator doesn't use
+// server-side apply.
+func (r *Reconciler) determineFieldConflictsCondition(conflicts *sharedreconcile.FieldConflictReport) *v1.StatusCondition {
+	if conflicts == nil {
+		return nil
+	}
+
+	c := v1.StatusCondition{Type: v1.IstioRevisionConditionFieldConflicts}
+	if len(conflicts.Conflicts) == 0 {
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1.IstioRevisionReasonNoConflicts
+	} else {
+		c.Status = metav1.ConditionTrue
+		c.Reason = v1.IstioRevisionReasonConflictsSkipped
+		c.Message = "kept fields owned by other field managers: " + conflicts.Message()
+	}
+	return &c
+}
+
 func (r *Reconciler) determineInUseCondition(ctx context.Context, rev *v1.IstioRevision) (v1.StatusCondition, error) {
 	c := v1.StatusCondition{Type: v1.IstioRevisionConditionInUse}
 
@@ -482,71 +700,55 @@ func (r *Reconciler) determineInUseCondition(ctx context.Context, rev *v1.IstioR
 
 func (r *Reconciler) isRevisionReferenced(ctx context.Context, rev *v1.IstioRevision) (bool, error) {
 	log := logf.FromContext(ctx)
-	nsList := corev1.NamespaceList{}
-	nsMap := map[string]corev1.Namespace{}
-	// if an IstioRevision is referenced by a revisionTag, it's considered as InUse
-	revisionTagList := v1.IstioRevisionTagList{}
-	if err := r.Client.List(ctx, &revisionTagList); err != nil {
+	tagList := v1.IstioRevisionTagList{}
+	if err := r.Client.List(ctx, &tagList); err != nil {
 		return false, fmt.Errorf("failed to list IstioRevisionTags: %w", err)
 	}
-	for _, tag := range revisionTagList.Items {
+	for _, tag := range tagList.Items {
 		if tag.Status.IstioRevision == rev.Name {
 			log.V(2).Info("Revision is referenced by IstioRevisionTag", "IstioRevisionTag", tag.Name)
 			return true, nil
 		}
 	}
 
-	if err := r.Client.List(ctx, &nsList); err != nil { // TODO: can we optimize this by specifying a label selector
-		return false, fmt.Errorf("failed to list namespaces: %w", err)
+	pod, err := revision.FindInjectedPod(ctx, r.UsageReader, r.APIReader, rev.Name)
+	if err != nil {
+		return false, err
+	} else if pod != nil {
+		log.V(2).Info("Revision is referenced by Pod", "Pod", client.ObjectKeyFromObject(pod))
+		return true, nil
 	}
-	for _, ns := range nsList.Items {
-		if namespaceReferencesRevision(ns, rev) {
-			log.V(2).Info("Revision is referenced by Namespace", "Namespace", ns.Name)
-			return true, nil
+
+	obj, err := revision.FindInjectionReference(ctx, r.UsageReader, r.APIReader, rev.Name)
+	if err != nil {
+		return false, err
+	} else if obj != nil {
+		if obj.Namespace == "" {
+			log.V(2).Info("Revision is referenced by Namespace", "Namespace", obj.Name)
+		} else {
+			log.V(2).Info("Revision is referenced by Pod", "Pod", client.ObjectKeyFromObject(obj))
 		}
-		nsMap[ns.Name] = ns
+		return true, nil
 	}
 
-	podList := corev1.PodList{}
-	if err := r.Client.List(ctx, &podList); err != nil { // TODO: can we optimize this by specifying a label selector
-		return false, fmt.Errorf("failed to list pods: %w", err)
-	}
-	for _, pod := range podList.Items {
-		if pod.Status.Phase == corev1.PodSucceeded {
-			continue
-		}
-		if ns, found := nsMap[pod.Namespace]; found && podReferencesRevision(pod, ns, rev) {
-			log.V(2).Info("Revision is referenced by Pod", "Pod", client.ObjectKeyFromObject(&pod))
+	if r.Config.GatewayAPIAvailable {
+		gw, err := revision.FindWaypoint(ctx, r.UsageReader, rev.Name)
+		if err != nil {
+			return false, err
+		} else if gw != nil {
+			log.V(2).Info("Revision is referenced by waypoint Gateway", "Gateway", client.ObjectKeyFromObject(gw))
 			return true, nil
 		}
 	}
 
-	if rev.Name == v1.DefaultRevision && rev.Spec.Values != nil &&
-		rev.Spec.Values.SidecarInjectorWebhook != nil &&
-		rev.Spec.Values.SidecarInjectorWebhook.EnableNamespacesByDefault != nil &&
-		*rev.Spec.Values.SidecarInjectorWebhook.EnableNamespacesByDefault {
+	if rev.Name == v1.DefaultRevision && revision.InjectsAllNamespaces(rev) {
 		return true, nil
 	}
 
-	log.V(2).Info("Revision is not referenced by any Pod or Namespace")
+	log.V(2).Info("Revision is not referenced by any Pod, Namespace or waypoint Gateway")
 	return false, nil
 }
 
-func namespaceReferencesRevision(ns corev1.Namespace, rev *v1.IstioRevision) bool {
-	return rev.Name == revision.GetReferencedRevisionFromNamespace(ns.Labels)
-}
-
-func podReferencesRevision(pod corev1.Pod, ns corev1.Namespace, rev *v1.IstioRevision) bool {
-	if rev.Name == revision.GetInjectedRevisionFromPod(pod.GetAnnotations()) {
-		return true
-	}
-	if revision.GetReferencedRevisionFromNamespace(ns.Labels) == "" &&
-		rev.Name == revision.GetReferencedRevisionFromPod(pod.GetLabels()) {
-		return true
-	}
-	return false
-}
-
 func istiodDeploymentKey(rev *v1.IstioRevision) client.ObjectKey {
 	name := "istiod"
 	if rev.Spec.Values != nil && rev.Spec.Values.Revision != nil && *rev.Spec.Values.Revision != "" {
@@ -572,9 +774,11 @@ func injectionWebhookKey(rev *v1.IstioRevision) client.ObjectKey {
 	}
 }
 
-func (r *Reconciler) mapNamespaceToReconcileRequest(ctx context.Context, ns client.Object) []reconcile.Request {
+// namespaceDependents returns the IstioRevisions that are installed in the namespace and those that the namespace or,
+// if the namespace doesn't reference a revision, its pods reference.
+func (r *Reconciler) namespaceDependents(ctx context.Context, ns client.Object) []string {
 	log := logf.FromContext(ctx)
-	var requests []reconcile.Request
+	var names []string
 
 	// Check if any IstioRevision references this namespace in .spec.namespace
 	revList := v1.IstioRevisionList{}
@@ -584,39 +788,36 @@ func (r *Reconciler) mapNamespaceToReconcileRequest(ctx context.Context, ns clie
 	}
 	for _, rev := range revList.Items {
 		if rev.Spec.Namespace == ns.GetName() {
-			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: rev.Name}})
+			names = append(names, rev.Name)
 		}
 	}
 
-	// Check if the namespace references an IstioRevision in its labels
-	revisionName := revision.GetReferencedRevisionFromNamespace(ns.GetLabels())
-	if revisionName != "" {
-		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: revisionName}})
+	// Check if the namespace or its pods reference an IstioRevision in their labels
+	references, err := revision.NamespaceReferences(ctx, r.UsageReader, ns)
+	if err != nil {
+		log.Error(err, "failed to determine the IstioRevisions referenced by namespace", "Namespace", ns.GetName())
 	}
-	return requests
+	return append(names, references...)
 }
 
-// mapPodToReconcileRequest will collect all referenced revisions from a pod and trigger reconciliation if there are any
-func (r *Reconciler) mapPodToReconcileRequest(ctx context.Context, pod client.Object) []reconcile.Request {
-	revisionNames := []string{}
-	revisionName := revision.GetInjectedRevisionFromPod(pod.GetAnnotations())
-	if revisionName != "" {
-		revisionNames = append(revisionNames, revisionName)
-	} else {
-		revisionName = revision.GetReferencedRevisionFromPod(pod.GetLabels())
-		if revisionName != "" {
-			revisionNames = append(revisionNames, revisionName)
-		}
+// podReferences returns the IstioRevisions that the pod was injected by or that its labels or the labels of its
+// namespace reference
+func (r *Reconciler) podReferences(ctx context.Context, pod client.Object) []string {
+	references, err := revision.PodReferences(ctx, r.UsageReader, pod)
+	if err != nil {
+		logf.FromContext(ctx).Error(err, "failed to determine the IstioRevisions referenced by pod", "Pod", client.ObjectKeyFromObject(pod))
 	}
+	return references
+}
 
-	if len(revisionNames) > 0 {
-		reqs := []reconcile.Request{}
-		for _, revName := range revisionNames {
-			reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{Name: revName}})
-		}
-		return reqs
+// waypointReferences returns the IstioRevisions that the waypoint Gateway references through its istio.io/rev
+// label or that of its namespace
+func (r *Reconciler) waypointReferences(ctx context.Context, gw client.Object) []string {
+	references, err := revision.WaypointReferences(ctx, r.UsageReader, gw)
+	if err != nil {
+		logf.FromContext(ctx).Error(err, "failed to determine the IstioRevisions referenced by waypoint", "Gateway", client.ObjectKeyFromObject(gw))
 	}
-	return nil
+	return references
 }
 
 func (r *Reconciler) mapRevisionTagToReconcileRequest(ctx context.Context, revisionTag client.Object) []reconcile.Request {
@@ -628,14 +829,14 @@ func (r *Reconciler) mapRevisionTagToReconcileRequest(ctx context.Context, revis
 }
 
 // mapIstioCniToReconcileRequests returns reconcile requests for all IstioRevisions that depend on IstioCNI
-func (r *Reconciler) mapIstioCniToReconcileRequests(ctx context.Context, _ client.Object) []reconcile.Request {
+func (r *Reconciler) mapIstioCniToReconcileRequests(ctx context.Context, obj client.Object) []reconcile.Request {
 	list := v1.IstioRevisionList{}
 	if err := r.Client.List(ctx, &list); err != nil {
 		return nil
 	}
 	var reqs []reconcile.Request
 	for _, rev := range list.Items {
-		if revision.DependsOnIstioCNI(&rev, r.Config) {
+		if revision.IstioCNIName(&rev) == obj.GetName() && revision.DependsOnIstioCNI(&rev, r.Config) {
 			reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{Name: rev.Name}})
 		}
 	}
@@ -643,20 +844,37 @@ func (r *Reconciler) mapIstioCniToReconcileRequests(ctx context.Context, _ clien
 }
 
 // mapZTunnelToReconcileRequests returns reconcile requests for all IstioRevisions that depend on ZTunnel
-func (r *Reconciler) mapZTunnelToReconcileRequests(ctx context.Context, _ client.Object) []reconcile.Request {
+func (r *Reconciler) mapZTunnelToReconcileRequests(ctx context.Context, obj client.Object) []reconcile.Request {
 	list := v1.IstioRevisionList{}
 	if err := r.Client.List(ctx, &list); err != nil {
 		return nil
 	}
 	var reqs []reconcile.Request
 	for _, rev := range list.Items {
-		if revision.DependsOnZTunnel(&rev, r.Config) {
+		if revision.ZTunnelName(&rev) == obj.GetName() && revision.DependsOnZTunnel(&rev, r.Config) {
 			reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{Name: rev.Name}})
 		}
 	}
 	return reqs
 }
 
+// mapChartSourceToReconcileRequests returns all IstioRevision objects that use a version that isn't embedded in the
+// operator, because the versions provided by IstioChartSources have changed.
+func (r *Reconciler) mapChartSourceToReconcileRequests(ctx context.Context, _ client.Object) []reconcile.Request {
+	list := v1.IstioRevisionList{}
+	if err := r.Client.List(ctx, &list); err != nil {
+		logf.FromContext(ctx).Error(err, "failed to list IstioRevisions")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, rev := range list.Items {
+		if !istioversion.IsEmbeddedVersion(rev.Spec.Version) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: rev.Name}})
+		}
+	}
+	return requests
+}
+
 func wrapEventHandler(logger logr.Logger, handler handler.EventHandler) handler.EventHandler {
 	return enqueuelogger.WrapIfNecessary(v1.IstioRevisionKind, logger, handler)
 }
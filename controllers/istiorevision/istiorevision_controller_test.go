@@ -20,20 +20,26 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
 	"github.com/istio-ecosystem/sail-operator/pkg/config"
 	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
 	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
 	sharedreconcile "github.com/istio-ecosystem/sail-operator/pkg/reconcile"
 	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	"github.com/istio-ecosystem/sail-operator/pkg/revision"
 	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
 	. "github.com/onsi/gomega"
 	admissionv1 "k8s.io/api/admissionregistration/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/meta/testrestmapper"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -376,7 +382,7 @@ func TestMapEndpointSliceToReconcileRequests(t *testing.T) {
 
 	for _, tc := range testCases {
 		cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(tc.objs...).Build()
-		r := NewReconciler(newReconcilerTestConfig(t), cl, scheme.Scheme, nil)
+		r := NewReconciler(newReconcilerTestConfig(t), cl, cl, cl, scheme.Scheme, nil)
 
 		got := r.mapEndpointSliceToReconcileRequests(context.Background(), tc.endpointSlice)
 
@@ -467,6 +473,77 @@ func newCondition(
 	}
 }
 
+func TestDetermineReconciledCondition(t *testing.T) {
+	plan := &v1.IstioRevisionPlan{
+		Added:   []v1.PlannedObject{{APIVersion: "v1", Kind: "ConfigMap", Name: "istio"}},
+		Changed: []v1.PlannedObject{{APIVersion: "apps/v1", Kind: "Deployment", Name: "istiod"}},
+	}
+
+	testCases := []struct {
+		name           string
+		outcome        reconcileOutcome
+		err            error
+		expectedStatus metav1.ConditionStatus
+		expectedReason v1.IstioRevisionConditionReason
+	}{
+		{
+			name:           "reconciled",
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: v1.IstioRevisionConditionReason(v1.IstioRevisionConditionReconciled),
+		},
+		{
+			name:           "reconcile error",
+			err:            fmt.Errorf("failed to install chart"),
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1.IstioRevisionReasonReconcileError,
+		},
+		{
+			name:           "dry run",
+			outcome:        reconcileOutcome{plan: plan},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1.IstioRevisionReasonDryRun,
+		},
+		{
+			name:           "invalid patch",
+			err:            fmt.Errorf("failed to install chart: %w", &helm.PatchError{Index: 0, Err: fmt.Errorf("bad patch")}),
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1.IstioRevisionReasonInvalidPatch,
+		},
+		{
+			name:           "rolled back",
+			outcome:        reconcileOutcome{rollbackRevision: 2},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1.IstioRevisionReasonRolledBack,
+		},
+		{
+			name:           "rollback error",
+			outcome:        reconcileOutcome{rollbackRevision: 2},
+			err:            fmt.Errorf("revision 2 is not retained"),
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1.IstioRevisionReasonReconcileError,
+		},
+		{
+			name:           "dry run error",
+			err:            fmt.Errorf("failed to render chart"),
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1.IstioRevisionReasonReconcileError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			r := &Reconciler{}
+			c := r.determineReconciledCondition(tc.outcome, tc.err)
+			g.Expect(c.Status).To(Equal(tc.expectedStatus))
+			g.Expect(c.Reason).To(Equal(tc.expectedReason))
+			if tc.outcome.plan != nil {
+				g.Expect(c.Message).To(ContainSubstring("1 objects would be added, 1 changed and 0 removed"))
+			}
+		})
+	}
+}
+
 func TestDetermineReadyCondition(t *testing.T) {
 	cfg := newReconcilerTestConfig(t)
 
@@ -690,7 +767,7 @@ func TestDetermineReadyCondition(t *testing.T) {
 
 			cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(tt.clientObjects...).WithInterceptorFuncs(tt.interceptors).Build()
 
-			r := NewReconciler(cfg, cl, scheme.Scheme, nil)
+			r := NewReconciler(cfg, cl, cl, cl, scheme.Scheme, nil)
 
 			rev := &v1.IstioRevision{
 				ObjectMeta: metav1.ObjectMeta{
@@ -716,6 +793,155 @@ func TestDetermineReadyCondition(t *testing.T) {
 	}
 }
 
+func TestDetermineDependenciesHealthyCondition(t *testing.T) {
+	cfg := newDependencyTestConfig(t)
+
+	cniValues := &v1.Values{Pilot: &v1.PilotConfig{Cni: &v1.CNIUsageConfig{Enabled: ptr.Of(true)}}}
+	ambientValues := &v1.Values{Pilot: &v1.PilotConfig{Env: map[string]string{"PILOT_ENABLE_AMBIENT": "true"}}}
+
+	testCases := []struct {
+		name          string
+		values        *v1.Values
+		dependencies  *v1.Dependencies
+		clientObjects []client.Object
+		expected      v1.StatusCondition
+	}{
+		{
+			name:          "default IstioCNI healthy",
+			values:        cniValues,
+			clientObjects: []client.Object{newIstioCNI("default", v1.IstioCNIReasonHealthy)},
+			expected: v1.StatusCondition{
+				Type:   v1.IstioRevisionConditionDependenciesHealthy,
+				Status: metav1.ConditionTrue,
+				Reason: v1.ConditionReason(v1.IstioRevisionConditionDependenciesHealthy),
+			},
+		},
+		{
+			name:          "referenced IstioCNI healthy",
+			values:        cniValues,
+			dependencies:  &v1.Dependencies{IstioCNI: "cni-a"},
+			clientObjects: []client.Object{newIstioCNI("cni-a", v1.IstioCNIReasonHealthy)},
+			expected: v1.StatusCondition{
+				Type:   v1.IstioRevisionConditionDependenciesHealthy,
+				Status: metav1.ConditionTrue,
+				Reason: v1.ConditionReason(v1.IstioRevisionConditionDependenciesHealthy),
+			},
+		},
+		{
+			name:          "referenced IstioCNI not found",
+			values:        cniValues,
+			dependencies:  &v1.Dependencies{IstioCNI: "cni-a"},
+			clientObjects: []client.Object{newIstioCNI("default", v1.IstioCNIReasonHealthy)},
+			expected: v1.StatusCondition{
+				Type:    v1.IstioRevisionConditionDependenciesHealthy,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1.IstioRevisionReasonIstioCNINotFound,
+				Message: `IstioCNI resource "cni-a" does not exist`,
+			},
+		},
+		{
+			name:         "referenced IstioCNI not healthy",
+			values:       cniValues,
+			dependencies: &v1.Dependencies{IstioCNI: "cni-a"},
+			clientObjects: []client.Object{
+				newIstioCNI("default", v1.IstioCNIReasonHealthy),
+				newIstioCNI("cni-a", v1.IstioCNIReasonResourcesNotReady),
+			},
+			expected: v1.StatusCondition{
+				Type:    v1.IstioRevisionConditionDependenciesHealthy,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1.IstioRevisionReasonIstioCNINotHealthy,
+				Message: `IstioCNI resource "cni-a" status indicates that the component is not healthy`,
+			},
+		},
+		{
+			name:          "referenced ZTunnel healthy",
+			values:        ambientValues,
+			dependencies:  &v1.Dependencies{ZTunnel: "ztunnel-a"},
+			clientObjects: []client.Object{newZTunnel("ztunnel-a", v1.ZTunnelReasonHealthy)},
+			expected: v1.StatusCondition{
+				Type:   v1.IstioRevisionConditionDependenciesHealthy,
+				Status: metav1.ConditionTrue,
+				Reason: v1.ConditionReason(v1.IstioRevisionConditionDependenciesHealthy),
+			},
+		},
+		{
+			name:          "referenced ZTunnel not found",
+			values:        ambientValues,
+			dependencies:  &v1.Dependencies{ZTunnel: "ztunnel-a"},
+			clientObjects: []client.Object{newZTunnel("default", v1.ZTunnelReasonHealthy)},
+			expected: v1.StatusCondition{
+				Type:    v1.IstioRevisionConditionDependenciesHealthy,
+				Status:  metav1.ConditionFalse,
+				Reason:  v1.IstioRevisionReasonZTunnelNotFound,
+				Message: `ZTunnel resource "ztunnel-a" does not exist`,
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+
+			cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(tt.clientObjects...).Build()
+			r := NewReconciler(cfg, cl, cl, cl, scheme.Scheme, nil)
+
+			rev := &v1.IstioRevision{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "my-istio",
+				},
+				Spec: v1.IstioRevisionSpec{
+					Version:      istioversion.Default,
+					Namespace:    "istio-system",
+					Values:       tt.values,
+					Dependencies: tt.dependencies,
+				},
+			}
+
+			result, err := r.determineDependenciesHealthyCondition(context.TODO(), rev)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(result.Type).To(Equal(tt.expected.Type))
+			g.Expect(result.Status).To(Equal(tt.expected.Status))
+			g.Expect(result.Reason).To(Equal(tt.expected.Reason))
+			g.Expect(result.Message).To(Equal(tt.expected.Message))
+		})
+	}
+}
+
+func TestMapDependencyToReconcileRequests(t *testing.T) {
+	g := NewWithT(t)
+
+	values := &v1.Values{Pilot: &v1.PilotConfig{
+		Cni: &v1.CNIUsageConfig{Enabled: ptr.Of(true)},
+		Env: map[string]string{"PILOT_ENABLE_AMBIENT": "true"},
+	}}
+	newRev := func(name string, dependencies *v1.Dependencies) *v1.IstioRevision {
+		return &v1.IstioRevision{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1.IstioRevisionSpec{
+				Version:      istioversion.Default,
+				Namespace:    "istio-system",
+				Values:       values,
+				Dependencies: dependencies,
+			},
+		}
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		newRev("rev-default", nil),
+		newRev("rev-a", &v1.Dependencies{IstioCNI: "cni-a", ZTunnel: "ztunnel-a"}),
+	).Build()
+	r := NewReconciler(newDependencyTestConfig(t), cl, cl, cl, scheme.Scheme, nil)
+
+	request := func(name string) reconcile.Request {
+		return reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}
+	}
+	g.Expect(r.mapIstioCniToReconcileRequests(context.TODO(), newIstioCNI("default", ""))).To(ConsistOf(request("rev-default")))
+	g.Expect(r.mapIstioCniToReconcileRequests(context.TODO(), newIstioCNI("cni-a", ""))).To(ConsistOf(request("rev-a")))
+	g.Expect(r.mapIstioCniToReconcileRequests(context.TODO(), newIstioCNI("cni-b", ""))).To(BeEmpty())
+	g.Expect(r.mapZTunnelToReconcileRequests(context.TODO(), newZTunnel("default", ""))).To(ConsistOf(request("rev-default")))
+	g.Expect(r.mapZTunnelToReconcileRequests(context.TODO(), newZTunnel("ztunnel-a", ""))).To(ConsistOf(request("rev-a")))
+}
+
 func TestDetermineInUseCondition(t *testing.T) {
 	cfg := newReconcilerTestConfig(t)
 
@@ -724,6 +950,8 @@ func TestDetermineInUseCondition(t *testing.T) {
 		podAnnotations      map[string]string
 		nsLabels            map[string]string
 		podPhase            corev1.PodPhase
+		podJob              string
+		waypointLabels      map[string]string
 		enableAllNamespaces bool
 		interceptors        interceptor.Funcs
 		matchesRevision     string
@@ -748,6 +976,23 @@ func TestDetermineInUseCondition(t *testing.T) {
 			podPhase:        corev1.PodSucceeded,
 			matchesRevision: "",
 		},
+		{
+			podLabels:       map[string]string{"istio.io/rev": "my-rev"},
+			podPhase:        corev1.PodSucceeded,
+			matchesRevision: "",
+		},
+
+		// pod of a Job; the Job controller removes its finalizer once the pod has completed
+		{
+			podAnnotations:  map[string]string{"istio.io/rev": "default"},
+			podJob:          "running",
+			matchesRevision: "default",
+		},
+		{
+			podAnnotations:  map[string]string{"istio.io/rev": "default"},
+			podJob:          "completed",
+			matchesRevision: "",
+		},
 
 		// namespace labels only
 		{
@@ -846,6 +1091,38 @@ func TestDetermineInUseCondition(t *testing.T) {
 			matchesRevision: "default",
 		},
 
+		// ambient namespaces and pods
+		{
+			nsLabels:        map[string]string{"istio.io/dataplane-mode": "ambient"},
+			matchesRevision: "default",
+		},
+		{
+			nsLabels:        map[string]string{"istio.io/dataplane-mode": "ambient", "istio.io/rev": "my-rev"},
+			matchesRevision: "my-rev",
+		},
+		{
+			nsLabels:        map[string]string{"istio.io/use-waypoint": "waypoint"},
+			matchesRevision: "default",
+		},
+		{
+			podLabels:       map[string]string{"istio.io/dataplane-mode": "ambient"},
+			matchesRevision: "default",
+		},
+		{
+			podLabels:       map[string]string{"istio.io/dataplane-mode": "ambient", "istio.io/rev": "my-rev", "sidecar.istio.io/inject": "false"},
+			matchesRevision: "my-rev",
+		},
+
+		// waypoints
+		{
+			waypointLabels:  map[string]string{},
+			matchesRevision: "default",
+		},
+		{
+			waypointLabels:  map[string]string{"istio.io/rev": "my-rev"},
+			matchesRevision: "my-rev",
+		},
+
 		// special case: when Values.sidecarInjectorWebhook.enableNamespacesByDefault is true, all pods should match the default revision
 		// unless they are in one of the system namespaces ("kube-system","kube-public","kube-node-lease","local-path-storage")
 		{
@@ -884,6 +1161,15 @@ func TestDetermineInUseCondition(t *testing.T) {
 			if len(tc.podPhase) > 0 {
 				nameBuilder.WriteString("Phase:" + string(tc.podPhase) + ",")
 			}
+			if len(tc.podJob) > 0 {
+				nameBuilder.WriteString("Job:" + tc.podJob + ",")
+			}
+			if tc.waypointLabels != nil {
+				nameBuilder.WriteString("WAYPOINT:")
+				for k, v := range tc.waypointLabels {
+					nameBuilder.WriteString(k + ":" + v + ",")
+				}
+			}
 			name := strings.TrimSuffix(nameBuilder.String(), ",")
 
 			t.Run(name, func(t *testing.T) {
@@ -924,14 +1210,34 @@ func TestDetermineInUseCondition(t *testing.T) {
 						Phase: tc.podPhase,
 					},
 				}
+				if tc.podJob != "" {
+					pod.OwnerReferences = []metav1.OwnerReference{
+						{APIVersion: "batch/v1", Kind: "Job", Name: "some-job", Controller: ptr.Of(true)},
+					}
+					if tc.podJob == "running" {
+						pod.Finalizers = []string{"batch.kubernetes.io/job-tracking"}
+					}
+				}
+
+				objs := []client.Object{rev, ns, pod}
+				if tc.waypointLabels != nil {
+					gw := revision.Gateway()
+					gw.SetNamespace(namespace)
+					gw.SetName("waypoint")
+					gw.SetLabels(tc.waypointLabels)
+					g.Expect(unstructured.SetNestedField(gw.Object, "istio-waypoint", "spec", "gatewayClassName")).To(Succeed())
+					objs = append(objs, gw)
+				}
 
-				cl := fake.NewClientBuilder().
-					WithScheme(scheme.Scheme).
-					WithObjects(rev, ns, pod).
+				cl := newFakeClientBuilder().
+					WithRESTMapper(newGatewayRESTMapper()).
+					WithObjects(objs...).
 					WithInterceptorFuncs(tc.interceptors).
 					Build()
 
-				r := NewReconciler(cfg, cl, scheme.Scheme, nil)
+				cfg := cfg
+				cfg.GatewayAPIAvailable = true
+				r := NewReconciler(cfg, cl, cl, cl, scheme.Scheme, nil)
 
 				result, _ := r.determineInUseCondition(context.TODO(), rev)
 				g.Expect(result.Type).To(Equal(v1.IstioRevisionConditionInUse))
@@ -957,6 +1263,15 @@ func TestDetermineInUseCondition(t *testing.T) {
 	}
 }
 
+// newFakeClientBuilder returns a fake client builder with the field indexes that the reconciler's UsageReader needs
+func newFakeClientBuilder() *fake.ClientBuilder {
+	b := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+	for _, index := range revision.UsageIndexes() {
+		b = b.WithIndex(index.Object, index.Field, index.Extract)
+	}
+	return b
+}
+
 func newReconcilerTestConfig(t *testing.T) config.ReconcilerConfig {
 	return config.ReconcilerConfig{
 		ResourceFS:              os.DirFS(t.TempDir()),
@@ -965,3 +1280,35 @@ func newReconcilerTestConfig(t *testing.T) config.ReconcilerConfig {
 		MaxConcurrentReconciles: 1,
 	}
 }
+
+// newDependencyTestConfig returns a config with a default profile, so that the dependencies of a revision can be
+// determined from its values
+func newDependencyTestConfig(t *testing.T) config.ReconcilerConfig {
+	cfg := newReconcilerTestConfig(t)
+	cfg.DefaultProfile = "default"
+	cfg.ResourceFS = fstest.MapFS{
+		istioversion.Default + "/profiles/default.yaml": &fstest.MapFile{Data: []byte("spec:\n  values: {}\n")},
+	}
+	return cfg
+}
+
+func newIstioCNI(name string, state v1.IstioCNIConditionReason) *v1.IstioCNI {
+	return &v1.IstioCNI{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     v1.IstioCNIStatus{State: state},
+	}
+}
+
+func newZTunnel(name string, state v1.ZTunnelConditionReason) *v1.ZTunnel {
+	return &v1.ZTunnel{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status:     v1.ZTunnelStatus{State: state},
+	}
+}
+
+// newGatewayRESTMapper returns a RESTMapper for the types in the scheme and the Gateway API's Gateway
+func newGatewayRESTMapper() meta.RESTMapper {
+	gatewayMapper := meta.NewDefaultRESTMapper(nil)
+	gatewayMapper.Add(revision.GatewayGVK, meta.RESTScopeNamespace)
+	return meta.MultiRESTMapper{testrestmapper.TestOnlyStaticRESTMapper(scheme.Scheme), gatewayMapper}
+}
@@ -18,7 +18,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"path"
 
 	"github.com/go-logr/logr"
 	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
@@ -27,6 +26,7 @@ import (
 	"github.com/istio-ecosystem/sail-operator/pkg/enqueuelogger"
 	"github.com/istio-ecosystem/sail-operator/pkg/errlist"
 	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	sharedreconcile "github.com/istio-ecosystem/sail-operator/pkg/reconcile"
 	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
 	"github.com/istio-ecosystem/sail-operator/pkg/revision"
 	"github.com/istio-ecosystem/sail-operator/pkg/validation"
@@ -48,24 +48,31 @@ import (
 	"istio.io/istio/pkg/ptr"
 )
 
-const (
-	revisionTagsChartName = "revisiontags"
-)
-
 // Reconciler reconciles an IstioRevisionTag object
 type Reconciler struct {
 	client.Client
 	Scheme       *runtime.Scheme
 	Config       config.ReconcilerConfig
 	ChartManager *helm.ChartManager
+
+	// UsageReader looks up the namespaces and pods that reference a revision tag. It must be backed by a cache
+	// that has the indexes returned by revision.UsageIndexes.
+	UsageReader client.Reader
+
+	// APIReader reads the phase of the pods found through UsageReader, since the cache only holds their metadata.
+	APIReader client.Reader
 }
 
-func NewReconciler(reconcilerCfg config.ReconcilerConfig, client client.Client, scheme *runtime.Scheme, chartManager *helm.ChartManager) *Reconciler {
+func NewReconciler(
+	reconcilerCfg config.ReconcilerConfig, client client.Client, usageReader, apiReader client.Reader, scheme *runtime.Scheme, chartManager *helm.ChartManager,
+) *Reconciler {
 	return &Reconciler{
 		Client:       client,
 		Scheme:       scheme,
 		Config:       reconcilerCfg,
 		ChartManager: chartManager,
+		UsageReader:  usageReader,
+		APIReader:    apiReader,
 	}
 }
 
@@ -102,10 +109,17 @@ func (r *Reconciler) doReconcile(ctx context.Context, tag *v1.IstioRevisionTag)
 		return nil, err
 	}
 
-	if revision.IsUsingRemoteControlPlane(rev) {
+	if revision.IsUsingRemoteControlPlane(rev, r.Config) {
 		return nil, reconciler.NewValidationError("IstioRevisionTag cannot reference a remote IstioRevision")
 	}
 
+	// the spec of an IstioRevision in dry-run mode hasn't been installed yet, so the tag keeps pointing to the
+	// control plane that was installed for the previous spec
+	if revision.IsDryRun(rev) {
+		log.Info("Referenced IstioRevision is in dry-run mode; keeping the installed Helm charts")
+		return nil, nil
+	}
+
 	// if the IstioRevision's namespace changes, we need to completely reinstall the tag
 	if tag.Status.IstiodNamespace != "" && tag.Status.IstiodNamespace != rev.Spec.Namespace {
 		if err := r.uninstallHelmCharts(ctx, tag); err != nil {
@@ -161,48 +175,22 @@ func (r *Reconciler) installHelmCharts(ctx context.Context, tag *v1.IstioRevisio
 		Controller:         ptr.Of(true),
 		BlockOwnerDeletion: ptr.Of(true),
 	}
-
-	values := helm.FromValues(rev.Spec.Values)
-	if err := values.SetStringSlice("revisionTags", []string{tag.Name}); err != nil {
-		return err
-	}
-
-	_, err := r.ChartManager.UpgradeOrInstallChart(ctx, r.Config.ResourceFS, r.getChartPath(rev, revisionTagsChartName),
-		values, rev.Spec.Namespace, getReleaseName(tag, revisionTagsChartName), &ownerReference)
-	if err != nil {
-		return fmt.Errorf("failed to install/update Helm chart %q: %w", revisionTagsChartName, err)
-	}
-	if tag.Name == v1.DefaultRevisionTag {
-		if err := values.Set("defaultRevision", rev.Name); err != nil {
-			return err
-		}
-		_, err := r.ChartManager.UpgradeOrInstallChart(ctx, r.Config.ResourceFS, r.getChartPath(rev, constants.BaseChartName),
-			values, r.Config.OperatorNamespace, getReleaseName(tag, constants.BaseChartName), &ownerReference)
-		if err != nil {
-			return fmt.Errorf("failed to install/update Helm chart %q: %w", constants.BaseChartName, err)
-		}
-	}
-	return nil
-}
-
-func getReleaseName(tag *v1.IstioRevisionTag, chartName string) string {
-	return fmt.Sprintf("%s-%s", tag.Name, chartName)
+	return r.newRevisionTagReconciler().Install(ctx, rev.Spec.Version, rev.Spec.Namespace, tag.Name, rev.Name, rev.Spec.Values, &ownerReference)
 }
 
-func (r *Reconciler) getChartPath(rev *v1.IstioRevision, chartName string) string {
-	return path.Join(rev.Spec.Version, "charts", chartName)
+func (r *Reconciler) uninstallHelmCharts(ctx context.Context, tag *v1.IstioRevisionTag) error {
+	return r.newRevisionTagReconciler().Uninstall(ctx, tag.Status.IstiodNamespace, tag.Name)
 }
 
-func (r *Reconciler) uninstallHelmCharts(ctx context.Context, tag *v1.IstioRevisionTag) error {
-	if _, err := r.ChartManager.UninstallChart(ctx, getReleaseName(tag, revisionTagsChartName), tag.Status.IstiodNamespace); err != nil {
-		return fmt.Errorf("failed to uninstall Helm chart %q: %w", revisionTagsChartName, err)
-	}
-	if tag.Name == v1.DefaultRevisionTag {
-		if _, err := r.ChartManager.UninstallChart(ctx, getReleaseName(tag, constants.BaseChartName), r.Config.OperatorNamespace); err != nil {
-			return fmt.Errorf("failed to uninstall Helm chart %q: %w", constants.BaseChartName, err)
-		}
-	}
-	return nil
+func (r *Reconciler) newRevisionTagReconciler() *sharedreconcile.RevisionTagReconciler {
+	return sharedreconcile.NewRevisionTagReconciler(sharedreconcile.Config{
+		ResourceFS:        r.Config.ResourceFS,
+		Platform:          r.Config.Platform,
+		DefaultProfile:    r.Config.DefaultProfile,
+		OperatorNamespace: r.Config.OperatorNamespace,
+		ChartManager:      r.ChartManager,
+		TLSConfig:         r.Config.TLSConfig,
+	}, r.Client)
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -218,17 +206,20 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 	// operatorResourcesHandler handles watch events from operator CRDs Istio and IstioRevision
 	operatorResourcesHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapOperatorResourceToReconcileRequest))
-	// nsHandler triggers reconciliation in two cases:
-	// - when a namespace that references the IstioRevisionTag CR via the istio.io/rev
-	//   or istio-injection labels is updated, so that the InUse condition of
-	//   the IstioRevisionTag CR is updated.
-	nsHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapNamespaceToReconcileRequest))
+	// nsHandler triggers reconciliation when a namespace starts or stops referencing the IstioRevisionTag CR via
+	// the istio.io/rev or istio-injection labels, so that the InUse condition of the IstioRevisionTag CR is updated.
+	nsHandler := wrapEventHandler(logger, revision.EnqueueReferenceChanges(r.namespaceReferences))
 
-	// podHandler handles pods that reference the IstioRevisionTag CR via the istio.io/rev or sidecar.istio.io/inject labels.
-	// The handler triggers the reconciliation of the referenced IstioRevision CR so that its InUse condition is updated.
-	podHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapPodToReconcileRequest))
+	// podHandler handles pods that reference the IstioRevisionTag CR via the istio.io/rev or sidecar.istio.io/inject labels
+	// of the pod or its namespace. The handler triggers the reconciliation of the IstioRevisionTag CRs that the pod
+	// started or stopped referencing, so that their InUse condition and workload inventory are updated.
+	podHandler := wrapEventHandler(logger, revision.EnqueueReferenceChanges(r.podReferences))
 
-	return ctrl.NewControllerManagedBy(mgr).
+	// waypointHandler handles waypoint Gateways that reference the IstioRevisionTag CR via their istio.io/rev label
+	// or that of their namespace, so that the InUse condition of the IstioRevisionTag CR is updated.
+	waypointHandler := wrapEventHandler(logger, revision.EnqueueReferenceChanges(r.waypointReferences))
+
+	b := ctrl.NewControllerManagedBy(mgr).
 		WithOptions(controller.Options{
 			LogConstructor: func(req *reconcile.Request) logr.Logger {
 				log := logger
@@ -242,9 +233,9 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 		// we use the Watches function instead of For(), so that we can wrap the handler so that events that cause the object to be enqueued are logged
 		Watches(&v1.IstioRevisionTag{}, mainObjectHandler).
 		Named("istiorevisiontag").
-		// watches related to in-use detection
-		Watches(&corev1.Namespace{}, nsHandler, builder.WithPredicates(watches.AsPredicate(watches.IgnoreStatusChanges()))).
-		Watches(&corev1.Pod{}, podHandler, builder.WithPredicates(watches.AsPredicate(watches.IgnoreStatusChanges()))).
+		// watches related to in-use detection; only the metadata of namespaces and pods is cached
+		Watches(&corev1.Namespace{}, nsHandler, builder.OnlyMetadata, builder.WithPredicates(watches.AsPredicate(watches.IgnoreStatusChanges()))).
+		Watches(&corev1.Pod{}, podHandler, builder.OnlyMetadata, builder.WithPredicates(watches.AsPredicate(watches.IgnoreStatusChanges()))).
 
 		// cluster-scoped resources
 		Watches(&v1.Istio{}, operatorResourcesHandler).
@@ -252,8 +243,13 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Watches(&admissionv1.MutatingWebhookConfiguration{}, ownedResourceHandler,
 			builder.WithPredicates(watches.AsPredicate(watches.WebhookFilter()))).
 		Watches(&admissionv1.ValidatingWebhookConfiguration{}, ownedResourceHandler,
-			builder.WithPredicates(watches.AsPredicate(watches.WebhookFilter()))).
-		Complete(reconciler.NewStandardReconcilerWithFinalizer[*v1.IstioRevisionTag](r.Client, r.Reconcile, r.Finalize, constants.FinalizerName))
+			builder.WithPredicates(watches.AsPredicate(watches.WebhookFilter())))
+
+	if r.Config.GatewayAPIAvailable {
+		b = b.Watches(revision.Gateway(), waypointHandler, builder.WithPredicates(watches.AsPredicate(watches.IgnoreStatusChanges())))
+	}
+
+	return b.Complete(reconciler.NewStandardReconcilerWithFinalizer[*v1.IstioRevisionTag](r.Client, r.Reconcile, r.Finalize, constants.FinalizerName))
 }
 
 func (r *Reconciler) determineStatus(ctx context.Context, tag *v1.IstioRevisionTag,
@@ -271,6 +267,13 @@ func (r *Reconciler) determineStatus(ctx context.Context, tag *v1.IstioRevisionT
 		status.IstiodNamespace = rev.Spec.Namespace
 		status.IstioRevision = rev.Name
 	}
+	if rev != nil {
+		workloads, err := revision.GetWorkloadInventory(ctx, r.UsageReader, tag.Name, rev.Name)
+		errs.Add(err)
+		if workloads != nil {
+			status.Workloads = workloads
+		}
+	}
 	status.SetCondition(reconciledCondition)
 	status.SetCondition(inUseCondition)
 	status.State = reconciler.DeriveState(v1.IstioRevisionTagReasonHealthy, reconciledCondition, inUseCondition)
@@ -327,29 +330,24 @@ func (r *Reconciler) determineInUseCondition(ctx context.Context, tag *v1.IstioR
 
 func (r *Reconciler) isRevisionTagReferencedByWorkloads(ctx context.Context, tag *v1.IstioRevisionTag) (bool, error) {
 	log := logf.FromContext(ctx)
-	nsList := corev1.NamespaceList{}
-	nsMap := map[string]corev1.Namespace{}
-	if err := r.Client.List(ctx, &nsList); err != nil { // TODO: can we optimize this by specifying a label selector
-		return false, fmt.Errorf("failed to list namespaces: %w", err)
-	}
-	for _, ns := range nsList.Items {
-		if namespaceReferencesRevisionTag(ns, tag) {
-			log.V(2).Info("RevisionTag is referenced by Namespace", "Namespace", ns.Name)
-			return true, nil
+	obj, err := revision.FindInjectionReference(ctx, r.UsageReader, r.APIReader, tag.Name)
+	if err != nil {
+		return false, err
+	} else if obj != nil {
+		if obj.Namespace == "" {
+			log.V(2).Info("RevisionTag is referenced by Namespace", "Namespace", obj.Name)
+		} else {
+			log.V(2).Info("RevisionTag is referenced by Pod", "Pod", client.ObjectKeyFromObject(obj))
 		}
-		nsMap[ns.Name] = ns
+		return true, nil
 	}
 
-	podList := corev1.PodList{}
-	if err := r.Client.List(ctx, &podList); err != nil { // TODO: can we optimize this by specifying a label selector
-		return false, fmt.Errorf("failed to list pods: %w", err)
-	}
-	for _, pod := range podList.Items {
-		if pod.Status.Phase == corev1.PodSucceeded {
-			continue
-		}
-		if ns, found := nsMap[pod.Namespace]; found && podReferencesRevisionTag(pod, tag, ns) {
-			log.V(2).Info("RevisionTag is referenced by Pod", "Pod", client.ObjectKeyFromObject(&pod))
+	if r.Config.GatewayAPIAvailable {
+		gw, err := revision.FindWaypoint(ctx, r.UsageReader, tag.Name)
+		if err != nil {
+			return false, err
+		} else if gw != nil {
+			log.V(2).Info("RevisionTag is referenced by waypoint Gateway", "Gateway", client.ObjectKeyFromObject(gw))
 			return true, nil
 		}
 	}
@@ -359,43 +357,41 @@ func (r *Reconciler) isRevisionTagReferencedByWorkloads(ctx context.Context, tag
 		return false, err
 	}
 
-	if tag.Name == v1.DefaultRevision && rev.Spec.Values != nil &&
-		rev.Spec.Values.SidecarInjectorWebhook != nil &&
-		rev.Spec.Values.SidecarInjectorWebhook.EnableNamespacesByDefault != nil &&
-		*rev.Spec.Values.SidecarInjectorWebhook.EnableNamespacesByDefault {
+	if tag.Name == v1.DefaultRevision && revision.InjectsAllNamespaces(rev) {
 		return true, nil
 	}
 
-	log.V(2).Info("RevisionTag is not referenced by any Pod or Namespace")
+	log.V(2).Info("RevisionTag is not referenced by any Pod, Namespace or waypoint Gateway")
 	return false, nil
 }
 
-func namespaceReferencesRevisionTag(ns corev1.Namespace, tag *v1.IstioRevisionTag) bool {
-	return tag.Name == revision.GetReferencedRevisionFromNamespace(ns.Labels)
-}
-
-func podReferencesRevisionTag(pod corev1.Pod, tag *v1.IstioRevisionTag, ns corev1.Namespace) bool {
-	return revision.GetReferencedRevisionFromNamespace(ns.Labels) == "" &&
-		tag.Name == revision.GetReferencedRevisionFromPod(pod.GetLabels())
+// namespaceReferences returns the IstioRevisionTags that the namespace or, if the namespace doesn't reference a
+// tag, its pods reference in their labels
+func (r *Reconciler) namespaceReferences(ctx context.Context, ns client.Object) []string {
+	references, err := revision.NamespaceReferences(ctx, r.UsageReader, ns)
+	if err != nil {
+		logf.FromContext(ctx).Error(err, "failed to determine the IstioRevisionTags referenced by namespace", "Namespace", ns.GetName())
+	}
+	return references
 }
 
-func (r *Reconciler) mapNamespaceToReconcileRequest(ctx context.Context, ns client.Object) []reconcile.Request {
-	var requests []reconcile.Request
-
-	// Check if the namespace references an IstioRevisionTag in its labels
-	tag := revision.GetReferencedRevisionFromNamespace(ns.GetLabels())
-	if tag != "" {
-		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: tag}})
+// podReferences returns the IstioRevisionTags that the labels of the pod or its namespace reference
+func (r *Reconciler) podReferences(ctx context.Context, pod client.Object) []string {
+	references, err := revision.PodLabelReferences(ctx, r.UsageReader, pod)
+	if err != nil {
+		logf.FromContext(ctx).Error(err, "failed to determine the IstioRevisionTags referenced by pod", "Pod", client.ObjectKeyFromObject(pod))
 	}
-	return requests
+	return references
 }
 
-func (r *Reconciler) mapPodToReconcileRequest(ctx context.Context, pod client.Object) []reconcile.Request {
-	tag := revision.GetReferencedRevisionFromPod(pod.GetLabels())
-	if tag != "" {
-		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: tag}}}
+// waypointReferences returns the IstioRevisionTags that the waypoint Gateway references through its istio.io/rev
+// label or that of its namespace
+func (r *Reconciler) waypointReferences(ctx context.Context, gw client.Object) []string {
+	references, err := revision.WaypointReferences(ctx, r.UsageReader, gw)
+	if err != nil {
+		logf.FromContext(ctx).Error(err, "failed to determine the IstioRevisionTags referenced by waypoint", "Gateway", client.ObjectKeyFromObject(gw))
 	}
-	return nil
+	return references
 }
 
 func (r *Reconciler) mapOperatorResourceToReconcileRequest(ctx context.Context, obj client.Object) []reconcile.Request {
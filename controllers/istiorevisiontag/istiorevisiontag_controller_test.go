@@ -23,10 +23,15 @@ import (
 
 	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
 	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/revision"
 	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/meta/testrestmapper"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
@@ -43,6 +48,7 @@ func TestDetermineInUseCondition(t *testing.T) {
 		podLabels           map[string]string
 		podAnnotations      map[string]string
 		nsLabels            map[string]string
+		waypointLabels      map[string]string
 		enableAllNamespaces bool
 		interceptors        interceptor.Funcs
 		matchesTag          string
@@ -157,6 +163,30 @@ func TestDetermineInUseCondition(t *testing.T) {
 			matchesTag: "default",
 		},
 
+		// ambient namespaces and pods
+		{
+			nsLabels:   map[string]string{"istio.io/dataplane-mode": "ambient"},
+			matchesTag: "default",
+		},
+		{
+			nsLabels:   map[string]string{"istio.io/dataplane-mode": "ambient", "istio.io/rev": "my-rev"},
+			matchesTag: "my-rev",
+		},
+		{
+			podLabels:  map[string]string{"istio.io/dataplane-mode": "ambient", "istio.io/rev": "my-rev", "sidecar.istio.io/inject": "false"},
+			matchesTag: "my-rev",
+		},
+
+		// waypoints
+		{
+			waypointLabels: map[string]string{},
+			matchesTag:     "default",
+		},
+		{
+			waypointLabels: map[string]string{"istio.io/rev": "my-rev"},
+			matchesTag:     "my-rev",
+		},
+
 		// special case: mismatch between pod annotation and label. revision tag controller should only look at label
 		{
 			podLabels:      map[string]string{"istio.io/rev": revName},
@@ -198,6 +228,12 @@ func TestDetermineInUseCondition(t *testing.T) {
 					nameBuilder.WriteString(k + ":" + v + ",")
 				}
 			}
+			if tc.waypointLabels != nil {
+				nameBuilder.WriteString("WAYPOINT:")
+				for k, v := range tc.waypointLabels {
+					nameBuilder.WriteString(k + ":" + v + ",")
+				}
+			}
 			name := strings.TrimSuffix(nameBuilder.String(), ",")
 
 			t.Run(name, func(t *testing.T) {
@@ -243,13 +279,25 @@ func TestDetermineInUseCondition(t *testing.T) {
 					},
 				}
 
-				cl := fake.NewClientBuilder().
-					WithScheme(scheme.Scheme).
-					WithObjects(rev, tag, ns, pod).
+				objs := []client.Object{rev, tag, ns, pod}
+				if tc.waypointLabels != nil {
+					gw := revision.Gateway()
+					gw.SetNamespace(namespace)
+					gw.SetName("waypoint")
+					gw.SetLabels(tc.waypointLabels)
+					g.Expect(unstructured.SetNestedField(gw.Object, "istio-waypoint", "spec", "gatewayClassName")).To(Succeed())
+					objs = append(objs, gw)
+				}
+
+				cl := newFakeClientBuilder().
+					WithRESTMapper(newGatewayRESTMapper()).
+					WithObjects(objs...).
 					WithInterceptorFuncs(tc.interceptors).
 					Build()
 
-				r := NewReconciler(cfg, cl, scheme.Scheme, nil)
+				cfg := cfg
+				cfg.GatewayAPIAvailable = true
+				r := NewReconciler(cfg, cl, cl, cl, scheme.Scheme, nil)
 
 				result, _ := r.determineInUseCondition(context.TODO(), tag)
 				g.Expect(result.Type).To(Equal(v1.IstioRevisionTagConditionInUse))
@@ -276,6 +324,22 @@ func TestDetermineInUseCondition(t *testing.T) {
 	}
 }
 
+// newFakeClientBuilder returns a fake client builder with the field indexes that the reconciler's UsageReader needs
+func newFakeClientBuilder() *fake.ClientBuilder {
+	b := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+	for _, index := range revision.UsageIndexes() {
+		b = b.WithIndex(index.Object, index.Field, index.Extract)
+	}
+	return b
+}
+
+// newGatewayRESTMapper returns a RESTMapper for the types in the scheme and the Gateway API's Gateway
+func newGatewayRESTMapper() meta.RESTMapper {
+	gatewayMapper := meta.NewDefaultRESTMapper(nil)
+	gatewayMapper.Add(revision.GatewayGVK, meta.RESTScopeNamespace)
+	return meta.MultiRESTMapper{testrestmapper.TestOnlyStaticRESTMapper(scheme.Scheme), gatewayMapper}
+}
+
 func newReconcilerTestConfig(t *testing.T) config.ReconcilerConfig {
 	return config.ReconcilerConfig{
 		ResourceFS:              os.DirFS(t.TempDir()),
@@ -345,7 +409,7 @@ func TestValidation(t *testing.T) {
 				WithObjects(append(tc.objs, tc.tag)...).
 				Build()
 
-			r := NewReconciler(cfg, cl, scheme.Scheme, nil)
+			r := NewReconciler(cfg, cl, cl, cl, scheme.Scheme, nil)
 
 			ctx := context.TODO()
 			_, err := r.doReconcile(ctx, tc.tag)
@@ -358,3 +422,44 @@ func TestValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestDoReconcileSkipsDryRunRevision(t *testing.T) {
+	g := NewWithT(t)
+	cfg := newReconcilerTestConfig(t)
+
+	tag := &v1.IstioRevisionTag{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "default",
+		},
+		Spec: v1.IstioRevisionTagSpec{
+			TargetRef: v1.TargetReference{
+				Kind: v1.IstioRevisionKind,
+				Name: revName,
+			},
+		},
+		Status: v1.IstioRevisionTagStatus{
+			IstiodNamespace: "istio-system",
+			IstioRevision:   revName,
+		},
+	}
+	rev := &v1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        revName,
+			Annotations: map[string]string{constants.DryRunAnnotationKey: "true"},
+		},
+		Spec: v1.IstioRevisionSpec{
+			Namespace: "other-namespace",
+			Values:    &v1.Values{},
+		},
+	}
+	cl := fake.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(tag, rev).
+		Build()
+
+	// the reconciler has no ChartManager, so installing or uninstalling a Helm chart would panic
+	r := NewReconciler(cfg, cl, cl, cl, scheme.Scheme, nil)
+	returnedRev, err := r.doReconcile(context.TODO(), tag)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(returnedRev).To(BeNil())
+}
@@ -0,0 +1,94 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meshcluster
+
+import (
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// eastWestGatewaySelector selects the pods of the east-west gateway. The gateway chart sets the istio label to
+// the name of the IstioGateway without the istio- prefix.
+var eastWestGatewaySelector = map[string]any{"istio": "eastwestgateway"}
+
+func newIstioObject(gvk schema.GroupVersionKind, name, namespace string, spec map[string]any) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+	obj.SetLabels(map[string]string{constants.ManagedByLabelKey: constants.ManagedByLabelValue})
+	return obj
+}
+
+// newCrossNetworkGateway returns the Gateway that exposes all services in the mesh to the other networks. The
+// east-west gateway passes the mTLS traffic through to the destination workloads based on the SNI.
+func newCrossNetworkGateway(namespace string) *unstructured.Unstructured {
+	return newIstioObject(GatewayGVK, CrossNetworkGatewayName, namespace, map[string]any{
+		"selector": eastWestGatewaySelector,
+		"servers": []any{
+			map[string]any{
+				"port":  map[string]any{"number": int64(15443), "name": "tls", "protocol": "TLS"},
+				"tls":   map[string]any{"mode": "AUTO_PASSTHROUGH"},
+				"hosts": []any{"*.local"},
+			},
+		},
+	})
+}
+
+// newIstiodGateway returns the Gateway that exposes the XDS and webhook ports of istiod to remote clusters
+func newIstiodGateway(namespace string) *unstructured.Unstructured {
+	return newIstioObject(GatewayGVK, IstiodGatewayName, namespace, map[string]any{
+		"selector": eastWestGatewaySelector,
+		"servers": []any{
+			passthroughServer("tls-istiod", 15012),
+			passthroughServer("tls-istiodwebhook", 15017),
+		},
+	})
+}
+
+func passthroughServer(name string, port int64) map[string]any {
+	return map[string]any{
+		"port":  map[string]any{"number": port, "name": name, "protocol": "TLS"},
+		"tls":   map[string]any{"mode": "PASSTHROUGH"},
+		"hosts": []any{"*"},
+	}
+}
+
+// newIstiodVirtualService returns the VirtualService that routes the traffic received by the istiod Gateway to
+// the given istiod host
+func newIstiodVirtualService(namespace, istiodHost string) *unstructured.Unstructured {
+	return newIstioObject(VirtualServiceGVK, IstiodVirtualServiceName, namespace, map[string]any{
+		"hosts":    []any{"*"},
+		"gateways": []any{IstiodGatewayName},
+		"tls": []any{
+			passthroughRoute(15012, istiodHost, 15012),
+			passthroughRoute(15017, istiodHost, 443),
+		},
+	})
+}
+
+func passthroughRoute(port int64, host string, targetPort int64) map[string]any {
+	return map[string]any{
+		"match": []any{
+			map[string]any{"port": port, "sniHosts": []any{"*"}},
+		},
+		"route": []any{
+			map[string]any{
+				"destination": map[string]any{"host": host, "port": map[string]any{"number": targetPort}},
+			},
+		},
+	}
+}
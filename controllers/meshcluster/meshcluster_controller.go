@@ -0,0 +1,681 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meshcluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/enqueuelogger"
+	"github.com/istio-ecosystem/sail-operator/pkg/errlist"
+	"github.com/istio-ecosystem/sail-operator/pkg/kube"
+	"github.com/istio-ecosystem/sail-operator/pkg/meshcluster"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	"github.com/istio-ecosystem/sail-operator/pkg/revision"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+const (
+	// EastWestGatewayName is the name of the IstioGateway that handles the cross-network traffic
+	EastWestGatewayName = "istio-eastwestgateway"
+
+	// CrossNetworkGatewayName is the name of the Gateway that exposes the services in this cluster to the other
+	// networks through the east-west gateway
+	CrossNetworkGatewayName = "cross-network-gateway"
+
+	// IstiodGatewayName is the name of the Gateway that exposes istiod through the east-west gateway
+	IstiodGatewayName = "istiod-gateway"
+
+	// IstiodVirtualServiceName is the name of the VirtualService that routes the istiod traffic received by
+	// the east-west gateway
+	IstiodVirtualServiceName = "istiod-vs"
+
+	// NetworkLabelKey is the label that sets the network of the workloads in a namespace
+	NetworkLabelKey = "topology.istio.io/network"
+
+	// resyncPeriod is how often the tokens in the remote secrets are checked for renewal and the connectivity to
+	// the peer clusters is probed
+	resyncPeriod = time.Minute
+
+	// probeTimeout limits how long a request to a peer cluster may take
+	probeTimeout = 10 * time.Second
+
+	defaultClusterDomain = "cluster.local"
+)
+
+var (
+	GatewayGVK        = schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1", Kind: "Gateway"}
+	VirtualServiceGVK = schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1", Kind: "VirtualService"}
+)
+
+// Reconciler reconciles the MeshCluster object
+type Reconciler struct {
+	client.Client
+	Config config.ReconcilerConfig
+	Scheme *runtime.Scheme
+
+	// SecretReader reads the kubeconfig Secrets and the remote secrets. It shouldn't be backed by the cache, so
+	// that the operator doesn't need to watch all Secrets in the cluster.
+	SecretReader client.Reader
+
+	// NewPeerClient creates the clients that connect to the peer clusters
+	NewPeerClient meshcluster.PeerClientFactory
+}
+
+func NewReconciler(cfg config.ReconcilerConfig, client client.Client, secretReader client.Reader, scheme *runtime.Scheme) *Reconciler {
+	return &Reconciler{
+		Config:        cfg,
+		Client:        client,
+		Scheme:        scheme,
+		SecretReader:  secretReader,
+		NewPeerClient: meshcluster.NewPeerClient,
+	}
+}
+
+// observedState holds what the reconciliation learned about the cluster, which is reported in the status
+type observedState struct {
+	istio *v1.Istio
+	rev   *v1.IstioRevision
+	role  v1alpha1.MeshClusterRole
+	peers []v1alpha1.MeshClusterPeerStatus
+}
+
+// +kubebuilder:rbac:groups=sailoperator.io,resources=meshclusters,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=sailoperator.io,resources=meshclusters/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=sailoperator.io,resources=meshclusters/finalizers,verbs=update
+// +kubebuilder:rbac:groups="networking.istio.io",resources=gateways;virtualservices,verbs=get;list;watch;create;delete
+
+// Reconcile deploys the east-west gateway, maintains the istio-remote-secrets for the peer clusters and probes
+// the connectivity to them. The MeshCluster is reconciled periodically, so that the tokens in the remote secrets
+// are renewed before they expire and changes to the kubeconfig Secrets are picked up.
+func (r *Reconciler) Reconcile(ctx context.Context, mc *v1alpha1.MeshCluster) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	state, reconcileErr := r.doReconcile(ctx, mc)
+
+	log.Info("Reconciliation done. Updating status.")
+	statusErr := r.updateStatus(ctx, mc, state, reconcileErr)
+
+	if err := errors.Join(reconcileErr, statusErr); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: resyncPeriod}, nil
+}
+
+func (r *Reconciler) doReconcile(ctx context.Context, mc *v1alpha1.MeshCluster) (observedState, error) {
+	state := observedState{}
+
+	istio := &v1.Istio{}
+	if err := r.Client.Get(ctx, kube.Key(mc.Spec.TargetRef.Name), istio); err != nil {
+		if apierrors.IsNotFound(err) {
+			return state, reconciler.NewReferenceNotFoundError("referenced Istio resource does not exist", err)
+		}
+		return state, fmt.Errorf("failed to get Istio: %w", err)
+	}
+	state.istio = istio
+
+	rev, err := revision.GetIstioRevisionFromTargetReference(ctx, r.Client, mc.Spec.TargetRef)
+	if err != nil {
+		if apierrors.IsNotFound(err) || reconciler.IsTransientError(err) {
+			return state, reconciler.NewReferenceNotFoundError("referenced Istio resource has no active revision", err)
+		}
+		return state, err
+	}
+	state.rev = rev
+
+	var errs errlist.Builder
+	errs.Add(r.reconcileNetwork(ctx, mc, istio.Spec.Namespace))
+	errs.Add(r.reconcileEastWestGateway(ctx, mc, istio, rev))
+
+	var peers []v1alpha1.MeshClusterPeer
+	if revision.IsUsingRemoteControlPlane(rev, r.Config) {
+		state.role = v1alpha1.MeshClusterRoleRemote
+	} else {
+		state.role = v1alpha1.MeshClusterRolePrimary
+		peers = mc.Spec.Peers
+	}
+	for _, peer := range peers {
+		status, err := r.reconcilePeer(ctx, mc, peer, istio.Spec.Namespace)
+		state.peers = append(state.peers, status)
+		errs.Add(err)
+	}
+	errs.Add(r.deleteStaleRemoteSecrets(ctx, mc, istio.Spec.Namespace, peers))
+	return state, errs.Error()
+}
+
+// reconcileNetwork labels the control plane namespace with the network of this cluster
+func (r *Reconciler) reconcileNetwork(ctx context.Context, mc *v1alpha1.MeshCluster, namespace string) error {
+	if mc.Spec.Network == "" {
+		return nil
+	}
+	ns := revision.NamespaceMetadata()
+	if err := r.Client.Get(ctx, kube.Key(namespace), ns); err != nil {
+		return fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+	}
+	if ns.Labels[NetworkLabelKey] == mc.Spec.Network {
+		return nil
+	}
+	patch := client.MergeFrom(ns.DeepCopy())
+	if ns.Labels == nil {
+		ns.Labels = map[string]string{}
+	}
+	ns.Labels[NetworkLabelKey] = mc.Spec.Network
+	logf.FromContext(ctx).Info("Setting network label on namespace", "namespace", namespace, "network", mc.Spec.Network)
+	if err := r.Client.Patch(ctx, ns, patch); err != nil {
+		return fmt.Errorf("failed to label namespace %s: %w", namespace, err)
+	}
+	return nil
+}
+
+// reconcileEastWestGateway deploys the east-west gateway and the Gateway objects that expose the services, and
+// optionally istiod, to the other networks. Nothing is deployed unless spec.network is set.
+func (r *Reconciler) reconcileEastWestGateway(ctx context.Context, mc *v1alpha1.MeshCluster, istio *v1.Istio, rev *v1.IstioRevision) error {
+	namespace := istio.Spec.Namespace
+	if mc.Spec.Network == "" {
+		return r.deleteOwned(ctx, mc,
+			newIstiodVirtualService(namespace, ""), newIstiodGateway(namespace), newCrossNetworkGateway(namespace),
+			&v1alpha1.IstioGateway{ObjectMeta: metav1.ObjectMeta{Name: EastWestGatewayName, Namespace: namespace}})
+	}
+
+	var errs errlist.Builder
+	errs.Add(r.createOrUpdateGateway(ctx, mc, istio))
+	errs.Add(r.createIfNotExists(ctx, mc, newCrossNetworkGateway(namespace)))
+	if exposesIstiod(mc) {
+		errs.Add(r.createIfNotExists(ctx, mc, newIstiodGateway(namespace)))
+		// the istiod Service of a revision in dry-run mode may not exist yet
+		if !revision.IsDryRun(rev) {
+			errs.Add(r.createIfNotExists(ctx, mc, newIstiodVirtualService(namespace, istiodHost(rev))))
+		}
+	} else {
+		errs.Add(r.deleteOwned(ctx, mc, newIstiodVirtualService(namespace, ""), newIstiodGateway(namespace)))
+	}
+	return errs.Error()
+}
+
+func (r *Reconciler) createOrUpdateGateway(ctx context.Context, mc *v1alpha1.MeshCluster, istio *v1.Istio) error {
+	log := logf.FromContext(ctx)
+	spec := v1alpha1.IstioGatewaySpec{
+		TargetRef: v1.TargetReference{Kind: v1.IstioKind, Name: istio.Name},
+		Values: &v1alpha1.GatewayValues{
+			NetworkGateway: ptr.Of(mc.Spec.Network),
+			Service:        &v1alpha1.GatewayService{Type: ptr.Of(serviceType(mc))},
+		},
+	}
+
+	gw := &v1alpha1.IstioGateway{}
+	key := types.NamespacedName{Namespace: istio.Spec.Namespace, Name: EastWestGatewayName}
+	if err := r.Client.Get(ctx, key, gw); err == nil {
+		if equality.Semantic.DeepEqual(gw.Spec, spec) {
+			return nil
+		}
+		gw.Spec = spec
+		log.Info("Updating east-west gateway")
+		if err := r.Client.Update(ctx, gw); err != nil {
+			return fmt.Errorf("failed to update IstioGateway %s: %w", key, err)
+		}
+		return nil
+	} else if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get IstioGateway %s: %w", key, err)
+	}
+
+	gw = &v1alpha1.IstioGateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            key.Name,
+			Namespace:       key.Namespace,
+			OwnerReferences: []metav1.OwnerReference{ownerReference(mc)},
+		},
+		Spec: spec,
+	}
+	log.Info("Creating east-west gateway")
+	if err := r.Client.Create(ctx, gw); err != nil {
+		return fmt.Errorf("failed to create IstioGateway %s: %w", key, err)
+	}
+	return nil
+}
+
+// createIfNotExists creates the given object if it doesn't exist yet. Existing objects are never updated, so
+// that users can customize them.
+func (r *Reconciler) createIfNotExists(ctx context.Context, mc *v1alpha1.MeshCluster, obj *unstructured.Unstructured) error {
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := r.Client.Get(ctx, client.ObjectKeyFromObject(obj), existing); err == nil {
+		return nil
+	} else if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get %s: %w", obj.GetKind(), err)
+	}
+
+	obj.SetOwnerReferences([]metav1.OwnerReference{ownerReference(mc)})
+	logf.FromContext(ctx).Info("Creating "+obj.GetKind(), "namespace", obj.GetNamespace(), "name", obj.GetName())
+	if err := r.Client.Create(ctx, obj); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create %s: %w", obj.GetKind(), err)
+	}
+	return nil
+}
+
+// deleteOwned deletes the given objects if they exist and are owned by the MeshCluster
+func (r *Reconciler) deleteOwned(ctx context.Context, mc *v1alpha1.MeshCluster, objs ...client.Object) error {
+	var errs errlist.Builder
+	for _, obj := range objs {
+		if err := r.Client.Get(ctx, client.ObjectKeyFromObject(obj), obj); err != nil {
+			if !apierrors.IsNotFound(err) {
+				errs.Add(fmt.Errorf("failed to get %s: %w", obj.GetName(), err))
+			}
+			continue
+		}
+		if !isOwnedBy(obj, mc) {
+			continue
+		}
+		logf.FromContext(ctx).Info("Deleting object", "namespace", obj.GetNamespace(), "name", obj.GetName())
+		if err := r.Client.Delete(ctx, obj); err != nil && !apierrors.IsNotFound(err) {
+			errs.Add(fmt.Errorf("failed to delete %s: %w", obj.GetName(), err))
+		}
+	}
+	return errs.Error()
+}
+
+// reconcilePeer creates the remote secret for the given peer, renews its token when necessary, and probes
+// whether the peer cluster accepts it. Problems with the peer cluster are reported in the returned status; only
+// errors that occur in this cluster are returned.
+func (r *Reconciler) reconcilePeer(
+	ctx context.Context, mc *v1alpha1.MeshCluster, peer v1alpha1.MeshClusterPeer, namespace string,
+) (v1alpha1.MeshClusterPeerStatus, error) {
+	log := logf.FromContext(ctx).WithValues("peer", peer.Name)
+	status := v1alpha1.MeshClusterPeerStatus{Name: peer.Name}
+
+	kubeconfigSecret := &corev1.Secret{}
+	kubeconfigKey := types.NamespacedName{Namespace: r.Config.OperatorNamespace, Name: peer.KubeconfigSecretRef.Name}
+	if err := r.SecretReader.Get(ctx, kubeconfigKey, kubeconfigSecret); err != nil {
+		status.Message = fmt.Sprintf("failed to get kubeconfig Secret %s: %v", kubeconfigKey, err)
+		return status, nil
+	}
+
+	remoteSecret, err := r.getRemoteSecret(ctx, peer.Name, namespace)
+	if err != nil {
+		return status, err
+	}
+
+	if meshcluster.NeedsRotation(remoteSecret, peer.Name, kubeconfigSecret.ResourceVersion, time.Now()) {
+		log.Info("Creating token for remote secret")
+		kubeconfig, expiration, err := r.createRemoteKubeconfig(ctx, peer, kubeconfigSecret, namespace)
+		if err != nil {
+			status.Message = err.Error()
+			return status, nil
+		}
+		desired := meshcluster.NewRemoteSecret(peer.Name, namespace, kubeconfig, expiration, kubeconfigSecret.ResourceVersion)
+		desired.OwnerReferences = []metav1.OwnerReference{ownerReference(mc)}
+		if remoteSecret, err = r.writeRemoteSecret(ctx, remoteSecret, desired); err != nil {
+			return status, err
+		}
+	}
+
+	if expiration, found := meshcluster.TokenExpiration(remoteSecret); found {
+		status.TokenExpirationTime = ptr.Of(metav1.NewTime(expiration))
+	}
+	status.ServerVersion, err = r.probe(ctx, remoteSecret, peer.Name)
+	if err != nil {
+		status.Message = err.Error()
+	} else {
+		status.Connected = true
+	}
+	return status, nil
+}
+
+func (r *Reconciler) getRemoteSecret(ctx context.Context, peer, namespace string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	key := types.NamespacedName{Namespace: namespace, Name: meshcluster.RemoteSecretName(peer)}
+	if err := r.SecretReader.Get(ctx, key, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get remote secret %s: %w", key, err)
+	}
+	return secret, nil
+}
+
+// createRemoteKubeconfig requests a token for the istio-reader-service-account in the peer cluster and returns
+// the kubeconfig that istiod uses to access the peer cluster
+func (r *Reconciler) createRemoteKubeconfig(
+	ctx context.Context, peer v1alpha1.MeshClusterPeer, kubeconfigSecret *corev1.Secret, namespace string,
+) ([]byte, time.Time, error) {
+	key := peer.KubeconfigSecretRef.Key
+	if key == "" {
+		key = "kubeconfig"
+	}
+	cfg, err := meshcluster.RESTConfigFromKubeconfig(kubeconfigSecret.Data[key])
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("kubeconfig Secret %s/%s: %w", kubeconfigSecret.Namespace, kubeconfigSecret.Name, err)
+	}
+	cfg.Timeout = probeTimeout
+
+	cs, err := r.NewPeerClient(cfg)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to create client for peer cluster: %w", err)
+	}
+	peerNamespace := peer.Namespace
+	if peerNamespace == "" {
+		peerNamespace = namespace
+	}
+	token, err := meshcluster.RequestToken(ctx, cs, peerNamespace)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	kubeconfig, err := meshcluster.BuildKubeconfig(peer.Name, cfg, token.Token)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to build kubeconfig for peer cluster: %w", err)
+	}
+	return kubeconfig, token.ExpirationTimestamp.Time, nil
+}
+
+func (r *Reconciler) writeRemoteSecret(ctx context.Context, existing, desired *corev1.Secret) (*corev1.Secret, error) {
+	if existing == nil {
+		if err := r.Client.Create(ctx, desired); err != nil {
+			return nil, fmt.Errorf("failed to create remote secret %s: %w", desired.Name, err)
+		}
+		return desired, nil
+	}
+	existing.Labels = desired.Labels
+	existing.Annotations = desired.Annotations
+	existing.OwnerReferences = desired.OwnerReferences
+	existing.Data = desired.Data
+	if err := r.Client.Update(ctx, existing); err != nil {
+		return nil, fmt.Errorf("failed to update remote secret %s: %w", existing.Name, err)
+	}
+	return existing, nil
+}
+
+// probe connects to the peer cluster with the credentials in the remote secret, in the same way istiod does, and
+// returns the peer's Kubernetes version
+func (r *Reconciler) probe(ctx context.Context, remoteSecret *corev1.Secret, peer string) (string, error) {
+	cfg, err := meshcluster.RESTConfigFromRemoteSecret(remoteSecret, peer)
+	if err != nil {
+		return "", fmt.Errorf("remote secret %s: %w", remoteSecret.Name, err)
+	}
+	cfg.Timeout = probeTimeout
+	cs, err := r.NewPeerClient(cfg)
+	if err != nil {
+		return "", fmt.Errorf("failed to create client for peer cluster: %w", err)
+	}
+	if _, err := cs.CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{Limit: 1}); err != nil {
+		return "", fmt.Errorf("failed to list services in peer cluster: %w", err)
+	}
+	version, err := cs.Discovery().ServerVersion()
+	if err != nil {
+		return "", fmt.Errorf("failed to get version of peer cluster: %w", err)
+	}
+	return version.GitVersion, nil
+}
+
+// deleteStaleRemoteSecrets deletes the remote secrets that the MeshCluster created for peers that are no longer
+// listed in spec.peers
+func (r *Reconciler) deleteStaleRemoteSecrets(ctx context.Context, mc *v1alpha1.MeshCluster, namespace string, peers []v1alpha1.MeshClusterPeer) error {
+	secrets := &corev1.SecretList{}
+	if err := r.SecretReader.List(ctx, secrets, client.InNamespace(namespace),
+		client.MatchingLabels{constants.ManagedByLabelKey: constants.ManagedByLabelValue, meshcluster.MultiClusterSecretLabelKey: "true"}); err != nil {
+		return fmt.Errorf("failed to list remote secrets: %w", err)
+	}
+
+	current := map[string]bool{}
+	for _, peer := range peers {
+		current[meshcluster.RemoteSecretName(peer.Name)] = true
+	}
+	var errs errlist.Builder
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if current[secret.Name] || !isOwnedBy(secret, mc) {
+			continue
+		}
+		logf.FromContext(ctx).Info("Deleting remote secret", "name", secret.Name)
+		if err := r.Client.Delete(ctx, secret); err != nil && !apierrors.IsNotFound(err) {
+			errs.Add(fmt.Errorf("failed to delete remote secret %s: %w", secret.Name, err))
+		}
+	}
+	return errs.Error()
+}
+
+func ownerReference(mc *v1alpha1.MeshCluster) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion:         v1alpha1.GroupVersion.String(),
+		Kind:               v1alpha1.MeshClusterKind,
+		Name:               mc.Name,
+		UID:                mc.UID,
+		Controller:         ptr.Of(true),
+		BlockOwnerDeletion: ptr.Of(true),
+	}
+}
+
+func isOwnedBy(obj client.Object, mc *v1alpha1.MeshCluster) bool {
+	owner := metav1.GetControllerOf(obj)
+	return owner != nil && owner.UID == mc.UID
+}
+
+func exposesIstiod(mc *v1alpha1.MeshCluster) bool {
+	return mc.Spec.EastWestGateway != nil && mc.Spec.EastWestGateway.ExposeIstiod
+}
+
+func serviceType(mc *v1alpha1.MeshCluster) string {
+	if mc.Spec.EastWestGateway != nil && mc.Spec.EastWestGateway.ServiceType != "" {
+		return mc.Spec.EastWestGateway.ServiceType
+	}
+	return string(corev1.ServiceTypeLoadBalancer)
+}
+
+// istiodHost returns the FQDN of the istiod Service of the given revision
+func istiodHost(rev *v1.IstioRevision) string {
+	name := "istiod"
+	clusterDomain := defaultClusterDomain
+	if values := rev.Spec.Values; values != nil {
+		if values.Revision != nil && *values.Revision != "" {
+			name += "-" + *values.Revision
+		}
+		if values.Global != nil && values.Global.Proxy != nil && values.Global.Proxy.ClusterDomain != nil {
+			clusterDomain = *values.Global.Proxy.ClusterDomain
+		}
+	}
+	return fmt.Sprintf("%s.%s.svc.%s", name, rev.Spec.Namespace, clusterDomain)
+}
+
+func (r *Reconciler) determineStatus(ctx context.Context, mc *v1alpha1.MeshCluster, state observedState, reconcileErr error) (v1alpha1.MeshClusterStatus, error) {
+	status := *mc.Status.DeepCopy()
+	status.ObservedGeneration = mc.Generation
+	status.Role = state.role
+	status.Peers = state.peers
+
+	reconciledCondition := determineReconciledCondition(reconcileErr)
+	readyCondition := v1.StatusCondition{Type: v1alpha1.MeshClusterConditionReady, Status: metav1.ConditionUnknown}
+	var err error
+	if state.istio != nil && state.rev != nil {
+		status.EastWestGatewayAddresses, readyCondition, err = r.determineReadyCondition(ctx, mc, state)
+	}
+	status.SetCondition(reconciledCondition)
+	status.SetCondition(readyCondition)
+	status.State = reconciler.DeriveState(v1alpha1.MeshClusterReasonHealthy, reconciledCondition, readyCondition)
+	return status, err
+}
+
+func (r *Reconciler) updateStatus(ctx context.Context, mc *v1alpha1.MeshCluster, state observedState, reconcileErr error) error {
+	status, err := r.determineStatus(ctx, mc, state, reconcileErr)
+	return reconciler.UpdateStatus(ctx, r.Client, mc, mc.Status, status, err)
+}
+
+func determineReconciledCondition(err error) v1.StatusCondition {
+	c := v1.StatusCondition{Type: v1alpha1.MeshClusterConditionReconciled}
+	if err == nil {
+		c.Status = metav1.ConditionTrue
+		c.Reason = v1.ConditionReason(v1alpha1.MeshClusterConditionReconciled)
+	} else {
+		c.Status = metav1.ConditionFalse
+		if reconciler.IsReferenceNotFoundError(err) {
+			c.Reason = v1alpha1.MeshClusterReasonReferenceNotFound
+			c.Message = err.Error()
+		} else {
+			c.Reason = v1alpha1.MeshClusterReasonReconcileError
+			c.Message = fmt.Sprintf("error reconciling resource: %v", err)
+		}
+	}
+	return c
+}
+
+// determineReadyCondition returns the addresses of the east-west gateway and the Ready condition. A remote
+// cluster is ready when the remote istiod is reachable, which is probed by the webhook controller and reported in
+// the Ready condition of the IstioRevision. A primary cluster is ready when the east-west gateway has an address
+// and all peer clusters are connected.
+func (r *Reconciler) determineReadyCondition(
+	ctx context.Context, mc *v1alpha1.MeshCluster, state observedState,
+) ([]string, v1.StatusCondition, error) {
+	c := v1.StatusCondition{Type: v1alpha1.MeshClusterConditionReady, Status: metav1.ConditionFalse}
+
+	var addresses []string
+	if mc.Spec.Network != "" {
+		var ready bool
+		var message string
+		var err error
+		addresses, ready, message, err = r.getEastWestGatewayStatus(ctx, mc, state.istio.Spec.Namespace)
+		if err != nil {
+			c.Status = metav1.ConditionUnknown
+			c.Reason = v1alpha1.MeshClusterReasonEastWestGatewayNotReady
+			c.Message = err.Error()
+			return nil, c, err
+		} else if !ready {
+			c.Reason = v1alpha1.MeshClusterReasonEastWestGatewayNotReady
+			c.Message = message
+			return addresses, c, nil
+		}
+	}
+
+	if state.role == v1alpha1.MeshClusterRoleRemote {
+		if revReady := state.rev.Status.GetCondition(v1.IstioRevisionConditionReady); revReady.Status != metav1.ConditionTrue {
+			c.Reason = v1alpha1.MeshClusterReasonRemoteIstiodNotReady
+			c.Message = revReady.Message
+			return addresses, c, nil
+		}
+	}
+
+	var disconnected []string
+	for _, peer := range state.peers {
+		if !peer.Connected {
+			disconnected = append(disconnected, peer.Name)
+		}
+	}
+	if len(disconnected) > 0 {
+		c.Reason = v1alpha1.MeshClusterReasonPeersNotConnected
+		c.Message = "the following peer clusters are not connected: " + strings.Join(disconnected, ", ")
+		return addresses, c, nil
+	}
+
+	c.Status = metav1.ConditionTrue
+	c.Reason = v1.ConditionReason(v1alpha1.MeshClusterConditionReady)
+	return addresses, c, nil
+}
+
+// getEastWestGatewayStatus returns the addresses of the east-west gateway and whether it is ready to receive
+// traffic from the other clusters
+func (r *Reconciler) getEastWestGatewayStatus(ctx context.Context, mc *v1alpha1.MeshCluster, namespace string) ([]string, bool, string, error) {
+	key := types.NamespacedName{Namespace: namespace, Name: EastWestGatewayName}
+	gw := &v1alpha1.IstioGateway{}
+	if err := r.Client.Get(ctx, key, gw); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, false, "east-west gateway not found", nil
+		}
+		return nil, false, "", fmt.Errorf("failed to get IstioGateway %s: %w", key, err)
+	}
+
+	svc := &corev1.Service{}
+	if err := r.Client.Get(ctx, key, svc); err != nil && !apierrors.IsNotFound(err) {
+		return nil, false, "", fmt.Errorf("failed to get Service %s: %w", key, err)
+	}
+	var addresses []string
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.IP != "" {
+			addresses = append(addresses, ingress.IP)
+		} else if ingress.Hostname != "" {
+			addresses = append(addresses, ingress.Hostname)
+		}
+	}
+	sort.Strings(addresses)
+
+	if gwReady := gw.Status.GetCondition(v1alpha1.IstioGatewayConditionReady); gwReady.Status != metav1.ConditionTrue {
+		return addresses, false, "east-west gateway is not ready: " + gwReady.Message, nil
+	}
+	if serviceType(mc) == string(corev1.ServiceTypeLoadBalancer) && len(addresses) == 0 {
+		return addresses, false, "east-west gateway has no load balancer address yet", nil
+	}
+	return addresses, true, "", nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	logger := mgr.GetLogger().WithName("ctrlr").WithName("meshcluster")
+
+	// mainObjectHandler handles the MeshCluster watch events
+	mainObjectHandler := wrapEventHandler(logger, &handler.EnqueueRequestForObject{})
+
+	// ownedResourceHandler handles the east-west gateway, whose readiness is reported in the status
+	ownedResourceHandler := wrapEventHandler(logger,
+		handler.EnqueueRequestForOwner(r.Scheme, r.RESTMapper(), &v1alpha1.MeshCluster{}, handler.OnlyControllerOwner()))
+
+	// operatorResourcesHandler handles watch events from operator CRDs Istio and IstioRevision, since the
+	// MeshCluster depends on the namespace and the active revision of the referenced Istio
+	operatorResourcesHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(
+		func(context.Context, client.Object) []reconcile.Request {
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: meshcluster.Name}}}
+		}))
+
+	return ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{
+			LogConstructor: func(req *reconcile.Request) logr.Logger {
+				log := logger
+				if req != nil {
+					log = log.WithValues(v1alpha1.MeshClusterKind, req.Name)
+				}
+				return log
+			},
+			MaxConcurrentReconciles: r.Config.MaxConcurrentReconciles,
+		}).
+		// we use the Watches function instead of For(), so that we can wrap the handler so that events that cause the object to be enqueued are logged
+		Watches(&v1alpha1.MeshCluster{}, mainObjectHandler).
+		Named("meshcluster").
+		Watches(&v1alpha1.IstioGateway{}, ownedResourceHandler).
+		Watches(&v1.Istio{}, operatorResourcesHandler).
+		Watches(&v1.IstioRevision{}, operatorResourcesHandler).
+		Complete(reconciler.NewStandardReconciler[*v1alpha1.MeshCluster](r.Client, r.Reconcile))
+}
+
+func wrapEventHandler(logger logr.Logger, handler handler.EventHandler) handler.EventHandler {
+	return enqueuelogger.WrapIfNecessary(v1alpha1.MeshClusterKind, logger, handler)
+}
@@ -0,0 +1,396 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meshcluster
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/meshcluster"
+	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
+	. "github.com/onsi/gomega"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes"
+	fakeclientset "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+const (
+	operatorNamespace = "sail-operator"
+	istioNamespace    = "istio-system"
+	peerKubeconfig    = `apiVersion: v1
+kind: Config
+clusters:
+- name: cluster2
+  cluster:
+    server: https://cluster2.example.com:6443
+    certificate-authority-data: Y2EtZGF0YQ==
+users:
+- name: admin
+  user:
+    token: admin-token
+contexts:
+- name: cluster2
+  context:
+    cluster: cluster2
+    user: admin
+current-context: cluster2
+`
+)
+
+// fakePeer simulates the API server of a peer cluster
+type fakePeer struct {
+	tokenRequests int
+	tokens        map[string]bool
+	hosts         []string
+}
+
+func (p *fakePeer) newClient(cfg *rest.Config) (kubernetes.Interface, error) {
+	p.hosts = append(p.hosts, cfg.Host)
+	cs := fakeclientset.NewClientset()
+	cs.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		p.tokenRequests++
+		req := action.(k8stesting.CreateActionImpl).GetObject().(*authenticationv1.TokenRequest)
+		req.Status = authenticationv1.TokenRequestStatus{
+			Token:               "reader-token",
+			ExpirationTimestamp: metav1.NewTime(time.Now().Add(meshcluster.TokenTTL)),
+		}
+		return true, req, nil
+	})
+	cs.PrependReactor("list", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if !p.tokens[cfg.BearerToken] {
+			return true, nil, &unauthorizedError{}
+		}
+		return true, &corev1.ServiceList{}, nil
+	})
+	cs.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.33.1"}
+	return cs, nil
+}
+
+type unauthorizedError struct{}
+
+func (e *unauthorizedError) Error() string {
+	return "Unauthorized"
+}
+
+func newFakeClientBuilder() *fake.ClientBuilder {
+	s := runtime.NewScheme()
+	for gvk := range scheme.Scheme.AllKnownTypes() {
+		obj, _ := scheme.Scheme.New(gvk)
+		s.AddKnownTypeWithName(gvk, obj)
+	}
+	mapper := meta.NewDefaultRESTMapper(nil)
+	for _, gvk := range []struct {
+		kind  string
+		scope meta.RESTScope
+	}{{"Gateway", meta.RESTScopeNamespace}, {"VirtualService", meta.RESTScopeNamespace}} {
+		gv := GatewayGVK.GroupVersion()
+		s.AddKnownTypeWithName(gv.WithKind(gvk.kind), &unstructured.Unstructured{})
+		s.AddKnownTypeWithName(gv.WithKind(gvk.kind+"List"), &unstructured.UnstructuredList{})
+		mapper.Add(gv.WithKind(gvk.kind), gvk.scope)
+	}
+	return fake.NewClientBuilder().
+		WithScheme(s).
+		WithRESTMapper(mapper).
+		WithStatusSubresource(&v1alpha1.MeshCluster{})
+}
+
+func newTestObjects(profile string) []client.Object {
+	rev := &v1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1.IstioRevisionSpec{
+			Version:   "v1.30.0",
+			Namespace: istioNamespace,
+			Values:    &v1.Values{},
+		},
+	}
+	if profile != "" {
+		rev.Spec.Values.Profile = ptr.Of(profile)
+	}
+	return []client.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: istioNamespace}},
+		&v1.Istio{
+			ObjectMeta: metav1.ObjectMeta{Name: "default"},
+			Spec:       v1.IstioSpec{Namespace: istioNamespace},
+			Status:     v1.IstioStatus{ActiveRevisionName: "default"},
+		},
+		rev,
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster2-kubeconfig", Namespace: operatorNamespace},
+			Data:       map[string][]byte{"kubeconfig": []byte(peerKubeconfig)},
+		},
+	}
+}
+
+func newMeshCluster(network string, peers ...string) *v1alpha1.MeshCluster {
+	mc := &v1alpha1.MeshCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: meshcluster.Name, UID: "mc-uid"},
+		Spec: v1alpha1.MeshClusterSpec{
+			TargetRef:       v1.TargetReference{Kind: v1.IstioKind, Name: "default"},
+			MeshID:          "mesh1",
+			ClusterName:     "cluster1",
+			Network:         network,
+			EastWestGateway: &v1alpha1.MeshClusterEastWestGateway{ExposeIstiod: true},
+		},
+	}
+	for _, peer := range peers {
+		mc.Spec.Peers = append(mc.Spec.Peers, v1alpha1.MeshClusterPeer{
+			Name:                peer,
+			KubeconfigSecretRef: v1alpha1.SecretKeyReference{Name: peer + "-kubeconfig", Key: "kubeconfig"},
+		})
+	}
+	return mc
+}
+
+func newTestReconciler(cl client.Client, peer *fakePeer) *Reconciler {
+	r := NewReconciler(config.ReconcilerConfig{OperatorNamespace: operatorNamespace, ResourceFS: fstest.MapFS{}}, cl, cl, scheme.Scheme)
+	r.NewPeerClient = peer.newClient
+	return r
+}
+
+func reconcileAndGet(g *WithT, r *Reconciler, cl client.Client) *v1alpha1.MeshCluster {
+	mc := &v1alpha1.MeshCluster{}
+	g.Expect(cl.Get(context.TODO(), types.NamespacedName{Name: meshcluster.Name}, mc)).To(Succeed())
+	_, err := r.Reconcile(context.TODO(), mc)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cl.Get(context.TODO(), types.NamespacedName{Name: meshcluster.Name}, mc)).To(Succeed())
+	return mc
+}
+
+func TestReconcilePrimary(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+	peer := &fakePeer{tokens: map[string]bool{"reader-token": true}}
+	cl := newFakeClientBuilder().
+		WithObjects(newTestObjects("")...).
+		WithObjects(newMeshCluster("network1", "cluster2")).
+		Build()
+	r := newTestReconciler(cl, peer)
+
+	mc := reconcileAndGet(g, r, cl)
+
+	// the namespace is labeled with the network
+	ns := &corev1.Namespace{}
+	g.Expect(cl.Get(ctx, types.NamespacedName{Name: istioNamespace}, ns)).To(Succeed())
+	g.Expect(ns.Labels).To(HaveKeyWithValue(NetworkLabelKey, "network1"))
+
+	// the east-west gateway is deployed
+	gw := &v1alpha1.IstioGateway{}
+	g.Expect(cl.Get(ctx, types.NamespacedName{Namespace: istioNamespace, Name: EastWestGatewayName}, gw)).To(Succeed())
+	g.Expect(gw.Spec.Values.NetworkGateway).To(Equal(ptr.Of("network1")))
+	g.Expect(gw.Spec.Values.Service.Type).To(Equal(ptr.Of("LoadBalancer")))
+	g.Expect(metav1.IsControlledBy(gw, mc)).To(BeTrue())
+	for _, obj := range []*unstructured.Unstructured{
+		newCrossNetworkGateway(istioNamespace), newIstiodGateway(istioNamespace), newIstiodVirtualService(istioNamespace, ""),
+	} {
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(obj), obj)).To(Succeed())
+	}
+	vs := newIstiodVirtualService(istioNamespace, "")
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(vs), vs)).To(Succeed())
+	routes, _, _ := unstructured.NestedSlice(vs.Object, "spec", "tls")
+	g.Expect(routes[0]).To(HaveKeyWithValue("route", ContainElement(HaveKeyWithValue("destination",
+		HaveKeyWithValue("host", "istiod.istio-system.svc.cluster.local")))))
+
+	// the remote secret gives istiod access to the peer
+	secret := &corev1.Secret{}
+	g.Expect(cl.Get(ctx, types.NamespacedName{Namespace: istioNamespace, Name: "istio-remote-secret-cluster2"}, secret)).To(Succeed())
+	g.Expect(secret.Labels).To(HaveKeyWithValue(meshcluster.MultiClusterSecretLabelKey, "true"))
+	g.Expect(secret.Annotations).To(HaveKeyWithValue(meshcluster.ClusterAnnotationKey, "cluster2"))
+	cfg, err := meshcluster.RESTConfigFromRemoteSecret(secret, "cluster2")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(cfg.Host).To(Equal("https://cluster2.example.com:6443"))
+	g.Expect(cfg.BearerToken).To(Equal("reader-token"))
+	g.Expect(string(cfg.TLSClientConfig.CAData)).To(Equal("ca-data"))
+
+	g.Expect(mc.Status.Role).To(Equal(v1alpha1.MeshClusterRolePrimary))
+	g.Expect(mc.Status.Peers).To(HaveLen(1))
+	g.Expect(mc.Status.Peers[0].Connected).To(BeTrue(), mc.Status.Peers[0].Message)
+	g.Expect(mc.Status.Peers[0].ServerVersion).To(Equal("v1.33.1"))
+	g.Expect(mc.Status.Peers[0].TokenExpirationTime).NotTo(BeNil())
+	g.Expect(mc.Status.GetCondition(v1alpha1.MeshClusterConditionReconciled).Status).To(Equal(metav1.ConditionTrue))
+	ready := mc.Status.GetCondition(v1alpha1.MeshClusterConditionReady)
+	g.Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+	g.Expect(ready.Reason).To(Equal(v1alpha1.MeshClusterReasonEastWestGatewayNotReady))
+
+	// once the gateway is ready and has an address, the cluster is ready
+	gw.Status.SetCondition(v1.StatusCondition{Type: v1alpha1.IstioGatewayConditionReady, Status: metav1.ConditionTrue})
+	g.Expect(cl.Update(ctx, gw)).To(Succeed())
+	g.Expect(cl.Create(ctx, &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: EastWestGatewayName, Namespace: istioNamespace},
+		Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+			Ingress: []corev1.LoadBalancerIngress{{IP: "192.0.2.10"}},
+		}},
+	})).To(Succeed())
+
+	mc = reconcileAndGet(g, r, cl)
+	g.Expect(mc.Status.EastWestGatewayAddresses).To(Equal([]string{"192.0.2.10"}))
+	g.Expect(mc.Status.GetCondition(v1alpha1.MeshClusterConditionReady).Status).To(Equal(metav1.ConditionTrue))
+	g.Expect(mc.Status.State).To(Equal(v1alpha1.MeshClusterReasonHealthy))
+
+	// the token isn't renewed while it's still valid
+	g.Expect(peer.tokenRequests).To(Equal(1))
+}
+
+func TestReconcileRenewsToken(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+	peer := &fakePeer{tokens: map[string]bool{"reader-token": true}}
+	mc := newMeshCluster("", "cluster2")
+	expiring := meshcluster.NewRemoteSecret("cluster2", istioNamespace, []byte("old"), time.Now().Add(time.Hour), "999")
+	expiring.OwnerReferences = []metav1.OwnerReference{ownerReference(mc)}
+	cl := newFakeClientBuilder().
+		WithObjects(newTestObjects("")...).
+		WithObjects(mc, expiring).
+		Build()
+	r := newTestReconciler(cl, peer)
+
+	mc = reconcileAndGet(g, r, cl)
+	g.Expect(peer.tokenRequests).To(Equal(1))
+
+	secret := &corev1.Secret{}
+	g.Expect(cl.Get(ctx, types.NamespacedName{Namespace: istioNamespace, Name: "istio-remote-secret-cluster2"}, secret)).To(Succeed())
+	expiration, found := meshcluster.TokenExpiration(secret)
+	g.Expect(found).To(BeTrue())
+	g.Expect(expiration).To(BeTemporally(">", time.Now().Add(meshcluster.TokenTTL/2)))
+	g.Expect(mc.Status.GetCondition(v1alpha1.MeshClusterConditionReady).Status).To(Equal(metav1.ConditionTrue))
+}
+
+func TestReconcilePeerNotConnected(t *testing.T) {
+	g := NewWithT(t)
+	// the peer doesn't accept the tokens it issues, e.g. because the istio-reader-service-account lacks permissions
+	peer := &fakePeer{tokens: map[string]bool{}}
+	cl := newFakeClientBuilder().
+		WithObjects(newTestObjects("")...).
+		WithObjects(newMeshCluster("", "cluster2", "cluster3")).
+		Build()
+	r := newTestReconciler(cl, peer)
+
+	mc := reconcileAndGet(g, r, cl)
+	g.Expect(mc.Status.Peers).To(HaveLen(2))
+	g.Expect(mc.Status.Peers[0].Connected).To(BeFalse())
+	g.Expect(mc.Status.Peers[0].Message).To(ContainSubstring("Unauthorized"))
+	g.Expect(mc.Status.Peers[1].Connected).To(BeFalse())
+	g.Expect(mc.Status.Peers[1].Message).To(ContainSubstring("failed to get kubeconfig Secret"))
+
+	ready := mc.Status.GetCondition(v1alpha1.MeshClusterConditionReady)
+	g.Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+	g.Expect(ready.Reason).To(Equal(v1alpha1.MeshClusterReasonPeersNotConnected))
+	g.Expect(ready.Message).To(ContainSubstring("cluster2, cluster3"))
+}
+
+func TestReconcileRemovesPeer(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+	peer := &fakePeer{tokens: map[string]bool{"reader-token": true}}
+	cl := newFakeClientBuilder().
+		WithObjects(newTestObjects("")...).
+		WithObjects(newMeshCluster("network1", "cluster2")).
+		Build()
+	r := newTestReconciler(cl, peer)
+	mc := reconcileAndGet(g, r, cl)
+
+	mc.Spec.Peers = nil
+	mc.Spec.EastWestGateway = nil
+	g.Expect(cl.Update(ctx, mc)).To(Succeed())
+	reconcileAndGet(g, r, cl)
+
+	g.Expect(cl.Get(ctx, types.NamespacedName{Namespace: istioNamespace, Name: "istio-remote-secret-cluster2"}, &corev1.Secret{})).
+		NotTo(Succeed())
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(newIstiodGateway(istioNamespace)), newIstiodGateway(istioNamespace))).
+		NotTo(Succeed())
+	g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(newCrossNetworkGateway(istioNamespace)), newCrossNetworkGateway(istioNamespace))).
+		To(Succeed())
+}
+
+func TestReconcileRemote(t *testing.T) {
+	g := NewWithT(t)
+	ctx := context.TODO()
+	peer := &fakePeer{tokens: map[string]bool{"reader-token": true}}
+	objects := newTestObjects("remote")
+	rev := objects[2].(*v1.IstioRevision)
+	rev.Status.SetCondition(v1.StatusCondition{
+		Type:    v1.IstioRevisionConditionReady,
+		Status:  metav1.ConditionFalse,
+		Reason:  v1.IstioRevisionReasonRemoteIstiodNotReady,
+		Message: "remote istiod is not reachable",
+	})
+	cl := newFakeClientBuilder().
+		WithObjects(objects...).
+		WithObjects(newMeshCluster("", "cluster2")).
+		Build()
+	r := newTestReconciler(cl, peer)
+
+	mc := reconcileAndGet(g, r, cl)
+	g.Expect(mc.Status.Role).To(Equal(v1alpha1.MeshClusterRoleRemote))
+	g.Expect(mc.Status.Peers).To(BeEmpty())
+	g.Expect(peer.tokenRequests).To(BeZero())
+	g.Expect(cl.Get(ctx, types.NamespacedName{Namespace: istioNamespace, Name: "istio-remote-secret-cluster2"}, &corev1.Secret{})).
+		NotTo(Succeed())
+
+	ready := mc.Status.GetCondition(v1alpha1.MeshClusterConditionReady)
+	g.Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+	g.Expect(ready.Reason).To(Equal(v1alpha1.MeshClusterReasonRemoteIstiodNotReady))
+	g.Expect(ready.Message).To(Equal("remote istiod is not reachable"))
+}
+
+func TestReconcileIstioNotFound(t *testing.T) {
+	g := NewWithT(t)
+	cl := newFakeClientBuilder().WithObjects(newMeshCluster("network1")).Build()
+	r := newTestReconciler(cl, &fakePeer{})
+
+	mc := &v1alpha1.MeshCluster{}
+	g.Expect(cl.Get(context.TODO(), types.NamespacedName{Name: meshcluster.Name}, mc)).To(Succeed())
+	_, err := r.Reconcile(context.TODO(), mc)
+	g.Expect(err).To(HaveOccurred())
+
+	g.Expect(cl.Get(context.TODO(), types.NamespacedName{Name: meshcluster.Name}, mc)).To(Succeed())
+	reconciled := mc.Status.GetCondition(v1alpha1.MeshClusterConditionReconciled)
+	g.Expect(reconciled.Status).To(Equal(metav1.ConditionFalse))
+	g.Expect(reconciled.Reason).To(Equal(v1alpha1.MeshClusterReasonReferenceNotFound))
+	g.Expect(mc.Status.GetCondition(v1alpha1.MeshClusterConditionReady).Status).To(Equal(metav1.ConditionUnknown))
+}
+
+func TestIstiodHost(t *testing.T) {
+	g := NewWithT(t)
+	rev := &v1.IstioRevision{Spec: v1.IstioRevisionSpec{Namespace: istioNamespace}}
+	g.Expect(istiodHost(rev)).To(Equal("istiod.istio-system.svc.cluster.local"))
+
+	rev.Spec.Values = &v1.Values{
+		Revision: ptr.Of("canary"),
+		Global:   &v1.GlobalConfig{Proxy: &v1.ProxyConfig{ClusterDomain: ptr.Of("example.local")}},
+	}
+	g.Expect(istiodHost(rev)).To(Equal("istiod-canary.istio-system.svc.example.local"))
+}
@@ -0,0 +1,372 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitoring
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/enqueuelogger"
+	"github.com/istio-ecosystem/sail-operator/pkg/errlist"
+	"github.com/istio-ecosystem/sail-operator/pkg/kube"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	"github.com/istio-ecosystem/sail-operator/pkg/revision"
+	"github.com/istio-ecosystem/sail-operator/pkg/watches"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Reconciler creates the ServiceMonitor and PodMonitor objects for the IstioRevisions owned by
+// an Istio object that has the sailoperator.io/monitoring annotation set to "enabled".
+type Reconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Config config.ReconcilerConfig
+}
+
+func NewReconciler(reconcilerCfg config.ReconcilerConfig, client client.Client, scheme *runtime.Scheme) *Reconciler {
+	return &Reconciler{
+		Client: client,
+		Scheme: scheme,
+		Config: reconcilerCfg,
+	}
+}
+
+// IsMonitoringEnabled returns true if the Istio object requests the creation of ServiceMonitor and PodMonitor objects.
+func IsMonitoringEnabled(istio *v1.Istio) bool {
+	return istio.Annotations[constants.MonitoringAnnotationKey] == constants.MonitoringEnabledValue
+}
+
+// +kubebuilder:rbac:groups="monitoring.coreos.com",resources=servicemonitors;podmonitors,verbs=get;list;watch;create;delete
+
+// Reconcile creates the ServiceMonitor and PodMonitor objects for the given IstioRevision if they don't exist yet.
+// Existing objects are never updated, so that users can customize them. On OpenShift, the PodMonitor objects are
+// created in the namespaces that reference the revision and are deleted when a namespace stops referencing it.
+// When monitoring is disabled, the monitors that the operator created for the revision are deleted.
+func (r *Reconciler) Reconcile(ctx context.Context, rev *v1.IstioRevision) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	istio, err := r.getOwnerIstio(ctx, rev)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if available, err := r.isMonitoringAvailable(); err != nil {
+		return ctrl.Result{}, err
+	} else if !available {
+		log.V(2).Info("ServiceMonitor and PodMonitor CRDs not found. Skipping reconciliation")
+		return ctrl.Result{}, nil
+	}
+
+	if istio == nil || !IsMonitoringEnabled(istio) {
+		log.V(2).Info("Monitoring is not enabled for this IstioRevision. Deleting managed monitors")
+		return ctrl.Result{}, r.deleteManagedMonitors(ctx, rev.Name)
+	}
+
+	var errs errlist.Builder
+	serviceMonitor := newServiceMonitor(rev, r.Config.Platform)
+	setOwnerReference(serviceMonitor, rev)
+	errs.Add(r.createIfNotExists(ctx, serviceMonitor))
+
+	if r.Config.Platform == config.PlatformOpenShift {
+		errs.Add(r.reconcileNamespacedPodMonitors(ctx, rev))
+	} else {
+		podMonitor := newPodMonitor(rev, rev.Spec.Namespace, r.Config.Platform)
+		setOwnerReference(podMonitor, rev)
+		errs.Add(r.createIfNotExists(ctx, podMonitor))
+	}
+	return ctrl.Result{}, errs.Error()
+}
+
+func (r *Reconciler) getOwnerIstio(ctx context.Context, rev *v1.IstioRevision) (*v1.Istio, error) {
+	ownerRef := metav1.GetControllerOf(rev)
+	if ownerRef == nil || ownerRef.Kind != v1.IstioKind {
+		return nil, nil
+	}
+
+	istio := &v1.Istio{}
+	if err := r.Client.Get(ctx, kube.Key(ownerRef.Name), istio); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get failed: %w", err)
+	}
+	if istio.UID != ownerRef.UID {
+		return nil, nil
+	}
+	return istio, nil
+}
+
+// isMonitoringAvailable checks whether the ServiceMonitor and PodMonitor CRDs are installed in the cluster
+func (r *Reconciler) isMonitoringAvailable() (bool, error) {
+	for _, gvk := range []schema.GroupVersionKind{ServiceMonitorGVK, PodMonitorGVK} {
+		if _, err := r.Client.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version); err != nil {
+			if meta.IsNoMatchError(err) {
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to get REST mapping for %s: %w", gvk.Kind, err)
+		}
+	}
+	return true, nil
+}
+
+func (r *Reconciler) createIfNotExists(ctx context.Context, obj *unstructured.Unstructured) error {
+	log := logf.FromContext(ctx)
+
+	existing := &unstructured.Unstructured{}
+	existing.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := r.Client.Get(ctx, client.ObjectKeyFromObject(obj), existing); err == nil {
+		return nil
+	} else if !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to get %s: %w", obj.GetKind(), err)
+	}
+
+	log.Info("Creating "+obj.GetKind(), "namespace", obj.GetNamespace(), "name", obj.GetName())
+	if err := r.Client.Create(ctx, obj); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create %s: %w", obj.GetKind(), err)
+	}
+	return nil
+}
+
+// deleteManagedMonitors deletes the ServiceMonitor and PodMonitors that the operator created for the given revision.
+// The PodMonitors on OpenShift aren't owned by the IstioRevision, so they would otherwise never be deleted.
+func (r *Reconciler) deleteManagedMonitors(ctx context.Context, revName string) error {
+	log := logf.FromContext(ctx)
+
+	var errs errlist.Builder
+	for _, gvk := range []schema.GroupVersionKind{ServiceMonitorGVK, PodMonitorGVK} {
+		monitors := &unstructured.UnstructuredList{}
+		monitors.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := r.Client.List(ctx, monitors, client.MatchingLabels{
+			constants.ManagedByLabelKey: constants.ManagedByLabelValue,
+			constants.IstioRevLabel:     revName,
+		}); err != nil {
+			errs.Add(fmt.Errorf("failed to list %ss: %w", gvk.Kind, err))
+			continue
+		}
+
+		for i := range monitors.Items {
+			monitor := &monitors.Items[i]
+			log.Info("Deleting "+gvk.Kind, "namespace", monitor.GetNamespace(), "name", monitor.GetName())
+			if err := r.Client.Delete(ctx, monitor); err != nil && !apierrors.IsNotFound(err) {
+				errs.Add(fmt.Errorf("failed to delete %s: %w", gvk.Kind, err))
+			}
+		}
+	}
+	return errs.Error()
+}
+
+// reconcileNamespacedPodMonitors creates a PodMonitor in each namespace that references the revision and deletes
+// the PodMonitors that the operator created in namespaces that no longer reference it. Since these PodMonitors
+// can't be owned by the IstioRevision, the PodMonitors of revisions that no longer exist are also deleted here.
+func (r *Reconciler) reconcileNamespacedPodMonitors(ctx context.Context, rev *v1.IstioRevision) error {
+	log := logf.FromContext(ctx)
+
+	namespaces, err := r.getReferencingNamespaces(ctx, rev.Name)
+	if err != nil {
+		return err
+	}
+
+	var errs errlist.Builder
+	for ns := range namespaces {
+		errs.Add(r.createIfNotExists(ctx, newPodMonitor(rev, ns, r.Config.Platform)))
+	}
+
+	podMonitors := &unstructured.UnstructuredList{}
+	podMonitors.SetGroupVersionKind(PodMonitorGVK.GroupVersion().WithKind(PodMonitorKind + "List"))
+	if err := r.Client.List(ctx, podMonitors,
+		client.MatchingLabels{constants.ManagedByLabelKey: constants.ManagedByLabelValue},
+		client.HasLabels{constants.IstioRevLabel}); err != nil {
+		errs.Add(fmt.Errorf("failed to list PodMonitors: %w", err))
+		return errs.Error()
+	}
+
+	for i := range podMonitors.Items {
+		podMonitor := &podMonitors.Items[i]
+		revName := podMonitor.GetLabels()[constants.IstioRevLabel]
+		if podMonitor.GetName() != PodMonitorName(revName) || len(podMonitor.GetOwnerReferences()) > 0 {
+			// owned PodMonitors are garbage-collected by Kubernetes
+			continue
+		}
+
+		var keep bool
+		if revName == rev.Name {
+			keep = namespaces[podMonitor.GetNamespace()]
+		} else {
+			keep, err = r.revisionExists(ctx, revName)
+			if err != nil {
+				errs.Add(err)
+				continue
+			}
+		}
+		if keep {
+			continue
+		}
+
+		log.Info("Deleting PodMonitor", "namespace", podMonitor.GetNamespace(), "name", podMonitor.GetName())
+		if err := r.Client.Delete(ctx, podMonitor); err != nil && !apierrors.IsNotFound(err) {
+			errs.Add(fmt.Errorf("failed to delete PodMonitor: %w", err))
+		}
+	}
+	return errs.Error()
+}
+
+// getReferencingNamespaces returns the namespaces that reference the revision either directly or through an
+// IstioRevisionTag.
+func (r *Reconciler) getReferencingNamespaces(ctx context.Context, revName string) (map[string]bool, error) {
+	tagList := v1.IstioRevisionTagList{}
+	if err := r.Client.List(ctx, &tagList); err != nil {
+		return nil, fmt.Errorf("failed to list IstioRevisionTags: %w", err)
+	}
+	// a namespace references the revision if its labels select the revision itself or a tag targeting it, unless
+	// a tag with the revision's name targets another revision
+	var names []string
+	isTag := false
+	for _, tag := range tagList.Items {
+		if tag.Status.IstioRevision == "" {
+			continue
+		}
+		isTag = isTag || tag.Name == revName
+		if tag.Status.IstioRevision == revName {
+			names = append(names, tag.Name)
+		}
+	}
+	if !isTag {
+		names = append(names, revName)
+	}
+
+	namespaces := map[string]bool{}
+	for _, name := range names {
+		referencing, err := revision.ListNamespaceReferences(ctx, r.Client, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range referencing {
+			// namespaces that are only served by the revision in ambient mode don't get a PodMonitor
+			if ns.DeletionTimestamp.IsZero() && revision.GetReferencedRevisionFromNamespace(ns.Labels) == name {
+				namespaces[ns.Name] = true
+			}
+		}
+	}
+	return namespaces, nil
+}
+
+func (r *Reconciler) revisionExists(ctx context.Context, name string) (bool, error) {
+	rev := &v1.IstioRevision{}
+	if err := r.Client.Get(ctx, kube.Key(name), rev); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("get failed: %w", err)
+	}
+	return true, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	logger := mgr.GetLogger().WithName("ctrlr").WithName("monitoring")
+
+	// mainObjectHandler handles the IstioRevision watch events
+	mainObjectHandler := wrapEventHandler(logger, &handler.EnqueueRequestForObject{})
+
+	// istioHandler triggers the reconciliation of the IstioRevisions owned by an Istio object, so that the
+	// monitors are created as soon as the sailoperator.io/monitoring annotation is set
+	istioHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapIstioToReconcileRequests))
+
+	b := ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{
+			LogConstructor: func(req *reconcile.Request) logr.Logger {
+				log := logger
+				if req != nil {
+					log = log.WithValues("IstioRevision", req.Name)
+				}
+				return log
+			},
+			MaxConcurrentReconciles: r.Config.MaxConcurrentReconciles,
+		}).
+		// we use the Watches function instead of For(), so that we can wrap the handler so that events that cause the object to be enqueued are logged
+		Watches(&v1.IstioRevision{}, mainObjectHandler).
+		Named("monitoring").
+		Watches(&v1.Istio{}, istioHandler)
+
+	// ServiceMonitors and PodMonitors aren't watched, because their CRDs may not be installed and because the
+	// controller never updates them after they're created.
+	if r.Config.Platform == config.PlatformOpenShift {
+		// the PodMonitors are created in the namespaces that reference each revision, so all revisions must be
+		// reconciled when a namespace or IstioRevisionTag changes or when a revision is deleted
+		allRevisionsHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapToAllRevisions))
+		b = b.
+			Watches(&corev1.Namespace{}, allRevisionsHandler, builder.OnlyMetadata, builder.WithPredicates(watches.AsPredicate(watches.IgnoreStatusChanges()))).
+			Watches(&v1.IstioRevisionTag{}, allRevisionsHandler).
+			Watches(&v1.IstioRevision{}, allRevisionsHandler, builder.WithPredicates(predicate.Funcs{
+				CreateFunc:  func(event.CreateEvent) bool { return false },
+				UpdateFunc:  func(event.UpdateEvent) bool { return false },
+				GenericFunc: func(event.GenericEvent) bool { return false },
+			}))
+	}
+
+	return b.Complete(reconciler.NewStandardReconciler[*v1.IstioRevision](r.Client, r.Reconcile))
+}
+
+func (r *Reconciler) mapIstioToReconcileRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	log := logf.FromContext(ctx)
+	revisions, err := revision.ListOwned(ctx, r.Client, obj.GetUID())
+	if err != nil {
+		log.Error(err, "failed to list IstioRevisions owned by Istio", "Istio", obj.GetName())
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(revisions))
+	for _, rev := range revisions {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: rev.Name}})
+	}
+	return requests
+}
+
+func (r *Reconciler) mapToAllRevisions(ctx context.Context, _ client.Object) []reconcile.Request {
+	log := logf.FromContext(ctx)
+	revList := v1.IstioRevisionList{}
+	if err := r.Client.List(ctx, &revList); err != nil {
+		log.Error(err, "failed to list IstioRevisions")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(revList.Items))
+	for _, rev := range revList.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: rev.Name}})
+	}
+	return requests
+}
+
+func wrapEventHandler(logger logr.Logger, handler handler.EventHandler) handler.EventHandler {
+	return enqueuelogger.WrapIfNecessary(v1.IstioRevisionKind, logger, handler)
+}
@@ -0,0 +1,356 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitoring
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/kube"
+	"github.com/istio-ecosystem/sail-operator/pkg/revision"
+	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+const (
+	istioName    = "my-istio"
+	revName      = "my-istio-v1"
+	otherRevName = "my-istio-v2"
+	istioNs      = "istio-system"
+)
+
+var ctx = context.Background()
+
+func TestReconcileKubernetes(t *testing.T) {
+	tests := []struct {
+		name          string
+		annotations   map[string]string
+		crdsInstalled bool
+		expectCreated bool
+	}{
+		{
+			name:          "monitoring enabled",
+			annotations:   map[string]string{constants.MonitoringAnnotationKey: constants.MonitoringEnabledValue},
+			crdsInstalled: true,
+			expectCreated: true,
+		},
+		{
+			name:          "annotation not set",
+			crdsInstalled: true,
+		},
+		{
+			name:          "annotation set to other value",
+			annotations:   map[string]string{constants.MonitoringAnnotationKey: "disabled"},
+			crdsInstalled: true,
+		},
+		{
+			name:        "CRDs not installed",
+			annotations: map[string]string{constants.MonitoringAnnotationKey: constants.MonitoringEnabledValue},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			istio, rev := newIstioAndRevision(tc.annotations)
+
+			var created []string
+			cl := newFakeClientBuilder(tc.crdsInstalled).
+				WithObjects(istio, rev).
+				WithInterceptorFuncs(interceptor.Funcs{
+					Create: func(ctx context.Context, cl client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+						created = append(created, obj.GetObjectKind().GroupVersionKind().Kind+"/"+obj.GetName())
+						return cl.Create(ctx, obj, opts...)
+					},
+				}).
+				Build()
+			r := NewReconciler(config.ReconcilerConfig{Platform: config.PlatformKubernetes}, cl, cl.Scheme())
+
+			_, err := r.Reconcile(ctx, rev)
+			g.Expect(err).NotTo(HaveOccurred())
+
+			if !tc.expectCreated {
+				g.Expect(created).To(BeEmpty())
+				return
+			}
+
+			g.Expect(created).To(ConsistOf(
+				ServiceMonitorKind+"/"+ServiceMonitorName(revName),
+				PodMonitorKind+"/"+PodMonitorName(revName),
+			))
+
+			serviceMonitor := getMonitor(g, cl, ServiceMonitorGVK, ServiceMonitorName(revName), istioNs)
+			g.Expect(serviceMonitor.GetOwnerReferences()).To(HaveLen(1))
+			g.Expect(serviceMonitor.GetOwnerReferences()[0].Name).To(Equal(revName))
+			g.Expect(serviceMonitor.GetLabels()).To(HaveKeyWithValue(constants.IstioRevLabel, revName))
+			matchLabels, _, _ := unstructured.NestedStringMap(serviceMonitor.Object, "spec", "selector", "matchLabels")
+			g.Expect(matchLabels).To(HaveKeyWithValue(constants.IstioRevLabel, revName))
+
+			podMonitor := getMonitor(g, cl, PodMonitorGVK, PodMonitorName(revName), istioNs)
+			g.Expect(podMonitor.GetOwnerReferences()).To(HaveLen(1))
+			anyNamespace, _, _ := unstructured.NestedBool(podMonitor.Object, "spec", "namespaceSelector", "any")
+			g.Expect(anyNamespace).To(BeTrue())
+			g.Expect(relabelingTargets(g, podMonitor)).NotTo(ContainElement("mesh_id"))
+		})
+	}
+}
+
+func TestReconcileDoesNotOverwriteExistingMonitors(t *testing.T) {
+	g := NewWithT(t)
+	istio, rev := newIstioAndRevision(map[string]string{constants.MonitoringAnnotationKey: constants.MonitoringEnabledValue})
+
+	existing := newServiceMonitor(rev, config.PlatformKubernetes)
+	existing.Object["spec"] = map[string]any{"endpoints": []any{map[string]any{"port": "custom", "interval": "60s"}}}
+
+	cl := newFakeClientBuilder(true).
+		WithObjects(istio, rev, existing).
+		Build()
+	r := NewReconciler(config.ReconcilerConfig{Platform: config.PlatformKubernetes}, cl, cl.Scheme())
+
+	_, err := r.Reconcile(ctx, rev)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	serviceMonitor := getMonitor(g, cl, ServiceMonitorGVK, ServiceMonitorName(revName), istioNs)
+	endpoints, _, _ := unstructured.NestedSlice(serviceMonitor.Object, "spec", "endpoints")
+	g.Expect(endpoints).To(Equal([]any{map[string]any{"port": "custom", "interval": "60s"}}))
+
+	getMonitor(g, cl, PodMonitorGVK, PodMonitorName(revName), istioNs)
+}
+
+func TestReconcileOpenShift(t *testing.T) {
+	g := NewWithT(t)
+	istio, rev := newIstioAndRevision(map[string]string{constants.MonitoringAnnotationKey: constants.MonitoringEnabledValue})
+	rev.Spec.Values = &v1.Values{Global: &v1.GlobalConfig{MeshID: ptr.Of("my-mesh")}}
+
+	tag := &v1.IstioRevisionTag{
+		ObjectMeta: metav1.ObjectMeta{Name: "prod"},
+		Status:     v1.IstioRevisionTagStatus{IstioRevision: revName},
+	}
+
+	staleMonitor := newPodMonitor(rev, "unlabeled", config.PlatformOpenShift)
+	otherRevMonitor := newPodMonitor(&v1.IstioRevision{ObjectMeta: metav1.ObjectMeta{Name: otherRevName}}, "other-rev", config.PlatformOpenShift)
+	userMonitor := newPodMonitor(rev, "user", config.PlatformOpenShift)
+	userMonitor.SetLabels(nil)
+
+	cl := newFakeClientBuilder(true).
+		WithObjects(istio, rev, tag,
+			newNamespace(istioNs, nil),
+			newNamespace("direct", map[string]string{constants.IstioRevLabel: revName}),
+			newNamespace("via-tag", map[string]string{constants.IstioRevLabel: "prod"}),
+			newNamespace("other-rev", map[string]string{constants.IstioRevLabel: otherRevName}),
+			newNamespace("unlabeled", nil),
+			newNamespace("user", nil),
+			staleMonitor, otherRevMonitor, userMonitor).
+		WithStatusSubresource(&v1.IstioRevisionTag{}).
+		Build()
+	r := NewReconciler(config.ReconcilerConfig{Platform: config.PlatformOpenShift}, newCacheClient(t, cl), cl.Scheme())
+
+	_, err := r.Reconcile(ctx, rev)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	serviceMonitor := getMonitor(g, cl, ServiceMonitorGVK, ServiceMonitorName(revName), istioNs)
+	endpoints, _, _ := unstructured.NestedSlice(serviceMonitor.Object, "spec", "endpoints")
+	g.Expect(endpoints).To(HaveLen(1))
+	g.Expect(endpoints[0]).To(HaveKeyWithValue("relabelings", ContainElement(HaveKeyWithValue("replacement", "my-mesh"))))
+
+	for _, ns := range []string{"direct", "via-tag"} {
+		podMonitor := getMonitor(g, cl, PodMonitorGVK, PodMonitorName(revName), ns)
+		g.Expect(podMonitor.GetOwnerReferences()).To(BeEmpty())
+		_, found, _ := unstructured.NestedFieldNoCopy(podMonitor.Object, "spec", "namespaceSelector")
+		g.Expect(found).To(BeFalse())
+		g.Expect(relabelingTargets(g, podMonitor)).To(ContainElements("app", "version", "namespace", "mesh_id"))
+	}
+
+	expectMonitorNotFound(g, cl, PodMonitorGVK, PodMonitorName(revName), istioNs)
+	expectMonitorNotFound(g, cl, PodMonitorGVK, PodMonitorName(revName), "unlabeled")
+	expectMonitorNotFound(g, cl, PodMonitorGVK, PodMonitorName(otherRevName), "other-rev")
+	getMonitor(g, cl, PodMonitorGVK, PodMonitorName(revName), "user")
+}
+
+func TestReconcileDeletesMonitorsWhenMonitoringIsDisabled(t *testing.T) {
+	for _, platform := range []config.Platform{config.PlatformKubernetes, config.PlatformOpenShift} {
+		t.Run(string(platform), func(t *testing.T) {
+			g := NewWithT(t)
+			istio, rev := newIstioAndRevision(map[string]string{constants.MonitoringAnnotationKey: constants.MonitoringEnabledValue})
+
+			podMonitorNs := istioNs
+			if platform == config.PlatformOpenShift {
+				podMonitorNs = "direct"
+			}
+			otherRevMonitor := newPodMonitor(&v1.IstioRevision{ObjectMeta: metav1.ObjectMeta{Name: otherRevName}}, "other-rev", platform)
+			userMonitor := newPodMonitor(rev, "user", platform)
+			userMonitor.SetLabels(nil)
+
+			cl := newFakeClientBuilder(true).
+				WithObjects(istio, rev,
+					newNamespace(istioNs, nil),
+					newNamespace("direct", map[string]string{constants.IstioRevLabel: revName}),
+					newNamespace("other-rev", map[string]string{constants.IstioRevLabel: otherRevName}),
+					newNamespace("user", nil),
+					&v1.IstioRevision{ObjectMeta: metav1.ObjectMeta{Name: otherRevName}},
+					otherRevMonitor, userMonitor).
+				Build()
+			r := NewReconciler(config.ReconcilerConfig{Platform: platform}, newCacheClient(t, cl), cl.Scheme())
+
+			_, err := r.Reconcile(ctx, rev)
+			g.Expect(err).NotTo(HaveOccurred())
+			getMonitor(g, cl, ServiceMonitorGVK, ServiceMonitorName(revName), istioNs)
+			getMonitor(g, cl, PodMonitorGVK, PodMonitorName(revName), podMonitorNs)
+
+			istio.Annotations = nil
+			g.Expect(cl.Update(ctx, istio)).To(Succeed())
+
+			_, err = r.Reconcile(ctx, rev)
+			g.Expect(err).NotTo(HaveOccurred())
+			expectMonitorNotFound(g, cl, ServiceMonitorGVK, ServiceMonitorName(revName), istioNs)
+			expectMonitorNotFound(g, cl, PodMonitorGVK, PodMonitorName(revName), podMonitorNs)
+			getMonitor(g, cl, PodMonitorGVK, PodMonitorName(otherRevName), "other-rev")
+			getMonitor(g, cl, PodMonitorGVK, PodMonitorName(revName), "user")
+		})
+	}
+}
+
+func newIstioAndRevision(annotations map[string]string) (*v1.Istio, *v1.IstioRevision) {
+	istio := &v1.Istio{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        istioName,
+			UID:         "istio-uid",
+			Annotations: annotations,
+		},
+	}
+	rev := &v1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: revName,
+			UID:  "rev-uid",
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: v1.GroupVersion.String(),
+					Kind:       v1.IstioKind,
+					Name:       istioName,
+					UID:        istio.UID,
+					Controller: ptr.Of(true),
+				},
+			},
+		},
+		Spec: v1.IstioRevisionSpec{
+			Namespace: istioNs,
+		},
+	}
+	return istio, rev
+}
+
+func newNamespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: labels,
+		},
+	}
+}
+
+// newFakeClientBuilder returns a fake client builder whose RESTMapper and scheme contain the
+// ServiceMonitor and PodMonitor kinds if crdsInstalled is true
+func newFakeClientBuilder(crdsInstalled bool) *fake.ClientBuilder {
+	s := runtime.NewScheme()
+	for gvk := range scheme.Scheme.AllKnownTypes() {
+		obj, _ := scheme.Scheme.New(gvk)
+		s.AddKnownTypeWithName(gvk, obj)
+	}
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	if crdsInstalled {
+		for _, gvk := range []schema.GroupVersionKind{ServiceMonitorGVK, PodMonitorGVK} {
+			s.AddKnownTypeWithName(gvk, &unstructured.Unstructured{})
+			s.AddKnownTypeWithName(gvk.GroupVersion().WithKind(gvk.Kind+"List"), &unstructured.UnstructuredList{})
+			mapper.Add(gvk, meta.RESTScopeNamespace)
+		}
+	}
+	b := fake.NewClientBuilder().WithScheme(s).WithRESTMapper(mapper)
+	for _, index := range revision.UsageIndexes() {
+		b = b.WithIndex(index.Object, index.Field, index.Extract)
+	}
+	return b
+}
+
+// newCacheClient wraps the given client so that it fails the test when typed namespaces or pods are read
+// through it, since the manager's cache would start an informer that holds all of them.
+func newCacheClient(t *testing.T, cl client.WithWatch) client.Client {
+	rejectTyped := func(obj runtime.Object) error {
+		switch obj.(type) {
+		case *corev1.Pod, *corev1.PodList, *corev1.Namespace, *corev1.NamespaceList:
+			t.Errorf("%T read through the cache, which would start a typed informer", obj)
+			return fmt.Errorf("%T must not be read through the cache", obj)
+		}
+		return nil
+	}
+	return interceptor.NewClient(cl, interceptor.Funcs{
+		Get: func(ctx context.Context, cl client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			if err := rejectTyped(obj); err != nil {
+				return err
+			}
+			return cl.Get(ctx, key, obj, opts...)
+		},
+		List: func(ctx context.Context, cl client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			if err := rejectTyped(list); err != nil {
+				return err
+			}
+			return cl.List(ctx, list, opts...)
+		},
+	})
+}
+
+func getMonitor(g *WithT, cl client.Client, gvk schema.GroupVersionKind, name, namespace string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	g.Expect(cl.Get(ctx, kube.Key(name, namespace), obj)).To(Succeed(), "%s %s/%s not found", gvk.Kind, namespace, name)
+	return obj
+}
+
+func expectMonitorNotFound(g *WithT, cl client.Client, gvk schema.GroupVersionKind, name, namespace string) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	err := cl.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, obj)
+	g.Expect(apierrors.IsNotFound(err)).To(BeTrue(), "expected %s %s/%s to not exist", gvk.Kind, namespace, name)
+}
+
+func relabelingTargets(g *WithT, podMonitor *unstructured.Unstructured) []string {
+	endpoints, _, err := unstructured.NestedSlice(podMonitor.Object, "spec", "podMetricsEndpoints")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(endpoints).To(HaveLen(1))
+
+	var targets []string
+	for _, relabeling := range endpoints[0].(map[string]any)["relabelings"].([]any) {
+		if target, ok := relabeling.(map[string]any)["targetLabel"].(string); ok {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
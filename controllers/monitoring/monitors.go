@@ -0,0 +1,239 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package monitoring
+
+import (
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+const (
+	// MonitoringGroup is the API group of the Prometheus Operator resources
+	MonitoringGroup = "monitoring.coreos.com"
+
+	ServiceMonitorKind = "ServiceMonitor"
+	PodMonitorKind     = "PodMonitor"
+
+	// releaseLabelValue is the value of the release label the operator sets on the monitors it creates, so that
+	// they are selected by Prometheus instances that follow the Istio integration samples
+	releaseLabelKey   = "release"
+	releaseLabelValue = "istio"
+
+	istiodMetricsPort = "http-monitoring"
+	scrapeInterval    = "30s"
+	defaultMeshID     = "cluster.local"
+)
+
+var (
+	ServiceMonitorGVK = schema.GroupVersionKind{Group: MonitoringGroup, Version: "v1", Kind: ServiceMonitorKind}
+	PodMonitorGVK     = schema.GroupVersionKind{Group: MonitoringGroup, Version: "v1", Kind: PodMonitorKind}
+)
+
+// ServiceMonitorName returns the name of the ServiceMonitor that scrapes istiod of the given revision
+func ServiceMonitorName(revName string) string {
+	return revName + "-istiod-metrics"
+}
+
+// PodMonitorName returns the name of the PodMonitor that scrapes the proxies injected by the given revision
+func PodMonitorName(revName string) string {
+	return revName + "-proxies-metrics"
+}
+
+func newMonitor(gvk schema.GroupVersionKind, name, namespace, revName string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+	obj.SetLabels(map[string]string{
+		constants.ManagedByLabelKey: constants.ManagedByLabelValue,
+		constants.IstioRevLabel:     revName,
+		releaseLabelKey:             releaseLabelValue,
+	})
+	return obj
+}
+
+// newServiceMonitor returns the ServiceMonitor that scrapes the istiod instance of the given revision
+func newServiceMonitor(rev *v1.IstioRevision, platform config.Platform) *unstructured.Unstructured {
+	endpoint := map[string]any{
+		"port":     istiodMetricsPort,
+		"interval": scrapeInterval,
+	}
+	if platform == config.PlatformOpenShift {
+		endpoint["relabelings"] = []any{meshIDRelabeling(rev)}
+	}
+
+	obj := newMonitor(ServiceMonitorGVK, ServiceMonitorName(rev.Name), rev.Spec.Namespace, rev.Name)
+	obj.Object["spec"] = map[string]any{
+		"targetLabels": []any{"app"},
+		"selector": map[string]any{
+			"matchLabels": map[string]any{
+				"app":                   "istiod",
+				constants.IstioRevLabel: rev.Name,
+			},
+		},
+		"endpoints": []any{endpoint},
+	}
+	return obj
+}
+
+// newPodMonitor returns the PodMonitor that scrapes the proxies injected by the given revision. On Kubernetes, a
+// single PodMonitor in the control plane namespace selects pods in all namespaces. On OpenShift, the namespaceSelector
+// is ignored by the platform's monitoring stack, so a PodMonitor must be created in each application namespace.
+func newPodMonitor(rev *v1.IstioRevision, namespace string, platform config.Platform) *unstructured.Unstructured {
+	endpoint := map[string]any{
+		"path":        "/stats/prometheus",
+		"interval":    scrapeInterval,
+		"relabelings": proxyRelabelings(rev, platform),
+	}
+
+	spec := map[string]any{
+		"selector": map[string]any{
+			"matchExpressions": []any{
+				map[string]any{
+					"key":      "istio-prometheus-ignore",
+					"operator": "DoesNotExist",
+				},
+			},
+		},
+		"podMetricsEndpoints": []any{endpoint},
+	}
+	if platform != config.PlatformOpenShift {
+		spec["jobLabel"] = "envoy-stats"
+		spec["namespaceSelector"] = map[string]any{"any": true}
+	}
+
+	obj := newMonitor(PodMonitorGVK, PodMonitorName(rev.Name), namespace, rev.Name)
+	obj.Object["spec"] = spec
+	return obj
+}
+
+func proxyRelabelings(rev *v1.IstioRevision, platform config.Platform) []any {
+	relabelings := []any{
+		map[string]any{
+			"action":       "keep",
+			"sourceLabels": []any{"__meta_kubernetes_pod_container_name"},
+			"regex":        "istio-proxy",
+		},
+		map[string]any{
+			// only scrape the proxies injected by this revision
+			"action":       "keep",
+			"sourceLabels": []any{"__meta_kubernetes_pod_annotation_istio_io_rev"},
+			"regex":        rev.Name,
+		},
+		map[string]any{
+			"action":       "keep",
+			"sourceLabels": []any{"__meta_kubernetes_pod_annotationpresent_prometheus_io_scrape"},
+		},
+		map[string]any{
+			"action":       "replace",
+			"regex":        `(\d+);(([A-Fa-f0-9]{1,4}::?){1,7}[A-Fa-f0-9]{1,4})`,
+			"replacement":  "[$2]:$1",
+			"sourceLabels": []any{"__meta_kubernetes_pod_annotation_prometheus_io_port", "__meta_kubernetes_pod_ip"},
+			"targetLabel":  "__address__",
+		},
+		map[string]any{
+			"action":       "replace",
+			"regex":        `(\d+);((([0-9]+?)(\.|$)){4})`,
+			"replacement":  "$2:$1",
+			"sourceLabels": []any{"__meta_kubernetes_pod_annotation_prometheus_io_port", "__meta_kubernetes_pod_ip"},
+			"targetLabel":  "__address__",
+		},
+	}
+
+	if platform == config.PlatformOpenShift {
+		// the OpenShift console and Kiali expect the app, version, namespace and mesh_id labels
+		return append(relabelings,
+			map[string]any{
+				"action":       "replace",
+				"sourceLabels": []any{"__meta_kubernetes_pod_label_app_kubernetes_io_name", "__meta_kubernetes_pod_label_app"},
+				"separator":    ";",
+				"regex":        "(.+);.*|.*;(.+)",
+				"replacement":  "${1}${2}",
+				"targetLabel":  "app",
+			},
+			map[string]any{
+				"action":       "replace",
+				"sourceLabels": []any{"__meta_kubernetes_pod_label_app_kubernetes_io_version", "__meta_kubernetes_pod_label_version"},
+				"separator":    ";",
+				"regex":        "(.+);.*|.*;(.+)",
+				"replacement":  "${1}${2}",
+				"targetLabel":  "version",
+			},
+			map[string]any{
+				"action":       "replace",
+				"sourceLabels": []any{"__meta_kubernetes_namespace"},
+				"targetLabel":  "namespace",
+			},
+			meshIDRelabeling(rev),
+		)
+	}
+
+	return append(relabelings,
+		map[string]any{
+			"action": "labeldrop",
+			"regex":  "__meta_kubernetes_pod_label_(.+)",
+		},
+		map[string]any{
+			"action":       "replace",
+			"sourceLabels": []any{"__meta_kubernetes_namespace"},
+			"targetLabel":  "namespace",
+		},
+		map[string]any{
+			"action":       "replace",
+			"sourceLabels": []any{"__meta_kubernetes_pod_name"},
+			"targetLabel":  "pod_name",
+		},
+	)
+}
+
+func meshIDRelabeling(rev *v1.IstioRevision) map[string]any {
+	return map[string]any{
+		"action":      "replace",
+		"replacement": getMeshID(rev),
+		"targetLabel": "mesh_id",
+	}
+}
+
+// getMeshID returns the mesh ID configured in the revision. Like istiod, it falls back to the trust domain.
+func getMeshID(rev *v1.IstioRevision) string {
+	if values := rev.Spec.Values; values != nil {
+		if values.Global != nil && values.Global.MeshID != nil && *values.Global.MeshID != "" {
+			return *values.Global.MeshID
+		}
+		if values.MeshConfig != nil && values.MeshConfig.TrustDomain != nil && *values.MeshConfig.TrustDomain != "" {
+			return *values.MeshConfig.TrustDomain
+		}
+	}
+	return defaultMeshID
+}
+
+func setOwnerReference(obj *unstructured.Unstructured, rev *v1.IstioRevision) {
+	obj.SetOwnerReferences([]metav1.OwnerReference{
+		{
+			APIVersion:         v1.GroupVersion.String(),
+			Kind:               v1.IstioRevisionKind,
+			Name:               rev.Name,
+			UID:                rev.UID,
+			Controller:         ptr.Of(true),
+			BlockOwnerDeletion: ptr.Of(true),
+		},
+	})
+}
@@ -0,0 +1,194 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package versioncatalog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/enqueuelogger"
+	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// catalogName is the name of the IstioVersionCatalog, which is a singleton
+const catalogName = "default"
+
+// Reconciler reconciles the IstioVersionCatalog. It applies the catalog to the versions known to the operator and
+// notifies the Istio, IstioCNI and ZTunnel reconcilers, so that they resolve their versions again.
+type Reconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Config config.ReconcilerConfig
+}
+
+func NewReconciler(reconcilerCfg config.ReconcilerConfig, client client.Client, scheme *runtime.Scheme) *Reconciler {
+	return &Reconciler{
+		Client: client,
+		Scheme: scheme,
+		Config: reconcilerCfg,
+	}
+}
+
+// +kubebuilder:rbac:groups=sailoperator.io,resources=istioversioncatalogs,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=sailoperator.io,resources=istioversioncatalogs/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=sailoperator.io,resources=istioversioncatalogs/finalizers,verbs=update
+
+// Reconcile applies the IstioVersionCatalog and reports whether all of its aliases and its default version can be
+// resolved.
+func (r *Reconciler) Reconcile(ctx context.Context, catalog *v1alpha1.IstioVersionCatalog) (ctrl.Result, error) {
+	log := logf.FromContext(ctx)
+
+	r.apply(catalog)
+
+	log.Info("Reconciliation done. Updating status.")
+	status := r.determineStatus(catalog)
+	return ctrl.Result{}, reconciler.UpdateStatus(ctx, r.Client, catalog, catalog.Status, status, nil)
+}
+
+// Finalize removes the entries and the default version of the IstioVersionCatalog from the versions known to the
+// operator.
+func (r *Reconciler) Finalize(_ context.Context, _ *v1alpha1.IstioVersionCatalog) error {
+	r.apply(nil)
+	return nil
+}
+
+func (r *Reconciler) apply(catalog *v1alpha1.IstioVersionCatalog) {
+	// the default version is only used for new resources, so changing it doesn't affect any existing resource
+	istioversion.SetDefaultVersion(getDefaultVersion(catalog))
+	if istioversion.SetCatalog(getEntries(catalog)) {
+		r.Config.VersionCatalog.Notify(&v1alpha1.IstioVersionCatalog{ObjectMeta: metav1.ObjectMeta{Name: catalogName}})
+	}
+}
+
+func getEntries(catalog *v1alpha1.IstioVersionCatalog) []istioversion.CatalogEntry {
+	if catalog == nil {
+		return nil
+	}
+	entries := make([]istioversion.CatalogEntry, 0, len(catalog.Spec.Versions))
+	for _, v := range catalog.Spec.Versions {
+		entries = append(entries, istioversion.CatalogEntry{Name: v.Name, Ref: v.Ref, EOL: v.EOL})
+	}
+	return entries
+}
+
+func getDefaultVersion(catalog *v1alpha1.IstioVersionCatalog) string {
+	if catalog == nil {
+		return ""
+	}
+	return catalog.Spec.DefaultVersion
+}
+
+func (r *Reconciler) determineStatus(catalog *v1alpha1.IstioVersionCatalog) v1alpha1.IstioVersionCatalogStatus {
+	status := *catalog.Status.DeepCopy()
+	status.ObservedGeneration = catalog.Generation
+
+	var unresolved []string
+	for _, v := range catalog.Spec.Versions {
+		if v.Ref == "" {
+			continue
+		}
+		if _, err := istioversion.Resolve(v.Name); err != nil {
+			unresolved = append(unresolved, fmt.Sprintf("%s (refers to %s)", v.Name, v.Ref))
+		}
+	}
+
+	c := v1.StatusCondition{Type: v1.ConditionType(v1alpha1.IstioVersionCatalogConditionReady)}
+	defaultVersion := catalog.Spec.DefaultVersion
+	switch {
+	case len(unresolved) == 0 && defaultVersion != "" && istioversion.ValidateVersion(defaultVersion) != nil:
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1.ConditionReason(v1alpha1.IstioVersionCatalogReasonInvalidDefaultVersion)
+		c.Message = fmt.Sprintf("the default version %s is unknown or end-of-life; the operator's default version %s is used instead",
+			defaultVersion, istioversion.DefaultVersion())
+	case len(unresolved) == 0:
+		c.Status = metav1.ConditionTrue
+		c.Reason = v1.ConditionReason(v1alpha1.IstioVersionCatalogConditionReady)
+	default:
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1.ConditionReason(v1alpha1.IstioVersionCatalogReasonUnresolvedAlias)
+		c.Message = "the following aliases refer to versions that are unknown or end-of-life: " + strings.Join(unresolved, ", ")
+	}
+	status.SetCondition(c)
+	return status
+}
+
+// Load applies the IstioVersionCatalog before the controllers are started, so that the other reconcilers never
+// resolve a version without taking the catalog into account. It does nothing if the IstioVersionCatalog doesn't
+// exist or its CRD isn't installed.
+func Load(ctx context.Context, cl client.Reader) error {
+	catalog := &v1alpha1.IstioVersionCatalog{}
+	if err := cl.Get(ctx, types.NamespacedName{Name: catalogName}, catalog); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get IstioVersionCatalog: %w", err)
+	}
+	if catalog.DeletionTimestamp == nil {
+		istioversion.SetDefaultVersion(getDefaultVersion(catalog))
+		istioversion.SetCatalog(getEntries(catalog))
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	logger := mgr.GetLogger().WithName("ctrlr").WithName("istioversioncatalog")
+
+	// mainObjectHandler handles the IstioVersionCatalog watch events
+	mainObjectHandler := enqueuelogger.WrapIfNecessary(v1alpha1.IstioVersionCatalogKind, logger, &handler.EnqueueRequestForObject{})
+
+	// chartSourceHandler handles changes to the versions provided by IstioChartSources, since the aliases in the
+	// catalog may refer to them
+	chartSourceHandler := enqueuelogger.WrapIfNecessary(v1alpha1.IstioVersionCatalogKind, logger,
+		handler.EnqueueRequestsFromMapFunc(func(context.Context, client.Object) []reconcile.Request {
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: catalogName}}}
+		}))
+
+	b := ctrl.NewControllerManagedBy(mgr).
+		WithOptions(controller.Options{
+			LogConstructor: func(req *reconcile.Request) logr.Logger {
+				log := logger
+				if req != nil {
+					log = log.WithValues(v1alpha1.IstioVersionCatalogKind, req.Name)
+				}
+				return log
+			},
+			MaxConcurrentReconciles: r.Config.MaxConcurrentReconciles,
+		}).
+		// we use the Watches function instead of For(), so that we can wrap the handler so that events that cause the object to be enqueued are logged
+		Watches(&v1alpha1.IstioVersionCatalog{}, mainObjectHandler).
+		Named("istioversioncatalog")
+
+	return r.Config.ChartSources.Watch(b, chartSourceHandler).
+		Complete(reconciler.NewStandardReconcilerWithFinalizer[*v1alpha1.IstioVersionCatalog](r.Client, r.Reconcile, r.Finalize, constants.FinalizerName))
+}
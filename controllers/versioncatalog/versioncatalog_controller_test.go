@@ -0,0 +1,161 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package versioncatalog
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var ctx = context.Background()
+
+func TestReconcile(t *testing.T) {
+	previous := istioversion.List[1].Name
+
+	tests := []struct {
+		name            string
+		versions        []v1alpha1.CatalogVersion
+		defaultVersion  string
+		expectedStatus  metav1.ConditionStatus
+		expectedReason  v1alpha1.IstioVersionCatalogConditionReason
+		expectedDefault string
+	}{
+		{
+			name: "ready",
+			versions: []v1alpha1.CatalogVersion{
+				{Name: "v1.99-latest", Ref: previous},
+				{Name: istioversion.List[0].Name, EOL: true},
+			},
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: v1alpha1.IstioVersionCatalogConditionReason(v1alpha1.IstioVersionCatalogConditionReady),
+		},
+		{
+			name:            "default version",
+			versions:        []v1alpha1.CatalogVersion{{Name: "v1.99-latest", Ref: previous}},
+			defaultVersion:  "v1.99-latest",
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  v1alpha1.IstioVersionCatalogConditionReason(v1alpha1.IstioVersionCatalogConditionReady),
+			expectedDefault: "v1.99-latest",
+		},
+		{
+			name:           "end-of-life default version",
+			versions:       []v1alpha1.CatalogVersion{{Name: previous, EOL: true}},
+			defaultVersion: previous,
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1alpha1.IstioVersionCatalogReasonInvalidDefaultVersion,
+		},
+		{
+			name:           "alias of unknown version",
+			versions:       []v1alpha1.CatalogVersion{{Name: "v1.99-latest", Ref: "v1.99.0"}},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1alpha1.IstioVersionCatalogReasonUnresolvedAlias,
+		},
+		{
+			name: "alias of end-of-life version",
+			versions: []v1alpha1.CatalogVersion{
+				{Name: "v1.99-latest", Ref: previous},
+				{Name: previous, EOL: true},
+			},
+			expectedStatus: metav1.ConditionFalse,
+			expectedReason: v1alpha1.IstioVersionCatalogReasonUnresolvedAlias,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			t.Cleanup(func() {
+				istioversion.SetDefaultVersion("")
+				istioversion.SetCatalog(nil)
+			})
+
+			catalog := &v1alpha1.IstioVersionCatalog{
+				ObjectMeta: metav1.ObjectMeta{Name: catalogName, Generation: 2},
+				Spec:       v1alpha1.IstioVersionCatalogSpec{Versions: tc.versions, DefaultVersion: tc.defaultVersion},
+			}
+			cl := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(catalog).
+				WithStatusSubresource(&v1alpha1.IstioVersionCatalog{}).
+				Build()
+			notifier := &reconciler.Notifier{}
+			events := notifier.Subscribe()
+			r := NewReconciler(config.ReconcilerConfig{VersionCatalog: notifier}, cl, scheme.Scheme)
+
+			_, err := r.Reconcile(ctx, catalog)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(events).To(HaveLen(1), "reconcilers should be notified of the catalog change")
+
+			g.Expect(cl.Get(ctx, types.NamespacedName{Name: catalogName}, catalog)).To(Succeed())
+			g.Expect(catalog.Status.ObservedGeneration).To(Equal(int64(2)))
+			ready := catalog.Status.GetCondition(v1alpha1.IstioVersionCatalogConditionReady)
+			g.Expect(ready.Status).To(Equal(tc.expectedStatus))
+			g.Expect(ready.Reason).To(Equal(v1.ConditionReason(tc.expectedReason)))
+			expectedDefault := tc.expectedDefault
+			if expectedDefault == "" {
+				expectedDefault = istioversion.Default
+			}
+			g.Expect(istioversion.DefaultVersion()).To(Equal(expectedDefault))
+
+			<-events
+			_, err = r.Reconcile(ctx, catalog)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(events).To(BeEmpty(), "reconcilers should not be notified if the catalog didn't change")
+		})
+	}
+}
+
+func TestFinalize(t *testing.T) {
+	g := NewWithT(t)
+	t.Cleanup(func() {
+		istioversion.SetDefaultVersion("")
+		istioversion.SetCatalog(nil)
+	})
+
+	version := istioversion.List[0].Name
+	istioversion.SetCatalog([]istioversion.CatalogEntry{{Name: version, EOL: true}})
+	istioversion.SetDefaultVersion(istioversion.List[1].Name)
+	r := NewReconciler(config.ReconcilerConfig{}, nil, scheme.Scheme)
+
+	g.Expect(r.Finalize(ctx, &v1alpha1.IstioVersionCatalog{})).To(Succeed())
+	g.Expect(istioversion.IsEOLVersion(version)).To(BeFalse())
+	g.Expect(istioversion.DefaultVersion()).To(Equal(istioversion.Default))
+}
+
+func TestLoad(t *testing.T) {
+	g := NewWithT(t)
+	t.Cleanup(func() { istioversion.SetCatalog(nil) })
+
+	version := istioversion.List[0].Name
+	g.Expect(Load(ctx, fake.NewClientBuilder().WithScheme(scheme.Scheme).Build())).To(Succeed())
+	g.Expect(istioversion.IsEOLVersion(version)).To(BeFalse())
+
+	catalog := &v1alpha1.IstioVersionCatalog{
+		ObjectMeta: metav1.ObjectMeta{Name: catalogName},
+		Spec:       v1alpha1.IstioVersionCatalogSpec{Versions: []v1alpha1.CatalogVersion{{Name: version, EOL: true}}},
+	}
+	g.Expect(Load(ctx, fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(catalog).Build())).To(Succeed())
+	g.Expect(istioversion.IsEOLVersion(version)).To(BeTrue())
+}
@@ -197,29 +197,29 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 		// we use the Watches function instead of For(), so that we can wrap the handler so that events that cause the object to be enqueued are logged
 		// +lint-watches:ignore: IstioRevision (not found in charts, but this is the main resource watched by this controller)
-		Watches(&admissionv1.MutatingWebhookConfiguration{}, objectHandler, builder.WithPredicates(ownedByRemoteIstioRevisionPredicate(mgr.GetClient()))).
+		Watches(&admissionv1.MutatingWebhookConfiguration{}, objectHandler, builder.WithPredicates(ownedByRemoteIstioRevisionPredicate(mgr.GetClient(), r.Config))).
 		Named("mutatingwebhookconfiguration").
 		Complete(reconciler.NewStandardReconciler[*admissionv1.MutatingWebhookConfiguration](r.Client, r.Reconcile))
 }
 
-func ownedByRemoteIstioRevisionPredicate(cl client.Client) predicate.Predicate {
+func ownedByRemoteIstioRevisionPredicate(cl client.Client, cfg config.ReconcilerConfig) predicate.Predicate {
 	return predicate.Funcs{
 		CreateFunc: func(e event.CreateEvent) bool {
-			return IsOwnedByRevisionWithRemoteControlPlane(cl, e.Object)
+			return IsOwnedByRevisionWithRemoteControlPlane(cl, cfg, e.Object)
 		},
 		UpdateFunc: func(e event.UpdateEvent) bool {
-			return IsOwnedByRevisionWithRemoteControlPlane(cl, e.ObjectNew)
+			return IsOwnedByRevisionWithRemoteControlPlane(cl, cfg, e.ObjectNew)
 		},
 		DeleteFunc: func(e event.DeleteEvent) bool {
-			return IsOwnedByRevisionWithRemoteControlPlane(cl, e.Object)
+			return IsOwnedByRevisionWithRemoteControlPlane(cl, cfg, e.Object)
 		},
 		GenericFunc: func(e event.GenericEvent) bool {
-			return IsOwnedByRevisionWithRemoteControlPlane(cl, e.Object)
+			return IsOwnedByRevisionWithRemoteControlPlane(cl, cfg, e.Object)
 		},
 	}
 }
 
-func IsOwnedByRevisionWithRemoteControlPlane(cl client.Client, obj client.Object) bool {
+func IsOwnedByRevisionWithRemoteControlPlane(cl client.Client, cfg config.ReconcilerConfig, obj client.Object) bool {
 	for _, ownerRef := range obj.GetOwnerReferences() {
 		if ownerRef.APIVersion == v1.GroupVersion.String() && ownerRef.Kind == v1.IstioRevisionKind {
 			rev := &v1.IstioRevision{}
@@ -227,7 +227,7 @@ func IsOwnedByRevisionWithRemoteControlPlane(cl client.Client, obj client.Object
 			if err != nil {
 				return false
 			}
-			if revision.IsUsingRemoteControlPlane(rev) {
+			if revision.IsUsingRemoteControlPlane(rev, cfg) {
 				return true
 			}
 		}
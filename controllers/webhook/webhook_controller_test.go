@@ -474,6 +474,31 @@ func TestIsOwnedByRevisionWithRemoteControlPlane(t *testing.T) {
 			},
 			expected: true,
 		},
+		{
+			name: "IstioRevision enables istiodRemote",
+			ownerRefs: []metav1.OwnerReference{
+				{
+					APIVersion: v1.GroupVersion.String(),
+					Kind:       v1.IstioRevisionKind,
+					Name:       "revision1",
+				},
+			},
+			objects: []client.Object{
+				&v1.IstioRevision{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "revision1",
+					},
+					Spec: v1.IstioRevisionSpec{
+						Values: &v1.Values{
+							IstiodRemote: &v1.IstiodRemoteConfig{
+								Enabled: ptr.Of(true),
+							},
+						},
+					},
+				},
+			},
+			expected: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -491,7 +516,7 @@ func TestIsOwnedByRevisionWithRemoteControlPlane(t *testing.T) {
 				},
 			}
 
-			result := IsOwnedByRevisionWithRemoteControlPlane(cl, obj)
+			result := IsOwnedByRevisionWithRemoteControlPlane(cl, newReconcilerTestConfig(t), obj)
 			g.Expect(result).To(Equal(tt.expected))
 		})
 	}
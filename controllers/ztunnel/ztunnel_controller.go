@@ -21,21 +21,23 @@ import (
 
 	"github.com/go-logr/logr"
 	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
-	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
 	"github.com/istio-ecosystem/sail-operator/pkg/config"
 	"github.com/istio-ecosystem/sail-operator/pkg/constants"
 	"github.com/istio-ecosystem/sail-operator/pkg/enqueuelogger"
 	"github.com/istio-ecosystem/sail-operator/pkg/errlist"
 	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
 	sharedreconcile "github.com/istio-ecosystem/sail-operator/pkg/reconcile"
 	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
 	"github.com/istio-ecosystem/sail-operator/pkg/revision"
+	"github.com/istio-ecosystem/sail-operator/pkg/validation"
 	"github.com/istio-ecosystem/sail-operator/pkg/watches"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -80,43 +82,109 @@ func NewReconciler(cfg config.ReconcilerConfig, client client.Client, scheme *ru
 func (r *Reconciler) Reconcile(ctx context.Context, ztunnel *v1.ZTunnel) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
 
-	rev, reconcileErr := r.doReconcile(ctx, ztunnel)
+	rev, drift, reconcileErr := r.doReconcile(ctx, ztunnel)
 
 	log.Info("Reconciliation done. Updating status.")
-	statusErr := r.updateStatus(ctx, ztunnel, rev, reconcileErr)
+	statusErr := r.updateStatus(ctx, ztunnel, rev, drift, reconcileErr)
 
 	return ctrl.Result{}, errors.Join(reconcileErr, statusErr)
 }
 
 func (r *Reconciler) Finalize(ctx context.Context, ztunnel *v1.ZTunnel) error {
-	ztunnelReconciler := r.newZTunnelReconciler()
+	if err := r.validateNamespaceNotShared(ctx, ztunnel); reconciler.IsValidationError(err) {
+		// the Helm release in the namespace belongs to another ZTunnel
+		return nil
+	} else if err != nil {
+		return err
+	}
+	ztunnelReconciler := r.newZTunnelReconciler(ztunnel)
 	return ztunnelReconciler.Uninstall(ctx, ztunnel.Spec.Namespace)
 }
 
-func (r *Reconciler) doReconcile(ctx context.Context, ztunnel *v1.ZTunnel) (rev *v1.IstioRevision, err error) {
+func (r *Reconciler) doReconcile(
+	ctx context.Context, ztunnel *v1.ZTunnel,
+) (rev *v1.IstioRevision, drift *sharedreconcile.DriftReport, err error) {
 	log := logf.FromContext(ctx)
-	ztunnelReconciler := r.newZTunnelReconciler()
+	ztunnelReconciler := r.newZTunnelReconciler(ztunnel)
 
 	if err := ztunnelReconciler.Validate(ctx, ztunnel.Spec.Version, ztunnel.Spec.Namespace); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if err := r.validateNamespaceNotShared(ctx, ztunnel); err != nil {
+		return nil, nil, err
 	}
 
 	if ztunnel.Spec.TargetRef != nil {
 		log.Info("Retrieving referenced IstioRevision")
 		rev, err = revision.GetIstioRevisionFromTargetReference(ctx, r.Client, *ztunnel.Spec.TargetRef)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+
+		// the spec of an IstioRevision in dry-run mode hasn't been installed yet, so ztunnel keeps the values
+		// that were copied from the previous spec
+		if revision.IsDryRun(rev) {
+			log.Info("Referenced IstioRevision is in dry-run mode; keeping the installed ztunnel Helm chart")
+			return rev, nil, nil
+		}
+	}
+
+	if rollbackRevision, err := sharedreconcile.RollbackRevision(ztunnel); err != nil {
+		return rev, nil, err
+	} else if rollbackRevision > 0 {
+		log.Info("Rollback requested. Reinstalling a previous revision of the ztunnel Helm release", "revision", rollbackRevision)
+		_, err := ztunnelReconciler.Rollback(ctx, ztunnel.Spec.Namespace, rollbackRevision)
+		return rev, nil, err
+	}
+
+	drift = ztunnelReconciler.DetectDrift(ctx, ztunnel.Spec.Namespace, ztunnel.Spec.DriftPolicy)
+	ztunnelReconciler.KeepingFields(drift)
+	if !drift.UpgradeAllowed() {
+		upToDate, err := r.isHelmChartUpToDate(ctx, ztunnel, ztunnelReconciler, rev)
+		if err != nil {
+			return rev, drift, err
+		}
+		if upToDate {
+			log.Info("ztunnel Helm chart is up to date; keeping drifted fields as configured in spec.driftPolicy")
+			return rev, drift, nil
 		}
 	}
 
 	log.Info("Installing ztunnel Helm chart")
-	return rev, r.installHelmChart(ctx, ztunnel, ztunnelReconciler, rev)
+	if err := r.installHelmChart(ctx, ztunnel, ztunnelReconciler, rev); err != nil {
+		return rev, drift, err
+	}
+	drift.ChartsUpgraded()
+	return rev, drift, nil
 }
 
 func (r *Reconciler) installHelmChart(ctx context.Context, ztunnel *v1.ZTunnel,
 	ztunnelReconciler *sharedreconcile.ZTunnelReconciler, rev *v1.IstioRevision,
 ) error {
-	ownerReference := metav1.OwnerReference{
+	ownerReference := r.ownerReference(ztunnel)
+	return ztunnelReconciler.Install(
+		ctx, ztunnel.Spec.Version, ztunnel.Spec.Namespace, ztunnel.Spec.Values, patches(ztunnel), &ownerReference, revisionValues(rev)...)
+}
+
+func (r *Reconciler) isHelmChartUpToDate(ctx context.Context, ztunnel *v1.ZTunnel,
+	ztunnelReconciler *sharedreconcile.ZTunnelReconciler, rev *v1.IstioRevision,
+) (bool, error) {
+	ownerReference := r.ownerReference(ztunnel)
+	diff, err := ztunnelReconciler.Plan(
+		ctx, ztunnel.Spec.Version, ztunnel.Spec.Namespace, ztunnel.Spec.Values, patches(ztunnel), &ownerReference, revisionValues(rev)...)
+	if err != nil {
+		return false, err
+	}
+	return diff.IsEmpty(), nil
+}
+
+// patches returns the patches from the spec and the patch that applies spec.nodeSelector
+func patches(ztunnel *v1.ZTunnel) []v1.Patch {
+	return sharedreconcile.WithNodeSelector(ztunnel.Spec.Patches, ztunnel.Spec.NodeSelector)
+}
+
+func (r *Reconciler) ownerReference(ztunnel *v1.ZTunnel) metav1.OwnerReference {
+	return metav1.OwnerReference{
 		APIVersion:         v1.GroupVersion.String(),
 		Kind:               v1.ZTunnelKind,
 		Name:               ztunnel.Name,
@@ -124,21 +192,37 @@ func (r *Reconciler) installHelmChart(ctx context.Context, ztunnel *v1.ZTunnel,
 		Controller:         ptr.Of(true),
 		BlockOwnerDeletion: ptr.Of(true),
 	}
+}
 
-	if rev != nil && rev.Spec.Values != nil {
-		revisionValues := helm.FromValues(v1.Values{
-			MeshConfig: rev.Spec.Values.MeshConfig,
-			Revision:   rev.Spec.Values.Revision,
-			Global:     rev.Spec.Values.Global,
-		})
-		return ztunnelReconciler.Install(
-			ctx, ztunnel.Spec.Version, ztunnel.Spec.Namespace, ztunnel.Spec.Values, &ownerReference, revisionValues)
+// revisionValues returns the values that ZTunnel copies from the referenced IstioRevision, if any.
+func revisionValues(rev *v1.IstioRevision) []helm.Values {
+	if rev == nil || rev.Spec.Values == nil {
+		return nil
 	}
+	return []helm.Values{helm.FromValues(v1.Values{
+		MeshConfig: rev.Spec.Values.MeshConfig,
+		Revision:   rev.Spec.Values.Revision,
+		Global:     rev.Spec.Values.Global,
+	})}
+}
 
-	return ztunnelReconciler.Install(ctx, ztunnel.Spec.Version, ztunnel.Spec.Namespace, ztunnel.Spec.Values, &ownerReference)
+// validateNamespaceNotShared checks that no other ZTunnel that takes precedence uses the same namespace, because
+// both would manage the same Helm release
+func (r *Reconciler) validateNamespaceNotShared(ctx context.Context, ztunnel *v1.ZTunnel) error {
+	ztunnelList := v1.ZTunnelList{}
+	if err := r.Client.List(ctx, &ztunnelList); err != nil {
+		return fmt.Errorf("list failed: %w", err)
+	}
+	for _, other := range ztunnelList.Items {
+		if other.Name != ztunnel.Name && other.Spec.Namespace == ztunnel.Spec.Namespace &&
+			validation.ResourceTakesPrecedence(&other.ObjectMeta, &ztunnel.ObjectMeta) {
+			return reconciler.NewValidationError(fmt.Sprintf("namespace %q is already used by ZTunnel %q", ztunnel.Spec.Namespace, other.Name))
+		}
+	}
+	return nil
 }
 
-func (r *Reconciler) newZTunnelReconciler() *sharedreconcile.ZTunnelReconciler {
+func (r *Reconciler) newZTunnelReconciler(ztunnel *v1.ZTunnel) *sharedreconcile.ZTunnelReconciler {
 	return sharedreconcile.NewZTunnelReconciler(sharedreconcile.Config{
 		ResourceFS:        r.Config.ResourceFS,
 		Platform:          r.Config.Platform,
@@ -146,15 +230,16 @@ func (r *Reconciler) newZTunnelReconciler() *sharedreconcile.ZTunnelReconciler {
 		OperatorNamespace: r.Config.OperatorNamespace,
 		ChartManager:      r.ChartManager,
 		TLSConfig:         r.Config.TLSConfig,
-	}, r.Client)
+	}, r.Client).ForInstance(ztunnel.Name)
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 	logger := mgr.GetLogger().WithName("ctrlr").WithName("ztunnel")
 
-	// mainObjectHandler handles the ZTunnel watch events
-	mainObjectHandler := wrapEventHandler(logger, &handler.EnqueueRequestForObject{})
+	// ztunnelHandler handles the ZTunnel watch events; it also enqueues the other ZTunnels in the same namespace.
+	// ZTunnels created through the v1alpha1 API are converted to v1, so they are seen by this watch as well.
+	ztunnelHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapZTunnelToReconcileRequests))
 
 	// operatorResourcesHandler handles watch events from operator CRDs Istio and IstioRevision
 	operatorResourcesHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapOperatorResourceToReconcileRequest))
@@ -165,6 +250,9 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 
 	namespaceHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapNamespaceToReconcileRequest))
 
+	// versionHandler handles changes to the versions provided by IstioChartSources and the IstioVersionCatalog
+	versionHandler := wrapEventHandler(logger, handler.EnqueueRequestsFromMapFunc(r.mapVersionChangeToReconcileRequests))
+
 	b := ctrl.NewControllerManagedBy(mgr).
 		WithOptions(controller.Options{
 			LogConstructor: func(req *reconcile.Request) logr.Logger {
@@ -177,30 +265,55 @@ func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
 			MaxConcurrentReconciles: r.Config.MaxConcurrentReconciles,
 		}).
 		// we use the Watches function instead of For(), so that we can wrap the handler so that events that cause the object to be enqueued are logged
-		Watches(&v1alpha1.ZTunnel{}, mainObjectHandler).
-		Watches(&v1.ZTunnel{}, mainObjectHandler).
+		Watches(&v1.ZTunnel{}, ztunnelHandler).
 		Named("ztunnel")
 
 	watches.RegisterOwnedWatches(b, watches.ZTunnelWatches, ownedResourceHandler, nil)
+	b = r.Config.ChartSources.Watch(b, versionHandler)
+	b = r.Config.VersionCatalog.Watch(b, versionHandler)
 
 	return b.
 		// +lint-watches:ignore: Namespace (not present in charts, but must be watched to reconcile ZTunnel when its namespace is created)
-		Watches(&corev1.Namespace{}, namespaceHandler).
+		Watches(&corev1.Namespace{}, namespaceHandler, builder.OnlyMetadata).
 		Watches(&v1.Istio{}, operatorResourcesHandler).
 		Watches(&v1.IstioRevision{}, operatorResourcesHandler).
 		Complete(reconciler.NewStandardReconcilerWithFinalizer[*v1.ZTunnel](r.Client, r.Reconcile, r.Finalize, constants.FinalizerName))
 }
 
-func (r *Reconciler) determineStatus(ctx context.Context, ztunnel *v1.ZTunnel, rev *v1.IstioRevision, reconcileErr error) (v1.ZTunnelStatus, error) {
+func (r *Reconciler) determineStatus(
+	ctx context.Context, ztunnel *v1.ZTunnel, rev *v1.IstioRevision, drift *sharedreconcile.DriftReport, reconcileErr error,
+) (v1.ZTunnelStatus, error) {
 	var errs errlist.Builder
-	reconciledCondition := r.determineReconciledCondition(reconcileErr)
+	rollbackRevision, _ := sharedreconcile.RollbackRevision(ztunnel)
+	reconciledCondition := r.determineReconciledCondition(rollbackRevision, reconcileErr)
 	readyCondition, err := r.determineReadyCondition(ctx, ztunnel)
 	errs.Add(err)
+	var resources []v1.ResourceStatus
+	var history []v1.HelmReleaseRevision
+	var conflicts *sharedreconcile.FieldConflictReport
+	if r.ChartManager != nil {
+		ztunnelReconciler := r.newZTunnelReconciler(ztunnel)
+		resources, err = ztunnelReconciler.CheckReadiness(ctx, ztunnel.Spec.Namespace)
+		errs.Add(err)
+		history, err = ztunnelReconciler.ReleaseHistory(ctx, ztunnel.Spec.Namespace)
+		errs.Add(err)
+		conflicts = ztunnelReconciler.FieldConflicts(ztunnel.Spec.Namespace)
+	}
+	readyCondition = reconciler.ApplyResourcesReadiness(readyCondition, resources, v1.ZTunnelReasonResourcesNotReady)
 
 	status := *ztunnel.Status.DeepCopy()
 	status.ObservedGeneration = ztunnel.Generation
+	status.ResolvedVersion, _ = istioversion.Resolve(ztunnel.Spec.Version)
+	status.Resources = resources
+	status.ReleaseHistory = history
 	status.SetCondition(reconciledCondition)
 	status.SetCondition(readyCondition)
+	if driftCondition := r.determineDriftCondition(ztunnel, drift); driftCondition != nil {
+		status.SetCondition(*driftCondition)
+	}
+	if conflictsCondition := r.determineFieldConflictsCondition(conflicts); conflictsCondition != nil {
+		status.SetCondition(*conflictsCondition)
+	}
 	status.State = reconciler.DeriveState(v1.ZTunnelReasonHealthy, reconciledCondition, readyCondition)
 	status.IstioRevision = ""
 	if rev != nil {
@@ -209,24 +322,88 @@ func (r *Reconciler) determineStatus(ctx context.Context, ztunnel *v1.ZTunnel, r
 	return status, errs.Error()
 }
 
-func (r *Reconciler) updateStatus(ctx context.Context, ztunnel *v1.ZTunnel, rev *v1.IstioRevision, reconcileErr error) error {
-	status, err := r.determineStatus(ctx, ztunnel, rev, reconcileErr)
+func (r *Reconciler) updateStatus(
+	ctx context.Context, ztunnel *v1.ZTunnel, rev *v1.IstioRevision, drift *sharedreconcile.DriftReport, reconcileErr error,
+) error {
+	status, err := r.determineStatus(ctx, ztunnel, rev, drift, reconcileErr)
 	return reconciler.UpdateStatus(ctx, r.Client, ztunnel, ztunnel.Status, status, err)
 }
 
-func (r *Reconciler) determineReconciledCondition(err error) v1.StatusCondition {
+func (r *Reconciler) determineReconciledCondition(rollbackRevision int, err error) v1.StatusCondition {
 	c := v1.StatusCondition{Type: v1.ZTunnelConditionReconciled}
-	if err == nil {
+	if err == nil && rollbackRevision > 0 {
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1.ZTunnelReasonRolledBack
+		c.Message = fmt.Sprintf("the ztunnel Helm release was rolled back to revision %d because the %s annotation is set; "+
+			"remove the annotation to install the version and values defined in the spec", rollbackRevision, constants.RollbackAnnotationKey)
+	} else if err == nil {
 		c.Status = metav1.ConditionTrue
 		c.Reason = v1.ConditionReason(v1.ZTunnelConditionReconciled)
 	} else {
 		c.Status = metav1.ConditionFalse
-		c.Reason = v1.ZTunnelReasonReconcileError
-		c.Message = fmt.Sprintf("error reconciling resource: %v", err)
+		switch {
+		case helm.IsPatchError(err):
+			c.Reason = v1.ZTunnelReasonInvalidPatch
+			c.Message = err.Error()
+		default:
+			c.Reason = v1.ZTunnelReasonReconcileError
+			c.Message = fmt.Sprintf("error reconciling resource: %v", err)
+		}
 	}
 	return c
 }
 
+// determineDriftCondition returns the DriftDetected condition, or nil if drift wasn't checked. A condition
+// that reports reverted drift is kept until the spec of the ZTunnel changes, so that users can see it.
+func (r *Reconciler) determineDriftCondition(ztunnel *v1.ZTunnel, drift *sharedreconcile.DriftReport) *v1.StatusCondition {
+	if drift == nil {
+		return nil
+	}
+
+	c := v1.StatusCondition{Type: v1.ZTunnelConditionDriftDetected}
+	switch {
+	case drift.Err != nil:
+		c.Status = metav1.ConditionUnknown
+		c.Reason = v1.ZTunnelReasonDriftCheckFailed
+		c.Message = fmt.Sprintf("failed to check for drift: %v", drift.Err)
+	case len(drift.Drifts) == 0:
+		previous := ztunnel.Status.GetCondition(v1.ZTunnelConditionDriftDetected)
+		if previous.Reason == v1.ZTunnelReasonDriftReverted && ztunnel.Status.ObservedGeneration == ztunnel.Generation {
+			return &previous
+		}
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1.ZTunnelReasonNoDrift
+	case drift.Reverted:
+		c.Status = metav1.ConditionTrue
+		c.Reason = v1.ZTunnelReasonDriftReverted
+		c.Message = "reverted changes made outside of the operator: " + drift.Message()
+	default:
+		c.Status = metav1.ConditionTrue
+		c.Reason = v1.ZTunnelReasonDriftReported
+		c.Message = "found changes made outside of the operator: " + drift.Message()
+	}
+	return &c
+}
+
+// determineFieldConflictsCondition returns the FieldConflicts condition, or nil if the operator doesn't use
+// server-side apply.
+func (r *Reconciler) determineFieldConflictsCondition(conflicts *sharedreconcile.FieldConflictReport) *v1.StatusCondition {
+	if conflicts == nil {
+		return nil
+	}
+
+	c := v1.StatusCondition{Type: v1.ZTunnelConditionFieldConflicts}
+	if len(conflicts.Conflicts) == 0 {
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1.ZTunnelReasonNoConflicts
+	} else {
+		c.Status = metav1.ConditionTrue
+		c.Reason = v1.ZTunnelReasonConflictsSkipped
+		c.Message = "kept fields owned by other field managers: " + conflicts.Message()
+	}
+	return &c
+}
+
 func (r *Reconciler) determineReadyCondition(ctx context.Context, ztunnel *v1.ZTunnel) (v1.StatusCondition, error) {
 	return reconciler.CheckDaemonSetReadiness(ctx, r.Client, r.getDaemonSetKey(ztunnel),
 		"ztunnel", v1.ZTunnelConditionReady, v1.ZTunnelDaemonSetNotReady, v1.ZTunnelReasonReadinessCheckFailed)
@@ -239,6 +416,22 @@ func (r *Reconciler) getDaemonSetKey(ztunnel *v1.ZTunnel) client.ObjectKey {
 	}
 }
 
+// mapZTunnelToReconcileRequests returns the ZTunnel and the other ZTunnels that use the same namespace, so that a
+// ZTunnel that was rejected because of another one in its namespace is reconciled again when the other one is deleted
+func (r *Reconciler) mapZTunnelToReconcileRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	requests := []reconcile.Request{{NamespacedName: types.NamespacedName{Name: obj.GetName()}}}
+	ztunnel, ok := obj.(*v1.ZTunnel)
+	if !ok {
+		return requests
+	}
+	for _, req := range r.mapNamespaceToReconcileRequest(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ztunnel.Spec.Namespace}}) {
+		if req.Name != ztunnel.Name {
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
 func (r *Reconciler) mapNamespaceToReconcileRequest(ctx context.Context, ns client.Object) []reconcile.Request {
 	log := logf.FromContext(ctx)
 
@@ -283,6 +476,23 @@ func (r *Reconciler) mapOperatorResourceToReconcileRequest(ctx context.Context,
 	return requests
 }
 
+// mapVersionChangeToReconcileRequests returns all ZTunnel objects whose spec.version may refer to different charts
+// after the versions provided by IstioChartSources or the IstioVersionCatalog have changed.
+func (r *Reconciler) mapVersionChangeToReconcileRequests(ctx context.Context, _ client.Object) []reconcile.Request {
+	list := v1.ZTunnelList{}
+	if err := r.Client.List(ctx, &list); err != nil {
+		logf.FromContext(ctx).Error(err, "failed to list ZTunnels")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, ztunnel := range list.Items {
+		if istioversion.IsAffectedByChange(ztunnel.Spec.Version, ztunnel.Status.ResolvedVersion) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: ztunnel.Name}})
+		}
+	}
+	return requests
+}
+
 func wrapEventHandler(logger logr.Logger, handler handler.EventHandler) handler.EventHandler {
 	return enqueuelogger.WrapIfNecessary(v1.ZTunnelKind, logger, handler)
 }
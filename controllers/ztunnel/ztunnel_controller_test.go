@@ -24,6 +24,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
 	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
 	"github.com/istio-ecosystem/sail-operator/pkg/istiovalues"
 	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
 	sharedreconcile "github.com/istio-ecosystem/sail-operator/pkg/reconcile"
@@ -33,9 +34,11 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"istio.io/istio/pkg/ptr"
 )
@@ -540,9 +543,11 @@ func TestDetermineStatus(t *testing.T) {
 	cfg := newReconcilerTestConfig(t)
 
 	tests := []struct {
-		name         string
-		reconcileErr error
-		rev          *v1.IstioRevision
+		name           string
+		annotations    map[string]string
+		reconcileErr   error
+		rev            *v1.IstioRevision
+		expectedReason v1.ZTunnelConditionReason
 	}{
 		{
 			name: "no error",
@@ -551,11 +556,18 @@ func TestDetermineStatus(t *testing.T) {
 					Name: "test",
 				},
 			},
-			reconcileErr: nil,
+			reconcileErr:   nil,
+			expectedReason: v1.ZTunnelConditionReason(v1.ZTunnelConditionReconciled),
+		},
+		{
+			name:           "reconcile error",
+			reconcileErr:   fmt.Errorf("some reconcile error"),
+			expectedReason: v1.ZTunnelReasonReconcileError,
 		},
 		{
-			name:         "reconcile error",
-			reconcileErr: fmt.Errorf("some reconcile error"),
+			name:           "rolled back",
+			annotations:    map[string]string{constants.RollbackAnnotationKey: "2"},
+			expectedReason: v1.ZTunnelReasonRolledBack,
 		},
 	}
 
@@ -569,17 +581,20 @@ func TestDetermineStatus(t *testing.T) {
 
 			ztunnel := &v1.ZTunnel{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:       "ztunnel",
-					Generation: 123,
+					Name:        "ztunnel",
+					Generation:  123,
+					Annotations: tt.annotations,
 				},
 			}
 
-			status, err := r.determineStatus(ctx, ztunnel, tt.rev, tt.reconcileErr)
+			status, err := r.determineStatus(ctx, ztunnel, tt.rev, nil, tt.reconcileErr)
 			g.Expect(err).ToNot(HaveOccurred())
 
 			g.Expect(status.ObservedGeneration).To(Equal(ztunnel.Generation))
 
-			reconciledCondition := r.determineReconciledCondition(tt.reconcileErr)
+			rollbackRevision, _ := sharedreconcile.RollbackRevision(ztunnel)
+			reconciledCondition := r.determineReconciledCondition(rollbackRevision, tt.reconcileErr)
+			g.Expect(reconciledCondition.Reason).To(Equal(tt.expectedReason))
 			readyCondition, err := r.determineReadyCondition(ctx, ztunnel)
 			g.Expect(err).ToNot(HaveOccurred())
 
@@ -608,3 +623,33 @@ func newReconcilerTestConfig(t *testing.T) config.ReconcilerConfig {
 		MaxConcurrentReconciles: 1,
 	}
 }
+
+func TestValidateNamespaceNotShared(t *testing.T) {
+	earlyTimestamp := metav1.Now()
+	lateTimestamp := metav1.NewTime(earlyTimestamp.Add(time.Hour))
+
+	newZTunnel := func(name, namespace string, creationTimestamp metav1.Time) *v1.ZTunnel {
+		return &v1.ZTunnel{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: creationTimestamp},
+			Spec:       v1.ZTunnelSpec{Version: istioversion.Default, Namespace: namespace},
+		}
+	}
+	defaultZTunnel := newZTunnel("default", ztunnelNamespace, earlyTimestamp)
+	sharedZTunnel := newZTunnel("pool-a", ztunnelNamespace, lateTimestamp)
+	otherZTunnel := newZTunnel("pool-b", "ztunnel-b", lateTimestamp)
+
+	g := NewWithT(t)
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(defaultZTunnel, sharedZTunnel, otherZTunnel).Build()
+	r := NewReconciler(newReconcilerTestConfig(t), cl, scheme.Scheme, nil)
+
+	g.Expect(r.validateNamespaceNotShared(context.TODO(), defaultZTunnel)).To(Succeed())
+	g.Expect(r.validateNamespaceNotShared(context.TODO(), otherZTunnel)).To(Succeed())
+	err := r.validateNamespaceNotShared(context.TODO(), sharedZTunnel)
+	g.Expect(reconciler.IsValidationError(err)).To(BeTrue())
+	g.Expect(err).To(MatchError(ContainSubstring(`namespace "ztunnel" is already used by ZTunnel "default"`)))
+
+	g.Expect(r.mapZTunnelToReconcileRequests(context.TODO(), sharedZTunnel)).To(ConsistOf(
+		reconcile.Request{NamespacedName: types.NamespacedName{Name: "pool-a"}},
+		reconcile.Request{NamespacedName: types.NamespacedName{Name: "default"}},
+	))
+}
@@ -38,7 +38,7 @@ check_watches() {
     echo "Watched kinds: ${watchedKinds[*]}"
 
     # Find ignored kinds from all source files. Starting list is all operator CRDs
-    local ignoredStr="Istio IstioCNI ZTunnel IstioRevision IstioRevisionTag"
+    local ignoredStr="Istio IstioCNI ZTunnel IstioRevision IstioRevisionTag IstioGateway"
     for sp in "${sourcePaths[@]}"; do
         ignoredStr+=" $(sed -n 's/.*\+lint-watches:ignore:\s*\(\w*\).*/\1/p' "$sp" 2>/dev/null || true)"
     done
@@ -89,3 +89,4 @@ check_watches() {
 check_watches "./controllers/istiorevision/istiorevision_controller.go:./pkg/watches/istiod.go" "./resources/*/charts/istiod ./resources/*/charts/istiod-remote ./resources/*/charts/base"
 check_watches "./controllers/istiocni/istiocni_controller.go:./pkg/watches/cni.go" "./resources/*/charts/cni"
 check_watches "./controllers/ztunnel/ztunnel_controller.go:./pkg/watches/ztunnel.go" "./resources/*/charts/ztunnel"
+check_watches "./controllers/istiogateway/istiogateway_controller.go:./pkg/watches/gateway.go" "./resources/*/charts/gateway"
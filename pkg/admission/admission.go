@@ -0,0 +1,219 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/istio-ecosystem/sail-operator/pkg/istiovalues"
+	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconcile"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	"github.com/istio-ecosystem/sail-operator/pkg/validation"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	webhookadmission "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWithManager registers the validating and defaulting admission webhooks for the Istio, IstioCNI and ZTunnel
+// resources with the manager's webhook server. Because the v1alpha1 ZTunnel can be converted to and from the v1 ZTunnel,
+// the conversion webhook is registered at ConversionPath as well.
+func SetupWithManager(mgr ctrl.Manager, cfg config.ReconcilerConfig) error {
+	v := newValidator(cfg, mgr.GetClient())
+	if err := ctrl.NewWebhookManagedBy(mgr, &v1.Istio{}).
+		WithValidator(&IstioValidator{v}).
+		WithDefaulter(versionDefaulter[*v1.Istio]{func(istio *v1.Istio) *string { return &istio.Spec.Version }}).
+		Complete(); err != nil {
+		return fmt.Errorf("failed to set up webhook for Istio: %w", err)
+	}
+	if err := ctrl.NewWebhookManagedBy(mgr, &v1.IstioCNI{}).
+		WithValidator(&IstioCNIValidator{v}).
+		WithDefaulter(versionDefaulter[*v1.IstioCNI]{func(cni *v1.IstioCNI) *string { return &cni.Spec.Version }}).
+		Complete(); err != nil {
+		return fmt.Errorf("failed to set up webhook for IstioCNI: %w", err)
+	}
+	// the ZTunnel webhook also validates v1alpha1 ZTunnels, which the builder's handler can't decode, so it's
+	// registered here; the builder then only registers the defaulting and conversion webhooks
+	ztunnelWebhook := webhookadmission.WithValidator[*v1.ZTunnel](mgr.GetScheme(), &ZTunnelValidator{v})
+	ztunnelWebhook.Handler = v1alpha1ZTunnelConverter{ztunnelWebhook.Handler}
+	mgr.GetWebhookServer().Register(ztunnelValidationPath, ztunnelWebhook)
+	if err := ctrl.NewWebhookManagedBy(mgr, &v1.ZTunnel{}).
+		WithDefaulter(versionDefaulter[*v1.ZTunnel]{func(ztunnel *v1.ZTunnel) *string { return &ztunnel.Spec.Version }}).
+		Complete(); err != nil {
+		return fmt.Errorf("failed to set up webhook for ZTunnel: %w", err)
+	}
+	return nil
+}
+
+// versionDefaulter sets the version of new resources to the default version of the IstioVersionCatalog. The API
+// server sets the version to the default of the CRD schema before it calls the webhook, so a resource that is
+// created with the operator's default version can't be told apart from one created without a version; both are
+// moved to the catalog's default version. The webhook is only called for new resources, so that changing the
+// catalog's default version never upgrades existing resources.
+type versionDefaulter[T client.Object] struct {
+	version func(T) *string
+}
+
+func (d versionDefaulter[T]) Default(_ context.Context, obj T) error {
+	if version := d.version(obj); *version == "" || *version == istioversion.Default {
+		*version = istioversion.DefaultVersion()
+	}
+	return nil
+}
+
+// validator holds the checks shared by the webhooks of all resource kinds. They are the same checks that the
+// reconcilers perform, so that invalid resources are rejected when they are applied instead of being reported
+// in the Reconciled condition.
+type validator struct {
+	cfg    config.ReconcilerConfig
+	client client.Client
+	values *istiovalues.SupportedValues
+}
+
+func newValidator(cfg config.ReconcilerConfig, cl client.Client) *validator {
+	return &validator{
+		cfg:    cfg,
+		client: cl,
+		values: istiovalues.NewSupportedValues(cfg.ResourceFS),
+	}
+}
+
+// validateVersion checks that the version can be installed and returns the version it resolves to, or an
+// empty string if it's invalid.
+func (v *validator) validateVersion(fldPath *field.Path, version string) (string, field.ErrorList) {
+	if version == "" {
+		return "", field.ErrorList{field.Required(fldPath, "")}
+	}
+	if err := istioversion.ValidateVersion(version); err != nil {
+		return "", field.ErrorList{field.Invalid(fldPath, version, err.Error())}
+	}
+	resolved, err := istioversion.Resolve(version)
+	if err != nil {
+		return "", field.ErrorList{field.Invalid(fldPath, version, err.Error())}
+	}
+	return resolved, nil
+}
+
+// validateProfile checks that the profile, and the default profile of the platform, exist in the given version.
+func (v *validator) validateProfile(fldPath *field.Path, resolvedVersion, profile string) field.ErrorList {
+	if err := istiovalues.ValidateProfile(v.cfg.ResourceFS, resolvedVersion, v.cfg.DefaultProfile, profile); err != nil {
+		return field.ErrorList{field.Invalid(fldPath, profile, err.Error())}
+	}
+	return nil
+}
+
+// validateValues rejects the values that the charts of the given version don't support, but other versions do.
+func (v *validator) validateValues(ctx context.Context, fldPath *field.Path, resolvedVersion string, charts []istiovalues.ChartValues,
+	values any,
+) field.ErrorList {
+	unsupported, err := v.values.Unsupported(resolvedVersion, versionNames(), charts, helm.FromValues(values))
+	if err != nil {
+		// the reconciler reports the errors of broken charts, they shouldn't block changes to the resource
+		log.FromContext(ctx).Error(err, "failed to determine the values supported by version", "version", resolvedVersion)
+		return nil
+	}
+	var errs field.ErrorList
+	for _, p := range unsupported {
+		errs = append(errs, field.Forbidden(fldPath.Child(p), fmt.Sprintf("not supported in version %s", resolvedVersion)))
+	}
+	return errs
+}
+
+// checkNamespace returns a warning if the target namespace doesn't exist. This isn't an error, because the
+// namespace may be created after the resource; the reconciler waits for it.
+func (v *validator) checkNamespace(ctx context.Context, namespace string) webhookadmission.Warnings {
+	if namespace == "" {
+		return nil
+	}
+	if err := validation.ValidateTargetNamespace(ctx, v.client, namespace); err != nil && reconciler.IsValidationError(err) {
+		return webhookadmission.Warnings{err.Error()}
+	}
+	return nil
+}
+
+// dataplaneInstance is an IstioCNI or ZTunnel resource. Several of them can be installed if each uses its own
+// namespace and their node selectors select disjoint sets of nodes.
+type dataplaneInstance struct {
+	name         string
+	namespace    string
+	nodeSelector map[string]string
+}
+
+// checkInstances rejects a new target namespace that another resource of the same kind already uses, because
+// both resources would manage the same Helm release. It returns a warning for each other resource whose node
+// selector overlaps, because both DaemonSets may then run on the same nodes.
+func checkInstances(specPath *field.Path, kind string, oldInstance *dataplaneInstance, instance dataplaneInstance,
+	others []dataplaneInstance,
+) (field.ErrorList, webhookadmission.Warnings) {
+	var errs field.ErrorList
+	var warnings webhookadmission.Warnings
+	namespaceChanged := oldInstance == nil || oldInstance.namespace != instance.namespace
+	for _, other := range others {
+		if other.name == instance.name {
+			continue
+		}
+		if other.namespace == instance.namespace {
+			if namespaceChanged {
+				errs = append(errs, field.Invalid(specPath.Child("namespace"), instance.namespace,
+					fmt.Sprintf("namespace is already used by %s %q", kind, other.name)))
+			}
+		} else if validation.NodeSelectorsOverlap(instance.nodeSelector, other.nodeSelector) {
+			warnings = append(warnings, fmt.Sprintf("spec.nodeSelector overlaps with the nodeSelector of %s %q; "+
+				"their DaemonSets may run on the same nodes", kind, other.name))
+		}
+	}
+	return errs, warnings
+}
+
+func validatePatches(fldPath *field.Path, patches []v1.Patch) field.ErrorList {
+	var patchErr *helm.PatchError
+	if err := reconcile.ValidatePatches(patches); errors.As(err, &patchErr) {
+		return field.ErrorList{field.Invalid(fldPath.Index(patchErr.Index), field.OmitValueType{}, patchErr.Err.Error())}
+	}
+	return nil
+}
+
+// validateNamespaceUpdate rejects changes to the target namespace, which would leave the components installed
+// in the old namespace behind.
+func validateNamespaceUpdate(fldPath *field.Path, oldNamespace, newNamespace string) field.ErrorList {
+	if oldNamespace != newNamespace {
+		return field.ErrorList{field.Forbidden(fldPath, "field is immutable")}
+	}
+	return nil
+}
+
+func versionNames() []string {
+	names := make([]string, 0, len(istioversion.List))
+	for _, info := range istioversion.List {
+		names = append(names, info.Name)
+	}
+	return names
+}
+
+func toError(kind schema.GroupKind, name string, errs field.ErrorList) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(kind, name, errs)
+}
@@ -0,0 +1,375 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
+	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
+	"github.com/istio-ecosystem/sail-operator/resources"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	webhookadmission "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func newTestValidator(objs ...client.Object) *validator {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "istio-system"}}
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(append(objs, ns)...).Build()
+	return newValidator(config.ReconcilerConfig{
+		ResourceFS:     resources.FS,
+		Platform:       config.PlatformKubernetes,
+		DefaultProfile: "default",
+	}, cl)
+}
+
+// causes returns the field paths of the causes of an Invalid error
+func causes(t *testing.T, err error) []string {
+	t.Helper()
+	statusErr := &apierrors.StatusError{}
+	if !assert.ErrorAs(t, err, &statusErr) {
+		return nil
+	}
+	assert.True(t, apierrors.IsInvalid(err), "expected Invalid error, got %v", err)
+	var fields []string
+	for _, cause := range statusErr.ErrStatus.Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+	return fields
+}
+
+func newIstio(mutate func(*v1.Istio)) *v1.Istio {
+	istio := &v1.Istio{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: v1.IstioSpec{
+			Version:   "v1.30.3",
+			Namespace: "istio-system",
+		},
+	}
+	if mutate != nil {
+		mutate(istio)
+	}
+	return istio
+}
+
+func TestIstioValidator(t *testing.T) {
+	v := &IstioValidator{newTestValidator()}
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		istio        *v1.Istio
+		wantFields   []string
+		wantWarnings int
+	}{
+		{
+			name:  "valid",
+			istio: newIstio(nil),
+		},
+		{
+			name:       "missing version",
+			istio:      newIstio(func(i *v1.Istio) { i.Spec.Version = "" }),
+			wantFields: []string{"spec.version"},
+		},
+		{
+			name:       "unsupported version",
+			istio:      newIstio(func(i *v1.Istio) { i.Spec.Version = "v1.0.0" }),
+			wantFields: []string{"spec.version"},
+		},
+		{
+			name:       "end-of-life version",
+			istio:      newIstio(func(i *v1.Istio) { i.Spec.Version = "v1.28.0" }),
+			wantFields: []string{"spec.version"},
+		},
+		{
+			name: "profile missing in version",
+			istio: newIstio(func(i *v1.Istio) {
+				i.Spec.Version = "v1.29.0"
+				i.Spec.Profile = "external"
+			}),
+			wantFields: []string{"spec.profile"},
+		},
+		{
+			name: "value not supported by version",
+			istio: newIstio(func(i *v1.Istio) {
+				i.Spec.Version = "v1.29.0"
+				i.Spec.Values = &v1.Values{Global: &v1.GlobalConfig{EnableReaderRBAC: ptr.Of(true)}}
+			}),
+			wantFields: []string{"spec.values.global.enableReaderRBAC"},
+		},
+		{
+			name: "value supported by version",
+			istio: newIstio(func(i *v1.Istio) {
+				i.Spec.Values = &v1.Values{Global: &v1.GlobalConfig{EnableReaderRBAC: ptr.Of(true)}}
+			}),
+		},
+		{
+			name: "invalid patch",
+			istio: newIstio(func(i *v1.Istio) {
+				i.Spec.Patches = []v1.Patch{
+					{Target: v1.PatchTarget{Kind: "Deployment"}, Patch: "spec: {}"},
+					{Patch: "spec: {}"},
+				}
+			}),
+			wantFields: []string{"spec.patches[1]"},
+		},
+		{
+			name:         "namespace doesn't exist",
+			istio:        newIstio(func(i *v1.Istio) { i.Spec.Namespace = "other" }),
+			wantWarnings: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, err := v.ValidateCreate(ctx, tt.istio)
+			if len(tt.wantFields) > 0 {
+				assert.Equal(t, tt.wantFields, causes(t, err))
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Len(t, warnings, tt.wantWarnings)
+		})
+	}
+}
+
+func TestIstioValidatorUpdate(t *testing.T) {
+	v := &IstioValidator{newTestValidator()}
+	ctx := context.Background()
+
+	t.Run("namespace change", func(t *testing.T) {
+		_, err := v.ValidateUpdate(ctx, newIstio(nil), newIstio(func(i *v1.Istio) { i.Spec.Namespace = "other" }))
+		assert.Equal(t, []string{"spec.namespace"}, causes(t, err))
+	})
+
+	t.Run("unchanged end-of-life version", func(t *testing.T) {
+		eol := newIstio(func(i *v1.Istio) { i.Spec.Version = "v1.28.0" })
+		_, err := v.ValidateUpdate(ctx, eol, eol.DeepCopy())
+		assert.NoError(t, err)
+	})
+
+	t.Run("changed to end-of-life version", func(t *testing.T) {
+		_, err := v.ValidateUpdate(ctx, newIstio(nil), newIstio(func(i *v1.Istio) { i.Spec.Version = "v1.28.0" }))
+		assert.Equal(t, []string{"spec.version"}, causes(t, err))
+	})
+
+	t.Run("being deleted", func(t *testing.T) {
+		deleted := newIstio(func(i *v1.Istio) {
+			i.Spec.Version = ""
+			i.DeletionTimestamp = ptr.Of(metav1.Now())
+		})
+		_, err := v.ValidateUpdate(ctx, newIstio(nil), deleted)
+		assert.NoError(t, err)
+	})
+}
+
+func TestIstioCNIValidator(t *testing.T) {
+	v := &IstioCNIValidator{newTestValidator()}
+	ctx := context.Background()
+
+	newCNI := func(version string) *v1.IstioCNI {
+		return &v1.IstioCNI{
+			ObjectMeta: metav1.ObjectMeta{Name: "default"},
+			Spec: v1.IstioCNISpec{
+				Version:   version,
+				Namespace: "istio-system",
+				Values:    &v1.CNIValues{Cni: &v1.CNIConfig{UseAppArmorAnnotation: ptr.Of(false)}},
+			},
+		}
+	}
+
+	_, err := v.ValidateCreate(ctx, newCNI("v1.29.0"))
+	assert.Equal(t, []string{"spec.values.cni.useAppArmorAnnotation"}, causes(t, err))
+
+	_, err = v.ValidateCreate(ctx, newCNI("v1.30.3"))
+	assert.NoError(t, err)
+}
+
+func TestZTunnelValidator(t *testing.T) {
+	v := &ZTunnelValidator{newTestValidator()}
+	ctx := context.Background()
+
+	newZTunnel := func(version string) *v1.ZTunnel {
+		return &v1.ZTunnel{
+			ObjectMeta: metav1.ObjectMeta{Name: "default"},
+			Spec: v1.ZTunnelSpec{
+				Version:   version,
+				Namespace: "istio-system",
+				Values:    &v1.ZTunnelValues{ZTunnel: &v1.ZTunnelConfig{DNSPolicy: ptr.Of(corev1.DNSClusterFirst)}},
+			},
+		}
+	}
+
+	_, err := v.ValidateCreate(ctx, newZTunnel("v1.29.0"))
+	assert.Equal(t, []string{"spec.values.ztunnel.dnsPolicy"}, causes(t, err))
+
+	_, err = v.ValidateCreate(ctx, newZTunnel("v1.30.3"))
+	assert.NoError(t, err)
+
+	_, err = v.ValidateCreate(ctx, newZTunnel(""))
+	assert.Equal(t, []string{"spec.version"}, causes(t, err))
+}
+
+func TestZTunnelValidatorV1alpha1(t *testing.T) {
+	ctx := context.Background()
+	existing := &v1.ZTunnel{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec:       v1.ZTunnelSpec{Version: "v1.30.3", Namespace: "istio-system"},
+	}
+	webhook := webhookadmission.WithValidator[*v1.ZTunnel](scheme.Scheme, &ZTunnelValidator{newTestValidator(existing)})
+	handler := v1alpha1ZTunnelConverter{webhook.Handler}
+
+	newRequest := func(operation admissionv1.Operation, version string, old *v1alpha1.ZTunnel) webhookadmission.Request {
+		ztunnel := &v1alpha1.ZTunnel{
+			TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: v1alpha1.ZTunnelKind},
+			ObjectMeta: metav1.ObjectMeta{Name: "default"},
+			Spec: v1alpha1.ZTunnelSpec{
+				Version:   version,
+				Profile:   "ambient",
+				Namespace: "istio-system",
+				Values:    &v1.ZTunnelValues{ZTunnel: &v1.ZTunnelConfig{DNSPolicy: ptr.Of(corev1.DNSClusterFirst)}},
+			},
+		}
+		req := webhookadmission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+			Operation: operation,
+			Kind:      metav1.GroupVersionKind(v1alpha1.GroupVersion.WithKind(v1alpha1.ZTunnelKind)),
+			Name:      ztunnel.Name,
+		}}
+		req.Object.Raw = toJSON(t, ztunnel)
+		if old != nil {
+			req.OldObject.Raw = toJSON(t, old)
+		}
+		return req
+	}
+
+	resp := handler.Handle(ctx, newRequest(admissionv1.Create, "v1.29.0", nil))
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, "spec.values.ztunnel.dnsPolicy")
+
+	resp = handler.Handle(ctx, newRequest(admissionv1.Create, "v1.30.3", nil))
+	assert.True(t, resp.Allowed, resp.Result)
+
+	old := &v1alpha1.ZTunnel{}
+	assert.NoError(t, old.ConvertFrom(existing))
+	old.TypeMeta = metav1.TypeMeta{APIVersion: v1alpha1.GroupVersion.String(), Kind: v1alpha1.ZTunnelKind}
+	resp = handler.Handle(ctx, newRequest(admissionv1.Update, "v1.29.0", old))
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, "spec.values.ztunnel.dnsPolicy")
+}
+
+func toJSON(t *testing.T, obj any) []byte {
+	t.Helper()
+	data, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestIstioCNIValidatorInstances(t *testing.T) {
+	ctx := context.Background()
+
+	newCNI := func(name, namespace string, nodeSelector map[string]string) *v1.IstioCNI {
+		return &v1.IstioCNI{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1.IstioCNISpec{
+				Version:      "v1.30.3",
+				Namespace:    namespace,
+				NodeSelector: nodeSelector,
+			},
+		}
+	}
+	existing := newCNI("default", "istio-cni", map[string]string{"pool": "default"})
+	v := &IstioCNIValidator{newTestValidator(existing, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "istio-cni"}})}
+
+	_, err := v.ValidateCreate(ctx, newCNI("pool-a", "istio-cni", map[string]string{"pool": "a"}))
+	assert.Equal(t, []string{"spec.namespace"}, causes(t, err))
+
+	warnings, err := v.ValidateCreate(ctx, newCNI("pool-a", "istio-system", map[string]string{"pool": "a"}))
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+
+	warnings, err = v.ValidateCreate(ctx, newCNI("pool-a", "istio-system", map[string]string{"zone": "a"}))
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 1)
+
+	warnings, err = v.ValidateUpdate(ctx, existing, newCNI("default", "istio-cni", nil))
+	assert.NoError(t, err)
+	assert.Empty(t, warnings)
+}
+
+func TestZTunnelValidatorInstances(t *testing.T) {
+	ctx := context.Background()
+
+	newZTunnel := func(name, namespace string, nodeSelector map[string]string) *v1.ZTunnel {
+		return &v1.ZTunnel{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: v1.ZTunnelSpec{
+				Version:      "v1.30.3",
+				Namespace:    namespace,
+				NodeSelector: nodeSelector,
+			},
+		}
+	}
+	existing := newZTunnel("default", "ztunnel", nil)
+	v := &ZTunnelValidator{newTestValidator(existing)}
+
+	_, err := v.ValidateCreate(ctx, newZTunnel("pool-a", "ztunnel", map[string]string{"pool": "a"}))
+	assert.Equal(t, []string{"spec.namespace"}, causes(t, err))
+
+	warnings, err := v.ValidateCreate(ctx, newZTunnel("pool-a", "istio-system", map[string]string{"pool": "a"}))
+	assert.NoError(t, err)
+	assert.Len(t, warnings, 1)
+
+	// an existing conflict doesn't block updates that leave the namespace unchanged
+	other := newZTunnel("pool-b", "ztunnel", nil)
+	_, err = v.ValidateUpdate(ctx, other, other)
+	assert.NoError(t, err)
+}
+
+func TestVersionDefaulter(t *testing.T) {
+	ctx := context.Background()
+	d := versionDefaulter[*v1.Istio]{func(istio *v1.Istio) *string { return &istio.Spec.Version }}
+	t.Cleanup(func() { istioversion.SetDefaultVersion("") })
+
+	istio := newIstio(func(i *v1.Istio) { i.Spec.Version = istioversion.Default })
+	assert.NoError(t, d.Default(ctx, istio))
+	assert.Equal(t, istioversion.Default, istio.Spec.Version, "version should not change without a catalog default version")
+
+	istioversion.SetDefaultVersion("v1.30.3")
+	istio = newIstio(func(i *v1.Istio) { i.Spec.Version = istioversion.Default })
+	assert.NoError(t, d.Default(ctx, istio))
+	assert.Equal(t, "v1.30.3", istio.Spec.Version)
+
+	istio = newIstio(func(i *v1.Istio) { i.Spec.Version = "" })
+	assert.NoError(t, d.Default(ctx, istio))
+	assert.Equal(t, "v1.30.3", istio.Spec.Version)
+
+	istio = newIstio(func(i *v1.Istio) { i.Spec.Version = "v1.29.0" })
+	assert.NoError(t, d.Default(ctx, istio))
+	assert.Equal(t, "v1.29.0", istio.Spec.Version, "versions other than the operator's default should be kept")
+}
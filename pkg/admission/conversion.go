@@ -0,0 +1,69 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission
+
+import (
+	"context"
+	"fmt"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+// ConversionPath is the path at which the manager's webhook server serves the conversion webhook. It's
+// registered by SetupWithManager for all resources that have multiple versions.
+const ConversionPath = "/convert"
+
+// ConfigureConversionWebhook sets the conversion strategy of the CRD with the given name to the conversion
+// webhook served through the given Service, whose serving certificate is signed by caBundle. OLM configures
+// the conversion webhook of the CRDs itself, so this is only needed when the operator is installed otherwise.
+func ConfigureConversionWebhook(ctx context.Context, cl client.Client, crdName string, service types.NamespacedName,
+	caBundle []byte,
+) error {
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := cl.Get(ctx, types.NamespacedName{Name: crdName}, crd); err != nil {
+		return fmt.Errorf("failed to get CRD %s: %w", crdName, err)
+	}
+
+	conversion := &apiextensionsv1.CustomResourceConversion{
+		Strategy: apiextensionsv1.WebhookConverter,
+		Webhook: &apiextensionsv1.WebhookConversion{
+			ClientConfig: &apiextensionsv1.WebhookClientConfig{
+				Service: &apiextensionsv1.ServiceReference{
+					Namespace: service.Namespace,
+					Name:      service.Name,
+					Path:      ptr.Of(ConversionPath),
+					Port:      ptr.Of(int32(443)),
+				},
+				CABundle: caBundle,
+			},
+			ConversionReviewVersions: []string{"v1"},
+		},
+	}
+	if equality.Semantic.DeepEqual(crd.Spec.Conversion, conversion) {
+		return nil
+	}
+
+	patch := client.MergeFrom(crd.DeepCopy())
+	crd.Spec.Conversion = conversion
+	if err := cl.Patch(ctx, crd, patch); err != nil {
+		return fmt.Errorf("failed to configure conversion webhook of CRD %s: %w", crdName, err)
+	}
+	return nil
+}
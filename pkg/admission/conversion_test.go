@@ -0,0 +1,69 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission
+
+import (
+	"context"
+	"testing"
+
+	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestConfigureConversionWebhook(t *testing.T) {
+	ctx := context.Background()
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "ztunnels.sailoperator.io"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Conversion: &apiextensionsv1.CustomResourceConversion{Strategy: apiextensionsv1.NoneConverter},
+		},
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(crd).Build()
+	service := types.NamespacedName{Namespace: "sail-operator", Name: "sail-operator-webhook-service"}
+
+	require.NoError(t, ConfigureConversionWebhook(ctx, cl, crd.Name, service, []byte("ca")))
+	require.NoError(t, cl.Get(ctx, types.NamespacedName{Name: crd.Name}, crd))
+	assert.Equal(t, &apiextensionsv1.CustomResourceConversion{
+		Strategy: apiextensionsv1.WebhookConverter,
+		Webhook: &apiextensionsv1.WebhookConversion{
+			ClientConfig: &apiextensionsv1.WebhookClientConfig{
+				Service: &apiextensionsv1.ServiceReference{
+					Namespace: "sail-operator",
+					Name:      "sail-operator-webhook-service",
+					Path:      ptr.Of("/convert"),
+					Port:      ptr.Of(int32(443)),
+				},
+				CABundle: []byte("ca"),
+			},
+			ConversionReviewVersions: []string{"v1"},
+		},
+	}, crd.Spec.Conversion)
+
+	// an unchanged configuration isn't patched again
+	resourceVersion := crd.ResourceVersion
+	require.NoError(t, ConfigureConversionWebhook(ctx, cl, crd.Name, service, []byte("ca")))
+	require.NoError(t, cl.Get(ctx, types.NamespacedName{Name: crd.Name}, crd))
+	assert.Equal(t, resourceVersion, crd.ResourceVersion)
+
+	assert.ErrorContains(t, ConfigureConversionWebhook(ctx, cl, "missing.sailoperator.io", service, []byte("ca")),
+		"failed to get CRD missing.sailoperator.io")
+}
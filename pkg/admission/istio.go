@@ -0,0 +1,84 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission
+
+import (
+	"context"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/istiovalues"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	webhookadmission "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// +kubebuilder:webhook:path=/validate-sailoperator-io-v1-istio,mutating=false,failurePolicy=fail,sideEffects=None,groups=sailoperator.io,resources=istios,verbs=create;update,versions=v1,name=vistio.sailoperator.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-sailoperator-io-v1-istio,mutating=true,failurePolicy=fail,sideEffects=None,groups=sailoperator.io,resources=istios,verbs=create,versions=v1,name=mistio.sailoperator.io,admissionReviewVersions=v1
+
+// istioCharts are the charts whose values are set in the Istio spec.values.
+var istioCharts = []istiovalues.ChartValues{
+	{Chart: "base"},
+	{Chart: "istiod", Prefix: "pilot"},
+}
+
+// IstioValidator validates Istio resources when they are created or updated.
+type IstioValidator struct {
+	*validator
+}
+
+var _ webhookadmission.Validator[*v1.Istio] = &IstioValidator{}
+
+func (v *IstioValidator) ValidateCreate(ctx context.Context, istio *v1.Istio) (webhookadmission.Warnings, error) {
+	return v.validate(ctx, nil, istio)
+}
+
+func (v *IstioValidator) ValidateUpdate(ctx context.Context, oldIstio, newIstio *v1.Istio) (webhookadmission.Warnings, error) {
+	if newIstio.DeletionTimestamp != nil {
+		return nil, nil
+	}
+	return v.validate(ctx, oldIstio, newIstio)
+}
+
+func (v *IstioValidator) ValidateDelete(_ context.Context, _ *v1.Istio) (webhookadmission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *IstioValidator) validate(ctx context.Context, oldIstio, istio *v1.Istio) (webhookadmission.Warnings, error) {
+	specPath := field.NewPath("spec")
+	var errs field.ErrorList
+
+	resolvedVersion, versionErrs := v.validateVersion(specPath.Child("version"), istio.Spec.Version)
+	// an existing resource whose version has become end-of-life can still be updated, as long as the version
+	// isn't changed; the reconciler reports the error
+	if oldIstio == nil || oldIstio.Spec.Version != istio.Spec.Version {
+		errs = append(errs, versionErrs...)
+	}
+	if resolvedVersion != "" {
+		errs = append(errs, v.validateProfile(specPath.Child("profile"), resolvedVersion, istio.Spec.Profile)...)
+		errs = append(errs, v.validateValues(ctx, specPath.Child("values"), resolvedVersion, istioCharts, istio.Spec.Values)...)
+	}
+
+	if istio.Spec.Namespace == "" {
+		errs = append(errs, field.Required(specPath.Child("namespace"), ""))
+	}
+	if oldIstio != nil {
+		errs = append(errs, validateNamespaceUpdate(specPath.Child("namespace"), oldIstio.Spec.Namespace, istio.Spec.Namespace)...)
+	}
+	errs = append(errs, validatePatches(specPath.Child("patches"), istio.Spec.Patches)...)
+
+	if err := toError(v1.GroupVersion.WithKind(v1.IstioKind).GroupKind(), istio.Name, errs); err != nil {
+		return nil, err
+	}
+	return v.checkNamespace(ctx, istio.Spec.Namespace), nil
+}
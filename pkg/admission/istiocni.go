@@ -0,0 +1,107 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission
+
+import (
+	"context"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/istiovalues"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	webhookadmission "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+// +kubebuilder:webhook:path=/validate-sailoperator-io-v1-istiocni,mutating=false,failurePolicy=fail,sideEffects=None,groups=sailoperator.io,resources=istiocnis,verbs=create;update,versions=v1,name=vistiocni.sailoperator.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-sailoperator-io-v1-istiocni,mutating=true,failurePolicy=fail,sideEffects=None,groups=sailoperator.io,resources=istiocnis,verbs=create,versions=v1,name=mistiocni.sailoperator.io,admissionReviewVersions=v1
+
+// cniCharts are the charts whose values are set in the IstioCNI spec.values.
+var cniCharts = []istiovalues.ChartValues{
+	{Chart: "cni", Prefix: "cni"},
+}
+
+// IstioCNIValidator validates IstioCNI resources when they are created or updated.
+type IstioCNIValidator struct {
+	*validator
+}
+
+var _ webhookadmission.Validator[*v1.IstioCNI] = &IstioCNIValidator{}
+
+func (v *IstioCNIValidator) ValidateCreate(ctx context.Context, cni *v1.IstioCNI) (webhookadmission.Warnings, error) {
+	return v.validate(ctx, nil, cni)
+}
+
+func (v *IstioCNIValidator) ValidateUpdate(ctx context.Context, oldCNI, newCNI *v1.IstioCNI) (webhookadmission.Warnings, error) {
+	if newCNI.DeletionTimestamp != nil {
+		return nil, nil
+	}
+	return v.validate(ctx, oldCNI, newCNI)
+}
+
+func (v *IstioCNIValidator) ValidateDelete(_ context.Context, _ *v1.IstioCNI) (webhookadmission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *IstioCNIValidator) validate(ctx context.Context, oldCNI, cni *v1.IstioCNI) (webhookadmission.Warnings, error) {
+	specPath := field.NewPath("spec")
+	var errs field.ErrorList
+
+	resolvedVersion, versionErrs := v.validateVersion(specPath.Child("version"), cni.Spec.Version)
+	if oldCNI == nil || oldCNI.Spec.Version != cni.Spec.Version {
+		errs = append(errs, versionErrs...)
+	}
+	if resolvedVersion != "" {
+		errs = append(errs, v.validateProfile(specPath.Child("profile"), resolvedVersion, cni.Spec.Profile)...)
+		errs = append(errs, v.validateValues(ctx, specPath.Child("values"), resolvedVersion, cniCharts, cni.Spec.Values)...)
+	}
+
+	if cni.Spec.Namespace == "" {
+		errs = append(errs, field.Required(specPath.Child("namespace"), ""))
+	}
+	errs = append(errs, validatePatches(specPath.Child("patches"), cni.Spec.Patches)...)
+
+	var oldInstance *dataplaneInstance
+	if oldCNI != nil {
+		oldInstance = ptr.Of(cniInstance(oldCNI))
+	}
+	instanceErrs, warnings := checkInstances(specPath, v1.IstioCNIKind, oldInstance, cniInstance(cni), v.otherInstances(ctx))
+	errs = append(errs, instanceErrs...)
+
+	if err := toError(v1.GroupVersion.WithKind(v1.IstioCNIKind).GroupKind(), cni.Name, errs); err != nil {
+		return nil, err
+	}
+	return append(v.checkNamespace(ctx, cni.Spec.Namespace), warnings...), nil
+}
+
+// otherInstances returns all IstioCNI resources. If they can't be listed, the checks that depend on them are
+// left to the reconciler.
+func (v *IstioCNIValidator) otherInstances(ctx context.Context) []dataplaneInstance {
+	list := v1.IstioCNIList{}
+	if err := v.client.List(ctx, &list); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list IstioCNIs")
+		return nil
+	}
+	instances := make([]dataplaneInstance, 0, len(list.Items))
+	for i := range list.Items {
+		instances = append(instances, cniInstance(&list.Items[i]))
+	}
+	return instances
+}
+
+func cniInstance(cni *v1.IstioCNI) dataplaneInstance {
+	return dataplaneInstance{name: cni.Name, namespace: cni.Spec.Namespace, nodeSelector: cni.Spec.NodeSelector}
+}
@@ -0,0 +1,149 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admission
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"github.com/istio-ecosystem/sail-operator/pkg/istiovalues"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	webhookadmission "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+// +kubebuilder:webhook:path=/validate-sailoperator-io-v1-ztunnel,mutating=false,failurePolicy=fail,sideEffects=None,groups=sailoperator.io,resources=ztunnels,verbs=create;update,versions=v1;v1alpha1,name=vztunnel.sailoperator.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-sailoperator-io-v1-ztunnel,mutating=true,failurePolicy=fail,sideEffects=None,groups=sailoperator.io,resources=ztunnels,verbs=create,versions=v1,name=mztunnel.sailoperator.io,admissionReviewVersions=v1
+
+// ztunnelValidationPath is the path of the ZTunnel validating webhook. It receives both v1 and v1alpha1 ZTunnels.
+const ztunnelValidationPath = "/validate-sailoperator-io-v1-ztunnel"
+
+// ztunnelCharts are the charts whose values are set in the ZTunnel spec.values.
+var ztunnelCharts = []istiovalues.ChartValues{
+	{Chart: "ztunnel", Prefix: "ztunnel"},
+}
+
+// ZTunnelValidator validates ZTunnel resources when they are created or updated.
+type ZTunnelValidator struct {
+	*validator
+}
+
+var _ webhookadmission.Validator[*v1.ZTunnel] = &ZTunnelValidator{}
+
+func (v *ZTunnelValidator) ValidateCreate(ctx context.Context, ztunnel *v1.ZTunnel) (webhookadmission.Warnings, error) {
+	return v.validate(ctx, nil, ztunnel)
+}
+
+func (v *ZTunnelValidator) ValidateUpdate(ctx context.Context, oldZTunnel, newZTunnel *v1.ZTunnel) (webhookadmission.Warnings, error) {
+	if newZTunnel.DeletionTimestamp != nil {
+		return nil, nil
+	}
+	return v.validate(ctx, oldZTunnel, newZTunnel)
+}
+
+func (v *ZTunnelValidator) ValidateDelete(_ context.Context, _ *v1.ZTunnel) (webhookadmission.Warnings, error) {
+	return nil, nil
+}
+
+func (v *ZTunnelValidator) validate(ctx context.Context, oldZTunnel, ztunnel *v1.ZTunnel) (webhookadmission.Warnings, error) {
+	specPath := field.NewPath("spec")
+	var errs field.ErrorList
+
+	resolvedVersion, versionErrs := v.validateVersion(specPath.Child("version"), ztunnel.Spec.Version)
+	if oldZTunnel == nil || oldZTunnel.Spec.Version != ztunnel.Spec.Version {
+		errs = append(errs, versionErrs...)
+	}
+	if resolvedVersion != "" {
+		errs = append(errs, v.validateValues(ctx, specPath.Child("values"), resolvedVersion, ztunnelCharts, ztunnel.Spec.Values)...)
+	}
+
+	if ztunnel.Spec.Namespace == "" {
+		errs = append(errs, field.Required(specPath.Child("namespace"), ""))
+	}
+	errs = append(errs, validatePatches(specPath.Child("patches"), ztunnel.Spec.Patches)...)
+
+	var oldInstance *dataplaneInstance
+	if oldZTunnel != nil {
+		oldInstance = ptr.Of(ztunnelInstance(oldZTunnel))
+	}
+	instanceErrs, warnings := checkInstances(specPath, v1.ZTunnelKind, oldInstance, ztunnelInstance(ztunnel), v.otherInstances(ctx))
+	errs = append(errs, instanceErrs...)
+
+	if err := toError(v1.GroupVersion.WithKind(v1.ZTunnelKind).GroupKind(), ztunnel.Name, errs); err != nil {
+		return nil, err
+	}
+	return append(v.checkNamespace(ctx, ztunnel.Spec.Namespace), warnings...), nil
+}
+
+// otherInstances returns all ZTunnel resources. If they can't be listed, the checks that depend on them are
+// left to the reconciler.
+func (v *ZTunnelValidator) otherInstances(ctx context.Context) []dataplaneInstance {
+	list := v1.ZTunnelList{}
+	if err := v.client.List(ctx, &list); err != nil {
+		log.FromContext(ctx).Error(err, "failed to list ZTunnels")
+		return nil
+	}
+	instances := make([]dataplaneInstance, 0, len(list.Items))
+	for i := range list.Items {
+		instances = append(instances, ztunnelInstance(&list.Items[i]))
+	}
+	return instances
+}
+
+func ztunnelInstance(ztunnel *v1.ZTunnel) dataplaneInstance {
+	return dataplaneInstance{name: ztunnel.Name, namespace: ztunnel.Spec.Namespace, nodeSelector: ztunnel.Spec.NodeSelector}
+}
+
+// v1alpha1ZTunnelConverter converts the v1alpha1 ZTunnels in admission requests to v1 before passing the requests
+// to the wrapped handler, so that ZTunnels created or updated through v1alpha1 are validated like v1 ZTunnels.
+type v1alpha1ZTunnelConverter struct {
+	webhookadmission.Handler
+}
+
+func (h v1alpha1ZTunnelConverter) Handle(ctx context.Context, req webhookadmission.Request) webhookadmission.Response {
+	if req.Kind.Version == v1alpha1.GroupVersion.Version {
+		for _, obj := range []*runtime.RawExtension{&req.Object, &req.OldObject} {
+			if len(obj.Raw) == 0 {
+				continue
+			}
+			converted, err := convertV1alpha1ZTunnel(obj.Raw)
+			if err != nil {
+				return webhookadmission.Errored(http.StatusBadRequest, err)
+			}
+			obj.Raw = converted
+		}
+	}
+	return h.Handler.Handle(ctx, req)
+}
+
+func convertV1alpha1ZTunnel(raw []byte) ([]byte, error) {
+	src := &v1alpha1.ZTunnel{}
+	if err := json.Unmarshal(raw, src); err != nil {
+		return nil, fmt.Errorf("failed to decode v1alpha1 ZTunnel: %w", err)
+	}
+	dst := &v1.ZTunnel{}
+	if err := src.ConvertTo(dst); err != nil {
+		return nil, fmt.Errorf("failed to convert v1alpha1 ZTunnel: %w", err)
+	}
+	dst.SetGroupVersionKind(v1.GroupVersion.WithKind(v1.ZTunnelKind))
+	return json.Marshal(dst)
+}
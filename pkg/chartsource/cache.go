@@ -0,0 +1,167 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsource
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"helm.sh/helm/v4/pkg/chart/loader/archive"
+	chartv2loader "helm.sh/helm/v4/pkg/chart/v2/loader"
+)
+
+const digestPrefix = "sha256:"
+
+// ArchiveKind specifies what a fetched archive contains.
+type ArchiveKind int
+
+const (
+	// ChartArchive is a Helm chart archive.
+	ChartArchive ArchiveKind = iota
+	// ProfilesArchive is an archive that contains a profiles directory with the profile YAML files.
+	ProfilesArchive
+)
+
+// VerificationError is returned when a fetched archive doesn't match its digest or doesn't have the expected
+// contents.
+type VerificationError struct {
+	Digest string
+	Err    error
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("verification of archive %s failed: %v", e.Digest, e.Err)
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
+
+// IsVerificationError returns true if the error, or any error it wraps, is a VerificationError.
+func IsVerificationError(err error) bool {
+	e := &VerificationError{}
+	return errors.As(err, &e)
+}
+
+// Cache stores verified archives, extracted, in a directory on disk. The archives are stored by digest, so an
+// archive is fetched again only when its digest changes.
+type Cache struct {
+	dir string
+}
+
+// NewCache returns a Cache that stores the archives in the given directory.
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Get returns the directory that contains the extracted archive with the given digest. The directory of a chart
+// archive is the root directory of the chart; the directory of a profiles archive contains the profile files.
+// If the archive isn't cached yet, it is fetched with the given function, verified and extracted first.
+func (c *Cache) Get(digest string, kind ArchiveKind, fetch func() ([]byte, error)) (string, error) {
+	hexDigest, found := strings.CutPrefix(digest, digestPrefix)
+	if !found {
+		return "", fmt.Errorf("unsupported digest %q; only %s digests are supported", digest, strings.TrimSuffix(digestPrefix, ":"))
+	}
+
+	dir := filepath.Join(c.dir, hexDigest)
+	if _, err := os.Stat(dir); err == nil {
+		return dir, nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	data, err := fetch()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != hexDigest {
+		return "", &VerificationError{Digest: digest, Err: fmt.Errorf("archive has digest %s%s", digestPrefix, actual)}
+	}
+
+	files, err := archive.LoadArchiveFiles(bytes.NewReader(data))
+	if err != nil {
+		return "", &VerificationError{Digest: digest, Err: err}
+	}
+	if err := verifyContents(kind, files); err != nil {
+		return "", &VerificationError{Digest: digest, Err: err}
+	}
+
+	if err := c.extract(dir, files); err != nil {
+		return "", fmt.Errorf("failed to store archive %s in cache: %w", digest, err)
+	}
+	return dir, nil
+}
+
+func verifyContents(kind ArchiveKind, files []*archive.BufferedFile) error {
+	switch kind {
+	case ChartArchive:
+		_, err := chartv2loader.LoadFiles(files)
+		return err
+	case ProfilesArchive:
+		hasDefault := false
+		for _, f := range files {
+			if strings.Contains(f.Name, "/") || filepath.Ext(f.Name) != ".yaml" {
+				return fmt.Errorf("profiles archive contains unexpected file %q", f.Name)
+			}
+			hasDefault = hasDefault || f.Name == "default.yaml"
+		}
+		if !hasDefault {
+			return errors.New("profiles archive doesn't contain the default profile")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown archive kind %d", kind)
+	}
+}
+
+// extract writes the files to a temporary directory first and then renames it, so that a partially extracted
+// archive is never mistaken for a cached one.
+func (c *Cache) extract(dir string, files []*archive.BufferedFile) error {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return err
+	}
+	tmpDir, err := os.MkdirTemp(c.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, f := range files {
+		// archive.LoadArchiveFiles rejects absolute paths and paths outside the archive's base directory
+		file := filepath.Join(tmpDir, filepath.FromSlash(f.Name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, f.Data, 0o644); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(tmpDir, dir); err != nil {
+		if _, statErr := os.Stat(dir); statErr == nil {
+			// the archive was extracted concurrently
+			return nil
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsource
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testChartYAML = `apiVersion: v2
+name: istiod
+version: 1.99.0
+`
+
+// archiveOf returns a tar.gz archive with the given files and its sha256 digest
+func archiveOf(t *testing.T, files map[string]string) ([]byte, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	sum := sha256.Sum256(buf.Bytes())
+	return buf.Bytes(), digestPrefix + hex.EncodeToString(sum[:])
+}
+
+func chartArchive(t *testing.T) ([]byte, string) {
+	return archiveOf(t, map[string]string{
+		"istiod/Chart.yaml":               testChartYAML,
+		"istiod/templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\n",
+	})
+}
+
+func TestCacheGet(t *testing.T) {
+	data, digest := chartArchive(t)
+
+	t.Run("fetches, verifies and extracts archive", func(t *testing.T) {
+		cache := NewCache(t.TempDir())
+		fetched := 0
+		fetch := func() ([]byte, error) {
+			fetched++
+			return data, nil
+		}
+
+		dir, err := cache.Get(digest, ChartArchive, fetch)
+		require.NoError(t, err)
+		content, err := os.ReadFile(filepath.Join(dir, "Chart.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, testChartYAML, string(content))
+		assert.FileExists(t, filepath.Join(dir, "templates", "configmap.yaml"))
+
+		cachedDir, err := cache.Get(digest, ChartArchive, fetch)
+		require.NoError(t, err)
+		assert.Equal(t, dir, cachedDir)
+		assert.Equal(t, 1, fetched, "cached archive should not be fetched again")
+	})
+
+	t.Run("rejects archive that doesn't match digest", func(t *testing.T) {
+		_, otherDigest := archiveOf(t, map[string]string{"other/Chart.yaml": testChartYAML})
+		cache := NewCache(t.TempDir())
+
+		_, err := cache.Get(otherDigest, ChartArchive, func() ([]byte, error) { return data, nil })
+		assert.True(t, IsVerificationError(err), "expected VerificationError, got %v", err)
+		entries, err := os.ReadDir(cache.dir)
+		require.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("rejects chart archive without Chart.yaml", func(t *testing.T) {
+		invalid, invalidDigest := archiveOf(t, map[string]string{"istiod/values.yaml": "foo: bar\n"})
+		_, err := NewCache(t.TempDir()).Get(invalidDigest, ChartArchive, func() ([]byte, error) { return invalid, nil })
+		assert.True(t, IsVerificationError(err), "expected VerificationError, got %v", err)
+	})
+
+	t.Run("extracts profiles archive", func(t *testing.T) {
+		profiles, profilesDigest := archiveOf(t, map[string]string{
+			"profiles/default.yaml": "spec: {}\n",
+			"profiles/ambient.yaml": "spec: {}\n",
+		})
+		dir, err := NewCache(t.TempDir()).Get(profilesDigest, ProfilesArchive, func() ([]byte, error) { return profiles, nil })
+		require.NoError(t, err)
+		assert.FileExists(t, filepath.Join(dir, "default.yaml"))
+		assert.FileExists(t, filepath.Join(dir, "ambient.yaml"))
+	})
+
+	t.Run("rejects profiles archive without default profile", func(t *testing.T) {
+		profiles, profilesDigest := archiveOf(t, map[string]string{"profiles/ambient.yaml": "spec: {}\n"})
+		_, err := NewCache(t.TempDir()).Get(profilesDigest, ProfilesArchive, func() ([]byte, error) { return profiles, nil })
+		assert.True(t, IsVerificationError(err), "expected VerificationError, got %v", err)
+	})
+
+	t.Run("returns fetch error", func(t *testing.T) {
+		fetchErr := errors.New("connection refused")
+		_, err := NewCache(t.TempDir()).Get(digest, ChartArchive, func() ([]byte, error) { return nil, fetchErr })
+		assert.ErrorIs(t, err, fetchErr)
+		assert.False(t, IsVerificationError(err))
+	})
+
+	t.Run("rejects unsupported digest", func(t *testing.T) {
+		_, err := NewCache(t.TempDir()).Get("md5:abc", ChartArchive, func() ([]byte, error) { return data, nil })
+		assert.ErrorContains(t, err, "unsupported digest")
+	})
+}
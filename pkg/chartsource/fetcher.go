@@ -0,0 +1,180 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"helm.sh/helm/v4/pkg/registry"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+const (
+	// fetchTimeout is the maximum time it may take to fetch a single archive
+	fetchTimeout = 2 * time.Minute
+
+	// maxArchiveSize is the maximum size of a fetched archive
+	maxArchiveSize = 50 * 1024 * 1024
+)
+
+// archiveLayerMediaTypes are the media types of the OCI layers that may contain a chart or profiles archive
+var archiveLayerMediaTypes = []string{
+	registry.ChartLayerMediaType,
+	registry.LegacyChartLayerMediaType,
+	ocispec.MediaTypeImageLayerGzip,
+}
+
+// Fetcher downloads archives from a chart repository.
+type Fetcher interface {
+	// Fetch returns the archive containing the given version of the named chart.
+	Fetch(ctx context.Context, name, version string) ([]byte, error)
+}
+
+// Credentials are used to authenticate with a chart repository.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// NewFetcher returns a Fetcher for the repository described by the given spec. If creds is nil, the
+// repository is accessed anonymously.
+func NewFetcher(spec v1alpha1.IstioChartSourceSpec, creds *Credentials) (Fetcher, error) {
+	switch spec.Type {
+	case v1alpha1.ChartSourceTypeHTTP:
+		return &httpFetcher{
+			url:    strings.TrimSuffix(spec.URL, "/"),
+			creds:  creds,
+			client: &http.Client{Timeout: fetchTimeout},
+		}, nil
+	case v1alpha1.ChartSourceTypeOCI:
+		client := &auth.Client{
+			Client: &http.Client{Timeout: fetchTimeout},
+			Cache:  auth.NewCache(),
+		}
+		if creds != nil {
+			client.Credential = func(context.Context, string) (auth.Credential, error) {
+				return auth.Credential{Username: creds.Username, Password: creds.Password}, nil
+			}
+		}
+		return &ociFetcher{
+			url:       strings.TrimSuffix(strings.TrimPrefix(spec.URL, registry.OCIScheme+"://"), "/"),
+			plainHTTP: spec.PlainHTTP,
+			client:    client,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported chart source type %q", spec.Type)
+	}
+}
+
+// httpFetcher downloads chart archives from a Helm chart repository served over HTTP(S).
+type httpFetcher struct {
+	url    string
+	creds  *Credentials
+	client *http.Client
+}
+
+func (f *httpFetcher) Fetch(ctx context.Context, name, version string) ([]byte, error) {
+	archiveURL := fmt.Sprintf("%s/%s-%s.tgz", f.url, name, version)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, archiveURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if f.creds != nil {
+		req.SetBasicAuth(f.creds.Username, f.creds.Password)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", archiveURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", archiveURL, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxArchiveSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", archiveURL, err)
+	}
+	if len(data) > maxArchiveSize {
+		return nil, fmt.Errorf("archive %s is larger than the maximum size %d", archiveURL, maxArchiveSize)
+	}
+	return data, nil
+}
+
+// ociFetcher pulls chart archives stored as OCI artifacts from a registry. It uses oras directly instead of
+// Helm's registry client, because the latter doesn't allow pulls to be cancelled through a context.
+type ociFetcher struct {
+	url       string
+	plainHTTP bool
+	client    *auth.Client
+}
+
+func (f *ociFetcher) Fetch(ctx context.Context, name, version string) ([]byte, error) {
+	ref := fmt.Sprintf("%s/%s:%s", f.url, name, version)
+	repository, err := remote.NewRepository(f.url + "/" + name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reference %s: %w", ref, err)
+	}
+	repository.PlainHTTP = f.plainHTTP
+	repository.Client = f.client
+
+	manifestDesc, manifestData, err := oras.FetchBytes(ctx, repository, version, oras.DefaultFetchBytesOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pull %s: %w", ref, err)
+	}
+	if manifestDesc.MediaType != ocispec.MediaTypeImageManifest {
+		return nil, fmt.Errorf("artifact %s has unsupported media type %s", ref, manifestDesc.MediaType)
+	}
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest of %s: %w", ref, err)
+	}
+
+	var layer *ocispec.Descriptor
+	for i, desc := range manifest.Layers {
+		if !slices.Contains(archiveLayerMediaTypes, desc.MediaType) {
+			continue
+		}
+		if layer != nil {
+			return nil, fmt.Errorf("artifact %s contains more than one archive layer", ref)
+		}
+		layer = &manifest.Layers[i]
+	}
+	if layer == nil {
+		return nil, fmt.Errorf("artifact %s doesn't contain an archive layer", ref)
+	}
+	if layer.Size > maxArchiveSize {
+		return nil, fmt.Errorf("layer %s is larger than the maximum size %d", layer.Digest, maxArchiveSize)
+	}
+
+	data, err := content.FetchAll(ctx, repository, *layer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read layer %s of %s: %w", layer.Digest, ref, err)
+	}
+	return data, nil
+}
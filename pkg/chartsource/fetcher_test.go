@@ -0,0 +1,199 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsource
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"helm.sh/helm/v4/pkg/registry"
+)
+
+func TestHTTPFetcher(t *testing.T) {
+	data, _ := chartArchive(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/charts/istiod-1.99.0.tgz" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+
+	spec := v1alpha1.IstioChartSourceSpec{Type: v1alpha1.ChartSourceTypeHTTP, URL: server.URL + "/charts/"}
+
+	t.Run("fetches archive", func(t *testing.T) {
+		fetcher, err := NewFetcher(spec, &Credentials{Username: "user", Password: "secret"})
+		require.NoError(t, err)
+		fetched, err := fetcher.Fetch(context.Background(), "istiod", "1.99.0")
+		require.NoError(t, err)
+		assert.Equal(t, data, fetched)
+	})
+
+	t.Run("returns error for missing archive", func(t *testing.T) {
+		fetcher, err := NewFetcher(spec, &Credentials{Username: "user", Password: "secret"})
+		require.NoError(t, err)
+		_, err = fetcher.Fetch(context.Background(), "istiod", "1.98.0")
+		assert.ErrorContains(t, err, "404")
+	})
+
+	t.Run("returns error without credentials", func(t *testing.T) {
+		fetcher, err := NewFetcher(spec, nil)
+		require.NoError(t, err)
+		_, err = fetcher.Fetch(context.Background(), "istiod", "1.99.0")
+		assert.ErrorContains(t, err, "401")
+	})
+}
+
+// ociRegistry is a minimal stand-in for an OCI distribution registry that serves artifacts by repository and tag
+type ociRegistry struct {
+	manifests map[string][]byte // keyed by "<repository>:<tag>"
+	blobs     map[digest.Digest][]byte
+}
+
+func newOCIRegistry() *ociRegistry {
+	return &ociRegistry{manifests: map[string][]byte{}, blobs: map[digest.Digest][]byte{}}
+}
+
+func (o *ociRegistry) addBlob(data []byte) ocispec.Descriptor {
+	d := digest.FromBytes(data)
+	o.blobs[d] = data
+	return ocispec.Descriptor{Digest: d, Size: int64(len(data))}
+}
+
+// push stores an artifact with a single layer of the given media type
+func (o *ociRegistry) push(t *testing.T, repository, tag, layerMediaType string, layer []byte) {
+	config := o.addBlob([]byte(`{"name":"istiod","version":"1.99.0"}`))
+	config.MediaType = registry.ConfigMediaType
+	layerDesc := o.addBlob(layer)
+	layerDesc.MediaType = layerMediaType
+
+	manifest, err := json.Marshal(ocispec.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: ocispec.MediaTypeImageManifest,
+		Config:    config,
+		Layers:    []ocispec.Descriptor{layerDesc},
+	})
+	require.NoError(t, err)
+	o.manifests[repository+":"+tag] = manifest
+}
+
+func (o *ociRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v2/")
+	if path == "" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var data []byte
+	var mediaType string
+	if repository, ref, ok := strings.Cut(path, "/manifests/"); ok {
+		data = o.manifests[repository+":"+ref]
+		if data == nil {
+			// manifests are also fetched by digest after the tag is resolved
+			for _, m := range o.manifests {
+				if digest.FromBytes(m).String() == ref {
+					data = m
+				}
+			}
+		}
+		mediaType = ocispec.MediaTypeImageManifest
+	} else if _, ref, ok := strings.Cut(path, "/blobs/"); ok {
+		data = o.blobs[digest.Digest(ref)]
+		mediaType = "application/octet-stream"
+	}
+	if data == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Set("Docker-Content-Digest", digest.FromBytes(data).String())
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(data)
+	}
+}
+
+func TestOCIFetcher(t *testing.T) {
+	data, _ := chartArchive(t)
+	profiles, _ := archiveOf(t, map[string]string{"profiles/default.yaml": "spec: {}\n"})
+
+	reg := newOCIRegistry()
+	reg.push(t, "charts/istiod", "1.99.0", registry.ChartLayerMediaType, data)
+	reg.push(t, "charts/profiles", "1.99.0", ocispec.MediaTypeImageLayerGzip, profiles)
+	server := httptest.NewServer(reg)
+	defer server.Close()
+
+	spec := v1alpha1.IstioChartSourceSpec{
+		Type:      v1alpha1.ChartSourceTypeOCI,
+		URL:       "oci://" + strings.TrimPrefix(server.URL, "http://") + "/charts",
+		PlainHTTP: true,
+	}
+	fetcher, err := NewFetcher(spec, nil)
+	require.NoError(t, err)
+
+	t.Run("pulls chart", func(t *testing.T) {
+		fetched, err := fetcher.Fetch(context.Background(), "istiod", "1.99.0")
+		require.NoError(t, err)
+		assert.Equal(t, data, fetched)
+	})
+
+	t.Run("pulls profiles stored as an OCI layer", func(t *testing.T) {
+		fetched, err := fetcher.Fetch(context.Background(), "profiles", "1.99.0")
+		require.NoError(t, err)
+		assert.Equal(t, profiles, fetched)
+	})
+
+	t.Run("returns error for missing artifact", func(t *testing.T) {
+		_, err := fetcher.Fetch(context.Background(), "istiod", "1.98.0")
+		assert.Error(t, err)
+	})
+
+	t.Run("stops when the context is cancelled", func(t *testing.T) {
+		hanging := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+		defer hanging.Close()
+
+		fetcher, err := NewFetcher(v1alpha1.IstioChartSourceSpec{
+			Type:      v1alpha1.ChartSourceTypeOCI,
+			URL:       "oci://" + strings.TrimPrefix(hanging.URL, "http://") + "/charts",
+			PlainHTTP: true,
+		}, nil)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		_, err = fetcher.Fetch(ctx, "istiod", "1.99.0")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
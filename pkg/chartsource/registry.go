@@ -0,0 +1,201 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsource
+
+import (
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+// Version describes where the charts and profiles of a version provided by an IstioChartSource are stored.
+type Version struct {
+	// Name of the version, e.g. v1.30.4
+	Name string
+	// Charts maps the name of each chart to the directory that contains it
+	Charts map[string]string
+	// ProfilesDir is the directory that contains the profiles. If empty, the profiles of ProfilesFrom are used.
+	ProfilesDir string
+	// ProfilesFrom is the version in the base filesystem whose profiles are used when ProfilesDir is empty.
+	ProfilesFrom string
+}
+
+// Registry is an fs.FS that serves the charts and profiles of the versions provided by IstioChartSources in
+// addition to the resources in the base filesystem (usually the embedded resources), so that it can be used as
+// the ResourceFS of the reconcilers. The versions registered in the Registry are also registered in
+// istioversion, so that they pass version validation.
+type Registry struct {
+	base fs.FS
+
+	mu       sync.RWMutex
+	sources  map[string][]Version
+	notifier reconciler.Notifier
+}
+
+var _ fs.FS = &Registry{}
+
+// NewRegistry returns a Registry that serves the resources in the given filesystem in addition to the
+// registered versions.
+func NewRegistry(base fs.FS) *Registry {
+	return &Registry{
+		base:    base,
+		sources: map[string][]Version{},
+	}
+}
+
+// Set registers the versions provided by the given source, replacing the versions it registered previously.
+func (r *Registry) Set(source string, versions []Version) error {
+	infos := make([]istioversion.VersionInfo, 0, len(versions))
+	for _, v := range versions {
+		semVer, err := semver.NewVersion(strings.TrimPrefix(v.Name, "v"))
+		if err != nil {
+			return fmt.Errorf("invalid version %q: %w", v.Name, err)
+		}
+		infos = append(infos, istioversion.VersionInfo{Name: v.Name, Version: semVer})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(versions) == 0 {
+		delete(r.sources, source)
+	} else {
+		r.sources[source] = slices.Clone(versions)
+	}
+	istioversion.SetExternalVersions(source, infos)
+	r.notifier.Notify(&v1alpha1.IstioChartSource{ObjectMeta: metav1.ObjectMeta{Name: source}})
+	return nil
+}
+
+// Remove unregisters all versions provided by the given source.
+func (r *Registry) Remove(source string) {
+	// Set can only fail when parsing the given versions
+	_ = r.Set(source, nil)
+}
+
+// Versions returns the names of the versions registered by the given source.
+func (r *Registry) Versions(source string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var names []string
+	for _, v := range r.sources[source] {
+		names = append(names, v.Name)
+	}
+	return names
+}
+
+// Owner returns the name of the source that registered the given version, or an empty string if no source
+// registered it.
+func (r *Registry) Owner(version string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for source, versions := range r.sources {
+		if slices.ContainsFunc(versions, func(v Version) bool { return v.Name == version }) {
+			return source
+		}
+	}
+	return ""
+}
+
+// Digest returns a string that identifies the charts and profiles served for the given version. It changes
+// whenever a source registers different charts or profiles for the version, because the archives of a version
+// are stored in directories named after their digests. Digest returns an empty string if the Registry is nil or
+// no source registered the version, in which case the version is served from the base filesystem.
+func (r *Registry) Digest(version string) string {
+	if r == nil {
+		return ""
+	}
+	v, found := r.lookup(version)
+	if !found {
+		return ""
+	}
+	var sb strings.Builder
+	for _, chart := range slices.Sorted(maps.Keys(v.Charts)) {
+		fmt.Fprintf(&sb, "%s=%s;", chart, v.Charts[chart])
+	}
+	fmt.Fprintf(&sb, "profiles=%s;profilesFrom=%s", v.ProfilesDir, v.ProfilesFrom)
+	return sb.String()
+}
+
+// Watch adds a watch to the controller built by b, which passes an event to the given handler whenever the
+// versions registered by a source change. The object in the event is an IstioChartSource that only has its name
+// set. Watch does nothing if the Registry is nil, i.e. if chart sources are disabled.
+func (r *Registry) Watch(b *builder.Builder, h handler.EventHandler) *builder.Builder {
+	if r == nil {
+		return b
+	}
+	return r.notifier.Watch(b, h)
+}
+
+// Open implements fs.FS. Paths below a registered version are served from the directories the version's charts
+// and profiles are stored in; all other paths are served from the base filesystem.
+func (r *Registry) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	versionName, rest, _ := strings.Cut(name, "/")
+	version, found := r.lookup(versionName)
+	if !found {
+		return r.base.Open(name)
+	}
+
+	dir, rest, _ := strings.Cut(rest, "/")
+	switch dir {
+	case "charts":
+		chartName, chartPath, _ := strings.Cut(rest, "/")
+		if chartDir, ok := version.Charts[chartName]; ok {
+			return os.DirFS(chartDir).Open(orDot(chartPath))
+		}
+	case "profiles":
+		if version.ProfilesDir != "" {
+			return os.DirFS(version.ProfilesDir).Open(orDot(rest))
+		}
+		return r.base.Open(path.Join(version.ProfilesFrom, "profiles", rest))
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (r *Registry) lookup(name string) (Version, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, versions := range r.sources {
+		for _, v := range versions {
+			if v.Name == name {
+				return v, true
+			}
+		}
+	}
+	return Version{}, false
+}
+
+func orDot(p string) string {
+	if p == "" {
+		return "."
+	}
+	return p
+}
@@ -0,0 +1,114 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chartsource
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	base := fstest.MapFS{
+		"v1.99.0/charts/istiod/Chart.yaml": {Data: []byte(testChartYAML)},
+		"v1.99.0/profiles/default.yaml":    {Data: []byte("embedded: true\n")},
+	}
+
+	chartDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(chartDir, "templates"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(chartDir, "Chart.yaml"), []byte(testChartYAML), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(chartDir, "templates", "cm.yaml"), []byte("kind: ConfigMap\n"), 0o644))
+	profilesDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(profilesDir, "default.yaml"), []byte("external: true\n"), 0o644))
+
+	registry := NewRegistry(base)
+	t.Cleanup(func() { registry.Remove("test") })
+	events := registry.notifier.Subscribe()
+
+	require.NoError(t, registry.Set("test", []Version{
+		{Name: "v1.99.1", Charts: map[string]string{"istiod": chartDir}, ProfilesDir: profilesDir},
+		{Name: "v1.99.2", Charts: map[string]string{"istiod": chartDir}, ProfilesFrom: "v1.99.0"},
+	}))
+
+	t.Run("notifies subscribers", func(t *testing.T) {
+		select {
+		case evt := <-events:
+			assert.Equal(t, "test", evt.Object.GetName())
+		default:
+			t.Fatal("expected an event")
+		}
+	})
+
+	t.Run("registers versions in istioversion", func(t *testing.T) {
+		assert.NoError(t, istioversion.ValidateVersion("v1.99.1"))
+		assert.NoError(t, istioversion.ValidateVersion("v1.99.2"))
+		assert.Equal(t, []string{"v1.99.1", "v1.99.2"}, registry.Versions("test"))
+		assert.Equal(t, "test", registry.Owner("v1.99.1"))
+		assert.Equal(t, "", registry.Owner("v1.99.0"))
+	})
+
+	t.Run("serves charts of registered versions", func(t *testing.T) {
+		chart, err := helm.LoadChart(registry, "v1.99.1/charts/istiod")
+		require.NoError(t, err)
+		assert.Equal(t, "istiod", chart.Metadata.Name)
+		assert.Len(t, chart.Templates, 1)
+
+		_, err = fs.ReadFile(registry, "v1.99.1/charts/cni/Chart.yaml")
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("serves profiles of registered versions", func(t *testing.T) {
+		data, err := fs.ReadFile(registry, "v1.99.1/profiles/default.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, "external: true\n", string(data))
+
+		data, err = fs.ReadFile(registry, "v1.99.2/profiles/default.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, "embedded: true\n", string(data), "profiles should be taken from ProfilesFrom")
+	})
+
+	t.Run("serves other paths from base filesystem", func(t *testing.T) {
+		data, err := fs.ReadFile(registry, "v1.99.0/profiles/default.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, "embedded: true\n", string(data))
+	})
+
+	t.Run("rejects invalid paths", func(t *testing.T) {
+		_, err := registry.Open("v1.99.1/charts/istiod/../../../etc/passwd")
+		assert.ErrorIs(t, err, fs.ErrInvalid)
+	})
+
+	t.Run("identifies the content of registered versions", func(t *testing.T) {
+		assert.NotEqual(t, registry.Digest("v1.99.1"), registry.Digest("v1.99.2"))
+		assert.Empty(t, registry.Digest("v1.99.0"), "versions in the base filesystem don't have a digest")
+		assert.Empty(t, (*Registry)(nil).Digest("v1.99.1"))
+	})
+
+	t.Run("unregisters versions", func(t *testing.T) {
+		registry.Remove("test")
+		assert.Empty(t, registry.Digest("v1.99.1"))
+		assert.Error(t, istioversion.ValidateVersion("v1.99.1"))
+		assert.Empty(t, registry.Versions("test"))
+		_, err := fs.ReadFile(registry, "v1.99.1/charts/istiod/Chart.yaml")
+		assert.ErrorIs(t, err, fs.ErrNotExist)
+	})
+}
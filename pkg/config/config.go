@@ -18,6 +18,8 @@ import (
 	"io/fs"
 	"strings"
 
+	"github.com/istio-ecosystem/sail-operator/pkg/chartsource"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
 	"github.com/magiconair/properties"
 )
 
@@ -41,6 +43,14 @@ type ReconcilerConfig struct {
 	OperatorNamespace       string
 	MaxConcurrentReconciles int
 	TLSConfig               *TLSConfig
+	// ChartSources holds the versions fetched from IstioChartSources. It is also the ResourceFS when chart
+	// sources are enabled; nil otherwise.
+	ChartSources *chartsource.Registry
+	// VersionCatalog notifies the reconcilers when the IstioVersionCatalog changes; nil if the catalog is disabled.
+	VersionCatalog *reconciler.Notifier
+	// GatewayAPIAvailable is true if the Gateway API CRDs were installed when the operator started. Waypoint
+	// Gateways are only watched and taken into account when determining whether a revision is in use if they were.
+	GatewayAPIAvailable bool
 }
 
 func Read(configFile string) error {
@@ -43,6 +43,10 @@ const (
 	// InternalKey is used to identify the resource as being internal to the mesh itself (i.e. should not be applied to members)
 	InternalKey = MetadataNamespace + "/internal"
 
+	// IstioVersionKey is used in pod annotations to record the version of the control plane that injects the proxy.
+	// Changing it restarts the pods, so that they are injected with the proxy of the new version.
+	IstioVersionKey = MetadataNamespace + "/istio-version"
+
 	// FinalizerName is the finalizer name the controllers add to any resources that need to be finalized during deletion
 	FinalizerName = MetadataNamespace + "/sail-operator"
 
@@ -80,6 +84,37 @@ const (
 	// specifies the timeout for the readiness probe
 	WebhookReadinessProbeTimeoutSecondsAnnotationKey = MetadataNamespace + "/readinessProbe.timeoutSeconds"
 
+	// WorkloadRevisionAnnotationKey is an annotation the operator sets on a workload and its pod template when it
+	// restarts the workload to move it to a new IstioRevision. The value is the name of the target IstioRevision.
+	WorkloadRevisionAnnotationKey = MetadataNamespace + "/workload-revision"
+
+	// WorkloadRestartedAtAnnotationKey is an annotation the operator sets on a workload when it restarts the
+	// workload to move it to a new IstioRevision. The value is the time of the restart in RFC 3339 format.
+	WorkloadRestartedAtAnnotationKey = MetadataNamespace + "/workload-restarted-at"
+
+	// MonitoringAnnotationKey is an annotation on the Istio resource that enables the creation of ServiceMonitor and
+	// PodMonitor resources for the IstioRevisions owned by the Istio resource
+	MonitoringAnnotationKey = MetadataNamespace + "/monitoring"
+
+	// MonitoringEnabledValue is the value of MonitoringAnnotationKey that enables monitoring
+	MonitoringEnabledValue = "enabled"
+
+	// DryRunAnnotationKey is an annotation on the Istio and IstioRevision resources. When it's set to "true", the operator
+	// doesn't install the Helm charts of the IstioRevision, but reports the changes that it would make in the status
+	DryRunAnnotationKey = MetadataNamespace + "/dry-run"
+
+	// RollbackAnnotationKey is an annotation on the IstioRevision, IstioCNI and ZTunnel resources. When it's set to
+	// the number of a revision in status.releaseHistory, the operator rolls the Helm release back to that revision
+	// and stops applying the spec until the annotation is removed
+	RollbackAnnotationKey = MetadataNamespace + "/rollback-to"
+
+	// KeptFieldsAnnotationKey is an annotation that the operator adds to the objects deployed by a Helm chart. It lists
+	// the drifted fields whose live values were kept in the release manifest because of the drift policy
+	KeptFieldsAnnotationKey = MetadataNamespace + "/kept-fields"
+
+	// RestartedAtAnnotationKey is the pod template annotation used by `kubectl rollout restart` to trigger a rollout
+	RestartedAtAnnotationKey = "kubectl.kubernetes.io/restartedAt"
+
 	// IstioInjectionLabel is the label that is used to configure injection for the 'default' IstioRevision
 	IstioInjectionLabel = "istio-injection"
 
@@ -92,6 +127,18 @@ const (
 	// IstioSidecarInjectLabel is the label that is used to configure injection for specific workloads
 	IstioSidecarInjectLabel = "sidecar.istio.io/inject"
 
+	// IstioDataplaneModeLabel is the label that is used to enroll namespaces and workloads in the ambient mesh
+	IstioDataplaneModeLabel = "istio.io/dataplane-mode"
+
+	// IstioDataplaneModeAmbient is the value for IstioDataplaneModeLabel that enrolls a namespace or workload in the ambient mesh
+	IstioDataplaneModeAmbient = "ambient"
+
+	// IstioUseWaypointLabel is the label that is used to configure the waypoint of a namespace or workload
+	IstioUseWaypointLabel = "istio.io/use-waypoint"
+
+	// IstioWaypointGatewayClassName is the name of the GatewayClass that Istio uses for waypoint proxies
+	IstioWaypointGatewayClassName = "istio-waypoint"
+
 	// IstiodChartName is the name of the chart that installs istiod
 	IstiodChartName = "istiod"
 
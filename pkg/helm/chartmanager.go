@@ -19,6 +19,8 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"strconv"
+	"sync"
 
 	"github.com/go-logr/logr"
 	"github.com/istio-ecosystem/sail-operator/pkg/constants"
@@ -33,6 +35,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -40,16 +43,43 @@ import (
 // reconciliation loop: install/upgrade and uninstall.
 type ChartReconciler interface {
 	UpgradeOrInstallChart(ctx context.Context, resourceFS fs.FS, chartPath string, values Values,
-		namespace, releaseName string, ownerReference *metav1.OwnerReference) (release.Releaser, error)
+		namespace, releaseName string, ownerReference *metav1.OwnerReference, opts ...ChartOption) (release.Releaser, error)
 	UninstallChart(ctx context.Context, releaseName, namespace string) (*release.UninstallReleaseResponse, error)
 }
 
+// ChartPlanner is implemented by chart managers that can compute the changes an install or upgrade
+// would make to a Helm release without applying them.
+type ChartPlanner interface {
+	PlanChart(ctx context.Context, resourceFS fs.FS, chartPath string, values Values,
+		namespace, releaseName string, ownerReference *metav1.OwnerReference, opts ...ChartOption) (ReleaseDiff, error)
+}
+
+// DriftDetector is implemented by chart managers that can compare the live objects of a Helm release
+// with the manifest of the release.
+type DriftDetector interface {
+	DetectDrift(ctx context.Context, cl client.Client, namespace, releaseName string) ([]Drift, error)
+}
+
+// ManifestGetter is implemented by chart managers that can return the manifest of an installed Helm release.
+type ManifestGetter interface {
+	GetManifest(ctx context.Context, namespace, releaseName string) (string, error)
+}
+
 type ChartManager struct {
 	restClientGetter genericclioptions.RESTClientGetter
 	driver           string
 	managedByValue   string
+	maxHistory       int
+
+	// fieldOwnerReader is set when the ChartManager uses server-side apply; see WithServerSideApply.
+	fieldOwnerReader client.Reader
+	conflictsMu      sync.Mutex
+	conflicts        map[string][]FieldConflict
 }
 
+// DefaultMaxHistory is the default number of revisions that are retained in the history of each Helm release.
+const DefaultMaxHistory = 10
+
 // ChartManagerOption is a functional option for configuring a ChartManager.
 type ChartManagerOption func(*ChartManager)
 
@@ -62,6 +92,47 @@ func WithManagedByValue(v string) ChartManagerOption {
 	}
 }
 
+// WithMaxHistory sets the maximum number of revisions that are retained in the history of each Helm
+// release. Older revisions are pruned on every upgrade or rollback. A value of 0 retains all revisions.
+// The default value is DefaultMaxHistory.
+func WithMaxHistory(n int) ChartManagerOption {
+	return func(cm *ChartManager) {
+		cm.maxHistory = n
+	}
+}
+
+// ChartOption is a functional option for a single install, upgrade or plan of a chart.
+type ChartOption func(*chartOptions)
+
+type chartOptions struct {
+	patches                 []Patch
+	clusterScopedNameSuffix string
+	keptFields              []Drift
+}
+
+// WithPatches applies the given patches to the rendered manifests before they are applied to the cluster.
+func WithPatches(patches ...Patch) ChartOption {
+	return func(o *chartOptions) {
+		o.patches = append(o.patches, patches...)
+	}
+}
+
+// WithClusterScopedNameSuffix appends the given suffix to the names of the ClusterRoles and ClusterRoleBindings
+// rendered from the chart, so that several releases of the same chart can be installed in different namespaces.
+func WithClusterScopedNameSuffix(suffix string) ChartOption {
+	return func(o *chartOptions) {
+		o.clusterScopedNameSuffix = suffix
+	}
+}
+
+func newChartOptions(opts []ChartOption) chartOptions {
+	o := chartOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
 // NewChartManager creates a new Helm chart manager using cfg as the configuration
 // that Helm will use to connect to the cluster when installing or uninstalling
 // charts, and using the specified driver to store information about releases
@@ -71,6 +142,7 @@ func NewChartManager(cfg *rest.Config, driver string, opts ...ChartManagerOption
 		restClientGetter: NewRESTClientGetter(cfg),
 		driver:           driver,
 		managedByValue:   constants.ManagedByLabelValue,
+		maxHistory:       DefaultMaxHistory,
 	}
 	for _, o := range opts {
 		o(cm)
@@ -90,20 +162,20 @@ func (h *ChartManager) newActionConfig(ctx context.Context, namespace string) (*
 // It loads the chart from an fs.FS (e.g., embed.FS or os.DirFS).
 func (h *ChartManager) UpgradeOrInstallChart(
 	ctx context.Context, resourceFS fs.FS, chartPath string, values Values,
-	namespace, releaseName string, ownerReference *metav1.OwnerReference,
+	namespace, releaseName string, ownerReference *metav1.OwnerReference, opts ...ChartOption,
 ) (release.Releaser, error) {
 	loadedChart, err := LoadChart(resourceFS, chartPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load chart from fs: %w", err)
 	}
 
-	return h.upgradeOrInstallChart(ctx, loadedChart, values, namespace, releaseName, ownerReference)
+	return h.upgradeOrInstallChart(ctx, loadedChart, values, namespace, releaseName, ownerReference, newChartOptions(opts))
 }
 
 // upgradeOrInstallChart is the internal implementation that works with an already-loaded chart
 func (h *ChartManager) upgradeOrInstallChart(
 	ctx context.Context, chart *chartv2.Chart, values Values,
-	namespace, releaseName string, ownerReference *metav1.OwnerReference,
+	namespace, releaseName string, ownerReference *metav1.OwnerReference, opts chartOptions,
 ) (release.Releaser, error) {
 	log := logf.FromContext(ctx)
 
@@ -149,10 +221,7 @@ func (h *ChartManager) upgradeOrInstallChart(
 		break
 	case relV1.Info.Status == releasecommon.StatusFailed && relV1.Version > 1:
 		log.V(2).Info("Performing helm rollback", "release", releaseName)
-		rollbackAction := action.NewRollback(cfg)
-		rollbackAction.WaitStrategy = kube.HookOnlyStrategy
-		rollbackAction.WaitForJobs = false
-		rollbackAction.ServerSideApply = "false"
+		rollbackAction := h.newRollback(cfg)
 		if err := rollbackAction.Run(releaseName); err != nil {
 			return nil, fmt.Errorf("failed to roll back helm release %s: %w", releaseName, err)
 		}
@@ -169,16 +238,18 @@ func (h *ChartManager) upgradeOrInstallChart(
 		return nil, fmt.Errorf("unexpected helm release status %s", relV1.Info.Status)
 	}
 
+	var filter *conflictFilter
 	if releaseExists {
 		log.V(2).Info("Performing helm upgrade", "chartName", chart.Name())
 
 		updateAction := action.NewUpgrade(cfg)
-		updateAction.PostRenderer = NewHelmPostRenderer(ownerReference, "", true, h.managedByValue)
-		updateAction.MaxHistory = 1
+		filter = h.newConflictFilter(ctx, namespace, newPostRenderer(ownerReference, true, h.managedByValue, opts))
+		updateAction.PostRenderer = filter
+		updateAction.MaxHistory = h.maxHistory
 		updateAction.SkipCRDs = true
 		updateAction.DisableOpenAPIValidation = true
 		updateAction.WaitStrategy = kube.HookOnlyStrategy
-		updateAction.ServerSideApply = "false"
+		updateAction.ServerSideApply = strconv.FormatBool(h.serverSideApply())
 		updateAction.WaitForJobs = false
 		rel, err = updateAction.RunWithContext(ctx, releaseName, chart, values)
 		if err != nil {
@@ -188,19 +259,23 @@ func (h *ChartManager) upgradeOrInstallChart(
 		log.V(2).Info("Performing helm install", "chartName", chart.Name())
 
 		installAction := action.NewInstall(cfg)
-		installAction.PostRenderer = NewHelmPostRenderer(ownerReference, "", false, h.managedByValue)
+		filter = h.newConflictFilter(ctx, namespace, newPostRenderer(ownerReference, false, h.managedByValue, opts))
+		installAction.PostRenderer = filter
 		installAction.Namespace = namespace
 		installAction.ReleaseName = releaseName
 		installAction.SkipCRDs = true
 		installAction.DisableOpenAPIValidation = true
 		installAction.WaitStrategy = kube.HookOnlyStrategy
-		installAction.ServerSideApply = false
+		installAction.ServerSideApply = h.serverSideApply()
 		installAction.WaitForJobs = false
 		rel, err = installAction.RunWithContext(ctx, chart, values)
 		if err != nil {
 			return nil, fmt.Errorf("failed to install helm chart %s: %w", chart.Name(), err)
 		}
 	}
+	if h.serverSideApply() {
+		h.setFieldConflicts(namespace, releaseName, filter.conflicts)
+	}
 	return rel, nil
 }
 
@@ -218,6 +293,7 @@ func (h *ChartManager) UninstallChart(ctx context.Context, releaseName, namespac
 		return &release.UninstallReleaseResponse{Info: "release not found"}, nil
 	}
 
+	h.setFieldConflicts(namespace, releaseName, nil)
 	uninstallAction := action.NewUninstall(cfg)
 	uninstallAction.WaitStrategy = kube.HookOnlyStrategy
 	resp, err := uninstallAction.Run(releaseName)
@@ -251,6 +327,23 @@ func (h *ChartManager) GetRelease(ctx context.Context, namespace, releaseName st
 	return getRelease(cfg, releaseName)
 }
 
+// GetManifest returns the manifest of the given Helm release. It returns an empty string if the release
+// doesn't exist or is being uninstalled.
+func (h *ChartManager) GetManifest(ctx context.Context, namespace, releaseName string) (string, error) {
+	rel, err := h.GetRelease(ctx, namespace, releaseName)
+	if err != nil || rel == nil {
+		return "", err
+	}
+	relV1, ok := rel.(*releasev1.Release)
+	if !ok {
+		return "", fmt.Errorf("unexpected release type %T for helm release %s", rel, releaseName)
+	}
+	if relV1.Info.Status == releasecommon.StatusUninstalling || relV1.Info.Status == releasecommon.StatusUninstalled {
+		return "", nil
+	}
+	return relV1.Manifest, nil
+}
+
 func (h *ChartManager) UpdateRelease(ctx context.Context, namespace string, rel release.Releaser) error {
 	cfg, err := h.newActionConfig(ctx, namespace)
 	if err != nil {
@@ -234,3 +234,67 @@ func setReleaseStatus(g *WithT, helm *ChartManager, ns, releaseName string, stat
 	concreteRel.SetStatus(status, "simulated status")
 	g.Expect(cfg.Releases.Update(rel)).To(Succeed())
 }
+
+func TestRollbackRelease(t *testing.T) {
+	_, cl, cfg := test.SetupEnv(os.Stdout, false)
+
+	g := NewWithT(t)
+	helm := NewChartManager(cfg, "")
+	ns := "test-" + rand.String(8)
+	g.Expect(createNamespace(cl, ns)).To(Succeed())
+
+	for _, value := range []string{"first", "second", "third"} {
+		_, err := helm.UpgradeOrInstallChart(ctx, chartFS, chartPath, Values{"value": value}, ns, relName, &owner)
+		g.Expect(err).ToNot(HaveOccurred())
+	}
+
+	history, err := helm.ReleaseHistory(ctx, ns, relName)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(history).To(HaveLen(3))
+	g.Expect(history[0].Revision).To(Equal(3))
+	g.Expect(history[0].Status).To(Equal(releasecommon.StatusDeployed.String()))
+	g.Expect(history[2].Status).To(Equal(releasecommon.StatusSuperseded.String()))
+
+	rolledBack, err := helm.RollbackRelease(ctx, ns, relName, 1)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(rolledBack).To(BeTrue())
+
+	configMap := &corev1.ConfigMap{}
+	g.Expect(cl.Get(ctx, types.NamespacedName{Name: "test", Namespace: ns}, configMap)).To(Succeed())
+	g.Expect(configMap.Data).To(HaveKeyWithValue("value", "first"))
+
+	// rolling back to the same revision again is a no-op
+	rolledBack, err = helm.RollbackRelease(ctx, ns, relName, 1)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(rolledBack).To(BeFalse())
+
+	history, err = helm.ReleaseHistory(ctx, ns, relName)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(history).To(HaveLen(4))
+	g.Expect(history[0].Description).To(Equal("Rollback to 1"))
+
+	_, err = helm.RollbackRelease(ctx, ns, relName, 10)
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestMaxHistory(t *testing.T) {
+	_, cl, cfg := test.SetupEnv(os.Stdout, false)
+
+	g := NewWithT(t)
+	helm := NewChartManager(cfg, "", WithMaxHistory(2))
+	ns := "test-" + rand.String(8)
+	g.Expect(createNamespace(cl, ns)).To(Succeed())
+
+	for range 4 {
+		upgradeOrInstall(g, helm, ns, relName, owner)
+	}
+
+	history, err := helm.ReleaseHistory(ctx, ns, relName)
+	g.Expect(err).ToNot(HaveOccurred())
+	g.Expect(history).To(HaveLen(2))
+	g.Expect(history[0].Revision).To(Equal(4))
+	g.Expect(history[1].Revision).To(Equal(3))
+
+	_, err = helm.RollbackRelease(ctx, ns, relName, 1)
+	g.Expect(err).To(MatchError(ContainSubstring("not retained")))
+}
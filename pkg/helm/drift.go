@@ -0,0 +1,269 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	releasecommon "helm.sh/helm/v4/pkg/release/common"
+	releasev1 "helm.sh/helm/v4/pkg/release/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Drift describes how a live object differs from the same object in the manifest of a Helm release.
+type Drift struct {
+	Object ObjectReference
+	// Missing is true if the object no longer exists in the cluster.
+	Missing bool
+	// Fields contains the paths of the fields whose live values differ from the manifest,
+	// e.g. spec.template.spec.containers[0].image.
+	Fields []string
+	// Values maps each path in Fields to the live value of the field, so that the field can be kept
+	// when the chart is upgraded (see WithKeptFields).
+	Values map[string]FieldValue
+}
+
+// FieldValue is the value of a field of a live object.
+type FieldValue struct {
+	// Keys lead from the root of the object to the field: a string for each map key and an int for
+	// each list index.
+	Keys []any
+	// Value is nil if the field isn't set on the live object.
+	Value any
+}
+
+// DetectDrift compares the live objects of a Helm release with the manifest of the release. It returns
+// nil if the release doesn't exist or isn't deployed, as the manifest then doesn't describe the
+// objects in the cluster.
+func (h *ChartManager) DetectDrift(ctx context.Context, cl client.Client, namespace, releaseName string) ([]Drift, error) {
+	rel, err := h.GetRelease(ctx, namespace, releaseName)
+	if err != nil || rel == nil {
+		return nil, err
+	}
+	relV1, ok := rel.(*releasev1.Release)
+	if !ok {
+		return nil, fmt.Errorf("unexpected release type %T for helm release %s", rel, releaseName)
+	}
+	if relV1.Info.Status != releasecommon.StatusDeployed {
+		return nil, nil
+	}
+	return DetectManifestDrift(ctx, cl, relV1.Manifest, namespace)
+}
+
+// DetectManifestDrift compares each object in the manifest with the live object in the cluster. Objects
+// without a namespace are looked up in the given namespace, unless they are cluster-scoped. Only the
+// fields that are set in the manifest are compared, so fields that are defaulted by the API server or
+// set by other controllers aren't reported. Of the object metadata, only labels and annotations are
+// compared. The fields that were kept when the release was installed (see WithKeptFields) are compared
+// with the values from the chart, since the manifest contains their live values.
+func DetectManifestDrift(ctx context.Context, cl client.Client, manifest, namespace string) ([]Drift, error) {
+	objects, err := parseManifest(strings.NewReader(manifest))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse release manifest: %w", err)
+	}
+
+	refs := make([]ObjectReference, 0, len(objects))
+	for ref := range objects {
+		refs = append(refs, ref)
+	}
+	sortObjectReferences(refs)
+
+	var drifts []Drift
+	for _, ref := range refs {
+		desired := unstructured.Unstructured{Object: objects[ref]}
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(desired.GroupVersionKind())
+		if ref.Namespace == "" {
+			namespaced, err := cl.IsObjectNamespaced(live)
+			if err != nil {
+				return nil, fmt.Errorf("failed to determine the scope of %s: %w", ref, err)
+			}
+			if namespaced {
+				ref.Namespace = namespace
+			}
+		}
+
+		if err := cl.Get(ctx, client.ObjectKey{Namespace: ref.Namespace, Name: ref.Name}, live); err != nil {
+			if apierrors.IsNotFound(err) {
+				drifts = append(drifts, Drift{Object: ref, Missing: true})
+				continue
+			}
+			return nil, fmt.Errorf("failed to get %s: %w", ref, err)
+		}
+
+		drift, err := compareObjects(desired.Object, live.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compare %s: %w", ref, err)
+		}
+		if len(drift.Fields) > 0 {
+			drift.Object = ref
+			drifts = append(drifts, drift)
+		}
+	}
+	return drifts, nil
+}
+
+func compareObjects(desired, live map[string]any) (Drift, error) {
+	// both objects are normalized through JSON, so that numbers have the same type
+	var normalizedDesired, normalizedLive map[string]any
+	if err := normalize(desired, &normalizedDesired); err != nil {
+		return Drift{}, err
+	}
+	if err := normalize(live, &normalizedLive); err != nil {
+		return Drift{}, err
+	}
+
+	// the manifest contains the live values of the kept fields, so they're compared with the values from the
+	// chart that are recorded in the annotation instead
+	kept, err := keptFields(normalizedDesired)
+	if err != nil {
+		return Drift{}, err
+	}
+	keptPaths := make([]string, 0, len(kept))
+	for _, field := range kept {
+		keptPaths = append(keptPaths, fieldPath(field.Keys))
+	}
+
+	drift := Drift{Values: map[string]FieldValue{}}
+	add := func(path string, keys []any, value any) {
+		if _, found := drift.Values[path]; !found {
+			drift.Fields = append(drift.Fields, path)
+			drift.Values[path] = FieldValue{Keys: slices.Clone(keys), Value: value}
+		}
+	}
+	addUnlessKept := func(path string, keys []any, value any) {
+		for _, keptPath := range keptPaths {
+			if path == keptPath || strings.HasPrefix(path, keptPath+".") || strings.HasPrefix(path, keptPath+"[") {
+				return
+			}
+		}
+		add(path, keys, value)
+	}
+	for _, key := range sortedKeys(normalizedDesired) {
+		switch key {
+		case "apiVersion", "kind", "status":
+			continue
+		case "metadata":
+			desiredMeta, _ := normalizedDesired[key].(map[string]any)
+			liveMeta, _ := normalizedLive[key].(map[string]any)
+			for _, metaKey := range []string{"labels", "annotations"} {
+				compareValues("metadata."+metaKey, []any{"metadata", metaKey}, desiredMeta[metaKey], liveMeta[metaKey], addUnlessKept)
+			}
+		default:
+			compareValues(key, []any{key}, normalizedDesired[key], normalizedLive[key], addUnlessKept)
+		}
+	}
+
+	for i, field := range kept {
+		live, found := lookupField(normalizedLive, field.Keys)
+		if field.Value == nil && found && live != nil {
+			add(keptPaths[i], field.Keys, live)
+			continue
+		}
+		compareValues(keptPaths[i], field.Keys, field.Value, live, add)
+	}
+	return drift, nil
+}
+
+// compareValues calls add with the path, the keys and the live value of each field that differs.
+func compareValues(path string, keys []any, desired, live any, add func(path string, keys []any, live any)) {
+	switch d := desired.(type) {
+	case nil:
+		// a null value in the manifest means that the field isn't set
+	case map[string]any:
+		l, ok := live.(map[string]any)
+		if !ok {
+			if len(d) > 0 || live != nil {
+				add(path, keys, live)
+			}
+			return
+		}
+		for _, key := range sortedKeys(d) {
+			compareValues(path+"."+key, append(keys, key), d[key], l[key], add)
+		}
+	case []any:
+		l, ok := live.([]any)
+		if !ok && (len(d) > 0 || live != nil) || len(l) != len(d) {
+			add(path, keys, live)
+			return
+		}
+		for i := range d {
+			compareValues(fmt.Sprintf("%s[%d]", path, i), append(keys, i), d[i], l[i], add)
+		}
+	default:
+		if !scalarsEqual(d, live) {
+			add(path, keys, live)
+		}
+	}
+}
+
+// scalarsEqual compares two scalar values. Zero values in the manifest are equal to unset fields,
+// since the API server drops them, and quantities are compared by value, since the API server
+// converts them to their canonical form (e.g. 1000m to 1).
+func scalarsEqual(desired, live any) bool {
+	if reflect.DeepEqual(desired, live) {
+		return true
+	}
+	if live == nil {
+		return reflect.ValueOf(desired).IsZero()
+	}
+	_, desiredIsString := desired.(string)
+	_, liveIsString := live.(string)
+	if !desiredIsString && !liveIsString {
+		return false
+	}
+	desiredQuantity, err := resource.ParseQuantity(scalarString(desired))
+	if err != nil {
+		return false
+	}
+	liveQuantity, err := resource.ParseQuantity(scalarString(live))
+	if err != nil {
+		return false
+	}
+	return desiredQuantity.Cmp(liveQuantity) == 0
+}
+
+func scalarString(v any) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func normalize(in any, out any) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,197 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+const driftManifest = `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unchanged
+  labels:
+    app: istiod
+data:
+  key: value
+  empty: ""
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istiod
+  annotations: {}
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: discovery
+        image: istio/pilot:1.27.0
+        resources:
+          requests:
+            cpu: 1000m
+            memory: 2048Mi
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: deleted
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: istiod-clusterrole
+  labels:
+    app: istiod
+`
+
+func TestDetectManifestDrift(t *testing.T) {
+	objects := []client.Object{
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "unchanged",
+				Namespace: "istio-system",
+				Labels:    map[string]string{"app": "istiod", "added-by-someone": "true"},
+			},
+			Data: map[string]string{"key": "value"},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "istiod", Namespace: "istio-system"},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: ptr.Of(int32(1)),
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{
+							Name:            "discovery",
+							Image:           "istio/pilot:1.27.0-debug",
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									corev1.ResourceCPU:    resource.MustParse("1"),
+									corev1.ResourceMemory: resource.MustParse("2Gi"),
+								},
+							},
+						}},
+					},
+				},
+			},
+		},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "istiod-clusterrole", Labels: map[string]string{"app": "something-else"}},
+		},
+	}
+
+	cl := newDriftFakeClientBuilder().WithObjects(objects...).Build()
+	drifts, err := DetectManifestDrift(context.Background(), cl, driftManifest, "istio-system")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	expected := []Drift{
+		{
+			Object: ObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "istio-system", Name: "istiod"},
+			Fields: []string{"spec.template.spec.containers[0].image"},
+			Values: map[string]FieldValue{
+				"spec.template.spec.containers[0].image": {
+					Keys:  []any{"spec", "template", "spec", "containers", 0, "image"},
+					Value: "istio/pilot:1.27.0-debug",
+				},
+			},
+		},
+		{
+			Object: ObjectReference{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole", Name: "istiod-clusterrole"},
+			Fields: []string{"metadata.labels.app"},
+			Values: map[string]FieldValue{
+				"metadata.labels.app": {Keys: []any{"metadata", "labels", "app"}, Value: "something-else"},
+			},
+		},
+		{
+			Object:  ObjectReference{APIVersion: "v1", Kind: "Service", Namespace: "istio-system", Name: "deleted"},
+			Missing: true,
+		},
+	}
+	if d := cmp.Diff(expected, drifts); d != "" {
+		t.Errorf("unexpected drifts (-expected, +actual):\n%s", d)
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		desired  any
+		live     any
+		expected []string
+	}{
+		{name: "equal strings", desired: "a", live: "a"},
+		{name: "different strings", desired: "a", live: "b", expected: []string{"f"}},
+		{name: "zero value and unset field", desired: false, live: nil},
+		{name: "non-zero value and unset field", desired: true, live: nil, expected: []string{"f"}},
+		{name: "equal quantities", desired: "500m", live: "0.5"},
+		{name: "number and quantity", desired: float64(2), live: "2"},
+		{name: "null in manifest", desired: nil, live: "anything"},
+		{name: "extra live fields", desired: map[string]any{"a": "1"}, live: map[string]any{"a": "1", "b": "2"}},
+		{name: "empty map and unset field", desired: map[string]any{}, live: nil},
+		{name: "empty list and unset field", desired: []any{}, live: nil},
+		{name: "list length differs", desired: []any{"a"}, live: []any{"a", "b"}, expected: []string{"f"}},
+		{
+			name:     "nested list element differs",
+			desired:  []any{map[string]any{"name": "a", "value": "1"}},
+			live:     []any{map[string]any{"name": "a", "value": "2"}},
+			expected: []string{"f[0].value"},
+		},
+		{name: "type differs", desired: map[string]any{"a": "1"}, live: "1", expected: []string{"f"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields []string
+			compareValues("f", []any{"f"}, tt.desired, tt.live, func(path string, _ []any, _ any) {
+				fields = append(fields, path)
+			})
+			if d := cmp.Diff(tt.expected, fields); d != "" {
+				t.Errorf("unexpected fields (-expected, +actual):\n%s", d)
+			}
+		})
+	}
+}
+
+func newDriftFakeClientBuilder() *fake.ClientBuilder {
+	s := runtime.NewScheme()
+	_ = corev1.AddToScheme(s)
+	_ = appsv1.AddToScheme(s)
+	_ = rbacv1.AddToScheme(s)
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Service"), meta.RESTScopeNamespace)
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+	mapper.Add(rbacv1.SchemeGroupVersion.WithKind("ClusterRole"), meta.RESTScopeRoot)
+	return fake.NewClientBuilder().WithScheme(s).WithRESTMapper(mapper)
+}
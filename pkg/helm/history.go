@@ -0,0 +1,166 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"helm.sh/helm/v4/pkg/action"
+	"helm.sh/helm/v4/pkg/kube"
+	"helm.sh/helm/v4/pkg/release"
+	releasecommon "helm.sh/helm/v4/pkg/release/common"
+	releasev1 "helm.sh/helm/v4/pkg/release/v1"
+	"helm.sh/helm/v4/pkg/storage/driver"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ReleaseRevision describes a revision of a Helm release that is retained in the release history.
+type ReleaseRevision struct {
+	// Revision is the number of the revision. It's incremented on every install, upgrade and rollback.
+	Revision int
+	// ChartVersion is the version of the chart that was deployed in this revision.
+	ChartVersion string
+	// Updated is the time when this revision was deployed.
+	Updated time.Time
+	// Status is the Helm status of the revision (e.g. deployed, superseded, failed).
+	Status string
+	// Description is the Helm description of the revision (e.g. "Upgrade complete" or "Rollback to 2").
+	Description string
+}
+
+// ReleaseHistoryManager is implemented by chart managers that retain the history of Helm releases and
+// can roll a release back to one of the retained revisions.
+type ReleaseHistoryManager interface {
+	ReleaseHistory(ctx context.Context, namespace, releaseName string) ([]ReleaseRevision, error)
+	RollbackRelease(ctx context.Context, namespace, releaseName string, revision int) (bool, error)
+}
+
+// ReleaseHistory returns the retained revisions of the given Helm release, newest first. It returns an
+// empty list if the release doesn't exist.
+func (h *ChartManager) ReleaseHistory(ctx context.Context, namespace, releaseName string) ([]ReleaseRevision, error) {
+	cfg, err := h.newActionConfig(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	releases, err := releaseHistory(cfg, releaseName)
+	if err != nil {
+		return nil, err
+	}
+	return toReleaseRevisions(releases), nil
+}
+
+// RollbackRelease reinstalls the given revision of a Helm release. The rollback creates a new revision
+// with the manifest and values of the target revision. If the latest revision is already a successful
+// rollback to the target revision, nothing is done, so that the method can be called on every
+// reconciliation. It returns true if a rollback was performed.
+func (h *ChartManager) RollbackRelease(ctx context.Context, namespace, releaseName string, revision int) (bool, error) {
+	log := logf.FromContext(ctx)
+
+	if revision <= 0 {
+		return false, fmt.Errorf("invalid revision %d of helm release %s", revision, releaseName)
+	}
+
+	cfg, err := h.newActionConfig(ctx, namespace)
+	if err != nil {
+		return false, err
+	}
+
+	releases, err := releaseHistory(cfg, releaseName)
+	if err != nil {
+		return false, err
+	} else if len(releases) == 0 {
+		return false, fmt.Errorf("helm release %s not found", releaseName)
+	}
+
+	if isRollbackTo(releases[0], revision) {
+		return false, nil
+	}
+	if !slices.ContainsFunc(releases, func(rel *releasev1.Release) bool { return rel.Version == revision }) {
+		return false, fmt.Errorf("revision %d of helm release %s is not retained in the release history", revision, releaseName)
+	}
+
+	log.V(2).Info("Performing helm rollback", "release", releaseName, "revision", revision)
+	rollbackAction := h.newRollback(cfg)
+	rollbackAction.Version = revision
+	if err := rollbackAction.Run(releaseName); err != nil {
+		return false, fmt.Errorf("failed to roll back helm release %s to revision %d: %w", releaseName, revision, err)
+	}
+	return true, nil
+}
+
+func (h *ChartManager) newRollback(cfg *action.Configuration) *action.Rollback {
+	rollbackAction := action.NewRollback(cfg)
+	rollbackAction.MaxHistory = h.maxHistory
+	rollbackAction.WaitStrategy = kube.HookOnlyStrategy
+	rollbackAction.WaitForJobs = false
+	rollbackAction.ServerSideApply = strconv.FormatBool(h.serverSideApply())
+	return rollbackAction
+}
+
+// releaseHistory returns the retained revisions of the given release, newest first.
+func releaseHistory(cfg *action.Configuration, releaseName string) ([]*releasev1.Release, error) {
+	history, err := cfg.Releases.History(releaseName)
+	if err != nil {
+		if errors.Is(err, driver.ErrReleaseNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get history of helm release %s: %w", releaseName, err)
+	}
+	return sortedReleases(history, releaseName)
+}
+
+func sortedReleases(history []release.Releaser, releaseName string) ([]*releasev1.Release, error) {
+	releases := make([]*releasev1.Release, 0, len(history))
+	for _, r := range history {
+		rel, ok := r.(*releasev1.Release)
+		if !ok {
+			return nil, fmt.Errorf("unexpected release type %T for helm release %s", r, releaseName)
+		}
+		releases = append(releases, rel)
+	}
+	slices.SortFunc(releases, func(a, b *releasev1.Release) int {
+		return b.Version - a.Version
+	})
+	return releases, nil
+}
+
+func toReleaseRevisions(releases []*releasev1.Release) []ReleaseRevision {
+	revisions := make([]ReleaseRevision, 0, len(releases))
+	for _, rel := range releases {
+		revision := ReleaseRevision{Revision: rel.Version}
+		if rel.Chart != nil && rel.Chart.Metadata != nil {
+			revision.ChartVersion = rel.Chart.Metadata.Version
+		}
+		if rel.Info != nil {
+			revision.Updated = rel.Info.LastDeployed
+			revision.Status = rel.Info.Status.String()
+			revision.Description = rel.Info.Description
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions
+}
+
+// isRollbackTo returns true if the given release was successfully created by rolling back to the given revision.
+func isRollbackTo(rel *releasev1.Release, revision int) bool {
+	return rel.Info != nil && rel.Info.Status == releasecommon.StatusDeployed &&
+		rel.Info.Description == fmt.Sprintf("Rollback to %d", revision)
+}
@@ -0,0 +1,97 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	chartv2 "helm.sh/helm/v4/pkg/chart/v2"
+	"helm.sh/helm/v4/pkg/release"
+	releasecommon "helm.sh/helm/v4/pkg/release/common"
+	releasev1 "helm.sh/helm/v4/pkg/release/v1"
+)
+
+func newHistoryRelease(version int, status releasecommon.Status, description string, updated time.Time) *releasev1.Release {
+	return &releasev1.Release{
+		Name:    relName,
+		Version: version,
+		Chart:   &chartv2.Chart{Metadata: &chartv2.Metadata{Name: "istiod", Version: "1.27.0"}},
+		Info: &releasev1.Info{
+			Status:       status,
+			Description:  description,
+			LastDeployed: updated,
+		},
+	}
+}
+
+func TestToReleaseRevisions(t *testing.T) {
+	g := NewWithT(t)
+	now := time.Now()
+
+	releases, err := sortedReleases([]release.Releaser{
+		newHistoryRelease(1, releasecommon.StatusSuperseded, "Install complete", now.Add(-2*time.Hour)),
+		newHistoryRelease(3, releasecommon.StatusDeployed, "Rollback to 1", now),
+		newHistoryRelease(2, releasecommon.StatusSuperseded, "Upgrade complete", now.Add(-time.Hour)),
+	}, relName)
+	g.Expect(err).ToNot(HaveOccurred())
+
+	g.Expect(toReleaseRevisions(releases)).To(Equal([]ReleaseRevision{
+		{Revision: 3, ChartVersion: "1.27.0", Updated: now, Status: "deployed", Description: "Rollback to 1"},
+		{Revision: 2, ChartVersion: "1.27.0", Updated: now.Add(-time.Hour), Status: "superseded", Description: "Upgrade complete"},
+		{Revision: 1, ChartVersion: "1.27.0", Updated: now.Add(-2 * time.Hour), Status: "superseded", Description: "Install complete"},
+	}))
+}
+
+func TestIsRollbackTo(t *testing.T) {
+	tests := []struct {
+		name     string
+		rel      *releasev1.Release
+		revision int
+		want     bool
+	}{
+		{
+			name:     "rollback to the same revision",
+			rel:      newHistoryRelease(3, releasecommon.StatusDeployed, "Rollback to 1", time.Now()),
+			revision: 1,
+			want:     true,
+		},
+		{
+			name:     "rollback to another revision",
+			rel:      newHistoryRelease(3, releasecommon.StatusDeployed, "Rollback to 2", time.Now()),
+			revision: 1,
+			want:     false,
+		},
+		{
+			name:     "failed rollback",
+			rel:      newHistoryRelease(3, releasecommon.StatusFailed, "Rollback to 1", time.Now()),
+			revision: 1,
+			want:     false,
+		},
+		{
+			name:     "upgrade",
+			rel:      newHistoryRelease(3, releasecommon.StatusDeployed, "Upgrade complete", time.Now()),
+			revision: 1,
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(isRollbackTo(tt.rel, tt.revision)).To(Equal(tt.want))
+		})
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// WithKeptFields keeps the live values of the given drifted fields when the chart is installed. Helm would
+// otherwise revert them to the values in the chart, because it merges the rendered manifests with the live
+// objects. The post-renderer copies the live values into the rendered manifests and records the fields in the
+// sailoperator.io/kept-fields annotation, so that DetectManifestDrift still reports them after the upgrade.
+func WithKeptFields(drifts ...Drift) ChartOption {
+	return func(o *chartOptions) {
+		o.keptFields = append(o.keptFields, drifts...)
+	}
+}
+
+// keptField is an entry of the sailoperator.io/kept-fields annotation. Value is the value of the field in the
+// chart, or nil if the chart doesn't set it.
+type keptField struct {
+	Keys  []any `json:"keys"`
+	Value any   `json:"value"`
+}
+
+// keepFields copies the live values of the kept fields of the object into the manifest and records the values
+// from the chart in the sailoperator.io/kept-fields annotation.
+func (pr HelmPostRenderer) keepFields(manifest map[string]any) (map[string]any, error) {
+	u := unstructured.Unstructured{Object: manifest}
+	var kept []keptField
+	for _, drift := range pr.keptFields {
+		ref := drift.Object
+		if ref.APIVersion != u.GetAPIVersion() || ref.Kind != u.GetKind() || ref.Name != u.GetName() ||
+			u.GetNamespace() != "" && ref.Namespace != u.GetNamespace() {
+			continue
+		}
+		for _, field := range drift.Fields {
+			value, found := drift.Values[field]
+			if !found {
+				continue
+			}
+			chartValue, _ := lookupField(manifest, value.Keys)
+			if !setField(manifest, value.Keys, value.Value) {
+				continue
+			}
+			kept = append(kept, keptField{Keys: value.Keys, Value: chartValue})
+		}
+	}
+	if len(kept) == 0 {
+		return manifest, nil
+	}
+
+	data, err := json.Marshal(kept)
+	if err != nil {
+		return nil, err
+	}
+	annotations := u.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[constants.KeptFieldsAnnotationKey] = string(data)
+	u.SetAnnotations(annotations)
+	return u.Object, nil
+}
+
+// keptFields returns the fields listed in the sailoperator.io/kept-fields annotation of the object.
+func keptFields(obj map[string]any) ([]keptField, error) {
+	annotation, _, _ := unstructured.NestedString(obj, "metadata", "annotations", constants.KeptFieldsAnnotationKey)
+	if annotation == "" {
+		return nil, nil
+	}
+	var kept []keptField
+	if err := json.Unmarshal([]byte(annotation), &kept); err != nil {
+		return nil, fmt.Errorf("failed to parse annotation %s: %w", constants.KeptFieldsAnnotationKey, err)
+	}
+	// list indexes are decoded as float64
+	for _, field := range kept {
+		for i, key := range field.Keys {
+			if f, ok := key.(float64); ok {
+				field.Keys[i] = int(f)
+			}
+		}
+	}
+	return kept, nil
+}
+
+// fieldPath returns the path of the field in the same format as Drift.Fields.
+func fieldPath(keys []any) string {
+	var sb strings.Builder
+	for _, key := range keys {
+		if i, ok := key.(int); ok {
+			sb.WriteString("[" + strconv.Itoa(i) + "]")
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteString(".")
+		}
+		sb.WriteString(fmt.Sprint(key))
+	}
+	return sb.String()
+}
+
+// lookupField returns the value of the field with the given keys.
+func lookupField(obj any, keys []any) (any, bool) {
+	for _, key := range keys {
+		switch k := key.(type) {
+		case string:
+			m, ok := obj.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if obj, ok = m[k]; !ok {
+				return nil, false
+			}
+		case int:
+			l, ok := obj.([]any)
+			if !ok || k < 0 || k >= len(l) {
+				return nil, false
+			}
+			obj = l[k]
+		default:
+			return nil, false
+		}
+	}
+	return obj, true
+}
+
+// setField sets the field with the given keys to the value, or removes it if the value is nil. Missing maps
+// are created, but lists aren't extended. It returns false if the field can't be set.
+func setField(obj map[string]any, keys []any, value any) bool {
+	if len(keys) == 0 {
+		return false
+	}
+	parent, found := lookupField(obj, keys[:len(keys)-1])
+	if !found || parent == nil {
+		// the field is already unset
+		if value == nil {
+			return true
+		}
+		// the parent is missing, so len(keys) >= 2
+		if _, isIndex := keys[len(keys)-2].(int); isIndex {
+			return false
+		}
+		parentMap := map[string]any{}
+		if !setField(obj, keys[:len(keys)-1], parentMap) {
+			return false
+		}
+		parent = parentMap
+	}
+
+	switch k := keys[len(keys)-1].(type) {
+	case string:
+		m, ok := parent.(map[string]any)
+		if !ok {
+			return false
+		}
+		if value == nil {
+			delete(m, k)
+		} else {
+			m[k] = value
+		}
+	case int:
+		l, ok := parent.([]any)
+		if !ok || k < 0 || k >= len(l) || value == nil {
+			return false
+		}
+		l[k] = value
+	default:
+		return false
+	}
+	return true
+}
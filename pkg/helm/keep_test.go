@@ -0,0 +1,207 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestKeptFieldsSurviveUpgrade(t *testing.T) {
+	live := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "istiod", Namespace: "istio-system"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.Of(int32(3)),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "discovery", Image: "istio/pilot:1.27.0-debug"}},
+				},
+			},
+		},
+	}
+	cl := newDriftFakeClientBuilder().WithObjects(live).Build()
+
+	installed := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istiod
+  namespace: istio-system
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: discovery
+        image: istio/pilot:1.27.0
+`
+	drifts, err := DetectManifestDrift(context.Background(), cl, installed, "istio-system")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(drifts) != 1 {
+		t.Fatalf("expected one drifted object, got: %v", drifts)
+	}
+
+	// spec.replicas is report-only, so it's kept, while the image is reverted by the upgrade
+	kept := drifts[0]
+	kept.Fields = []string{"spec.replicas"}
+
+	// the new version of the chart changes the image
+	upgraded := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istiod
+  namespace: istio-system
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: discovery
+        image: istio/pilot:1.28.0
+`
+	expected := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  annotations:
+    sailoperator.io/kept-fields: '[{"keys":["spec","replicas"],"value":1}]'
+  labels:
+    managed-by: sail-operator
+  name: istiod
+  namespace: istio-system
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+        - image: istio/pilot:1.28.0
+          name: discovery
+`
+	postRenderer := newPostRenderer(nil, true, constants.ManagedByLabelValue, newChartOptions([]ChartOption{WithKeptFields(kept)}))
+	manifest, err := postRenderer.Run(bytes.NewBufferString(upgraded))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := cmp.Diff(expected, manifest.String()); d != "" {
+		t.Fatalf("kept field wasn't copied into the manifest; diff (-expected, +actual):\n%s", d)
+	}
+
+	// Helm applies the manifest, which keeps the live replicas
+	live.Spec.Template.Spec.Containers[0].Image = "istio/pilot:1.28.0"
+	live.Annotations = map[string]string{constants.KeptFieldsAnnotationKey: `[{"keys":["spec","replicas"],"value":1}]`}
+	live.Labels = map[string]string{"managed-by": "sail-operator"}
+	if err := cl.Update(context.Background(), live); err != nil {
+		t.Fatal(err)
+	}
+
+	// the kept field is still reported as drifted from the chart
+	drifts, err = DetectManifestDrift(context.Background(), cl, manifest.String(), "istio-system")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	expectedDrifts := []Drift{
+		{
+			Object: ObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "istio-system", Name: "istiod"},
+			Fields: []string{"spec.replicas"},
+			Values: map[string]FieldValue{"spec.replicas": {Keys: []any{"spec", "replicas"}, Value: float64(3)}},
+		},
+	}
+	if d := cmp.Diff(expectedDrifts, drifts); d != "" {
+		t.Errorf("unexpected drifts (-expected, +actual):\n%s", d)
+	}
+
+	// once the field is changed back to the value in the chart, it's no longer reported
+	live.Spec.Replicas = ptr.Of(int32(1))
+	if err := cl.Update(context.Background(), live); err != nil {
+		t.Fatal(err)
+	}
+	drifts, err = DetectManifestDrift(context.Background(), cl, manifest.String(), "istio-system")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(drifts) != 0 {
+		t.Errorf("expected no drift, got: %v", drifts)
+	}
+}
+
+func TestSetField(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     []any
+		value    any
+		expected map[string]any
+		ok       bool
+	}{
+		{
+			name:     "existing field",
+			keys:     []any{"spec", "replicas"},
+			value:    3,
+			expected: map[string]any{"spec": map[string]any{"replicas": 3, "list": []any{"a"}}},
+			ok:       true,
+		},
+		{
+			name:     "missing parents are created",
+			keys:     []any{"metadata", "labels", "app"},
+			value:    "istiod",
+			expected: map[string]any{"spec": map[string]any{"replicas": 1, "list": []any{"a"}}, "metadata": map[string]any{"labels": map[string]any{"app": "istiod"}}},
+			ok:       true,
+		},
+		{
+			name:     "nil value removes the field",
+			keys:     []any{"spec", "replicas"},
+			expected: map[string]any{"spec": map[string]any{"list": []any{"a"}}},
+			ok:       true,
+		},
+		{
+			name:     "list item",
+			keys:     []any{"spec", "list", 0},
+			value:    "b",
+			expected: map[string]any{"spec": map[string]any{"replicas": 1, "list": []any{"b"}}},
+			ok:       true,
+		},
+		{
+			name:     "lists aren't extended",
+			keys:     []any{"spec", "list", 1},
+			value:    "b",
+			expected: map[string]any{"spec": map[string]any{"replicas": 1, "list": []any{"a"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := map[string]any{"spec": map[string]any{"replicas": 1, "list": []any{"a"}}}
+			if ok := setField(obj, tt.keys, tt.value); ok != tt.ok {
+				t.Errorf("expected %v, got %v", tt.ok, ok)
+			}
+			if d := cmp.Diff(tt.expected, obj); d != "" {
+				t.Errorf("unexpected object (-expected, +actual):\n%s", d)
+			}
+		})
+	}
+}
+
+func TestFieldPath(t *testing.T) {
+	if path := fieldPath([]any{"spec", "template", "spec", "containers", 0, "image"}); path != "spec.template.spec.containers[0].image" {
+		t.Errorf("unexpected path %q", path)
+	}
+}
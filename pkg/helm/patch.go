@@ -0,0 +1,205 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// PatchType is the format of a Patch.
+type PatchType string
+
+const (
+	// StrategicMergePatchType patches are merged into the object using the strategic merge patch rules of the
+	// object's type. For kinds that aren't known to the operator, a JSON merge patch (RFC 7386) is applied instead.
+	StrategicMergePatchType PatchType = "StrategicMerge"
+
+	// JSON6902PatchType patches are lists of JSON patch (RFC 6902) operations.
+	JSON6902PatchType PatchType = "JSON6902"
+)
+
+// Patch modifies the rendered manifests of a chart before they are applied to the cluster.
+type Patch struct {
+	// Kind of the objects to patch.
+	Kind string
+	// Name of the object to patch. If empty, all objects of the given Kind are patched.
+	Name string
+	// Type of the patch. Defaults to StrategicMergePatchType.
+	Type PatchType
+	// Patch in YAML or JSON format.
+	Patch string
+}
+
+// PatchError is returned when a Patch is invalid or can't be applied to a rendered manifest.
+type PatchError struct {
+	// Index of the patch in the list of patches.
+	Index int
+	Err   error
+}
+
+func (e *PatchError) Error() string {
+	return fmt.Sprintf("patches[%d]: %v", e.Index, e.Err)
+}
+
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// IsPatchError returns true if the error, or any error it wraps, is a PatchError.
+func IsPatchError(err error) bool {
+	e := &PatchError{}
+	return errors.As(err, &e)
+}
+
+// ValidatePatches checks that all patches have a target kind and a patch that can be parsed. It doesn't check
+// whether the patches apply cleanly, as that can only be known once the chart is rendered.
+func ValidatePatches(patches []Patch) error {
+	for i, p := range patches {
+		if err := p.validate(); err != nil {
+			return &PatchError{Index: i, Err: err}
+		}
+	}
+	return nil
+}
+
+func (p Patch) validate() error {
+	if p.Kind == "" {
+		return errors.New("target kind must be set")
+	}
+	patchJSON, err := sigsyaml.YAMLToJSON([]byte(p.Patch))
+	if err != nil {
+		return fmt.Errorf("failed to parse patch: %w", err)
+	}
+	switch p.Type {
+	case "", StrategicMergePatchType:
+		var m map[string]any
+		if err := json.Unmarshal(patchJSON, &m); err != nil || m == nil {
+			return errors.New("strategic merge patch must be an object")
+		}
+	case JSON6902PatchType:
+		ops, err := jsonpatch.DecodePatch(patchJSON)
+		if err != nil {
+			return fmt.Errorf("failed to decode JSON patch: %w", err)
+		}
+		if len(ops) == 0 {
+			return errors.New("JSON patch must contain at least one operation")
+		}
+		for j, op := range ops {
+			switch op.Kind() {
+			case "add", "remove", "replace", "move", "copy", "test":
+			default:
+				return fmt.Errorf("JSON patch operation %d has unsupported op %q", j, op.Kind())
+			}
+			if _, err := op.Path(); err != nil {
+				return fmt.Errorf("JSON patch operation %d: %w", j, err)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported patch type %q", p.Type)
+	}
+	return nil
+}
+
+func (p Patch) matches(manifest map[string]any) bool {
+	kind, _ := manifest["kind"].(string)
+	if kind != p.Kind {
+		return false
+	}
+	if p.Name == "" {
+		return true
+	}
+	metadata, _ := manifest["metadata"].(map[string]any)
+	name, _ := metadata["name"].(string)
+	return name == p.Name
+}
+
+func (p Patch) apply(manifest map[string]any) (map[string]any, error) {
+	doc, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	patchJSON, err := sigsyaml.YAMLToJSON([]byte(p.Patch))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse patch: %w", err)
+	}
+
+	var patched []byte
+	switch p.Type {
+	case "", StrategicMergePatchType:
+		patched, err = strategicMergePatch(manifest, doc, patchJSON)
+	case JSON6902PatchType:
+		var ops jsonpatch.Patch
+		ops, err = jsonpatch.DecodePatch(patchJSON)
+		if err == nil {
+			patched, err = ops.Apply(doc)
+		}
+	default:
+		err = fmt.Errorf("unsupported patch type %q", p.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is a subset of YAML; decoding with yaml.v3 keeps integers as ints, so that the
+	// patched manifest is encoded the same way as the manifests that weren't patched
+	result := map[string]any{}
+	if err := yaml.Unmarshal(patched, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func strategicMergePatch(manifest map[string]any, doc, patchJSON []byte) ([]byte, error) {
+	apiVersion, _ := manifest["apiVersion"].(string)
+	kind, _ := manifest["kind"].(string)
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return nil, err
+	}
+	dataStruct, err := clientgoscheme.Scheme.New(gv.WithKind(kind))
+	if runtime.IsNotRegisteredError(err) {
+		// there's no patch strategy for custom resources, so fall back to a JSON merge patch
+		return jsonpatch.MergePatch(doc, patchJSON)
+	} else if err != nil {
+		return nil, err
+	}
+	return strategicpatch.StrategicMergePatch(doc, patchJSON, dataStruct)
+}
+
+// applyPatches applies all patches that target the given manifest, in order.
+func applyPatches(patches []Patch, manifest map[string]any) (map[string]any, error) {
+	for i, p := range patches {
+		if !p.matches(manifest) {
+			continue
+		}
+		patched, err := p.apply(manifest)
+		if err != nil {
+			metadata, _ := manifest["metadata"].(map[string]any)
+			return nil, &PatchError{Index: i, Err: fmt.Errorf("failed to apply patch to %s %v: %w", p.Kind, metadata["name"], err)}
+		}
+		manifest = patched
+	}
+	return manifest, nil
+}
@@ -0,0 +1,391 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+)
+
+func TestValidatePatches(t *testing.T) {
+	testCases := []struct {
+		name    string
+		patches []Patch
+		wantErr bool
+	}{
+		{
+			name:    "no patches",
+			patches: nil,
+		},
+		{
+			name: "valid strategic merge patch",
+			patches: []Patch{
+				{Kind: "ServiceAccount", Name: "ztunnel", Patch: "metadata:\n  annotations:\n    foo: bar\n"},
+			},
+		},
+		{
+			name: "valid JSON6902 patch",
+			patches: []Patch{
+				{Kind: "DaemonSet", Type: JSON6902PatchType, Patch: `[{"op": "remove", "path": "/spec/template/spec/nodeSelector"}]`},
+			},
+		},
+		{
+			name:    "missing kind",
+			patches: []Patch{{Patch: "metadata: {}"}},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported type",
+			patches: []Patch{{Kind: "DaemonSet", Type: "Kustomize", Patch: "metadata: {}"}},
+			wantErr: true,
+		},
+		{
+			name:    "strategic merge patch is not an object",
+			patches: []Patch{{Kind: "DaemonSet", Patch: "- foo"}},
+			wantErr: true,
+		},
+		{
+			name:    "malformed YAML",
+			patches: []Patch{{Kind: "DaemonSet", Patch: "metadata: [foo"}},
+			wantErr: true,
+		},
+		{
+			name:    "JSON6902 patch is not a list",
+			patches: []Patch{{Kind: "DaemonSet", Type: JSON6902PatchType, Patch: `{"op": "add"}`}},
+			wantErr: true,
+		},
+		{
+			name:    "JSON6902 patch with unsupported op",
+			patches: []Patch{{Kind: "DaemonSet", Type: JSON6902PatchType, Patch: `[{"op": "merge", "path": "/spec"}]`}},
+			wantErr: true,
+		},
+		{
+			name:    "JSON6902 patch without path",
+			patches: []Patch{{Kind: "DaemonSet", Type: JSON6902PatchType, Patch: `[{"op": "remove"}]`}},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidatePatches(tc.patches)
+			if tc.wantErr {
+				if !IsPatchError(err) {
+					t.Errorf("expected a PatchError, got %v", err)
+				}
+			} else if err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestHelmPostRendererPatches(t *testing.T) {
+	const input = `apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: istio-cni-node
+spec:
+  template:
+    spec:
+      containers:
+        - image: install-cni
+          name: install-cni
+      nodeSelector:
+        kubernetes.io/os: linux
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: ztunnel
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: istio-cni
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: my-widget
+spec:
+  items:
+    - a
+  size: 1
+`
+
+	testCases := []struct {
+		name     string
+		patches  []Patch
+		expected string
+	}{
+		{
+			name: "strategic merge patch merges lists by key",
+			patches: []Patch{
+				{
+					Kind: "DaemonSet",
+					Patch: `spec:
+  template:
+    spec:
+      containers:
+        - name: install-cni
+          resources:
+            requests:
+              cpu: 100m
+      topologySpreadConstraints:
+        - maxSkew: 1
+          topologyKey: topology.kubernetes.io/zone
+          whenUnsatisfiable: ScheduleAnyway
+`,
+				},
+			},
+			expected: `apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  labels:
+    managed-by: sail-operator
+  name: istio-cni-node
+spec:
+  template:
+    spec:
+      containers:
+        - image: install-cni
+          name: install-cni
+          resources:
+            requests:
+              cpu: 100m
+      nodeSelector:
+        kubernetes.io/os: linux
+      topologySpreadConstraints:
+        - maxSkew: 1
+          topologyKey: topology.kubernetes.io/zone
+          whenUnsatisfiable: ScheduleAnyway
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  labels:
+    managed-by: sail-operator
+  name: ztunnel
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  labels:
+    managed-by: sail-operator
+  name: istio-cni
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  labels:
+    managed-by: sail-operator
+  name: my-widget
+spec:
+  items:
+    - a
+  size: 1
+`,
+		},
+		{
+			name: "patches are applied in order and only to objects with the target name",
+			patches: []Patch{
+				{Kind: "ServiceAccount", Name: "ztunnel", Patch: `{"metadata": {"annotations": {"foo": "bar"}}}`},
+				{
+					Kind:  "ServiceAccount",
+					Name:  "ztunnel",
+					Type:  JSON6902PatchType,
+					Patch: `[{"op": "replace", "path": "/metadata/annotations/foo", "value": "baz"}]`,
+				},
+				{Kind: "DaemonSet", Type: JSON6902PatchType, Patch: `[{"op": "remove", "path": "/spec/template/spec/nodeSelector"}]`},
+			},
+			expected: `apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  labels:
+    managed-by: sail-operator
+  name: istio-cni-node
+spec:
+  template:
+    spec:
+      containers:
+        - image: install-cni
+          name: install-cni
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  annotations:
+    foo: baz
+  labels:
+    managed-by: sail-operator
+  name: ztunnel
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  labels:
+    managed-by: sail-operator
+  name: istio-cni
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  labels:
+    managed-by: sail-operator
+  name: my-widget
+spec:
+  items:
+    - a
+  size: 1
+`,
+		},
+		{
+			name: "JSON merge patch is used for unknown kinds",
+			patches: []Patch{
+				{Kind: "Widget", Patch: "spec:\n  items:\n    - b\n  size: 3\n"},
+			},
+			expected: `apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  labels:
+    managed-by: sail-operator
+  name: istio-cni-node
+spec:
+  template:
+    spec:
+      containers:
+        - image: install-cni
+          name: install-cni
+      nodeSelector:
+        kubernetes.io/os: linux
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  labels:
+    managed-by: sail-operator
+  name: ztunnel
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  labels:
+    managed-by: sail-operator
+  name: istio-cni
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  labels:
+    managed-by: sail-operator
+  name: my-widget
+spec:
+  items:
+    - b
+  size: 3
+`,
+		},
+		{
+			name: "patches can't remove the managed-by label",
+			patches: []Patch{
+				{Kind: "ServiceAccount", Name: "istio-cni", Patch: `{"metadata": {"labels": {"managed-by": null}}}`},
+			},
+			expected: `apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  labels:
+    managed-by: sail-operator
+  name: istio-cni-node
+spec:
+  template:
+    spec:
+      containers:
+        - image: install-cni
+          name: install-cni
+      nodeSelector:
+        kubernetes.io/os: linux
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  labels:
+    managed-by: sail-operator
+  name: ztunnel
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  labels:
+    managed-by: sail-operator
+  name: istio-cni
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  labels:
+    managed-by: sail-operator
+  name: my-widget
+spec:
+  items:
+    - a
+  size: 1
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			postRenderer := NewHelmPostRenderer(nil, "", false, constants.ManagedByLabelValue, tc.patches)
+
+			actual, err := postRenderer.Run(bytes.NewBufferString(input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tc.expected, actual.String()); diff != "" {
+				t.Errorf("patches weren't applied properly; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestHelmPostRendererPatchError(t *testing.T) {
+	const input = `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: ztunnel
+`
+	patches := []Patch{
+		{Kind: "ServiceAccount", Patch: `{"metadata": {"annotations": {"foo": "bar"}}}`},
+		{Kind: "ServiceAccount", Type: JSON6902PatchType, Patch: `[{"op": "replace", "path": "/spec/missing", "value": 1}]`},
+	}
+	postRenderer := NewHelmPostRenderer(nil, "", false, constants.ManagedByLabelValue, patches)
+
+	_, err := postRenderer.Run(bytes.NewBufferString(input))
+	wrapped := fmt.Errorf("error while running post render on files: %w", err)
+	if !IsPatchError(wrapped) {
+		t.Fatalf("expected a PatchError, got %v", err)
+	}
+	expectedPrefix := "patches[1]: failed to apply patch to ServiceAccount ztunnel"
+	if msg := err.Error(); !strings.HasPrefix(msg, expectedPrefix) {
+		t.Errorf("expected error message to start with %q, got %q", expectedPrefix, msg)
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v4/pkg/postrenderer"
+	releasecommon "helm.sh/helm/v4/pkg/release/common"
+	releasev1 "helm.sh/helm/v4/pkg/release/v1"
+	releaseutil "helm.sh/helm/v4/pkg/release/v1/util"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ObjectReference identifies an object in a Helm release manifest.
+type ObjectReference struct {
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+}
+
+func (o ObjectReference) String() string {
+	if o.Namespace == "" {
+		return fmt.Sprintf("%s/%s %s", o.APIVersion, o.Kind, o.Name)
+	}
+	return fmt.Sprintf("%s/%s %s/%s", o.APIVersion, o.Kind, o.Namespace, o.Name)
+}
+
+// ReleaseDiff describes how the objects of a Helm release would change if a chart was installed or upgraded.
+type ReleaseDiff struct {
+	// Added contains the objects that are in the rendered chart, but not in the live release.
+	Added []ObjectReference
+	// Changed contains the objects that are in both, but whose manifests differ.
+	Changed []ObjectReference
+	// Removed contains the objects that are in the live release, but not in the rendered chart.
+	Removed []ObjectReference
+}
+
+// IsEmpty returns true if installing the chart wouldn't change any object.
+func (d ReleaseDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Removed) == 0
+}
+
+// Append adds the changes of another release to the diff.
+func (d *ReleaseDiff) Append(other ReleaseDiff) {
+	d.Added = append(d.Added, other.Added...)
+	d.Changed = append(d.Changed, other.Changed...)
+	d.Removed = append(d.Removed, other.Removed...)
+}
+
+// PlanChart renders a chart with the given values and compares the result with the manifest of the
+// live Helm release, without changing anything in the cluster. The rendered manifests go through the
+// same post-renderer as in UpgradeOrInstallChart, so that only the changes that an upgrade would
+// actually apply are reported. If the release doesn't exist, all rendered objects are reported as added.
+func (h *ChartManager) PlanChart(
+	ctx context.Context, resourceFS fs.FS, chartPath string, values Values,
+	namespace, releaseName string, ownerReference *metav1.OwnerReference, opts ...ChartOption,
+) (ReleaseDiff, error) {
+	loadedChart, err := LoadChart(resourceFS, chartPath)
+	if err != nil {
+		return ReleaseDiff{}, fmt.Errorf("failed to load chart from fs: %w", err)
+	}
+
+	rendered, err := RenderLoadedChart(loadedChart, values, namespace, releaseName)
+	if err != nil {
+		return ReleaseDiff{}, err
+	}
+
+	rel, err := h.GetRelease(ctx, namespace, releaseName)
+	if err != nil {
+		return ReleaseDiff{}, err
+	}
+
+	liveManifest := ""
+	if rel != nil {
+		relV1, ok := rel.(*releasev1.Release)
+		if !ok {
+			return ReleaseDiff{}, fmt.Errorf("unexpected release type %T for helm release %s", rel, releaseName)
+		}
+		// a release that was uninstalled or never deployed successfully will be replaced by a fresh install
+		if relV1.Info.Status != releasecommon.StatusUninstalling && (relV1.Info.Status != releasecommon.StatusFailed || relV1.Version > 1) {
+			liveManifest = relV1.Manifest
+		}
+	}
+
+	// in server-side apply mode, the fields owned by other field managers are left out of the plan, as they
+	// won't be applied either
+	postRenderer := h.newConflictFilter(ctx, namespace,
+		newPostRenderer(ownerReference, liveManifest != "", h.managedByValue, newChartOptions(opts)))
+	return DiffManifests(liveManifest, rendered, postRenderer)
+}
+
+// DiffManifests compares the manifest of a live Helm release with the templates rendered by
+// RenderLoadedChart. Partials, NOTES.txt and hooks are ignored, as they are never part of the
+// release manifest. If postRenderer is not nil, it is applied to the rendered templates first.
+func DiffManifests(liveManifest string, rendered map[string]string, postRenderer postrenderer.PostRenderer) (ReleaseDiff, error) {
+	files := map[string]string{}
+	for name, content := range rendered {
+		if !strings.HasSuffix(name, "NOTES.txt") {
+			files[name] = content
+		}
+	}
+	_, manifests, err := releaseutil.SortManifests(files, nil, releaseutil.InstallOrder)
+	if err != nil {
+		return ReleaseDiff{}, fmt.Errorf("failed to sort rendered manifests: %w", err)
+	}
+
+	desired := &bytes.Buffer{}
+	for _, m := range manifests {
+		fmt.Fprintf(desired, "---\n%s\n", m.Content)
+	}
+	if postRenderer != nil {
+		desired, err = postRenderer.Run(desired)
+		if err != nil {
+			return ReleaseDiff{}, fmt.Errorf("failed to post-render manifests: %w", err)
+		}
+	}
+
+	desiredObjects, err := parseManifest(desired)
+	if err != nil {
+		return ReleaseDiff{}, fmt.Errorf("failed to parse rendered manifests: %w", err)
+	}
+	liveObjects, err := parseManifest(strings.NewReader(liveManifest))
+	if err != nil {
+		return ReleaseDiff{}, fmt.Errorf("failed to parse release manifest: %w", err)
+	}
+
+	diff := ReleaseDiff{}
+	for ref, desiredObj := range desiredObjects {
+		if liveObj, found := liveObjects[ref]; !found {
+			diff.Added = append(diff.Added, ref)
+		} else if !reflect.DeepEqual(desiredObj, liveObj) {
+			diff.Changed = append(diff.Changed, ref)
+		}
+	}
+	for ref := range liveObjects {
+		if _, found := desiredObjects[ref]; !found {
+			diff.Removed = append(diff.Removed, ref)
+		}
+	}
+	sortObjectReferences(diff.Added)
+	sortObjectReferences(diff.Changed)
+	sortObjectReferences(diff.Removed)
+	return diff, nil
+}
+
+// ManifestObjects returns the objects in a Helm release manifest, sorted by their ObjectReference.
+func ManifestObjects(manifest string) ([]*unstructured.Unstructured, error) {
+	objects, err := parseManifest(strings.NewReader(manifest))
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]ObjectReference, 0, len(objects))
+	for ref := range objects {
+		refs = append(refs, ref)
+	}
+	sortObjectReferences(refs)
+
+	result := make([]*unstructured.Unstructured, 0, len(refs))
+	for _, ref := range refs {
+		result = append(result, &unstructured.Unstructured{Object: objects[ref]})
+	}
+	return result, nil
+}
+
+func parseManifest(r io.Reader) (map[ObjectReference]map[string]any, error) {
+	objects := map[ObjectReference]map[string]any{}
+	decoder := yaml.NewDecoder(r)
+	for {
+		manifest := map[string]any{}
+		if err := decoder.Decode(&manifest); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if len(manifest) == 0 {
+			continue
+		}
+
+		u := unstructured.Unstructured{Object: manifest}
+		ref := ObjectReference{
+			APIVersion: u.GetAPIVersion(),
+			Kind:       u.GetKind(),
+			Namespace:  u.GetNamespace(),
+			Name:       u.GetName(),
+		}
+		objects[ref] = manifest
+	}
+	return objects, nil
+}
+
+func sortObjectReferences(refs []ObjectReference) {
+	slices.SortFunc(refs, func(a, b ObjectReference) int {
+		return strings.Compare(a.String(), b.String())
+	})
+}
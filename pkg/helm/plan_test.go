@@ -0,0 +1,132 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestDiffManifests(t *testing.T) {
+	liveManifest := `---
+# Source: test/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unchanged
+  labels:
+    managed-by: sail-operator
+  ownerReferences:
+  - apiVersion: sailoperator.io/v1
+    kind: IstioRevision
+    name: default
+    uid: "123"
+    controller: true
+data:
+  key: value
+---
+# Source: test/templates/changed.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: changed
+  labels:
+    managed-by: sail-operator
+  ownerReferences:
+  - apiVersion: sailoperator.io/v1
+    kind: IstioRevision
+    name: default
+    uid: "123"
+    controller: true
+data:
+  key: old
+---
+# Source: test/templates/removed.yaml
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: removed
+`
+	rendered := map[string]string{
+		"test/templates/_helpers.tpl": "",
+		"test/templates/NOTES.txt":    "Thank you for installing",
+		"test/templates/configmap.yaml": `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unchanged
+data:
+  key: value
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: changed
+data:
+  key: new
+`,
+		"test/templates/added.yaml": `apiVersion: v1
+kind: Service
+metadata:
+  name: added
+  namespace: istio-system
+`,
+	}
+	ownerReference := &metav1.OwnerReference{
+		APIVersion: "sailoperator.io/v1",
+		Kind:       "IstioRevision",
+		Name:       "default",
+		UID:        "123",
+		Controller: ptr.Of(true),
+	}
+
+	diff, err := DiffManifests(liveManifest, rendered, NewHelmPostRenderer(ownerReference, "", true, "sail-operator", nil))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	expected := ReleaseDiff{
+		Added:   []ObjectReference{{APIVersion: "v1", Kind: "Service", Namespace: "istio-system", Name: "added"}},
+		Changed: []ObjectReference{{APIVersion: "v1", Kind: "ConfigMap", Name: "changed"}},
+		Removed: []ObjectReference{{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRole", Name: "removed"}},
+	}
+	if d := cmp.Diff(expected, diff); d != "" {
+		t.Errorf("unexpected diff (-expected, +actual):\n%s", d)
+	}
+}
+
+func TestDiffManifestsWithoutRelease(t *testing.T) {
+	rendered := map[string]string{
+		"test/templates/configmap.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n",
+	}
+
+	diff, err := DiffManifests("", rendered, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	expected := ReleaseDiff{
+		Added: []ObjectReference{{APIVersion: "v1", Kind: "ConfigMap", Name: "cm"}},
+	}
+	if d := cmp.Diff(expected, diff); d != "" {
+		t.Errorf("unexpected diff (-expected, +actual):\n%s", d)
+	}
+	if diff.IsEmpty() {
+		t.Error("expected diff not to be empty")
+	}
+}
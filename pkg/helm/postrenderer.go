@@ -24,9 +24,11 @@ import (
 	"gopkg.in/yaml.v3"
 	"helm.sh/helm/v4/pkg/postrenderer"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
 )
 
 const (
@@ -35,24 +37,43 @@ const (
 )
 
 // NewHelmPostRenderer creates a Helm PostRenderer that adds the following to each rendered manifest:
+// - applies the given patches that target the manifest
 // - adds the "managed-by" label with the given managedByValue
 // - adds the specified OwnerReference
 // It also removes the failurePolicy field from ValidatingWebhookConfigurations on updates, so
 // the in-cluster setting stays as-is, to prevent clashing with the istiod validation controller.
-func NewHelmPostRenderer(ownerReference *metav1.OwnerReference, ownerNamespace string, isUpdate bool, managedByValue string) postrenderer.PostRenderer {
+func NewHelmPostRenderer(ownerReference *metav1.OwnerReference, ownerNamespace string, isUpdate bool, managedByValue string,
+	patches []Patch,
+) postrenderer.PostRenderer {
 	return HelmPostRenderer{
 		ownerReference: ownerReference,
 		ownerNamespace: ownerNamespace,
 		isUpdate:       isUpdate,
 		managedByValue: managedByValue,
+		patches:        patches,
+	}
+}
+
+// newPostRenderer creates the HelmPostRenderer for a single install, upgrade or plan of a chart.
+func newPostRenderer(ownerReference *metav1.OwnerReference, isUpdate bool, managedByValue string, opts chartOptions) HelmPostRenderer {
+	return HelmPostRenderer{
+		ownerReference:          ownerReference,
+		isUpdate:                isUpdate,
+		managedByValue:          managedByValue,
+		patches:                 opts.patches,
+		clusterScopedNameSuffix: opts.clusterScopedNameSuffix,
+		keptFields:              opts.keptFields,
 	}
 }
 
 type HelmPostRenderer struct {
-	ownerReference *metav1.OwnerReference
-	ownerNamespace string
-	isUpdate       bool
-	managedByValue string
+	ownerReference          *metav1.OwnerReference
+	ownerNamespace          string
+	isUpdate                bool
+	managedByValue          string
+	patches                 []Patch
+	clusterScopedNameSuffix string
+	keptFields              []Drift
 }
 
 var _ postrenderer.PostRenderer = HelmPostRenderer{}
@@ -62,6 +83,7 @@ func (pr HelmPostRenderer) Run(renderedManifests *bytes.Buffer) (modifiedManifes
 	encoder := yaml.NewEncoder(modifiedManifests)
 	encoder.SetIndent(2)
 	decoder := yaml.NewDecoder(renderedManifests)
+	var manifests []map[string]any
 	for {
 		manifest := map[string]any{}
 
@@ -72,8 +94,29 @@ func (pr HelmPostRenderer) Run(renderedManifests *bytes.Buffer) (modifiedManifes
 			return nil, err
 		}
 
-		if manifest == nil {
-			continue
+		if manifest != nil {
+			manifests = append(manifests, manifest)
+		}
+	}
+
+	renamedClusterRoles := pr.clusterRolesToRename(manifests)
+	for _, manifest := range manifests {
+		// patches are applied first, so that they can't remove the owner reference or the managed-by label.
+		// Cluster-scoped objects are renamed after the patches, so that patches can target the names from the chart.
+		manifest, err = applyPatches(pr.patches, manifest)
+		if err != nil {
+			return nil, err
+		}
+
+		manifest, err = pr.renameClusterScopedObject(manifest, renamedClusterRoles)
+		if err != nil {
+			return nil, err
+		}
+
+		// kept fields refer to the live objects, so they're applied after the objects are renamed
+		manifest, err = pr.keepFields(manifest)
+		if err != nil {
+			return nil, err
 		}
 
 		manifest, err = pr.addOwnerReference(manifest)
@@ -103,6 +146,55 @@ func (pr HelmPostRenderer) Run(renderedManifests *bytes.Buffer) (modifiedManifes
 	return modifiedManifests, nil
 }
 
+// clusterRolesToRename returns the names of the ClusterRoles among the manifests, if cluster-scoped objects
+// are to be renamed. Only references to these ClusterRoles are renamed in ClusterRoleBindings.
+func (pr HelmPostRenderer) clusterRolesToRename(manifests []map[string]any) sets.Set[string] {
+	names := sets.New[string]()
+	if pr.clusterScopedNameSuffix == "" {
+		return names
+	}
+	for _, manifest := range manifests {
+		if isRBACObject(manifest, "ClusterRole") {
+			name, _, _ := unstructured.NestedString(manifest, "metadata", "name")
+			names.Insert(name)
+		}
+	}
+	return names
+}
+
+// renameClusterScopedObject appends the clusterScopedNameSuffix to the name of ClusterRoles and
+// ClusterRoleBindings, and to the roleRef of ClusterRoleBindings that bind one of the renamed ClusterRoles.
+func (pr HelmPostRenderer) renameClusterScopedObject(manifest map[string]any, renamedClusterRoles sets.Set[string]) (map[string]any, error) {
+	if pr.clusterScopedNameSuffix == "" {
+		return manifest, nil
+	}
+	if !isRBACObject(manifest, "ClusterRole") && !isRBACObject(manifest, "ClusterRoleBinding") {
+		return manifest, nil
+	}
+
+	name, _, _ := unstructured.NestedString(manifest, "metadata", "name")
+	if err := unstructured.SetNestedField(manifest, name+pr.clusterScopedNameSuffix, "metadata", "name"); err != nil {
+		return nil, err
+	}
+
+	if isRBACObject(manifest, "ClusterRoleBinding") {
+		roleKind, _, _ := unstructured.NestedString(manifest, "roleRef", "kind")
+		roleName, _, _ := unstructured.NestedString(manifest, "roleRef", "name")
+		if roleKind == "ClusterRole" && renamedClusterRoles.Has(roleName) {
+			if err := unstructured.SetNestedField(manifest, roleName+pr.clusterScopedNameSuffix, "roleRef", "name"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return manifest, nil
+}
+
+func isRBACObject(manifest map[string]any, kind string) bool {
+	apiVersion, _, _ := unstructured.NestedString(manifest, "apiVersion")
+	objKind, _, _ := unstructured.NestedString(manifest, "kind")
+	return apiVersion == rbacv1.SchemeGroupVersion.String() && objKind == kind
+}
+
 func (pr HelmPostRenderer) removeValidatingWebhookFailurePolicy(manifest map[string]any) (map[string]any, error) {
 	apiVersion, _, _ := unstructured.NestedString(manifest, "apiVersion")
 	if apiVersion != admissionregistrationv1.SchemeGroupVersion.String() {
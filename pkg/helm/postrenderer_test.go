@@ -323,3 +323,91 @@ spec:
 		})
 	}
 }
+
+func TestHelmPostRendererClusterScopedNameSuffix(t *testing.T) {
+	input := `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: istio-cni
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: istio-cni
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: istio-cni
+subjects:
+  - kind: ServiceAccount
+    name: istio-cni
+    namespace: istio-cni
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: istio-cni-view
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: view
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: istio-cni
+  namespace: istio-cni
+`
+	expected := `apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  labels:
+    managed-by: sail-operator
+  name: istio-cni-pool-a
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  labels:
+    managed-by: sail-operator
+  name: istio-cni-pool-a
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: istio-cni-pool-a
+subjects:
+  - kind: ServiceAccount
+    name: istio-cni
+    namespace: istio-cni
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  labels:
+    managed-by: sail-operator
+  name: istio-cni-view-pool-a
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: view
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  labels:
+    managed-by: sail-operator
+  name: istio-cni
+  namespace: istio-cni
+`
+
+	postRenderer := newPostRenderer(nil, false, constants.ManagedByLabelValue,
+		newChartOptions([]ChartOption{WithClusterScopedNameSuffix("-pool-a")}))
+	actual, err := postRenderer.Run(bytes.NewBufferString(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(expected, actual.String()); diff != "" {
+		t.Errorf("cluster-scoped objects weren't renamed properly; diff (-expected, +actual):\n%v", diff)
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v4/pkg/kube"
+	"helm.sh/helm/v4/pkg/postrenderer"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// FieldManager is the field manager that the ChartManager uses in server-side apply mode. It's the
+// same name that Helm derives from the operator binary in client-side apply mode, so that enabling
+// server-side apply doesn't change the ownership of the fields the operator has applied before.
+const FieldManager = "sail-operator"
+
+// FieldConflict describes a field of a rendered object that wasn't applied, because another field
+// manager owns the field and has set it to a different value.
+type FieldConflict struct {
+	Object ObjectReference
+	// Field is the path of the field, e.g. spec.replicas or spec.template.spec.containers[0].image.
+	Field string
+	// Manager is the field manager that owns the field, e.g. kube-controller-manager.
+	Manager string
+}
+
+func (c FieldConflict) String() string {
+	return fmt.Sprintf("%s %s (owned by %s)", c.Object, c.Field, c.Manager)
+}
+
+// ConflictReporter is implemented by chart managers that can apply Helm releases with server-side
+// apply and skip the fields owned by other field managers.
+type ConflictReporter interface {
+	// FieldConflicts returns the fields that were skipped during the last install or upgrade of the
+	// given release. The second return value is false if server-side apply isn't enabled.
+	FieldConflicts(namespace, releaseName string) ([]FieldConflict, bool)
+}
+
+// WithServerSideApply makes the ChartManager apply the objects of Helm releases with server-side apply,
+// using FieldManager as the field manager. Before each install or upgrade, the live objects are read with
+// the given reader, and the fields that another field manager owns with a different value (e.g.
+// spec.replicas of a Deployment scaled by a HorizontalPodAutoscaler) are removed from the rendered
+// manifests instead of being overwritten. The removed fields are reported by FieldConflicts.
+//
+// Helm reads the name of the field manager from a package variable, so this option affects all
+// Helm clients in the process.
+func WithServerSideApply(reader client.Reader) ChartManagerOption {
+	return func(cm *ChartManager) {
+		cm.fieldOwnerReader = reader
+		kube.ManagedFieldsManager = FieldManager
+	}
+}
+
+// FieldConflicts returns the fields that were skipped during the last install or upgrade of the given
+// release because they are owned by other field managers. The second return value is false if the
+// ChartManager doesn't use server-side apply.
+func (h *ChartManager) FieldConflicts(namespace, releaseName string) ([]FieldConflict, bool) {
+	if !h.serverSideApply() {
+		return nil, false
+	}
+	h.conflictsMu.Lock()
+	defer h.conflictsMu.Unlock()
+	return slices.Clone(h.conflicts[namespace+"/"+releaseName]), true
+}
+
+func (h *ChartManager) serverSideApply() bool {
+	return h.fieldOwnerReader != nil
+}
+
+func (h *ChartManager) setFieldConflicts(namespace, releaseName string, conflicts []FieldConflict) {
+	h.conflictsMu.Lock()
+	defer h.conflictsMu.Unlock()
+	if h.conflicts == nil {
+		h.conflicts = map[string][]FieldConflict{}
+	}
+	if len(conflicts) == 0 {
+		delete(h.conflicts, namespace+"/"+releaseName)
+		return
+	}
+	h.conflicts[namespace+"/"+releaseName] = conflicts
+}
+
+// conflictFilter is a PostRenderer that runs another PostRenderer and then removes the fields that are
+// owned by other field managers from its output.
+type conflictFilter struct {
+	ctx       context.Context
+	reader    client.Reader
+	namespace string
+	next      postrenderer.PostRenderer
+	conflicts []FieldConflict
+}
+
+var _ postrenderer.PostRenderer = &conflictFilter{}
+
+// newConflictFilter wraps the given PostRenderer in a conflictFilter. If the ChartManager doesn't use
+// server-side apply, the filter only runs the wrapped PostRenderer.
+func (h *ChartManager) newConflictFilter(ctx context.Context, namespace string, next postrenderer.PostRenderer) *conflictFilter {
+	return &conflictFilter{ctx: ctx, reader: h.fieldOwnerReader, namespace: namespace, next: next}
+}
+
+func (f *conflictFilter) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	manifests, err := f.next.Run(renderedManifests)
+	if err != nil || f.reader == nil {
+		return manifests, err
+	}
+
+	f.conflicts = nil
+	modifiedManifests := &bytes.Buffer{}
+	encoder := yaml.NewEncoder(modifiedManifests)
+	encoder.SetIndent(2)
+	decoder := yaml.NewDecoder(manifests)
+	for {
+		manifest := map[string]any{}
+		if err := decoder.Decode(&manifest); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if len(manifest) == 0 {
+			continue
+		}
+
+		if err := f.removeConflictingFields(manifest); err != nil {
+			return nil, err
+		}
+		if err := encoder.Encode(manifest); err != nil {
+			return nil, err
+		}
+	}
+	return modifiedManifests, nil
+}
+
+// removeConflictingFields removes the fields from the manifest that another field manager of the live
+// object owns with a different value.
+func (f *conflictFilter) removeConflictingFields(manifest map[string]any) error {
+	desired := unstructured.Unstructured{Object: manifest}
+	ref := ObjectReference{
+		APIVersion: desired.GetAPIVersion(),
+		Kind:       desired.GetKind(),
+		Namespace:  desired.GetNamespace(),
+		Name:       desired.GetName(),
+	}
+	namespace := ref.Namespace
+	if namespace == "" {
+		// ignored by the client for cluster-scoped objects
+		namespace = f.namespace
+	}
+
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(desired.GroupVersionKind())
+	if err := f.reader.Get(f.ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, live); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get %s: %w", ref, err)
+	}
+
+	for _, conflict := range findFieldConflicts(manifest, live.Object, live.GetManagedFields()) {
+		conflict.Object = ref
+		f.conflicts = append(f.conflicts, conflict)
+	}
+	return nil
+}
+
+// findFieldConflicts removes the fields from the desired object that are owned by a field manager other
+// than FieldManager and whose live value differs from the desired value. It returns the removed fields.
+func findFieldConflicts(desired, live map[string]any, managedFields []metav1.ManagedFieldsEntry) []FieldConflict {
+	var conflicts []FieldConflict
+	for _, entry := range managedFields {
+		if entry.Manager == FieldManager || entry.Subresource == "status" || entry.FieldsV1 == nil {
+			continue
+		}
+		var fields map[string]any
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		for _, path := range ownedLeaves(fields, nil) {
+			if field, removed := removeIfConflicting(desired, live, path); removed {
+				conflicts = append(conflicts, FieldConflict{Field: field, Manager: entry.Manager})
+			}
+		}
+	}
+	return conflicts
+}
+
+// ownedLeaves returns the paths of the leaf fields in a FieldsV1 set, e.g. ["f:spec", "f:replicas"].
+// The paths are sorted, so that the conflicts are reported in a stable order.
+func ownedLeaves(fields map[string]any, prefix []string) [][]string {
+	var leaves [][]string
+	for _, key := range sortedKeys(fields) {
+		if key == "." {
+			continue
+		}
+		path := append(slices.Clone(prefix), key)
+		children, _ := fields[key].(map[string]any)
+		if len(children) == 0 {
+			leaves = append(leaves, path)
+			continue
+		}
+		leaves = append(leaves, ownedLeaves(children, path)...)
+	}
+	return leaves
+}
+
+// removeIfConflicting looks up the field with the given FieldsV1 path in the desired and live object.
+// If the field is set in the desired object to a different value than in the live object, it's removed
+// from the desired object. Only paths made of field names ("f:") and associative list keys ("k:") are
+// supported; fields in sets and plain lists are left alone.
+func removeIfConflicting(desired, live map[string]any, path []string) (string, bool) {
+	var desiredNode, liveNode any = desired, live
+	var parent map[string]any
+	var name, field string
+	for _, elem := range path {
+		switch {
+		case strings.HasPrefix(elem, "f:"):
+			desiredMap, ok := desiredNode.(map[string]any)
+			if !ok {
+				return "", false
+			}
+			liveMap, _ := liveNode.(map[string]any)
+			name = strings.TrimPrefix(elem, "f:")
+			if desiredNode, ok = desiredMap[name]; !ok {
+				return "", false
+			}
+			parent, liveNode = desiredMap, liveMap[name]
+			if field != "" {
+				field += "."
+			}
+			field += name
+		case strings.HasPrefix(elem, "k:"):
+			var key map[string]any
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(elem, "k:")), &key); err != nil {
+				return "", false
+			}
+			desiredList, _ := desiredNode.([]any)
+			index := findListItem(desiredList, key)
+			if index < 0 {
+				return "", false
+			}
+			liveList, _ := liveNode.([]any)
+			desiredNode, liveNode = desiredList[index], nil
+			if liveIndex := findListItem(liveList, key); liveIndex >= 0 {
+				liveNode = liveList[liveIndex]
+			}
+			parent = nil
+			field += fmt.Sprintf("[%d]", index)
+		default:
+			return "", false
+		}
+	}
+	if parent == nil {
+		return "", false
+	}
+
+	var normalizedDesired, normalizedLive any
+	if normalize(desiredNode, &normalizedDesired) != nil || normalize(liveNode, &normalizedLive) != nil {
+		return "", false
+	}
+	differs := false
+	compareValues(field, nil, normalizedDesired, normalizedLive, func(string, []any, any) { differs = true })
+	if !differs {
+		return "", false
+	}
+	delete(parent, name)
+	return field, true
+}
+
+// findListItem returns the index of the item in the list whose fields match the given key, or -1.
+func findListItem(list []any, key map[string]any) int {
+	return slices.IndexFunc(list, func(item any) bool {
+		fields, ok := item.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, v := range key {
+			if scalarString(fields[k]) != scalarString(v) {
+				return false
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,254 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helm
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+const ssaManifest = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: istiod
+  labels:
+    app: istiod
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: discovery
+        image: istio/pilot:1.27.0
+        ports:
+        - containerPort: 8080
+          protocol: TCP
+          name: http-debug
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: not-found
+data:
+  key: value
+`
+
+func TestConflictFilter(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "istiod",
+			Namespace: "istio-system",
+			Labels:    map[string]string{"app": "istiod"},
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{
+					Manager:    FieldManager,
+					APIVersion: "apps/v1",
+					Operation:  metav1.ManagedFieldsOperationApply,
+					FieldsType: "FieldsV1",
+					FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:labels":{"f:app":{}}},"f:spec":{"f:replicas":{}}}`)},
+				},
+				{
+					// the HorizontalPodAutoscaler scales the deployment
+					Manager:     "kube-controller-manager",
+					APIVersion:  "apps/v1",
+					Operation:   metav1.ManagedFieldsOperationUpdate,
+					Subresource: "scale",
+					FieldsType:  "FieldsV1",
+					FieldsV1:    &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:replicas":{}}}`)},
+				},
+				{
+					// a GitOps tool pins the image, but sets the port to the same value as the chart
+					Manager:    "gitops",
+					APIVersion: "apps/v1",
+					Operation:  metav1.ManagedFieldsOperationApply,
+					FieldsType: "FieldsV1",
+					FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec":{"f:template":{"f:spec":{"f:containers":{` +
+						`"k:{\"name\":\"discovery\"}":{".":{},"f:image":{},"f:ports":{` +
+						`"k:{\"containerPort\":8080,\"protocol\":\"TCP\"}":{".":{},"f:containerPort":{},"f:name":{}}}}}}}}}`)},
+				},
+				{
+					Manager:     "kube-controller-manager",
+					APIVersion:  "apps/v1",
+					Operation:   metav1.ManagedFieldsOperationUpdate,
+					Subresource: "status",
+					FieldsType:  "FieldsV1",
+					FieldsV1:    &metav1.FieldsV1{Raw: []byte(`{"f:status":{"f:replicas":{}}}`)},
+				},
+			},
+		},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.Of(int32(3)),
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{
+						Name:  "discovery",
+						Image: "registry.example.com/pilot:1.27.0",
+						Ports: []corev1.ContainerPort{{ContainerPort: 8080, Protocol: corev1.ProtocolTCP, Name: "http-debug"}},
+					}},
+				},
+			},
+		},
+	}
+
+	cl := newDriftFakeClientBuilder().WithObjects(deployment).WithReturnManagedFields().Build()
+	filter := &conflictFilter{
+		ctx:       context.Background(),
+		reader:    cl,
+		namespace: "istio-system",
+		next:      passThroughPostRenderer{},
+	}
+	out, err := filter.Run(bytes.NewBufferString(ssaManifest))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	ref := ObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "istiod"}
+	expectedConflicts := []FieldConflict{
+		{Object: ref, Field: "spec.replicas", Manager: "kube-controller-manager"},
+		{Object: ref, Field: "spec.template.spec.containers[0].image", Manager: "gitops"},
+	}
+	if d := cmp.Diff(expectedConflicts, filter.conflicts); d != "" {
+		t.Errorf("unexpected conflicts (-expected, +actual):\n%s", d)
+	}
+
+	var manifests []map[string]any
+	decoder := yaml.NewDecoder(out)
+	for {
+		manifest := map[string]any{}
+		if decoder.Decode(&manifest) != nil {
+			break
+		}
+		manifests = append(manifests, manifest)
+	}
+	expectedDeploymentSpec := map[string]any{
+		"template": map[string]any{
+			"spec": map[string]any{
+				"containers": []any{map[string]any{
+					"name":  "discovery",
+					"ports": []any{map[string]any{"containerPort": 8080, "protocol": "TCP", "name": "http-debug"}},
+				}},
+			},
+		},
+	}
+	if len(manifests) != 2 {
+		t.Fatalf("expected 2 manifests, got %d", len(manifests))
+	}
+	if d := cmp.Diff(expectedDeploymentSpec, manifests[0]["spec"]); d != "" {
+		t.Errorf("unexpected deployment spec (-expected, +actual):\n%s", d)
+	}
+	if d := cmp.Diff(map[string]any{"key": "value"}, manifests[1]["data"]); d != "" {
+		t.Errorf("unexpected configmap data (-expected, +actual):\n%s", d)
+	}
+}
+
+func TestOwnedLeaves(t *testing.T) {
+	fields := map[string]any{
+		"f:spec": map[string]any{
+			"f:replicas": map[string]any{},
+			"f:selector": map[string]any{},
+			"f:template": map[string]any{
+				"f:spec": map[string]any{
+					"f:containers": map[string]any{
+						`k:{"name":"discovery"}`: map[string]any{".": map[string]any{}, "f:image": map[string]any{}},
+					},
+				},
+			},
+		},
+		"f:metadata": map[string]any{
+			"f:finalizers": map[string]any{".": map[string]any{}, `v:"example.com/finalizer"`: map[string]any{}},
+		},
+	}
+	expected := [][]string{
+		{"f:metadata", "f:finalizers", `v:"example.com/finalizer"`},
+		{"f:spec", "f:replicas"},
+		{"f:spec", "f:selector"},
+		{"f:spec", "f:template", "f:spec", "f:containers", `k:{"name":"discovery"}`, "f:image"},
+	}
+	if d := cmp.Diff(expected, ownedLeaves(fields, nil)); d != "" {
+		t.Errorf("unexpected leaves (-expected, +actual):\n%s", d)
+	}
+}
+
+func TestRemoveIfConflicting(t *testing.T) {
+	tests := []struct {
+		name          string
+		desired       map[string]any
+		live          map[string]any
+		path          []string
+		expectedField string
+	}{
+		{
+			name:          "different value",
+			desired:       map[string]any{"spec": map[string]any{"replicas": 1}},
+			live:          map[string]any{"spec": map[string]any{"replicas": int64(3)}},
+			path:          []string{"f:spec", "f:replicas"},
+			expectedField: "spec.replicas",
+		},
+		{
+			name:    "same value",
+			desired: map[string]any{"spec": map[string]any{"replicas": 3}},
+			live:    map[string]any{"spec": map[string]any{"replicas": int64(3)}},
+			path:    []string{"f:spec", "f:replicas"},
+		},
+		{
+			name:    "not in manifest",
+			desired: map[string]any{"spec": map[string]any{}},
+			live:    map[string]any{"spec": map[string]any{"replicas": int64(3)}},
+			path:    []string{"f:spec", "f:replicas"},
+		},
+		{
+			name:    "list item not in manifest",
+			desired: map[string]any{"env": []any{map[string]any{"name": "A", "value": "1"}}},
+			live:    map[string]any{"env": []any{map[string]any{"name": "B", "value": "2"}}},
+			path:    []string{"f:env", `k:{"name":"B"}`, "f:value"},
+		},
+		{
+			name:          "list item with different value",
+			desired:       map[string]any{"env": []any{map[string]any{"name": "A", "value": "1"}, map[string]any{"name": "B", "value": "1"}}},
+			live:          map[string]any{"env": []any{map[string]any{"name": "B", "value": "2"}}},
+			path:          []string{"f:env", `k:{"name":"B"}`, "f:value"},
+			expectedField: "env[1].value",
+		},
+		{
+			name:    "set element",
+			desired: map[string]any{"finalizers": []any{"a"}},
+			live:    map[string]any{"finalizers": []any{"b"}},
+			path:    []string{"f:finalizers", `v:"b"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field, removed := removeIfConflicting(tt.desired, tt.live, tt.path)
+			if field != tt.expectedField || removed != (tt.expectedField != "") {
+				t.Errorf("expected field %q to be removed, got %q (removed: %v)", tt.expectedField, field, removed)
+			}
+		})
+	}
+}
+
+type passThroughPostRenderer struct{}
+
+func (passThroughPostRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	return renderedManifests, nil
+}
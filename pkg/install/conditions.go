@@ -0,0 +1,181 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"fmt"
+	"slices"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ConditionInstalled signifies whether the Helm releases of the installation are up to date.
+	ConditionInstalled v1.ConditionType = "Installed"
+
+	// ConditionCRDsReady signifies whether the CRDs managed by the installation are established.
+	// It is only set if the installation manages CRDs.
+	ConditionCRDsReady v1.ConditionType = "CRDsReady"
+
+	// ConditionIstiodReady signifies whether the istiod Deployment is ready.
+	ConditionIstiodReady v1.ConditionType = "IstiodReady"
+
+	// ConditionWebhooksReady signifies whether the sidecar injection and validation webhooks of
+	// istiod are ready, i.e. have their CA bundle injected.
+	ConditionWebhooksReady v1.ConditionType = "WebhooksReady"
+
+	// ConditionReady signifies whether istiod and all the optional components are ready.
+	ConditionReady v1.ConditionType = "Ready"
+)
+
+const (
+	// ReasonInstalled indicates that the Helm releases were installed successfully.
+	ReasonInstalled v1.ConditionReason = "Installed"
+
+	// ReasonReconcileError indicates that the installation couldn't be reconciled.
+	ReasonReconcileError v1.ConditionReason = "ReconcileError"
+
+	// ReasonReady indicates that the objects are ready.
+	ReasonReady v1.ConditionReason = "Ready"
+
+	// ReasonNotReady indicates that some objects aren't ready.
+	ReasonNotReady v1.ConditionReason = "NotReady"
+
+	// ReasonReadinessUnknown indicates that the readiness couldn't be determined, e.g. because
+	// the component isn't installed yet.
+	ReasonReadinessUnknown v1.ConditionReason = "ReadinessUnknown"
+)
+
+// GetCondition returns the condition of the specified type, or a condition
+// with Status=Unknown if the status doesn't contain it.
+func (s Status) GetCondition(conditionType v1.ConditionType) v1.StatusCondition {
+	return v1.GetCondition(s.Conditions, conditionType)
+}
+
+// setReadiness sets Ready and the conditions of the status. The
+// LastTransitionTime of the conditions whose status didn't change is taken
+// from the previous conditions.
+func (s *Status) setReadiness(previous []v1.StatusCondition) {
+	s.Ready = s.Installed && s.Istiod.Ready &&
+		componentReady(s.CNI) && componentReady(s.ZTunnel) && componentReady(s.Gateway) && componentReady(s.DefaultTag) &&
+		(s.CRDState == "" || s.CRDState == CRDManagementStateReady)
+
+	conditions := slices.Clone(previous)
+	if s.Installed {
+		v1.SetCondition(&conditions, v1.StatusCondition{Type: ConditionInstalled, Status: metav1.ConditionTrue, Reason: ReasonInstalled})
+	} else {
+		message := "installation hasn't completed"
+		if s.Error != nil {
+			message = s.Error.Error()
+		}
+		v1.SetCondition(&conditions, v1.StatusCondition{
+			Type: ConditionInstalled, Status: metav1.ConditionFalse, Reason: ReasonReconcileError, Message: message,
+		})
+	}
+
+	if s.CRDState == "" {
+		v1.RemoveCondition(&conditions, ConditionCRDsReady)
+	} else {
+		v1.SetCondition(&conditions, crdsReadyCondition(s.CRDState, s.CRDMessage))
+	}
+
+	v1.SetCondition(&conditions, resourcesReadyCondition(ConditionIstiodReady, s.Istiod, "Deployment"))
+	v1.SetCondition(&conditions, resourcesReadyCondition(ConditionWebhooksReady, s.Istiod,
+		"MutatingWebhookConfiguration", "ValidatingWebhookConfiguration"))
+
+	if s.Ready {
+		v1.SetCondition(&conditions, v1.StatusCondition{Type: ConditionReady, Status: metav1.ConditionTrue, Reason: ReasonReady})
+	} else {
+		v1.SetCondition(&conditions, v1.StatusCondition{
+			Type: ConditionReady, Status: metav1.ConditionFalse, Reason: ReasonNotReady, Message: s.notReadyMessage(),
+		})
+	}
+	s.Conditions = conditions
+}
+
+// notReadyMessage explains why the installation isn't ready.
+func (s *Status) notReadyMessage() string {
+	switch {
+	case !s.Installed:
+		return "installation hasn't completed"
+	case s.CRDState != "" && s.CRDState != CRDManagementStateReady:
+		return "CRDs: " + s.CRDMessage
+	}
+	components := []struct {
+		name   string
+		status *ComponentStatus
+	}{
+		{"istiod", &s.Istiod},
+		{"istio-cni", s.CNI},
+		{"ztunnel", s.ZTunnel},
+		{"gateway", s.Gateway},
+		{"default revision tag", s.DefaultTag},
+	}
+	for _, c := range components {
+		if !componentReady(c.status) {
+			return fmt.Sprintf("%s: %s", c.name, c.status.Message)
+		}
+	}
+	return ""
+}
+
+// componentReady returns true if the optional component is ready or isn't
+// requested.
+func componentReady(status *ComponentStatus) bool {
+	return status == nil || status.Ready
+}
+
+func crdsReadyCondition(state CRDManagementState, message string) v1.StatusCondition {
+	c := v1.StatusCondition{Type: ConditionCRDsReady, Message: message}
+	switch state {
+	case CRDManagementStateReady:
+		c.Status, c.Reason = metav1.ConditionTrue, ReasonReady
+	case CRDManagementStateNotReady:
+		c.Status, c.Reason = metav1.ConditionFalse, ReasonNotReady
+	case CRDManagementStateError:
+		c.Status, c.Reason = metav1.ConditionFalse, ReasonReconcileError
+	default:
+		c.Status, c.Reason = metav1.ConditionUnknown, ReasonReadinessUnknown
+	}
+	return c
+}
+
+// resourcesReadyCondition returns a condition that reflects the readiness
+// of the objects of the given kinds in the component's Helm releases.
+func resourcesReadyCondition(conditionType v1.ConditionType, component ComponentStatus, kinds ...string) v1.StatusCondition {
+	c := v1.StatusCondition{Type: conditionType}
+	if !component.Installed {
+		c.Status, c.Reason, c.Message = metav1.ConditionUnknown, ReasonReadinessUnknown, "not installed"
+		return c
+	}
+	var resources []v1.ResourceStatus
+	for _, r := range component.Resources {
+		if slices.Contains(kinds, r.Kind) {
+			resources = append(resources, r)
+		}
+	}
+	if len(resources) == 0 {
+		c.Status, c.Reason, c.Message = metav1.ConditionUnknown, ReasonReadinessUnknown, "readiness is not reported"
+		return c
+	}
+	if ready, message := reconciler.SummarizeReadiness(resources); ready {
+		c.Status, c.Reason = metav1.ConditionTrue, ReasonReady
+	} else {
+		c.Status, c.Reason, c.Message = metav1.ConditionFalse, ReasonNotReady, message
+	}
+	return c
+}
@@ -0,0 +1,153 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"context"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/log"
+)
+
+// eventBufferSize is the number of events that are buffered for each
+// subscriber. Events are dropped when a subscriber falls this far behind.
+const eventBufferSize = 64
+
+// EventType is the type of an Event.
+type EventType string
+
+const (
+	// EventUpgradeStarted is published when the reconciliation of a new version starts.
+	EventUpgradeStarted EventType = "UpgradeStarted"
+	// EventInstalled is published when the Helm releases of an installation were installed for the
+	// first time, or again after a failure.
+	EventInstalled EventType = "Installed"
+	// EventUpgraded is published when the Helm releases of an installation were upgraded to a new version.
+	EventUpgraded EventType = "Upgraded"
+	// EventReconcileFailed is published when the reconciliation of an installation fails with a new error.
+	EventReconcileFailed EventType = "ReconcileFailed"
+	// EventCRDsReady is published when the CRDs managed by an installation become ready.
+	EventCRDsReady EventType = "CRDsReady"
+	// EventCRDsNotReady is published when the CRDs managed by an installation stop being ready.
+	EventCRDsNotReady EventType = "CRDsNotReady"
+	// EventIstiodReady is published when the istiod Deployment becomes ready.
+	EventIstiodReady EventType = "IstiodReady"
+	// EventIstiodUnhealthy is published when the istiod Deployment stops being ready.
+	EventIstiodUnhealthy EventType = "IstiodUnhealthy"
+	// EventReady is published when istiod and all the optional components become ready.
+	EventReady EventType = "Ready"
+	// EventUninstalled is published when an installation was uninstalled.
+	EventUninstalled EventType = "Uninstalled"
+)
+
+// Event describes a change in the state of an installation.
+type Event struct {
+	Type         EventType
+	Installation InstallationKey
+	// Message gives details about the change, e.g. the reconciliation error.
+	Message string
+	// Status is the status of the installation after the change. It is
+	// empty for EventUninstalled.
+	Status Status
+}
+
+// Subscribe returns a channel that receives the events of all
+// installations, in the order in which they occur. The channel is closed
+// when the context is cancelled. Events are dropped if the subscriber
+// doesn't keep up, so Status remains the source of truth.
+func (l *Library) Subscribe(ctx context.Context) <-chan Event {
+	ch := make(chan Event, eventBufferSize)
+	l.eventsMu.Lock()
+	l.subscribers = append(l.subscribers, ch)
+	l.eventsMu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		l.eventsMu.Lock()
+		defer l.eventsMu.Unlock()
+		for i, subscriber := range l.subscribers {
+			if subscriber == ch {
+				l.subscribers = append(l.subscribers[:i], l.subscribers[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+	return ch
+}
+
+// publish sends the events to all subscribers.
+func (l *Library) publish(events ...Event) {
+	l.eventsMu.Lock()
+	defer l.eventsMu.Unlock()
+	for _, evt := range events {
+		for _, ch := range l.subscribers {
+			select {
+			case ch <- evt:
+			default:
+				log.Warnf("dropping %s event of installation %s, subscriber is not keeping up", evt.Type, evt.Installation)
+			}
+		}
+	}
+}
+
+// statusEvents returns the events for the transition of an installation's
+// status from previous to current. previous is nil if the installation
+// wasn't reconciled before.
+func statusEvents(key InstallationKey, previous *Status, current Status) []Event {
+	var events []Event
+	add := func(eventType EventType, message string) {
+		events = append(events, Event{Type: eventType, Installation: key, Message: message, Status: current})
+	}
+	transitioned := func(conditionType v1.ConditionType, status metav1.ConditionStatus) bool {
+		c := current.GetCondition(conditionType)
+		if c.Status != status {
+			return false
+		}
+		return previous == nil || previous.GetCondition(conditionType).Status != status
+	}
+
+	if current.Error != nil {
+		if previous == nil || previous.Error == nil || previous.Error.Error() != current.Error.Error() {
+			add(EventReconcileFailed, current.Error.Error())
+		}
+	} else if transitioned(ConditionInstalled, metav1.ConditionTrue) || (previous != nil && previous.Version != current.Version) {
+		if previous != nil && previous.Installed && previous.Version != current.Version {
+			add(EventUpgraded, "upgraded from "+previous.Version+" to "+current.Version)
+		} else {
+			add(EventInstalled, "")
+		}
+	}
+
+	if transitioned(ConditionCRDsReady, metav1.ConditionTrue) {
+		add(EventCRDsReady, "")
+	} else if transitioned(ConditionCRDsReady, metav1.ConditionFalse) {
+		add(EventCRDsNotReady, current.CRDMessage)
+	}
+
+	if transitioned(ConditionIstiodReady, metav1.ConditionTrue) {
+		add(EventIstiodReady, "")
+	} else if previous != nil && previous.GetCondition(ConditionIstiodReady).Status == metav1.ConditionTrue &&
+		current.GetCondition(ConditionIstiodReady).Status == metav1.ConditionFalse {
+		add(EventIstiodUnhealthy, current.GetCondition(ConditionIstiodReady).Message)
+	}
+
+	if transitioned(ConditionReady, metav1.ConditionTrue) {
+		add(EventReady, "")
+	}
+	return events
+}
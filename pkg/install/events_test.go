@@ -0,0 +1,200 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func istiodStatus(deployment, webhook v1.ReadinessStatus) ComponentStatus {
+	resources := []v1.ResourceStatus{
+		{Kind: "Deployment", Name: "istiod", Status: deployment},
+		{Kind: "MutatingWebhookConfiguration", Name: "istio-sidecar-injector", Status: webhook},
+	}
+	status := ComponentStatus{Installed: true, Resources: resources}
+	status.Ready = deployment == v1.ReadinessStatusCurrent && webhook == v1.ReadinessStatusCurrent
+	if !status.Ready {
+		status.Message = "not ready"
+	}
+	return status
+}
+
+func TestStatusSetReadiness(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    Status
+		wantReady bool
+		want      map[v1.ConditionType]metav1.ConditionStatus
+	}{
+		{
+			name:   "reconcile failed",
+			status: Status{Error: errors.New("boom")},
+			want: map[v1.ConditionType]metav1.ConditionStatus{
+				ConditionInstalled:     metav1.ConditionFalse,
+				ConditionIstiodReady:   metav1.ConditionUnknown,
+				ConditionWebhooksReady: metav1.ConditionUnknown,
+				ConditionReady:         metav1.ConditionFalse,
+			},
+		},
+		{
+			name:   "installed, but istiod not ready",
+			status: Status{Installed: true, Istiod: istiodStatus(v1.ReadinessStatusInProgress, v1.ReadinessStatusCurrent)},
+			want: map[v1.ConditionType]metav1.ConditionStatus{
+				ConditionInstalled:     metav1.ConditionTrue,
+				ConditionIstiodReady:   metav1.ConditionFalse,
+				ConditionWebhooksReady: metav1.ConditionTrue,
+				ConditionReady:         metav1.ConditionFalse,
+			},
+		},
+		{
+			name: "CRDs not ready",
+			status: Status{
+				Installed: true, Istiod: istiodStatus(v1.ReadinessStatusCurrent, v1.ReadinessStatusCurrent),
+				CRDState: CRDManagementStateNotReady,
+			},
+			want: map[v1.ConditionType]metav1.ConditionStatus{
+				ConditionCRDsReady: metav1.ConditionFalse,
+				ConditionReady:     metav1.ConditionFalse,
+			},
+		},
+		{
+			name: "optional component not ready",
+			status: Status{
+				Installed: true, Istiod: istiodStatus(v1.ReadinessStatusCurrent, v1.ReadinessStatusCurrent),
+				CNI: &ComponentStatus{Installed: true, Message: "DaemonSet istio-cni-node is InProgress"},
+			},
+			want: map[v1.ConditionType]metav1.ConditionStatus{
+				ConditionIstiodReady: metav1.ConditionTrue,
+				ConditionReady:       metav1.ConditionFalse,
+			},
+		},
+		{
+			name: "ready",
+			status: Status{
+				Installed: true, Istiod: istiodStatus(v1.ReadinessStatusCurrent, v1.ReadinessStatusCurrent),
+				CRDState: CRDManagementStateReady,
+			},
+			wantReady: true,
+			want: map[v1.ConditionType]metav1.ConditionStatus{
+				ConditionInstalled:     metav1.ConditionTrue,
+				ConditionCRDsReady:     metav1.ConditionTrue,
+				ConditionIstiodReady:   metav1.ConditionTrue,
+				ConditionWebhooksReady: metav1.ConditionTrue,
+				ConditionReady:         metav1.ConditionTrue,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			tc.status.setReadiness(nil)
+			g.Expect(tc.status.Ready).To(Equal(tc.wantReady))
+			for conditionType, want := range tc.want {
+				g.Expect(tc.status.GetCondition(conditionType).Status).To(Equal(want), string(conditionType))
+			}
+		})
+	}
+}
+
+func TestStatusSetReadiness_preservesLastTransitionTime(t *testing.T) {
+	g := NewWithT(t)
+
+	transitionTime := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	previous := []v1.StatusCondition{
+		{Type: ConditionInstalled, Status: metav1.ConditionTrue, Reason: ReasonInstalled, LastTransitionTime: transitionTime},
+		{Type: ConditionIstiodReady, Status: metav1.ConditionTrue, Reason: ReasonReady, LastTransitionTime: transitionTime},
+	}
+	status := Status{Installed: true, Istiod: istiodStatus(v1.ReadinessStatusFailed, v1.ReadinessStatusCurrent)}
+	status.setReadiness(previous)
+
+	g.Expect(status.GetCondition(ConditionInstalled).LastTransitionTime).To(Equal(transitionTime))
+	g.Expect(status.GetCondition(ConditionIstiodReady).LastTransitionTime).NotTo(Equal(transitionTime))
+	g.Expect(previous[1].Status).To(Equal(metav1.ConditionTrue), "previous conditions must not be modified")
+}
+
+func TestStatusEvents(t *testing.T) {
+	g := NewWithT(t)
+	key := InstallationKey{Namespace: "istio-system", Revision: "default"}
+
+	eventTypes := func(events []Event) []EventType {
+		var types []EventType
+		for _, e := range events {
+			types = append(types, e.Type)
+		}
+		return types
+	}
+	newStatus := func(version string, istiod ComponentStatus, err error) Status {
+		s := Status{Version: version, Installed: err == nil, Istiod: istiod, Error: err, CRDState: CRDManagementStateReady}
+		s.setReadiness(nil)
+		return s
+	}
+
+	installing := newStatus("v1.0.0", istiodStatus(v1.ReadinessStatusInProgress, v1.ReadinessStatusCurrent), nil)
+	g.Expect(eventTypes(statusEvents(key, nil, installing))).To(Equal([]EventType{EventInstalled, EventCRDsReady}))
+
+	ready := newStatus("v1.0.0", istiodStatus(v1.ReadinessStatusCurrent, v1.ReadinessStatusCurrent), nil)
+	g.Expect(eventTypes(statusEvents(key, &installing, ready))).To(Equal([]EventType{EventIstiodReady, EventReady}))
+	g.Expect(statusEvents(key, &ready, ready)).To(BeEmpty())
+
+	unhealthy := newStatus("v1.0.0", istiodStatus(v1.ReadinessStatusFailed, v1.ReadinessStatusCurrent), nil)
+	events := statusEvents(key, &ready, unhealthy)
+	g.Expect(eventTypes(events)).To(Equal([]EventType{EventIstiodUnhealthy}))
+	g.Expect(events[0].Installation).To(Equal(key))
+
+	failed := newStatus("v1.1.0", ComponentStatus{}, errors.New("boom"))
+	g.Expect(eventTypes(statusEvents(key, &ready, failed))).To(Equal([]EventType{EventReconcileFailed}))
+	g.Expect(statusEvents(key, &failed, failed)).To(BeEmpty())
+
+	upgraded := newStatus("v1.1.0", istiodStatus(v1.ReadinessStatusCurrent, v1.ReadinessStatusCurrent), nil)
+	g.Expect(eventTypes(statusEvents(key, &ready, upgraded))).To(Equal([]EventType{EventUpgraded}))
+}
+
+func TestSubscribe(t *testing.T) {
+	g := NewWithT(t)
+
+	l := &Library{}
+	ctx, cancel := context.WithCancel(context.Background())
+	events := l.Subscribe(ctx)
+
+	key := InstallationKey{Namespace: "istio-system", Revision: "default"}
+	l.publish(Event{Type: EventInstalled, Installation: key}, Event{Type: EventReady, Installation: key})
+	g.Expect(<-events).To(HaveField("Type", EventInstalled))
+	g.Expect(<-events).To(HaveField("Type", EventReady))
+
+	// events are dropped instead of blocking the library when the subscriber doesn't keep up
+	for range eventBufferSize + 1 {
+		l.publish(Event{Type: EventReady, Installation: key})
+	}
+	g.Expect(events).To(HaveLen(eventBufferSize))
+
+	cancel()
+	g.Eventually(func() bool {
+		l.eventsMu.Lock()
+		defer l.eventsMu.Unlock()
+		return len(l.subscribers) == 0
+	}).Should(BeTrue())
+	for len(events) > 0 {
+		<-events
+	}
+	g.Eventually(events).Should(BeClosed())
+}
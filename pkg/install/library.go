@@ -18,11 +18,13 @@ import (
 	"context"
 	"fmt"
 	"io/fs"
+	"maps"
 	"os"
 	"reflect"
 	"sync"
 
 	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
 	"github.com/istio-ecosystem/sail-operator/pkg/config"
 	"github.com/istio-ecosystem/sail-operator/pkg/constants"
 	"github.com/istio-ecosystem/sail-operator/pkg/helm"
@@ -85,7 +87,8 @@ func WithCRDOwnershipLabel(key, value string) LibraryOption {
 	}
 }
 
-// Options specifies the desired state for the istiod installation.
+// Options specifies the desired state for an istiod installation. The
+// Library manages one installation per namespace and revision, see Key.
 type Options struct {
 	Namespace      string
 	Version        string
@@ -95,12 +98,77 @@ type Options struct {
 	ManageCRDs     bool
 	IncludeAllCRDs bool
 
+	// Profile is applied to istiod and istio-cni on top of the platform's
+	// default profile (e.g. "ambient" together with ZTunnel).
+	Profile string
+
+	// CNI, if set, installs the istio-cni node agent in the same version
+	// as istiod. Removing it uninstalls istio-cni.
+	CNI *CNIOptions
+
+	// ZTunnel, if set, installs the ztunnel node proxy in the same version
+	// as istiod. Removing it uninstalls ztunnel.
+	ZTunnel *ZTunnelOptions
+
+	// Gateway, if set, installs a gateway from the gateway chart, injected
+	// by the istiod revision. Removing it uninstalls the gateway.
+	Gateway *GatewayOptions
+
 	// OverwriteOLMManagedCRD is called when a CRD is detected with OLM
 	// ownership labels. Skipped by optionsEqual since function values
 	// are not comparable.
 	OverwriteOLMManagedCRD OverwriteOLMManagedCRDFunc
 }
 
+// CNIOptions specifies the desired state for the istio-cni installation.
+type CNIOptions struct {
+	// Namespace defaults to the istiod namespace.
+	Namespace string
+	Values    *v1.CNIValues
+}
+
+// ZTunnelOptions specifies the desired state for the ztunnel installation.
+type ZTunnelOptions struct {
+	// Namespace defaults to the istiod namespace.
+	Namespace string
+	Values    *v1.ZTunnelValues
+}
+
+// GatewayOptions specifies the desired state for a gateway installation.
+// The gateway Deployment and Service are named after the gateway.
+type GatewayOptions struct {
+	Name string
+	// Namespace defaults to the istiod namespace.
+	Namespace string
+	Values    *v1alpha1.GatewayValues
+}
+
+// InstallationKey identifies an installation managed by the Library.
+type InstallationKey struct {
+	Namespace string
+	Revision  string
+}
+
+func (k InstallationKey) String() string {
+	return k.Namespace + "/" + k.Revision
+}
+
+// newInstallationKey returns the key of the installation of the given
+// revision. An empty revision refers to the default revision.
+func newInstallationKey(namespace, revision string) InstallationKey {
+	if revision == "" {
+		revision = v1.DefaultRevision
+	}
+	return InstallationKey{Namespace: namespace, Revision: revision}
+}
+
+// Key returns the key of the installation described by the options.
+// Applying options with a different key creates an additional installation
+// instead of replacing the existing one.
+func (o Options) Key() InstallationKey {
+	return newInstallationKey(o.Namespace, o.Revision)
+}
+
 // optionsEqual compares two Options for equality, skipping function
 // fields (OverwriteOLMManagedCRD, TLSConfigFunc). Used by Apply to
 // suppress no-op reconciliation triggers.
@@ -110,12 +178,39 @@ func optionsEqual(a, b Options) bool {
 		a.Revision != b.Revision ||
 		a.ManageCRDs != b.ManageCRDs ||
 		a.IncludeAllCRDs != b.IncludeAllCRDs ||
-		!openShiftTLSEqual(a.OpenShiftTLS, b.OpenShiftTLS) {
+		a.Profile != b.Profile ||
+		!openShiftTLSEqual(a.OpenShiftTLS, b.OpenShiftTLS) ||
+		!reflect.DeepEqual(a.CNI, b.CNI) ||
+		!reflect.DeepEqual(a.ZTunnel, b.ZTunnel) ||
+		!reflect.DeepEqual(a.Gateway, b.Gateway) {
 		return false
 	}
 	return reflect.DeepEqual(helm.FromValues(a.Values), helm.FromValues(b.Values))
 }
 
+// copyOptions returns a copy of the options that doesn't share any values
+// with the original.
+func copyOptions(opts Options) Options {
+	copied := opts
+	copied.Values = opts.Values.DeepCopy()
+	if opts.CNI != nil {
+		cni := *opts.CNI
+		cni.Values = opts.CNI.Values.DeepCopy()
+		copied.CNI = &cni
+	}
+	if opts.ZTunnel != nil {
+		ztunnel := *opts.ZTunnel
+		ztunnel.Values = opts.ZTunnel.Values.DeepCopy()
+		copied.ZTunnel = &ztunnel
+	}
+	if opts.Gateway != nil {
+		gateway := *opts.Gateway
+		gateway.Values = opts.Gateway.Values.DeepCopy()
+		copied.Gateway = &gateway
+	}
+	return copied
+}
+
 func openShiftTLSEqual(a, b *config.OpenShiftTLS) bool {
 	if a == nil && b == nil {
 		return true
@@ -144,19 +239,63 @@ type CRDInfo struct {
 	Ready   bool
 }
 
+// ComponentStatus contains the state of a single component of the
+// installation.
+type ComponentStatus struct {
+	// Installed is true when the Helm release of the component is up to date.
+	Installed bool
+	// Ready is true when all objects in the Helm release are ready.
+	Ready bool
+	// Message explains why the component isn't ready.
+	Message   string
+	Resources []v1.ResourceStatus
+}
+
 // Status contains the current state of the installation.
 type Status struct {
 	Generation uint64
 	CRDState   CRDManagementState
 	CRDMessage string
 	CRDs       []CRDInfo
-	Installed  bool
-	Version    string
-	Error      error
+	// Installed is true when the Helm releases of istiod and all the
+	// optional components are installed. It doesn't imply that they are
+	// ready, see Ready.
+	Installed bool
+	// Ready is true when the installation is Installed and the objects of
+	// istiod, the optional components and the managed CRDs are ready.
+	Ready   bool
+	Version string
+	Error   error
+	// Conditions describe the state of the installation in detail, see
+	// the Condition* constants.
+	Conditions []v1.StatusCondition
+
+	Istiod ComponentStatus
+	// CNI, ZTunnel and Gateway are nil unless the component is requested
+	// in the Options.
+	CNI     *ComponentStatus
+	ZTunnel *ComponentStatus
+	Gateway *ComponentStatus
+	// DefaultTag is nil unless the default revision tag points to the
+	// installation (see SetDefaultRevision).
+	DefaultTag *ComponentStatus
 }
 
-// Library manages the lifecycle of an istiod installation without
-// requiring the full Sail Operator to be deployed.
+// installation holds the state of a single installation.
+type installation struct {
+	// generation is the library generation at which the desired state of
+	// the installation last changed.
+	generation uint64
+	// desired is nil while the installation is being uninstalled.
+	desired *Options
+	// applied are the options that were last reconciled, used to remove
+	// the components that are no longer desired.
+	applied *Options
+}
+
+// Library manages the lifecycle of one or more istiod installations without
+// requiring the full Sail Operator to be deployed. Installations are keyed
+// by namespace and revision and are reconciled independently.
 type Library struct {
 	mu          sync.Mutex
 	lifecycleMu sync.Mutex
@@ -176,9 +315,22 @@ type Library struct {
 	cancel    context.CancelFunc
 	done      chan struct{}
 
+	// generation, installations, pending and the default tag are guarded
+	// by mu.
 	generation    uint64
-	desiredOpts   *Options
-	currentStatus Status
+	installations map[InstallationKey]*installation
+	pending       map[InstallationKey]struct{}
+	// defaultTag is the installation that the default revision tag should
+	// point to, appliedDefaultTag the one it was last installed for.
+	defaultTag        *InstallationKey
+	appliedDefaultTag *InstallationKey
+
+	// statuses and lastKey are guarded by statusMu.
+	statuses map[InstallationKey]Status
+	lastKey  InstallationKey
+
+	eventsMu    sync.Mutex
+	subscribers []chan Event
 }
 
 // ValidateOptions checks that the provided options are valid.
@@ -186,6 +338,9 @@ func ValidateOptions(opts Options) error {
 	if opts.Namespace == "" {
 		return fmt.Errorf("namespace must not be empty")
 	}
+	if opts.Gateway != nil && opts.Gateway.Name == "" {
+		return fmt.Errorf("gateway name must not be empty")
+	}
 	return istioversion.ValidateVersion(opts.Version)
 }
 
@@ -254,27 +409,175 @@ func FromDirectory(path string) fs.FS {
 	return os.DirFS(path)
 }
 
-// Apply validates the desired installation state and triggers reconciliation.
-// If the options are identical to the previously applied options, this is a
-// no-op — no new reconciliation is triggered. Use Enqueue to force a
-// reconciliation with the current options (e.g. after drift detection).
+// Apply validates the desired state of the installation identified by
+// opts.Key() and triggers its reconciliation. Other installations are left
+// untouched. If the options are identical to the previously applied options,
+// this is a no-op — no new reconciliation is triggered. Use Enqueue to force
+// a reconciliation with the current options (e.g. after drift detection).
 func (l *Library) Apply(opts Options) error {
+	return l.apply(opts, nil)
+}
+
+// apply implements Apply. If from is set, the CRDs and the components that
+// opts also requests are handed over from that installation, so that they
+// aren't uninstalled when it is.
+func (l *Library) apply(opts Options, from *InstallationKey) error {
 	if err := ValidateOptions(opts); err != nil {
 		return fmt.Errorf("invalid options: %w", err)
 	}
 	l.lifecycleMu.Lock()
 	defer l.lifecycleMu.Unlock()
 	l.mu.Lock()
-	if l.desiredOpts != nil && optionsEqual(*l.desiredOpts, opts) {
+	key := opts.Key()
+	var previous *installation
+	if from != nil {
+		if previous = l.installations[*from]; previous == nil || previous.desired == nil {
+			l.mu.Unlock()
+			return fmt.Errorf("installation %s not found", *from)
+		}
+	}
+	if err := l.validateConflicts(key, opts, from); err != nil {
 		l.mu.Unlock()
-		return nil
+		return fmt.Errorf("invalid options: %w", err)
+	}
+	changed := false
+	if previous != nil {
+		changed = l.handOver(*from, previous, opts)
+	}
+	inst := l.installations[key]
+	if inst == nil || inst.desired == nil || !optionsEqual(*inst.desired, opts) {
+		if inst == nil {
+			inst = &installation{}
+			if l.installations == nil {
+				l.installations = map[InstallationKey]*installation{}
+			}
+			l.installations[key] = inst
+		}
+		l.generation++
+		copied := copyOptions(opts)
+		inst.desired = &copied
+		inst.generation = l.generation
+		l.markPending(key)
+		changed = true
+	}
+	l.mu.Unlock()
+
+	l.statusMu.Lock()
+	l.lastKey = key
+	l.statusMu.Unlock()
+	if changed {
+		l.sendTrigger()
+	}
+	return nil
+}
+
+// validateConflicts checks that the options don't request anything that is
+// already managed by another installation, except the one identified by
+// ignored. The caller must hold mu.
+func (l *Library) validateConflicts(key InstallationKey, opts Options, ignored *InstallationKey) error {
+	if key.Revision == v1.DefaultRevision && l.defaultTag != nil && *l.defaultTag != key {
+		return fmt.Errorf("the default revision conflicts with the default revision tag, which points to installation %s", *l.defaultTag)
+	}
+	for otherKey, other := range l.installations {
+		if otherKey == key || other.desired == nil || (ignored != nil && otherKey == *ignored) {
+			continue
+		}
+		switch {
+		case opts.ManageCRDs && other.desired.ManageCRDs:
+			return fmt.Errorf("CRDs are already managed by installation %s", otherKey)
+		case opts.CNI != nil && other.desired.CNI != nil:
+			return fmt.Errorf("istio-cni is already installed by installation %s", otherKey)
+		case opts.ZTunnel != nil && other.desired.ZTunnel != nil:
+			return fmt.Errorf("ztunnel is already installed by installation %s", otherKey)
+		case sameGateway(opts, *other.desired):
+			return fmt.Errorf("gateway %q is already installed by installation %s", opts.Gateway.Name, otherKey)
+		}
+	}
+	return nil
+}
+
+// handOver removes the CRDs and the components that opts requests from the
+// given installation, without uninstalling them, and returns true if its
+// desired state changed. The caller must hold mu.
+func (l *Library) handOver(key InstallationKey, inst *installation, opts Options) bool {
+	desired := copyOptions(*inst.desired)
+	var applied *Options
+	if inst.applied != nil {
+		copied := copyOptions(*inst.applied)
+		applied = &copied
+	}
+	if opts.ManageCRDs {
+		desired.ManageCRDs = false
+	}
+	if opts.CNI != nil && desired.CNI != nil {
+		desired.CNI = nil
+		if applied != nil && applied.CNI != nil &&
+			componentNamespace(applied.CNI.Namespace, applied.Namespace) == componentNamespace(opts.CNI.Namespace, opts.Namespace) {
+			applied.CNI = nil
+		}
+	}
+	if opts.ZTunnel != nil && desired.ZTunnel != nil {
+		desired.ZTunnel = nil
+		if applied != nil && applied.ZTunnel != nil &&
+			componentNamespace(applied.ZTunnel.Namespace, applied.Namespace) == componentNamespace(opts.ZTunnel.Namespace, opts.Namespace) {
+			applied.ZTunnel = nil
+		}
+	}
+	if sameGateway(opts, desired) {
+		desired.Gateway = nil
+		if applied != nil && sameGateway(opts, *applied) {
+			applied.Gateway = nil
+		}
+	}
+	inst.applied = applied
+	if optionsEqual(*inst.desired, desired) {
+		return false
 	}
 	l.generation++
-	copied := opts
-	if copied.Values != nil {
-		copied.Values = copied.Values.DeepCopy()
+	inst.desired = &desired
+	inst.generation = l.generation
+	l.markPending(key)
+	return true
+}
+
+// sameGateway returns true if both options request the same gateway.
+func sameGateway(a, b Options) bool {
+	return a.Gateway != nil && b.Gateway != nil && a.Gateway.Name == b.Gateway.Name &&
+		componentNamespace(a.Gateway.Namespace, a.Namespace) == componentNamespace(b.Gateway.Namespace, b.Namespace)
+}
+
+// SetDefaultRevision points the default revision tag to the installation
+// of the given revision, so that namespaces labeled with
+// istio-injection=enabled are injected by it. The tag is moved away from the
+// installation it pointed to before. It can't point to the revision named
+// "default", which already is the default revision when it is installed.
+func (l *Library) SetDefaultRevision(namespace, revision string) error {
+	key := newInstallationKey(namespace, revision)
+	if key.Revision == v1.DefaultRevision {
+		return fmt.Errorf("the default revision tag can't point to the %q revision", v1.DefaultRevision)
+	}
+	l.lifecycleMu.Lock()
+	defer l.lifecycleMu.Unlock()
+	l.mu.Lock()
+	inst := l.installations[key]
+	if inst == nil || inst.desired == nil {
+		l.mu.Unlock()
+		return fmt.Errorf("installation %s not found", key)
 	}
-	l.desiredOpts = &copied
+	for otherKey, other := range l.installations {
+		if otherKey.Revision == v1.DefaultRevision && other.desired != nil {
+			l.mu.Unlock()
+			return fmt.Errorf("the default revision tag conflicts with installation %s", otherKey)
+		}
+	}
+	if l.defaultTag != nil && *l.defaultTag == key {
+		l.mu.Unlock()
+		return nil
+	}
+	l.defaultTag = &key
+	l.generation++
+	inst.generation = l.generation
+	l.markPending(key)
 	l.mu.Unlock()
 	l.sendTrigger()
 	return nil
@@ -295,42 +598,113 @@ func (l *Library) Stop() {
 	}
 }
 
-// Enqueue triggers a reconciliation of the current desired state.
+// Enqueue triggers a reconciliation of the desired state of all
+// installations.
 func (l *Library) Enqueue() {
+	l.mu.Lock()
+	for key := range l.installations {
+		l.markPending(key)
+	}
+	l.mu.Unlock()
 	l.sendTrigger()
 }
 
-// Status returns the current installation status.
+// Status returns the status of the most recently applied installation. Use
+// InstallationStatus or Statuses when the Library manages several
+// installations.
 func (l *Library) Status() Status {
 	l.statusMu.RLock()
 	defer l.statusMu.RUnlock()
-	return l.currentStatus
+	return l.statuses[l.lastKey]
+}
+
+// InstallationStatus returns the status of the installation of the given
+// revision. It returns false if the installation hasn't been reconciled yet.
+func (l *Library) InstallationStatus(namespace, revision string) (Status, bool) {
+	l.statusMu.RLock()
+	defer l.statusMu.RUnlock()
+	status, found := l.statuses[newInstallationKey(namespace, revision)]
+	return status, found
+}
+
+// Statuses returns the status of all the installations that have been
+// reconciled.
+func (l *Library) Statuses() map[InstallationKey]Status {
+	l.statusMu.RLock()
+	defer l.statusMu.RUnlock()
+	return maps.Clone(l.statuses)
+}
+
+func (l *Library) setStatus(key InstallationKey, status Status) {
+	l.statusMu.Lock()
+	defer l.statusMu.Unlock()
+	if l.statuses == nil {
+		l.statuses = map[InstallationKey]Status{}
+	}
+	l.statuses[key] = status
+}
+
+// markPending marks the installation for reconciliation on the next
+// trigger. The caller must hold mu.
+func (l *Library) markPending(key InstallationKey) {
+	if l.pending == nil {
+		l.pending = map[InstallationKey]struct{}{}
+	}
+	l.pending[key] = struct{}{}
 }
 
-// Uninstall removes the istiod Helm release. It holds the lifecycle lock
-// so that Apply and the reconciliation loop cannot run concurrently,
-// preventing a race where an in-flight reconcile reinstalls istiod
-// immediately after the Helm uninstall completes.
-// On success, the status is cleared so that Status() reflects the
-// uninstalled state and a subsequent Apply with the same options will
-// trigger a fresh installation.
+// Uninstall removes the istiod Helm release of the given revision, along
+// with the istio-cni, ztunnel and gateway releases and the default revision
+// tag installed for it. Other installations are left untouched. It holds the
+// lifecycle lock so that Apply and the reconciliation loop cannot run
+// concurrently, preventing a race where an in-flight reconcile reinstalls
+// istiod immediately after the Helm uninstall completes.
+// On success, the installation and its status are forgotten so that
+// a subsequent Apply with the same options will trigger a fresh installation.
 func (l *Library) Uninstall(ctx context.Context, namespace, revision string) error {
+	key := newInstallationKey(namespace, revision)
 	l.lifecycleMu.Lock()
 	defer l.lifecycleMu.Unlock()
 
 	l.mu.Lock()
-	l.desiredOpts = nil
+	var applied *Options
+	if inst := l.installations[key]; inst != nil {
+		inst.desired = nil
+		applied = inst.applied
+	}
+	if l.defaultTag != nil && *l.defaultTag == key {
+		l.defaultTag = nil
+	}
+	ownsDefaultTag := l.appliedDefaultTag != nil && *l.appliedDefaultTag == key
 	inst := l.newInstaller(namespace)
 	l.mu.Unlock()
 
 	log.Infof("Uninstalling: namespace=%s, revision=%s", namespace, revision)
-	if err := inst.uninstall(ctx, namespace, revision); err != nil {
+	if ownsDefaultTag {
+		if err := inst.revisionTagReconciler.Uninstall(ctx, namespace, v1.DefaultRevisionTag); err != nil {
+			return err
+		}
+		l.mu.Lock()
+		l.appliedDefaultTag = nil
+		l.mu.Unlock()
+	}
+	if applied != nil {
+		if err := inst.removeComponents(ctx, applied, nil); err != nil {
+			return err
+		}
+	}
+	if err := inst.uninstall(ctx, namespace, key.Revision); err != nil {
 		return err
 	}
+	l.mu.Lock()
+	delete(l.installations, key)
+	delete(l.pending, key)
+	l.mu.Unlock()
 
 	l.statusMu.Lock()
-	l.currentStatus = Status{}
+	delete(l.statuses, key)
 	l.statusMu.Unlock()
+	l.publish(Event{Type: EventUninstalled, Installation: key})
 	log.Infof("Uninstall complete: namespace=%s, revision=%s", namespace, revision)
 	return nil
 }
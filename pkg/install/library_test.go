@@ -22,16 +22,18 @@ import (
 	"time"
 
 	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
 	"github.com/istio-ecosystem/sail-operator/pkg/helm"
 	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
 	"github.com/istio-ecosystem/sail-operator/resources"
 	. "github.com/onsi/gomega"
 	"helm.sh/helm/v4/pkg/release"
+	admissionv1 "k8s.io/api/admissionregistration/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/event"
-	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"istio.io/istio/pkg/ptr"
 )
@@ -130,11 +132,12 @@ func TestStatus(t *testing.T) {
 	l := &Library{
 		notifyCh: make(chan struct{}, 1),
 	}
-	l.currentStatus = Status{
+	l.lastKey = newInstallationKey("istio-system", "")
+	l.setStatus(l.lastKey, Status{
 		Installed: true,
 		Version:   "v1.0.0",
 		CRDState:  CRDManagementStateReady,
-	}
+	})
 
 	s := l.Status()
 	g.Expect(s.Installed).To(BeTrue())
@@ -160,25 +163,23 @@ func TestUninstall_clearsStateAndAllowsReinstall(t *testing.T) {
 		triggerCh: make(chan event.GenericEvent, 1),
 	}
 
-	// Simulate a successful install cycle: set desiredOpts and status.
+	// Simulate a successful install cycle: set the desired options and status.
 	opts := Options{Namespace: "istio-system", Version: "v1.0.0"}
 	err := l.Apply(opts)
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(l.generation).To(Equal(uint64(1)))
 	<-l.triggerCh
 
-	l.statusMu.Lock()
-	l.currentStatus = Status{Installed: true, Version: "v1.0.0"}
-	l.statusMu.Unlock()
+	l.setStatus(opts.Key(), Status{Installed: true, Version: "v1.0.0"})
 
-	// Directly nil the desiredOpts and clear status as Uninstall would
+	// Directly forget the installation and its status as Uninstall would
 	// after a successful Helm uninstall. We can't call the real Uninstall
 	// without a cluster, but we can verify the state contract.
 	l.mu.Lock()
-	l.desiredOpts = nil
+	delete(l.installations, opts.Key())
 	l.mu.Unlock()
 	l.statusMu.Lock()
-	l.currentStatus = Status{}
+	delete(l.statuses, opts.Key())
 	l.statusMu.Unlock()
 
 	// Status should now be zero-value.
@@ -188,7 +189,7 @@ func TestUninstall_clearsStateAndAllowsReinstall(t *testing.T) {
 	g.Expect(s.Error).NotTo(HaveOccurred())
 
 	// Apply with the same options should trigger a new install cycle
-	// because desiredOpts is nil (no optionsEqual comparison).
+	// because the installation was forgotten (no optionsEqual comparison).
 	err = l.Apply(opts)
 	g.Expect(err).NotTo(HaveOccurred())
 	g.Expect(l.generation).To(Equal(uint64(2)))
@@ -291,7 +292,7 @@ func TestApply_isolatesFromCallerMutation(t *testing.T) {
 	values.Pilot.Image = ptr.Of("mutated-by-caller")
 
 	// Apply again with a fresh copy of the original intent — must be a
-	// no-op because stored desiredOpts should be isolated from the
+	// no-op because the stored options should be isolated from the
 	// caller's mutation above.
 	freshOpts := Options{
 		Namespace: "istio-system",
@@ -349,7 +350,7 @@ var _ helm.ChartReconciler = (*slowChartReconciler)(nil)
 
 func (m *slowChartReconciler) UpgradeOrInstallChart(
 	_ context.Context, _ fs.FS, _ string, _ helm.Values,
-	_, _ string, _ *metav1.OwnerReference,
+	_, _ string, _ *metav1.OwnerReference, _ ...helm.ChartOption,
 ) (release.Releaser, error) {
 	m.mu.Lock()
 	m.ops = append(m.ops, "install_start")
@@ -398,7 +399,7 @@ func TestUninstall_raceWithReconcile(t *testing.T) {
 		Revision:  "racetest",
 		Values:    GatewayAPIDefaults("test-ns"),
 	}
-	l.desiredOpts = &opts
+	l.installations = map[InstallationKey]*installation{opts.Key(): {generation: 1, desired: &opts}}
 	l.generation = 1
 
 	reconciler := &libraryReconciler{lib: l}
@@ -409,7 +410,7 @@ func TestUninstall_raceWithReconcile(t *testing.T) {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
-		_, _ = reconciler.Reconcile(context.Background(), ctrlreconcile.Request{})
+		_, _ = reconciler.Reconcile(context.Background(), requestFor(opts.Key()))
 	}()
 
 	// Wait for the install to be in progress.
@@ -455,3 +456,348 @@ func TestUninstall_raceWithReconcile(t *testing.T) {
 		"expected the last Helm operation to be uninstall, but got ops=%v — "+
 			"this means a concurrent reconcile re-installed istiod after Uninstall", ops)
 }
+
+// recordingChartReconciler records the Helm releases that are installed and
+// uninstalled.
+type recordingChartReconciler struct {
+	mu          sync.Mutex
+	installed   []string
+	uninstalled []string
+	values      map[string]helm.Values
+}
+
+var _ helm.ChartReconciler = (*recordingChartReconciler)(nil)
+
+func (m *recordingChartReconciler) UpgradeOrInstallChart(
+	_ context.Context, _ fs.FS, _ string, values helm.Values,
+	namespace, releaseName string, _ *metav1.OwnerReference, _ ...helm.ChartOption,
+) (release.Releaser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.installed = append(m.installed, namespace+"/"+releaseName)
+	if m.values == nil {
+		m.values = map[string]helm.Values{}
+	}
+	m.values[namespace+"/"+releaseName] = values
+	return nil, nil
+}
+
+func (m *recordingChartReconciler) UninstallChart(
+	_ context.Context, releaseName, namespace string,
+) (*release.UninstallReleaseResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.uninstalled = append(m.uninstalled, namespace+"/"+releaseName)
+	return &release.UninstallReleaseResponse{Info: "ok"}, nil
+}
+
+func TestReconcile_installsComponents(t *testing.T) {
+	g := NewWithT(t)
+
+	mock := &recordingChartReconciler{}
+	cl := fake.NewClientBuilder().WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "istio-system"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "istio-cni"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "istio-ingress"}},
+	).Build()
+
+	l := &Library{
+		chartManager: mock,
+		cl:           cl,
+		resourceFS:   resources.FS,
+		triggerCh:    make(chan event.GenericEvent, 1),
+		notifyCh:     make(chan struct{}, 1),
+	}
+	reconciler := &libraryReconciler{lib: l}
+
+	opts := Options{
+		Namespace: "istio-system",
+		Version:   istioversion.Default,
+		Profile:   "ambient",
+		CNI:       &CNIOptions{Namespace: "istio-cni"},
+		ZTunnel:   &ZTunnelOptions{},
+		Gateway:   &GatewayOptions{Name: "istio-ingressgateway", Namespace: "istio-ingress"},
+	}
+	g.Expect(l.Apply(opts)).To(Succeed())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := l.Subscribe(ctx)
+
+	_, err := reconciler.Reconcile(context.Background(), requestFor(opts.Key()))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(<-events).To(HaveField("Type", EventInstalled))
+	g.Expect(mock.installed).To(ContainElements(
+		"istio-system/default-istiod",
+		"istio-cni/istio-cni",
+		"istio-system/ztunnel",
+		"istio-ingress/istio-ingressgateway",
+	))
+
+	status := l.Status()
+	g.Expect(status.Error).NotTo(HaveOccurred())
+	g.Expect(status.Installed).To(BeTrue())
+	g.Expect(status.Istiod.Installed).To(BeTrue())
+	g.Expect(status.CNI).NotTo(BeNil())
+	g.Expect(status.CNI.Installed).To(BeTrue())
+	g.Expect(status.ZTunnel).NotTo(BeNil())
+	g.Expect(status.ZTunnel.Installed).To(BeTrue())
+	g.Expect(status.Gateway).NotTo(BeNil())
+	g.Expect(status.Gateway.Installed).To(BeTrue())
+	g.Expect(status.GetCondition(ConditionInstalled).Status).To(Equal(metav1.ConditionTrue))
+
+	// the gateway pods are annotated with the version, so that they are restarted when istiod is upgraded
+	g.Expect(mock.values["istio-ingress/istio-ingressgateway"]).To(
+		HaveKeyWithValue("podAnnotations", HaveKey(constants.IstioVersionKey)))
+
+	// removing components uninstalls them, moving them reinstalls them in the new namespace
+	mock.installed = nil
+	opts.CNI = nil
+	opts.Gateway = &GatewayOptions{Name: "istio-ingressgateway", Namespace: "istio-system"}
+	g.Expect(l.Apply(opts)).To(Succeed())
+	_, err = reconciler.Reconcile(context.Background(), requestFor(opts.Key()))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mock.uninstalled).To(ConsistOf("istio-cni/istio-cni", "istio-ingress/istio-ingressgateway"))
+	g.Expect(mock.installed).To(ContainElement("istio-system/istio-ingressgateway"))
+	g.Expect(mock.installed).NotTo(ContainElement("istio-cni/istio-cni"))
+	g.Expect(l.Status().CNI).To(BeNil())
+
+	// Uninstall removes the remaining components along with istiod
+	mock.uninstalled = nil
+	g.Expect(l.Uninstall(context.Background(), "istio-system", "default")).To(Succeed())
+	g.Expect(mock.uninstalled).To(ContainElements(
+		"istio-system/ztunnel",
+		"istio-system/istio-ingressgateway",
+		"istio-system/default-istiod",
+	))
+}
+
+func TestApply_gatewayNameRequired(t *testing.T) {
+	g := NewWithT(t)
+
+	l := &Library{
+		triggerCh: make(chan event.GenericEvent, 1),
+	}
+	err := l.Apply(Options{Namespace: "istio-system", Version: istioversion.Default, Gateway: &GatewayOptions{}})
+	g.Expect(err).To(MatchError(ContainSubstring("gateway name")))
+}
+
+func TestApply_multipleInstallations(t *testing.T) {
+	g := NewWithT(t)
+
+	l := &Library{
+		triggerCh: make(chan event.GenericEvent, 1),
+	}
+
+	stable := Options{Namespace: "istio-system", Version: istioversion.Default, Revision: "stable", ManageCRDs: true, CNI: &CNIOptions{}}
+	canary := Options{Namespace: "istio-system", Version: istioversion.Default, Revision: "canary"}
+	tenant := Options{Namespace: "tenant-a", Version: istioversion.Default}
+	g.Expect(l.Apply(stable)).To(Succeed())
+	g.Expect(l.Apply(canary)).To(Succeed())
+	g.Expect(l.Apply(tenant)).To(Succeed())
+	g.Expect(l.installations).To(HaveLen(3))
+	g.Expect(l.installations).To(HaveKey(InstallationKey{Namespace: "tenant-a", Revision: "default"}))
+	g.Expect(l.pendingRequests()).To(ConsistOf(requestFor(stable.Key()), requestFor(canary.Key()), requestFor(tenant.Key())))
+
+	// cluster-wide components can only be managed by a single installation
+	canary.ManageCRDs = true
+	g.Expect(l.Apply(canary)).To(MatchError(ContainSubstring("CRDs are already managed by installation istio-system/stable")))
+	canary.ManageCRDs = false
+	canary.CNI = &CNIOptions{Namespace: "istio-cni"}
+	g.Expect(l.Apply(canary)).To(MatchError(ContainSubstring("istio-cni is already installed by installation istio-system/stable")))
+
+	// the default tag can't coexist with the default revision
+	g.Expect(l.SetDefaultRevision("istio-system", "canary")).To(MatchError(ContainSubstring("tenant-a/default")))
+}
+
+func TestReconcile_installationsAreIndependent(t *testing.T) {
+	g := NewWithT(t)
+
+	mock := &recordingChartReconciler{}
+	cl := fake.NewClientBuilder().WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-a"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "tenant-b"}},
+	).Build()
+
+	l := &Library{
+		chartManager: mock,
+		cl:           cl,
+		resourceFS:   resources.FS,
+		triggerCh:    make(chan event.GenericEvent, 1),
+		notifyCh:     make(chan struct{}, 1),
+	}
+	reconciler := &libraryReconciler{lib: l}
+
+	tenantA := Options{Namespace: "tenant-a", Version: istioversion.Default, Revision: "a"}
+	tenantB := Options{Namespace: "tenant-b", Version: istioversion.Default, Revision: "b"}
+	g.Expect(l.Apply(tenantA)).To(Succeed())
+	g.Expect(l.Apply(tenantB)).To(Succeed())
+
+	_, err := reconciler.Reconcile(context.Background(), requestFor(tenantA.Key()))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(mock.installed).To(ConsistOf("tenant-a/a-istiod"))
+
+	status, found := l.InstallationStatus("tenant-a", "a")
+	g.Expect(found).To(BeTrue())
+	g.Expect(status.Installed).To(BeTrue())
+	_, found = l.InstallationStatus("tenant-b", "b")
+	g.Expect(found).To(BeFalse())
+
+	_, err = reconciler.Reconcile(context.Background(), requestFor(tenantB.Key()))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(l.Statuses()).To(HaveLen(2))
+
+	g.Expect(l.Uninstall(context.Background(), "tenant-a", "a")).To(Succeed())
+	g.Expect(mock.uninstalled).To(ConsistOf("tenant-a/a-istiod"))
+	g.Expect(l.Statuses()).To(HaveKey(tenantB.Key()))
+	g.Expect(l.Statuses()).NotTo(HaveKey(tenantA.Key()))
+}
+
+// runReconcileLoop reconciles the pending installations whenever the
+// library is triggered, like the controller started by Start does.
+func runReconcileLoop(ctx context.Context, l *Library) {
+	reconciler := &libraryReconciler{lib: l}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-l.triggerCh:
+			for _, req := range l.pendingRequests() {
+				_, _ = reconciler.Reconcile(ctx, req)
+			}
+		}
+	}
+}
+
+func TestUpgradeRevision(t *testing.T) {
+	g := NewWithT(t)
+
+	mock := &recordingChartReconciler{}
+	cl := fake.NewClientBuilder().WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "istio-system"}},
+	).Build()
+
+	l := &Library{
+		chartManager: mock,
+		cl:           cl,
+		resourceFS:   resources.FS,
+		triggerCh:    make(chan event.GenericEvent, 1),
+		notifyCh:     make(chan struct{}, 1),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	go runReconcileLoop(ctx, l)
+
+	stable := Options{Namespace: "istio-system", Version: istioversion.Default, Revision: "stable", CNI: &CNIOptions{}}
+	g.Expect(l.Apply(stable)).To(Succeed())
+	g.Eventually(func() bool {
+		_, found := l.InstallationStatus("istio-system", "stable")
+		return found
+	}).Should(BeTrue())
+
+	canary := Options{Namespace: "istio-system", Version: istioversion.Default, Revision: "canary", CNI: &CNIOptions{}}
+	g.Expect(l.UpgradeRevision(ctx, stable.Key(), canary, WithPollInterval(10*time.Millisecond))).To(Succeed())
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	g.Expect(mock.installed).To(ContainElements(
+		"istio-system/canary-istiod",
+		"istio-system/default-revisiontags",
+		"istio-system/default-base",
+	))
+	// istio-cni was handed over to the new revision instead of being uninstalled
+	g.Expect(mock.uninstalled).To(ConsistOf("istio-system/stable-istiod"))
+
+	g.Expect(l.Statuses()).To(HaveLen(1))
+	status, found := l.InstallationStatus("istio-system", "canary")
+	g.Expect(found).To(BeTrue())
+	g.Expect(status.DefaultTag).NotTo(BeNil())
+	g.Expect(status.CNI).NotTo(BeNil())
+}
+
+func TestUpgradeRevision_requiresNamedRevisions(t *testing.T) {
+	g := NewWithT(t)
+
+	l := &Library{
+		triggerCh: make(chan event.GenericEvent, 1),
+	}
+	from := InstallationKey{Namespace: "istio-system", Revision: "default"}
+	err := l.UpgradeRevision(context.Background(), from, Options{Namespace: "istio-system", Version: istioversion.Default, Revision: "canary"})
+	g.Expect(err).To(MatchError(ContainSubstring("named revisions")))
+}
+
+func TestIsRevisionInUse(t *testing.T) {
+	tagWebhook := func(tag, rev string) *admissionv1.MutatingWebhookConfiguration {
+		return &admissionv1.MutatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{
+			Name:   "istio-revision-tag-" + tag,
+			Labels: map[string]string{"istio.io/tag": tag, "istio.io/rev": rev},
+		}}
+	}
+	pod := func(name, injectedRevision string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "apps", Name: name, Annotations: map[string]string{"istio.io/rev": injectedRevision}},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		objects  []client.Object
+		expected bool
+	}{
+		{
+			name:     "not referenced",
+			objects:  []client.Object{&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}}},
+			expected: false,
+		},
+		{
+			name: "namespace label only",
+			objects: []client.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps", Labels: map[string]string{"istio.io/rev": "stable"}}},
+			},
+			expected: true,
+		},
+		{
+			name: "namespace using the default tag, which points to the new revision",
+			objects: []client.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps", Labels: map[string]string{"istio-injection": "enabled"}}},
+				tagWebhook("default", "canary"),
+			},
+			expected: false,
+		},
+		{
+			name: "tag pointing to the revision",
+			objects: []client.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps", Labels: map[string]string{"istio.io/rev": "prod"}}},
+				tagWebhook("prod", "stable"),
+			},
+			expected: true,
+		},
+		{
+			name: "running pod",
+			objects: []client.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}},
+				pod("running", "stable", corev1.PodRunning),
+			},
+			expected: true,
+		},
+		{
+			name: "completed and failed pods",
+			objects: []client.Object{
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "apps"}},
+				pod("completed", "stable", corev1.PodSucceeded),
+				pod("failed", "stable", corev1.PodFailed),
+			},
+			expected: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewWithT(t)
+			l := &Library{cl: fake.NewClientBuilder().WithObjects(tt.objects...).Build()}
+
+			inUse, err := l.isRevisionInUse(context.Background(), "stable")
+			g.Expect(err).NotTo(HaveOccurred())
+			g.Expect(inUse).To(Equal(tt.expected))
+		})
+	}
+}
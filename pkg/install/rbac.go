@@ -20,7 +20,6 @@ import (
 	"sort"
 	"strings"
 
-	"github.com/istio-ecosystem/sail-operator/pkg/watches"
 	discoveryv1 "k8s.io/api/discovery/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -39,7 +38,8 @@ var readOnlyTypes = map[reflect.Type]bool{
 
 // LibraryRBACRules returns the RBAC PolicyRules that the library consumer
 // needs to grant to the service account running the library. Rules are
-// derived from watches.IstiodWatches plus static entries for CRDs,
+// derived from the watch lists of istiod and the optional components
+// (istio-cni, ztunnel and gateways) plus static entries for CRDs,
 // namespaces, and Helm release storage.
 func LibraryRBACRules() []rbacv1.PolicyRule {
 	type ruleKey struct {
@@ -55,7 +55,7 @@ func LibraryRBACRules() []rbacv1.PolicyRule {
 		}
 	}
 
-	for _, wr := range watches.IstiodWatches {
+	for _, wr := range libraryWatches() {
 		gvks, _, err := clientgoscheme.Scheme.ObjectKinds(wr.Object)
 		if err != nil || len(gvks) == 0 {
 			continue
@@ -73,6 +73,7 @@ func LibraryRBACRules() []rbacv1.PolicyRule {
 	addEntry("", "pods", readOnlyVerbs)
 	addEntry("", "secrets", helmManagedVerbs)
 	addEntry("apiextensions.k8s.io", "customresourcedefinitions", crdVerbs)
+	addEntry("k8s.cni.cncf.io", "network-attachment-definitions", helmManagedVerbs)
 	addEntry("rbac.authorization.k8s.io", "clusterroles", []string{"escalate"})
 
 	var rules []rbacv1.PolicyRule
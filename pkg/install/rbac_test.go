@@ -90,3 +90,17 @@ func TestLibraryRBACRules_CRDsNoDelete(t *testing.T) {
 	}
 	t.Fatal("customresourcedefinitions rule not found")
 }
+
+func TestLibraryRBACRules_IncludesComponents(t *testing.T) {
+	g := NewWithT(t)
+	rulesByGroup := map[string][]string{}
+	for _, r := range LibraryRBACRules() {
+		for _, group := range r.APIGroups {
+			rulesByGroup[group] = append(rulesByGroup[group], r.Resources...)
+		}
+	}
+	g.Expect(rulesByGroup["apps"]).To(ContainElement("daemonsets"), "istio-cni and ztunnel are DaemonSets")
+	g.Expect(rulesByGroup[""]).To(ContainElement("resourcequotas"))
+	g.Expect(rulesByGroup["policy"]).To(ContainElement("poddisruptionbudgets"))
+	g.Expect(rulesByGroup["k8s.cni.cncf.io"]).To(ContainElement("network-attachment-definitions"))
+}
@@ -18,9 +18,12 @@ import (
 	"context"
 	"fmt"
 
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
 	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
 	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
 	sharedreconcile "github.com/istio-ecosystem/sail-operator/pkg/reconcile"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
 	"github.com/istio-ecosystem/sail-operator/pkg/revision"
 	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
 	"github.com/istio-ecosystem/sail-operator/pkg/watches"
@@ -46,33 +49,68 @@ type libraryReconciler struct {
 	lib *Library
 }
 
-func (r *libraryReconciler) Reconcile(ctx context.Context, _ ctrlreconcile.Request) (ctrlreconcile.Result, error) {
+// Reconcile reconciles the installation whose namespace and revision are
+// the namespace and name of the request.
+func (r *libraryReconciler) Reconcile(ctx context.Context, req ctrlreconcile.Request) (ctrlreconcile.Result, error) {
+	key := InstallationKey{Namespace: req.Namespace, Revision: req.Name}
 	r.lib.lifecycleMu.Lock()
 	r.lib.mu.Lock()
-	opts := r.lib.desiredOpts
-	gen := r.lib.generation
-	r.lib.mu.Unlock()
-
-	if opts == nil {
+	inst := r.lib.installations[key]
+	if inst == nil || inst.desired == nil {
+		r.lib.mu.Unlock()
 		r.lib.lifecycleMu.Unlock()
 		return ctrlreconcile.Result{}, nil
 	}
+	optsCopy := copyOptions(*inst.desired)
+	applied := inst.applied
+	gen := inst.generation
+	defaultTag := r.lib.defaultTag != nil && *r.lib.defaultTag == key
+	appliedDefaultTag := r.lib.appliedDefaultTag
+	r.lib.mu.Unlock()
 
-	optsCopy := *opts
-	if optsCopy.Values != nil {
-		optsCopy.Values = optsCopy.Values.DeepCopy()
+	log.Infof("Reconciling installation %s", key)
+	if applied != nil && applied.Version != optsCopy.Version {
+		r.lib.publish(Event{
+			Type:         EventUpgradeStarted,
+			Installation: key,
+			Message:      fmt.Sprintf("upgrading from %s to %s", applied.Version, optsCopy.Version),
+		})
+	}
+	installer := r.lib.newInstaller(optsCopy.Namespace)
+	status := Status{Version: optsCopy.Version}
+	if applied != nil {
+		if err := installer.removeComponents(ctx, applied, &optsCopy); err != nil {
+			status.Error = err
+		}
+	}
+	if status.Error == nil && defaultTag && appliedDefaultTag != nil && appliedDefaultTag.Namespace != key.Namespace {
+		// the tag's Helm releases are installed in the istiod namespace, so they must be moved
+		previousInstaller := r.lib.newInstaller(appliedDefaultTag.Namespace)
+		if err := previousInstaller.revisionTagReconciler.Uninstall(ctx, appliedDefaultTag.Namespace, v1.DefaultRevisionTag); err != nil {
+			status.Error = err
+		}
+	}
+	if status.Error == nil {
+		status = installer.reconcile(ctx, optsCopy, defaultTag)
+		r.lib.mu.Lock()
+		inst.applied = &optsCopy
+		if defaultTag && status.DefaultTag != nil {
+			r.lib.appliedDefaultTag = &key
+		}
+		r.lib.mu.Unlock()
 	}
-
-	log.Info("Reconciling")
-	inst := r.lib.newInstaller(optsCopy.Namespace)
-	status := inst.reconcile(ctx, optsCopy)
 	status.Generation = gen
-	log.Infof("Reconcile complete: installed=%v, error=%v", status.Installed, status.Error)
+	log.Infof("Reconcile of installation %s complete: installed=%v, error=%v", key, status.Installed, status.Error)
 	r.lib.lifecycleMu.Unlock()
 
-	r.lib.statusMu.Lock()
-	r.lib.currentStatus = status
-	r.lib.statusMu.Unlock()
+	previous, found := r.lib.InstallationStatus(key.Namespace, key.Revision)
+	status.setReadiness(previous.Conditions)
+	r.lib.setStatus(key, status)
+	if found {
+		r.lib.publish(statusEvents(key, &previous, status)...)
+	} else {
+		r.lib.publish(statusEvents(key, nil, status)...)
+	}
 
 	select {
 	case r.lib.notifyCh <- struct{}{}:
@@ -80,16 +118,53 @@ func (r *libraryReconciler) Reconcile(ctx context.Context, _ ctrlreconcile.Reque
 	}
 
 	if status.Error != nil {
-		log.Errorf("reconciliation failed: %v", status.Error)
+		log.Errorf("reconciliation of installation %s failed: %v", key, status.Error)
 		return ctrlreconcile.Result{}, status.Error
 	}
 	return ctrlreconcile.Result{}, nil
 }
 
+// requestFor returns the reconcile request for the given installation.
+func requestFor(key InstallationKey) ctrlreconcile.Request {
+	return ctrlreconcile.Request{NamespacedName: types.NamespacedName{Namespace: key.Namespace, Name: key.Revision}}
+}
+
+// pendingRequests returns the requests for the installations whose
+// reconciliation was triggered since the last call.
+func (l *Library) pendingRequests() []ctrlreconcile.Request {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	requests := make([]ctrlreconcile.Request, 0, len(l.pending))
+	for key := range l.pending {
+		requests = append(requests, requestFor(key))
+	}
+	clear(l.pending)
+	return requests
+}
+
+// installationRequests returns the requests for all installations.
+func (l *Library) installationRequests() []ctrlreconcile.Request {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	requests := make([]ctrlreconcile.Request, 0, len(l.installations))
+	for key := range l.installations {
+		requests = append(requests, requestFor(key))
+	}
+	return requests
+}
+
 func (l *Library) setupController(mgr ctrl.Manager) error {
-	fixedKeyHandler := handler.EnqueueRequestsFromMapFunc(
+	// the trigger channel only wakes up the controller, the installations
+	// to reconcile are the ones marked as pending
+	triggerHandler := handler.EnqueueRequestsFromMapFunc(
+		func(_ context.Context, _ client.Object) []ctrlreconcile.Request {
+			return l.pendingRequests()
+		},
+	)
+	// drift in any watched object causes all installations to be reconciled
+	driftHandler := handler.EnqueueRequestsFromMapFunc(
 		func(_ context.Context, _ client.Object) []ctrlreconcile.Request {
-			return []ctrlreconcile.Request{{NamespacedName: types.NamespacedName{Name: "sail-library"}}}
+			return l.installationRequests()
 		},
 	)
 
@@ -103,17 +178,18 @@ func (l *Library) setupController(mgr ctrl.Manager) error {
 	b := ctrl.NewControllerManagedBy(mgr).
 		Named("sail-library").
 		WithOptions(controller.Options{SkipNameValidation: ptr.Of(true)}).
-		WatchesRawSource(source.Channel(l.triggerCh, fixedKeyHandler))
+		WatchesRawSource(source.Channel(l.triggerCh, triggerHandler))
 
-	watches.RegisterOwnedWatches(b, watches.IstiodWatches, fixedKeyHandler, nil, managedByPred)
-	b.Watches(&apiextensionsv1.CustomResourceDefinition{}, fixedKeyHandler)
+	watches.RegisterOwnedWatches(b, libraryWatches(), driftHandler, nil, managedByPred)
+	b.Watches(&apiextensionsv1.CustomResourceDefinition{}, driftHandler)
 
 	return b.Complete(&libraryReconciler{lib: l})
 }
 
 // Start begins the reconciliation loop and drift-detection watches.
 // The returned channel receives a notification each time a reconciliation
-// completes. The loop runs until the context is cancelled or Stop is called.
+// completes; use Subscribe to receive typed events about what changed
+// instead. The loop runs until the context is cancelled or Stop is called.
 func (l *Library) Start(ctx context.Context) (<-chan struct{}, error) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
@@ -157,14 +233,31 @@ func (l *Library) sendTrigger() {
 	}
 }
 
+// libraryWatches returns the resources watched for drift detection: those of
+// istiod and of all the optional components, since the components can be
+// enabled after the library is started.
+func libraryWatches() []watches.WatchedResource {
+	return watches.Merge(watches.IstiodWatches, watches.CNIWatches, watches.ZTunnelWatches, watches.GatewayWatches)
+}
+
 func (l *Library) newInstaller(namespace string) *installer {
+	defaultProfile := ""
+	if l.platform == config.PlatformOpenShift {
+		defaultProfile = "openshift"
+	}
 	cfg := sharedreconcile.Config{
 		ResourceFS:        l.resourceFS,
+		Platform:          l.platform,
+		DefaultProfile:    defaultProfile,
 		ChartManager:      l.chartManager,
 		OperatorNamespace: namespace,
 	}
 	return &installer{
-		istiodReconciler: sharedreconcile.NewIstiodReconciler(cfg, l.cl),
+		istiodReconciler:      sharedreconcile.NewIstiodReconciler(cfg, l.cl),
+		cniReconciler:         sharedreconcile.NewCNIReconciler(cfg, l.cl),
+		ztunnelReconciler:     sharedreconcile.NewZTunnelReconciler(cfg, l.cl),
+		gatewayReconciler:     sharedreconcile.NewGatewayReconciler(cfg, l.cl),
+		revisionTagReconciler: sharedreconcile.NewRevisionTagReconciler(cfg, l.cl),
 		crdManager: &crdManager{
 			cl:                  l.cl,
 			crdFS:               l.crdFS,
@@ -177,13 +270,19 @@ func (l *Library) newInstaller(namespace string) *installer {
 }
 
 type installer struct {
-	istiodReconciler *sharedreconcile.IstiodReconciler
-	crdManager       *crdManager
-	cfg              sharedreconcile.Config
-	platform         config.Platform
+	istiodReconciler      *sharedreconcile.IstiodReconciler
+	cniReconciler         *sharedreconcile.CNIReconciler
+	ztunnelReconciler     *sharedreconcile.ZTunnelReconciler
+	gatewayReconciler     *sharedreconcile.GatewayReconciler
+	revisionTagReconciler *sharedreconcile.RevisionTagReconciler
+	crdManager            *crdManager
+	cfg                   sharedreconcile.Config
+	platform              config.Platform
 }
 
-func (inst *installer) reconcile(ctx context.Context, opts Options) Status {
+// reconcile installs istiod and the optional components. If defaultTag is
+// true, the default revision tag is pointed to the revision.
+func (inst *installer) reconcile(ctx context.Context, opts Options, defaultTag bool) Status {
 	status := Status{Version: opts.Version}
 
 	if err := istioversion.ValidateVersion(opts.Version); err != nil {
@@ -197,15 +296,7 @@ func (inst *installer) reconcile(ctx context.Context, opts Options) Status {
 		return status
 	}
 
-	revisionName := opts.Revision
-	if revisionName == "" {
-		revisionName = "default"
-	}
-
-	defaultProfile := ""
-	if inst.platform == config.PlatformOpenShift {
-		defaultProfile = "openshift"
-	}
+	revisionName := opts.Key().Revision
 
 	var tlsCfg *config.TLSConfig
 	if opts.OpenShiftTLS != nil {
@@ -221,8 +312,8 @@ func (inst *installer) reconcile(ctx context.Context, opts Options) Status {
 		opts.Namespace,
 		resolvedVersion,
 		inst.platform,
-		defaultProfile,
-		"",
+		inst.cfg.DefaultProfile,
+		opts.Profile,
 		inst.cfg.ResourceFS,
 		revisionName,
 		tlsCfg,
@@ -250,7 +341,7 @@ func (inst *installer) reconcile(ctx context.Context, opts Options) Status {
 		return status
 	}
 
-	if err := inst.istiodReconciler.Install(ctx, resolvedVersion, opts.Namespace, values, revisionName, nil); err != nil {
+	if err := inst.istiodReconciler.Install(ctx, resolvedVersion, opts.Namespace, values, nil, revisionName, nil); err != nil {
 		status.Error = fmt.Errorf("failed to install istiod: %w", err)
 		return status
 	}
@@ -262,10 +353,123 @@ func (inst *installer) reconcile(ctx context.Context, opts Options) Status {
 		}
 	}
 
+	status.Istiod = newComponentStatus(inst.istiodReconciler.CheckReadiness(ctx, opts.Namespace, revisionName))
+
+	if opts.CNI != nil {
+		status.CNI = &ComponentStatus{}
+		namespace := componentNamespace(opts.CNI.Namespace, opts.Namespace)
+		if err := inst.cniReconciler.Validate(ctx, resolvedVersion, namespace); err != nil {
+			status.Error = fmt.Errorf("failed to validate istio-cni: %w", err)
+			return status
+		}
+		if err := inst.cniReconciler.Install(ctx, resolvedVersion, namespace, opts.CNI.Values, opts.Profile, nil, nil); err != nil {
+			status.Error = fmt.Errorf("failed to install istio-cni: %w", err)
+			return status
+		}
+		*status.CNI = newComponentStatus(inst.cniReconciler.CheckReadiness(ctx, namespace))
+	}
+
+	if opts.ZTunnel != nil {
+		status.ZTunnel = &ComponentStatus{}
+		namespace := componentNamespace(opts.ZTunnel.Namespace, opts.Namespace)
+		if err := inst.ztunnelReconciler.Validate(ctx, resolvedVersion, namespace); err != nil {
+			status.Error = fmt.Errorf("failed to validate ztunnel: %w", err)
+			return status
+		}
+		baseValues := helm.FromValues(v1.Values{MeshConfig: values.MeshConfig, Revision: values.Revision, Global: values.Global})
+		if err := inst.ztunnelReconciler.Install(ctx, resolvedVersion, namespace, opts.ZTunnel.Values, nil, nil, baseValues); err != nil {
+			status.Error = fmt.Errorf("failed to install ztunnel: %w", err)
+			return status
+		}
+		*status.ZTunnel = newComponentStatus(inst.ztunnelReconciler.CheckReadiness(ctx, namespace))
+	}
+
+	if opts.Gateway != nil {
+		status.Gateway = &ComponentStatus{}
+		namespace := componentNamespace(opts.Gateway.Namespace, opts.Namespace)
+		if err := inst.gatewayReconciler.Validate(ctx, resolvedVersion, namespace); err != nil {
+			status.Error = fmt.Errorf("failed to validate gateway %q: %w", opts.Gateway.Name, err)
+			return status
+		}
+		baseValues := sharedreconcile.GatewayRevisionValues(resolvedVersion, values)
+		if err := inst.gatewayReconciler.Install(
+			ctx, resolvedVersion, namespace, opts.Gateway.Name, opts.Gateway.Values, nil, nil, baseValues); err != nil {
+			status.Error = fmt.Errorf("failed to install gateway %q: %w", opts.Gateway.Name, err)
+			return status
+		}
+		*status.Gateway = newComponentStatus(inst.gatewayReconciler.CheckReadiness(ctx, namespace, opts.Gateway.Name))
+	}
+
+	if defaultTag {
+		status.DefaultTag = &ComponentStatus{}
+		if err := inst.revisionTagReconciler.Install(
+			ctx, resolvedVersion, opts.Namespace, v1.DefaultRevisionTag, revisionName, values, nil); err != nil {
+			status.Error = fmt.Errorf("failed to install the default revision tag: %w", err)
+			return status
+		}
+		*status.DefaultTag = newComponentStatus(inst.revisionTagReconciler.CheckReadiness(ctx, opts.Namespace, v1.DefaultRevisionTag))
+	}
+
 	status.Installed = true
 	return status
 }
 
+// componentStatus returns the status of an installed component from the
+// readiness of the objects in its Helm release.
+func newComponentStatus(resources []v1.ResourceStatus, err error) ComponentStatus {
+	status := ComponentStatus{Installed: true, Resources: resources}
+	if err != nil {
+		status.Message = fmt.Sprintf("failed to check readiness: %v", err)
+		return status
+	}
+	status.Ready, status.Message = reconciler.SummarizeReadiness(resources)
+	return status
+}
+
+// removeComponents uninstalls the optional components that were installed
+// with the previous options, but are no longer requested, or were moved to
+// a different namespace or name. If desired is nil, all of them are removed.
+func (inst *installer) removeComponents(ctx context.Context, previous, desired *Options) error {
+	if desired == nil {
+		desired = &Options{}
+	}
+	if previous.CNI != nil {
+		namespace := componentNamespace(previous.CNI.Namespace, previous.Namespace)
+		if desired.CNI == nil || componentNamespace(desired.CNI.Namespace, desired.Namespace) != namespace {
+			if err := inst.cniReconciler.Uninstall(ctx, namespace); err != nil {
+				return err
+			}
+		}
+	}
+	if previous.ZTunnel != nil {
+		namespace := componentNamespace(previous.ZTunnel.Namespace, previous.Namespace)
+		if desired.ZTunnel == nil || componentNamespace(desired.ZTunnel.Namespace, desired.Namespace) != namespace {
+			if err := inst.ztunnelReconciler.Uninstall(ctx, namespace); err != nil {
+				return err
+			}
+		}
+	}
+	if previous.Gateway != nil {
+		namespace := componentNamespace(previous.Gateway.Namespace, previous.Namespace)
+		if desired.Gateway == nil || desired.Gateway.Name != previous.Gateway.Name ||
+			componentNamespace(desired.Gateway.Namespace, desired.Namespace) != namespace {
+			if err := inst.gatewayReconciler.Uninstall(ctx, namespace, previous.Gateway.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// componentNamespace returns the namespace of an optional component, which
+// defaults to the istiod namespace.
+func componentNamespace(namespace, istiodNamespace string) string {
+	if namespace != "" {
+		return namespace
+	}
+	return istiodNamespace
+}
+
 func (inst *installer) uninstall(ctx context.Context, namespace, revisionName string) error {
 	if err := inst.istiodReconciler.Uninstall(ctx, namespace, revisionName); err != nil {
 		return err
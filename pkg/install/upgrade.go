@@ -0,0 +1,211 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package install
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/revision"
+	admissionv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"istio.io/istio/pkg/log"
+)
+
+const (
+	defaultUpgradePollInterval = 5 * time.Second
+
+	// podListPageSize is the number of pods listed at once when checking
+	// whether a revision is still in use.
+	podListPageSize = 500
+
+	// revisionTagLabel is set on the webhook of a revision tag to the tag's
+	// name; its istio.io/rev label is set to the revision it points to.
+	revisionTagLabel = "istio.io/tag"
+)
+
+// UpgradeOption configures optional UpgradeRevision parameters.
+type UpgradeOption func(*upgradeOptions)
+
+type upgradeOptions struct {
+	pollInterval     time.Duration
+	waitForWorkloads bool
+}
+
+// WithPollInterval sets how often UpgradeRevision checks the status of the
+// installations. Defaults to 5 seconds.
+func WithPollInterval(interval time.Duration) UpgradeOption {
+	return func(o *upgradeOptions) { o.pollInterval = interval }
+}
+
+// WithWaitForWorkloads makes UpgradeRevision wait until no revision tag,
+// namespace or running pod references the old revision anymore before
+// uninstalling it, so that the caller can move the workloads in the meantime. Without it, the old revision is
+// uninstalled as soon as the default tag points to the new one.
+func WithWaitForWorkloads() UpgradeOption {
+	return func(o *upgradeOptions) { o.waitForWorkloads = true }
+}
+
+// UpgradeRevision performs a revision-based upgrade from the installation
+// identified by from to the installation described by to, like the
+// RevisionBased update strategy of the Sail Operator does:
+//
+//  1. the new revision is installed next to the old one; the CRDs and the
+//     components that both installations request are handed over to it,
+//  2. once istiod of the new revision is ready, the default revision tag is
+//     pointed to it,
+//  3. the old revision is uninstalled.
+//
+// Both installations must use named revisions, since the revision named
+// "default" can't be moved with the default tag; it is upgraded in place by
+// Apply. The reconciliation loop must be running (see Start). The context
+// bounds how long UpgradeRevision waits; if it expires, the old revision is
+// left installed.
+func (l *Library) UpgradeRevision(ctx context.Context, from InstallationKey, to Options, opts ...UpgradeOption) error {
+	o := upgradeOptions{pollInterval: defaultUpgradePollInterval}
+	for _, fn := range opts {
+		fn(&o)
+	}
+
+	toKey := to.Key()
+	if from.Revision == v1.DefaultRevision || toKey.Revision == v1.DefaultRevision {
+		return fmt.Errorf("revision-based upgrades require named revisions, use Apply to upgrade the %q revision in place",
+			v1.DefaultRevision)
+	}
+	if from == toKey {
+		return fmt.Errorf("installation %s can't be upgraded to itself", from)
+	}
+
+	log.Infof("Upgrading installation %s to %s", from, toKey)
+	if err := l.apply(to, &from); err != nil {
+		return err
+	}
+	if err := l.waitForInstallation(ctx, toKey, o.pollInterval, func(status Status) bool {
+		return status.Installed && status.Istiod.Ready
+	}); err != nil {
+		return fmt.Errorf("installation %s didn't become ready: %w", toKey, err)
+	}
+
+	if err := l.SetDefaultRevision(toKey.Namespace, toKey.Revision); err != nil {
+		return err
+	}
+	if err := l.waitForInstallation(ctx, toKey, o.pollInterval, func(status Status) bool {
+		return status.DefaultTag != nil && status.DefaultTag.Ready
+	}); err != nil {
+		return fmt.Errorf("default revision tag didn't become ready: %w", err)
+	}
+
+	if o.waitForWorkloads {
+		log.Infof("Waiting for workloads to move off revision %s", from.Revision)
+		if err := wait.PollUntilContextCancel(ctx, o.pollInterval, true, func(ctx context.Context) (bool, error) {
+			inUse, err := l.isRevisionInUse(ctx, from.Revision)
+			return !inUse, err
+		}); err != nil {
+			return fmt.Errorf("revision %s is still in use: %w", from.Revision, err)
+		}
+	}
+
+	return l.Uninstall(ctx, from.Namespace, from.Revision)
+}
+
+// waitForInstallation waits until the installation was reconciled at its
+// current generation without errors and the given condition is met.
+func (l *Library) waitForInstallation(ctx context.Context, key InstallationKey, interval time.Duration, condition func(Status) bool) error {
+	l.mu.Lock()
+	inst := l.installations[key]
+	if inst == nil {
+		l.mu.Unlock()
+		return fmt.Errorf("installation %s not found", key)
+	}
+	generation := inst.generation
+	l.mu.Unlock()
+
+	var lastErr error
+	err := wait.PollUntilContextCancel(ctx, interval, true, func(context.Context) (bool, error) {
+		status, found := l.InstallationStatus(key.Namespace, key.Revision)
+		if !found || status.Generation < generation {
+			return false, nil
+		}
+		if status.Error != nil {
+			lastErr = status.Error
+			return false, nil
+		}
+		return condition(status), nil
+	})
+	if err != nil && lastErr != nil {
+		return fmt.Errorf("%w (last error: %w)", err, lastErr)
+	}
+	return err
+}
+
+// isRevisionInUse returns true if a revision tag points to the given
+// revision, or if a namespace or a pod that hasn't terminated references it,
+// as determined by revision.FindReferencesIn. The library doesn't cache any
+// objects, so the namespaces are checked before the pods are listed, and the
+// pods are listed in pages.
+func (l *Library) isRevisionInUse(ctx context.Context, revisionName string) (bool, error) {
+	rev := &v1.IstioRevision{ObjectMeta: metav1.ObjectMeta{Name: revisionName}}
+	tags, err := l.listRevisionTags(ctx, revisionName)
+	if err != nil {
+		return false, err
+	}
+	namespaces := &corev1.NamespaceList{}
+	if err := l.cl.List(ctx, namespaces); err != nil {
+		return false, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	if refs := revision.FindReferencesIn(rev, tags, namespaces.Items, nil); refs.InUse() {
+		log.Debugf("Revision %s is referenced by tags %v and namespaces %v", revisionName, refs.Tags, refs.Namespaces)
+		return true, nil
+	}
+
+	pods := &corev1.PodList{}
+	for {
+		if err := l.cl.List(ctx, pods, client.Limit(podListPageSize), client.Continue(pods.Continue)); err != nil {
+			return false, fmt.Errorf("failed to list pods: %w", err)
+		}
+		if refs := revision.FindReferencesIn(rev, nil, namespaces.Items, pods.Items); refs.InUse() {
+			log.Debugf("Revision %s is referenced by pods %v", revisionName, refs.Pods)
+			return true, nil
+		}
+		if pods.Continue == "" {
+			return false, nil
+		}
+	}
+}
+
+// listRevisionTags returns the revision tags that point to the given
+// revision. Tags aren't represented by IstioRevisionTag objects outside of
+// the operator, so they are determined from the labels of the tag webhooks.
+func (l *Library) listRevisionTags(ctx context.Context, revisionName string) ([]v1.IstioRevisionTag, error) {
+	webhooks := &admissionv1.MutatingWebhookConfigurationList{}
+	if err := l.cl.List(ctx, webhooks, client.MatchingLabels{constants.IstioRevLabel: revisionName}, client.HasLabels{revisionTagLabel}); err != nil {
+		return nil, fmt.Errorf("failed to list mutating webhook configurations: %w", err)
+	}
+	var tags []v1.IstioRevisionTag
+	for _, webhook := range webhooks.Items {
+		tags = append(tags, v1.IstioRevisionTag{
+			ObjectMeta: metav1.ObjectMeta{Name: webhook.Labels[revisionTagLabel]},
+			Status:     v1.IstioRevisionTagStatus{IstioRevision: revisionName},
+		})
+	}
+	return tags, nil
+}
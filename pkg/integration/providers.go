@@ -0,0 +1,113 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+const (
+	// PrometheusProviderName is the name of the extension provider added by a MetricsIntegration
+	PrometheusProviderName = "prometheus"
+
+	// OpenTelemetryProviderName is the name of the extension provider added by an OpenTelemetry TracingIntegration
+	OpenTelemetryProviderName = "otel"
+
+	// TempoStackProviderName is the name of the extension provider added by a TempoStack TracingIntegration
+	TempoStackProviderName = "tempo"
+
+	// otlpGRPCPort is the port on which OpenTelemetry Collectors and Tempo distributors receive OTLP over gRPC
+	otlpGRPCPort = 4317
+
+	defaultClusterDomain = "cluster.local"
+)
+
+// GetExtensionProviders returns the meshConfig.extensionProviders that the integrations targeting the given Istio
+// object contribute to its values. The clusterDomain is used to build the addresses of the backend services.
+func GetExtensionProviders(ctx context.Context, cl client.Client, istioName, clusterDomain string) ([]*v1.MeshConfigExtensionProvider, error) {
+	var providers []*v1.MeshConfigExtensionProvider
+	target := IstioTarget(istioName)
+
+	metrics, err := GetMetricsIntegrationForTarget(ctx, cl, target)
+	if err != nil {
+		return nil, err
+	}
+	if metrics != nil {
+		providers = append(providers, MetricsExtensionProvider(metrics))
+	}
+
+	tracing, err := GetTracingIntegrationForTarget(ctx, cl, target)
+	if err != nil {
+		return nil, err
+	}
+	if tracing != nil {
+		if provider := TracingExtensionProvider(tracing, clusterDomain); provider != nil {
+			providers = append(providers, provider)
+		}
+	}
+	return providers, nil
+}
+
+// MetricsExtensionProvider returns the extension provider for the given MetricsIntegration. All metrics backends
+// scrape the proxies' Prometheus endpoint, so this is always the prometheus provider.
+func MetricsExtensionProvider(_ *v1alpha1.MetricsIntegration) *v1.MeshConfigExtensionProvider {
+	return &v1.MeshConfigExtensionProvider{
+		Name:       ptr.Of(PrometheusProviderName),
+		Prometheus: &v1.MeshConfigExtensionProviderPrometheusMetricsProvider{},
+	}
+}
+
+// TracingExtensionProvider returns the extension provider that sends traces to the backend configured in the
+// given TracingIntegration, or nil if the backend isn't configured.
+func TracingExtensionProvider(tracing *v1alpha1.TracingIntegration, clusterDomain string) *v1.MeshConfigExtensionProvider {
+	if clusterDomain == "" {
+		clusterDomain = defaultClusterDomain
+	}
+
+	var name, service string
+	switch tracing.Spec.Type {
+	case v1alpha1.TracingTypeOpenTelemetry:
+		if tracing.Spec.OpenTelemetry == nil {
+			return nil
+		}
+		ref := tracing.Spec.OpenTelemetry.OTELCollectorRef
+		// the OpenTelemetry Operator exposes a collector through the <name>-collector service
+		name, service = OpenTelemetryProviderName, fmt.Sprintf("%s-collector.%s.svc.%s", ref.Name, ref.Namespace, clusterDomain)
+	case v1alpha1.TracingTypeTempoStack:
+		if tracing.Spec.TempoStack == nil {
+			return nil
+		}
+		ref := tracing.Spec.TempoStack.TempoStackRef
+		// the Tempo Operator exposes the distributor of a TempoStack through the tempo-<name>-distributor service
+		name, service = TempoStackProviderName, fmt.Sprintf("tempo-%s-distributor.%s.svc.%s", ref.Name, ref.Namespace, clusterDomain)
+	default:
+		return nil
+	}
+
+	return &v1.MeshConfigExtensionProvider{
+		Name: ptr.Of(name),
+		Opentelemetry: &v1.MeshConfigExtensionProviderOpenTelemetryTracingProvider{
+			Service: ptr.Of(service),
+			Port:    ptr.Of(uint32(otlpGRPCPort)),
+		},
+	}
+}
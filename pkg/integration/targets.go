@@ -0,0 +1,115 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GetIstioFromTargetReference returns the Istio object referenced by the target reference. It returns a
+// validation error if the reference points to a kind of resource that the operator can't configure yet.
+func GetIstioFromTargetReference(ctx context.Context, cl client.Client, ref v1alpha1.TargetReference) (*v1.Istio, error) {
+	switch ref.Kind {
+	case v1alpha1.IstioTargetKind:
+		istio := &v1.Istio{}
+		if err := cl.Get(ctx, types.NamespacedName{Name: ref.Name}, istio); err != nil {
+			return nil, err
+		}
+		return istio, nil
+	case v1alpha1.KialiTargetKind:
+		return nil, reconciler.NewValidationError("configuring Kiali is not supported yet")
+	default:
+		return nil, reconciler.NewValidationError("unknown targetRef.kind")
+	}
+}
+
+// GetMetricsIntegrationForTarget returns the MetricsIntegration that configures the target. When several
+// MetricsIntegrations reference the same target, the oldest one takes precedence. It returns nil if no
+// MetricsIntegration references the target.
+func GetMetricsIntegrationForTarget(ctx context.Context, cl client.Client, target v1alpha1.TargetReference) (*v1alpha1.MetricsIntegration, error) {
+	list := v1alpha1.MetricsIntegrationList{}
+	if err := cl.List(ctx, &list); err != nil {
+		return nil, fmt.Errorf("failed to list MetricsIntegrations: %w", err)
+	}
+	var candidates []*v1alpha1.MetricsIntegration
+	for i := range list.Items {
+		if referencesTarget(list.Items[i].Spec.TargetRefs, target) {
+			candidates = append(candidates, &list.Items[i])
+		}
+	}
+	return oldest(candidates), nil
+}
+
+// GetTracingIntegrationForTarget returns the TracingIntegration that configures the target. When several
+// TracingIntegrations reference the same target, the oldest one takes precedence. It returns nil if no
+// TracingIntegration references the target.
+func GetTracingIntegrationForTarget(ctx context.Context, cl client.Client, target v1alpha1.TargetReference) (*v1alpha1.TracingIntegration, error) {
+	list := v1alpha1.TracingIntegrationList{}
+	if err := cl.List(ctx, &list); err != nil {
+		return nil, fmt.Errorf("failed to list TracingIntegrations: %w", err)
+	}
+	var candidates []*v1alpha1.TracingIntegration
+	for i := range list.Items {
+		if referencesTarget(list.Items[i].Spec.TargetRefs, target) {
+			candidates = append(candidates, &list.Items[i])
+		}
+	}
+	return oldest(candidates), nil
+}
+
+// IstioTarget returns the target reference of the given Istio object.
+func IstioTarget(istioName string) v1alpha1.TargetReference {
+	return v1alpha1.TargetReference{Kind: v1alpha1.IstioTargetKind, Name: istioName}
+}
+
+func referencesTarget(refs []v1alpha1.TargetReference, target v1alpha1.TargetReference) bool {
+	for _, ref := range refs {
+		if ref == target {
+			return true
+		}
+	}
+	return false
+}
+
+// oldest returns the object that was created first, using the name to break ties. Objects that are being
+// deleted are ignored.
+func oldest[T client.Object](objs []T) T {
+	var live []T
+	for _, obj := range objs {
+		if obj.GetDeletionTimestamp().IsZero() {
+			live = append(live, obj)
+		}
+	}
+	var zero T
+	if len(live) == 0 {
+		return zero
+	}
+	sort.SliceStable(live, func(i, j int) bool {
+		ti, tj := live[i].GetCreationTimestamp(), live[j].GetCreationTimestamp()
+		if !ti.Equal(&tj) {
+			return ti.Before(&tj)
+		}
+		return live[i].GetName() < live[j].GetName()
+	})
+	return live[0]
+}
@@ -0,0 +1,45 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiovalues
+
+import (
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+)
+
+// ApplyExtensionProviders adds the given providers to meshConfig.extensionProviders. A provider is not added
+// if the values already contain a provider with the same name, so that providers configured by the user always
+// take precedence.
+func ApplyExtensionProviders(values *v1.Values, providers []*v1.MeshConfigExtensionProvider) {
+	if values == nil || len(providers) == 0 {
+		return
+	}
+	if values.MeshConfig == nil {
+		values.MeshConfig = &v1.MeshConfig{}
+	}
+
+	existing := map[string]bool{}
+	for _, provider := range values.MeshConfig.ExtensionProviders {
+		if provider != nil && provider.Name != nil {
+			existing[*provider.Name] = true
+		}
+	}
+	for _, provider := range providers {
+		if provider == nil || provider.Name == nil || existing[*provider.Name] {
+			continue
+		}
+		values.MeshConfig.ExtensionProviders = append(values.MeshConfig.ExtensionProviders, provider)
+		existing[*provider.Name] = true
+	}
+}
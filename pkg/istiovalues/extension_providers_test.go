@@ -0,0 +1,96 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiovalues
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestApplyExtensionProviders(t *testing.T) {
+	otel := &v1.MeshConfigExtensionProvider{
+		Name: ptr.Of("otel"),
+		Opentelemetry: &v1.MeshConfigExtensionProviderOpenTelemetryTracingProvider{
+			Service: ptr.Of("otel-collector.istio-system.svc.cluster.local"),
+			Port:    ptr.Of(uint32(4317)),
+		},
+	}
+	userOtel := &v1.MeshConfigExtensionProvider{
+		Name: ptr.Of("otel"),
+		Opentelemetry: &v1.MeshConfigExtensionProviderOpenTelemetryTracingProvider{
+			Service: ptr.Of("my-collector.observability.svc.cluster.local"),
+			Port:    ptr.Of(uint32(4318)),
+		},
+	}
+	prometheus := &v1.MeshConfigExtensionProvider{
+		Name:       ptr.Of("prometheus"),
+		Prometheus: &v1.MeshConfigExtensionProviderPrometheusMetricsProvider{},
+	}
+
+	tests := []struct {
+		name      string
+		values    *v1.Values
+		providers []*v1.MeshConfigExtensionProvider
+		expected  *v1.Values
+	}{
+		{
+			name:     "nil values",
+			values:   nil,
+			expected: nil,
+		},
+		{
+			name:     "no providers",
+			values:   &v1.Values{},
+			expected: &v1.Values{},
+		},
+		{
+			name:      "no meshConfig",
+			values:    &v1.Values{},
+			providers: []*v1.MeshConfigExtensionProvider{otel, prometheus},
+			expected: &v1.Values{
+				MeshConfig: &v1.MeshConfig{
+					ExtensionProviders: []*v1.MeshConfigExtensionProvider{otel, prometheus},
+				},
+			},
+		},
+		{
+			name: "user providers are preserved",
+			values: &v1.Values{
+				MeshConfig: &v1.MeshConfig{
+					ExtensionProviders: []*v1.MeshConfigExtensionProvider{userOtel},
+				},
+			},
+			providers: []*v1.MeshConfigExtensionProvider{otel, prometheus},
+			expected: &v1.Values{
+				MeshConfig: &v1.MeshConfig{
+					ExtensionProviders: []*v1.MeshConfigExtensionProvider{userOtel, prometheus},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ApplyExtensionProviders(tt.values, tt.providers)
+			if diff := cmp.Diff(tt.expected, tt.values); diff != "" {
+				t.Errorf("unexpected values; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
@@ -15,6 +15,7 @@
 package istiovalues
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"path"
@@ -55,6 +56,24 @@ func ApplyUserValues(mergedValues, userValues helm.Values,
 	return values, nil
 }
 
+// ValidateProfile checks that the profiles that ApplyProfilesAndPlatform applies for the given version exist.
+func ValidateProfile(resourceFS fs.FS, version, defaultProfile, userProfile string) error {
+	profilesPath := path.Join(version, "profiles")
+	for _, profile := range resolve(defaultProfile, userProfile) {
+		file := path.Join(profilesPath, profile+".yaml")
+		if path.Dir(file) != profilesPath {
+			return fmt.Errorf("invalid profile name %s", profile)
+		}
+		if _, err := fs.Stat(resourceFS, file); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("profile %q doesn't exist in version %s", profile, version)
+			}
+			return fmt.Errorf("failed to read profile file %v: %w", file, err)
+		}
+	}
+	return nil
+}
+
 func resolve(defaultProfile, userProfile string) []string {
 	switch {
 	case userProfile != "" && userProfile != "default":
@@ -18,7 +18,9 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/istio-ecosystem/sail-operator/pkg/helm"
@@ -261,3 +263,38 @@ func Must(t *testing.T, err error) {
 		t.Fatal(err)
 	}
 }
+
+func TestValidateProfile(t *testing.T) {
+	resourceFS := fstest.MapFS{
+		"v1.0.0/profiles/default.yaml":   {Data: []byte("spec: {}")},
+		"v1.0.0/profiles/openshift.yaml": {Data: []byte("spec: {}")},
+		"v1.0.0/profiles/ambient.yaml":   {Data: []byte("spec: {}")},
+	}
+
+	tests := []struct {
+		name           string
+		defaultProfile string
+		userProfile    string
+		errContains    string
+	}{
+		{name: "default profile"},
+		{name: "user profile", userProfile: "ambient"},
+		{name: "platform profile", defaultProfile: "openshift"},
+		{name: "missing user profile", userProfile: "stable", errContains: `profile "stable" doesn't exist in version v1.0.0`},
+		{name: "missing platform profile", defaultProfile: "other", errContains: `profile "other" doesn't exist`},
+		{name: "path traversal", userProfile: "../v2.0.0/profiles/default", errContains: "invalid profile name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateProfile(resourceFS, "v1.0.0", tt.defaultProfile, tt.userProfile)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("expected error containing %q, got %v", tt.errContains, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiovalues
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"sync"
+
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"gopkg.in/yaml.v3"
+
+	"istio.io/istio/pkg/util/sets"
+)
+
+// ChartValues identifies the values of a Helm chart within the values of a resource. The values at the root of
+// the chart's values.yaml can also be set under Prefix (e.g. `pilot` for the istiod chart), because they are
+// copied to the root when the chart is rendered.
+type ChartValues struct {
+	Chart  string
+	Prefix string
+}
+
+// SupportedValues determines which values the charts of each version support, based on the defaults declared in
+// their values.yaml files. Each file is only parsed once.
+type SupportedValues struct {
+	resourceFS fs.FS
+
+	mu    sync.Mutex
+	known map[string]sets.Set[string]
+}
+
+func NewSupportedValues(resourceFS fs.FS) *SupportedValues {
+	return &SupportedValues{
+		resourceFS: resourceFS,
+		known:      map[string]sets.Set[string]{},
+	}
+}
+
+// Unsupported returns the paths of the values that aren't supported by the charts in the given version, but are
+// supported by the same charts in one of the other versions. Only the first two levels of the values are compared,
+// because the deeper levels often hold free-form maps (e.g. pod annotations) that the charts don't declare.
+func (s *SupportedValues) Unsupported(version string, otherVersions []string, charts []ChartValues, values helm.Values) ([]string, error) {
+	known, err := s.knownPaths(version, charts)
+	if err != nil || known == nil {
+		return nil, err
+	}
+
+	var unsupported []string
+	for _, p := range valuePaths(values) {
+		if known.Contains(p) {
+			continue
+		}
+		for _, other := range otherVersions {
+			if other == version {
+				continue
+			}
+			otherKnown, err := s.knownPaths(other, charts)
+			if err != nil {
+				return nil, err
+			}
+			if otherKnown.Contains(p) {
+				unsupported = append(unsupported, p)
+				break
+			}
+		}
+	}
+	return unsupported, nil
+}
+
+// knownPaths returns the value paths declared by the charts in the given version, or nil if the version
+// doesn't contain any of the charts.
+func (s *SupportedValues) knownPaths(version string, charts []ChartValues) (sets.Set[string], error) {
+	var known sets.Set[string]
+	for _, chart := range charts {
+		paths, err := s.chartPaths(version, chart)
+		if err != nil {
+			return nil, err
+		}
+		if paths != nil {
+			known = known.Union(paths)
+		}
+	}
+	return known, nil
+}
+
+func (s *SupportedValues) chartPaths(version string, chart ChartValues) (sets.Set[string], error) {
+	file := path.Join(version, "charts", chart.Chart, "values.yaml")
+	key := file + ":" + chart.Prefix
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if paths, found := s.known[key]; found {
+		return paths, nil
+	}
+
+	contents, err := fs.ReadFile(s.resourceFS, file)
+	if errors.Is(err, fs.ErrNotExist) {
+		s.known[key] = nil
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	var defaults map[string]any
+	if err := yaml.Unmarshal(contents, &defaults); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", file, err)
+	}
+	// the charts declare their defaults under this key to work around a Helm limitation
+	if internal, ok := defaults["_internal_defaults_do_not_set"].(map[string]any); ok {
+		defaults = internal
+	}
+
+	paths := sets.New[string]()
+	for name, value := range defaults {
+		if chart.Prefix != "" {
+			paths.Insert(chart.Prefix + "." + name)
+		}
+		if m, ok := value.(map[string]any); ok {
+			for child := range m {
+				paths.Insert(name + "." + child)
+			}
+		}
+	}
+	s.known[key] = paths
+	return paths, nil
+}
+
+// valuePaths returns the sorted paths of the second-level keys in the given values.
+func valuePaths(values helm.Values) []string {
+	var paths []string
+	for key, value := range values {
+		if m, ok := value.(map[string]any); ok {
+			for child := range m {
+				paths = append(paths, key+"."+child)
+			}
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,72 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istiovalues
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupportedValues(t *testing.T) {
+	resourceFS := fstest.MapFS{
+		"v1.0.0/charts/istiod/values.yaml": {Data: []byte(`
+_internal_defaults_do_not_set:
+  replicaCount: 1
+  global:
+    hub: ""
+`)},
+		"v1.1.0/charts/istiod/values.yaml": {Data: []byte(`
+_internal_defaults_do_not_set:
+  replicaCount: 1
+  trustedZtunnelNamespace: ""
+  global:
+    hub: ""
+    nativeNftables: false
+`)},
+	}
+	charts := []ChartValues{{Chart: "istiod", Prefix: "pilot"}}
+	versions := []string{"v1.0.0", "v1.1.0"}
+
+	values := helm.Values{
+		"pilot": map[string]any{
+			"replicaCount":            2,
+			"trustedZtunnelNamespace": "kube-system",
+			"env":                     map[string]any{"FOO": "bar"},
+		},
+		"global": map[string]any{
+			"hub":            "quay.io/istio",
+			"nativeNftables": true,
+		},
+		"meshConfig": map[string]any{"accessLogFile": "/dev/stdout"},
+	}
+
+	s := NewSupportedValues(resourceFS)
+
+	unsupported, err := s.Unsupported("v1.0.0", versions, charts, values)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"global.nativeNftables", "pilot.trustedZtunnelNamespace"}, unsupported)
+
+	unsupported, err = s.Unsupported("v1.1.0", versions, charts, values)
+	assert.NoError(t, err)
+	assert.Empty(t, unsupported)
+
+	// versions that don't contain the chart don't support or reject anything
+	unsupported, err = s.Unsupported("v2.0.0", versions, charts, values)
+	assert.NoError(t, err)
+	assert.Empty(t, unsupported)
+}
@@ -0,0 +1,124 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioversion
+
+import (
+	"maps"
+	"slices"
+	"sync"
+)
+
+// CatalogEntry is an entry of the version catalog, which extends or overrides the embedded versions at runtime.
+type CatalogEntry struct {
+	// Name of the version or alias
+	Name string
+	// Ref is the version that the alias refers to. Empty if the entry isn't an alias.
+	Ref string
+	// EOL marks the version as end-of-life
+	EOL bool
+}
+
+var (
+	catalogMu sync.RWMutex
+	// catalogAliases maps the aliases defined in the catalog to the versions they refer to
+	catalogAliases = map[string]string{}
+	// catalogEOL contains the versions marked end-of-life in the catalog
+	catalogEOL = map[string]bool{}
+	// catalogDefault is the default version set in the catalog, or empty if the catalog doesn't set one
+	catalogDefault string
+)
+
+// SetCatalog replaces the entries of the version catalog. Aliases defined in the catalog take precedence over
+// the embedded aliases with the same name and may refer to embedded or external versions. Versions marked
+// end-of-life in the catalog can no longer be resolved, and neither can the aliases that refer to them.
+// SetCatalog returns true if the catalog changed.
+func SetCatalog(entries []CatalogEntry) bool {
+	aliases := map[string]string{}
+	eol := map[string]bool{}
+	for _, entry := range entries {
+		if entry.EOL {
+			eol[entry.Name] = true
+		} else if entry.Ref != "" {
+			aliases[entry.Name] = entry.Ref
+		}
+	}
+
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	if maps.Equal(aliases, catalogAliases) && maps.Equal(eol, catalogEOL) {
+		return false
+	}
+	catalogAliases = aliases
+	catalogEOL = eol
+	return true
+}
+
+// SetDefaultVersion replaces the default version of the version catalog, which takes precedence over the default
+// version in versions.yaml as long as it can be installed. An empty version removes the catalog's default version.
+func SetDefaultVersion(version string) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	catalogDefault = version
+}
+
+// lookup returns the VersionInfo of the given version or alias, taking into account the version catalog.
+func lookup(version string) (VersionInfo, bool) {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	info, ok := lookupVersion(catalogRef(version))
+	if !ok || catalogEOL[version] || catalogEOL[info.Name] {
+		return VersionInfo{}, false
+	}
+	return info, true
+}
+
+// isCatalogEOL returns true if the given version, or the version that the given alias refers to, is marked
+// end-of-life in the catalog. An alias defined in the catalog is also end-of-life if it refers to a version
+// that is marked end-of-life in versions.yaml.
+func isCatalogEOL(version string) bool {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+
+	if catalogEOL[version] {
+		return true
+	}
+	ref := catalogRef(version)
+	if ref != version && (catalogEOL[ref] || slices.Contains(EOL, ref)) {
+		return true
+	}
+	info, ok := lookupVersion(ref)
+	return ok && catalogEOL[info.Name]
+}
+
+// catalogRef returns the version that the given alias refers to, if the alias is defined in the catalog.
+// Otherwise, it returns the given version. Must be called with catalogMu held.
+func catalogRef(version string) string {
+	if ref, ok := catalogAliases[version]; ok {
+		return ref
+	}
+	return version
+}
+
+// IsAffectedByChange returns true if the given version may refer to different charts than before, after the
+// external versions or the version catalog changed. resolvedVersion is the version that the given version
+// resolved to previously.
+func IsAffectedByChange(version, resolvedVersion string) bool {
+	if !IsEmbeddedVersion(version) {
+		return true
+	}
+	resolved, err := Resolve(version)
+	return err != nil || resolved != resolvedVersion
+}
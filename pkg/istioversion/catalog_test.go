@@ -0,0 +1,116 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioversion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatalogAliases(t *testing.T) {
+	require.GreaterOrEqual(t, len(List), 2)
+	previous := List[1].Name
+	t.Cleanup(func() { SetCatalog(nil) })
+
+	SetCatalog([]CatalogEntry{{Name: "v99.0-latest", Ref: previous}})
+	resolved, err := Resolve("v99.0-latest")
+	assert.NoError(t, err)
+	assert.Equal(t, previous, resolved)
+	assert.False(t, IsEOLVersion("v99.0-latest"))
+
+	SetCatalog([]CatalogEntry{{Name: "v99.0-latest", Ref: "v99.0.0"}})
+	assert.Error(t, ValidateVersion("v99.0-latest"), "alias of an unknown version should not resolve")
+
+	if len(aliasList) > 0 {
+		embeddedAlias := aliasList[0].Name
+		SetCatalog([]CatalogEntry{{Name: embeddedAlias, Ref: previous}})
+		resolved, err = Resolve(embeddedAlias)
+		assert.NoError(t, err)
+		assert.Equal(t, previous, resolved, "catalog alias should override the embedded alias")
+	}
+
+	SetCatalog(nil)
+	assert.Error(t, ValidateVersion("v99.0-latest"))
+}
+
+func TestCatalogEOL(t *testing.T) {
+	require.GreaterOrEqual(t, len(List), 2)
+	previous := List[1].Name
+	t.Cleanup(func() { SetCatalog(nil) })
+
+	SetCatalog([]CatalogEntry{
+		{Name: previous, EOL: true},
+		{Name: "v99.0-latest", Ref: previous},
+	})
+	assert.ErrorContains(t, ValidateVersion(previous), "end-of-life")
+	assert.True(t, IsEOLVersion(previous))
+	_, err := Resolve("v99.0-latest")
+	assert.Error(t, err, "alias of an end-of-life version should not resolve")
+	assert.True(t, IsEOLVersion("v99.0-latest"))
+	assert.True(t, IsEmbeddedVersion(previous), "marking a version end-of-life should not change whether it is embedded")
+
+	SetCatalog(nil)
+	assert.NoError(t, ValidateVersion(previous))
+	assert.False(t, IsEOLVersion(previous))
+}
+
+func TestIsAffectedByChange(t *testing.T) {
+	require.GreaterOrEqual(t, len(List), 2)
+	newest, previous := List[0].Name, List[1].Name
+	t.Cleanup(func() { SetCatalog(nil) })
+
+	assert.False(t, IsAffectedByChange(newest, newest))
+	assert.True(t, IsAffectedByChange(newest, ""), "version that didn't resolve previously should be affected")
+	assert.True(t, IsAffectedByChange("v99.0.0", ""), "versions that aren't embedded should always be affected")
+
+	SetCatalog([]CatalogEntry{{Name: newest, EOL: true}})
+	assert.True(t, IsAffectedByChange(newest, newest))
+
+	SetCatalog(nil)
+	if len(aliasList) > 0 {
+		alias := aliasList[0]
+		resolved, err := Resolve(alias.Name)
+		require.NoError(t, err)
+		SetCatalog([]CatalogEntry{{Name: alias.Name, Ref: previous}})
+		assert.Equal(t, resolved != previous, IsAffectedByChange(alias.Name, resolved))
+	}
+}
+
+func TestCatalogDefaultVersion(t *testing.T) {
+	require.GreaterOrEqual(t, len(List), 2)
+	previous := List[1].Name
+	t.Cleanup(func() {
+		SetDefaultVersion("")
+		SetCatalog(nil)
+	})
+
+	assert.Equal(t, Default, DefaultVersion())
+
+	SetCatalog([]CatalogEntry{{Name: "v99.0-latest", Ref: previous}})
+	SetDefaultVersion("v99.0-latest")
+	assert.Equal(t, "v99.0-latest", DefaultVersion())
+	resolved, err := Resolve("")
+	assert.NoError(t, err)
+	assert.Equal(t, previous, resolved, "empty version should resolve to the catalog's default version")
+
+	SetCatalog([]CatalogEntry{{Name: previous, EOL: true}})
+	SetDefaultVersion(previous)
+	assert.Equal(t, Default, DefaultVersion(), "end-of-life default version should be ignored")
+
+	SetDefaultVersion("v99.0.0")
+	assert.Equal(t, Default, DefaultVersion(), "unknown default version should be ignored")
+}
@@ -0,0 +1,69 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioversion
+
+import (
+	"slices"
+	"sync"
+)
+
+var (
+	externalMu sync.RWMutex
+	// externalVersions contains the versions registered at runtime, keyed by the source that registered them
+	externalVersions = map[string][]VersionInfo{}
+)
+
+// SetExternalVersions registers versions that aren't embedded in the operator, but are provided at runtime by
+// the given source (e.g. an IstioChartSource). The versions replace those that the source registered previously.
+// Embedded versions always take precedence over external versions with the same name.
+func SetExternalVersions(source string, versions []VersionInfo) {
+	externalMu.Lock()
+	defer externalMu.Unlock()
+	if len(versions) == 0 {
+		delete(externalVersions, source)
+		return
+	}
+	externalVersions[source] = slices.Clone(versions)
+}
+
+// RemoveExternalVersions unregisters the versions registered by the given source.
+func RemoveExternalVersions(source string) {
+	SetExternalVersions(source, nil)
+}
+
+// IsEmbeddedVersion returns true if the given version or alias is embedded in the operator.
+func IsEmbeddedVersion(version string) bool {
+	_, ok := Map[version]
+	return ok
+}
+
+// lookupVersion returns the VersionInfo of the given version or alias, without consulting the version catalog.
+// Embedded versions are looked up first.
+func lookupVersion(version string) (VersionInfo, bool) {
+	if info, ok := Map[version]; ok {
+		return info, true
+	}
+
+	externalMu.RLock()
+	defer externalMu.RUnlock()
+	for _, versions := range externalVersions {
+		for _, info := range versions {
+			if info.Name == version {
+				return info, true
+			}
+		}
+	}
+	return VersionInfo{}, false
+}
@@ -0,0 +1,54 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioversion
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExternalVersions(t *testing.T) {
+	external := VersionInfo{Name: "v99.0.0", Version: semver.MustParse("99.0.0")}
+	t.Cleanup(func() { RemoveExternalVersions("test") })
+
+	assert.Error(t, ValidateVersion(external.Name))
+
+	SetExternalVersions("test", []VersionInfo{external})
+	assert.NoError(t, ValidateVersion(external.Name))
+	resolved, err := Resolve(external.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, external.Name, resolved)
+	assert.False(t, IsEmbeddedVersion(external.Name))
+
+	SetExternalVersions("test", []VersionInfo{{Name: "v99.0.1", Version: semver.MustParse("99.0.1")}})
+	assert.Error(t, ValidateVersion(external.Name), "versions registered previously by the source should be replaced")
+	assert.NoError(t, ValidateVersion("v99.0.1"))
+
+	RemoveExternalVersions("test")
+	assert.Error(t, ValidateVersion("v99.0.1"))
+}
+
+func TestExternalVersionsDoNotOverrideEmbedded(t *testing.T) {
+	embedded := Map[Default]
+	t.Cleanup(func() { RemoveExternalVersions("test") })
+
+	SetExternalVersions("test", []VersionInfo{{Name: Default, Version: semver.MustParse("99.0.0")}})
+	info, ok := lookup(Default)
+	assert.True(t, ok)
+	assert.Equal(t, embedded, info)
+	assert.True(t, IsEmbeddedVersion(Default))
+}
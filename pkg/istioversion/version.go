@@ -76,23 +76,39 @@ var (
 	EOL []string
 )
 
+// Resolve returns the version that the given version or alias refers to. An empty version resolves to the
+// default version.
 func Resolve(version string) (string, error) {
-	info, ok := Map[version]
+	if version == "" {
+		version = DefaultVersion()
+	}
+	info, ok := lookup(version)
 	if !ok {
 		return "", fmt.Errorf("version %q not found", version)
 	}
 	return info.Name, nil
 }
 
+// DefaultVersion returns the default version set in the version catalog, or the default version in versions.yaml
+// if the catalog doesn't set one or its default version can't be installed, e.g. because it's end-of-life.
 func DefaultVersion() string {
-	return Default
+	catalogMu.RLock()
+	version := catalogDefault
+	catalogMu.RUnlock()
+	if version == "" {
+		return Default
+	}
+	if _, ok := lookup(version); !ok {
+		return Default
+	}
+	return version
 }
 
 func ValidateVersion(version string) error {
 	if version == "" {
 		return fmt.Errorf("version must not be empty")
 	}
-	if _, ok := Map[version]; !ok {
+	if _, ok := lookup(version); !ok {
 		if IsEOLVersion(version) {
 			return fmt.Errorf("version %q is end-of-life and cannot be installed", version)
 		}
@@ -101,14 +117,20 @@ func ValidateVersion(version string) error {
 	return nil
 }
 
-// IsEOLVersion returns true if the version is known but has been marked end-of-life.
+// Aliases returns the aliases defined in versions.yaml (e.g. v1.30-latest), in the order in which they are defined.
+func Aliases() []AliasInfo {
+	return slices.Clone(aliasList)
+}
+
+// IsEOLVersion returns true if the version is known but has been marked end-of-life, either in versions.yaml
+// or in the version catalog.
 func IsEOLVersion(version string) bool {
 	for _, eolVersion := range EOL {
 		if eolVersion == version {
 			return true
 		}
 	}
-	return false
+	return isCatalogEOL(version)
 }
 
 func init() {
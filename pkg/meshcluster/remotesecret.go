@@ -0,0 +1,175 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meshcluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+const (
+	// ReaderServiceAccountName is the name of the ServiceAccount in the peer cluster whose token is stored in the
+	// istio-remote-secret. It is created by the base and istiod charts and can read the services and endpoints.
+	ReaderServiceAccountName = "istio-reader-service-account"
+
+	// MultiClusterSecretLabelKey is the label istiod uses to find the remote secrets in its namespace
+	MultiClusterSecretLabelKey = "istio/multiCluster"
+
+	// ClusterAnnotationKey is the annotation that records the name of the cluster the remote secret gives access to
+	ClusterAnnotationKey = "networking.istio.io/cluster"
+
+	// TokenExpirationAnnotationKey records when the token in the remote secret expires
+	TokenExpirationAnnotationKey = constants.MetadataNamespace + "/token-expiration"
+
+	// KubeconfigVersionAnnotationKey records the resourceVersion of the kubeconfig Secret the remote secret was
+	// created from, so that the remote secret is recreated when the kubeconfig changes
+	KubeconfigVersionAnnotationKey = constants.MetadataNamespace + "/kubeconfig-version"
+
+	// TokenTTL is the requested lifetime of the tokens in the remote secrets. The tokens are renewed when less
+	// than a third of their lifetime remains.
+	TokenTTL = 24 * time.Hour
+
+	remoteSecretPrefix = "istio-remote-secret-"
+)
+
+// PeerClientFactory creates a clientset for a peer cluster
+type PeerClientFactory func(cfg *rest.Config) (kubernetes.Interface, error)
+
+// NewPeerClient is the PeerClientFactory that connects to the actual peer cluster
+func NewPeerClient(cfg *rest.Config) (kubernetes.Interface, error) {
+	return kubernetes.NewForConfig(cfg)
+}
+
+// RemoteSecretName returns the name of the istio-remote-secret for the given peer cluster
+func RemoteSecretName(peer string) string {
+	return remoteSecretPrefix + peer
+}
+
+// RESTConfigFromKubeconfig returns the client configuration for the current context of the given kubeconfig
+func RESTConfigFromKubeconfig(kubeconfig []byte) (*rest.Config, error) {
+	if len(kubeconfig) == 0 {
+		return nil, fmt.Errorf("kubeconfig is empty")
+	}
+	cfg, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+	return cfg, nil
+}
+
+// RESTConfigFromRemoteSecret returns the client configuration stored in the remote secret of the given peer
+func RESTConfigFromRemoteSecret(secret *corev1.Secret, peer string) (*rest.Config, error) {
+	return RESTConfigFromKubeconfig(secret.Data[peer])
+}
+
+// RequestToken requests a token for the istio-reader-service-account in the given namespace of the peer cluster
+func RequestToken(ctx context.Context, cs kubernetes.Interface, namespace string) (*authenticationv1.TokenRequestStatus, error) {
+	req := &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{
+			ExpirationSeconds: ptr.Of(int64(TokenTTL.Seconds())),
+		},
+	}
+	resp, err := cs.CoreV1().ServiceAccounts(namespace).CreateToken(ctx, ReaderServiceAccountName, req, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to request token for ServiceAccount %s/%s: %w", namespace, ReaderServiceAccountName, err)
+	}
+	if resp.Status.Token == "" {
+		return nil, fmt.Errorf("token request for ServiceAccount %s/%s returned no token", namespace, ReaderServiceAccountName)
+	}
+	return &resp.Status, nil
+}
+
+// BuildKubeconfig returns a kubeconfig that connects to the API server in cfg with the given token. It contains
+// a single context named after the peer cluster, as expected by istiod.
+func BuildKubeconfig(peer string, cfg *rest.Config, token string) ([]byte, error) {
+	kubeconfig := clientcmdapi.NewConfig()
+	kubeconfig.Clusters[peer] = &clientcmdapi.Cluster{
+		Server:                   cfg.Host,
+		CertificateAuthorityData: cfg.TLSClientConfig.CAData,
+		InsecureSkipTLSVerify:    cfg.TLSClientConfig.Insecure,
+		TLSServerName:            cfg.TLSClientConfig.ServerName,
+	}
+	kubeconfig.AuthInfos[peer] = &clientcmdapi.AuthInfo{Token: token}
+	kubeconfig.Contexts[peer] = &clientcmdapi.Context{Cluster: peer, AuthInfo: peer}
+	kubeconfig.CurrentContext = peer
+	return clientcmd.Write(*kubeconfig)
+}
+
+// NewRemoteSecret returns the istio-remote-secret that gives istiod in the given namespace access to the peer
+// cluster.
+func NewRemoteSecret(peer, namespace string, kubeconfig []byte, expiration time.Time, kubeconfigVersion string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      RemoteSecretName(peer),
+			Namespace: namespace,
+			Labels: map[string]string{
+				MultiClusterSecretLabelKey:  "true",
+				constants.ManagedByLabelKey: constants.ManagedByLabelValue,
+			},
+			Annotations: map[string]string{
+				ClusterAnnotationKey:           peer,
+				TokenExpirationAnnotationKey:   expiration.UTC().Format(time.RFC3339),
+				KubeconfigVersionAnnotationKey: kubeconfigVersion,
+			},
+		},
+		Data: map[string][]byte{
+			peer: kubeconfig,
+		},
+	}
+}
+
+// TokenExpiration returns the expiration time of the token in the given remote secret
+func TokenExpiration(secret *corev1.Secret) (time.Time, bool) {
+	value, found := secret.Annotations[TokenExpirationAnnotationKey]
+	if !found {
+		return time.Time{}, false
+	}
+	expiration, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return expiration, true
+}
+
+// NeedsRotation returns true if the remote secret doesn't exist, was created from a different version of the
+// kubeconfig Secret, or if less than a third of its token's lifetime remains.
+func NeedsRotation(secret *corev1.Secret, peer, kubeconfigVersion string, now time.Time) bool {
+	if secret == nil || len(secret.Data[peer]) == 0 || secret.Annotations[KubeconfigVersionAnnotationKey] != kubeconfigVersion {
+		return true
+	}
+	expiration, found := TokenExpiration(secret)
+	return !found || expiration.Sub(now) < TokenTTL/3
+}
+
+// RenewalTime returns the time at which the token in the given remote secret must be renewed
+func RenewalTime(secret *corev1.Secret) (time.Time, bool) {
+	expiration, found := TokenExpiration(secret)
+	if !found {
+		return time.Time{}, false
+	}
+	return expiration.Add(-TokenTTL / 3), true
+}
@@ -0,0 +1,130 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meshcluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestBuildKubeconfig(t *testing.T) {
+	cfg := &rest.Config{
+		Host:            "https://cluster2.example.com:6443",
+		TLSClientConfig: rest.TLSClientConfig{CAData: []byte("ca-data")},
+	}
+	kubeconfig, err := BuildKubeconfig("cluster2", cfg, "token")
+	require.NoError(t, err)
+
+	secret := NewRemoteSecret("cluster2", "istio-system", kubeconfig, time.Now().Add(TokenTTL), "1")
+	assert.Equal(t, "istio-remote-secret-cluster2", secret.Name)
+	assert.Equal(t, "true", secret.Labels[MultiClusterSecretLabelKey])
+	assert.Equal(t, "cluster2", secret.Annotations[ClusterAnnotationKey])
+
+	actual, err := RESTConfigFromRemoteSecret(secret, "cluster2")
+	require.NoError(t, err)
+	assert.Equal(t, cfg.Host, actual.Host)
+	assert.Equal(t, cfg.TLSClientConfig.CAData, actual.TLSClientConfig.CAData)
+	assert.Equal(t, "token", actual.BearerToken)
+}
+
+func TestRESTConfigFromKubeconfig(t *testing.T) {
+	_, err := RESTConfigFromKubeconfig(nil)
+	assert.ErrorContains(t, err, "kubeconfig is empty")
+
+	_, err = RESTConfigFromKubeconfig([]byte("not a kubeconfig"))
+	assert.ErrorContains(t, err, "invalid kubeconfig")
+}
+
+func TestRequestToken(t *testing.T) {
+	expiration := metav1.NewTime(time.Now().Add(TokenTTL))
+	cs := fake.NewClientset()
+	cs.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "token" {
+			return false, nil, nil
+		}
+		createAction := action.(k8stesting.CreateActionImpl)
+		assert.Equal(t, "istio-system", createAction.GetNamespace())
+		assert.Equal(t, ReaderServiceAccountName, createAction.Name)
+		req := createAction.GetObject().(*authenticationv1.TokenRequest)
+		assert.Equal(t, int64(TokenTTL.Seconds()), *req.Spec.ExpirationSeconds)
+		req.Status = authenticationv1.TokenRequestStatus{Token: "token", ExpirationTimestamp: expiration}
+		return true, req, nil
+	})
+
+	status, err := RequestToken(context.Background(), cs, "istio-system")
+	require.NoError(t, err)
+	assert.Equal(t, "token", status.Token)
+	assert.Equal(t, expiration, status.ExpirationTimestamp)
+}
+
+func TestNeedsRotation(t *testing.T) {
+	now := time.Now()
+	newSecret := func(expiration time.Time, version string) *corev1.Secret {
+		return NewRemoteSecret("cluster2", "istio-system", []byte("kubeconfig"), expiration, version)
+	}
+
+	tests := []struct {
+		name     string
+		secret   *corev1.Secret
+		expected bool
+	}{
+		{
+			name:     "secret doesn't exist",
+			secret:   nil,
+			expected: true,
+		},
+		{
+			name:     "fresh token",
+			secret:   newSecret(now.Add(TokenTTL), "1"),
+			expected: false,
+		},
+		{
+			name:     "token about to expire",
+			secret:   newSecret(now.Add(TokenTTL/4), "1"),
+			expected: true,
+		},
+		{
+			name:     "kubeconfig changed",
+			secret:   newSecret(now.Add(TokenTTL), "2"),
+			expected: true,
+		},
+		{
+			name: "missing expiration",
+			secret: func() *corev1.Secret {
+				s := newSecret(now.Add(TokenTTL), "1")
+				delete(s.Annotations, TokenExpirationAnnotationKey)
+				return s
+			}(),
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, NeedsRotation(tt.secret, "cluster2", "1", now))
+		})
+	}
+}
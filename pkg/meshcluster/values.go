@@ -0,0 +1,72 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meshcluster
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+// Name is the name of the MeshCluster, which is a singleton
+const Name = "default"
+
+// GetForIstio returns the MeshCluster if it targets the Istio object with the given name. It returns nil if the
+// MeshCluster doesn't exist, is being deleted, targets another Istio object, or if its CRD isn't installed.
+func GetForIstio(ctx context.Context, cl client.Client, istioName string) (*v1alpha1.MeshCluster, error) {
+	mc := &v1alpha1.MeshCluster{}
+	if err := cl.Get(ctx, types.NamespacedName{Name: Name}, mc); err != nil {
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get MeshCluster: %w", err)
+	}
+	if mc.DeletionTimestamp != nil || mc.Spec.TargetRef.Kind != v1.IstioKind || mc.Spec.TargetRef.Name != istioName {
+		return nil, nil
+	}
+	return mc, nil
+}
+
+// ApplyValues sets global.meshID, global.multiCluster.clusterName and global.network to the values in the
+// MeshCluster. Values that the user has set explicitly are left untouched, so that they always take precedence.
+func ApplyValues(values *v1.Values, mc *v1alpha1.MeshCluster) {
+	if values == nil || mc == nil {
+		return
+	}
+	if values.Global == nil {
+		values.Global = &v1.GlobalConfig{}
+	}
+	global := values.Global
+	if global.MeshID == nil {
+		global.MeshID = ptr.Of(mc.Spec.MeshID)
+	}
+	if global.MultiCluster == nil {
+		global.MultiCluster = &v1.MultiClusterConfig{}
+	}
+	if global.MultiCluster.ClusterName == nil {
+		global.MultiCluster.ClusterName = ptr.Of(mc.Spec.ClusterName)
+	}
+	if global.Network == nil && mc.Spec.Network != "" {
+		global.Network = ptr.Of(mc.Spec.Network)
+	}
+}
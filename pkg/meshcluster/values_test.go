@@ -0,0 +1,160 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package meshcluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestApplyValues(t *testing.T) {
+	mc := &v1alpha1.MeshCluster{
+		Spec: v1alpha1.MeshClusterSpec{
+			MeshID:      "mesh1",
+			ClusterName: "cluster1",
+			Network:     "network1",
+		},
+	}
+
+	tests := []struct {
+		name     string
+		values   *v1.Values
+		mc       *v1alpha1.MeshCluster
+		expected *v1.Values
+	}{
+		{
+			name:     "nil values",
+			values:   nil,
+			mc:       mc,
+			expected: nil,
+		},
+		{
+			name:     "no MeshCluster",
+			values:   &v1.Values{},
+			expected: &v1.Values{},
+		},
+		{
+			name:   "no global values",
+			values: &v1.Values{},
+			mc:     mc,
+			expected: &v1.Values{
+				Global: &v1.GlobalConfig{
+					MeshID:       ptr.Of("mesh1"),
+					MultiCluster: &v1.MultiClusterConfig{ClusterName: ptr.Of("cluster1")},
+					Network:      ptr.Of("network1"),
+				},
+			},
+		},
+		{
+			name:   "no network",
+			values: &v1.Values{},
+			mc: &v1alpha1.MeshCluster{
+				Spec: v1alpha1.MeshClusterSpec{MeshID: "mesh1", ClusterName: "cluster1"},
+			},
+			expected: &v1.Values{
+				Global: &v1.GlobalConfig{
+					MeshID:       ptr.Of("mesh1"),
+					MultiCluster: &v1.MultiClusterConfig{ClusterName: ptr.Of("cluster1")},
+				},
+			},
+		},
+		{
+			name: "user values are preserved",
+			values: &v1.Values{
+				Global: &v1.GlobalConfig{
+					MeshID:       ptr.Of("user-mesh"),
+					MultiCluster: &v1.MultiClusterConfig{Enabled: ptr.Of(true), ClusterName: ptr.Of("user-cluster")},
+					Network:      ptr.Of("user-network"),
+				},
+			},
+			mc: mc,
+			expected: &v1.Values{
+				Global: &v1.GlobalConfig{
+					MeshID:       ptr.Of("user-mesh"),
+					MultiCluster: &v1.MultiClusterConfig{Enabled: ptr.Of(true), ClusterName: ptr.Of("user-cluster")},
+					Network:      ptr.Of("user-network"),
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ApplyValues(tt.values, tt.mc)
+			if diff := cmp.Diff(tt.expected, tt.values); diff != "" {
+				t.Errorf("unexpected values; diff (-expected, +actual):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestGetForIstio(t *testing.T) {
+	newMeshCluster := func(targetName string) *v1alpha1.MeshCluster {
+		return &v1alpha1.MeshCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: Name},
+			Spec: v1alpha1.MeshClusterSpec{
+				TargetRef:   v1.TargetReference{Kind: v1.IstioKind, Name: targetName},
+				MeshID:      "mesh1",
+				ClusterName: "cluster1",
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		objects   []*v1alpha1.MeshCluster
+		istioName string
+		expected  bool
+	}{
+		{
+			name:      "no MeshCluster",
+			istioName: "default",
+		},
+		{
+			name:      "MeshCluster targets the Istio",
+			objects:   []*v1alpha1.MeshCluster{newMeshCluster("default")},
+			istioName: "default",
+			expected:  true,
+		},
+		{
+			name:      "MeshCluster targets another Istio",
+			objects:   []*v1alpha1.MeshCluster{newMeshCluster("other")},
+			istioName: "default",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+			for _, obj := range tt.objects {
+				b.WithObjects(obj)
+			}
+			mc, err := GetForIstio(context.Background(), b.Build(), tt.istioName)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, mc != nil)
+		})
+	}
+}
@@ -36,8 +36,10 @@ const (
 
 // CNIReconciler handles reconciliation of the istio-cni component.
 type CNIReconciler struct {
-	cfg    Config
-	client client.Client
+	cfg        Config
+	client     client.Client
+	instance   string
+	keptFields []helm.Drift
 }
 
 // NewCNIReconciler creates a new CNIReconciler.
@@ -48,6 +50,20 @@ func NewCNIReconciler(cfg Config, client client.Client) *CNIReconciler {
 	}
 }
 
+// ForInstance sets the name of the IstioCNI resource that the reconciler installs. The ClusterRoles and
+// ClusterRoleBindings of every instance except the default one are suffixed with its name.
+func (r *CNIReconciler) ForInstance(name string) *CNIReconciler {
+	r.instance = name
+	return r
+}
+
+// KeepingFields makes Install and Plan keep the live values of the drifted fields that the DriftReport
+// doesn't revert.
+func (r *CNIReconciler) KeepingFields(report *DriftReport) *CNIReconciler {
+	r.keptFields = report.keptFields()
+	return r
+}
+
 // Validate performs general validation of the CNI specification.
 // This includes basic field validation and Kubernetes API checks (namespace exists).
 func (r *CNIReconciler) Validate(ctx context.Context, version, namespace string) error {
@@ -96,7 +112,14 @@ func (r *CNIReconciler) ComputeValues(version string, userValues *v1.CNIValues,
 }
 
 // Install installs or upgrades the istio-cni Helm chart.
-func (r *CNIReconciler) Install(ctx context.Context, version, namespace string, values *v1.CNIValues, profile string, ownerRef *metav1.OwnerReference) error {
+func (r *CNIReconciler) Install(
+	ctx context.Context, version, namespace string, values *v1.CNIValues, profile string, patches []v1.Patch,
+	ownerRef *metav1.OwnerReference,
+) error {
+	if err := ValidatePatches(patches); err != nil {
+		return err
+	}
+
 	mergedHelmValues, err := r.ComputeValues(version, values, profile)
 	if err != nil {
 		return err
@@ -116,13 +139,79 @@ func (r *CNIReconciler) Install(ctx context.Context, version, namespace string,
 		namespace,
 		cniReleaseName,
 		ownerRef,
+		withKeptFields(instanceChartOptions(patches, r.instance, v1.DefaultIstioCNIName), r.keptFields)...,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to install/update Helm chart %q: %w", cniChartName, err)
+		return asPatchValidationError(fmt.Errorf("failed to install/update Helm chart %q: %w", cniChartName, err))
 	}
 	return nil
 }
 
+// Plan computes the changes that Install would make to the istio-cni Helm release, without changing
+// anything in the cluster. The ChartManager in the Config must implement helm.ChartPlanner.
+func (r *CNIReconciler) Plan(
+	ctx context.Context, version, namespace string, values *v1.CNIValues, profile string, patches []v1.Patch,
+	ownerRef *metav1.OwnerReference,
+) (helm.ReleaseDiff, error) {
+	if err := ValidatePatches(patches); err != nil {
+		return helm.ReleaseDiff{}, err
+	}
+
+	planner, ok := r.cfg.ChartManager.(helm.ChartPlanner)
+	if !ok {
+		return helm.ReleaseDiff{}, fmt.Errorf("chart manager %T doesn't support planning", r.cfg.ChartManager)
+	}
+
+	mergedHelmValues, err := r.ComputeValues(version, values, profile)
+	if err != nil {
+		return helm.ReleaseDiff{}, err
+	}
+
+	resolvedVersion, err := istioversion.Resolve(version)
+	if err != nil {
+		return helm.ReleaseDiff{}, fmt.Errorf("failed to resolve CNI version: %w", err)
+	}
+
+	chartPath := GetChartPath(resolvedVersion, cniChartName)
+	diff, err := planner.PlanChart(ctx, r.cfg.ResourceFS, chartPath, mergedHelmValues, namespace, cniReleaseName, ownerRef,
+		withKeptFields(instanceChartOptions(patches, r.instance, v1.DefaultIstioCNIName), r.keptFields)...)
+	if err != nil {
+		return helm.ReleaseDiff{}, asPatchValidationError(fmt.Errorf("failed to plan Helm chart %q: %w", cniChartName, err))
+	}
+	return diff, nil
+}
+
+// DetectDrift compares the objects deployed by the istio-cni Helm chart with the release manifest and
+// applies the given DriftPolicy. It returns nil if the ChartManager doesn't support drift detection.
+func (r *CNIReconciler) DetectDrift(ctx context.Context, namespace string, policy *v1.DriftPolicy) *DriftReport {
+	return detectDrift(ctx, r.cfg, r.client, []releaseRef{{namespace: namespace, name: cniReleaseName}}, policy)
+}
+
+// CheckReadiness evaluates the readiness of the objects deployed by the istio-cni Helm chart. It returns
+// nil if the ChartManager can't return the release manifest.
+func (r *CNIReconciler) CheckReadiness(ctx context.Context, namespace string) ([]v1.ResourceStatus, error) {
+	return checkReadiness(ctx, r.cfg, r.client, []releaseRef{{namespace: namespace, name: cniReleaseName}})
+}
+
+// ReleaseHistory returns the retained revisions of the istio-cni Helm release, newest first. It returns
+// nil if the ChartManager doesn't retain release history.
+func (r *CNIReconciler) ReleaseHistory(ctx context.Context, namespace string) ([]v1.HelmReleaseRevision, error) {
+	return releaseHistory(ctx, r.cfg, releaseRef{namespace: namespace, name: cniReleaseName})
+}
+
+// Rollback reinstalls the given revision of the istio-cni Helm release. It returns true if a rollback was
+// performed and false if the release had already been rolled back to that revision.
+func (r *CNIReconciler) Rollback(ctx context.Context, namespace string, revision int) (bool, error) {
+	return rollback(ctx, r.cfg, releaseRef{namespace: namespace, name: cniReleaseName}, revision)
+}
+
+// FieldConflicts returns the fields of the istio-cni Helm release that weren't applied during the last
+// install or upgrade, because other field managers own them. It returns nil if the ChartManager doesn't
+// use server-side apply.
+func (r *CNIReconciler) FieldConflicts(namespace string) *FieldConflictReport {
+	return fieldConflicts(r.cfg, []releaseRef{{namespace: namespace, name: cniReleaseName}})
+}
+
 // Uninstall removes the istio-cni Helm chart.
 func (r *CNIReconciler) Uninstall(ctx context.Context, namespace string) error {
 	_, err := r.cfg.ChartManager.UninstallChart(ctx, cniReleaseName, namespace)
@@ -0,0 +1,66 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+)
+
+const maxReportedFieldConflicts = 5
+
+// FieldConflictReport lists the fields of the objects deployed for a component that weren't applied during
+// the last install or upgrade, because other field managers own them.
+type FieldConflictReport struct {
+	Conflicts []helm.FieldConflict
+}
+
+// Message describes the skipped fields in a form that is suitable for a condition message.
+func (r *FieldConflictReport) Message() string {
+	var fields []string
+	for i, conflict := range r.Conflicts {
+		if i == maxReportedFieldConflicts {
+			fields = append(fields, fmt.Sprintf("and %d more fields", len(r.Conflicts)-i))
+			break
+		}
+		name := conflict.Object.Name
+		if conflict.Object.Namespace != "" {
+			name = conflict.Object.Namespace + "/" + name
+		}
+		fields = append(fields, fmt.Sprintf("%s %s %s (owned by %s)", conflict.Object.Kind, name, conflict.Field, conflict.Manager))
+	}
+	return strings.Join(fields, "; ")
+}
+
+// fieldConflicts returns the fields that were skipped during the last install or upgrade of the given Helm
+// releases. It returns nil if the ChartManager doesn't apply the releases with server-side apply.
+func fieldConflicts(cfg Config, releases []releaseRef) *FieldConflictReport {
+	reporter, ok := cfg.ChartManager.(helm.ConflictReporter)
+	if !ok {
+		return nil
+	}
+
+	report := &FieldConflictReport{}
+	for _, rel := range releases {
+		conflicts, enabled := reporter.FieldConflicts(rel.namespace, rel.name)
+		if !enabled {
+			return nil
+		}
+		report.Conflicts = append(report.Conflicts, conflicts...)
+	}
+	return report
+}
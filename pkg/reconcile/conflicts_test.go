@@ -0,0 +1,91 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v4/pkg/release"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// conflictChartManager is a helm.ChartReconciler that also implements helm.ConflictReporter.
+type conflictChartManager struct {
+	serverSideApply bool
+	conflicts       map[string][]helm.FieldConflict
+}
+
+func (m *conflictChartManager) UpgradeOrInstallChart(context.Context, fs.FS, string, helm.Values, string, string,
+	*metav1.OwnerReference, ...helm.ChartOption,
+) (release.Releaser, error) {
+	return nil, nil
+}
+
+func (m *conflictChartManager) UninstallChart(context.Context, string, string) (*release.UninstallReleaseResponse, error) {
+	return nil, nil
+}
+
+func (m *conflictChartManager) FieldConflicts(namespace, releaseName string) ([]helm.FieldConflict, bool) {
+	return m.conflicts[namespace+"/"+releaseName], m.serverSideApply
+}
+
+func TestIstiodReconciler_FieldConflicts(t *testing.T) {
+	istiod := helm.ObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "istio-system", Name: "istiod"}
+	webhook := helm.ObjectReference{
+		APIVersion: "admissionregistration.k8s.io/v1", Kind: "ValidatingWebhookConfiguration", Name: "istiod-default-validator",
+	}
+	cm := &conflictChartManager{
+		serverSideApply: true,
+		conflicts: map[string][]helm.FieldConflict{
+			"istio-system/default-istiod": {{Object: istiod, Field: "spec.replicas", Manager: "kube-controller-manager"}},
+			"sail-operator/default-base":  {{Object: webhook, Field: "webhooks[0].failurePolicy", Manager: "pilot-discovery"}},
+		},
+	}
+	r := NewIstiodReconciler(Config{ChartManager: cm, OperatorNamespace: "sail-operator"}, nil)
+
+	report := r.FieldConflicts("istio-system", v1.DefaultRevision)
+	assert.Len(t, report.Conflicts, 2)
+	assert.Equal(t, "Deployment istio-system/istiod spec.replicas (owned by kube-controller-manager); "+
+		"ValidatingWebhookConfiguration istiod-default-validator webhooks[0].failurePolicy (owned by pilot-discovery)", report.Message())
+
+	report = r.FieldConflicts("istio-system", "canary")
+	assert.NotNil(t, report)
+	assert.Empty(t, report.Conflicts)
+}
+
+func TestFieldConflictsWithoutServerSideApply(t *testing.T) {
+	r := NewCNIReconciler(Config{ChartManager: &conflictChartManager{}}, nil)
+	assert.Nil(t, r.FieldConflicts("istio-cni"))
+
+	r = NewCNIReconciler(Config{ChartManager: &historyChartManager{}}, nil)
+	assert.Nil(t, r.FieldConflicts("istio-cni"))
+}
+
+func TestFieldConflictReportMessage(t *testing.T) {
+	report := &FieldConflictReport{}
+	for range maxReportedFieldConflicts + 2 {
+		report.Conflicts = append(report.Conflicts, helm.FieldConflict{
+			Object:  helm.ObjectReference{Kind: "ClusterRole", Name: "istio-cni"},
+			Field:   "metadata.labels.app",
+			Manager: "gitops",
+		})
+	}
+	assert.Contains(t, report.Message(), "; and 2 more fields")
+}
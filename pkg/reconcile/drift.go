@@ -0,0 +1,244 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	maxReportedDriftObjects = 5
+	maxReportedDriftFields  = 5
+)
+
+// defaultDriftRules ignore the fields that istiod itself updates on the objects deployed by the charts.
+// They are evaluated after the rules in the DriftPolicy, so that users can override them.
+var defaultDriftRules = []v1.DriftRule{
+	{Kind: "MutatingWebhookConfiguration", Path: "webhooks.clientConfig.caBundle", Action: v1.DriftActionIgnore},
+	{Kind: "ValidatingWebhookConfiguration", Path: "webhooks.clientConfig.caBundle", Action: v1.DriftActionIgnore},
+	{Kind: "ValidatingWebhookConfiguration", Path: "webhooks.failurePolicy", Action: v1.DriftActionIgnore},
+}
+
+var listIndexRegex = regexp.MustCompile(`\[\d+\]`)
+
+// DriftReport is the result of comparing the objects deployed for a component with the manifests of its
+// Helm releases, after the DriftPolicy was applied.
+type DriftReport struct {
+	// Drifts contains the drifted objects and fields whose action is either Revert or Report.
+	Drifts []helm.Drift
+
+	// Revert is true if at least one drifted field must be reverted.
+	Revert bool
+
+	// Keep is true if at least one drifted field must not be reverted, because its action is either
+	// Report or Ignore.
+	Keep bool
+
+	// Kept contains the drifted fields whose action is either Report or Ignore, along with their live
+	// values. The reconcilers keep them when they upgrade the Helm charts (see helm.WithKeptFields).
+	Kept []helm.Drift
+
+	// Reverted is set by ChartsUpgraded if the upgrade of the Helm charts reverted drifted fields.
+	Reverted bool
+
+	// Err is set if the objects couldn't be compared with the manifests.
+	Err error
+}
+
+// UpgradeAllowed returns true if the Helm charts of the component may be upgraded even if their releases
+// wouldn't change. This is the case unless drifted fields were found that must not be reverted. The upgrade
+// would keep them, but it would also create a new release revision every time the drift is detected.
+func (r *DriftReport) UpgradeAllowed() bool {
+	return r == nil || r.Err != nil || r.Revert || !r.Keep
+}
+
+// ChartsUpgraded updates the report after the Helm charts were upgraded. The upgrade reverts the drifted
+// fields whose action is Revert and recreates deleted objects, but keeps all other fields, so only the
+// reverted drift remains in Drifts.
+func (r *DriftReport) ChartsUpgraded() {
+	if r == nil {
+		return
+	}
+	var reverted []helm.Drift
+	for _, drift := range r.Drifts {
+		kept := slices.IndexFunc(r.Kept, func(k helm.Drift) bool { return k.Object == drift.Object })
+		if kept >= 0 {
+			drift.Fields = slices.DeleteFunc(slices.Clone(drift.Fields), func(field string) bool {
+				return slices.Contains(r.Kept[kept].Fields, field)
+			})
+		}
+		if drift.Missing || len(drift.Fields) > 0 {
+			reverted = append(reverted, drift)
+		}
+	}
+	if len(reverted) > 0 {
+		r.Drifts = reverted
+		r.Reverted = true
+	}
+}
+
+// Message describes the drifted objects and fields in a form that is suitable for a condition message.
+func (r *DriftReport) Message() string {
+	var objects []string
+	for i, drift := range r.Drifts {
+		if i == maxReportedDriftObjects {
+			objects = append(objects, fmt.Sprintf("and %d more objects", len(r.Drifts)-i))
+			break
+		}
+		name := drift.Object.Name
+		if drift.Object.Namespace != "" {
+			name = drift.Object.Namespace + "/" + name
+		}
+		details := "deleted"
+		if !drift.Missing {
+			fields := drift.Fields
+			if len(fields) > maxReportedDriftFields {
+				fields = append(fields[:maxReportedDriftFields:maxReportedDriftFields],
+					fmt.Sprintf("and %d more", len(drift.Fields)-maxReportedDriftFields))
+			}
+			details = strings.Join(fields, ", ")
+		}
+		objects = append(objects, fmt.Sprintf("%s %s (%s)", drift.Object.Kind, name, details))
+	}
+	return strings.Join(objects, "; ")
+}
+
+// keptFields returns the drifted fields that must be kept when the Helm charts are upgraded.
+func (r *DriftReport) keptFields() []helm.Drift {
+	if r == nil {
+		return nil
+	}
+	return r.Kept
+}
+
+// withKeptFields appends the chart option that keeps the given drifted fields to the options.
+func withKeptFields(opts []helm.ChartOption, kept []helm.Drift) []helm.ChartOption {
+	if len(kept) == 0 {
+		return opts
+	}
+	return append(opts, helm.WithKeptFields(kept...))
+}
+
+type releaseRef struct {
+	namespace string
+	name      string
+}
+
+// detectDrift compares the objects of the given Helm releases with the live objects and applies the
+// DriftPolicy. It returns nil if the ChartManager doesn't implement helm.DriftDetector.
+func detectDrift(ctx context.Context, cfg Config, cl client.Client, releases []releaseRef, policy *v1.DriftPolicy) *DriftReport {
+	detector, ok := cfg.ChartManager.(helm.DriftDetector)
+	if !ok {
+		return nil
+	}
+
+	var drifts []helm.Drift
+	for _, rel := range releases {
+		releaseDrifts, err := detector.DetectDrift(ctx, cl, rel.namespace, rel.name)
+		if err != nil {
+			return &DriftReport{Err: fmt.Errorf("failed to detect drift in Helm release %q: %w", rel.name, err)}
+		}
+		drifts = append(drifts, releaseDrifts...)
+	}
+	return applyDriftPolicy(drifts, policy)
+}
+
+// applyDriftPolicy determines the action for each drifted object and field. The first rule that matches
+// wins; if no rule matches, the policy's default action is used.
+func applyDriftPolicy(drifts []helm.Drift, policy *v1.DriftPolicy) *DriftReport {
+	defaultAction := v1.DriftActionRevert
+	var rules []v1.DriftRule
+	if policy != nil {
+		if policy.Action != "" {
+			defaultAction = policy.Action
+		}
+		rules = policy.Rules
+	}
+	rules = append(rules[:len(rules):len(rules)], defaultDriftRules...)
+
+	actionFor := func(drift helm.Drift, path string) v1.DriftAction {
+		for _, rule := range rules {
+			if driftRuleMatches(rule, drift.Object, path) {
+				return rule.Action
+			}
+		}
+		return defaultAction
+	}
+
+	report := &DriftReport{}
+	record := func(action v1.DriftAction) bool {
+		switch action {
+		case v1.DriftActionIgnore:
+			report.Keep = true
+			return false
+		case v1.DriftActionReport:
+			report.Keep = true
+		default:
+			report.Revert = true
+		}
+		return true
+	}
+
+	for _, drift := range drifts {
+		if drift.Missing {
+			if record(actionFor(drift, "")) {
+				report.Drifts = append(report.Drifts, drift)
+			}
+			continue
+		}
+
+		var fields, keptFields []string
+		for _, field := range drift.Fields {
+			action := actionFor(drift, field)
+			if record(action) {
+				fields = append(fields, field)
+			}
+			if action == v1.DriftActionReport || action == v1.DriftActionIgnore {
+				keptFields = append(keptFields, field)
+			}
+		}
+		if len(fields) > 0 {
+			report.Drifts = append(report.Drifts, helm.Drift{Object: drift.Object, Fields: fields})
+		}
+		if len(keptFields) > 0 {
+			report.Kept = append(report.Kept, helm.Drift{Object: drift.Object, Fields: keptFields, Values: drift.Values})
+		}
+	}
+	return report
+}
+
+// driftRuleMatches returns true if the rule matches the given field of the object. An empty field path
+// stands for the whole object, which is only matched by rules without a path.
+func driftRuleMatches(rule v1.DriftRule, obj helm.ObjectReference, field string) bool {
+	if rule.Kind != "" && rule.Kind != obj.Kind {
+		return false
+	}
+	if rule.Name != "" && rule.Name != obj.Name {
+		return false
+	}
+	if rule.Path == "" {
+		return true
+	}
+	path := listIndexRegex.ReplaceAllString(field, "")
+	return path == rule.Path || strings.HasPrefix(path, rule.Path+".")
+}
@@ -0,0 +1,225 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"errors"
+	"testing"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyDriftPolicy(t *testing.T) {
+	deployment := helm.ObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Namespace: "istio-system", Name: "istiod"}
+	webhook := helm.ObjectReference{APIVersion: "admissionregistration.k8s.io/v1", Kind: "ValidatingWebhookConfiguration", Name: "istiod"}
+	service := helm.ObjectReference{APIVersion: "v1", Kind: "Service", Namespace: "istio-system", Name: "istiod"}
+
+	deploymentValues := map[string]helm.FieldValue{
+		"spec.replicas":                          {Keys: []any{"spec", "replicas"}, Value: float64(3)},
+		"spec.template.spec.containers[0].image": {Keys: []any{"spec", "template", "spec", "containers", 0, "image"}, Value: "debug"},
+	}
+	webhookFields := []string{"webhooks[0].clientConfig.caBundle", "webhooks[0].failurePolicy"}
+
+	drifts := []helm.Drift{
+		{Object: deployment, Fields: []string{"spec.replicas", "spec.template.spec.containers[0].image"}, Values: deploymentValues},
+		{Object: webhook, Fields: []string{"webhooks[0].clientConfig.caBundle", "webhooks[0].failurePolicy"}},
+		{Object: service, Missing: true},
+	}
+
+	tests := []struct {
+		name     string
+		policy   *v1.DriftPolicy
+		expected *DriftReport
+	}{
+		{
+			name:   "nil policy reverts everything except fields managed by istiod",
+			policy: nil,
+			expected: &DriftReport{
+				Drifts: []helm.Drift{
+					{Object: deployment, Fields: []string{"spec.replicas", "spec.template.spec.containers[0].image"}},
+					{Object: service, Missing: true},
+				},
+				Kept: []helm.Drift{
+					{Object: webhook, Fields: webhookFields},
+				},
+				Revert: true,
+				Keep:   true,
+			},
+		},
+		{
+			name:   "report",
+			policy: &v1.DriftPolicy{Action: v1.DriftActionReport},
+			expected: &DriftReport{
+				Drifts: []helm.Drift{
+					{Object: deployment, Fields: []string{"spec.replicas", "spec.template.spec.containers[0].image"}},
+					{Object: service, Missing: true},
+				},
+				Kept: []helm.Drift{
+					{Object: deployment, Fields: []string{"spec.replicas", "spec.template.spec.containers[0].image"}, Values: deploymentValues},
+					{Object: webhook, Fields: webhookFields},
+				},
+				Keep: true,
+			},
+		},
+		{
+			name: "rules",
+			policy: &v1.DriftPolicy{
+				Action: v1.DriftActionReport,
+				Rules: []v1.DriftRule{
+					{Kind: "Deployment", Path: "spec.replicas", Action: v1.DriftActionIgnore},
+					{Kind: "Deployment", Name: "istiod", Path: "spec.template.spec.containers", Action: v1.DriftActionRevert},
+					{Kind: "Service", Action: v1.DriftActionIgnore},
+				},
+			},
+			expected: &DriftReport{
+				Drifts: []helm.Drift{
+					{Object: deployment, Fields: []string{"spec.template.spec.containers[0].image"}},
+				},
+				Kept: []helm.Drift{
+					{Object: deployment, Fields: []string{"spec.replicas"}, Values: deploymentValues},
+					{Object: webhook, Fields: webhookFields},
+				},
+				Revert: true,
+				Keep:   true,
+			},
+		},
+		{
+			name: "user rules override default rules",
+			policy: &v1.DriftPolicy{
+				Action: v1.DriftActionIgnore,
+				Rules: []v1.DriftRule{
+					{Kind: "ValidatingWebhookConfiguration", Path: "webhooks.failurePolicy", Action: v1.DriftActionReport},
+				},
+			},
+			expected: &DriftReport{
+				Drifts: []helm.Drift{
+					{Object: webhook, Fields: []string{"webhooks[0].failurePolicy"}},
+				},
+				Kept: []helm.Drift{
+					{Object: deployment, Fields: []string{"spec.replicas", "spec.template.spec.containers[0].image"}, Values: deploymentValues},
+					{Object: webhook, Fields: webhookFields},
+				},
+				Keep: true,
+			},
+		},
+		{
+			name: "path prefix must match whole segments",
+			policy: &v1.DriftPolicy{
+				Rules: []v1.DriftRule{
+					{Path: "spec.rep", Action: v1.DriftActionIgnore},
+					{Path: "spec", Action: v1.DriftActionReport},
+					{Action: v1.DriftActionIgnore},
+				},
+			},
+			expected: &DriftReport{
+				Drifts: []helm.Drift{
+					{Object: deployment, Fields: []string{"spec.replicas", "spec.template.spec.containers[0].image"}},
+				},
+				Kept: []helm.Drift{
+					{Object: deployment, Fields: []string{"spec.replicas", "spec.template.spec.containers[0].image"}, Values: deploymentValues},
+					{Object: webhook, Fields: webhookFields},
+				},
+				Keep: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, applyDriftPolicy(drifts, tt.policy))
+		})
+	}
+}
+
+func TestDriftReportUpgradeAllowed(t *testing.T) {
+	tests := []struct {
+		name     string
+		report   *DriftReport
+		expected bool
+	}{
+		{name: "nil report", report: nil, expected: true},
+		{name: "no drift", report: &DriftReport{}, expected: true},
+		{name: "drift check failed", report: &DriftReport{Keep: true, Err: errors.New("boom")}, expected: true},
+		{name: "revert", report: &DriftReport{Revert: true, Keep: true}, expected: true},
+		{name: "keep", report: &DriftReport{Keep: true}, expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.report.UpgradeAllowed())
+		})
+	}
+}
+
+func TestDriftReportChartsUpgraded(t *testing.T) {
+	deployment := helm.ObjectReference{Kind: "Deployment", Namespace: "istio-system", Name: "istiod"}
+	service := helm.ObjectReference{Kind: "Service", Namespace: "istio-system", Name: "istiod"}
+
+	t.Run("reverted fields remain", func(t *testing.T) {
+		report := &DriftReport{
+			Drifts: []helm.Drift{
+				{Object: deployment, Fields: []string{"spec.replicas", "spec.template.spec.containers[0].image"}},
+				{Object: service, Missing: true},
+			},
+			Kept:   []helm.Drift{{Object: deployment, Fields: []string{"spec.replicas"}}},
+			Revert: true,
+			Keep:   true,
+		}
+		report.ChartsUpgraded()
+		assert.True(t, report.Reverted)
+		assert.Equal(t, []helm.Drift{
+			{Object: deployment, Fields: []string{"spec.template.spec.containers[0].image"}},
+			{Object: service, Missing: true},
+		}, report.Drifts)
+	})
+
+	t.Run("kept fields aren't reverted", func(t *testing.T) {
+		drifts := []helm.Drift{{Object: deployment, Fields: []string{"spec.replicas"}}}
+		report := &DriftReport{Drifts: drifts, Kept: drifts, Keep: true}
+		report.ChartsUpgraded()
+		assert.False(t, report.Reverted)
+		assert.Equal(t, drifts, report.Drifts)
+	})
+
+	t.Run("nil report", func(t *testing.T) {
+		var report *DriftReport
+		report.ChartsUpgraded()
+	})
+}
+
+func TestDriftReportMessage(t *testing.T) {
+	report := &DriftReport{
+		Drifts: []helm.Drift{
+			{
+				Object: helm.ObjectReference{Kind: "Deployment", Namespace: "istio-system", Name: "istiod"},
+				Fields: []string{"a", "b", "c", "d", "e", "f", "g"},
+			},
+			{Object: helm.ObjectReference{Kind: "ClusterRole", Name: "istiod"}, Missing: true},
+			{Object: helm.ObjectReference{Kind: "ConfigMap", Namespace: "istio-system", Name: "istio"}, Fields: []string{"data.mesh"}},
+			{Object: helm.ObjectReference{Kind: "ConfigMap", Namespace: "istio-system", Name: "cm1"}, Fields: []string{"data"}},
+			{Object: helm.ObjectReference{Kind: "ConfigMap", Namespace: "istio-system", Name: "cm2"}, Fields: []string{"data"}},
+			{Object: helm.ObjectReference{Kind: "ConfigMap", Namespace: "istio-system", Name: "cm3"}, Fields: []string{"data"}},
+			{Object: helm.ObjectReference{Kind: "ConfigMap", Namespace: "istio-system", Name: "cm4"}, Fields: []string{"data"}},
+		},
+	}
+
+	assert.Equal(t,
+		"Deployment istio-system/istiod (a, b, c, d, e, and 2 more); ClusterRole istiod (deleted); "+
+			"ConfigMap istio-system/istio (data.mesh); ConfigMap istio-system/cm1 (data); ConfigMap istio-system/cm2 (data); "+
+			"and 2 more objects",
+		report.Message())
+	assert.Len(t, report.Drifts[0].Fields, 7, "Message() must not modify the report")
+}
@@ -0,0 +1,160 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/istio-ecosystem/sail-operator/pkg/istiovalues"
+	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	"github.com/istio-ecosystem/sail-operator/pkg/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	gatewayChartName = "gateway"
+)
+
+// GatewayReconciler handles reconciliation of gateways deployed from the gateway chart. Each gateway is
+// installed as a separate Helm release, whose name is also the name of the gateway Deployment.
+type GatewayReconciler struct {
+	cfg    Config
+	client client.Client
+}
+
+// NewGatewayReconciler creates a new GatewayReconciler.
+func NewGatewayReconciler(cfg Config, client client.Client) *GatewayReconciler {
+	return &GatewayReconciler{
+		cfg:    cfg,
+		client: client,
+	}
+}
+
+// Validate performs general validation of the gateway specification.
+// This includes basic field validation and Kubernetes API checks (namespace exists).
+func (r *GatewayReconciler) Validate(ctx context.Context, version, namespace string) error {
+	if version == "" {
+		return reconciler.NewValidationError("version not set")
+	}
+	if namespace == "" {
+		return reconciler.NewValidationError("namespace not set")
+	}
+
+	// Validate target namespace exists
+	if err := validation.ValidateTargetNamespace(ctx, r.client, namespace); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ComputeValues computes the final Helm values of the gateway chart. If baseValues are provided (e.g. those
+// derived from the referenced IstioRevision), user values are applied on top of them, so they always take precedence.
+func (r *GatewayReconciler) ComputeValues(userValues *v1alpha1.GatewayValues, baseValues ...helm.Values) (helm.Values, error) {
+	if userValues == nil {
+		userValues = &v1alpha1.GatewayValues{}
+	}
+
+	values := helm.Values{}
+	if len(baseValues) > 0 && baseValues[0] != nil {
+		values = baseValues[0]
+	}
+
+	finalHelmValues, err := istiovalues.ApplyUserValues(values, helm.FromValues(userValues))
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply user values: %w", err)
+	}
+	return finalHelmValues, nil
+}
+
+// GatewayRevisionValues returns the gateway values derived from the values of the control plane revision that
+// injects the gateway. The pods are labeled with the revision and annotated with its version, so that they are
+// restarted and receive the new proxy when the control plane is upgraded.
+func GatewayRevisionValues(version string, revisionValues *v1.Values) helm.Values {
+	values := helm.Values{
+		"podAnnotations": map[string]any{constants.IstioVersionKey: version},
+	}
+	if revisionValues == nil {
+		return values
+	}
+	if revisionValues.Revision != nil {
+		values["revision"] = *revisionValues.Revision
+	}
+	if revisionValues.Global != nil && revisionValues.Global.NetworkPolicy != nil {
+		values["global"] = helm.FromValues(v1.GlobalConfig{NetworkPolicy: revisionValues.Global.NetworkPolicy})
+	}
+	return values
+}
+
+// Install installs or upgrades the gateway Helm chart as the release with the given name.
+func (r *GatewayReconciler) Install(
+	ctx context.Context, version, namespace, releaseName string, values *v1alpha1.GatewayValues, patches []v1.Patch,
+	ownerRef *metav1.OwnerReference, baseValues ...helm.Values,
+) error {
+	if err := ValidatePatches(patches); err != nil {
+		return err
+	}
+
+	resolvedVersion, err := istioversion.Resolve(version)
+	if err != nil {
+		if istioversion.IsEOLVersion(version) {
+			return reconciler.NewValidationError(fmt.Sprintf("version %q is end-of-life and cannot be installed; use a supported version", version))
+		}
+		return fmt.Errorf("failed to resolve gateway version: %w", err)
+	}
+
+	finalHelmValues, err := r.ComputeValues(values, baseValues...)
+	if err != nil {
+		return err
+	}
+
+	chartPath := GetChartPath(resolvedVersion, gatewayChartName)
+	_, err = r.cfg.ChartManager.UpgradeOrInstallChart(
+		ctx,
+		r.cfg.ResourceFS,
+		chartPath,
+		finalHelmValues,
+		namespace,
+		releaseName,
+		ownerRef,
+		chartOptions(patches)...,
+	)
+	if err != nil {
+		return asPatchValidationError(fmt.Errorf("failed to install/update Helm chart %q: %w", gatewayChartName, err))
+	}
+	return nil
+}
+
+// CheckReadiness evaluates the readiness of the objects deployed by the gateway Helm release. It returns
+// nil if the ChartManager can't return the release manifest.
+func (r *GatewayReconciler) CheckReadiness(ctx context.Context, namespace, releaseName string) ([]v1.ResourceStatus, error) {
+	return checkReadiness(ctx, r.cfg, r.client, []releaseRef{{namespace: namespace, name: releaseName}})
+}
+
+// Uninstall removes the gateway Helm release.
+func (r *GatewayReconciler) Uninstall(ctx context.Context, namespace, releaseName string) error {
+	_, err := r.cfg.ChartManager.UninstallChart(ctx, releaseName, namespace)
+	if err != nil {
+		return fmt.Errorf("failed to uninstall Helm chart %q: %w", gatewayChartName, err)
+	}
+	return nil
+}
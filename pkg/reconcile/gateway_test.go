@@ -0,0 +1,107 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"testing"
+
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestGatewayReconciler_Validate(t *testing.T) {
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "istio-ingress",
+		},
+	}
+
+	tests := []struct {
+		name        string
+		version     string
+		nsExists    bool
+		errContains string
+	}{
+		{
+			name:        "missing version",
+			nsExists:    true,
+			errContains: "version not set",
+		},
+		{
+			name:        "namespace not found",
+			version:     "v1.24.0",
+			errContains: `namespace "istio-ingress" doesn't exist`,
+		},
+		{
+			name:     "valid",
+			version:  "v1.24.0",
+			nsExists: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientBuilder := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+			if tt.nsExists {
+				clientBuilder = clientBuilder.WithObjects(ns)
+			}
+
+			r := NewGatewayReconciler(Config{}, clientBuilder.Build())
+			err := r.Validate(context.Background(), tt.version, "istio-ingress")
+
+			if tt.errContains != "" {
+				assert.Error(t, err)
+				assert.True(t, reconciler.IsValidationError(err), "expected validation error")
+				assert.Contains(t, err.Error(), tt.errContains)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGatewayReconciler_ComputeValues(t *testing.T) {
+	r := NewGatewayReconciler(Config{}, nil)
+
+	values, err := r.ComputeValues(&v1alpha1.GatewayValues{
+		ReplicaCount:   ptr.Of(int32(2)),
+		PodAnnotations: map[string]string{"example.com/team": "web"},
+	}, helm.Values{
+		"revision":       "canary",
+		"podAnnotations": map[string]any{"sailoperator.io/istio-version": "v1.30.3"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, helm.Values{
+		"revision":     "canary",
+		"replicaCount": float64(2),
+		"podAnnotations": map[string]any{
+			"sailoperator.io/istio-version": "v1.30.3",
+			"example.com/team":              "web",
+		},
+	}, values)
+
+	values, err = r.ComputeValues(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, values)
+}
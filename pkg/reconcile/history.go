@@ -0,0 +1,84 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RollbackRevision returns the Helm release revision that the sailoperator.io/rollback-to annotation on
+// the given object points to. It returns 0 if the annotation isn't set, and a ValidationError if the value
+// isn't a positive integer.
+func RollbackRevision(obj client.Object) (int, error) {
+	value, found := obj.GetAnnotations()[constants.RollbackAnnotationKey]
+	if !found {
+		return 0, nil
+	}
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision <= 0 {
+		return 0, reconciler.NewValidationError(
+			fmt.Sprintf("annotation %s must be set to a positive revision number, got %q", constants.RollbackAnnotationKey, value))
+	}
+	return revision, nil
+}
+
+// releaseHistory returns the retained revisions of the given Helm release. It returns nil if the
+// ChartManager doesn't implement helm.ReleaseHistoryManager.
+func releaseHistory(ctx context.Context, cfg Config, rel releaseRef) ([]v1.HelmReleaseRevision, error) {
+	manager, ok := cfg.ChartManager.(helm.ReleaseHistoryManager)
+	if !ok {
+		return nil, nil
+	}
+
+	revisions, err := manager.ReleaseHistory(ctx, rel.namespace, rel.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history of Helm release %q: %w", rel.name, err)
+	}
+	var history []v1.HelmReleaseRevision
+	for _, revision := range revisions {
+		history = append(history, v1.HelmReleaseRevision{
+			Revision:     int32(revision.Revision),
+			ChartVersion: revision.ChartVersion,
+			Updated:      metav1.NewTime(revision.Updated),
+			Status:       revision.Status,
+			Description:  revision.Description,
+		})
+	}
+	return history, nil
+}
+
+// rollback rolls the given Helm release back to the given revision, unless that was already done. It
+// returns true if a rollback was performed.
+func rollback(ctx context.Context, cfg Config, rel releaseRef, revision int) (bool, error) {
+	manager, ok := cfg.ChartManager.(helm.ReleaseHistoryManager)
+	if !ok {
+		return false, fmt.Errorf("chart manager %T doesn't support rollbacks", cfg.ChartManager)
+	}
+
+	rolledBack, err := manager.RollbackRelease(ctx, rel.namespace, rel.name, revision)
+	if err != nil {
+		return false, fmt.Errorf("failed to roll back Helm release %q: %w", rel.name, err)
+	}
+	return rolledBack, nil
+}
@@ -0,0 +1,131 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+	"time"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v4/pkg/release"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRollbackRevision(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		expected    int
+		wantErr     bool
+	}{
+		{
+			name:     "no annotation",
+			expected: 0,
+		},
+		{
+			name:        "valid revision",
+			annotations: map[string]string{constants.RollbackAnnotationKey: "3"},
+			expected:    3,
+		},
+		{
+			name:        "zero",
+			annotations: map[string]string{constants.RollbackAnnotationKey: "0"},
+			wantErr:     true,
+		},
+		{
+			name:        "not a number",
+			annotations: map[string]string{constants.RollbackAnnotationKey: "previous"},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rev := &v1.IstioRevision{ObjectMeta: metav1.ObjectMeta{Name: "default", Annotations: tt.annotations}}
+			revision, err := RollbackRevision(rev)
+			if tt.wantErr {
+				assert.True(t, reconciler.IsValidationError(err), "expected a validation error, got %v", err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, revision)
+		})
+	}
+}
+
+// historyChartManager is a helm.ChartReconciler that also implements helm.ReleaseHistoryManager.
+type historyChartManager struct {
+	history    []helm.ReleaseRevision
+	rolledBack []string
+}
+
+func (m *historyChartManager) UpgradeOrInstallChart(context.Context, fs.FS, string, helm.Values, string, string,
+	*metav1.OwnerReference, ...helm.ChartOption,
+) (release.Releaser, error) {
+	return nil, nil
+}
+
+func (m *historyChartManager) UninstallChart(context.Context, string, string) (*release.UninstallReleaseResponse, error) {
+	return nil, nil
+}
+
+func (m *historyChartManager) ReleaseHistory(_ context.Context, _, _ string) ([]helm.ReleaseRevision, error) {
+	return m.history, nil
+}
+
+func (m *historyChartManager) RollbackRelease(_ context.Context, namespace, releaseName string, _ int) (bool, error) {
+	m.rolledBack = append(m.rolledBack, namespace+"/"+releaseName)
+	return true, nil
+}
+
+func TestIstiodReconciler_ReleaseHistory(t *testing.T) {
+	updated := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	cm := &historyChartManager{
+		history: []helm.ReleaseRevision{
+			{Revision: 2, ChartVersion: "1.27.1", Updated: updated, Status: "deployed", Description: "Upgrade complete"},
+			{Revision: 1, ChartVersion: "1.27.0", Updated: updated.Add(-time.Hour), Status: "superseded", Description: "Install complete"},
+		},
+	}
+	r := NewIstiodReconciler(Config{ChartManager: cm, OperatorNamespace: "sail-operator"}, nil)
+
+	history, err := r.ReleaseHistory(context.Background(), "istio-system", v1.DefaultRevision)
+	assert.NoError(t, err)
+	assert.Equal(t, []v1.HelmReleaseRevision{
+		{Revision: 2, ChartVersion: "1.27.1", Updated: metav1.NewTime(updated), Status: "deployed", Description: "Upgrade complete"},
+		{Revision: 1, ChartVersion: "1.27.0", Updated: metav1.NewTime(updated.Add(-time.Hour)), Status: "superseded", Description: "Install complete"},
+	}, history)
+
+	rolledBack, err := r.Rollback(context.Background(), "istio-system", v1.DefaultRevision, 1)
+	assert.NoError(t, err)
+	assert.True(t, rolledBack)
+	assert.Equal(t, []string{"istio-system/default-istiod"}, cm.rolledBack, "only the istiod release should be rolled back")
+}
+
+func TestRollbackUnsupported(t *testing.T) {
+	cm := &historyChartManager{}
+	r := NewCNIReconciler(Config{ChartManager: struct{ helm.ChartReconciler }{cm}}, nil)
+
+	history, err := r.ReleaseHistory(context.Background(), "istio-cni")
+	assert.NoError(t, err)
+	assert.Nil(t, history)
+
+	_, err = r.Rollback(context.Background(), "istio-cni", 1)
+	assert.ErrorContains(t, err, "doesn't support rollbacks")
+}
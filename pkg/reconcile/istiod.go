@@ -29,8 +29,9 @@ import (
 
 // IstiodReconciler handles reconciliation of the istiod component.
 type IstiodReconciler struct {
-	cfg    Config
-	client client.Client
+	cfg        Config
+	client     client.Client
+	keptFields []helm.Drift
 }
 
 // NewIstiodReconciler creates a new IstiodReconciler.
@@ -41,6 +42,13 @@ func NewIstiodReconciler(cfg Config, client client.Client) *IstiodReconciler {
 	}
 }
 
+// KeepingFields makes Install and Plan keep the live values of the drifted fields that the DriftReport
+// doesn't revert.
+func (r *IstiodReconciler) KeepingFields(report *DriftReport) *IstiodReconciler {
+	r.keptFields = report.keptFields()
+	return r
+}
+
 // Validate performs general validation of the istiod specification.
 // This includes basic field validation and Kubernetes API checks (namespace exists).
 // CRD-specific validations (revision name consistency, IstioRevisionTag conflicts)
@@ -69,9 +77,14 @@ func (r *IstiodReconciler) Install(
 	ctx context.Context,
 	version, namespace string,
 	values *v1.Values,
+	patches []v1.Patch,
 	revisionName string,
 	ownerRef *metav1.OwnerReference,
 ) error {
+	if err := ValidatePatches(patches); err != nil {
+		return err
+	}
+
 	helmValues := helm.FromValues(values)
 
 	// Install istiod chart
@@ -86,9 +99,10 @@ func (r *IstiodReconciler) Install(
 		namespace,
 		istiodReleaseName,
 		ownerRef,
+		withKeptFields(chartOptions(patches), r.keptFields)...,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to install/update Helm chart %q: %w", constants.IstiodChartName, err)
+		return asPatchValidationError(fmt.Errorf("failed to install/update Helm chart %q: %w", constants.IstiodChartName, err))
 	}
 
 	// Install base chart for default revision
@@ -104,15 +118,105 @@ func (r *IstiodReconciler) Install(
 			r.cfg.OperatorNamespace,
 			baseReleaseName,
 			ownerRef,
+			withKeptFields(chartOptions(patches), r.keptFields)...,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to install/update Helm chart %q: %w", constants.BaseChartName, err)
+			return asPatchValidationError(fmt.Errorf("failed to install/update Helm chart %q: %w", constants.BaseChartName, err))
 		}
 	}
 
 	return nil
 }
 
+// Plan computes the changes that Install would make to the Helm releases of the revision, without changing
+// anything in the cluster. The ChartManager in the Config must implement helm.ChartPlanner.
+func (r *IstiodReconciler) Plan(
+	ctx context.Context,
+	version, namespace string,
+	values *v1.Values,
+	patches []v1.Patch,
+	revisionName string,
+	ownerRef *metav1.OwnerReference,
+) (helm.ReleaseDiff, error) {
+	if err := ValidatePatches(patches); err != nil {
+		return helm.ReleaseDiff{}, err
+	}
+
+	planner, ok := r.cfg.ChartManager.(helm.ChartPlanner)
+	if !ok {
+		return helm.ReleaseDiff{}, fmt.Errorf("chart manager %T doesn't support dry-run", r.cfg.ChartManager)
+	}
+
+	helmValues := helm.FromValues(values)
+
+	istiodChartPath := GetChartPath(version, constants.IstiodChartName)
+	istiodReleaseName := getReleaseName(revisionName, constants.IstiodChartName)
+	diff, err := planner.PlanChart(ctx, r.cfg.ResourceFS, istiodChartPath, helmValues, namespace, istiodReleaseName, ownerRef,
+		withKeptFields(chartOptions(patches), r.keptFields)...)
+	if err != nil {
+		return helm.ReleaseDiff{}, asPatchValidationError(fmt.Errorf("failed to plan Helm chart %q: %w", constants.IstiodChartName, err))
+	}
+
+	if revisionName == v1.DefaultRevision {
+		baseChartPath := GetChartPath(version, constants.BaseChartName)
+		baseReleaseName := getReleaseName(revisionName, constants.BaseChartName)
+		baseDiff, err := planner.PlanChart(ctx, r.cfg.ResourceFS, baseChartPath, helmValues, r.cfg.OperatorNamespace, baseReleaseName, ownerRef,
+			withKeptFields(chartOptions(patches), r.keptFields)...)
+		if err != nil {
+			return helm.ReleaseDiff{}, asPatchValidationError(fmt.Errorf("failed to plan Helm chart %q: %w", constants.BaseChartName, err))
+		}
+		diff.Append(baseDiff)
+	}
+
+	return diff, nil
+}
+
+// DetectDrift compares the objects deployed by the istiod Helm charts of the revision with the release
+// manifests and applies the given DriftPolicy. It returns nil if the ChartManager doesn't support drift
+// detection.
+func (r *IstiodReconciler) DetectDrift(ctx context.Context, namespace, revisionName string, policy *v1.DriftPolicy) *DriftReport {
+	return detectDrift(ctx, r.cfg, r.client, r.releases(namespace, revisionName), policy)
+}
+
+// CheckReadiness evaluates the readiness of the objects deployed by the istiod Helm charts of the revision.
+// It returns nil if the ChartManager can't return the release manifests.
+func (r *IstiodReconciler) CheckReadiness(ctx context.Context, namespace, revisionName string) ([]v1.ResourceStatus, error) {
+	return checkReadiness(ctx, r.cfg, r.client, r.releases(namespace, revisionName))
+}
+
+// FieldConflicts returns the fields of the istiod Helm charts of the revision that weren't applied during
+// the last install or upgrade, because other field managers own them. It returns nil if the ChartManager
+// doesn't use server-side apply.
+func (r *IstiodReconciler) FieldConflicts(namespace, revisionName string) *FieldConflictReport {
+	return fieldConflicts(r.cfg, r.releases(namespace, revisionName))
+}
+
+// ReleaseHistory returns the retained revisions of the istiod Helm release of the revision, newest first.
+// It returns nil if the ChartManager doesn't retain release history.
+func (r *IstiodReconciler) ReleaseHistory(ctx context.Context, namespace, revisionName string) ([]v1.HelmReleaseRevision, error) {
+	return releaseHistory(ctx, r.cfg, r.releases(namespace, revisionName)[0])
+}
+
+// Rollback reinstalls the given revision of the istiod Helm release of the revision. The base chart
+// release of the default revision isn't rolled back, as it only contains cluster-scoped objects that
+// are shared by all revisions. It returns true if a rollback was performed and false if the release had
+// already been rolled back to that revision.
+func (r *IstiodReconciler) Rollback(ctx context.Context, namespace, revisionName string, revision int) (bool, error) {
+	return rollback(ctx, r.cfg, r.releases(namespace, revisionName)[0], revision)
+}
+
+// releases returns the Helm releases of the revision.
+func (r *IstiodReconciler) releases(namespace, revisionName string) []releaseRef {
+	releases := []releaseRef{{namespace: namespace, name: getReleaseName(revisionName, constants.IstiodChartName)}}
+	if revisionName == v1.DefaultRevision {
+		releases = append(releases, releaseRef{
+			namespace: r.cfg.OperatorNamespace,
+			name:      getReleaseName(revisionName, constants.BaseChartName),
+		})
+	}
+	return releases
+}
+
 // Uninstall removes the istiod Helm charts.
 func (r *IstiodReconciler) Uninstall(ctx context.Context, namespace, revisionName string) error {
 	// Uninstall istiod chart
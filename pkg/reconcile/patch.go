@@ -0,0 +1,92 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+)
+
+// ValidatePatches checks that the patches in the spec of a resource can be parsed. The returned
+// ValidationError wraps the helm.PatchError of the first invalid patch.
+func ValidatePatches(patches []v1.Patch) error {
+	return asPatchValidationError(helm.ValidatePatches(toHelmPatches(patches)))
+}
+
+func toHelmPatches(patches []v1.Patch) []helm.Patch {
+	if len(patches) == 0 {
+		return nil
+	}
+	helmPatches := make([]helm.Patch, 0, len(patches))
+	for _, p := range patches {
+		helmPatches = append(helmPatches, helm.Patch{
+			Kind:  p.Target.Kind,
+			Name:  p.Target.Name,
+			Type:  helm.PatchType(p.Type),
+			Patch: p.Patch,
+		})
+	}
+	return helmPatches
+}
+
+func chartOptions(patches []v1.Patch) []helm.ChartOption {
+	if len(patches) == 0 {
+		return nil
+	}
+	return []helm.ChartOption{helm.WithPatches(toHelmPatches(patches)...)}
+}
+
+// instanceChartOptions returns the chart options for a component that can be installed more than once, like
+// istio-cni and ztunnel. The cluster-scoped objects of every instance except the default one get the instance
+// name as a suffix, so that the Helm releases of different instances don't conflict.
+func instanceChartOptions(patches []v1.Patch, instance, defaultInstance string) []helm.ChartOption {
+	opts := chartOptions(patches)
+	if instance != "" && instance != defaultInstance {
+		opts = append(opts, helm.WithClusterScopedNameSuffix("-"+instance))
+	}
+	return opts
+}
+
+// WithNodeSelector returns the patches followed by a patch that adds the given node selector to the DaemonSets
+// rendered from the chart. The patch is appended, so that the indices of the patches in errors match the spec.
+func WithNodeSelector(patches []v1.Patch, nodeSelector map[string]string) []v1.Patch {
+	if len(nodeSelector) == 0 {
+		return patches
+	}
+	patch, _ := json.Marshal(map[string]any{
+		"spec": map[string]any{"template": map[string]any{"spec": map[string]any{"nodeSelector": nodeSelector}}},
+	})
+	return append(slices.Clone(patches), v1.Patch{
+		Target: v1.PatchTarget{Kind: "DaemonSet"},
+		Type:   v1.PatchTypeStrategicMerge,
+		Patch:  string(patch),
+	})
+}
+
+// asPatchValidationError turns an error caused by a patch into a ValidationError, because retrying
+// won't help until the patch is changed. Other errors are returned as-is.
+func asPatchValidationError(err error) error {
+	var patchErr *helm.PatchError
+	if !errors.As(err, &patchErr) {
+		return err
+	}
+	return reconciler.NewValidationErrorWithCause(fmt.Sprintf("invalid spec.%v", patchErr), err)
+}
@@ -0,0 +1,88 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePatches(t *testing.T) {
+	valid := []v1.Patch{
+		{
+			Target: v1.PatchTarget{Kind: "ServiceAccount", Name: "ztunnel"},
+			Type:   v1.PatchTypeStrategicMerge,
+			Patch:  `{"metadata": {"annotations": {"foo": "bar"}}}`,
+		},
+	}
+	assert.NoError(t, ValidatePatches(valid))
+	assert.NoError(t, ValidatePatches(nil))
+
+	invalid := append(valid, v1.Patch{
+		Target: v1.PatchTarget{Kind: "DaemonSet"},
+		Type:   v1.PatchTypeJSON6902,
+		Patch:  `{"op": "add"}`,
+	})
+	err := ValidatePatches(invalid)
+	assert.True(t, reconciler.IsValidationError(err), "expected a ValidationError, got %v", err)
+	assert.True(t, helm.IsPatchError(err), "expected the error to wrap a PatchError, got %v", err)
+	assert.Contains(t, err.Error(), "invalid spec.patches[1]")
+}
+
+func TestAsPatchValidationError(t *testing.T) {
+	assert.NoError(t, asPatchValidationError(nil))
+
+	other := errors.New("connection refused")
+	assert.Equal(t, other, asPatchValidationError(other))
+
+	patchErr := fmt.Errorf("failed to install/update Helm chart: %w", &helm.PatchError{Index: 2, Err: errors.New("path not found")})
+	err := asPatchValidationError(patchErr)
+	assert.True(t, reconciler.IsValidationError(err))
+	assert.True(t, helm.IsPatchError(err))
+	assert.Equal(t, "validation error: invalid spec.patches[2]: path not found", err.Error())
+}
+
+func TestWithNodeSelector(t *testing.T) {
+	patches := []v1.Patch{
+		{
+			Target: v1.PatchTarget{Kind: "ServiceAccount"},
+			Type:   v1.PatchTypeStrategicMerge,
+			Patch:  `{"metadata": {"annotations": {"foo": "bar"}}}`,
+		},
+	}
+
+	assert.Equal(t, patches, WithNodeSelector(patches, nil))
+
+	actual := WithNodeSelector(patches, map[string]string{"pool": "a"})
+	assert.Equal(t, append(patches, v1.Patch{
+		Target: v1.PatchTarget{Kind: "DaemonSet"},
+		Type:   v1.PatchTypeStrategicMerge,
+		Patch:  `{"spec":{"template":{"spec":{"nodeSelector":{"pool":"a"}}}}}`,
+	}), actual)
+	assert.Len(t, patches, 1, "the patches from the spec must not be modified")
+	assert.NoError(t, ValidatePatches(actual))
+}
+
+func TestInstanceChartOptions(t *testing.T) {
+	assert.Empty(t, instanceChartOptions(nil, "", v1.DefaultIstioCNIName))
+	assert.Empty(t, instanceChartOptions(nil, v1.DefaultIstioCNIName, v1.DefaultIstioCNIName))
+	assert.Len(t, instanceChartOptions(nil, "pool-a", v1.DefaultIstioCNIName), 1)
+}
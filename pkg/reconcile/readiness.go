@@ -0,0 +1,52 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/istio-ecosystem/sail-operator/pkg/reconciler"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// checkReadiness evaluates the readiness of the objects in the manifests of the given Helm releases.
+// It returns nil if the ChartManager doesn't implement helm.ManifestGetter.
+func checkReadiness(ctx context.Context, cfg Config, cl client.Client, releases []releaseRef) ([]v1.ResourceStatus, error) {
+	getter, ok := cfg.ChartManager.(helm.ManifestGetter)
+	if !ok {
+		return nil, nil
+	}
+
+	var statuses []v1.ResourceStatus
+	for _, rel := range releases {
+		manifest, err := getter.GetManifest(ctx, rel.namespace, rel.name)
+		if err != nil {
+			return statuses, fmt.Errorf("failed to get manifest of Helm release %q: %w", rel.name, err)
+		}
+		objects, err := helm.ManifestObjects(manifest)
+		if err != nil {
+			return statuses, fmt.Errorf("failed to parse manifest of Helm release %q: %w", rel.name, err)
+		}
+		releaseStatuses, err := reconciler.CheckResourcesReadiness(ctx, cl, objects, rel.namespace)
+		statuses = append(statuses, releaseStatuses...)
+		if err != nil {
+			return statuses, err
+		}
+	}
+	return statuses, nil
+}
@@ -0,0 +1,164 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconcile
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const revisionTagsChartName = "revisiontags"
+
+// RevisionTagReconciler handles reconciliation of revision tags.
+type RevisionTagReconciler struct {
+	cfg    Config
+	client client.Client
+}
+
+// NewRevisionTagReconciler creates a new RevisionTagReconciler.
+func NewRevisionTagReconciler(cfg Config, client client.Client) *RevisionTagReconciler {
+	return &RevisionTagReconciler{
+		cfg:    cfg,
+		client: client,
+	}
+}
+
+// Install installs or upgrades the revisiontags Helm chart that points the tag to the given revision.
+// For the default tag, the base chart is also installed in the operator namespace, so that the
+// revision becomes the default revision of the cluster.
+func (r *RevisionTagReconciler) Install(
+	ctx context.Context,
+	version, namespace, tagName, revisionName string,
+	values *v1.Values,
+	ownerRef *metav1.OwnerReference,
+) error {
+	charts, err := r.charts(version, namespace, tagName, revisionName, values)
+	if err != nil {
+		return err
+	}
+	for _, c := range charts {
+		_, err := r.cfg.ChartManager.UpgradeOrInstallChart(ctx, r.cfg.ResourceFS, c.path, c.values, c.namespace, c.releaseName, ownerRef)
+		if err != nil {
+			return fmt.Errorf("failed to install/update Helm chart %q: %w", c.name, err)
+		}
+	}
+	return nil
+}
+
+// Plan computes the changes that Install would make to the Helm releases of the tag, without changing
+// anything in the cluster. The ChartManager in the Config must implement helm.ChartPlanner.
+func (r *RevisionTagReconciler) Plan(
+	ctx context.Context,
+	version, namespace, tagName, revisionName string,
+	values *v1.Values,
+	ownerRef *metav1.OwnerReference,
+) (helm.ReleaseDiff, error) {
+	planner, ok := r.cfg.ChartManager.(helm.ChartPlanner)
+	if !ok {
+		return helm.ReleaseDiff{}, fmt.Errorf("chart manager %T doesn't support dry-run", r.cfg.ChartManager)
+	}
+
+	charts, err := r.charts(version, namespace, tagName, revisionName, values)
+	if err != nil {
+		return helm.ReleaseDiff{}, err
+	}
+	var diff helm.ReleaseDiff
+	for _, c := range charts {
+		chartDiff, err := planner.PlanChart(ctx, r.cfg.ResourceFS, c.path, c.values, c.namespace, c.releaseName, ownerRef)
+		if err != nil {
+			return helm.ReleaseDiff{}, fmt.Errorf("failed to plan Helm chart %q: %w", c.name, err)
+		}
+		diff.Append(chartDiff)
+	}
+	return diff, nil
+}
+
+// tagChart describes a Helm chart that is installed for a revision tag.
+type tagChart struct {
+	name        string
+	path        string
+	values      helm.Values
+	namespace   string
+	releaseName string
+}
+
+// charts returns the Helm charts that point the tag to the given revision.
+func (r *RevisionTagReconciler) charts(version, namespace, tagName, revisionName string, values *v1.Values) ([]tagChart, error) {
+	helmValues := helm.FromValues(values)
+	if err := helmValues.SetStringSlice("revisionTags", []string{tagName}); err != nil {
+		return nil, err
+	}
+	charts := []tagChart{{
+		name:        revisionTagsChartName,
+		path:        GetChartPath(version, revisionTagsChartName),
+		values:      helmValues,
+		namespace:   namespace,
+		releaseName: getReleaseName(tagName, revisionTagsChartName),
+	}}
+	if tagName == v1.DefaultRevisionTag {
+		baseValues := helm.FromValues(values)
+		if err := baseValues.SetStringSlice("revisionTags", []string{tagName}); err != nil {
+			return nil, err
+		}
+		if err := baseValues.Set("defaultRevision", revisionName); err != nil {
+			return nil, err
+		}
+		charts = append(charts, tagChart{
+			name:        constants.BaseChartName,
+			path:        GetChartPath(version, constants.BaseChartName),
+			values:      baseValues,
+			namespace:   r.cfg.OperatorNamespace,
+			releaseName: getReleaseName(tagName, constants.BaseChartName),
+		})
+	}
+	return charts, nil
+}
+
+// CheckReadiness evaluates the readiness of the objects deployed by the Helm charts of the tag.
+// It returns nil if the ChartManager can't return the release manifests.
+func (r *RevisionTagReconciler) CheckReadiness(ctx context.Context, namespace, tagName string) ([]v1.ResourceStatus, error) {
+	return checkReadiness(ctx, r.cfg, r.client, r.releases(namespace, tagName))
+}
+
+// releases returns the Helm releases of the tag.
+func (r *RevisionTagReconciler) releases(namespace, tagName string) []releaseRef {
+	releases := []releaseRef{{namespace: namespace, name: getReleaseName(tagName, revisionTagsChartName)}}
+	if tagName == v1.DefaultRevisionTag {
+		releases = append(releases, releaseRef{
+			namespace: r.cfg.OperatorNamespace,
+			name:      getReleaseName(tagName, constants.BaseChartName),
+		})
+	}
+	return releases
+}
+
+// Uninstall removes the Helm charts of the tag.
+func (r *RevisionTagReconciler) Uninstall(ctx context.Context, namespace, tagName string) error {
+	if _, err := r.cfg.ChartManager.UninstallChart(ctx, getReleaseName(tagName, revisionTagsChartName), namespace); err != nil {
+		return fmt.Errorf("failed to uninstall Helm chart %q: %w", revisionTagsChartName, err)
+	}
+	if tagName == v1.DefaultRevisionTag {
+		if _, err := r.cfg.ChartManager.UninstallChart(ctx, getReleaseName(tagName, constants.BaseChartName), r.cfg.OperatorNamespace); err != nil {
+			return fmt.Errorf("failed to uninstall Helm chart %q: %w", constants.BaseChartName, err)
+		}
+	}
+	return nil
+}
@@ -17,6 +17,9 @@ package reconcile
 import (
 	"context"
 	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
 
 	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
 	"github.com/istio-ecosystem/sail-operator/pkg/config"
@@ -37,8 +40,10 @@ const (
 
 // ZTunnelReconciler handles reconciliation of the ztunnel component.
 type ZTunnelReconciler struct {
-	cfg    Config
-	client client.Client
+	cfg        Config
+	client     client.Client
+	instance   string
+	keptFields []helm.Drift
 }
 
 // NewZTunnelReconciler creates a new ZTunnelReconciler.
@@ -49,6 +54,20 @@ func NewZTunnelReconciler(cfg Config, client client.Client) *ZTunnelReconciler {
 	}
 }
 
+// ForInstance sets the name of the ZTunnel resource that the reconciler installs. The ClusterRoles and
+// ClusterRoleBindings of every instance except the default one are suffixed with its name.
+func (r *ZTunnelReconciler) ForInstance(name string) *ZTunnelReconciler {
+	r.instance = name
+	return r
+}
+
+// KeepingFields makes Install and Plan keep the live values of the drifted fields that the DriftReport
+// doesn't revert.
+func (r *ZTunnelReconciler) KeepingFields(report *DriftReport) *ZTunnelReconciler {
+	r.keptFields = report.keptFields()
+	return r
+}
+
 // Validate performs general validation of the ZTunnel specification.
 // This includes basic field validation and Kubernetes API checks (namespace exists).
 func (r *ZTunnelReconciler) Validate(ctx context.Context, version, namespace string) error {
@@ -58,6 +77,10 @@ func (r *ZTunnelReconciler) Validate(ctx context.Context, version, namespace str
 	if namespace == "" {
 		return reconciler.NewValidationError("namespace not set")
 	}
+	// the CRD doesn't restrict spec.version to a list of versions, since IstioChartSources can provide more
+	if v, err := semver.NewVersion(strings.TrimPrefix(version, "v")); err == nil && v.Major() == 1 && v.Minor() < 24 {
+		return reconciler.NewValidationError(fmt.Sprintf("version %q is not supported; ZTunnel requires Istio 1.24 or newer", version))
+	}
 
 	// Validate target namespace exists
 	if err := validation.ValidateTargetNamespace(ctx, r.client, namespace); err != nil {
@@ -128,8 +151,13 @@ func (r *ZTunnelReconciler) ComputeValues(version string, userValues *v1.ZTunnel
 // If baseValues are provided (e.g. from a referenced IstioRevision), they are passed to ComputeValues
 // to be merged early in the pipeline, before profiles and FIPS values are applied.
 func (r *ZTunnelReconciler) Install(
-	ctx context.Context, version, namespace string, values *v1.ZTunnelValues, ownerRef *metav1.OwnerReference, baseValues ...helm.Values,
+	ctx context.Context, version, namespace string, values *v1.ZTunnelValues, patches []v1.Patch,
+	ownerRef *metav1.OwnerReference, baseValues ...helm.Values,
 ) error {
+	if err := ValidatePatches(patches); err != nil {
+		return err
+	}
+
 	finalHelmValues, err := r.ComputeValues(version, values, baseValues...)
 	if err != nil {
 		return err
@@ -149,13 +177,79 @@ func (r *ZTunnelReconciler) Install(
 		namespace,
 		ztunnelReleaseName,
 		ownerRef,
+		withKeptFields(instanceChartOptions(patches, r.instance, v1.DefaultZTunnelName), r.keptFields)...,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to install/update Helm chart %q: %w", ztunnelChartName, err)
+		return asPatchValidationError(fmt.Errorf("failed to install/update Helm chart %q: %w", ztunnelChartName, err))
 	}
 	return nil
 }
 
+// Plan computes the changes that Install would make to the ztunnel Helm release, without changing
+// anything in the cluster. The ChartManager in the Config must implement helm.ChartPlanner.
+func (r *ZTunnelReconciler) Plan(
+	ctx context.Context, version, namespace string, values *v1.ZTunnelValues, patches []v1.Patch,
+	ownerRef *metav1.OwnerReference, baseValues ...helm.Values,
+) (helm.ReleaseDiff, error) {
+	if err := ValidatePatches(patches); err != nil {
+		return helm.ReleaseDiff{}, err
+	}
+
+	planner, ok := r.cfg.ChartManager.(helm.ChartPlanner)
+	if !ok {
+		return helm.ReleaseDiff{}, fmt.Errorf("chart manager %T doesn't support planning", r.cfg.ChartManager)
+	}
+
+	finalHelmValues, err := r.ComputeValues(version, values, baseValues...)
+	if err != nil {
+		return helm.ReleaseDiff{}, err
+	}
+
+	resolvedVersion, err := istioversion.Resolve(version)
+	if err != nil {
+		return helm.ReleaseDiff{}, fmt.Errorf("failed to resolve ZTunnel version: %w", err)
+	}
+
+	chartPath := GetChartPath(resolvedVersion, ztunnelChartName)
+	diff, err := planner.PlanChart(ctx, r.cfg.ResourceFS, chartPath, finalHelmValues, namespace, ztunnelReleaseName, ownerRef,
+		withKeptFields(instanceChartOptions(patches, r.instance, v1.DefaultZTunnelName), r.keptFields)...)
+	if err != nil {
+		return helm.ReleaseDiff{}, asPatchValidationError(fmt.Errorf("failed to plan Helm chart %q: %w", ztunnelChartName, err))
+	}
+	return diff, nil
+}
+
+// DetectDrift compares the objects deployed by the ztunnel Helm chart with the release manifest and
+// applies the given DriftPolicy. It returns nil if the ChartManager doesn't support drift detection.
+func (r *ZTunnelReconciler) DetectDrift(ctx context.Context, namespace string, policy *v1.DriftPolicy) *DriftReport {
+	return detectDrift(ctx, r.cfg, r.client, []releaseRef{{namespace: namespace, name: ztunnelReleaseName}}, policy)
+}
+
+// CheckReadiness evaluates the readiness of the objects deployed by the ztunnel Helm chart. It returns
+// nil if the ChartManager can't return the release manifest.
+func (r *ZTunnelReconciler) CheckReadiness(ctx context.Context, namespace string) ([]v1.ResourceStatus, error) {
+	return checkReadiness(ctx, r.cfg, r.client, []releaseRef{{namespace: namespace, name: ztunnelReleaseName}})
+}
+
+// ReleaseHistory returns the retained revisions of the ztunnel Helm release, newest first. It returns
+// nil if the ChartManager doesn't retain release history.
+func (r *ZTunnelReconciler) ReleaseHistory(ctx context.Context, namespace string) ([]v1.HelmReleaseRevision, error) {
+	return releaseHistory(ctx, r.cfg, releaseRef{namespace: namespace, name: ztunnelReleaseName})
+}
+
+// Rollback reinstalls the given revision of the ztunnel Helm release. It returns true if a rollback was
+// performed and false if the release had already been rolled back to that revision.
+func (r *ZTunnelReconciler) Rollback(ctx context.Context, namespace string, revision int) (bool, error) {
+	return rollback(ctx, r.cfg, releaseRef{namespace: namespace, name: ztunnelReleaseName}, revision)
+}
+
+// FieldConflicts returns the fields of the ztunnel Helm release that weren't applied during the last
+// install or upgrade, because other field managers own them. It returns nil if the ChartManager doesn't
+// use server-side apply.
+func (r *ZTunnelReconciler) FieldConflicts(namespace string) *FieldConflictReport {
+	return fieldConflicts(r.cfg, []releaseRef{{namespace: namespace, name: ztunnelReleaseName}})
+}
+
 // Uninstall removes the ztunnel Helm chart.
 func (r *ZTunnelReconciler) Uninstall(ctx context.Context, namespace string) error {
 	_, err := r.cfg.ChartManager.UninstallChart(ctx, ztunnelReleaseName, namespace)
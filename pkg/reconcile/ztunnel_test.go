@@ -57,6 +57,14 @@ func TestZTunnelReconciler_Validate(t *testing.T) {
 			wantErr:     true,
 			errContains: "namespace not set",
 		},
+		{
+			name:        "version without ambient support",
+			version:     "v1.23.6",
+			namespace:   "istio-system",
+			nsExists:    true,
+			wantErr:     true,
+			errContains: "ZTunnel requires Istio 1.24 or newer",
+		},
 		{
 			name:        "namespace not found",
 			version:     "v1.24.0",
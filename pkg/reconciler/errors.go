@@ -18,16 +18,27 @@ import "errors"
 
 type ValidationError struct {
 	message string
+	cause   error
 }
 
 func (v ValidationError) Error() string {
 	return "validation error: " + v.message
 }
 
+func (v ValidationError) Unwrap() error {
+	return v.cause
+}
+
 func NewValidationError(message string) error {
 	return &ValidationError{message: message}
 }
 
+// NewValidationErrorWithCause returns a ValidationError that wraps the given cause, so that callers
+// can inspect it with errors.As. The message should already include the cause's description.
+func NewValidationErrorWithCause(message string, cause error) error {
+	return &ValidationError{message: message, cause: cause}
+}
+
 func IsValidationError(err error) bool {
 	e := &ValidationError{}
 	return errors.As(err, &e)
@@ -15,6 +15,7 @@
 package reconciler
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
@@ -28,6 +29,14 @@ func TestNewValidationError(t *testing.T) {
 	assert.IsTypef(t, &ValidationError{}, err, "expected NewValidationError to return a ValidationError")
 }
 
+func TestNewValidationErrorWithCause(t *testing.T) {
+	cause := errors.New("cause")
+	err := NewValidationErrorWithCause("my message: cause", cause)
+	assert.Equal(t, "validation error: my message: cause", err.Error())
+	assert.True(t, IsValidationError(err), "expected IsValidationError to return true for a ValidationError with a cause")
+	assert.ErrorIs(t, err, cause)
+}
+
 func TestIsValidationError(t *testing.T) {
 	err := &ValidationError{message: "error"}
 	assert.True(t, IsValidationError(err), "expected IsValidationError to return true for a ValidationError")
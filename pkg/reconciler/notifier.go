@@ -0,0 +1,68 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconciler
+
+import (
+	"sync"
+
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// Notifier triggers the reconciliation of the controllers that watch it when state that isn't stored in a
+// Kubernetes object changes (e.g. the versions registered by IstioChartSources). The zero value is ready to use.
+type Notifier struct {
+	mu          sync.Mutex
+	subscribers []chan event.GenericEvent
+}
+
+// Watch adds a watch to the controller built by b, which passes an event to the given handler on every Notify.
+// Watch does nothing if the Notifier is nil.
+func (n *Notifier) Watch(b *builder.Builder, h handler.EventHandler) *builder.Builder {
+	if n == nil {
+		return b
+	}
+	return b.WatchesRawSource(source.Channel(n.Subscribe(), h))
+}
+
+// Subscribe returns a channel that receives an event on every Notify.
+func (n *Notifier) Subscribe() <-chan event.GenericEvent {
+	// a single pending event is enough, since handlers must look up the current state anyway
+	ch := make(chan event.GenericEvent, 1)
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.subscribers = append(n.subscribers, ch)
+	return ch
+}
+
+// Notify sends an event with the given object to all subscribers that don't have an event pending already.
+// Notify does nothing if the Notifier is nil.
+func (n *Notifier) Notify(obj client.Object) {
+	if n == nil {
+		return
+	}
+	evt := event.GenericEvent{Object: obj}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, ch := range n.subscribers {
+		select {
+		case ch <- evt:
+		default:
+		}
+	}
+}
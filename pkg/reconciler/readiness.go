@@ -17,12 +17,22 @@ package reconciler
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"istio.io/istio/pkg/ptr"
 )
 
 // CheckDaemonSetReadiness checks a DaemonSet's readiness state and returns
@@ -65,3 +75,330 @@ func CheckDaemonSetReadiness(
 	}
 	return c, nil
 }
+
+// CheckDeploymentReadiness checks a Deployment's readiness state and returns
+// the appropriate status condition. If the GET operation itself fails (not
+// a NotFound), the error is also returned for logging.
+func CheckDeploymentReadiness(
+	ctx context.Context,
+	cl client.Client,
+	key client.ObjectKey,
+	componentName string,
+	readyConditionType v1.ConditionType,
+	notReadyReason v1.ConditionReason,
+	checkFailedReason v1.ConditionReason,
+) (v1.StatusCondition, error) {
+	c := v1.StatusCondition{
+		Type:   readyConditionType,
+		Status: metav1.ConditionFalse,
+	}
+
+	deploy := appsv1.Deployment{}
+	if err := cl.Get(ctx, key, &deploy); err == nil {
+		if deploy.Status.Replicas == 0 {
+			c.Reason = notReadyReason
+			c.Message = fmt.Sprintf("%s Deployment is scaled to zero replicas", componentName)
+		} else if deploy.Status.ReadyReplicas < deploy.Status.Replicas {
+			c.Reason = notReadyReason
+			c.Message = fmt.Sprintf("not all %s pods are ready", componentName)
+		} else {
+			c.Status = metav1.ConditionTrue
+			c.Reason = v1.ConditionReason(readyConditionType)
+		}
+	} else if apierrors.IsNotFound(err) {
+		c.Reason = notReadyReason
+		c.Message = fmt.Sprintf("%s Deployment not found", componentName)
+	} else {
+		c.Status = metav1.ConditionUnknown
+		c.Reason = checkFailedReason
+		c.Message = fmt.Sprintf("failed to get readiness: %v", err)
+		return c, fmt.Errorf("get failed: %w", err)
+	}
+	return c, nil
+}
+
+// maxNotReadyResourcesInMessage limits the number of objects listed in the message returned by SummarizeReadiness
+const maxNotReadyResourcesInMessage = 3
+
+type readinessFunc func(obj *unstructured.Unstructured) (v1.ReadinessStatus, string, error)
+
+type readinessKind struct {
+	evaluate      readinessFunc
+	clusterScoped bool
+}
+
+// readinessKinds contains the readiness evaluators of the supported kinds
+var readinessKinds = map[schema.GroupKind]readinessKind{
+	{Group: "apps", Kind: "Deployment"}:                     {evaluate: deploymentReadiness},
+	{Group: "apps", Kind: "DaemonSet"}:                      {evaluate: daemonSetReadiness},
+	{Group: "", Kind: "Service"}:                            {evaluate: serviceReadiness},
+	{Group: "autoscaling", Kind: "HorizontalPodAutoscaler"}: {evaluate: hpaReadiness},
+	{Group: "policy", Kind: "PodDisruptionBudget"}:          {evaluate: pdbReadiness},
+	{Group: admissionregistrationv1.GroupName, Kind: "MutatingWebhookConfiguration"}: {
+		evaluate: mutatingWebhookReadiness, clusterScoped: true,
+	},
+	{Group: admissionregistrationv1.GroupName, Kind: "ValidatingWebhookConfiguration"}: {
+		evaluate: validatingWebhookReadiness, clusterScoped: true,
+	},
+}
+
+// CheckResourcesReadiness gets the live state of each of the given objects and evaluates its readiness.
+// Only Deployments, DaemonSets, Services, HorizontalPodAutoscalers, PodDisruptionBudgets and webhook
+// configurations are evaluated; objects of other kinds are skipped. Namespaced objects without a
+// namespace are looked up in the given namespace. If the GET operation for an object fails (other than
+// with NotFound), the object is reported as Unknown and the error is returned for logging.
+func CheckResourcesReadiness(
+	ctx context.Context, cl client.Client, objects []*unstructured.Unstructured, namespace string,
+) ([]v1.ResourceStatus, error) {
+	var statuses []v1.ResourceStatus
+	var errs []error
+	for _, obj := range objects {
+		gvk := obj.GroupVersionKind()
+		kind, supported := readinessKinds[gvk.GroupKind()]
+		if !supported {
+			continue
+		}
+
+		status := v1.ResourceStatus{
+			APIVersion: obj.GetAPIVersion(),
+			Kind:       obj.GetKind(),
+			Namespace:  obj.GetNamespace(),
+			Name:       obj.GetName(),
+		}
+		if kind.clusterScoped {
+			status.Namespace = ""
+		} else if status.Namespace == "" {
+			status.Namespace = namespace
+		}
+
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(gvk)
+		if err := cl.Get(ctx, client.ObjectKey{Namespace: status.Namespace, Name: status.Name}, live); err == nil {
+			var err error
+			status.Status, status.Message, err = kind.evaluate(live)
+			if err != nil {
+				status.Status = v1.ReadinessStatusUnknown
+				status.Message = fmt.Sprintf("failed to evaluate readiness: %v", err)
+			}
+		} else if apierrors.IsNotFound(err) {
+			status.Status = v1.ReadinessStatusNotFound
+			status.Message = fmt.Sprintf("%s not found", status.Kind)
+		} else {
+			status.Status = v1.ReadinessStatusUnknown
+			status.Message = fmt.Sprintf("failed to get readiness: %v", err)
+			errs = append(errs, fmt.Errorf("get %s %s failed: %w", status.Kind, status.Name, err))
+		}
+		statuses = append(statuses, status)
+	}
+	if len(errs) > 0 {
+		return statuses, errs[0]
+	}
+	return statuses, nil
+}
+
+// SummarizeReadiness returns true if all the given objects are Current. Otherwise, it returns a message
+// that lists the objects that aren't.
+func SummarizeReadiness(statuses []v1.ResourceStatus) (bool, string) {
+	var notReady []string
+	for _, s := range statuses {
+		if s.Status != v1.ReadinessStatusCurrent {
+			notReady = append(notReady, fmt.Sprintf("%s %s is %s (%s)", s.Kind, s.Name, s.Status, s.Message))
+		}
+	}
+	if len(notReady) == 0 {
+		return true, ""
+	}
+	count := len(notReady)
+	if count > maxNotReadyResourcesInMessage {
+		notReady = append(notReady[:maxNotReadyResourcesInMessage], "...")
+	}
+	return false, fmt.Sprintf("%d of %d resources are not ready: %s", count, len(statuses), strings.Join(notReady, "; "))
+}
+
+func fromUnstructured(obj *unstructured.Unstructured, into any) error {
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, into)
+}
+
+func deploymentReadiness(obj *unstructured.Unstructured) (v1.ReadinessStatus, string, error) {
+	deploy := appsv1.Deployment{}
+	if err := fromUnstructured(obj, &deploy); err != nil {
+		return "", "", err
+	}
+	if deploy.Generation > deploy.Status.ObservedGeneration {
+		return v1.ReadinessStatusInProgress, "waiting for the Deployment controller to observe the latest spec", nil
+	}
+	for _, c := range deploy.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Status == corev1.ConditionFalse && c.Reason == "ProgressDeadlineExceeded" {
+			return v1.ReadinessStatusFailed, fmt.Sprintf("progress deadline exceeded: %s", c.Message), nil
+		}
+	}
+
+	desired := ptr.OrDefault(deploy.Spec.Replicas, 1)
+	st := deploy.Status
+	switch {
+	case desired == 0:
+		return v1.ReadinessStatusInProgress, "Deployment is scaled to zero replicas", nil
+	case st.UpdatedReplicas < desired:
+		return v1.ReadinessStatusInProgress, fmt.Sprintf("updatedReplicas %d/%d", st.UpdatedReplicas, desired), nil
+	case st.Replicas > st.UpdatedReplicas:
+		return v1.ReadinessStatusInProgress, fmt.Sprintf("%d old replicas are pending termination", st.Replicas-st.UpdatedReplicas), nil
+	case st.AvailableReplicas < desired:
+		return v1.ReadinessStatusInProgress, fmt.Sprintf("availableReplicas %d/%d", st.AvailableReplicas, desired), nil
+	case st.ReadyReplicas < desired:
+		return v1.ReadinessStatusInProgress, fmt.Sprintf("readyReplicas %d/%d", st.ReadyReplicas, desired), nil
+	}
+	return v1.ReadinessStatusCurrent, fmt.Sprintf("readyReplicas %d/%d", st.ReadyReplicas, desired), nil
+}
+
+func daemonSetReadiness(obj *unstructured.Unstructured) (v1.ReadinessStatus, string, error) {
+	ds := appsv1.DaemonSet{}
+	if err := fromUnstructured(obj, &ds); err != nil {
+		return "", "", err
+	}
+	if ds.Generation > ds.Status.ObservedGeneration {
+		return v1.ReadinessStatusInProgress, "waiting for the DaemonSet controller to observe the latest spec", nil
+	}
+
+	st := ds.Status
+	desired := st.DesiredNumberScheduled
+	switch {
+	case desired == 0:
+		return v1.ReadinessStatusInProgress, "no pods are currently scheduled", nil
+	case st.UpdatedNumberScheduled < desired:
+		return v1.ReadinessStatusInProgress, fmt.Sprintf("updatedNumberScheduled %d/%d", st.UpdatedNumberScheduled, desired), nil
+	case st.NumberAvailable < desired:
+		return v1.ReadinessStatusInProgress, fmt.Sprintf("numberAvailable %d/%d", st.NumberAvailable, desired), nil
+	case st.NumberReady < desired:
+		return v1.ReadinessStatusInProgress, fmt.Sprintf("numberReady %d/%d", st.NumberReady, desired), nil
+	}
+	return v1.ReadinessStatusCurrent, fmt.Sprintf("numberReady %d/%d", st.NumberReady, desired), nil
+}
+
+func serviceReadiness(obj *unstructured.Unstructured) (v1.ReadinessStatus, string, error) {
+	svc := corev1.Service{}
+	if err := fromUnstructured(obj, &svc); err != nil {
+		return "", "", err
+	}
+	if svc.Spec.Type == corev1.ServiceTypeLoadBalancer && len(svc.Status.LoadBalancer.Ingress) == 0 {
+		return v1.ReadinessStatusInProgress, "waiting for the load balancer to be provisioned", nil
+	}
+	if svc.Spec.Type != corev1.ServiceTypeExternalName && svc.Spec.ClusterIP == "" {
+		return v1.ReadinessStatusInProgress, "waiting for a cluster IP to be assigned", nil
+	}
+	return v1.ReadinessStatusCurrent, "", nil
+}
+
+// hpaReadiness always reports HorizontalPodAutoscalers as Current, because an HPA that can't fetch metrics
+// (e.g. in a cluster without a metrics server) doesn't prevent the target from working. The message
+// reports the scaling state.
+func hpaReadiness(obj *unstructured.Unstructured) (v1.ReadinessStatus, string, error) {
+	hpa := autoscalingv2.HorizontalPodAutoscaler{}
+	if err := fromUnstructured(obj, &hpa); err != nil {
+		return "", "", err
+	}
+	message := fmt.Sprintf("currentReplicas %d, desiredReplicas %d", hpa.Status.CurrentReplicas, hpa.Status.DesiredReplicas)
+	for _, c := range hpa.Status.Conditions {
+		if c.Type == autoscalingv2.ScalingActive && c.Status == corev1.ConditionFalse {
+			message += fmt.Sprintf("; scaling is not active: %s", c.Message)
+		}
+	}
+	return v1.ReadinessStatusCurrent, message, nil
+}
+
+func pdbReadiness(obj *unstructured.Unstructured) (v1.ReadinessStatus, string, error) {
+	pdb := policyv1.PodDisruptionBudget{}
+	if err := fromUnstructured(obj, &pdb); err != nil {
+		return "", "", err
+	}
+	if pdb.Generation > pdb.Status.ObservedGeneration {
+		return v1.ReadinessStatusInProgress, "waiting for the disruption controller to observe the latest spec", nil
+	}
+	message := fmt.Sprintf("currentHealthy %d/%d", pdb.Status.CurrentHealthy, pdb.Status.DesiredHealthy)
+	if pdb.Status.CurrentHealthy < pdb.Status.DesiredHealthy {
+		return v1.ReadinessStatusInProgress, message, nil
+	}
+	return v1.ReadinessStatusCurrent, message, nil
+}
+
+func mutatingWebhookReadiness(obj *unstructured.Unstructured) (v1.ReadinessStatus, string, error) {
+	config := admissionregistrationv1.MutatingWebhookConfiguration{}
+	if err := fromUnstructured(obj, &config); err != nil {
+		return "", "", err
+	}
+	var missingCABundle []string
+	for _, webhook := range config.Webhooks {
+		if needsCABundle(webhook.ClientConfig) {
+			missingCABundle = append(missingCABundle, webhook.Name)
+		}
+	}
+	status, message := webhookReadiness(missingCABundle)
+	return status, message, nil
+}
+
+func validatingWebhookReadiness(obj *unstructured.Unstructured) (v1.ReadinessStatus, string, error) {
+	config := admissionregistrationv1.ValidatingWebhookConfiguration{}
+	if err := fromUnstructured(obj, &config); err != nil {
+		return "", "", err
+	}
+	var missingCABundle []string
+	for _, webhook := range config.Webhooks {
+		if needsCABundle(webhook.ClientConfig) {
+			missingCABundle = append(missingCABundle, webhook.Name)
+		}
+	}
+	status, message := webhookReadiness(missingCABundle)
+	return status, message, nil
+}
+
+// needsCABundle returns true if the webhook calls a Service, but istiod hasn't injected the CA bundle yet.
+// Webhooks that call a URL don't need a CA bundle if the URL uses a publicly trusted certificate.
+func needsCABundle(clientConfig admissionregistrationv1.WebhookClientConfig) bool {
+	return clientConfig.Service != nil && len(clientConfig.CABundle) == 0
+}
+
+func webhookReadiness(missingCABundle []string) (v1.ReadinessStatus, string) {
+	if len(missingCABundle) > 0 {
+		return v1.ReadinessStatusInProgress,
+			fmt.Sprintf("waiting for the caBundle to be injected into webhooks %s", strings.Join(missingCABundle, ", "))
+	}
+	return v1.ReadinessStatusCurrent, ""
+}
+
+// ApplyResourcesReadiness sets a Ready condition with status True to False with the given reason if any
+// of the given objects blocks readiness (see blocksReadiness). Conditions with other statuses are returned
+// unchanged, as they already report why the resource isn't ready.
+func ApplyResourcesReadiness(c v1.StatusCondition, statuses []v1.ResourceStatus, notReadyReason v1.ConditionReason) v1.StatusCondition {
+	if c.Status != metav1.ConditionTrue {
+		return c
+	}
+	var blocking []v1.ResourceStatus
+	for _, s := range statuses {
+		if blocksReadiness(s) {
+			blocking = append(blocking, s)
+		}
+	}
+	if ready, message := SummarizeReadiness(blocking); !ready {
+		c.Status = metav1.ConditionFalse
+		c.Reason = notReadyReason
+		c.Message = message
+	}
+	return c
+}
+
+// blocksReadiness returns true if the given object prevents the owning resource from being Ready. Workloads
+// must be fully rolled out, while other objects only block readiness if they're missing or have failed. Their
+// progress depends on controllers outside the control plane (e.g. the cloud provider's load balancer controller
+// or the disruption controller), so it's only reported in the status.
+func blocksReadiness(s v1.ResourceStatus) bool {
+	switch s.Status {
+	case v1.ReadinessStatusCurrent:
+		return false
+	case v1.ReadinessStatusNotFound, v1.ReadinessStatusFailed:
+		return true
+	}
+	gv, err := schema.ParseGroupVersion(s.APIVersion)
+	if err != nil {
+		return true
+	}
+	return gv.Group == appsv1.GroupName && (s.Kind == "Deployment" || s.Kind == "DaemonSet")
+}
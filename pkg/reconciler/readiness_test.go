@@ -0,0 +1,413 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reconciler
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
+	"github.com/stretchr/testify/assert"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func toUnstructured(t *testing.T, obj client.Object) *unstructured.Unstructured {
+	t.Helper()
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		t.Fatalf("failed to convert object: %v", err)
+	}
+	return &unstructured.Unstructured{Object: u}
+}
+
+func TestDeploymentReadiness(t *testing.T) {
+	tests := []struct {
+		name            string
+		replicas        *int32
+		generation      int64
+		status          appsv1.DeploymentStatus
+		expectedStatus  v1.ReadinessStatus
+		expectedMessage string
+	}{
+		{
+			name:            "not observed",
+			generation:      2,
+			status:          appsv1.DeploymentStatus{ObservedGeneration: 1},
+			expectedStatus:  v1.ReadinessStatusInProgress,
+			expectedMessage: "waiting for the Deployment controller to observe the latest spec",
+		},
+		{
+			name:     "progress deadline exceeded",
+			replicas: ptr.Of(int32(3)),
+			status: appsv1.DeploymentStatus{
+				Replicas: 3, UpdatedReplicas: 1,
+				Conditions: []appsv1.DeploymentCondition{
+					{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded", Message: "timed out"},
+				},
+			},
+			expectedStatus:  v1.ReadinessStatusFailed,
+			expectedMessage: "progress deadline exceeded: timed out",
+		},
+		{
+			name:            "rollout in progress",
+			replicas:        ptr.Of(int32(3)),
+			status:          appsv1.DeploymentStatus{Replicas: 4, UpdatedReplicas: 2, AvailableReplicas: 3, ReadyReplicas: 3},
+			expectedStatus:  v1.ReadinessStatusInProgress,
+			expectedMessage: "updatedReplicas 2/3",
+		},
+		{
+			name:            "old replicas pending termination",
+			replicas:        ptr.Of(int32(3)),
+			status:          appsv1.DeploymentStatus{Replicas: 4, UpdatedReplicas: 3, AvailableReplicas: 3, ReadyReplicas: 4},
+			expectedStatus:  v1.ReadinessStatusInProgress,
+			expectedMessage: "1 old replicas are pending termination",
+		},
+		{
+			name:            "not available",
+			replicas:        ptr.Of(int32(3)),
+			status:          appsv1.DeploymentStatus{Replicas: 3, UpdatedReplicas: 3, AvailableReplicas: 1, ReadyReplicas: 3},
+			expectedStatus:  v1.ReadinessStatusInProgress,
+			expectedMessage: "availableReplicas 1/3",
+		},
+		{
+			name:            "scaled to zero",
+			replicas:        ptr.Of(int32(0)),
+			expectedStatus:  v1.ReadinessStatusInProgress,
+			expectedMessage: "Deployment is scaled to zero replicas",
+		},
+		{
+			name:            "ready with default replicas",
+			status:          appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1, ReadyReplicas: 1},
+			expectedStatus:  v1.ReadinessStatusCurrent,
+			expectedMessage: "readyReplicas 1/1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deploy := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "istiod", Generation: tt.generation},
+				Spec:       appsv1.DeploymentSpec{Replicas: tt.replicas},
+				Status:     tt.status,
+			}
+			status, message, err := deploymentReadiness(toUnstructured(t, deploy))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, status)
+			assert.Equal(t, tt.expectedMessage, message)
+		})
+	}
+}
+
+func TestDaemonSetReadiness(t *testing.T) {
+	tests := []struct {
+		name            string
+		status          appsv1.DaemonSetStatus
+		expectedStatus  v1.ReadinessStatus
+		expectedMessage string
+	}{
+		{
+			name:            "no pods scheduled",
+			expectedStatus:  v1.ReadinessStatusInProgress,
+			expectedMessage: "no pods are currently scheduled",
+		},
+		{
+			name:            "rollout in progress",
+			status:          appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 1, NumberAvailable: 3, NumberReady: 3},
+			expectedStatus:  v1.ReadinessStatusInProgress,
+			expectedMessage: "updatedNumberScheduled 1/3",
+		},
+		{
+			name:            "not ready",
+			status:          appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3, NumberReady: 2},
+			expectedStatus:  v1.ReadinessStatusInProgress,
+			expectedMessage: "numberReady 2/3",
+		},
+		{
+			name:            "ready",
+			status:          appsv1.DaemonSetStatus{DesiredNumberScheduled: 3, UpdatedNumberScheduled: 3, NumberAvailable: 3, NumberReady: 3},
+			expectedStatus:  v1.ReadinessStatusCurrent,
+			expectedMessage: "numberReady 3/3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "ztunnel"}, Status: tt.status}
+			status, message, err := daemonSetReadiness(toUnstructured(t, ds))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, status)
+			assert.Equal(t, tt.expectedMessage, message)
+		})
+	}
+}
+
+func TestCheckResourcesReadiness(t *testing.T) {
+	ctx := context.Background()
+	deploy := &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: "istiod", Namespace: "istio-system"},
+		Status:     appsv1.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1, ReadyReplicas: 1},
+	}
+	lbService := &corev1.Service{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{Name: "istio-ingressgateway", Namespace: "istio-system"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer, ClusterIP: "10.0.0.1"},
+	}
+	pdb := &policyv1.PodDisruptionBudget{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "policy/v1", Kind: "PodDisruptionBudget"},
+		ObjectMeta: metav1.ObjectMeta{Name: "istiod", Namespace: "istio-system"},
+		Status:     policyv1.PodDisruptionBudgetStatus{CurrentHealthy: 1, DesiredHealthy: 1},
+	}
+	webhook := &admissionregistrationv1.MutatingWebhookConfiguration{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "admissionregistration.k8s.io/v1", Kind: "MutatingWebhookConfiguration"},
+		ObjectMeta: metav1.ObjectMeta{Name: "istio-sidecar-injector"},
+		Webhooks: []admissionregistrationv1.MutatingWebhook{
+			{
+				Name: "rev.namespace.sidecar-injector.istio.io",
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{
+					Service: &admissionregistrationv1.ServiceReference{Name: "istiod", Namespace: "istio-system"},
+				},
+			},
+		},
+	}
+	configMap := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: "istio", Namespace: "istio-system"},
+	}
+
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).
+		WithObjects(deploy, lbService, pdb, webhook, configMap).
+		WithStatusSubresource(&appsv1.Deployment{}, &policyv1.PodDisruptionBudget{}).
+		Build()
+	// the manifest objects don't have a namespace, and the webhook configuration is cluster-scoped
+	manifestDeploy := toUnstructured(t, &appsv1.Deployment{
+		TypeMeta: deploy.TypeMeta, ObjectMeta: metav1.ObjectMeta{Name: "istiod"},
+	})
+	manifestWebhook := toUnstructured(t, &admissionregistrationv1.MutatingWebhookConfiguration{
+		TypeMeta: webhook.TypeMeta, ObjectMeta: metav1.ObjectMeta{Name: "istio-sidecar-injector", Namespace: "istio-system"},
+	})
+	objects := []*unstructured.Unstructured{
+		manifestDeploy,
+		toUnstructured(t, lbService),
+		toUnstructured(t, pdb),
+		manifestWebhook,
+		toUnstructured(t, configMap),
+		toUnstructured(t, &corev1.Service{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Service"}, ObjectMeta: metav1.ObjectMeta{Name: "istiod"},
+		}),
+	}
+
+	// the fake client clears the TypeMeta of the objects, so the status is updated after the manifest is built
+	assert.NoError(t, cl.Status().Update(ctx, deploy))
+	assert.NoError(t, cl.Status().Update(ctx, pdb))
+
+	statuses, err := CheckResourcesReadiness(ctx, cl, objects, "istio-system")
+	assert.NoError(t, err)
+	assert.Equal(t, []v1.ResourceStatus{
+		{
+			APIVersion: "apps/v1", Kind: "Deployment", Namespace: "istio-system", Name: "istiod",
+			Status: v1.ReadinessStatusCurrent, Message: "readyReplicas 1/1",
+		},
+		{
+			APIVersion: "v1", Kind: "Service", Namespace: "istio-system", Name: "istio-ingressgateway",
+			Status: v1.ReadinessStatusInProgress, Message: "waiting for the load balancer to be provisioned",
+		},
+		{
+			APIVersion: "policy/v1", Kind: "PodDisruptionBudget", Namespace: "istio-system", Name: "istiod",
+			Status: v1.ReadinessStatusCurrent, Message: "currentHealthy 1/1",
+		},
+		{
+			APIVersion: "admissionregistration.k8s.io/v1", Kind: "MutatingWebhookConfiguration", Name: "istio-sidecar-injector",
+			Status:  v1.ReadinessStatusInProgress,
+			Message: "waiting for the caBundle to be injected into webhooks rev.namespace.sidecar-injector.istio.io",
+		},
+		{
+			APIVersion: "v1", Kind: "Service", Namespace: "istio-system", Name: "istiod",
+			Status: v1.ReadinessStatusNotFound, Message: "Service not found",
+		},
+	}, statuses)
+}
+
+func TestCheckResourcesReadinessGetError(t *testing.T) {
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(_ context.Context, _ client.WithWatch, _ client.ObjectKey, _ client.Object, _ ...client.GetOption) error {
+			return errors.New("simulated error")
+		},
+	}).Build()
+	objects := []*unstructured.Unstructured{
+		toUnstructured(t, &appsv1.DaemonSet{
+			TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "DaemonSet"}, ObjectMeta: metav1.ObjectMeta{Name: "ztunnel"},
+		}),
+	}
+
+	statuses, err := CheckResourcesReadiness(context.Background(), cl, objects, "ztunnel")
+	assert.ErrorContains(t, err, "simulated error")
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, v1.ReadinessStatusUnknown, statuses[0].Status)
+}
+
+func TestCheckDeploymentReadiness(t *testing.T) {
+	key := client.ObjectKey{Namespace: "istio-ingress", Name: "istio-ingressgateway"}
+	tests := []struct {
+		name            string
+		deployment      *appsv1.Deployment
+		expectedStatus  metav1.ConditionStatus
+		expectedReason  v1.ConditionReason
+		expectedMessage string
+	}{
+		{
+			name:            "not found",
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  "NotReady",
+			expectedMessage: "gateway Deployment not found",
+		},
+		{
+			name:            "scaled to zero",
+			deployment:      &appsv1.Deployment{},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  "NotReady",
+			expectedMessage: "gateway Deployment is scaled to zero replicas",
+		},
+		{
+			name:            "pods not ready",
+			deployment:      &appsv1.Deployment{Status: appsv1.DeploymentStatus{Replicas: 2, ReadyReplicas: 1}},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  "NotReady",
+			expectedMessage: "not all gateway pods are ready",
+		},
+		{
+			name:           "ready",
+			deployment:     &appsv1.Deployment{Status: appsv1.DeploymentStatus{Replicas: 2, ReadyReplicas: 2}},
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: "Ready",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder().WithScheme(scheme.Scheme)
+			if tt.deployment != nil {
+				tt.deployment.ObjectMeta = metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}
+				builder.WithObjects(tt.deployment)
+			}
+			c, err := CheckDeploymentReadiness(context.Background(), builder.Build(), key, "gateway", "Ready", "NotReady", "CheckFailed")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, c.Status)
+			assert.Equal(t, tt.expectedReason, c.Reason)
+			assert.Equal(t, tt.expectedMessage, c.Message)
+		})
+	}
+
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(_ context.Context, _ client.WithWatch, _ client.ObjectKey, _ client.Object, _ ...client.GetOption) error {
+			return errors.New("simulated error")
+		},
+	}).Build()
+	c, err := CheckDeploymentReadiness(context.Background(), cl, key, "gateway", "Ready", "NotReady", "CheckFailed")
+	assert.ErrorContains(t, err, "simulated error")
+	assert.Equal(t, metav1.ConditionUnknown, c.Status)
+	assert.Equal(t, v1.ConditionReason("CheckFailed"), c.Reason)
+}
+
+func TestSummarizeReadiness(t *testing.T) {
+	ready, message := SummarizeReadiness([]v1.ResourceStatus{
+		{Kind: "Deployment", Name: "istiod", Status: v1.ReadinessStatusCurrent},
+	})
+	assert.True(t, ready)
+	assert.Empty(t, message)
+
+	ready, message = SummarizeReadiness([]v1.ResourceStatus{
+		{Kind: "Deployment", Name: "a", Status: v1.ReadinessStatusInProgress, Message: "updatedReplicas 2/3"},
+		{Kind: "Deployment", Name: "b", Status: v1.ReadinessStatusCurrent},
+		{Kind: "Service", Name: "c", Status: v1.ReadinessStatusNotFound, Message: "Service not found"},
+		{Kind: "DaemonSet", Name: "d", Status: v1.ReadinessStatusFailed, Message: "failed"},
+		{Kind: "DaemonSet", Name: "e", Status: v1.ReadinessStatusUnknown, Message: "unknown"},
+	})
+	assert.False(t, ready)
+	assert.Equal(t, "4 of 5 resources are not ready: Deployment a is InProgress (updatedReplicas 2/3); "+
+		"Service c is NotFound (Service not found); DaemonSet d is Failed (failed); ...", message)
+}
+
+func TestApplyResourcesReadiness(t *testing.T) {
+	ready := v1.StatusCondition{Type: "Ready", Status: metav1.ConditionTrue, Reason: "Ready"}
+	tests := []struct {
+		name           string
+		condition      v1.StatusCondition
+		statuses       []v1.ResourceStatus
+		expectedStatus metav1.ConditionStatus
+	}{
+		{
+			name:      "all current",
+			condition: ready,
+			statuses: []v1.ResourceStatus{
+				{APIVersion: "apps/v1", Kind: "Deployment", Name: "istiod", Status: v1.ReadinessStatusCurrent},
+			},
+			expectedStatus: metav1.ConditionTrue,
+		},
+		{
+			name:      "workload in progress",
+			condition: ready,
+			statuses: []v1.ResourceStatus{
+				{APIVersion: "apps/v1", Kind: "Deployment", Name: "istiod", Status: v1.ReadinessStatusInProgress},
+			},
+			expectedStatus: metav1.ConditionFalse,
+		},
+		{
+			name:      "other object in progress",
+			condition: ready,
+			statuses: []v1.ResourceStatus{
+				{APIVersion: "policy/v1", Kind: "PodDisruptionBudget", Name: "istiod", Status: v1.ReadinessStatusInProgress},
+			},
+			expectedStatus: metav1.ConditionTrue,
+		},
+		{
+			name:      "other object not found",
+			condition: ready,
+			statuses: []v1.ResourceStatus{
+				{APIVersion: "v1", Kind: "Service", Name: "istiod", Status: v1.ReadinessStatusNotFound},
+			},
+			expectedStatus: metav1.ConditionFalse,
+		},
+		{
+			name:      "condition not true",
+			condition: v1.StatusCondition{Type: "Ready", Status: metav1.ConditionFalse, Reason: "IstiodNotReady"},
+			statuses: []v1.ResourceStatus{
+				{APIVersion: "apps/v1", Kind: "Deployment", Name: "istiod", Status: v1.ReadinessStatusInProgress},
+			},
+			expectedStatus: metav1.ConditionFalse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := ApplyResourcesReadiness(tt.condition, tt.statuses, "ResourcesNotReady")
+			assert.Equal(t, tt.expectedStatus, c.Status)
+			if tt.condition.Status == metav1.ConditionTrue && tt.expectedStatus == metav1.ConditionFalse {
+				assert.Equal(t, v1.ConditionReason("ResourcesNotReady"), c.Reason)
+			} else {
+				assert.Equal(t, tt.condition, c)
+			}
+		})
+	}
+}
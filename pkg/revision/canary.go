@@ -0,0 +1,178 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Canary identifies a revision that serves a subset of the namespaces in the mesh
+// before it is promoted to become the active revision.
+type Canary struct {
+	// RevisionName is the name of the canary IstioRevision.
+	RevisionName string
+
+	// Namespaces are the names of the namespaces served by the canary revision.
+	Namespaces []string
+}
+
+// CanaryHealth reports the health of a canary revision and of the pods it injected in the canary namespaces.
+type CanaryHealth struct {
+	// RevisionReady is true when the Ready condition of the revision is True.
+	RevisionReady bool
+
+	// RevisionFailed is true when the Ready condition of the revision is False.
+	RevisionFailed bool
+
+	// Pods is the number of pods in the canary namespaces that were injected by the canary revision.
+	Pods int
+
+	// NotReadyPods is the number of those pods that aren't ready.
+	NotReadyPods int
+
+	// CrashingPods is the number of those pods that have a container in CrashLoopBackOff.
+	CrashingPods int
+}
+
+// Healthy returns true when the revision is ready and all the pods it injected in the canary namespaces are ready.
+func (h CanaryHealth) Healthy() bool {
+	return h.RevisionReady && h.NotReadyPods == 0 && h.CrashingPods == 0
+}
+
+// Degraded returns true when the revision is reported as not ready or when any of the pods it
+// injected in the canary namespaces is crash looping. Pods that are merely not ready yet (e.g.
+// during a rollout) don't make the canary degraded.
+func (h CanaryHealth) Degraded() bool {
+	return h.RevisionFailed || h.CrashingPods > 0
+}
+
+// MoveNamespacesToCanary sets the istio.io/rev label of all namespaces matched by the selector
+// to the name of the canary revision. Namespaces that use the istio-injection label are skipped.
+// A nil selector matches no namespaces.
+func MoveNamespacesToCanary(ctx context.Context, cl client.Client, selector *metav1.LabelSelector, canaryRevisionName string) (Canary, error) {
+	log := logf.FromContext(ctx)
+	canary := Canary{RevisionName: canaryRevisionName}
+	if selector == nil {
+		return canary, nil
+	}
+
+	sel, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return canary, fmt.Errorf("invalid canary namespace selector: %w", err)
+	}
+	nsList := namespaceMetadataList()
+	if err := cl.List(ctx, nsList, client.MatchingLabelsSelector{Selector: sel}); err != nil {
+		return canary, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	for i := range nsList.Items {
+		ns := &nsList.Items[i]
+		if ns.Labels[constants.IstioInjectionLabel] == constants.IstioInjectionEnabledValue {
+			continue
+		}
+		canary.Namespaces = append(canary.Namespaces, ns.Name)
+		if ns.Labels[constants.IstioRevLabel] == canaryRevisionName {
+			continue
+		}
+
+		log.Info("Moving namespace to the canary revision", "Namespace", ns.Name, "IstioRevision", canaryRevisionName)
+		patch := client.MergeFrom(ns.DeepCopy())
+		if ns.Labels == nil {
+			ns.Labels = map[string]string{}
+		}
+		ns.Labels[constants.IstioRevLabel] = canaryRevisionName
+		if err := cl.Patch(ctx, ns, patch); err != nil {
+			return canary, fmt.Errorf("failed to update namespace %s: %w", ns.Name, err)
+		}
+	}
+	return canary, nil
+}
+
+// RollBackNamespaces relabels all namespaces that reference the specified revision through
+// the istio.io/rev label, so that they reference the previous revision instead.
+func RollBackNamespaces(ctx context.Context, cl client.Client, revisionName, previousRevisionName string) error {
+	_, err := updateNamespaces(ctx, cl, map[string]bool{revisionName: true}, previousRevisionName)
+	return err
+}
+
+// GetCanaryHealth determines the health of the canary revision and of the pods it injected in the canary namespaces.
+// Since only the metadata of pods is cached, the pods are listed through podReader, which should read from the API server.
+func GetCanaryHealth(ctx context.Context, cl client.Client, podReader client.Reader, canary Canary) (CanaryHealth, error) {
+	health := CanaryHealth{}
+
+	rev := v1.IstioRevision{}
+	if err := cl.Get(ctx, types.NamespacedName{Name: canary.RevisionName}, &rev); err != nil {
+		if apierrors.IsNotFound(err) {
+			return health, nil
+		}
+		return health, fmt.Errorf("failed to get IstioRevision %s: %w", canary.RevisionName, err)
+	}
+	switch rev.Status.GetCondition(v1.IstioRevisionConditionReady).Status {
+	case metav1.ConditionTrue:
+		health.RevisionReady = true
+	case metav1.ConditionFalse:
+		health.RevisionFailed = true
+	}
+
+	for _, ns := range canary.Namespaces {
+		podList := corev1.PodList{}
+		if err := podReader.List(ctx, &podList, client.InNamespace(ns)); err != nil {
+			return health, fmt.Errorf("failed to list pods in namespace %s: %w", ns, err)
+		}
+		for _, pod := range podList.Items {
+			if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed ||
+				GetInjectedRevisionFromPod(pod.GetAnnotations()) != canary.RevisionName {
+				continue
+			}
+			health.Pods++
+			if isPodCrashing(pod) {
+				health.CrashingPods++
+			} else if !isPodReady(pod) {
+				health.NotReadyPods++
+			}
+		}
+	}
+	return health, nil
+}
+
+func isPodReady(pod corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func isPodCrashing(pod corev1.Pod) bool {
+	for _, statuses := range [][]corev1.ContainerStatus{pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses} {
+		for _, cs := range statuses {
+			if cs.State.Waiting != nil && cs.State.Waiting.Reason == "CrashLoopBackOff" {
+				return true
+			}
+		}
+	}
+	return false
+}
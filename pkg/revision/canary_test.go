@@ -0,0 +1,186 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestMoveNamespacesToCanary(t *testing.T) {
+	const (
+		activeRevName = "my-istio-v1-0-0"
+		canaryRevName = "my-istio-v1-1-0"
+	)
+	ctx := context.Background()
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"canary": "true"}}
+
+	newNamespace := func(name string, labels map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+
+	t.Run("nil selector matches no namespaces", func(t *testing.T) {
+		g := NewWithT(t)
+		ns := newNamespace("ns1", map[string]string{"canary": "true", constants.IstioRevLabel: activeRevName})
+		cl := newFakeClientBuilder().WithObjects(ns).Build()
+
+		canary, err := MoveNamespacesToCanary(ctx, newCacheClient(t, cl), nil, canaryRevName)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(canary).To(Equal(Canary{RevisionName: canaryRevName}))
+
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(ns), ns)).To(Succeed())
+		g.Expect(ns.Labels[constants.IstioRevLabel]).To(Equal(activeRevName))
+	})
+
+	t.Run("relabels selected namespaces", func(t *testing.T) {
+		g := NewWithT(t)
+		selected := newNamespace("selected", map[string]string{"canary": "true", constants.IstioRevLabel: activeRevName})
+		unlabeled := newNamespace("unlabeled", map[string]string{"canary": "true"})
+		injection := newNamespace("injection", map[string]string{
+			"canary":                      "true",
+			constants.IstioInjectionLabel: constants.IstioInjectionEnabledValue,
+		})
+		other := newNamespace("other", map[string]string{constants.IstioRevLabel: activeRevName})
+		cl := newFakeClientBuilder().WithObjects(selected, unlabeled, injection, other).Build()
+
+		canary, err := MoveNamespacesToCanary(ctx, newCacheClient(t, cl), selector, canaryRevName)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(canary.Namespaces).To(ConsistOf("selected", "unlabeled"))
+
+		for _, ns := range []*corev1.Namespace{selected, unlabeled} {
+			g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(ns), ns)).To(Succeed())
+			g.Expect(ns.Labels[constants.IstioRevLabel]).To(Equal(canaryRevName))
+		}
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(injection), injection)).To(Succeed())
+		g.Expect(injection.Labels).ToNot(HaveKey(constants.IstioRevLabel))
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(other), other)).To(Succeed())
+		g.Expect(other.Labels[constants.IstioRevLabel]).To(Equal(activeRevName))
+
+		// rolling back moves the namespaces back to the previous revision
+		g.Expect(RollBackNamespaces(ctx, newCacheClient(t, cl), canaryRevName, activeRevName)).To(Succeed())
+		for _, ns := range []*corev1.Namespace{selected, unlabeled} {
+			g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(ns), ns)).To(Succeed())
+			g.Expect(ns.Labels[constants.IstioRevLabel]).To(Equal(activeRevName))
+		}
+	})
+}
+
+func TestGetCanaryHealth(t *testing.T) {
+	const canaryRevName = "my-istio-v1-1-0"
+	ctx := context.Background()
+
+	newRevision := func(ready metav1.ConditionStatus) *v1.IstioRevision {
+		return &v1.IstioRevision{
+			ObjectMeta: metav1.ObjectMeta{Name: canaryRevName},
+			Status: v1.IstioRevisionStatus{
+				Conditions: []v1.StatusCondition{{Type: v1.IstioRevisionConditionReady, Status: ready}},
+			},
+		}
+	}
+
+	newPod := func(namespace, name, injectedRevision string, status corev1.PodStatus) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   namespace,
+				Name:        name,
+				Annotations: map[string]string{constants.IstioRevLabel: injectedRevision},
+			},
+			Status: status,
+		}
+	}
+
+	readyPod := corev1.PodStatus{
+		Phase:      corev1.PodRunning,
+		Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+	}
+	notReadyPod := corev1.PodStatus{
+		Phase:      corev1.PodRunning,
+		Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse}},
+	}
+	crashingPod := corev1.PodStatus{
+		Phase: corev1.PodRunning,
+		InitContainerStatuses: []corev1.ContainerStatus{
+			{Name: "istio-proxy", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}},
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		objects        []client.Object
+		expected       CanaryHealth
+		expectHealthy  bool
+		expectDegraded bool
+	}{
+		{
+			name:     "revision not found",
+			expected: CanaryHealth{},
+		},
+		{
+			name:           "revision not ready",
+			objects:        []client.Object{newRevision(metav1.ConditionFalse)},
+			expected:       CanaryHealth{RevisionFailed: true},
+			expectDegraded: true,
+		},
+		{
+			name: "healthy",
+			objects: []client.Object{
+				newRevision(metav1.ConditionTrue),
+				newPod("canary", "ready", canaryRevName, readyPod),
+				newPod("canary", "other-revision", "other", notReadyPod),
+				newPod("other", "not-in-canary-namespace", canaryRevName, notReadyPod),
+			},
+			expected:      CanaryHealth{RevisionReady: true, Pods: 1},
+			expectHealthy: true,
+		},
+		{
+			name: "pods not ready",
+			objects: []client.Object{
+				newRevision(metav1.ConditionTrue),
+				newPod("canary", "ready", canaryRevName, readyPod),
+				newPod("canary", "not-ready", canaryRevName, notReadyPod),
+			},
+			expected: CanaryHealth{RevisionReady: true, Pods: 2, NotReadyPods: 1},
+		},
+		{
+			name: "pods crashing",
+			objects: []client.Object{
+				newRevision(metav1.ConditionTrue),
+				newPod("canary", "crashing", canaryRevName, crashingPod),
+			},
+			expected:       CanaryHealth{RevisionReady: true, Pods: 1, CrashingPods: 1},
+			expectDegraded: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			cl := newFakeClientBuilder().WithObjects(tc.objects...).Build()
+
+			health, err := GetCanaryHealth(ctx, newCacheClient(t, cl), cl, Canary{RevisionName: canaryRevName, Namespaces: []string{"canary"}})
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(health).To(Equal(tc.expected))
+			g.Expect(health.Healthy()).To(Equal(tc.expectHealthy))
+			g.Expect(health.Degraded()).To(Equal(tc.expectDegraded))
+		})
+	}
+}
@@ -16,19 +16,87 @@ package revision
 
 import (
 	"io/fs"
+	"sync"
 
 	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
 	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 type computeValuesFunc func(*v1.Values, string, string, config.Platform, string, string, fs.FS, string, *config.TLSConfig) (*v1.Values, error)
 
 var defaultComputeValues computeValuesFunc = ComputeValues
 
+// computedValues holds the values computed for a single generation of an IstioRevision.
+type computedValues struct {
+	generation     int64
+	version        string
+	platform       config.Platform
+	defaultProfile string
+	// resourceDigest identifies the charts and profiles provided for the version by an IstioChartSource,
+	// which may change without the generation of the IstioRevision changing
+	resourceDigest string
+	values         *v1.Values
+}
+
+// valuesCache caches the computed values of each IstioRevision, keyed by its UID. Only the values of the latest
+// generation and chart source contents are kept.
+var valuesCache = struct {
+	sync.Mutex
+	entries map[types.UID]computedValues
+}{entries: map[types.UID]computedValues{}}
+
+// GetComputedValues returns the values of the IstioRevision after the profiles, vendor defaults and overrides are
+// applied, as computed by ComputeValues. The result is cached per revision generation and per content of the
+// chart source that provides the revision's version, so the returned values must not be modified.
+func GetComputedValues(rev *v1.IstioRevision, cfg config.ReconcilerConfig) (*v1.Values, error) {
+	if rev.UID == "" {
+		return computeRevisionValues(rev, cfg)
+	}
+
+	resourceDigest := cfg.ChartSources.Digest(rev.Spec.Version)
+	valuesCache.Lock()
+	entry, found := valuesCache.entries[rev.UID]
+	valuesCache.Unlock()
+	if found && entry.generation == rev.Generation && entry.version == rev.Spec.Version &&
+		entry.platform == cfg.Platform && entry.defaultProfile == cfg.DefaultProfile && entry.resourceDigest == resourceDigest {
+		return entry.values, nil
+	}
+
+	values, err := computeRevisionValues(rev, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	valuesCache.Lock()
+	valuesCache.entries[rev.UID] = computedValues{
+		generation:     rev.Generation,
+		version:        rev.Spec.Version,
+		platform:       cfg.Platform,
+		defaultProfile: cfg.DefaultProfile,
+		resourceDigest: resourceDigest,
+		values:         values,
+	}
+	valuesCache.Unlock()
+	return values, nil
+}
+
+// ForgetComputedValues removes the cached values of the IstioRevision. It must be called when the
+// IstioRevision is deleted.
+func ForgetComputedValues(rev *v1.IstioRevision) {
+	valuesCache.Lock()
+	delete(valuesCache.entries, rev.UID)
+	valuesCache.Unlock()
+}
+
+func computeRevisionValues(rev *v1.IstioRevision, cfg config.ReconcilerConfig) (*v1.Values, error) {
+	return defaultComputeValues(rev.Spec.Values, rev.Spec.Namespace, rev.Spec.Version,
+		cfg.Platform, cfg.DefaultProfile, "", cfg.ResourceFS, rev.Name, nil)
+}
+
 // DependsOnIstioCNI returns true if CNI is enabled in the revision
 func DependsOnIstioCNI(rev *v1.IstioRevision, cfg config.ReconcilerConfig) bool {
-	values, err := defaultComputeValues(rev.Spec.Values, rev.Spec.Namespace, rev.Spec.Version,
-		cfg.Platform, cfg.DefaultProfile, "", cfg.ResourceFS, rev.Name, nil)
+	values, err := GetComputedValues(rev, cfg)
 	if err != nil || values == nil {
 		return false
 	}
@@ -47,10 +115,25 @@ func DependsOnIstioCNI(rev *v1.IstioRevision, cfg config.ReconcilerConfig) bool
 	return isCNIEnabled
 }
 
+// IstioCNIName returns the name of the IstioCNI resource that the revision depends on
+func IstioCNIName(rev *v1.IstioRevision) string {
+	if rev.Spec.Dependencies != nil && rev.Spec.Dependencies.IstioCNI != "" {
+		return rev.Spec.Dependencies.IstioCNI
+	}
+	return v1.DefaultIstioCNIName
+}
+
+// ZTunnelName returns the name of the ZTunnel resource that the revision depends on
+func ZTunnelName(rev *v1.IstioRevision) string {
+	if rev.Spec.Dependencies != nil && rev.Spec.Dependencies.ZTunnel != "" {
+		return rev.Spec.Dependencies.ZTunnel
+	}
+	return v1.DefaultZTunnelName
+}
+
 // DependsOnZTunnel returns true if the revision is configured for ambient mode and requires ZTunnel
 func DependsOnZTunnel(rev *v1.IstioRevision, cfg config.ReconcilerConfig) bool {
-	values, err := defaultComputeValues(rev.Spec.Values, rev.Spec.Namespace, rev.Spec.Version,
-		cfg.Platform, cfg.DefaultProfile, "", cfg.ResourceFS, rev.Name, nil)
+	values, err := GetComputedValues(rev, cfg)
 	if err != nil || values == nil {
 		return false
 	}
@@ -391,3 +391,38 @@ func TestDependsOnZTunnel(t *testing.T) {
 		})
 	}
 }
+
+func TestDependencyNames(t *testing.T) {
+	tests := []struct {
+		name            string
+		dependencies    *v1.Dependencies
+		expectedCNI     string
+		expectedZTunnel string
+	}{
+		{
+			name:            "NotSet",
+			expectedCNI:     v1.DefaultIstioCNIName,
+			expectedZTunnel: v1.DefaultZTunnelName,
+		},
+		{
+			name:            "Empty",
+			dependencies:    &v1.Dependencies{},
+			expectedCNI:     v1.DefaultIstioCNIName,
+			expectedZTunnel: v1.DefaultZTunnelName,
+		},
+		{
+			name:            "Named",
+			dependencies:    &v1.Dependencies{IstioCNI: "cni-a", ZTunnel: "ztunnel-a"},
+			expectedCNI:     "cni-a",
+			expectedZTunnel: "ztunnel-a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rev := &v1.IstioRevision{Spec: v1.IstioRevisionSpec{Dependencies: tt.dependencies}}
+			assert.Equal(t, tt.expectedCNI, IstioCNIName(rev))
+			assert.Equal(t, tt.expectedZTunnel, ZTunnelName(rev))
+		})
+	}
+}
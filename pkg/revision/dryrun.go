@@ -0,0 +1,50 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// IsDryRun returns true if the sailoperator.io/dry-run annotation is set to "true" on the given
+// Istio or IstioRevision. In dry-run mode, the operator doesn't install any Helm charts for the resource,
+// but reports the changes it would make in its status.plan field.
+func IsDryRun(obj client.Object) bool {
+	return obj.GetAnnotations()[constants.DryRunAnnotationKey] == "true"
+}
+
+// NewPlan converts the changes to the Helm releases into the plan that is reported in the status.
+func NewPlan(diff helm.ReleaseDiff) *v1.IstioRevisionPlan {
+	toPlannedObjects := func(refs []helm.ObjectReference) []v1.PlannedObject {
+		var objects []v1.PlannedObject
+		for _, ref := range refs {
+			objects = append(objects, v1.PlannedObject{
+				APIVersion: ref.APIVersion,
+				Kind:       ref.Kind,
+				Namespace:  ref.Namespace,
+				Name:       ref.Name,
+			})
+		}
+		return objects
+	}
+	return &v1.IstioRevisionPlan{
+		Added:   toPlannedObjects(diff.Added),
+		Changed: toPlannedObjects(diff.Changed),
+		Removed: toPlannedObjects(diff.Removed),
+	}
+}
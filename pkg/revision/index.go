@@ -0,0 +1,357 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// The operator watches pods and namespaces through metadata-only informers and indexes them by the names of
+// the revisions and revision tags they reference, so that it can determine whether a revision or tag is in use
+// without inspecting every pod in the cluster.
+const (
+	namespaceRevisionIndex     = "sailoperator.io/namespace-revision"
+	podInjectedRevisionIndex   = "sailoperator.io/injected-revision"
+	podReferencedRevisionIndex = "sailoperator.io/referenced-revision"
+
+	// jobTrackingFinalizer is added by the Job controller to the pods it creates and removed once the pod has
+	// terminated and has been accounted for in the Job's status.
+	jobTrackingFinalizer = "batch.kubernetes.io/job-tracking"
+)
+
+// FieldIndex is a field index that must be registered with the manager's cache before FindInjectedPod and
+// FindInjectionReference can be used.
+type FieldIndex struct {
+	Object  client.Object
+	Field   string
+	Extract client.IndexerFunc
+}
+
+// UsageIndexes returns the field indexes that FindInjectedPod and FindInjectionReference rely on.
+func UsageIndexes() []FieldIndex {
+	return []FieldIndex{
+		{Object: NamespaceMetadata(), Field: namespaceRevisionIndex, Extract: indexValue(namespaceReference)},
+		{Object: PodMetadata(), Field: podInjectedRevisionIndex, Extract: indexValue(podInjectedRevision)},
+		{Object: PodMetadata(), Field: podReferencedRevisionIndex, Extract: indexValue(podLabelReference)},
+	}
+}
+
+// RegisterUsageIndexes registers the indexes returned by UsageIndexes with the given indexer.
+func RegisterUsageIndexes(ctx context.Context, indexer client.FieldIndexer) error {
+	for _, index := range UsageIndexes() {
+		if err := indexer.IndexField(ctx, index.Object, index.Field, index.Extract); err != nil {
+			return fmt.Errorf("failed to register index %s: %w", index.Field, err)
+		}
+	}
+	return nil
+}
+
+// NamespaceMetadata returns an empty PartialObjectMetadata for a Namespace.
+func NamespaceMetadata() *metav1.PartialObjectMetadata {
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Namespace"))
+	return obj
+}
+
+// PodMetadata returns an empty PartialObjectMetadata for a Pod.
+func PodMetadata() *metav1.PartialObjectMetadata {
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Pod"))
+	return obj
+}
+
+func podMetadataList() *metav1.PartialObjectMetadataList {
+	list := &metav1.PartialObjectMetadataList{}
+	list.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("PodList"))
+	return list
+}
+
+func namespaceMetadataList() *metav1.PartialObjectMetadataList {
+	list := &metav1.PartialObjectMetadataList{}
+	list.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("NamespaceList"))
+	return list
+}
+
+// FindInjectedPod returns a pod that was injected by the given revision and hasn't succeeded, or nil if there is
+// none. The phase of the pods found in the index is read through apiReader, see podSucceeded.
+func FindInjectedPod(ctx context.Context, cl, apiReader client.Reader, revisionName string) (*metav1.PartialObjectMetadata, error) {
+	podList := podMetadataList()
+	if err := cl.List(ctx, podList, client.MatchingFields{podInjectedRevisionIndex: revisionName}, client.UnsafeDisableDeepCopy); err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if succeeded, err := podSucceeded(ctx, apiReader, pod); err != nil {
+			return nil, err
+		} else if !succeeded {
+			return pod.DeepCopy(), nil
+		}
+	}
+	return nil, nil
+}
+
+// FindInjectionReference returns a namespace or pod whose injection labels select the revision or revision tag
+// with the given name, or nil if there is none. The labels of a pod are only considered if the labels of its
+// namespace don't select any revision, and pods that have succeeded are skipped (see FindInjectedPod). Namespaces
+// and pods enrolled in the ambient mesh reference the revision in their istio.io/rev label or, if they don't have
+// one, the default revision.
+func FindInjectionReference(ctx context.Context, cl, apiReader client.Reader, name string) (*metav1.PartialObjectMetadata, error) {
+	nsList := namespaceMetadataList()
+	if err := cl.List(ctx, nsList, client.MatchingFields{namespaceRevisionIndex: name}, client.Limit(1)); err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	if len(nsList.Items) > 0 {
+		return &nsList.Items[0], nil
+	}
+
+	var reference *metav1.PartialObjectMetadata
+	var phaseErr error
+	err := forEachPodLabelReference(ctx, cl, name, func(pod *metav1.PartialObjectMetadata) bool {
+		succeeded, err := podSucceeded(ctx, apiReader, pod)
+		if err != nil {
+			phaseErr = err
+			return false
+		}
+		if succeeded {
+			return true
+		}
+		reference = pod.DeepCopy()
+		return false
+	})
+	if err == nil {
+		err = phaseErr
+	}
+	return reference, err
+}
+
+// podSucceeded returns true if the pod has run to completion or no longer exists. The cache only holds the
+// metadata of pods, so the pod is read through the given uncached reader. This costs one request for each
+// pod that's found in the indexes, which is usually the only one checked, since pods that are still running
+// end the search; completed Job pods are already left out of the indexes (see podTerminated).
+func podSucceeded(ctx context.Context, apiReader client.Reader, pod *metav1.PartialObjectMetadata) (bool, error) {
+	livePod := &corev1.Pod{}
+	if err := apiReader.Get(ctx, client.ObjectKeyFromObject(pod), livePod); err != nil {
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to get pod %s: %w", client.ObjectKeyFromObject(pod), err)
+	}
+	return livePod.Status.Phase == corev1.PodSucceeded, nil
+}
+
+// ListNamespaceReferences returns the namespaces whose injection labels select the revision or revision tag with
+// the given name or, if they don't select any, that the revision serves in ambient mode.
+func ListNamespaceReferences(ctx context.Context, cl client.Reader, name string) ([]metav1.PartialObjectMetadata, error) {
+	nsList := namespaceMetadataList()
+	if err := cl.List(ctx, nsList, client.MatchingFields{namespaceRevisionIndex: name}); err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	return nsList.Items, nil
+}
+
+// forEachPodLabelReference calls fn for each pod whose injection labels select the revision or revision tag with
+// the given name and whose namespace's labels don't select any revision, until fn returns false. The pods passed
+// to fn must not be modified.
+func forEachPodLabelReference(ctx context.Context, cl client.Reader, name string, fn func(pod *metav1.PartialObjectMetadata) bool) error {
+	podList := podMetadataList()
+	if err := cl.List(ctx, podList, client.MatchingFields{podReferencedRevisionIndex: name}, client.UnsafeDisableDeepCopy); err != nil {
+		return fmt.Errorf("failed to list pods: %w", err)
+	}
+	selectsRevision := map[string]bool{}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		selects, found := selectsRevision[pod.Namespace]
+		if !found {
+			ns := NamespaceMetadata()
+			if err := cl.Get(ctx, types.NamespacedName{Name: pod.Namespace}, ns); err != nil {
+				if !apierrors.IsNotFound(err) {
+					return fmt.Errorf("failed to get namespace %s: %w", pod.Namespace, err)
+				}
+				// the namespace is being deleted along with its pods
+				selects = true
+			} else {
+				selects = namespaceReference(ns) != ""
+			}
+			selectsRevision[pod.Namespace] = selects
+		}
+		if !selects && !fn(pod) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// PodReferences returns the names of the revisions and revision tags that the pod references through its
+// injected-revision annotation and the names returned by PodLabelReferences.
+func PodReferences(ctx context.Context, cl client.Reader, pod client.Object) ([]string, error) {
+	names, err := PodLabelReferences(ctx, cl, pod)
+	if injected := podInjectedRevision(pod); injected != "" && (len(names) == 0 || names[0] != injected) {
+		names = append(names, injected)
+	}
+	return names, err
+}
+
+// PodLabelReferences returns the name of the revision or revision tag that the injection labels of the pod's
+// namespace select or, if they don't select any, that the injection labels of the pod select.
+func PodLabelReferences(ctx context.Context, cl client.Reader, pod client.Object) ([]string, error) {
+	if podTerminated(pod) {
+		return nil, nil
+	}
+	ns := NamespaceMetadata()
+	if err := cl.Get(ctx, types.NamespacedName{Name: pod.GetNamespace()}, ns); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nonEmpty(podLabelReference(pod)), fmt.Errorf("failed to get namespace %s: %w", pod.GetNamespace(), err)
+	}
+	if name := namespaceReference(ns); name != "" {
+		return []string{name}, nil
+	}
+	return nonEmpty(podLabelReference(pod)), nil
+}
+
+// NamespaceReferences returns the names of the revisions and revision tags that the injection labels of the
+// namespace select. If the namespace doesn't select any revision, the labels of its pods do, so the names
+// selected by them are returned instead.
+func NamespaceReferences(ctx context.Context, cl client.Reader, ns client.Object) ([]string, error) {
+	if name := namespaceReference(ns); name != "" {
+		return []string{name}, nil
+	}
+	podList := podMetadataList()
+	if err := cl.List(ctx, podList, client.InNamespace(ns.GetName()), client.UnsafeDisableDeepCopy); err != nil {
+		return nil, fmt.Errorf("failed to list pods in namespace %s: %w", ns.GetName(), err)
+	}
+	var names []string
+	seen := map[string]bool{}
+	for i := range podList.Items {
+		if name := podLabelReference(&podList.Items[i]); name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// EnqueueReferenceChanges returns an event handler that enqueues the objects named after the revisions or
+// revision tags that the watched object references. On update, only the names that either the old or the new
+// object references are enqueued, since the usage of the names that both reference didn't change.
+func EnqueueReferenceChanges(references func(ctx context.Context, obj client.Object) []string) handler.EventHandler {
+	return handler.Funcs{
+		CreateFunc: func(ctx context.Context, e event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueueNames(q, references(ctx, e.Object))
+		},
+		UpdateFunc: func(ctx context.Context, e event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueueNames(q, changedNames(references(ctx, e.ObjectOld), references(ctx, e.ObjectNew)))
+		},
+		DeleteFunc: func(ctx context.Context, e event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueueNames(q, references(ctx, e.Object))
+		},
+		GenericFunc: func(ctx context.Context, e event.GenericEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			enqueueNames(q, references(ctx, e.Object))
+		},
+	}
+}
+
+func enqueueNames(q workqueue.TypedRateLimitingInterface[reconcile.Request], names []string) {
+	for _, name := range names {
+		q.Add(reconcile.Request{NamespacedName: types.NamespacedName{Name: name}})
+	}
+}
+
+// changedNames returns the names that are only in one of the two lists.
+func changedNames(oldNames, newNames []string) []string {
+	oldSet := map[string]bool{}
+	for _, name := range oldNames {
+		oldSet[name] = true
+	}
+	var changed []string
+	for _, name := range newNames {
+		if oldSet[name] {
+			delete(oldSet, name)
+		} else {
+			changed = append(changed, name)
+		}
+	}
+	for _, name := range oldNames {
+		if oldSet[name] {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+func indexValue(fn func(client.Object) string) client.IndexerFunc {
+	return func(obj client.Object) []string {
+		return nonEmpty(fn(obj))
+	}
+}
+
+func nonEmpty(names ...string) []string {
+	var result []string
+	for _, name := range names {
+		if name != "" {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// namespaceReference returns the revision or revision tag that the namespace's injection labels select or,
+// if they don't select any, the one that serves the namespace in ambient mode.
+func namespaceReference(ns client.Object) string {
+	if name := GetReferencedRevisionFromNamespace(ns.GetLabels()); name != "" {
+		return name
+	}
+	return GetAmbientRevisionFromNamespace(ns.GetLabels())
+}
+
+func podInjectedRevision(pod client.Object) string {
+	if podTerminated(pod) {
+		return ""
+	}
+	return GetInjectedRevisionFromPod(pod.GetAnnotations())
+}
+
+func podLabelReference(pod client.Object) string {
+	if podTerminated(pod) {
+		return ""
+	}
+	if name := GetReferencedRevisionFromPod(pod.GetLabels()); name != "" {
+		return name
+	}
+	return GetAmbientRevisionFromPod(pod.GetLabels())
+}
+
+// podTerminated returns true if the pod is known to have run to completion. Because only the pod's metadata
+// is available, this is limited to pods controlled by a Job, from which the Job controller removes its
+// tracking finalizer once they've terminated. Other pods reference their revision until they're deleted.
+func podTerminated(pod client.Object) bool {
+	owner := metav1.GetControllerOfNoCopy(pod)
+	return owner != nil && owner.Kind == "Job" && strings.HasPrefix(owner.APIVersion, "batch/") &&
+		!controllerutil.ContainsFinalizer(pod, jobTrackingFinalizer)
+}
@@ -0,0 +1,347 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func newIndexedClient(objs ...client.Object) client.Client {
+	b := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(objs...)
+	for _, index := range UsageIndexes() {
+		b = b.WithIndex(index.Object, index.Field, index.Extract)
+	}
+	return b.Build()
+}
+
+// newCacheClient wraps the given client so that it behaves like the manager's cache, which only holds the
+// metadata of pods and namespaces. Reading typed pods or namespaces through it fails the test, because the
+// cache would start an informer that holds every pod or namespace in the cluster.
+func newCacheClient(t *testing.T, cl client.WithWatch) client.Client {
+	rejectTyped := func(obj runtime.Object) error {
+		switch obj.(type) {
+		case *corev1.Pod, *corev1.PodList, *corev1.Namespace, *corev1.NamespaceList,
+			*appsv1.ReplicaSet, *appsv1.ReplicaSetList, *appsv1.Deployment, *appsv1.DeploymentList,
+			*appsv1.StatefulSet, *appsv1.StatefulSetList, *appsv1.DaemonSet, *appsv1.DaemonSetList:
+			t.Errorf("%T read through the cache, which would start a typed informer", obj)
+			return fmt.Errorf("%T must not be read through the cache", obj)
+		}
+		return nil
+	}
+	return interceptor.NewClient(cl, interceptor.Funcs{
+		Get: func(ctx context.Context, cl client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			if err := rejectTyped(obj); err != nil {
+				return err
+			}
+			return cl.Get(ctx, key, obj, opts...)
+		},
+		List: func(ctx context.Context, cl client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+			if err := rejectTyped(list); err != nil {
+				return err
+			}
+			return cl.List(ctx, list, opts...)
+		},
+	})
+}
+
+func jobPod(namespace, name string, completed bool, annotations map[string]string) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace:       namespace,
+		Name:            name,
+		Annotations:     annotations,
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "batch/v1", Kind: "Job", Name: name, Controller: ptr.Of(true)}},
+	}}
+	if !completed {
+		pod.Finalizers = []string{jobTrackingFinalizer}
+	}
+	return pod
+}
+
+func succeededPod(namespace, name string, labels, annotations map[string]string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels, Annotations: annotations},
+		Status:     corev1.PodStatus{Phase: corev1.PodSucceeded},
+	}
+}
+
+func TestFindInjectedPod(t *testing.T) {
+	ctx := context.Background()
+	cl := newIndexedClient(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "labeled", Labels: map[string]string{"istio.io/rev": "my-rev"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "injected", Annotations: map[string]string{"istio.io/rev": "my-rev"}}},
+		jobPod("ns", "completed", true, map[string]string{"istio.io/rev": "job-rev"}),
+		jobPod("ns", "running", false, map[string]string{"istio.io/rev": "running-job-rev"}),
+		succeededPod("ns", "succeeded", nil, map[string]string{"istio.io/rev": "succeeded-rev"}),
+		succeededPod("ns", "mixed-1-succeeded", nil, map[string]string{"istio.io/rev": "mixed-rev"}),
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "mixed-2-running", Annotations: map[string]string{"istio.io/rev": "mixed-rev"}}},
+	)
+	// pod phases are read through the API reader, since the cache only holds the metadata of pods
+	cache := newCacheClient(t, cl.(client.WithWatch))
+
+	pod, err := FindInjectedPod(ctx, cache, cl, "my-rev")
+	require.NoError(t, err)
+	require.NotNil(t, pod)
+	assert.Equal(t, types.NamespacedName{Namespace: "ns", Name: "injected"}, client.ObjectKeyFromObject(pod))
+
+	pod, err = FindInjectedPod(ctx, cache, cl, "job-rev")
+	require.NoError(t, err)
+	assert.Nil(t, pod, "pods of completed Jobs shouldn't reference their revision")
+
+	pod, err = FindInjectedPod(ctx, cache, cl, "running-job-rev")
+	require.NoError(t, err)
+	assert.NotNil(t, pod)
+
+	pod, err = FindInjectedPod(ctx, cache, cl, "succeeded-rev")
+	require.NoError(t, err)
+	assert.Nil(t, pod, "pods that have succeeded shouldn't reference their revision")
+
+	pod, err = FindInjectedPod(ctx, cache, cl, "mixed-rev")
+	require.NoError(t, err)
+	require.NotNil(t, pod)
+	assert.Equal(t, types.NamespacedName{Namespace: "ns", Name: "mixed-2-running"}, client.ObjectKeyFromObject(pod))
+}
+
+func TestFindInjectedPodPhaseCheckFails(t *testing.T) {
+	cl := newIndexedClient(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "injected", Annotations: map[string]string{"istio.io/rev": "my-rev"}}},
+	)
+	apiReader := interceptor.NewClient(cl.(client.WithWatch), interceptor.Funcs{
+		Get: func(ctx context.Context, cl client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			return fmt.Errorf("simulated error")
+		},
+	})
+
+	pod, err := FindInjectedPod(context.Background(), cl, apiReader, "my-rev")
+	assert.ErrorContains(t, err, "failed to get pod ns/injected")
+	assert.Nil(t, pod)
+}
+
+func TestFindInjectionReference(t *testing.T) {
+	ctx := context.Background()
+	cl := newIndexedClient(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "labeled", Labels: map[string]string{"istio.io/rev": "ns-rev"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "injection-enabled", Labels: map[string]string{"istio-injection": "enabled"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled"}},
+		// the namespace label takes precedence over the pod label
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "labeled", Name: "overridden", Labels: map[string]string{"istio.io/rev": "overridden-rev"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "unlabeled", Name: "pod-label", Labels: map[string]string{"istio.io/rev": "pod-rev"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "unlabeled", Name: "injected", Annotations: map[string]string{"istio.io/rev": "injected-rev"}}},
+		succeededPod("unlabeled", "succeeded", map[string]string{"istio.io/rev": "succeeded-rev"}, nil),
+	)
+
+	testCases := []struct {
+		name     string
+		expected *types.NamespacedName
+	}{
+		{name: "succeeded-rev"},
+		{name: "ns-rev", expected: &types.NamespacedName{Name: "labeled"}},
+		{name: "default", expected: &types.NamespacedName{Name: "injection-enabled"}},
+		{name: "pod-rev", expected: &types.NamespacedName{Namespace: "unlabeled", Name: "pod-label"}},
+		{name: "overridden-rev"},
+		{name: "injected-rev"},
+		{name: "unknown"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			obj, err := FindInjectionReference(ctx, cl, cl, tc.name)
+			require.NoError(t, err)
+			if tc.expected == nil {
+				assert.Nil(t, obj)
+			} else {
+				require.NotNil(t, obj)
+				assert.Equal(t, *tc.expected, client.ObjectKeyFromObject(obj))
+			}
+		})
+	}
+}
+
+func TestFindInjectionReferenceAmbient(t *testing.T) {
+	ctx := context.Background()
+	cl := newIndexedClient(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ambient", Labels: map[string]string{"istio.io/dataplane-mode": "ambient"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "waypoint", Labels: map[string]string{
+			"istio.io/use-waypoint": "waypoint",
+			"istio.io/rev":          "waypoint-rev",
+		}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "unlabeled", Name: "ambient-pod", Labels: map[string]string{
+			"istio.io/dataplane-mode": "ambient",
+			"istio.io/rev":            "pod-rev",
+			"sidecar.istio.io/inject": "false",
+		}}},
+	)
+
+	testCases := []struct {
+		name     string
+		expected types.NamespacedName
+	}{
+		{name: "default", expected: types.NamespacedName{Name: "ambient"}},
+		{name: "waypoint-rev", expected: types.NamespacedName{Name: "waypoint"}},
+		{name: "pod-rev", expected: types.NamespacedName{Namespace: "unlabeled", Name: "ambient-pod"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			obj, err := FindInjectionReference(ctx, cl, cl, tc.name)
+			require.NoError(t, err)
+			require.NotNil(t, obj)
+			assert.Equal(t, tc.expected, client.ObjectKeyFromObject(obj))
+		})
+	}
+}
+
+func TestNamespaceReferences(t *testing.T) {
+	ctx := context.Background()
+	labeled := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "labeled", Labels: map[string]string{"istio.io/rev": "ns-rev"}}}
+	unlabeled := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled"}}
+	cl := newIndexedClient(
+		labeled, unlabeled,
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "labeled", Name: "pod", Labels: map[string]string{"istio.io/rev": "pod-rev"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "unlabeled", Name: "pod-1", Labels: map[string]string{"istio.io/rev": "pod-rev"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "unlabeled", Name: "pod-2", Labels: map[string]string{"istio.io/rev": "pod-rev"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "unlabeled", Name: "pod-3", Annotations: map[string]string{"istio.io/rev": "injected-rev"}}},
+	)
+
+	names, err := NamespaceReferences(ctx, cl, labeled)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ns-rev"}, names)
+
+	names, err = NamespaceReferences(ctx, cl, unlabeled)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pod-rev"}, names)
+}
+
+func TestPodReferences(t *testing.T) {
+	ctx := context.Background()
+	cl := newIndexedClient(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "labeled", Labels: map[string]string{"istio.io/rev": "ns-rev"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled"}},
+	)
+	pod := func(namespace string, labelRev, injectedRev string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   namespace,
+			Name:        "pod",
+			Labels:      map[string]string{"istio.io/rev": labelRev},
+			Annotations: map[string]string{"istio.io/rev": injectedRev},
+		}}
+	}
+
+	testCases := []struct {
+		name          string
+		pod           *corev1.Pod
+		expected      []string
+		expectedLabel []string
+	}{
+		{
+			name:          "namespace label takes precedence",
+			pod:           pod("labeled", "pod-rev", "old-rev"),
+			expected:      []string{"ns-rev", "old-rev"},
+			expectedLabel: []string{"ns-rev"},
+		},
+		{
+			name:          "pod label",
+			pod:           pod("unlabeled", "pod-rev", "pod-rev"),
+			expected:      []string{"pod-rev"},
+			expectedLabel: []string{"pod-rev"},
+		},
+		{
+			name:     "injected only",
+			pod:      pod("unlabeled", "", "old-rev"),
+			expected: []string{"old-rev"},
+		},
+		{
+			name:     "namespace deleted",
+			pod:      pod("deleted", "pod-rev", "old-rev"),
+			expected: []string{"old-rev"},
+		},
+		{
+			name: "completed Job",
+			pod:  jobPod("labeled", "pod", true, map[string]string{"istio.io/rev": "old-rev"}),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			names, err := PodReferences(ctx, cl, tc.pod)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, names)
+
+			names, err = PodLabelReferences(ctx, cl, tc.pod)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedLabel, names)
+		})
+	}
+}
+
+func TestEnqueueReferenceChanges(t *testing.T) {
+	h := EnqueueReferenceChanges(func(_ context.Context, obj client.Object) []string {
+		return nonEmpty(podLabelReference(obj), podInjectedRevision(obj))
+	})
+	pod := func(labelRev, injectedRev string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "ns",
+			Name:        "pod",
+			Labels:      map[string]string{"istio.io/rev": labelRev},
+			Annotations: map[string]string{"istio.io/rev": injectedRev},
+		}}
+	}
+	requests := func(q workqueue.TypedRateLimitingInterface[reconcile.Request]) []string {
+		var names []string
+		for q.Len() > 0 {
+			req, _ := q.Get()
+			names = append(names, req.Name)
+			q.Done(req)
+		}
+		return names
+	}
+	newQueue := func() workqueue.TypedRateLimitingInterface[reconcile.Request] {
+		return workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+	}
+	ctx := context.Background()
+
+	q := newQueue()
+	h.Create(ctx, event.CreateEvent{Object: pod("rev-a", "rev-b")}, q)
+	assert.ElementsMatch(t, []string{"rev-a", "rev-b"}, requests(q))
+
+	q = newQueue()
+	h.Update(ctx, event.UpdateEvent{ObjectOld: pod("rev-a", "rev-b"), ObjectNew: pod("rev-a", "rev-b")}, q)
+	assert.Empty(t, requests(q), "nothing should be enqueued if the references didn't change")
+
+	q = newQueue()
+	h.Update(ctx, event.UpdateEvent{ObjectOld: pod("rev-a", "rev-b"), ObjectNew: pod("rev-a", "rev-c")}, q)
+	assert.ElementsMatch(t, []string{"rev-b", "rev-c"}, requests(q))
+
+	q = newQueue()
+	h.Delete(ctx, event.DeleteEvent{Object: pod("rev-a", "")}, q)
+	assert.Equal(t, []string{"rev-a"}, requests(q))
+}
@@ -0,0 +1,93 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// maxInventoryNamespaceNames is the maximum number of namespace names listed in a WorkloadInventory.
+const maxInventoryNamespaceNames = 10
+
+// GetWorkloadInventory counts the namespaces and pods that reference the revision or revision tag with the given
+// name, using the indexes returned by UsageIndexes. A pod references the name if the labels of its namespace or,
+// if those don't select any revision, its own labels select it. Referencing pods injected by the given revision
+// are counted as injected, those injected by another revision as pending restart. If name is the name of the
+// revision itself, all pods injected by it are counted as injected, regardless of their labels.
+func GetWorkloadInventory(ctx context.Context, cl client.Reader, name, revisionName string) (*v1.WorkloadInventory, error) {
+	inventory := &v1.WorkloadInventory{}
+
+	nsList := namespaceMetadataList()
+	if err := cl.List(ctx, nsList, client.MatchingFields{namespaceRevisionIndex: name}, client.UnsafeDisableDeepCopy); err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	namespaces := make([]string, 0, len(nsList.Items))
+	for _, ns := range nsList.Items {
+		namespaces = append(namespaces, ns.Name)
+	}
+	sort.Strings(namespaces)
+	inventory.Namespaces = int32(len(namespaces))
+	if len(namespaces) > 0 {
+		inventory.NamespaceNames = namespaces[:min(len(namespaces), maxInventoryNamespaceNames)]
+	}
+
+	countPod := func(pod *metav1.PartialObjectMetadata) {
+		switch injected := podInjectedRevision(pod); injected {
+		case "":
+			// the pod was never injected, e.g. because it opted out through the sidecar.istio.io/inject annotation
+		case revisionName:
+			inventory.InjectedPods++
+		default:
+			inventory.PendingRestartPods++
+		}
+	}
+
+	// the pods in the referencing namespaces reference the name regardless of their own labels
+	for _, ns := range namespaces {
+		podList := podMetadataList()
+		if err := cl.List(ctx, podList, client.InNamespace(ns), client.UnsafeDisableDeepCopy); err != nil {
+			return nil, fmt.Errorf("failed to list pods in namespace %s: %w", ns, err)
+		}
+		for i := range podList.Items {
+			if !podTerminated(&podList.Items[i]) {
+				countPod(&podList.Items[i])
+			}
+		}
+	}
+
+	// the labels of the other pods only count if their namespace doesn't select any revision
+	err := forEachPodLabelReference(ctx, cl, name, func(pod *metav1.PartialObjectMetadata) bool {
+		countPod(pod)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if name == revisionName {
+		injectedList := podMetadataList()
+		if err := cl.List(ctx, injectedList, client.MatchingFields{podInjectedRevisionIndex: revisionName}, client.UnsafeDisableDeepCopy); err != nil {
+			return nil, fmt.Errorf("failed to list pods: %w", err)
+		}
+		inventory.InjectedPods = int32(len(injectedList.Items))
+	}
+	return inventory, nil
+}
@@ -0,0 +1,103 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestGetWorkloadInventory(t *testing.T) {
+	pod := func(namespace, name string, labels, annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels, Annotations: annotations}}
+	}
+	rev := func(name string) map[string]string {
+		return map[string]string{"istio.io/rev": name}
+	}
+
+	objs := []client.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns-new", Labels: rev("new")}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns-tag", Labels: rev("prod")}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns-old", Labels: rev("old")}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled"}},
+
+		// namespace references the new revision
+		pod("ns-new", "injected", nil, rev("new")),
+		pod("ns-new", "pending", nil, rev("old")),
+		pod("ns-new", "not-injected", nil, nil),
+		jobPod("ns-new", "completed-job", true, rev("old")),
+
+		// namespace references the tag that points to the new revision
+		pod("ns-tag", "tag-injected", nil, rev("new")),
+		pod("ns-tag", "tag-pending", nil, rev("old")),
+
+		// pod label references the new revision, but the namespace label takes precedence
+		pod("ns-old", "overridden", rev("new"), rev("old")),
+
+		// pod labels reference the revision or tag
+		pod("unlabeled", "pod-label-injected", rev("new"), rev("new")),
+		pod("unlabeled", "pod-label-pending", rev("new"), rev("old")),
+		pod("unlabeled", "pod-label-tag", rev("prod"), rev("old")),
+	}
+	cl := newIndexedClient(objs...)
+
+	inventory, err := GetWorkloadInventory(context.Background(), cl, "new", "new")
+	require.NoError(t, err)
+	assert.Equal(t, &v1.WorkloadInventory{
+		Namespaces:     1,
+		NamespaceNames: []string{"ns-new"},
+		// all pods injected by the revision, including those that reference the tag
+		InjectedPods:       3,
+		PendingRestartPods: 2,
+	}, inventory)
+
+	inventory, err = GetWorkloadInventory(context.Background(), cl, "prod", "new")
+	require.NoError(t, err)
+	assert.Equal(t, &v1.WorkloadInventory{
+		Namespaces:         1,
+		NamespaceNames:     []string{"ns-tag"},
+		InjectedPods:       1,
+		PendingRestartPods: 2,
+	}, inventory)
+
+	inventory, err = GetWorkloadInventory(context.Background(), cl, "unused", "unused")
+	require.NoError(t, err)
+	assert.Equal(t, &v1.WorkloadInventory{}, inventory)
+}
+
+func TestGetWorkloadInventoryLimitsNamespaceNames(t *testing.T) {
+	var objs []client.Object
+	for i := range 15 {
+		objs = append(objs, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:   fmt.Sprintf("ns-%02d", i),
+			Labels: map[string]string{"istio-injection": "enabled"},
+		}})
+	}
+	cl := newIndexedClient(objs...)
+
+	inventory, err := GetWorkloadInventory(context.Background(), cl, v1.DefaultRevision, v1.DefaultRevision)
+	require.NoError(t, err)
+	assert.Equal(t, int32(15), inventory.Namespaces)
+	assert.Equal(t, []string{"ns-00", "ns-01", "ns-02", "ns-03", "ns-04", "ns-05", "ns-06", "ns-07", "ns-08", "ns-09"},
+		inventory.NamespaceNames)
+}
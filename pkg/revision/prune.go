@@ -17,6 +17,7 @@ package revision
 import (
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
 	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
@@ -28,8 +29,11 @@ import (
 )
 
 // PruneInactive deletes IstioRevisions owned by the specified owner that are
-// not in use and whose grace period has expired.
-func PruneInactive(ctx context.Context, cl client.Client, ownerUID types.UID, activeRevisionName string, gracePeriod time.Duration) (ctrl.Result, error) {
+// not in use and whose grace period has expired. The active revision and the
+// retained revisions (e.g. a canary revision that is about to be promoted) are never deleted.
+func PruneInactive(
+	ctx context.Context, cl client.Client, ownerUID types.UID, activeRevisionName string, gracePeriod time.Duration, retainedRevisionNames ...string,
+) (ctrl.Result, error) {
 	log := logf.FromContext(ctx)
 	revisions, err := ListOwned(ctx, cl, ownerUID)
 	if err != nil {
@@ -45,6 +49,10 @@ func PruneInactive(ctx context.Context, cl client.Client, ownerUID types.UID, ac
 			log.V(2).Info("IstioRevision is the active revision", "IstioRevision", rev.Name)
 			continue
 		}
+		if slices.Contains(retainedRevisionNames, rev.Name) {
+			log.V(2).Info("IstioRevision is retained", "IstioRevision", rev.Name)
+			continue
+		}
 		inUseCondition := rev.Status.GetCondition(v1.IstioRevisionConditionInUse)
 
 		// Only prune revisions that are confirmed to be not in use (i.e., ConditionFalse).
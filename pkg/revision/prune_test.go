@@ -77,6 +77,7 @@ func TestPruneInactive(t *testing.T) {
 		expectDeletion        bool
 		expectRequeueAfterAge *time.Duration
 		additionalRevisions   []additionalRevision
+		retainedRevisionNames []string
 	}{
 		{
 			name:               "preserves active IstioRevision even if not in use",
@@ -86,6 +87,15 @@ func TestPruneInactive(t *testing.T) {
 			inUseTransitionAge: time.Minute,
 			expectDeletion:     false,
 		},
+		{
+			name:                  "preserves retained IstioRevision even if not in use",
+			revName:               istioName + "-canary",
+			ownerReference:        ownedByIstio,
+			inUseCondition:        &inUseFalse,
+			inUseTransitionAge:    time.Minute,
+			retainedRevisionNames: []string{istioName + "-canary"},
+			expectDeletion:        false,
+		},
 		{
 			name:           "preserves non-active IstioRevision that's in use",
 			revName:        istioName + "-non-active",
@@ -205,7 +215,7 @@ func TestPruneInactive(t *testing.T) {
 
 			cl := newFakeClientBuilder().WithObjects(initObjs...).Build()
 
-			result, err := PruneInactive(ctx, cl, istio.UID, istioName, gracePeriod)
+			result, err := PruneInactive(ctx, cl, istio.UID, istioName, gracePeriod, tc.retainedRevisionNames...)
 			if err != nil {
 				t.Errorf("Expected no error, but got: %v", err)
 			}
@@ -244,7 +254,11 @@ func abs(duration time.Duration) time.Duration {
 }
 
 func newFakeClientBuilder() *fake.ClientBuilder {
-	return fake.NewClientBuilder().
+	b := fake.NewClientBuilder().
 		WithScheme(scheme.Scheme).
 		WithStatusSubresource(&v1.Istio{})
+	for _, index := range UsageIndexes() {
+		b = b.WithIndex(index.Object, index.Field, index.Extract)
+	}
+	return b
 }
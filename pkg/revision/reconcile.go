@@ -28,7 +28,8 @@ import (
 
 func CreateOrUpdate(
 	ctx context.Context, cl client.Client, revName string, version string, namespace string,
-	values *v1.Values, ownerRef metav1.OwnerReference,
+	values *v1.Values, driftPolicy *v1.DriftPolicy, patches []v1.Patch, dependencies *v1.Dependencies,
+	ownerRef metav1.OwnerReference,
 ) error {
 	log := logf.FromContext(ctx)
 	log = log.WithValues("IstioRevision", revName)
@@ -42,6 +43,9 @@ func CreateOrUpdate(
 		// update
 		rev.Spec.Version = version
 		rev.Spec.Values = values
+		rev.Spec.DriftPolicy = driftPolicy
+		rev.Spec.Patches = patches
+		rev.Spec.Dependencies = dependencies
 		log.Info("Updating IstioRevision")
 		if err = cl.Update(ctx, &rev); err != nil {
 			return fmt.Errorf("failed to update IstioRevision %q: %w", rev.Name, err)
@@ -54,9 +58,12 @@ func CreateOrUpdate(
 				OwnerReferences: []metav1.OwnerReference{ownerRef},
 			},
 			Spec: v1.IstioRevisionSpec{
-				Version:   version,
-				Namespace: namespace,
-				Values:    values,
+				Version:      version,
+				Namespace:    namespace,
+				Values:       values,
+				DriftPolicy:  driftPolicy,
+				Patches:      patches,
+				Dependencies: dependencies,
 			},
 		}
 		log.Info("Creating IstioRevision")
@@ -37,6 +37,7 @@ func TestReconcileActiveRevision(t *testing.T) {
 		name                 string
 		istioValues          v1.Values
 		revValues            *v1.Values
+		dependencies         *v1.Dependencies
 		expectOwnerReference bool
 	}{
 		{
@@ -66,6 +67,7 @@ func TestReconcileActiveRevision(t *testing.T) {
 					Image: ptr.Of("old-image"),
 				},
 			},
+			dependencies:         &v1.Dependencies{IstioCNI: "cni-a", ZTunnel: "ztunnel-a"},
 			expectOwnerReference: false,
 		},
 	}
@@ -98,7 +100,7 @@ func TestReconcileActiveRevision(t *testing.T) {
 				Controller:         ptr.Of(true),
 				BlockOwnerDeletion: ptr.Of(true),
 			}
-			err := CreateOrUpdate(ctx, cl, "my-revision", version, "istio-system", &tc.istioValues, ownerRef)
+			err := CreateOrUpdate(ctx, cl, "my-revision", version, "istio-system", &tc.istioValues, nil, nil, tc.dependencies, ownerRef)
 			if err != nil {
 				t.Errorf("Expected no error, but got: %v", err)
 			}
@@ -122,6 +124,10 @@ func TestReconcileActiveRevision(t *testing.T) {
 			if diff := cmp.Diff(helm.FromValues(&tc.istioValues), helm.FromValues(rev.Spec.Values)); diff != "" {
 				t.Errorf("IstioRevision.spec.values don't match Istio.spec.values; diff (-expected, +actual):\n%v", diff)
 			}
+
+			if diff := cmp.Diff(tc.dependencies, rev.Spec.Dependencies); diff != "" {
+				t.Errorf("IstioRevision.spec.dependencies don't match Istio.spec.dependencies; diff (-expected, +actual):\n%v", diff)
+			}
 		})
 	}
 }
@@ -33,6 +33,19 @@ func GetReferencedRevisionFromNamespace(labels map[string]string) string {
 	// TODO: if .Values.sidecarInjectorWebhook.enableNamespacesByDefault is true, then all namespaces except system namespaces should use the "default" revision
 }
 
+// GetAmbientRevisionFromNamespace returns the revision or revision tag that serves the namespace in ambient mode,
+// i.e. the one specified in its istio.io/rev label or the default revision, if the namespace is enrolled in the
+// ambient mesh or uses a waypoint. Otherwise, it returns an empty string.
+func GetAmbientRevisionFromNamespace(labels map[string]string) string {
+	if labels[constants.IstioDataplaneModeLabel] != constants.IstioDataplaneModeAmbient && labels[constants.IstioUseWaypointLabel] == "" {
+		return ""
+	}
+	if rev := labels[constants.IstioRevLabel]; rev != "" {
+		return rev
+	}
+	return v1.DefaultRevision
+}
+
 func GetReferencedRevisionFromPod(podLabels map[string]string) string {
 	// we only look at pod labels to identify injection intent
 	if podLabels[constants.IstioSidecarInjectLabel] != "false" {
@@ -47,6 +60,18 @@ func GetReferencedRevisionFromPod(podLabels map[string]string) string {
 	return ""
 }
 
+// GetAmbientRevisionFromPod returns the revision or revision tag that serves the pod in ambient mode if the pod
+// itself is enrolled in the ambient mesh. Otherwise, it returns an empty string.
+func GetAmbientRevisionFromPod(podLabels map[string]string) string {
+	if podLabels[constants.IstioDataplaneModeLabel] != constants.IstioDataplaneModeAmbient {
+		return ""
+	}
+	if rev := podLabels[constants.IstioRevLabel]; rev != "" {
+		return rev
+	}
+	return v1.DefaultRevision
+}
+
 func GetInjectedRevisionFromPod(podAnnotations map[string]string) string {
 	// if pod was already injected, the revision that did the injection is specified in the istio.io/rev annotation
 	return podAnnotations[constants.IstioRevLabel]
@@ -231,3 +231,103 @@ func TestGetIstioRevisionFromTargetReference(t *testing.T) {
 		})
 	}
 }
+
+func TestGetAmbientRevisionFromNamespace(t *testing.T) {
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		expected string
+	}{
+		{
+			name:     "no-labels",
+			labels:   map[string]string{},
+			expected: "",
+		},
+		{
+			name: "rev-label-only",
+			labels: map[string]string{
+				"istio.io/rev": "my-revision",
+			},
+			expected: "",
+		},
+		{
+			name: "ambient",
+			labels: map[string]string{
+				"istio.io/dataplane-mode": "ambient",
+			},
+			expected: "default",
+		},
+		{
+			name: "ambient-with-rev-label",
+			labels: map[string]string{
+				"istio.io/dataplane-mode": "ambient",
+				"istio.io/rev":            "my-revision",
+			},
+			expected: "my-revision",
+		},
+		{
+			name: "dataplane-mode-none",
+			labels: map[string]string{
+				"istio.io/dataplane-mode": "none",
+			},
+			expected: "",
+		},
+		{
+			name: "use-waypoint",
+			labels: map[string]string{
+				"istio.io/use-waypoint": "waypoint",
+				"istio.io/rev":          "my-revision",
+			},
+			expected: "my-revision",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := GetAmbientRevisionFromNamespace(tt.labels)
+			assert.Equalf(t, tt.expected, result, "GetAmbientRevisionFromNamespace(%v)", tt.labels)
+		})
+	}
+}
+
+func TestGetAmbientRevisionFromPod(t *testing.T) {
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		expected string
+	}{
+		{
+			name:     "no-labels",
+			labels:   map[string]string{},
+			expected: "",
+		},
+		{
+			name: "ambient",
+			labels: map[string]string{
+				"istio.io/dataplane-mode": "ambient",
+			},
+			expected: "default",
+		},
+		{
+			name: "ambient-with-rev-label",
+			labels: map[string]string{
+				"istio.io/dataplane-mode": "ambient",
+				"istio.io/rev":            "my-revision",
+			},
+			expected: "my-revision",
+		},
+		{
+			name: "dataplane-mode-none",
+			labels: map[string]string{
+				"istio.io/dataplane-mode": "none",
+				"istio.io/rev":            "my-revision",
+			},
+			expected: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := GetAmbientRevisionFromPod(tt.labels)
+			assert.Equalf(t, tt.expected, result, "GetAmbientRevisionFromPod(%v)", tt.labels)
+		})
+	}
+}
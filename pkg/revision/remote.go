@@ -14,12 +14,32 @@
 
 package revision
 
-import v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+import (
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/config"
+)
 
 // IsUsingRemoteControlPlane returns true if the IstioRevision is configured to
-// connect to a remote rather than deploy a local control plane.
-func IsUsingRemoteControlPlane(rev *v1.IstioRevision) bool {
-	// TODO: we should use values.istiodRemote.enabled instead of the profile, but we can't get the final set of values because of new profiles implementation
-	values := rev.Spec.Values
-	return values != nil && values.Profile != nil && *values.Profile == "remote"
+// connect to a remote rather than deploy a local control plane. The decision is
+// based on the computed values of the revision, so istiodRemote.enabled is honored
+// regardless of whether it's set in spec.values, a profile or the vendor defaults.
+// If the values can't be computed, spec.values is used as is.
+func IsUsingRemoteControlPlane(rev *v1.IstioRevision, cfg config.ReconcilerConfig) bool {
+	values, err := GetComputedValues(rev, cfg)
+	if err != nil || values == nil {
+		values = rev.Spec.Values
+	}
+	return isRemote(values)
+}
+
+func isRemote(values *v1.Values) bool {
+	if values == nil {
+		return false
+	}
+	// the remote profile in the istiod chart enables istiodRemote, but the profile
+	// is only applied by the chart itself, so it's not part of the computed values
+	if values.IstiodRemote != nil && values.IstiodRemote.Enabled != nil {
+		return *values.IstiodRemote.Enabled
+	}
+	return values.Profile != nil && *values.Profile == "remote"
 }
@@ -0,0 +1,195 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/chartsource"
+	"github.com/istio-ecosystem/sail-operator/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestIsUsingRemoteControlPlane(t *testing.T) {
+	// simulates a profile or vendor default that enables istiodRemote for the "external" profile
+	computeValues := func(
+		values *v1.Values, _, version string, _ config.Platform, _, _ string, _ fs.FS, _ string, _ *config.TLSConfig,
+	) (*v1.Values, error) {
+		if version == "invalid" {
+			return nil, errors.New("invalid version")
+		}
+		values = values.DeepCopy()
+		if values == nil {
+			values = &v1.Values{}
+		}
+		if values.Profile != nil && *values.Profile == "external" && values.IstiodRemote == nil {
+			values.IstiodRemote = &v1.IstiodRemoteConfig{Enabled: ptr.Of(true)}
+		}
+		return values, nil
+	}
+	defer func(orig computeValuesFunc) { defaultComputeValues = orig }(defaultComputeValues)
+	defaultComputeValues = computeValues
+
+	tests := []struct {
+		name     string
+		version  string
+		values   *v1.Values
+		expected bool
+	}{
+		{
+			name:     "nil values",
+			expected: false,
+		},
+		{
+			name:     "default profile",
+			values:   &v1.Values{Profile: ptr.Of("default")},
+			expected: false,
+		},
+		{
+			name:     "remote profile",
+			values:   &v1.Values{Profile: ptr.Of("remote")},
+			expected: true,
+		},
+		{
+			name:     "istiodRemote enabled in spec.values",
+			values:   &v1.Values{IstiodRemote: &v1.IstiodRemoteConfig{Enabled: ptr.Of(true)}},
+			expected: true,
+		},
+		{
+			name:     "istiodRemote enabled in computed values",
+			values:   &v1.Values{Profile: ptr.Of("external")},
+			expected: true,
+		},
+		{
+			name: "istiodRemote explicitly disabled",
+			values: &v1.Values{
+				Profile:      ptr.Of("external"),
+				IstiodRemote: &v1.IstiodRemoteConfig{Enabled: ptr.Of(false)},
+			},
+			expected: false,
+		},
+		{
+			name:     "values can't be computed",
+			version:  "invalid",
+			values:   &v1.Values{Profile: ptr.Of("remote")},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rev := &v1.IstioRevision{
+				Spec: v1.IstioRevisionSpec{
+					Version: tt.version,
+					Values:  tt.values,
+				},
+			}
+			assert.Equal(t, tt.expected, IsUsingRemoteControlPlane(rev, config.ReconcilerConfig{}))
+		})
+	}
+}
+
+func TestGetComputedValuesIsCachedPerGeneration(t *testing.T) {
+	calls := 0
+	computeValues := func(
+		values *v1.Values, _, _ string, _ config.Platform, _, _ string, _ fs.FS, _ string, _ *config.TLSConfig,
+	) (*v1.Values, error) {
+		calls++
+		return values.DeepCopy(), nil
+	}
+	defer func(orig computeValuesFunc) { defaultComputeValues = orig }(defaultComputeValues)
+	defaultComputeValues = computeValues
+
+	cfg := config.ReconcilerConfig{Platform: config.PlatformKubernetes, DefaultProfile: "default"}
+	rev := &v1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", UID: "uid-1", Generation: 1},
+		Spec: v1.IstioRevisionSpec{
+			Version: "v1.30.0",
+			Values:  &v1.Values{Profile: ptr.Of("ambient")},
+		},
+	}
+	defer ForgetComputedValues(rev)
+
+	DependsOnIstioCNI(rev, cfg)
+	DependsOnZTunnel(rev, cfg)
+	IsUsingRemoteControlPlane(rev, cfg)
+	assert.Equal(t, 1, calls, "values should only be computed once per generation")
+
+	rev.Generation = 2
+	rev.Spec.Values.Profile = ptr.Of("remote")
+	assert.True(t, IsUsingRemoteControlPlane(rev, cfg))
+	assert.Equal(t, 2, calls, "values should be recomputed when the generation changes")
+
+	IsUsingRemoteControlPlane(rev, config.ReconcilerConfig{Platform: config.PlatformOpenShift, DefaultProfile: "default"})
+	assert.Equal(t, 3, calls, "values should be recomputed when the platform changes")
+
+	ForgetComputedValues(rev)
+	IsUsingRemoteControlPlane(rev, config.ReconcilerConfig{Platform: config.PlatformOpenShift, DefaultProfile: "default"})
+	assert.Equal(t, 4, calls, "values should be recomputed after they are forgotten")
+
+	rev.UID = ""
+	IsUsingRemoteControlPlane(rev, cfg)
+	IsUsingRemoteControlPlane(rev, cfg)
+	assert.Equal(t, 6, calls, "values of revisions without a UID should not be cached")
+}
+
+func TestGetComputedValuesTracksChartSourceContent(t *testing.T) {
+	// simulates a profile that selects the remote profile, so that the content of the profile determines the result
+	computeValues := func(
+		_ *v1.Values, _, version string, _ config.Platform, _, _ string, resourceFS fs.FS, _ string, _ *config.TLSConfig,
+	) (*v1.Values, error) {
+		data, err := fs.ReadFile(resourceFS, version+"/profiles/default.yaml")
+		if err != nil {
+			return nil, err
+		}
+		return &v1.Values{Profile: ptr.Of(strings.TrimSpace(string(data)))}, nil
+	}
+	defer func(orig computeValuesFunc) { defaultComputeValues = orig }(defaultComputeValues)
+	defaultComputeValues = computeValues
+
+	writeProfile := func(profile string) string {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "default.yaml"), []byte(profile+"\n"), 0o644))
+		return dir
+	}
+
+	registry := chartsource.NewRegistry(fstest.MapFS{})
+	t.Cleanup(func() { registry.Remove("test") })
+	require.NoError(t, registry.Set("test", []chartsource.Version{{Name: "v1.99.1", ProfilesDir: writeProfile("default")}}))
+
+	cfg := config.ReconcilerConfig{ResourceFS: registry, ChartSources: registry, DefaultProfile: "default"}
+	rev := &v1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", UID: "uid-1", Generation: 1},
+		Spec:       v1.IstioRevisionSpec{Version: "v1.99.1"},
+	}
+	defer ForgetComputedValues(rev)
+
+	assert.False(t, IsUsingRemoteControlPlane(rev, cfg))
+
+	// the source now provides a different profile for the same version, while the revision's generation is unchanged
+	require.NoError(t, registry.Set("test", []chartsource.Version{{Name: "v1.99.1", ProfilesDir: writeProfile("remote")}}))
+	assert.True(t, IsUsingRemoteControlPlane(rev, cfg), "values should be recomputed when the profile content changes")
+}
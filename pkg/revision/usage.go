@@ -0,0 +1,100 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// References lists the objects that make an IstioRevision in use.
+type References struct {
+	// Tags are the names of the IstioRevisionTags that point to the revision.
+	Tags []string
+
+	// Namespaces are the names of the namespaces whose injection labels select the revision or that are
+	// enrolled in the ambient mesh and served by it.
+	Namespaces []string
+
+	// Pods are the pods that were injected by the revision or whose labels select it.
+	Pods []types.NamespacedName
+
+	// EnabledByDefault is true if the revision is the default revision and injects all namespaces
+	// (values.sidecarInjectorWebhook.enableNamespacesByDefault).
+	EnabledByDefault bool
+}
+
+// InUse returns true if anything references the revision.
+func (r References) InUse() bool {
+	return len(r.Tags) > 0 || len(r.Namespaces) > 0 || len(r.Pods) > 0 || r.EnabledByDefault
+}
+
+// FindReferencesIn returns the tags, namespaces and pods from the given lists that reference the given revision.
+// It allows callers that don't have access to the operator's cache to list the objects once and inspect many
+// revisions.
+func FindReferencesIn(rev *v1.IstioRevision, tags []v1.IstioRevisionTag, namespaces []corev1.Namespace, pods []corev1.Pod) References {
+	refs := References{}
+	for _, tag := range tags {
+		if tag.Status.IstioRevision == rev.Name {
+			refs.Tags = append(refs.Tags, tag.Name)
+		}
+	}
+
+	nsMap := map[string]corev1.Namespace{}
+	for _, ns := range namespaces {
+		if NamespaceReferencesRevision(ns, rev.Name) {
+			refs.Namespaces = append(refs.Namespaces, ns.Name)
+		}
+		nsMap[ns.Name] = ns
+	}
+
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if ns, found := nsMap[pod.Namespace]; found && PodReferencesRevision(pod, ns, rev.Name) {
+			refs.Pods = append(refs.Pods, types.NamespacedName{Namespace: pod.Namespace, Name: pod.Name})
+		}
+	}
+
+	refs.EnabledByDefault = rev.Name == v1.DefaultRevision && InjectsAllNamespaces(rev)
+	return refs
+}
+
+// InjectsAllNamespaces returns true if values.sidecarInjectorWebhook.enableNamespacesByDefault is set in the
+// given revision.
+func InjectsAllNamespaces(rev *v1.IstioRevision) bool {
+	return rev.Spec.Values != nil &&
+		rev.Spec.Values.SidecarInjectorWebhook != nil &&
+		rev.Spec.Values.SidecarInjectorWebhook.EnableNamespacesByDefault != nil &&
+		*rev.Spec.Values.SidecarInjectorWebhook.EnableNamespacesByDefault
+}
+
+// NamespaceReferencesRevision returns true if the injection labels of the namespace select the given
+// revision or revision tag, or if the namespace is enrolled in the ambient mesh and served by it.
+func NamespaceReferencesRevision(ns corev1.Namespace, name string) bool {
+	return name == namespaceReference(&ns)
+}
+
+// PodReferencesRevision returns true if the pod was injected by the given revision, or if the labels of the
+// pod select the given revision or revision tag and the labels of its namespace don't select any revision.
+func PodReferencesRevision(pod corev1.Pod, ns corev1.Namespace, name string) bool {
+	if name == GetInjectedRevisionFromPod(pod.GetAnnotations()) {
+		return true
+	}
+	return namespaceReference(&ns) == "" &&
+		name == podLabelReference(&pod)
+}
@@ -0,0 +1,83 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	"testing"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestFindReferencesIn(t *testing.T) {
+	rev := &v1.IstioRevision{ObjectMeta: metav1.ObjectMeta{Name: "my-rev"}}
+	tags := []v1.IstioRevisionTag{
+		{ObjectMeta: metav1.ObjectMeta{Name: "prod"}, Status: v1.IstioRevisionTagStatus{IstioRevision: "my-rev"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "other"}, Status: v1.IstioRevisionTagStatus{IstioRevision: "other-rev"}},
+	}
+	namespaces := []corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "labeled", Labels: map[string]string{"istio.io/rev": "my-rev"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "other-rev", Labels: map[string]string{"istio.io/rev": "other-rev"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled"}},
+	}
+	pods := []corev1.Pod{
+		// injected by the revision
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "other-rev", Name: "injected", Annotations: map[string]string{"istio.io/rev": "my-rev"}}},
+		// the namespace label takes precedence over the pod label
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "other-rev", Name: "overridden", Labels: map[string]string{"istio.io/rev": "my-rev"}}},
+		{ObjectMeta: metav1.ObjectMeta{Namespace: "unlabeled", Name: "pod-label", Labels: map[string]string{"istio.io/rev": "my-rev"}}},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "unlabeled", Name: "completed", Labels: map[string]string{"istio.io/rev": "my-rev"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodSucceeded},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "other-rev", Name: "failed", Annotations: map[string]string{"istio.io/rev": "my-rev"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodFailed},
+		},
+	}
+
+	refs := FindReferencesIn(rev, tags, namespaces, pods)
+	assert.Equal(t, References{
+		Tags:       []string{"prod"},
+		Namespaces: []string{"labeled"},
+		Pods: []types.NamespacedName{
+			{Namespace: "other-rev", Name: "injected"},
+			{Namespace: "unlabeled", Name: "pod-label"},
+		},
+	}, refs)
+	assert.True(t, refs.InUse())
+}
+
+func TestFindReferencesInEnabledByDefault(t *testing.T) {
+	values := &v1.Values{SidecarInjectorWebhook: &v1.SidecarInjectorConfig{EnableNamespacesByDefault: ptr.Of(true)}}
+
+	refs := FindReferencesIn(&v1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: v1.DefaultRevision},
+		Spec:       v1.IstioRevisionSpec{Values: values},
+	}, nil, nil, nil)
+	assert.True(t, refs.EnabledByDefault)
+	assert.True(t, refs.InUse())
+
+	refs = FindReferencesIn(&v1.IstioRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-rev"},
+		Spec:       v1.IstioRevisionSpec{Values: values},
+	}, nil, nil, nil)
+	assert.False(t, refs.InUse())
+}
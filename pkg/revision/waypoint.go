@@ -0,0 +1,105 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GatewayGVK is the GroupVersionKind of the Gateway API's Gateway, which Istio uses to declare waypoint proxies.
+var GatewayGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "Gateway"}
+
+// IsGatewayAPIAvailable checks whether the Gateway CRD is installed in the cluster. Waypoint Gateways can only
+// be watched and looked up if it is.
+func IsGatewayAPIAvailable(mapper meta.RESTMapper) (bool, error) {
+	if _, err := mapper.RESTMapping(GatewayGVK.GroupKind(), GatewayGVK.Version); err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get REST mapping for %s: %w", GatewayGVK.Kind, err)
+	}
+	return true, nil
+}
+
+// Gateway returns an empty Gateway. Since the operator doesn't depend on the Gateway API types, Gateways are
+// handled as unstructured objects.
+func Gateway() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(GatewayGVK)
+	return obj
+}
+
+// FindWaypoint returns a waypoint Gateway that is served by the revision or revision tag with the given name,
+// or nil if there is none.
+func FindWaypoint(ctx context.Context, cl client.Reader, name string) (*unstructured.Unstructured, error) {
+	gwList := &unstructured.UnstructuredList{}
+	gwList.SetGroupVersionKind(GatewayGVK.GroupVersion().WithKind("GatewayList"))
+	if err := cl.List(ctx, gwList); err != nil {
+		return nil, fmt.Errorf("failed to list gateways: %w", err)
+	}
+	for i := range gwList.Items {
+		gw := &gwList.Items[i]
+		references, err := WaypointReferences(ctx, cl, gw)
+		if err != nil {
+			return nil, err
+		}
+		if len(references) > 0 && references[0] == name {
+			return gw, nil
+		}
+	}
+	return nil, nil
+}
+
+// WaypointReferences returns the name of the revision or revision tag that serves the given Gateway if it is a
+// waypoint, i.e. the one specified in the Gateway's istio.io/rev label or, if it doesn't have one, in the
+// label of its namespace, and otherwise the default revision.
+func WaypointReferences(ctx context.Context, cl client.Reader, gw client.Object) ([]string, error) {
+	if !isWaypoint(gw) {
+		return nil, nil
+	}
+	if rev := gw.GetLabels()[constants.IstioRevLabel]; rev != "" {
+		return []string{rev}, nil
+	}
+	ns := NamespaceMetadata()
+	if err := cl.Get(ctx, types.NamespacedName{Name: gw.GetNamespace()}, ns); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get namespace %s: %w", gw.GetNamespace(), err)
+	}
+	if rev := ns.Labels[constants.IstioRevLabel]; rev != "" {
+		return []string{rev}, nil
+	}
+	return []string{v1.DefaultRevision}, nil
+}
+
+func isWaypoint(gw client.Object) bool {
+	u, ok := gw.(*unstructured.Unstructured)
+	if !ok {
+		return false
+	}
+	className, _, _ := unstructured.NestedString(u.Object, "spec", "gatewayClassName")
+	return className == constants.IstioWaypointGatewayClassName
+}
@@ -0,0 +1,110 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	"context"
+	"testing"
+
+	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func gatewayRESTMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("Namespace"), meta.RESTScopeRoot)
+	mapper.Add(GatewayGVK, meta.RESTScopeNamespace)
+	return mapper
+}
+
+func newGateway(namespace, name, className string, labels map[string]string) *unstructured.Unstructured {
+	gw := Gateway()
+	gw.SetNamespace(namespace)
+	gw.SetName(name)
+	gw.SetLabels(labels)
+	_ = unstructured.SetNestedField(gw.Object, className, "spec", "gatewayClassName")
+	return gw
+}
+
+func TestIsGatewayAPIAvailable(t *testing.T) {
+	available, err := IsGatewayAPIAvailable(gatewayRESTMapper())
+	require.NoError(t, err)
+	assert.True(t, available)
+
+	available, err = IsGatewayAPIAvailable(meta.NewDefaultRESTMapper(nil))
+	require.NoError(t, err)
+	assert.False(t, available)
+}
+
+func TestFindWaypoint(t *testing.T) {
+	ctx := context.Background()
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRESTMapper(gatewayRESTMapper()).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "labeled", Labels: map[string]string{"istio.io/rev": "ns-rev"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled"}},
+		newGateway("labeled", "waypoint", "istio-waypoint", nil),
+		newGateway("labeled", "tagged-waypoint", "istio-waypoint", map[string]string{"istio.io/rev": "my-tag"}),
+		newGateway("unlabeled", "waypoint", "istio-waypoint", nil),
+		newGateway("unlabeled", "ingress", "istio", map[string]string{"istio.io/rev": "ingress-rev"}),
+	).Build()
+
+	testCases := []struct {
+		name     string
+		expected *types.NamespacedName
+	}{
+		{name: "ns-rev", expected: &types.NamespacedName{Namespace: "labeled", Name: "waypoint"}},
+		{name: "my-tag", expected: &types.NamespacedName{Namespace: "labeled", Name: "tagged-waypoint"}},
+		{name: "default", expected: &types.NamespacedName{Namespace: "unlabeled", Name: "waypoint"}},
+		{name: "ingress-rev"},
+		{name: "unknown"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gw, err := FindWaypoint(ctx, cl, tc.name)
+			require.NoError(t, err)
+			if tc.expected == nil {
+				assert.Nil(t, gw)
+			} else {
+				require.NotNil(t, gw)
+				assert.Equal(t, *tc.expected, client.ObjectKeyFromObject(gw))
+			}
+		})
+	}
+}
+
+func TestWaypointReferences(t *testing.T) {
+	ctx := context.Background()
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRESTMapper(gatewayRESTMapper()).Build()
+
+	// the Gateway's namespace doesn't exist, e.g. because it's being deleted
+	names, err := WaypointReferences(ctx, cl, newGateway("missing", "waypoint", "istio-waypoint", nil))
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	names, err = WaypointReferences(ctx, cl, newGateway("missing", "waypoint", "istio-waypoint", map[string]string{"istio.io/rev": "my-rev"}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"my-rev"}, names)
+
+	names, err = WaypointReferences(ctx, cl, newGateway("missing", "ingress", "istio", map[string]string{"istio.io/rev": "my-rev"}))
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
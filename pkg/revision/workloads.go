@@ -0,0 +1,523 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// maxConcurrentWorkloadRestarts limits how many workloads are rolled out at the same time when
+// workloads are moved to a new revision. Workloads are restarted one at a time, so that a broken
+// revision only ever affects a single workload.
+const maxConcurrentWorkloadRestarts = 1
+
+// workloadRestartTimeout is how long a restarted workload may take to move to the target revision. Workloads
+// that take longer are reported as failed and no longer prevent other workloads from being restarted. It
+// matches the default progress deadline of Deployments.
+const workloadRestartTimeout = 10 * time.Minute
+
+// WorkloadUpdateProgress reports the progress of moving workloads from the inactive
+// revisions of an Istio to its active revision.
+type WorkloadUpdateProgress struct {
+	// ActiveRevisionReady is false when the active revision doesn't exist yet or isn't Ready.
+	// Workloads are only moved once the active revision is ready to serve them.
+	ActiveRevisionReady bool
+
+	// UpdatedNamespaces is the number of namespaces that were relabeled during this update.
+	UpdatedNamespaces int
+
+	// PendingWorkloads is the number of workloads that still need to be restarted.
+	PendingWorkloads int
+
+	// RestartingWorkloads is the number of workloads that were restarted, but whose pods
+	// are still injected by an inactive revision.
+	RestartingWorkloads int
+
+	// UnmanagedPods is the number of pods that reference an inactive revision, but aren't
+	// controlled by a Deployment, StatefulSet or DaemonSet and must be restarted manually.
+	UnmanagedPods int
+
+	// SkippedWorkloads lists the workloads that aren't restarted, because their pods would be injected by
+	// a revision other than the target revision, e.g. because their namespace uses the istio-injection
+	// label and the default revision tag doesn't point to the target revision.
+	SkippedWorkloads []string
+
+	// FailedWorkloads lists the workloads whose restart didn't complete within the deadline or whose
+	// rollout reports that it exceeded its progress deadline.
+	FailedWorkloads []string
+}
+
+// Done returns true when no workload references an inactive revision anymore.
+func (p WorkloadUpdateProgress) Done() bool {
+	return p.ActiveRevisionReady && p.PendingWorkloads == 0 && p.RestartingWorkloads == 0 && p.UnmanagedPods == 0 &&
+		len(p.SkippedWorkloads) == 0 && len(p.FailedWorkloads) == 0
+}
+
+// UpdateWorkloads moves the namespaces and workloads that reference the inactive IstioRevisions
+// owned by the specified owner to the active revision. Namespaces are relabeled immediately,
+// whereas the Deployments, StatefulSets and DaemonSets whose pods were injected by an inactive
+// revision are restarted one after the other, in namespace and name order. Workloads that wouldn't move to
+// the target revision when restarted are skipped, and restarts that don't complete within
+// workloadRestartTimeout are reported as failed, so that they don't block the remaining workloads.
+//
+// If a canary is specified, the canary revision isn't considered inactive. Instead, the workloads
+// in the canary namespaces are moved to the canary revision once it is ready.
+//
+// Namespaces and pods are looked up through the indexes returned by UsageIndexes, and the workloads that
+// control the pods through metadata-only reads, so that the cache only holds the metadata of pods and
+// workloads. The phase of the pods and the full workloads are read through apiReader, which should read
+// from the API server, but only for the workloads that are restarted or whose restart is checked.
+func UpdateWorkloads(
+	ctx context.Context, cl client.Client, apiReader client.Reader, ownerUID types.UID, activeRevisionName string, canary *Canary,
+) (WorkloadUpdateProgress, error) {
+	log := logf.FromContext(ctx)
+	progress := WorkloadUpdateProgress{}
+
+	revisions, err := ListOwned(ctx, cl, ownerUID)
+	if err != nil {
+		return progress, fmt.Errorf("failed to get revisions: %w", err)
+	}
+
+	canaryReady := false
+	inactiveRevisions := map[string]bool{}
+	for _, rev := range revisions {
+		ready := rev.Status.GetCondition(v1.IstioRevisionConditionReady).Status == metav1.ConditionTrue
+		switch {
+		case rev.Name == activeRevisionName:
+			progress.ActiveRevisionReady = ready
+		case canary != nil && rev.Name == canary.RevisionName:
+			canaryReady = ready
+		default:
+			inactiveRevisions[rev.Name] = true
+		}
+	}
+	if !progress.ActiveRevisionReady {
+		log.V(2).Info("Active IstioRevision is not ready; not updating workloads", "IstioRevision", activeRevisionName)
+		return progress, nil
+	}
+
+	// the workloads in the canary namespaces are moved from the active revision to the canary revision,
+	// whereas all other workloads are moved from the inactive revisions to the active revision
+	canaryNamespaces := map[string]bool{}
+	canarySources := map[string]bool{activeRevisionName: true}
+	sourceRevisions := maps.Clone(inactiveRevisions)
+	if canary != nil {
+		for _, ns := range canary.Namespaces {
+			canaryNamespaces[ns] = true
+		}
+		for name := range inactiveRevisions {
+			canarySources[name] = true
+		}
+		sourceRevisions[activeRevisionName] = true
+	}
+	getMove := func(namespace string) (map[string]bool, string) {
+		if !canaryNamespaces[namespace] {
+			return inactiveRevisions, activeRevisionName
+		}
+		if !canaryReady {
+			return nil, ""
+		}
+		return canarySources, canary.RevisionName
+	}
+
+	if len(inactiveRevisions) == 0 && len(canaryNamespaces) == 0 {
+		return progress, nil
+	}
+
+	if progress.UpdatedNamespaces, err = updateNamespaces(ctx, cl, inactiveRevisions, activeRevisionName); err != nil {
+		return progress, err
+	}
+
+	moves, unmanagedPods, err := findWorkloadsToMove(ctx, cl, apiReader, sourceRevisions, getMove)
+	if err != nil {
+		return progress, err
+	}
+	progress.UnmanagedPods = unmanagedPods
+
+	// the pods of a workload are only checked for whether they're still running when the workload is about to
+	// be restarted or reported, since that requires reading them from the API server
+	var pending []workloadMove
+	for _, move := range moves {
+		revisionAfterRestart, err := getRevisionAfterRestart(ctx, cl, move)
+		if err != nil {
+			return progress, err
+		}
+		switch {
+		case revisionAfterRestart != "" && revisionAfterRestart != move.targetRevision:
+			running, err := hasRunningPod(ctx, apiReader, move)
+			if err != nil {
+				return progress, err
+			}
+			if running {
+				log.V(2).Info("Workload would not move to the new revision when restarted; skipping it",
+					"Workload", workloadName(move.workload), "IstioRevision", revisionAfterRestart)
+				progress.SkippedWorkloads = append(progress.SkippedWorkloads, workloadName(move.workload))
+			}
+		case move.workload.GetAnnotations()[constants.WorkloadRevisionAnnotationKey] != move.targetRevision:
+			pending = append(pending, move)
+		default:
+			failed, err := restartFailed(ctx, apiReader, move.workload)
+			if err != nil {
+				return progress, err
+			}
+			if !failed {
+				progress.RestartingWorkloads++
+				continue
+			}
+			running, err := hasRunningPod(ctx, apiReader, move)
+			if err != nil {
+				return progress, err
+			}
+			if running {
+				log.V(2).Info("Restarted workload did not move to the new revision in time", "Workload", workloadName(move.workload))
+				progress.FailedWorkloads = append(progress.FailedWorkloads, workloadName(move.workload))
+			}
+		}
+	}
+
+	for _, move := range pending {
+		if progress.RestartingWorkloads >= maxConcurrentWorkloadRestarts {
+			progress.PendingWorkloads++
+			continue
+		}
+		if running, err := hasRunningPod(ctx, apiReader, move); err != nil {
+			return progress, err
+		} else if !running {
+			continue
+		}
+		log.Info("Restarting workload to move it to a new revision",
+			"Kind", move.workload.GetObjectKind().GroupVersionKind().Kind, "Workload", client.ObjectKeyFromObject(move.workload),
+			"IstioRevision", move.targetRevision)
+		if err := restartWorkload(ctx, cl, apiReader, move); err != nil {
+			return progress, err
+		}
+		progress.RestartingWorkloads++
+	}
+	return progress, nil
+}
+
+// getRevisionAfterRestart returns the revision that would inject the pods of the workload if it was restarted,
+// or an empty string if it can't be determined from the labels of the workload's namespace and pods. The labels
+// of the pods stand in for those of the pod template, which isn't part of the workload's metadata.
+// Revision tags are resolved to the revision they point to. References to one of the source revisions through
+// the istio.io/rev label are moved to the target revision by updateNamespaces, MoveNamespacesToCanary and
+// restartWorkload, so they resolve to the target revision.
+func getRevisionAfterRestart(ctx context.Context, cl client.Client, move workloadMove) (string, error) {
+	ns := NamespaceMetadata()
+	if err := cl.Get(ctx, client.ObjectKey{Name: move.workload.GetNamespace()}, ns); client.IgnoreNotFound(err) != nil {
+		return "", fmt.Errorf("failed to get namespace %s: %w", move.workload.GetNamespace(), err)
+	}
+
+	labels := ns.Labels
+	ref := GetReferencedRevisionFromNamespace(labels)
+	if ref == "" {
+		// the pod's own labels are only considered if its namespace doesn't select a revision
+		labels = move.podLabels
+		ref = GetReferencedRevisionFromPod(labels)
+	}
+	if ref == "" {
+		return "", nil
+	}
+	if move.sourceRevisions[ref] && labels[constants.IstioRevLabel] == ref &&
+		labels[constants.IstioInjectionLabel] != constants.IstioInjectionEnabledValue {
+		return move.targetRevision, nil
+	}
+
+	tag := &v1.IstioRevisionTag{}
+	if err := cl.Get(ctx, client.ObjectKey{Name: ref}, tag); err != nil {
+		if apierrors.IsNotFound(err) {
+			return ref, nil
+		}
+		return "", fmt.Errorf("failed to get IstioRevisionTag %s: %w", ref, err)
+	}
+	return tag.Status.IstioRevision, nil
+}
+
+// restartFailed returns true if the restart of a workload to move it to a new revision was triggered longer than
+// workloadRestartTimeout ago or, for a Deployment, if its rollout exceeded its progress deadline. The status of
+// the Deployment is read through apiReader, since only the metadata of workloads is cached.
+func restartFailed(ctx context.Context, apiReader client.Reader, workload *metav1.PartialObjectMetadata) (bool, error) {
+	restartedAt, err := time.Parse(time.RFC3339, workload.Annotations[constants.WorkloadRestartedAtAnnotationKey])
+	if err == nil && time.Since(restartedAt) > workloadRestartTimeout {
+		return true, nil
+	}
+	if workload.GetObjectKind().GroupVersionKind().Kind != "Deployment" {
+		return false, nil
+	}
+	deployment := &appsv1.Deployment{}
+	if found, err := getWorkload(ctx, apiReader, workload, deployment); !found {
+		return false, err
+	}
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false, nil
+	}
+	for _, c := range deployment.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Status == corev1.ConditionFalse && c.Reason == "ProgressDeadlineExceeded" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func workloadName(workload client.Object) string {
+	return fmt.Sprintf("%s %s/%s", workload.GetObjectKind().GroupVersionKind().Kind, workload.GetNamespace(), workload.GetName())
+}
+
+// updateNamespaces relabels all namespaces that reference one of the inactive revisions
+// through the istio.io/rev label, so that they reference the active revision instead.
+func updateNamespaces(ctx context.Context, cl client.Client, inactiveRevisions map[string]bool, activeRevisionName string) (int, error) {
+	log := logf.FromContext(ctx)
+	updated := 0
+	for _, name := range slices.Sorted(maps.Keys(inactiveRevisions)) {
+		namespaces, err := ListNamespaceReferences(ctx, cl, name)
+		if err != nil {
+			return updated, err
+		}
+		for i := range namespaces {
+			ns := &namespaces[i]
+			// namespaces with the istio-injection label reference the default revision (or a tag with that
+			// name), which is not something an individual Istio revision switch should change
+			if ns.Labels[constants.IstioInjectionLabel] == constants.IstioInjectionEnabledValue ||
+				ns.Labels[constants.IstioRevLabel] != name {
+				continue
+			}
+
+			log.Info("Moving namespace to the active revision", "Namespace", ns.Name, "IstioRevision", activeRevisionName)
+			patch := client.MergeFrom(ns.DeepCopy())
+			ns.Labels[constants.IstioRevLabel] = activeRevisionName
+			if err := cl.Patch(ctx, ns, patch); err != nil {
+				return updated, fmt.Errorf("failed to update namespace %s: %w", ns.Name, err)
+			}
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// workloadMove is a workload whose pods need to be moved from one of the source revisions to the target revision.
+type workloadMove struct {
+	workload        *metav1.PartialObjectMetadata
+	pods            []types.NamespacedName
+	podLabels       map[string]string
+	sourceRevisions map[string]bool
+	targetRevision  string
+}
+
+// findWorkloadsToMove returns the workloads whose pods were injected by or reference one of the
+// source revisions returned by getMove for the pod's namespace, sorted by namespace and name. It also
+// returns the number of such pods that are still running, but aren't controlled by a workload the operator
+// knows how to restart. The candidate pods are looked up by the names of all revisions in revisionNames.
+func findWorkloadsToMove(
+	ctx context.Context, cl client.Client, apiReader client.Reader, revisionNames map[string]bool,
+	getMove func(namespace string) (sourceRevisions map[string]bool, targetRevision string),
+) ([]workloadMove, int, error) {
+	pods := map[types.NamespacedName]*metav1.PartialObjectMetadata{}
+	for name := range revisionNames {
+		for _, index := range []string{podInjectedRevisionIndex, podReferencedRevisionIndex} {
+			podList := podMetadataList()
+			if err := cl.List(ctx, podList, client.MatchingFields{index: name}); err != nil {
+				return nil, 0, fmt.Errorf("failed to list pods: %w", err)
+			}
+			for i := range podList.Items {
+				pods[client.ObjectKeyFromObject(&podList.Items[i])] = &podList.Items[i]
+			}
+		}
+	}
+	podKeys := slices.SortedFunc(maps.Keys(pods), func(a, b types.NamespacedName) int {
+		return strings.Compare(a.String(), b.String())
+	})
+
+	unmanagedPods := 0
+	moves := map[string]*workloadMove{}
+	for _, podKey := range podKeys {
+		pod := pods[podKey]
+		sourceRevisions, targetRevision := getMove(pod.Namespace)
+		if !sourceRevisions[GetInjectedRevisionFromPod(pod.GetAnnotations())] &&
+			!sourceRevisions[pod.Labels[constants.IstioRevLabel]] {
+			continue
+		}
+
+		workload, err := getPodWorkload(ctx, cl, pod)
+		if err != nil {
+			return nil, 0, err
+		}
+		if workload == nil {
+			if running, err := isPodRunning(ctx, apiReader, podKey); err != nil {
+				return nil, 0, err
+			} else if running {
+				unmanagedPods++
+			}
+			continue
+		}
+		key := fmt.Sprintf("%s/%s/%s", workload.GetNamespace(), workload.GetName(), workload.GetObjectKind().GroupVersionKind().Kind)
+		if move, found := moves[key]; found {
+			move.pods = append(move.pods, podKey)
+			continue
+		}
+		moves[key] = &workloadMove{
+			workload:        workload,
+			pods:            []types.NamespacedName{podKey},
+			podLabels:       pod.Labels,
+			sourceRevisions: sourceRevisions,
+			targetRevision:  targetRevision,
+		}
+	}
+
+	result := make([]workloadMove, 0, len(moves))
+	for _, key := range slices.Sorted(maps.Keys(moves)) {
+		result = append(result, *moves[key])
+	}
+	return result, unmanagedPods, nil
+}
+
+// hasRunningPod returns true if at least one of the workload's pods that reference a source revision is still
+// running. The pods are read through apiReader one after the other until a running pod is found, which is
+// usually the first one.
+func hasRunningPod(ctx context.Context, apiReader client.Reader, move workloadMove) (bool, error) {
+	for _, key := range move.pods {
+		if running, err := isPodRunning(ctx, apiReader, key); err != nil || running {
+			return running, err
+		}
+	}
+	return false, nil
+}
+
+// isPodRunning returns false if the pod doesn't exist anymore or has run to completion. The pod's phase
+// isn't part of its metadata, so the pod is read through the given reader.
+func isPodRunning(ctx context.Context, podReader client.Reader, key types.NamespacedName) (bool, error) {
+	pod := &corev1.Pod{}
+	if err := podReader.Get(ctx, key, pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get pod %s: %w", key, err)
+	}
+	return pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed, nil
+}
+
+// getPodWorkload returns the metadata of the Deployment, StatefulSet or DaemonSet that controls the given pod,
+// or nil if the pod isn't controlled by any of them. The owners are read as metadata only, so that the cache
+// doesn't hold the full ReplicaSets and workloads in the cluster.
+func getPodWorkload(ctx context.Context, cl client.Client, pod client.Object) (*metav1.PartialObjectMetadata, error) {
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil || owner.APIVersion != appsv1.SchemeGroupVersion.String() {
+		return nil, nil
+	}
+
+	switch owner.Kind {
+	case "ReplicaSet":
+		rs, err := getOwnerMetadata(ctx, cl, pod.GetNamespace(), *owner)
+		if rs == nil {
+			return nil, err
+		}
+		rsOwner := metav1.GetControllerOf(rs)
+		if rsOwner == nil || rsOwner.APIVersion != appsv1.SchemeGroupVersion.String() || rsOwner.Kind != "Deployment" {
+			return nil, nil
+		}
+		return getOwnerMetadata(ctx, cl, pod.GetNamespace(), *rsOwner)
+	case "StatefulSet", "DaemonSet":
+		return getOwnerMetadata(ctx, cl, pod.GetNamespace(), *owner)
+	}
+	return nil, nil
+}
+
+// getOwnerMetadata returns the metadata of the object that the owner reference points to, or nil if it doesn't exist.
+func getOwnerMetadata(ctx context.Context, cl client.Client, namespace string, owner metav1.OwnerReference) (*metav1.PartialObjectMetadata, error) {
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind(owner.Kind))
+	if err := cl.Get(ctx, types.NamespacedName{Namespace: namespace, Name: owner.Name}, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get %s %s/%s: %w", owner.Kind, namespace, owner.Name, err)
+	}
+	return obj, nil
+}
+
+// getWorkload reads the full workload with the given metadata through the given reader into obj. It returns
+// false if the workload no longer exists.
+func getWorkload(ctx context.Context, reader client.Reader, workload *metav1.PartialObjectMetadata, obj client.Object) (bool, error) {
+	if err := reader.Get(ctx, client.ObjectKeyFromObject(workload), obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get %s: %w", workloadName(workload), err)
+	}
+	return true, nil
+}
+
+func newWorkload(kind string) (client.Object, *corev1.PodTemplateSpec) {
+	switch kind {
+	case "Deployment":
+		w := &appsv1.Deployment{}
+		return w, &w.Spec.Template
+	case "StatefulSet":
+		w := &appsv1.StatefulSet{}
+		return w, &w.Spec.Template
+	case "DaemonSet":
+		w := &appsv1.DaemonSet{}
+		return w, &w.Spec.Template
+	}
+	panic(fmt.Sprintf("unsupported workload kind %s", kind))
+}
+
+// restartWorkload triggers a rollout of the workload in the same way as `kubectl rollout restart`. The workload
+// is read through apiReader, since its pod template isn't cached. If the pod template references one of the
+// source revisions through the istio.io/rev label, the label is updated to reference the target revision. The
+// target revision and the time of the restart are also recorded in the annotations of the workload itself, so
+// that the restart can be tracked through the workload's metadata.
+func restartWorkload(ctx context.Context, cl client.Client, apiReader client.Reader, move workloadMove) error {
+	workload, template := newWorkload(move.workload.GetObjectKind().GroupVersionKind().Kind)
+	if found, err := getWorkload(ctx, apiReader, move.workload, workload); !found {
+		return err
+	}
+
+	patch := client.MergeFrom(workload.DeepCopyObject().(client.Object))
+	if move.sourceRevisions[template.Labels[constants.IstioRevLabel]] {
+		template.Labels[constants.IstioRevLabel] = move.targetRevision
+	}
+	now := time.Now().Format(time.RFC3339)
+	if template.Annotations == nil {
+		template.Annotations = map[string]string{}
+	}
+	template.Annotations[constants.WorkloadRevisionAnnotationKey] = move.targetRevision
+	template.Annotations[constants.RestartedAtAnnotationKey] = now
+	annotations := workload.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[constants.WorkloadRevisionAnnotationKey] = move.targetRevision
+	annotations[constants.WorkloadRestartedAtAnnotationKey] = now
+	workload.SetAnnotations(annotations)
+	if err := cl.Patch(ctx, workload, patch); err != nil {
+		return fmt.Errorf("failed to restart %s: %w", workloadName(move.workload), err)
+	}
+	return nil
+}
@@ -0,0 +1,394 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revision
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/constants"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"istio.io/istio/pkg/ptr"
+)
+
+func TestUpdateWorkloads(t *testing.T) {
+	const (
+		istioName  = "my-istio"
+		istioUID   = "my-uid"
+		oldRevName = "my-istio-v1-0-0"
+		newRevName = "my-istio-v1-1-0"
+	)
+
+	ctx := context.Background()
+
+	ownedByIstio := metav1.OwnerReference{
+		APIVersion:         v1.GroupVersion.String(),
+		Kind:               v1.IstioKind,
+		Name:               istioName,
+		UID:                istioUID,
+		Controller:         ptr.Of(true),
+		BlockOwnerDeletion: ptr.Of(true),
+	}
+
+	newRevision := func(name string, ready bool) *v1.IstioRevision {
+		readyStatus := metav1.ConditionFalse
+		if ready {
+			readyStatus = metav1.ConditionTrue
+		}
+		return &v1.IstioRevision{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				OwnerReferences: []metav1.OwnerReference{ownedByIstio},
+			},
+			Status: v1.IstioRevisionStatus{
+				Conditions: []v1.StatusCondition{
+					{Type: v1.IstioRevisionConditionReady, Status: readyStatus},
+				},
+			},
+		}
+	}
+
+	newNamespace := func(name string, labels map[string]string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+
+	newDeployment := func(namespace, name string, templateLabels map[string]string) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, UID: types.UID("deploy-" + name)},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: templateLabels}},
+			},
+		}
+	}
+
+	newReplicaSet := func(deployment *appsv1.Deployment) *appsv1.ReplicaSet {
+		return &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       deployment.Namespace,
+				Name:            deployment.Name + "-abc",
+				UID:             types.UID("rs-" + deployment.Name),
+				OwnerReferences: []metav1.OwnerReference{controllerRef("Deployment", deployment.Name)},
+			},
+		}
+	}
+
+	newPod := func(namespace, name string, owner *metav1.OwnerReference, injectedRevision string) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   namespace,
+				Name:        name,
+				Annotations: map[string]string{constants.IstioRevLabel: injectedRevision},
+			},
+		}
+		if owner != nil {
+			pod.OwnerReferences = []metav1.OwnerReference{*owner}
+		}
+		return pod
+	}
+
+	t.Run("waits for active revision to be ready", func(t *testing.T) {
+		g := NewWithT(t)
+		ns := newNamespace("ns1", map[string]string{constants.IstioRevLabel: oldRevName})
+		cl := newFakeClientBuilder().
+			WithObjects(newRevision(oldRevName, true), newRevision(newRevName, false), ns).
+			Build()
+
+		progress, err := UpdateWorkloads(ctx, newCacheClient(t, cl), cl, istioUID, newRevName, nil)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(progress.ActiveRevisionReady).To(BeFalse())
+		g.Expect(progress.Done()).To(BeFalse())
+
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(ns), ns)).To(Succeed())
+		g.Expect(ns.Labels[constants.IstioRevLabel]).To(Equal(oldRevName))
+	})
+
+	t.Run("is done when there are no inactive revisions", func(t *testing.T) {
+		g := NewWithT(t)
+		cl := newFakeClientBuilder().WithObjects(newRevision(newRevName, true)).Build()
+
+		progress, err := UpdateWorkloads(ctx, newCacheClient(t, cl), cl, istioUID, newRevName, nil)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(progress.Done()).To(BeTrue())
+	})
+
+	t.Run("relabels namespaces referencing inactive revisions", func(t *testing.T) {
+		g := NewWithT(t)
+		oldNs := newNamespace("old", map[string]string{constants.IstioRevLabel: oldRevName})
+		injectionNs := newNamespace("injection", map[string]string{
+			constants.IstioRevLabel:       oldRevName,
+			constants.IstioInjectionLabel: constants.IstioInjectionEnabledValue,
+		})
+		otherNs := newNamespace("other", map[string]string{constants.IstioRevLabel: "other-revision"})
+		cl := newFakeClientBuilder().
+			WithObjects(newRevision(oldRevName, true), newRevision(newRevName, true), oldNs, injectionNs, otherNs).
+			Build()
+
+		progress, err := UpdateWorkloads(ctx, newCacheClient(t, cl), cl, istioUID, newRevName, nil)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(progress.UpdatedNamespaces).To(Equal(1))
+		g.Expect(progress.Done()).To(BeTrue())
+
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(oldNs), oldNs)).To(Succeed())
+		g.Expect(oldNs.Labels[constants.IstioRevLabel]).To(Equal(newRevName))
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(injectionNs), injectionNs)).To(Succeed())
+		g.Expect(injectionNs.Labels[constants.IstioRevLabel]).To(Equal(oldRevName))
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(otherNs), otherNs)).To(Succeed())
+		g.Expect(otherNs.Labels[constants.IstioRevLabel]).To(Equal("other-revision"))
+	})
+
+	t.Run("restarts workloads one at a time", func(t *testing.T) {
+		g := NewWithT(t)
+		deployA := newDeployment("ns1", "a", map[string]string{constants.IstioRevLabel: oldRevName})
+		deployB := newDeployment("ns1", "b", nil)
+		rsA := newReplicaSet(deployA)
+		rsB := newReplicaSet(deployB)
+		cl := newFakeClientBuilder().
+			WithObjects(
+				newRevision(oldRevName, true), newRevision(newRevName, true),
+				deployA, deployB, rsA, rsB,
+				newPod("ns1", "a-1", ptr.Of(controllerRef("ReplicaSet", rsA.Name)), oldRevName),
+				newPod("ns1", "a-2", ptr.Of(controllerRef("ReplicaSet", rsA.Name)), oldRevName),
+				newPod("ns1", "b-1", ptr.Of(controllerRef("ReplicaSet", rsB.Name)), oldRevName),
+				newPod("ns1", "c-1", ptr.Of(controllerRef("ReplicaSet", "c")), newRevName),
+			).
+			Build()
+
+		progress, err := UpdateWorkloads(ctx, newCacheClient(t, cl), cl, istioUID, newRevName, nil)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(progress).To(Equal(WorkloadUpdateProgress{ActiveRevisionReady: true, RestartingWorkloads: 1, PendingWorkloads: 1}))
+
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(deployA), deployA)).To(Succeed())
+		g.Expect(deployA.Spec.Template.Labels[constants.IstioRevLabel]).To(Equal(newRevName))
+		g.Expect(deployA.Spec.Template.Annotations[constants.WorkloadRevisionAnnotationKey]).To(Equal(newRevName))
+		g.Expect(deployA.Spec.Template.Annotations).To(HaveKey(constants.RestartedAtAnnotationKey))
+
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(deployB), deployB)).To(Succeed())
+		g.Expect(deployB.Spec.Template.Annotations).ToNot(HaveKey(constants.RestartedAtAnnotationKey))
+
+		// the rollout of the first deployment hasn't finished yet, so the second one must not be restarted
+		progress, err = UpdateWorkloads(ctx, newCacheClient(t, cl), cl, istioUID, newRevName, nil)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(progress).To(Equal(WorkloadUpdateProgress{ActiveRevisionReady: true, RestartingWorkloads: 1, PendingWorkloads: 1}))
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(deployB), deployB)).To(Succeed())
+		g.Expect(deployB.Spec.Template.Annotations).ToNot(HaveKey(constants.RestartedAtAnnotationKey))
+
+		// once the pods of the first deployment are replaced, the second one is restarted
+		g.Expect(cl.Delete(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "a-1"}})).To(Succeed())
+		g.Expect(cl.Delete(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "a-2"}})).To(Succeed())
+		progress, err = UpdateWorkloads(ctx, newCacheClient(t, cl), cl, istioUID, newRevName, nil)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(progress).To(Equal(WorkloadUpdateProgress{ActiveRevisionReady: true, RestartingWorkloads: 1}))
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(deployB), deployB)).To(Succeed())
+		g.Expect(deployB.Spec.Template.Annotations[constants.WorkloadRevisionAnnotationKey]).To(Equal(newRevName))
+	})
+
+	t.Run("restarts StatefulSets and DaemonSets", func(t *testing.T) {
+		g := NewWithT(t)
+		sts := &appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "sts"}}
+		ds := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Namespace: "ns1", Name: "ds"}}
+		cl := newFakeClientBuilder().
+			WithObjects(
+				newRevision(oldRevName, true), newRevision(newRevName, true), sts, ds,
+				newPod("ns1", "sts-0", ptr.Of(controllerRef("StatefulSet", sts.Name)), oldRevName),
+				newPod("ns1", "ds-1", ptr.Of(controllerRef("DaemonSet", ds.Name)), oldRevName),
+			).
+			Build()
+
+		progress, err := UpdateWorkloads(ctx, newCacheClient(t, cl), cl, istioUID, newRevName, nil)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(progress).To(Equal(WorkloadUpdateProgress{ActiveRevisionReady: true, RestartingWorkloads: 1, PendingWorkloads: 1}))
+
+		// workloads are restarted in name order, so the DaemonSet goes first
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(ds), ds)).To(Succeed())
+		g.Expect(ds.Spec.Template.Annotations[constants.WorkloadRevisionAnnotationKey]).To(Equal(newRevName))
+	})
+
+	t.Run("moves workloads in canary namespaces to the canary revision", func(t *testing.T) {
+		g := NewWithT(t)
+		canaryNs := newNamespace("canary", map[string]string{constants.IstioRevLabel: newRevName})
+		otherNs := newNamespace("other", map[string]string{constants.IstioRevLabel: oldRevName})
+		canaryDeploy := newDeployment("canary", "a", nil)
+		otherDeploy := newDeployment("other", "a", nil)
+		canaryRs := newReplicaSet(canaryDeploy)
+		otherRs := newReplicaSet(otherDeploy)
+		cl := newFakeClientBuilder().
+			WithObjects(
+				newRevision(oldRevName, true), newRevision(newRevName, true),
+				canaryNs, otherNs, canaryDeploy, otherDeploy, canaryRs, otherRs,
+				newPod("canary", "a-1", ptr.Of(controllerRef("ReplicaSet", canaryRs.Name)), oldRevName),
+				newPod("other", "a-1", ptr.Of(controllerRef("ReplicaSet", otherRs.Name)), oldRevName),
+			).
+			Build()
+
+		// the old revision is the active one, the new revision is the canary
+		progress, err := UpdateWorkloads(ctx, newCacheClient(t, cl), cl, istioUID, oldRevName, &Canary{RevisionName: newRevName, Namespaces: []string{"canary"}})
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(progress).To(Equal(WorkloadUpdateProgress{ActiveRevisionReady: true, RestartingWorkloads: 1}))
+
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(canaryNs), canaryNs)).To(Succeed())
+		g.Expect(canaryNs.Labels[constants.IstioRevLabel]).To(Equal(newRevName))
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(canaryDeploy), canaryDeploy)).To(Succeed())
+		g.Expect(canaryDeploy.Spec.Template.Annotations[constants.WorkloadRevisionAnnotationKey]).To(Equal(newRevName))
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(otherDeploy), otherDeploy)).To(Succeed())
+		g.Expect(otherDeploy.Spec.Template.Annotations).ToNot(HaveKey(constants.RestartedAtAnnotationKey))
+	})
+
+	t.Run("skips workloads that would stay on the inactive revision", func(t *testing.T) {
+		g := NewWithT(t)
+		injectionNs := newNamespace("injection", map[string]string{constants.IstioInjectionLabel: constants.IstioInjectionEnabledValue})
+		defaultTag := &v1.IstioRevisionTag{
+			ObjectMeta: metav1.ObjectMeta{Name: v1.DefaultRevision},
+			Status:     v1.IstioRevisionTagStatus{IstioRevision: oldRevName},
+		}
+		stuckDeploy := newDeployment("injection", "a", nil)
+		otherDeploy := newDeployment("other", "a", nil)
+		stuckRs := newReplicaSet(stuckDeploy)
+		otherRs := newReplicaSet(otherDeploy)
+		cl := newFakeClientBuilder().
+			WithObjects(
+				newRevision(oldRevName, true), newRevision(newRevName, true), defaultTag,
+				injectionNs, newNamespace("other", map[string]string{constants.IstioRevLabel: oldRevName}),
+				stuckDeploy, otherDeploy, stuckRs, otherRs,
+				newPod("injection", "a-1", ptr.Of(controllerRef("ReplicaSet", stuckRs.Name)), oldRevName),
+				newPod("other", "a-1", ptr.Of(controllerRef("ReplicaSet", otherRs.Name)), oldRevName),
+			).
+			Build()
+
+		progress, err := UpdateWorkloads(ctx, newCacheClient(t, cl), cl, istioUID, newRevName, nil)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(progress).To(Equal(WorkloadUpdateProgress{
+			ActiveRevisionReady: true,
+			UpdatedNamespaces:   1,
+			RestartingWorkloads: 1,
+			SkippedWorkloads:    []string{"Deployment injection/a"},
+		}))
+
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(stuckDeploy), stuckDeploy)).To(Succeed())
+		g.Expect(stuckDeploy.Spec.Template.Annotations).ToNot(HaveKey(constants.RestartedAtAnnotationKey))
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(otherDeploy), otherDeploy)).To(Succeed())
+		g.Expect(otherDeploy.Spec.Template.Annotations[constants.WorkloadRevisionAnnotationKey]).To(Equal(newRevName))
+	})
+
+	t.Run("reports restarts that don't complete and moves on", func(t *testing.T) {
+		g := NewWithT(t)
+		restartedAt := func(d time.Duration) map[string]string {
+			return map[string]string{
+				constants.WorkloadRevisionAnnotationKey:    newRevName,
+				constants.WorkloadRestartedAtAnnotationKey: time.Now().Add(-d).Format(time.RFC3339),
+			}
+		}
+		timedOut := newDeployment("ns1", "a", nil)
+		timedOut.Annotations = restartedAt(workloadRestartTimeout + time.Minute)
+		deadlineExceeded := newDeployment("ns1", "b", nil)
+		deadlineExceeded.Annotations = restartedAt(time.Minute)
+		deadlineExceeded.Status.Conditions = []appsv1.DeploymentCondition{
+			{Type: appsv1.DeploymentProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded"},
+		}
+		pending := newDeployment("ns1", "c", nil)
+		rsA := newReplicaSet(timedOut)
+		rsB := newReplicaSet(deadlineExceeded)
+		rsC := newReplicaSet(pending)
+		cl := newFakeClientBuilder().
+			WithObjects(
+				newRevision(oldRevName, true), newRevision(newRevName, true),
+				timedOut, deadlineExceeded, pending, rsA, rsB, rsC,
+				newPod("ns1", "a-1", ptr.Of(controllerRef("ReplicaSet", rsA.Name)), oldRevName),
+				newPod("ns1", "b-1", ptr.Of(controllerRef("ReplicaSet", rsB.Name)), oldRevName),
+				newPod("ns1", "c-1", ptr.Of(controllerRef("ReplicaSet", rsC.Name)), oldRevName),
+			).
+			Build()
+
+		progress, err := UpdateWorkloads(ctx, newCacheClient(t, cl), cl, istioUID, newRevName, nil)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(progress).To(Equal(WorkloadUpdateProgress{
+			ActiveRevisionReady: true,
+			RestartingWorkloads: 1,
+			FailedWorkloads:     []string{"Deployment ns1/a", "Deployment ns1/b"},
+		}))
+		g.Expect(progress.Done()).To(BeFalse())
+
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(pending), pending)).To(Succeed())
+		g.Expect(pending.Spec.Template.Annotations[constants.WorkloadRevisionAnnotationKey]).To(Equal(newRevName))
+		g.Expect(pending.Annotations[constants.WorkloadRevisionAnnotationKey]).To(Equal(newRevName))
+		g.Expect(pending.Annotations).To(HaveKey(constants.WorkloadRestartedAtAnnotationKey))
+	})
+
+	t.Run("doesn't restart workloads whose pods have all run to completion", func(t *testing.T) {
+		g := NewWithT(t)
+		deploy := newDeployment("ns1", "a", nil)
+		rs := newReplicaSet(deploy)
+		completed := newPod("ns1", "a-1", ptr.Of(controllerRef("ReplicaSet", rs.Name)), oldRevName)
+		completed.Status.Phase = corev1.PodSucceeded
+		cl := newFakeClientBuilder().
+			WithObjects(newRevision(oldRevName, true), newRevision(newRevName, true), deploy, rs, completed).
+			Build()
+
+		progress, err := UpdateWorkloads(ctx, newCacheClient(t, cl), cl, istioUID, newRevName, nil)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(progress.Done()).To(BeTrue())
+
+		g.Expect(cl.Get(ctx, client.ObjectKeyFromObject(deploy), deploy)).To(Succeed())
+		g.Expect(deploy.Annotations).ToNot(HaveKey(constants.WorkloadRevisionAnnotationKey))
+		g.Expect(deploy.Spec.Template.Annotations).ToNot(HaveKey(constants.RestartedAtAnnotationKey))
+	})
+
+	t.Run("ignores pods that have run to completion", func(t *testing.T) {
+		g := NewWithT(t)
+		completed := newPod("ns1", "completed", nil, oldRevName)
+		completed.Status.Phase = corev1.PodSucceeded
+		failed := newPod("ns1", "failed", nil, oldRevName)
+		failed.Status.Phase = corev1.PodFailed
+		cl := newFakeClientBuilder().
+			WithObjects(newRevision(oldRevName, true), newRevision(newRevName, true), completed, failed).
+			Build()
+
+		progress, err := UpdateWorkloads(ctx, newCacheClient(t, cl), cl, istioUID, newRevName, nil)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(progress.Done()).To(BeTrue())
+	})
+
+	t.Run("reports pods not controlled by a workload", func(t *testing.T) {
+		g := NewWithT(t)
+		cl := newFakeClientBuilder().
+			WithObjects(
+				newRevision(oldRevName, true), newRevision(newRevName, true),
+				newPod("ns1", "bare-pod", nil, oldRevName),
+			).
+			Build()
+
+		progress, err := UpdateWorkloads(ctx, newCacheClient(t, cl), cl, istioUID, newRevName, nil)
+		g.Expect(err).ToNot(HaveOccurred())
+		g.Expect(progress).To(Equal(WorkloadUpdateProgress{ActiveRevisionReady: true, UnmanagedPods: 1}))
+		g.Expect(progress.Done()).To(BeFalse())
+	})
+}
+
+func controllerRef(kind, name string) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: appsv1.SchemeGroupVersion.String(),
+		Kind:       kind,
+		Name:       name,
+		UID:        types.UID(name),
+		Controller: ptr.Of(true),
+	}
+}
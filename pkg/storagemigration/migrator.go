@@ -0,0 +1,163 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package storagemigration rewrites the stored objects of a custom resource in the storage version of its CRD,
+// so that the other versions can be removed from status.storedVersions and eventually from the CRD.
+package storagemigration
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// +kubebuilder:rbac:groups="apiextensions.k8s.io",resources=customresourcedefinitions;customresourcedefinitions/status,resourceNames=ztunnels.sailoperator.io,verbs=update;patch
+
+// backoff determines how often the migration is retried, e.g. while the conversion webhook isn't reachable yet.
+var backoff = wait.Backoff{
+	Duration: 5 * time.Second,
+	Factor:   2,
+	Steps:    6,
+	Cap:      5 * time.Minute,
+}
+
+// Migrator migrates the objects of a single CRD. It runs once when the manager starts, after the webhook server
+// that serves the conversion webhook has been started. It must only be added to a manager that serves the
+// conversion webhook.
+type Migrator struct {
+	client  client.Client
+	reader  client.Reader
+	crdName string
+}
+
+var (
+	_ manager.Runnable               = &Migrator{}
+	_ manager.LeaderElectionRunnable = &Migrator{}
+)
+
+// New returns a Migrator for the CRD with the given name. The objects are read with the reader, so that they
+// don't need to be cached, and written with the client.
+func New(cl client.Client, reader client.Reader, crdName string) *Migrator {
+	return &Migrator{
+		client:  cl,
+		reader:  reader,
+		crdName: crdName,
+	}
+}
+
+// Start runs the migration, retrying it if it fails. A migration that keeps failing is logged, but doesn't stop
+// the manager; it's attempted again the next time the operator starts.
+func (m *Migrator) Start(ctx context.Context) error {
+	log := logf.FromContext(ctx).WithName("storagemigration").WithValues("crd", m.crdName)
+	ctx = logf.IntoContext(ctx, log)
+	var lastErr error
+	err := wait.ExponentialBackoffWithContext(ctx, backoff, func(ctx context.Context) (bool, error) {
+		lastErr = m.Migrate(ctx)
+		if lastErr != nil {
+			log.Info("storage version migration failed; will retry", "error", lastErr.Error())
+		}
+		return lastErr == nil, nil
+	})
+	if err != nil && lastErr != nil {
+		log.Error(lastErr, "storage version migration failed")
+	}
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, so that only the leader migrates the objects.
+func (m *Migrator) NeedLeaderElection() bool {
+	return true
+}
+
+// Migrate rewrites all objects of the CRD that may be stored in a version other than the storage version, and
+// then sets status.storedVersions of the CRD to the storage version. It does nothing if no other version is
+// stored, the CRD doesn't exist or the CRD doesn't use a conversion webhook.
+func (m *Migrator) Migrate(ctx context.Context) error {
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := m.reader.Get(ctx, types.NamespacedName{Name: m.crdName}, crd); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to get CRD %s: %w", m.crdName, err)
+	}
+	storageVersion := storageVersion(crd)
+	if storageVersion == "" {
+		return fmt.Errorf("CRD %s has no storage version", m.crdName)
+	}
+	if slices.Equal(crd.Status.StoredVersions, []string{storageVersion}) {
+		return nil
+	}
+
+	log := logf.FromContext(ctx)
+	// without the conversion webhook, the API server only changes the apiVersion of the objects, so rewriting
+	// them would drop the fields that don't exist in the storage version
+	if !usesConversionWebhook(crd) {
+		log.Info("Not migrating stored objects, because the CRD doesn't use a conversion webhook",
+			"storedVersions", crd.Status.StoredVersions)
+		return nil
+	}
+	log.Info("Migrating stored objects to the storage version", "storageVersion", storageVersion,
+		"storedVersions", crd.Status.StoredVersions)
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(schema.GroupVersionKind{
+		Group:   crd.Spec.Group,
+		Version: storageVersion,
+		Kind:    crd.Spec.Names.ListKind,
+	})
+	if err := m.reader.List(ctx, list); err != nil {
+		return fmt.Errorf("failed to list %s: %w", crd.Spec.Names.Plural, err)
+	}
+	for i := range list.Items {
+		// the API server writes the object in the storage version even if it's unchanged, because the
+		// encoded object differs from the one in etcd
+		if err := m.client.Update(ctx, &list.Items[i]); err != nil && !apierrors.IsNotFound(err) && !apierrors.IsConflict(err) {
+			return fmt.Errorf("failed to migrate %s %s: %w", crd.Spec.Names.Kind, list.Items[i].GetName(), err)
+		}
+	}
+
+	// objects that were updated or created in the meantime are stored in the storage version, so the
+	// other versions can be removed
+	crd.Status.StoredVersions = []string{storageVersion}
+	if err := m.client.Status().Update(ctx, crd); err != nil {
+		return fmt.Errorf("failed to update stored versions of CRD %s: %w", m.crdName, err)
+	}
+	log.Info("Migrated stored objects to the storage version", "objects", len(list.Items))
+	return nil
+}
+
+func usesConversionWebhook(crd *apiextensionsv1.CustomResourceDefinition) bool {
+	conversion := crd.Spec.Conversion
+	return conversion != nil && conversion.Strategy == apiextensionsv1.WebhookConverter &&
+		conversion.Webhook != nil && conversion.Webhook.ClientConfig != nil
+}
+
+func storageVersion(crd *apiextensionsv1.CustomResourceDefinition) string {
+	for _, version := range crd.Spec.Versions {
+		if version.Storage {
+			return version.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,178 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storagemigration
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+const crdName = "ztunnels.sailoperator.io"
+
+func newCRD(storedVersions ...string) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: crdName},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: v1.GroupVersion.Group,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Kind:     v1.ZTunnelKind,
+				ListKind: "ZTunnelList",
+				Plural:   "ztunnels",
+			},
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1", Served: true, Storage: true},
+				{Name: "v1alpha1", Served: true},
+			},
+			Conversion: &apiextensionsv1.CustomResourceConversion{
+				Strategy: apiextensionsv1.WebhookConverter,
+				Webhook: &apiextensionsv1.WebhookConversion{
+					ClientConfig: &apiextensionsv1.WebhookClientConfig{
+						Service: &apiextensionsv1.ServiceReference{Namespace: "sail-operator", Name: "sail-operator-webhook-service"},
+					},
+					ConversionReviewVersions: []string{"v1"},
+				},
+			},
+		},
+		Status: apiextensionsv1.CustomResourceDefinitionStatus{StoredVersions: storedVersions},
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	ztunnels := []client.Object{
+		&v1.ZTunnel{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&v1.ZTunnel{ObjectMeta: metav1.ObjectMeta{Name: "gpu"}},
+	}
+
+	testCases := []struct {
+		name                   string
+		crd                    *apiextensionsv1.CustomResourceDefinition
+		interceptors           interceptor.Funcs
+		expectUpdated          []string
+		expectStoredVersions   []string
+		expectErr              string
+		expectCRDStatusUpdated bool
+	}{
+		{
+			name:                   "migrates objects and trims stored versions",
+			crd:                    newCRD("v1alpha1", "v1"),
+			expectUpdated:          []string{"default", "gpu"},
+			expectStoredVersions:   []string{"v1"},
+			expectCRDStatusUpdated: true,
+		},
+		{
+			name:                 "does nothing if only the storage version is stored",
+			crd:                  newCRD("v1"),
+			expectStoredVersions: []string{"v1"},
+		},
+		{
+			name: "does nothing if the CRD doesn't use the conversion webhook",
+			crd: func() *apiextensionsv1.CustomResourceDefinition {
+				crd := newCRD("v1alpha1", "v1")
+				crd.Spec.Conversion = &apiextensionsv1.CustomResourceConversion{Strategy: apiextensionsv1.NoneConverter}
+				return crd
+			}(),
+			expectStoredVersions: []string{"v1alpha1", "v1"},
+		},
+		{
+			name: "ignores objects that were changed in the meantime",
+			crd:  newCRD("v1alpha1", "v1"),
+			interceptors: interceptor.Funcs{
+				Update: func(ctx context.Context, cl client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+					if obj.GetName() == "gpu" {
+						return apierrors.NewConflict(schema.GroupResource{Resource: "ztunnels"}, "gpu", fmt.Errorf("conflict"))
+					}
+					return cl.Update(ctx, obj, opts...)
+				},
+			},
+			expectUpdated:          []string{"default", "gpu"},
+			expectStoredVersions:   []string{"v1"},
+			expectCRDStatusUpdated: true,
+		},
+		{
+			name: "keeps stored versions if an object can't be migrated",
+			crd:  newCRD("v1alpha1", "v1"),
+			interceptors: interceptor.Funcs{
+				Update: func(ctx context.Context, cl client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+					if obj.GetName() == "gpu" {
+						return fmt.Errorf("conversion webhook unavailable")
+					}
+					return cl.Update(ctx, obj, opts...)
+				},
+			},
+			expectUpdated:        []string{"default", "gpu"},
+			expectStoredVersions: []string{"v1alpha1", "v1"},
+			expectErr:            "failed to migrate ZTunnel gpu: conversion webhook unavailable",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var updated []string
+			crdStatusUpdated := false
+			updateFn := tc.interceptors.Update
+			tc.interceptors.Update = func(ctx context.Context, cl client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				updated = append(updated, obj.GetName())
+				if updateFn != nil {
+					return updateFn(ctx, cl, obj, opts...)
+				}
+				return cl.Update(ctx, obj, opts...)
+			}
+			tc.interceptors.SubResourceUpdate = func(ctx context.Context, cl client.Client, subResourceName string, obj client.Object,
+				opts ...client.SubResourceUpdateOption,
+			) error {
+				crdStatusUpdated = true
+				return cl.SubResource(subResourceName).Update(ctx, obj, opts...)
+			}
+			cl := fake.NewClientBuilder().
+				WithScheme(scheme.Scheme).
+				WithObjects(append([]client.Object{tc.crd}, ztunnels...)...).
+				WithStatusSubresource(&apiextensionsv1.CustomResourceDefinition{}).
+				WithInterceptorFuncs(tc.interceptors).
+				Build()
+
+			err := New(cl, cl, crdName).Migrate(ctx)
+			if tc.expectErr != "" {
+				assert.EqualError(t, err, tc.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expectUpdated, updated)
+			assert.Equal(t, tc.expectCRDStatusUpdated, crdStatusUpdated)
+
+			crd := &apiextensionsv1.CustomResourceDefinition{}
+			require.NoError(t, cl.Get(ctx, types.NamespacedName{Name: crdName}, crd))
+			assert.Equal(t, tc.expectStoredVersions, crd.Status.StoredVersions)
+		})
+	}
+}
+
+func TestMigrateMissingCRD(t *testing.T) {
+	cl := fake.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	assert.NoError(t, New(cl, cl, crdName).Migrate(context.Background()))
+}
@@ -29,7 +29,9 @@ import (
 
 // ValidateTargetNamespace checks if the target namespace exists and is not being deleted.
 func ValidateTargetNamespace(ctx context.Context, cl client.Client, namespace string) error {
-	ns := &corev1.Namespace{}
+	// only the namespace's metadata is needed, so that the cache doesn't hold the full objects of all namespaces
+	ns := &metav1.PartialObjectMetadata{}
+	ns.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Namespace"))
 	if err := cl.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
 		if apierrors.IsNotFound(err) {
 			return reconciler.NewValidationError(fmt.Sprintf("namespace %q doesn't exist", namespace))
@@ -51,3 +53,15 @@ func ResourceTakesPrecedence(object1 *metav1.ObjectMeta, object2 *metav1.ObjectM
 	return object1.CreationTimestamp.Before(&object2.CreationTimestamp) ||
 		(object1.CreationTimestamp.Equal(&object2.CreationTimestamp) && strings.Compare(string(object1.UID), string(object2.UID)) < 0)
 }
+
+// NodeSelectorsOverlap returns `true` if a node can match both node selectors. Two node selectors select
+// disjoint sets of nodes only if they require different values for the same label. An empty node selector
+// matches all nodes.
+func NodeSelectorsOverlap(selector1, selector2 map[string]string) bool {
+	for key, value1 := range selector1 {
+		if value2, found := selector2[key]; found && value1 != value2 {
+			return false
+		}
+	}
+	return true
+}
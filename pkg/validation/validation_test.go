@@ -159,3 +159,53 @@ func TestResourceTakesPrecedence(t *testing.T) {
 		})
 	}
 }
+
+func TestNodeSelectorsOverlap(t *testing.T) {
+	testCases := []struct {
+		name           string
+		selector1      map[string]string
+		selector2      map[string]string
+		expectedResult bool
+	}{
+		{
+			name:           "both empty",
+			expectedResult: true,
+		},
+		{
+			name:           "one empty",
+			selector1:      map[string]string{"pool": "a"},
+			expectedResult: true,
+		},
+		{
+			name:           "same label and value",
+			selector1:      map[string]string{"pool": "a"},
+			selector2:      map[string]string{"pool": "a"},
+			expectedResult: true,
+		},
+		{
+			name:           "different labels",
+			selector1:      map[string]string{"pool": "a"},
+			selector2:      map[string]string{"zone": "a"},
+			expectedResult: true,
+		},
+		{
+			name:           "same label with different values",
+			selector1:      map[string]string{"pool": "a"},
+			selector2:      map[string]string{"pool": "b"},
+			expectedResult: false,
+		},
+		{
+			name:           "one of several labels with different values",
+			selector1:      map[string]string{"pool": "a", "zone": "a"},
+			selector2:      map[string]string{"pool": "a", "zone": "b"},
+			expectedResult: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(NodeSelectorsOverlap(tc.selector1, tc.selector2)).To(Equal(tc.expectedResult))
+			g.Expect(NodeSelectorsOverlap(tc.selector2, tc.selector1)).To(Equal(tc.expectedResult))
+		})
+	}
+}
@@ -78,6 +78,46 @@ func AsPredicate(fn ShouldReconcileFunc) predicate.Funcs {
 	}
 }
 
+// Merge combines watch lists into a single list that contains each resource type once. When a type is in
+// several lists, an update triggers reconciliation if any of their filters accepts it. Skipped entries are
+// only skipped if the type is skipped in all lists.
+func Merge(watchLists ...[]WatchedResource) []WatchedResource {
+	var merged []WatchedResource
+	index := map[reflect.Type]int{}
+	for _, watchList := range watchLists {
+		for _, wr := range watchList {
+			t := reflect.TypeOf(wr.Object)
+			i, found := index[t]
+			if !found {
+				index[t] = len(merged)
+				merged = append(merged, wr)
+				continue
+			}
+			existing := &merged[i]
+			if wr.Skipped {
+				continue
+			}
+			if existing.Skipped {
+				*existing = wr
+				continue
+			}
+			existing.ShouldReconcile = anyOf(existing.ShouldReconcile, wr.ShouldReconcile)
+		}
+	}
+	return merged
+}
+
+// anyOf returns a filter that accepts an update if any of the given filters accepts it. A nil filter accepts
+// all updates.
+func anyOf(a, b ShouldReconcileFunc) ShouldReconcileFunc {
+	if a == nil || b == nil {
+		return nil
+	}
+	return func(oldObj, newObj client.Object) bool {
+		return a(oldObj, newObj) || b(oldObj, newObj)
+	}
+}
+
 // RegisterOwnedWatches registers watches for all non-skipped resources in the watch list.
 // handlerOverrides is keyed by reflect.Type (e.g. reflect.TypeOf(&discoveryv1.EndpointSlice{})).
 func RegisterOwnedWatches(
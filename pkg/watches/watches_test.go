@@ -172,3 +172,40 @@ func TestWebhookFilter(t *testing.T) {
 	newObj2.Generation = 2
 	g.Expect(shouldReconcile(oldObj2, newObj2)).To(BeFalse(), "generation-only change should not trigger reconcile (cleared by filter)")
 }
+
+func TestMerge(t *testing.T) {
+	g := NewWithT(t)
+
+	first := []WatchedResource{
+		{Object: &corev1.ConfigMap{}},
+		{Object: &corev1.ServiceAccount{}, ShouldReconcile: IgnoreAllUpdates()},
+		{Object: &corev1.Service{}, Skipped: true},
+	}
+	second := []WatchedResource{
+		{Object: &corev1.ConfigMap{}, ShouldReconcile: IgnoreAllUpdates()},
+		{Object: &corev1.ServiceAccount{}, ShouldReconcile: IgnoreStatusChanges()},
+		{Object: &corev1.Service{}},
+		{Object: &corev1.Secret{}},
+	}
+
+	merged := Merge(first, second)
+	g.Expect(merged).To(HaveLen(4))
+
+	// a type without a filter in any list is always reconciled
+	g.Expect(merged[0].Object).To(BeAssignableToTypeOf(&corev1.ConfigMap{}))
+	g.Expect(merged[0].ShouldReconcile).To(BeNil())
+
+	// the filters of a type in several lists are combined
+	g.Expect(merged[1].Object).To(BeAssignableToTypeOf(&corev1.ServiceAccount{}))
+	oldSA := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Generation: 1}}
+	g.Expect(merged[1].ShouldReconcile(oldSA, oldSA.DeepCopy())).To(BeFalse())
+	newSA := oldSA.DeepCopy()
+	newSA.Labels = map[string]string{"app": "test"}
+	g.Expect(merged[1].ShouldReconcile(oldSA, newSA)).To(BeTrue())
+
+	// a type is only skipped if it's skipped in all lists
+	g.Expect(merged[2].Object).To(BeAssignableToTypeOf(&corev1.Service{}))
+	g.Expect(merged[2].Skipped).To(BeFalse())
+
+	g.Expect(merged[3].Object).To(BeAssignableToTypeOf(&corev1.Secret{}))
+}
@@ -18,7 +18,6 @@ package ambient
 
 import (
 	"fmt"
-	"strings"
 	"time"
 
 	. "github.com/istio-ecosystem/sail-operator/pkg/test/util/ginkgo"
@@ -35,6 +34,11 @@ var _ = Describe("Ambient API Validation", Label("ambient", "ambient-validation"
 	// Use the latest supported ambient version for these tests
 	version := getLatestAmbientVersion()
 
+	const (
+		customCniNamespace     = "custom-istio-cni"
+		customZTunnelNamespace = "custom-ztunnel"
+	)
+
 	var clr cleaner.Cleaner
 
 	BeforeAll(func(ctx SpecContext) {
@@ -56,9 +60,10 @@ var _ = Describe("Ambient API Validation", Label("ambient", "ambient-validation"
 		clr.Cleanup(ctx)
 	})
 
-	Context("Single Instance Name Enforcement", func() {
-		When("attempting to create IstioCNI with non-default name", func() {
-			It("rejects IstioCNI CR with name != 'default'", func(ctx SpecContext) {
+	Context("Instance Names", func() {
+		When("creating IstioCNI with non-default name", func() {
+			It("accepts IstioCNI CR with name != 'default'", func(ctx SpecContext) {
+				Expect(k.CreateNamespace(customCniNamespace)).To(Succeed())
 				cniYAML := fmt.Sprintf(`
 apiVersion: sailoperator.io/v1
 kind: IstioCNI
@@ -67,21 +72,17 @@ metadata:
 spec:
   version: %s
   namespace: %s
-  profile: ambient`, version.Name, istioCniNamespace)
-
-				err := k.CreateFromString(cniYAML)
-				Expect(err).To(HaveOccurred(), "IstioCNI creation should fail with non-default name")
+  profile: ambient`, version.Name, customCniNamespace)
 
-				// Verify error message indicates validation failure
-				errorMsg := err.Error()
-				Expect(strings.Contains(errorMsg, "metadata.name") || strings.Contains(errorMsg, "default")).To(BeTrue(),
-					"Error message should mention metadata.name or 'default' requirement")
-				Success("IstioCNI with custom name correctly rejected")
+				Expect(k.CreateFromString(cniYAML)).To(Succeed(), "IstioCNI creation should succeed with non-default name")
+				Expect(k.Delete("istiocni", "custom-cni")).To(Succeed())
+				Success("IstioCNI with custom name created successfully")
 			})
 		})
 
-		When("attempting to create ZTunnel with non-default name", func() {
-			It("rejects ZTunnel CR with name != 'default'", func(ctx SpecContext) {
+		When("creating ZTunnel with non-default name", func() {
+			It("accepts ZTunnel CR with name != 'default'", func(ctx SpecContext) {
+				Expect(k.CreateNamespace(customZTunnelNamespace)).To(Succeed())
 				ztunnelYAML := fmt.Sprintf(`
 apiVersion: sailoperator.io/v1
 kind: ZTunnel
@@ -89,16 +90,11 @@ metadata:
   name: custom-ztunnel
 spec:
   version: %s
-  namespace: %s`, version.Name, ztunnelNamespace)
-
-				err := k.CreateFromString(ztunnelYAML)
-				Expect(err).To(HaveOccurred(), "ZTunnel creation should fail with non-default name")
+  namespace: %s`, version.Name, customZTunnelNamespace)
 
-				// Verify error message indicates validation failure
-				errorMsg := err.Error()
-				Expect(strings.Contains(errorMsg, "metadata.name") || strings.Contains(errorMsg, "default")).To(BeTrue(),
-					"Error message should mention metadata.name or 'default' requirement")
-				Success("ZTunnel with custom name correctly rejected")
+				Expect(k.CreateFromString(ztunnelYAML)).To(Succeed(), "ZTunnel creation should succeed with non-default name")
+				Expect(k.Delete("ztunnel", "custom-ztunnel")).To(Succeed())
+				Success("ZTunnel with custom name created successfully")
 			})
 		})
 
@@ -0,0 +1,39 @@
+//go:build integration
+
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Manager cache", Label("cache"), func() {
+	It("doesn't start typed Pod or Namespace informers", func() {
+		// the controllers start their informers as soon as the manager starts, so any typed informer would be
+		// recorded within a few seconds
+		Consistently(func() []string {
+			var types []string
+			typedInformers.Range(func(key, _ any) bool {
+				types = append(types, key.(string))
+				return true
+			})
+			return types
+		}).WithTimeout(5 * time.Second).WithPolling(time.Second).Should(BeEmpty())
+	})
+})
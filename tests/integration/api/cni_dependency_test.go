@@ -199,8 +199,7 @@ var _ = Describe("CNI Dependency", Ordered, func() {
 				ds := &appsv1.DaemonSet{}
 				Expect(k8sClient.Create(ctx, istiocni)).To(Succeed())
 				Eventually(k8sClient.Get).WithArguments(ctx, daemonsetKey, ds).Should(Succeed())
-				ds.Status.CurrentNumberScheduled = 1
-				ds.Status.NumberReady = 1
+				setDaemonSetStatus(ds, 1, 1)
 				Expect(k8sClient.Status().Update(ctx, ds)).To(Succeed())
 
 				Step("Creating Istio resource with CNI enabled")
@@ -330,8 +329,7 @@ var _ = Describe("CNI Dependency", Ordered, func() {
 				ds := &appsv1.DaemonSet{}
 				Expect(k8sClient.Create(ctx, istiocni)).To(Succeed())
 				Eventually(k8sClient.Get).WithArguments(ctx, daemonsetKey, ds).Should(Succeed())
-				ds.Status.CurrentNumberScheduled = 1
-				ds.Status.NumberReady = 1
+				setDaemonSetStatus(ds, 1, 1)
 				Expect(k8sClient.Status().Update(ctx, ds)).To(Succeed())
 			})
 
@@ -71,17 +71,19 @@ var _ = Describe("IstioCNI", Label("istiocni"), Ordered, func() {
 	})
 
 	Describe("validation", func() {
-		It("only accepts IstioCNI with the name 'default'", func() {
+		It("accepts IstioCNI with a name other than 'default'", func() {
 			cni = &v1.IstioCNI{
 				ObjectMeta: metav1.ObjectMeta{
 					Name: "not-default",
 				},
 				Spec: v1.IstioCNISpec{
 					Version:   istioversion.Default,
-					Namespace: cniNamespace,
+					Namespace: "nonexistent-namespace-" + rand.String(8),
 				},
 			}
-			Expect(k8sClient.Create(ctx, cni)).To(Not(Succeed()))
+			Expect(k8sClient.Create(ctx, cni)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, cni)).To(Succeed())
+			Eventually(k8sClient.Get).WithArguments(ctx, kube.Key(cni.Name), cni).Should(ReturnNotFoundError())
 		})
 	})
 
@@ -168,8 +170,7 @@ var _ = Describe("IstioCNI", Label("istiocni"), Ordered, func() {
 			When("DaemonSet becomes ready", func() {
 				BeforeAll(func() {
 					Expect(k8sClient.Get(ctx, daemonsetKey, ds)).To(Succeed())
-					ds.Status.CurrentNumberScheduled = 3
-					ds.Status.NumberReady = 3
+					setDaemonSetStatus(ds, 3, 3)
 					Expect(k8sClient.Status().Update(ctx, ds)).To(Succeed())
 				})
 
@@ -181,8 +182,7 @@ var _ = Describe("IstioCNI", Label("istiocni"), Ordered, func() {
 			When("DaemonSet becomes not ready", func() {
 				BeforeAll(func() {
 					Expect(k8sClient.Get(ctx, daemonsetKey, ds)).To(Succeed())
-					ds.Status.CurrentNumberScheduled = 3
-					ds.Status.NumberReady = 2
+					setDaemonSetStatus(ds, 3, 2)
 					Expect(k8sClient.Status().Update(ctx, ds)).To(Succeed())
 				})
 
@@ -223,8 +223,7 @@ var _ = Describe("IstioRevision resource", Label("istiorevision"), Ordered, func
 			dsKey := client.ObjectKey{Name: "istio-cni-node", Namespace: cni.Spec.Namespace}
 			ds := &appsv1.DaemonSet{}
 			Expect(k8sClient.Get(ctx, dsKey, ds)).To(Succeed())
-			ds.Status.CurrentNumberScheduled = 3
-			ds.Status.NumberReady = 3
+			setDaemonSetStatus(ds, 3, 3)
 			Expect(k8sClient.Status().Update(ctx, ds)).To(Succeed())
 
 			expectCNICondition(ctx, v1.IstioCNIConditionReady, metav1.ConditionTrue)
@@ -263,8 +262,7 @@ var _ = Describe("IstioRevision resource", Label("istiorevision"), Ordered, func
 			ds := &appsv1.DaemonSet{}
 			Eventually(k8sClient.Get).WithArguments(ctx, dsKey, ds).Should(Succeed())
 			// Make CNI healthy
-			ds.Status.CurrentNumberScheduled = 3
-			ds.Status.NumberReady = 3
+			setDaemonSetStatus(ds, 3, 3)
 			Expect(k8sClient.Status().Update(ctx, ds)).To(Succeed())
 			expectCNICondition(ctx, v1.IstioCNIConditionReady, metav1.ConditionTrue)
 
@@ -316,8 +314,7 @@ var _ = Describe("IstioRevision resource", Label("istiorevision"), Ordered, func
 			dsKey := client.ObjectKey{Name: "ztunnel", Namespace: ztunnel.Spec.Namespace}
 			ds := &appsv1.DaemonSet{}
 			Expect(k8sClient.Get(ctx, dsKey, ds)).To(Succeed())
-			ds.Status.CurrentNumberScheduled = 3
-			ds.Status.NumberReady = 3
+			setDaemonSetStatus(ds, 3, 3)
 			Expect(k8sClient.Status().Update(ctx, ds)).To(Succeed())
 
 			expectZTunnelCondition(ctx, v1.ZTunnelConditionReady, metav1.ConditionTrue)
@@ -466,8 +463,7 @@ var _ = Describe("IstioRevision resource", Label("istiorevision"), Ordered, func
 			By("setting the Ready condition status to true when istiod is ready", func() {
 				istiod := &appsv1.Deployment{}
 				Expect(k8sClient.Get(ctx, istiodKey, istiod)).To(Succeed())
-				istiod.Status.Replicas = 1
-				istiod.Status.ReadyReplicas = 1
+				setDeploymentStatus(istiod, 1, 1)
 				Expect(k8sClient.Status().Update(ctx, istiod)).To(Succeed())
 
 				expectCondition(ctx, revName, v1.IstioRevisionConditionReady, metav1.ConditionTrue)
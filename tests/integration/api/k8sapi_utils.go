@@ -17,6 +17,7 @@ package integration
 import (
 	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
 	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -56,3 +57,24 @@ func NewOwnerReference(obj client.Object) metav1.OwnerReference {
 		BlockOwnerDeletion: ptr.Of(true),
 	}
 }
+
+// setDaemonSetStatus sets the status of the DaemonSet as if the DaemonSet controller had scheduled the given
+// number of up-to-date pods, of which the given number are ready
+func setDaemonSetStatus(ds *appsv1.DaemonSet, scheduled, ready int32) {
+	ds.Status.ObservedGeneration = ds.Generation
+	ds.Status.CurrentNumberScheduled = scheduled
+	ds.Status.DesiredNumberScheduled = scheduled
+	ds.Status.UpdatedNumberScheduled = scheduled
+	ds.Status.NumberAvailable = ready
+	ds.Status.NumberReady = ready
+}
+
+// setDeploymentStatus sets the status of the Deployment as if the Deployment controller had created the given
+// number of up-to-date replicas, of which the given number are ready
+func setDeploymentStatus(deploy *appsv1.Deployment, replicas, ready int32) {
+	deploy.Status.ObservedGeneration = deploy.Generation
+	deploy.Status.Replicas = replicas
+	deploy.Status.UpdatedReplicas = replicas
+	deploy.Status.AvailableReplicas = ready
+	deploy.Status.ReadyReplicas = ready
+}
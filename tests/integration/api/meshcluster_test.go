@@ -0,0 +1,176 @@
+//go:build integration
+
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"context"
+	"time"
+
+	v1 "github.com/istio-ecosystem/sail-operator/api/v1"
+	"github.com/istio-ecosystem/sail-operator/api/v1alpha1"
+	"github.com/istio-ecosystem/sail-operator/pkg/istioversion"
+	"github.com/istio-ecosystem/sail-operator/pkg/kube"
+	"github.com/istio-ecosystem/sail-operator/pkg/meshcluster"
+	"github.com/istio-ecosystem/sail-operator/pkg/test"
+	. "github.com/istio-ecosystem/sail-operator/pkg/test/util/ginkgo"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+const meshClusterNamespace = "meshcluster-test"
+
+// The MeshCluster tests start a second API server that acts as the peer cluster.
+var _ = Describe("MeshCluster", Label("meshcluster"), Ordered, func() {
+	SetDefaultEventuallyPollingInterval(time.Second)
+	SetDefaultEventuallyTimeout(30 * time.Second)
+
+	ctx := context.Background()
+
+	var peerEnv *envtest.Environment
+	var peerClient client.Client
+
+	istio := &v1.Istio{}
+	mc := &v1alpha1.MeshCluster{}
+	remoteSecretKey := client.ObjectKey{Namespace: meshClusterNamespace, Name: meshcluster.RemoteSecretName("cluster2")}
+
+	BeforeAll(func() {
+		Step("Starting the API server of the peer cluster")
+		env, cl, cfg := test.SetupEnv(GinkgoWriter, false)
+		peerEnv, peerClient = env, cl
+
+		Step("Creating the istio-reader-service-account in the peer cluster")
+		Expect(peerClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: meshClusterNamespace}})).To(Succeed())
+		Expect(peerClient.Create(ctx, &corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: meshcluster.ReaderServiceAccountName, Namespace: meshClusterNamespace},
+		})).To(Succeed())
+		Expect(peerClient.Create(ctx, &rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio-reader"},
+			Rules: []rbacv1.PolicyRule{
+				{APIGroups: []string{""}, Resources: []string{"services", "endpoints", "pods", "nodes"}, Verbs: []string{"get", "list", "watch"}},
+			},
+		})).To(Succeed())
+		Expect(peerClient.Create(ctx, &rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio-reader"},
+			RoleRef:    rbacv1.RoleRef{APIGroup: rbacv1.GroupName, Kind: "ClusterRole", Name: "istio-reader"},
+			Subjects: []rbacv1.Subject{
+				{Kind: rbacv1.ServiceAccountKind, Name: meshcluster.ReaderServiceAccountName, Namespace: meshClusterNamespace},
+			},
+		})).To(Succeed())
+
+		Step("Storing the kubeconfig of the peer cluster in the operator namespace")
+		kubeconfig := clientcmdapi.NewConfig()
+		kubeconfig.Clusters["cluster2"] = &clientcmdapi.Cluster{Server: cfg.Host, CertificateAuthorityData: cfg.CAData}
+		kubeconfig.AuthInfos["admin"] = &clientcmdapi.AuthInfo{ClientCertificateData: cfg.CertData, ClientKeyData: cfg.KeyData}
+		kubeconfig.Contexts["cluster2"] = &clientcmdapi.Context{Cluster: "cluster2", AuthInfo: "admin"}
+		kubeconfig.CurrentContext = "cluster2"
+		data, err := clientcmd.Write(*kubeconfig)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(k8sClient.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster2-kubeconfig", Namespace: operatorNamespace},
+			Data:       map[string][]byte{"kubeconfig": data},
+		})).To(Succeed())
+
+		Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: meshClusterNamespace}})).To(Succeed())
+	})
+
+	AfterAll(func() {
+		deleteAllIstiosAndRevisions(ctx)
+		Expect(k8sClient.Delete(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: meshClusterNamespace}})).To(Succeed())
+		Expect(peerEnv.Stop()).To(Succeed())
+	})
+
+	When("the MeshCluster targets an Istio with a peer", func() {
+		BeforeAll(func() {
+			istio = &v1.Istio{
+				ObjectMeta: metav1.ObjectMeta{Name: "default"},
+				Spec: v1.IstioSpec{
+					Version:   istioversion.Default,
+					Namespace: meshClusterNamespace,
+				},
+			}
+			Expect(k8sClient.Create(ctx, istio)).To(Succeed())
+
+			mc = &v1alpha1.MeshCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: meshcluster.Name},
+				Spec: v1alpha1.MeshClusterSpec{
+					TargetRef:   v1.TargetReference{Kind: v1.IstioKind, Name: istio.Name},
+					MeshID:      "mesh1",
+					ClusterName: "cluster1",
+					Peers: []v1alpha1.MeshClusterPeer{
+						{Name: "cluster2", KubeconfigSecretRef: v1alpha1.SecretKeyReference{Name: "cluster2-kubeconfig"}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, mc)).To(Succeed())
+		})
+
+		AfterAll(func() {
+			Expect(k8sClient.Delete(ctx, mc)).To(Succeed())
+			Eventually(k8sClient.Get).WithArguments(ctx, kube.Key(meshcluster.Name), mc).Should(ReturnNotFoundError())
+		})
+
+		It("sets the mesh ID and cluster name in the IstioRevision", func() {
+			Eventually(func(g Gomega) {
+				rev := &v1.IstioRevision{}
+				g.Expect(k8sClient.Get(ctx, kube.Key(istio.Name), rev)).To(Succeed())
+				g.Expect(rev.Spec.Values.Global.MeshID).To(HaveValue(Equal("mesh1")))
+				g.Expect(rev.Spec.Values.Global.MultiCluster.ClusterName).To(HaveValue(Equal("cluster1")))
+			}).Should(Succeed())
+		})
+
+		It("creates the remote secret with a token issued by the peer cluster", func() {
+			secret := &corev1.Secret{}
+			Eventually(k8sClient.Get).WithArguments(ctx, remoteSecretKey, secret).Should(Succeed())
+			Expect(secret.Labels).To(HaveKeyWithValue(meshcluster.MultiClusterSecretLabelKey, "true"))
+
+			cfg, err := meshcluster.RESTConfigFromRemoteSecret(secret, "cluster2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.BearerToken).NotTo(BeEmpty())
+			Expect(cfg.CertData).To(BeEmpty())
+		})
+
+		It("reports that the peer cluster is connected", func() {
+			Eventually(func(g Gomega) {
+				g.Expect(k8sClient.Get(ctx, kube.Key(meshcluster.Name), mc)).To(Succeed())
+				g.Expect(mc.Status.Role).To(Equal(v1alpha1.MeshClusterRolePrimary))
+				g.Expect(mc.Status.Peers).To(HaveLen(1))
+				g.Expect(mc.Status.Peers[0].Connected).To(BeTrue(), mc.Status.Peers[0].Message)
+				g.Expect(mc.Status.Peers[0].ServerVersion).NotTo(BeEmpty())
+				g.Expect(mc.Status.Peers[0].TokenExpirationTime).NotTo(BeNil())
+			}).Should(Succeed())
+		})
+
+		When("the peer is removed", func() {
+			BeforeAll(func() {
+				Expect(k8sClient.Get(ctx, kube.Key(meshcluster.Name), mc)).To(Succeed())
+				mc.Spec.Peers = nil
+				Expect(k8sClient.Update(ctx, mc)).To(Succeed())
+			})
+
+			It("deletes the remote secret", func() {
+				Eventually(k8sClient.Get).WithArguments(ctx, remoteSecretKey, &corev1.Secret{}).Should(ReturnNotFoundError())
+			})
+		})
+	})
+})
@@ -18,17 +18,22 @@ package integration
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"path"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/istio-ecosystem/sail-operator/controllers/istio"
 	"github.com/istio-ecosystem/sail-operator/controllers/istiocni"
 	"github.com/istio-ecosystem/sail-operator/controllers/istiorevision"
 	"github.com/istio-ecosystem/sail-operator/controllers/istiorevisiontag"
+	"github.com/istio-ecosystem/sail-operator/controllers/meshcluster"
 	"github.com/istio-ecosystem/sail-operator/controllers/ztunnel"
 	"github.com/istio-ecosystem/sail-operator/pkg/config"
 	"github.com/istio-ecosystem/sail-operator/pkg/helm"
+	"github.com/istio-ecosystem/sail-operator/pkg/revision"
 	"github.com/istio-ecosystem/sail-operator/pkg/scheme"
 	"github.com/istio-ecosystem/sail-operator/pkg/test"
 	"github.com/istio-ecosystem/sail-operator/pkg/test/project"
@@ -36,8 +41,11 @@ import (
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/rest"
+	toolscache "k8s.io/client-go/tools/cache"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -58,6 +66,11 @@ var (
 	istioRevisionTagReconciler *istiorevisiontag.Reconciler
 	istioCNIReconciler         *istiocni.Reconciler
 	zTunnelReconciler          *ztunnel.Reconciler
+	meshClusterReconciler      *meshcluster.Reconciler
+
+	// typedInformers records the typed Pod and Namespace informers started by the manager's cache, which must
+	// only hold the metadata of pods and namespaces
+	typedInformers sync.Map
 )
 
 const operatorNamespace = "sail-operator"
@@ -73,6 +86,15 @@ var _ = BeforeSuite(func() {
 	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
 		Scheme:  scheme.Scheme,
 		Metrics: metricsserver.Options{BindAddress: ":8080"},
+		Cache: cache.Options{
+			NewInformer: func(lw toolscache.ListerWatcher, obj runtime.Object, resync time.Duration, indexers toolscache.Indexers) toolscache.SharedIndexInformer {
+				switch obj.(type) {
+				case *corev1.Pod, *corev1.Namespace:
+					typedInformers.Store(fmt.Sprintf("%T", obj), true)
+				}
+				return toolscache.NewSharedIndexInformer(lw, obj, resync, indexers)
+			},
+		},
 		NewClient: func(config *rest.Config, options client.Options) (client.Client, error) {
 			return k8sClient, nil
 		},
@@ -94,18 +116,22 @@ var _ = BeforeSuite(func() {
 		MaxConcurrentReconciles: 5,
 	}
 
+	Expect(revision.RegisterUsageIndexes(context.TODO(), mgr.GetFieldIndexer())).To(Succeed())
+
 	cl := mgr.GetClient()
 	scheme := mgr.GetScheme()
-	istioReconciler = istio.NewReconciler(cfg, cl, scheme)
-	istioRevisionReconciler = istiorevision.NewReconciler(cfg, cl, scheme, chartManager)
-	istioRevisionTagReconciler = istiorevisiontag.NewReconciler(cfg, cl, scheme, chartManager)
+	istioReconciler = istio.NewReconciler(cfg, cl, mgr.GetAPIReader(), scheme, chartManager)
+	istioRevisionReconciler = istiorevision.NewReconciler(cfg, cl, mgr.GetCache(), mgr.GetAPIReader(), scheme, chartManager)
+	istioRevisionTagReconciler = istiorevisiontag.NewReconciler(cfg, cl, mgr.GetCache(), mgr.GetAPIReader(), scheme, chartManager)
 	istioCNIReconciler = istiocni.NewReconciler(cfg, cl, scheme, chartManager)
 	zTunnelReconciler = ztunnel.NewReconciler(cfg, cl, scheme, chartManager)
+	meshClusterReconciler = meshcluster.NewReconciler(cfg, cl, mgr.GetAPIReader(), scheme)
 	Expect(istioReconciler.SetupWithManager(mgr)).To(Succeed())
 	Expect(istioRevisionReconciler.SetupWithManager(mgr)).To(Succeed())
 	Expect(istioRevisionTagReconciler.SetupWithManager(mgr)).To(Succeed())
 	Expect(istioCNIReconciler.SetupWithManager(mgr)).To(Succeed())
 	Expect(zTunnelReconciler.SetupWithManager(mgr)).To(Succeed())
+	Expect(meshClusterReconciler.SetupWithManager(mgr)).To(Succeed())
 
 	// create new cancellable context
 	var ctx context.Context
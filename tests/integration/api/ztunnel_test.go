@@ -144,8 +144,7 @@ var _ = Describe("ZTunnel DaemonSet status changes", Label("ztunnel"), Ordered,
 			When("DaemonSet becomes ready", func() {
 				BeforeAll(func() {
 					Expect(k8sClient.Get(ctx, daemonsetKey, ds)).To(Succeed())
-					ds.Status.CurrentNumberScheduled = 3
-					ds.Status.NumberReady = 3
+					setDaemonSetStatus(ds, 3, 3)
 					Expect(k8sClient.Status().Update(ctx, ds)).To(Succeed())
 				})
 
@@ -161,8 +160,7 @@ var _ = Describe("ZTunnel DaemonSet status changes", Label("ztunnel"), Ordered,
 			When("DaemonSet becomes not ready", func() {
 				BeforeAll(func() {
 					Expect(k8sClient.Get(ctx, daemonsetKey, ds)).To(Succeed())
-					ds.Status.CurrentNumberScheduled = 3
-					ds.Status.NumberReady = 2
+					setDaemonSetStatus(ds, 3, 2)
 					Expect(k8sClient.Status().Update(ctx, ds)).To(Succeed())
 				})
 